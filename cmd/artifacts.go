@@ -0,0 +1,184 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/clobrano/openshift-sts-wrapper/pkg/logger"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/util"
+	"github.com/spf13/cobra"
+)
+
+var (
+	pruneKeepLast  int
+	pruneOlderThan string
+	pruneDryRun    bool
+	pruneYes       bool
+
+	migrateDryRun bool
+)
+
+var artifactsCmd = &cobra.Command{
+	Use:   "artifacts",
+	Short: "Manage the artifacts directory",
+	Long:  `Inspect and maintain the shared and cluster-specific artifacts created by the installer`,
+}
+
+var artifactsPruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Delete shared artifacts no longer used by any cluster",
+	Long: `Lists the shared artifacts directories (one per OpenShift version), shows which
+clusters still reference them, and deletes the unreferenced ones`,
+	Run: runArtifactsPrune,
+}
+
+var artifactsMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Move legacy artifacts into the shared and cluster directories",
+	Long: `Moves the artifacts/<version>-<arch> directories of older releases of the
+wrapper into the current layout: the binaries and credentials requests into
+artifacts/shared/<version>-<arch>, the installation files into
+artifacts/clusters/<cluster-name>, with the install metadata cleanup needs.
+Nothing is overwritten: entries already in the new layout stay where they are.`,
+	Args: cobra.NoArgs,
+	Run:  runArtifactsMigrate,
+}
+
+func init() {
+	rootCmd.AddCommand(artifactsCmd)
+	artifactsCmd.AddCommand(artifactsPruneCmd)
+	artifactsCmd.AddCommand(artifactsMigrateCmd)
+
+	artifactsPruneCmd.Flags().IntVar(&pruneKeepLast, "keep-last", 0, "Keep the N most recently used unreferenced versions")
+	artifactsPruneCmd.Flags().StringVar(&pruneOlderThan, "older-than", "", "Only prune versions unused for at least this long (e.g. 72h, 30d)")
+	artifactsPruneCmd.Flags().BoolVar(&pruneDryRun, "dry-run", false, "List what would be deleted without deleting anything")
+	artifactsPruneCmd.Flags().BoolVar(&pruneYes, "yes", false, "Do not ask for confirmation")
+
+	artifactsMigrateCmd.Flags().BoolVar(&migrateDryRun, "dry-run", false, "List what would be moved without moving anything")
+}
+
+func runArtifactsPrune(cmd *cobra.Command, args []string) {
+	log := logger.New(logger.Level(getLogLevel()), nil)
+
+	var olderThan time.Duration
+	if pruneOlderThan != "" {
+		var err error
+		olderThan, err = util.ParseAge(pruneOlderThan)
+		if err != nil {
+			log.Error(fmt.Sprintf("Invalid --older-than value: %v", err))
+			exit(1)
+		}
+	}
+
+	artifacts, err := util.ListSharedArtifacts()
+	if err != nil {
+		log.Error(fmt.Sprintf("Failed to list shared artifacts: %v", err))
+		exit(1)
+	}
+	if len(artifacts) == 0 {
+		log.Info("No shared artifacts found.")
+		return
+	}
+
+	log.Info("Shared artifacts:")
+	for _, artifact := range artifacts {
+		usedBy := "unreferenced"
+		if len(artifact.Clusters) > 0 {
+			usedBy = "used by " + strings.Join(artifact.Clusters, ", ")
+		}
+		log.Info(fmt.Sprintf("  %-24s %10s  last used %s  (%s)",
+			artifact.VersionArch, util.FormatSize(artifact.Size), artifact.LastUsed.Format("2006-01-02 15:04"), usedBy))
+	}
+	log.Info("")
+
+	prunable := util.SelectPrunable(artifacts, pruneKeepLast, olderThan, time.Now())
+	if len(prunable) == 0 {
+		log.Info("Nothing to prune.")
+		return
+	}
+
+	var total int64
+	log.Info("The following shared artifacts will be deleted:")
+	for _, artifact := range prunable {
+		total += artifact.Size
+		log.Info(fmt.Sprintf("  - %s (%s)", artifact.Path, util.FormatSize(artifact.Size)))
+	}
+	log.Info(fmt.Sprintf("Total: %s", util.FormatSize(total)))
+
+	if pruneDryRun {
+		log.Info("Dry run: nothing deleted.")
+		return
+	}
+
+	if !pruneYes {
+		reader := bufio.NewReader(os.Stdin)
+		fmt.Print("Continue? (y/n): ")
+		response, _ := reader.ReadString('\n')
+		response = strings.TrimSpace(strings.ToLower(response))
+		if response != "y" && response != "yes" {
+			log.Info("Prune cancelled.")
+			return
+		}
+	}
+
+	failed := false
+	for _, artifact := range prunable {
+		if err := os.RemoveAll(artifact.Path); err != nil {
+			log.Error(fmt.Sprintf("Failed to remove %s: %v", artifact.Path, err))
+			failed = true
+			continue
+		}
+		log.Info(fmt.Sprintf("Removed %s", artifact.Path))
+	}
+	if failed {
+		exit(1)
+	}
+}
+
+func runArtifactsMigrate(cmd *cobra.Command, args []string) {
+	log := logger.New(logger.Level(getLogLevel()), nil)
+
+	legacy, err := util.FindLegacyArtifacts()
+	if err != nil {
+		log.Error(fmt.Sprintf("Failed to find legacy artifacts: %v", err))
+		exit(1)
+	}
+	if len(legacy) == 0 {
+		log.Info("No legacy artifacts found.")
+		return
+	}
+
+	skipped := 0
+	for _, dir := range legacy {
+		cluster := "no cluster"
+		if dir.ClusterName != "" {
+			cluster = "cluster " + dir.ClusterName
+		}
+		log.Info(fmt.Sprintf("%s (%s):", dir.Path, cluster))
+
+		moves, err := util.MigrateLegacyArtifacts(dir, migrateDryRun)
+		for _, move := range moves {
+			if move.Skipped != "" {
+				log.Info(fmt.Sprintf("  ⚠  %s left in place: %s", move.From, move.Skipped))
+				skipped++
+				continue
+			}
+			log.Info(fmt.Sprintf("  %s -> %s", move.From, move.To))
+		}
+		if err != nil {
+			log.Error(fmt.Sprintf("Failed to migrate %s: %v", dir.Path, err))
+			exit(1)
+		}
+	}
+
+	if migrateDryRun {
+		log.Info("Dry run: nothing moved.")
+		return
+	}
+	if skipped > 0 {
+		log.Info(fmt.Sprintf("⚠  %d entries left in the legacy directories: move or delete them by hand", skipped))
+	}
+}
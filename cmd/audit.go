@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/clobrano/openshift-sts-wrapper/pkg/logger"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/steps"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/util"
+	"github.com/spf13/cobra"
+)
+
+var (
+	auditClusterName string
+	auditAwsRegion   string
+)
+
+var credentialsAuditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Compare the IAM roles of a cluster to the CredentialsRequests of its release",
+	Long: `Lists the IAM roles, their inline policies, the OIDC provider and the S3
+bucket created by ccoctl for a cluster (found by name and by the ownership tag
+of ccoctl), and compares the policies of the roles to the CredentialsRequests
+of the release the cluster was installed with.
+
+Roles whose permissions were edited by hand (e.g. in the AWS console), roles a
+CredentialsRequest has no role for, and roles matching no CredentialsRequest
+are reported. The exit status is 1 when any role is not in sync.`,
+	Run: runCredentialsAudit,
+}
+
+func init() {
+	credentialsCmd.AddCommand(credentialsAuditCmd)
+
+	credentialsAuditCmd.Flags().StringVar(&auditClusterName, "cluster-name", "", "Cluster name (required)")
+	credentialsAuditCmd.Flags().StringVar(&auditAwsRegion, "region", "", "AWS region (optional - will be read from metadata.json if not provided)")
+}
+
+func runCredentialsAudit(cmd *cobra.Command, args []string) {
+	out := redirectOutput()
+	log := logger.New(logger.Level(getLogLevel()), nil)
+
+	if auditClusterName == "" {
+		log.Error("Cluster name is required (use --cluster-name flag)")
+		exit(1)
+	}
+
+	cfg := loadClusterConfig(log, auditClusterName, auditAwsRegion)
+	useVaultAWSCredentials(log, cfg)
+	validateAWSCredentials(log, cfg.AwsProfile)
+	assumeRole(log, cfg)
+
+	executor := logCommands(log, &util.RealExecutor{})
+	if err := steps.EnsureCredentialsRequests(cfg, log, executor); err != nil {
+		log.Error(err.Error())
+		exit(1)
+	}
+	versionArch, err := util.ExtractVersionArch(cfg.ReleaseImage)
+	if err != nil {
+		log.Error(err.Error())
+		exit(1)
+	}
+	requests, err := util.ReadCredentialsRequests(util.GetSharedCredReqsPath(versionArch))
+	if err != nil {
+		log.Error(err.Error())
+		exit(1)
+	}
+
+	log.Info("Looking up the IAM roles, OIDC provider and S3 bucket of the cluster...")
+	resources, err := util.FindClusterResources(executor, util.ClusterResourceQuery{
+		Profile:        cfg.AwsProfile,
+		Region:         cfg.AwsRegion,
+		ClusterName:    cfg.ClusterName,
+		ResourcePrefix: cfg.ResourcePrefix,
+	})
+	if err != nil {
+		log.Error(fmt.Sprintf("Failed to look up the resources of the cluster: %v", err))
+		exit(1)
+	}
+	roles, err := util.AuditRoles(executor, cfg.AwsProfile, cfg.CcoctlName(), requests, resources.IAMRoles)
+	if err != nil {
+		log.Error(fmt.Sprintf("Failed to audit the IAM roles: %v", err))
+		exit(1)
+	}
+
+	audit := &util.CredentialsAudit{
+		ClusterName:   cfg.ClusterName,
+		ReleaseImage:  cfg.ReleaseImage,
+		OIDCProviders: resources.OIDCProviders,
+		S3Buckets:     resources.S3Buckets,
+		Roles:         roles,
+	}
+	printCredentialsAudit(out, audit)
+	if len(audit.Drifted()) > 0 {
+		exit(1)
+	}
+}
+
+// printCredentialsAudit prints the audit in the selected output format
+func printCredentialsAudit(out *os.File, audit *util.CredentialsAudit) {
+	if outputFormat == outputJSON {
+		data, err := json.MarshalIndent(audit, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to encode audit: %v\n", err)
+			return
+		}
+		fmt.Fprintln(out, string(data))
+		return
+	}
+
+	fmt.Fprintf(out, "\n=== Credentials of %s (%s) ===\n", audit.ClusterName, audit.ReleaseImage)
+	fmt.Fprintln(out)
+	fmt.Fprintf(out, "  OIDC provider: %s\n", listOrNone(audit.OIDCProviders))
+	fmt.Fprintf(out, "  S3 bucket:     %s\n", listOrNone(audit.S3Buckets))
+	fmt.Fprintln(out)
+	for _, role := range audit.Roles {
+		fmt.Fprintf(out, "  %-10s %s\n", role.Status, role.Role)
+		if role.CredentialsRequest != "" {
+			fmt.Fprintf(out, "             CredentialsRequest %s\n", role.CredentialsRequest)
+		}
+		if len(role.Policies) > 0 {
+			fmt.Fprintf(out, "             Inline policies: %s\n", strings.Join(role.Policies, ", "))
+		}
+		for _, permission := range role.MissingPermissions {
+			fmt.Fprintf(out, "             - %s\n", permission)
+		}
+		for _, permission := range role.ExtraPermissions {
+			fmt.Fprintf(out, "             + %s\n", permission)
+		}
+	}
+	fmt.Fprintln(out)
+	if drifted := audit.Drifted(); len(drifted) > 0 {
+		fmt.Fprintf(out, "  %d of %d IAM roles are not in sync with the release (- not granted, + not requested)\n", len(drifted), len(audit.Roles))
+	} else {
+		fmt.Fprintf(out, "  All %d IAM roles are in sync with the release\n", len(audit.Roles))
+	}
+}
+
+// listOrNone joins a list of resources, or reports there are none
+func listOrNone(items []string) string {
+	if len(items) == 0 {
+		return "(none found)"
+	}
+	return strings.Join(items, ", ")
+}
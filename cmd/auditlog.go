@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/clobrano/openshift-sts-wrapper/pkg/util"
+	"github.com/spf13/cobra"
+)
+
+var auditLogCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Inspect the audit logs of the external commands run by the wrapper",
+	Long: `Every run of the wrapper records the external commands it executes (binary,
+arguments, names of the environment variables set, working directory, exit
+code and duration) into its own append-only audit log in artifacts/audit/.`,
+}
+
+var auditShowCmd = &cobra.Command{
+	Use:   "show [run]",
+	Short: "List the audited runs, or show the commands of a run",
+	Long: `Without argument, lists the audited runs with the number of commands they ran
+and how many failed. With a run name (or a unique prefix of it, e.g. its date),
+shows the commands of that run in order.`,
+	Args: cobra.MaximumNArgs(1),
+	Run:  runAuditShow,
+}
+
+func init() {
+	rootCmd.AddCommand(auditLogCmd)
+	auditLogCmd.AddCommand(auditShowCmd)
+}
+
+func runAuditShow(cmd *cobra.Command, args []string) {
+	out := redirectOutput()
+
+	if len(args) == 0 {
+		runs, err := util.ListAuditRuns()
+		checkErr(err)
+		printAuditRuns(out, runs)
+		return
+	}
+
+	run, err := util.FindAuditRun(args[0])
+	checkErr(err)
+	entries, err := util.ReadAuditLog(run.Path)
+	checkErr(err)
+	printAuditEntries(out, entries)
+}
+
+// printAuditRuns prints the audited runs in the selected output format
+func printAuditRuns(out *os.File, runs []util.AuditRun) {
+	if outputFormat == outputJSON {
+		printAuditJSON(out, runs)
+		return
+	}
+	if len(runs) == 0 {
+		fmt.Fprintf(out, "No audit log in %s\n", util.GetAuditDir())
+		return
+	}
+	for _, run := range runs {
+		fmt.Fprintf(out, "%-48s %4d commands, %d failed\n", run.Name, run.Commands, run.Failed)
+	}
+}
+
+// printAuditEntries prints the commands of a run in the selected output format
+func printAuditEntries(out *os.File, entries []util.AuditEntry) {
+	if outputFormat == outputJSON {
+		printAuditJSON(out, entries)
+		return
+	}
+	for _, entry := range entries {
+		mark := "✓"
+		if entry.ExitCode != 0 {
+			mark = "✗"
+		}
+		duration := time.Duration(entry.Duration * float64(time.Second)).Round(time.Millisecond)
+		fmt.Fprintf(out, "%s %s %s (exit %d, %s)\n", mark, entry.Time.Local().Format("15:04:05"), util.CommandLine(entry.Command, entry.Args...), entry.ExitCode, duration)
+		fmt.Fprintf(out, "    dir: %s\n", entry.Dir)
+		if len(entry.Env) > 0 {
+			fmt.Fprintf(out, "    env: %s\n", strings.Join(entry.Env, ", "))
+		}
+		if entry.Error != "" {
+			fmt.Fprintf(out, "    error: %s\n", strings.TrimSpace(entry.Error))
+		}
+	}
+}
+
+func printAuditJSON(out *os.File, value interface{}) {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to encode audit log: %v\n", err)
+		return
+	}
+	fmt.Fprintln(out, string(data))
+}
@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/clobrano/openshift-sts-wrapper/pkg/batch"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/logger"
+	"github.com/spf13/cobra"
+)
+
+var (
+	batchCount      int
+	batchNamePrefix string
+	batchParallel   int
+)
+
+// batchOwnedFlags are the install flags set by install-batch for every install
+var batchOwnedFlags = []string{"--cluster-name", "--start-from-step", "--stop-after-step", "--only-step", "--output", "-o",
+	"--confirm-each-step", "--emit-script", "--record", "--replay", "--name-suffix"}
+
+var installBatchCmd = &cobra.Command{
+	Use:   "install-batch [-- install flags]",
+	Short: "Install several identical clusters concurrently",
+	Long: `Installs --count clusters named <name-prefix>1 to <name-prefix>N from the same
+configuration, concurrently. The flags after -- are passed to every install,
+which runs as 'install --non-interactive'.
+
+The shared artifacts of the release are extracted once, by the first cluster,
+before the other installs start. A progress table shows the running step of
+every install, and a summary of every cluster is printed at the end. The log
+of each install is written to artifacts/batch/<cluster>.log. The exit status
+is 1 when any install failed.`,
+	Example: `  openshift-sts-wrapper install-batch --count 3 --name-prefix qe- --config fleet.yaml
+  openshift-sts-wrapper install-batch --count 4 --name-prefix qe- --parallel 2 -- --version 4.15.12 --worker-replicas 2`,
+	Run: runInstallBatch,
+}
+
+func init() {
+	rootCmd.AddCommand(installBatchCmd)
+
+	installBatchCmd.Flags().IntVar(&batchCount, "count", 0, "Number of clusters to install (required)")
+	installBatchCmd.Flags().StringVar(&batchNamePrefix, "name-prefix", "", "Prefix of the cluster names, followed by their number (required)")
+	installBatchCmd.Flags().IntVar(&batchParallel, "parallel", 0, "Maximum number of installs running at a time (default: all)")
+}
+
+func runInstallBatch(cmd *cobra.Command, args []string) {
+	out := redirectOutput()
+	log := logger.New(logger.Level(getLogLevel()), nil)
+
+	if batchCount < 1 || batchNamePrefix == "" {
+		log.Error("--count and --name-prefix are required")
+		exit(exitConfigError)
+	}
+	for _, arg := range args {
+		for _, flag := range batchOwnedFlags {
+			if arg == flag || strings.HasPrefix(arg, flag+"=") {
+				log.Error(fmt.Sprintf("%s is set by install-batch for every install, it can't be passed to install", flag))
+				exit(exitConfigError)
+			}
+		}
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		log.Error(fmt.Sprintf("Failed to find the executable: %v", err))
+		exit(1)
+	}
+
+	// The global flags of the batch apply to every install
+	var installArgs []string
+	if cfgFile != "" {
+		installArgs = append(installArgs, "--config", cfgFile)
+	}
+	if configProfile != "" {
+		installArgs = append(installArgs, "--profile", configProfile)
+	}
+	if verbose {
+		installArgs = append(installArgs, "--verbose")
+	}
+	installArgs = append(installArgs, args...)
+
+	names := batch.ClusterNames(batchNamePrefix, batchCount)
+	log.Info(fmt.Sprintf("Installing %d clusters: %s", len(names), strings.Join(names, ", ")))
+	log.Info(fmt.Sprintf("Logs: %s/<cluster>.log", batch.GetBatchDir()))
+	b := batch.New(executable, names, installArgs, batchParallel)
+	b.Run(func(clusters []batch.Cluster) {
+		printBatchProgress(log, clusters)
+	})
+
+	printBatchResults(out, b.Clusters())
+	if b.Failed() > 0 {
+		exit(1)
+	}
+}
+
+// printBatchProgress logs the state of every install of the batch
+func printBatchProgress(log *logger.Logger, clusters []batch.Cluster) {
+	now := time.Now()
+	log.Info("")
+	log.Info(fmt.Sprintf("  %-24s %-10s %8s  %s", "CLUSTER", "STATUS", "ELAPSED", "STEP"))
+	for _, cluster := range clusters {
+		log.Info(fmt.Sprintf("  %-24s %-10s %8s  %s", cluster.Name, cluster.Status, cluster.Elapsed(now).Round(time.Second), cluster.Step))
+	}
+}
+
+// printBatchResults prints the outcome of every install in the selected output format
+func printBatchResults(out *os.File, clusters []batch.Cluster) {
+	if outputFormat == outputJSON {
+		data, err := json.MarshalIndent(clusters, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to encode summary: %v\n", err)
+			return
+		}
+		fmt.Fprintln(out, logger.Redact(string(data)))
+		return
+	}
+
+	now := time.Now()
+	failed := 0
+	fmt.Fprintln(out, "\n=== Batch Installation Summary ===")
+	for _, cluster := range clusters {
+		fmt.Fprintln(out)
+		fmt.Fprintf(out, "%s: %s in %s\n", cluster.Name, strings.ToUpper(cluster.Status), cluster.Elapsed(now).Round(time.Second))
+		if url := cluster.ConsoleURL(); url != "" {
+			fmt.Fprintf(out, "  Console: %s\n", url)
+		}
+		if cluster.Status != batch.ClusterFailed {
+			continue
+		}
+		failed++
+		if step, ok := cluster.FailedStep(); ok {
+			fmt.Fprintf(out, "  Failed step: %s: %s\n", step.Name, logger.Redact(step.Error))
+			if step.Hint != "" {
+				fmt.Fprintf(out, "  Hint: %s\n", step.Hint)
+			}
+		} else {
+			fmt.Fprintf(out, "  Error: %s\n", logger.Redact(cluster.Error))
+		}
+		if cluster.StartedAt != nil {
+			fmt.Fprintf(out, "  Log: %s\n", cluster.LogPath)
+		}
+	}
+	fmt.Fprintf(out, "\n%d/%d clusters installed\n", len(clusters)-failed, len(clusters))
+}
@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	stderrors "errors"
+	"os"
+
+	"github.com/clobrano/openshift-sts-wrapper/pkg/config"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/errors"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/preflight"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/util"
+	"github.com/spf13/cobra"
+)
+
+// Exit codes of install, one per failure class, so that pipelines can tell why
+// an installation failed. Other failures exit with 1.
+const (
+	exitConfigError     = 10 // Invalid or incomplete configuration, missing prerequisites
+	exitAWSAuth         = 11 // AWS credentials or role assumption failed
+	exitQuota           = 12 // Service quotas too low for the cluster
+	exitDNSConflict     = 13 // Base domain missing, or records of the cluster already exist
+	exitBootstrapFailed = 14 // The cluster did not bootstrap
+	exitVerifyFailed    = 15 // The post-install verification failed
+)
+
+// Names of the preflight checks with their own exit code
+const (
+	checkQuotas     = "Service quotas"
+	checkBaseDomain = "Base domain"
+)
+
+// applyCIMode sets the behavior of --ci: never prompt, print the summary as JSON
+// unless --output is set, and keep escape sequences out of the output of the
+// wrapper and of the tools it runs
+func applyCIMode(cmd *cobra.Command) {
+	if !ciMode {
+		return
+	}
+	nonInteractive = true
+	if !cmd.Flags().Changed("output") {
+		outputFormat = outputJSON
+	}
+	os.Setenv("NO_COLOR", "1")
+}
+
+// preflightExitCode returns the exit code of failed preflight checks
+func preflightExitCode(err error) int {
+	var failed *preflight.ChecksFailedError
+	if stderrors.As(err, &failed) {
+		switch {
+		case failed.Failed(checkQuotas):
+			return exitQuota
+		case failed.Failed(checkBaseDomain):
+			return exitDNSConflict
+		}
+	}
+	return exitConfigError
+}
+
+// installExitCode returns the exit code of an installation with a failed step,
+// classified by the first step that failed
+func installExitCode(cfg *config.Config, summary *errors.Summary) int {
+	for _, step := range summary.Steps {
+		if step.Status != errors.StatusFailed {
+			continue
+		}
+		switch step.ID {
+		case config.StepName(10):
+			if util.InstallFailedAtBootstrap(util.GetInstallLogPath(cfg.ClusterName)) {
+				return exitBootstrapFailed
+			}
+		case config.StepName(11):
+			return exitVerifyFailed
+		}
+		return 1
+	}
+	return 1
+}
@@ -2,20 +2,43 @@ package cmd
 
 import (
 	"bufio"
+	"encoding/json"
+	stderrors "errors"
 	"fmt"
 	"os"
+	"os/exec"
 	"strings"
+	"time"
 
-	"github.com/spf13/cobra"
 	"github.com/clobrano/openshift-sts-wrapper/pkg/config"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/errors"
 	"github.com/clobrano/openshift-sts-wrapper/pkg/logger"
 	"github.com/clobrano/openshift-sts-wrapper/pkg/util"
+	"github.com/spf13/cobra"
 )
 
 var (
 	cleanupClusterName  string
 	cleanupAwsRegion    string
 	cleanupReleaseImage string
+	cleanupDryRun       bool
+	cleanupYes          bool
+	cleanupRemoveDir    bool
+	cleanupKeepDir      bool
+	cleanupForcePurge   bool
+	cleanupDiscover     bool
+	cleanupInfraID      string // Discovered from the AWS tags with --discover
+	cleanupPrefix       string // Name of the ccoctl resources
+	cleanupAll          bool
+	cleanupOlderThan    string
+)
+
+// Exit codes of a cleanup that failed to delete some resources
+const (
+	exitDestroyFailed     = 3 // openshift-install destroy cluster failed
+	exitIAMDeleteFailed   = 4 // ccoctl aws delete failed
+	exitAllCleanupsFailed = 5 // Both failed
+	exitLeftoverResources = 6 // Both succeeded, but resources of the cluster still exist
 )
 
 var cleanupCmd = &cobra.Command{
@@ -30,11 +53,39 @@ func init() {
 
 	cleanupCmd.Flags().StringVar(&cleanupClusterName, "cluster-name", "", "Cluster/infrastructure name (required)")
 	cleanupCmd.Flags().StringVar(&cleanupAwsRegion, "region", "", "AWS region (optional - will be read from metadata.json if not provided)")
+	cleanupCmd.Flags().BoolVar(&forceUnlock, "force-unlock", false, "Remove the lock of a run against the cluster that is hung or stale")
+	cleanupCmd.Flags().StringVar(&cleanupPrefix, "resource-prefix", "", "Name of the IAM roles, OIDC provider and S3 bucket created by ccoctl (optional - will be read from install-metadata.json, defaults to the cluster name)")
 	cleanupCmd.Flags().StringVar(&cleanupReleaseImage, "release-image", "", "OpenShift release image (optional - will be read from install-metadata.json if not provided)")
+	cleanupCmd.Flags().BoolVar(&cleanupDryRun, "dry-run", false, "List the AWS resources that would be deleted without deleting anything")
+	cleanupCmd.Flags().BoolVar(&cleanupYes, "yes", false, "Do not ask for confirmation (the artifacts directory is kept unless --remove-artifacts is set)")
+	cleanupCmd.Flags().BoolVar(&cleanupRemoveDir, "remove-artifacts", false, "Remove the cluster artifacts directory once the AWS resources are deleted, without asking")
+	cleanupCmd.Flags().BoolVar(&cleanupKeepDir, "keep-artifacts", false, "Keep the cluster artifacts directory, without asking")
+	cleanupCmd.Flags().BoolVar(&cleanupForcePurge, "force-purge", false, "Delete the resources of the cluster still found in AWS after the cleanup")
+	cleanupCmd.Flags().BoolVar(&cleanupDiscover, "discover", false, "Find the infrastructure from its AWS tags when metadata.json and the state file are gone (requires --region)")
+	cleanupCmd.Flags().BoolVar(&cleanupAll, "all", false, "Clean up every cluster with an artifacts directory created longer ago than --older-than (required)")
+	cleanupCmd.Flags().StringVar(&cleanupOlderThan, "older-than", "", "With --all, only clean up clusters created longer ago than this (e.g. 72h, 7d, or 0 for every cluster)")
+	cleanupCmd.MarkFlagsMutuallyExclusive("remove-artifacts", "keep-artifacts")
+	cleanupCmd.MarkFlagsMutuallyExclusive("all", "cluster-name")
+	cleanupCmd.MarkFlagsMutuallyExclusive("all", "region")
+	cleanupCmd.MarkFlagsMutuallyExclusive("all", "release-image")
+	cleanupCmd.MarkFlagsMutuallyExclusive("all", "discover")
+	cleanupCmd.MarkFlagsMutuallyExclusive("all", "resource-prefix")
 }
 
 func runCleanup(cmd *cobra.Command, args []string) {
+	started := time.Now()
+	out := redirectOutput()
 	log := logger.New(logger.Level(getLogLevel()), nil)
+	summary := errors.NewSummary()
+
+	if cleanupAll {
+		cleanupAllClusters(out, log)
+		return
+	}
+	if cleanupOlderThan != "" {
+		log.Error("--older-than requires --all")
+		exit(1)
+	}
 
 	// Validate that cluster name is provided
 	if cleanupClusterName == "" {
@@ -42,9 +93,13 @@ func runCleanup(cmd *cobra.Command, args []string) {
 		log.Info("")
 		log.Info("Example:")
 		log.Info("  openshift-sts-wrapper cleanup --cluster-name=my-cluster")
-		os.Exit(1)
+		exit(1)
 	}
 
+	// Prevent concurrent runs against the same cluster
+	lock := lockCluster(log, cleanupClusterName)
+	defer lock.Unlock()
+
 	// Construct cluster directory path from cluster name
 	clusterDir := util.GetClusterPath(cleanupClusterName, "")
 
@@ -68,14 +123,15 @@ func runCleanup(cmd *cobra.Command, args []string) {
 		log.Info("Either provide --region flag or ensure metadata.json exists in cluster artifacts")
 		log.Info("Example:")
 		log.Info("  openshift-sts-wrapper cleanup --cluster-name=my-cluster --region=us-east-2")
-		os.Exit(1)
+		exit(1)
 	}
 
 	log.Info(fmt.Sprintf("AWS Region: %s", cleanupAwsRegion))
 
 	// Try to load release image from install-metadata.json if not provided via flag
+	releaseDigest := ""
+	installMetadata, err := util.ReadInstallMetadata(clusterDir)
 	if cleanupReleaseImage == "" {
-		installMetadata, err := util.ReadInstallMetadata(clusterDir)
 		if err == nil && installMetadata.ReleaseImage != "" {
 			cleanupReleaseImage = installMetadata.ReleaseImage
 			log.Info(fmt.Sprintf("Detected Release Image: %s", cleanupReleaseImage))
@@ -83,102 +139,161 @@ func runCleanup(cmd *cobra.Command, args []string) {
 			log.Debug(fmt.Sprintf("Could not read install metadata: %v", err))
 		}
 	}
-
-	// Load config to get AWS profile
-	cfg := &config.Config{}
-	configFile := cfgFile
-	if configFile == "" {
-		configFile = "openshift-sts-wrapper.yaml"
+	if err == nil && installMetadata.ReleaseImage == cleanupReleaseImage && installMetadata.ReleaseDigest != "" {
+		releaseDigest = installMetadata.ReleaseDigest
+		log.Info(fmt.Sprintf("Detected Release Digest: %s", releaseDigest))
 	}
-	if util.FileExists(configFile) {
-		fileCfg, err := config.LoadFromFile(configFile)
-		if err != nil {
-			log.Debug(fmt.Sprintf("Could not load config file: %v", err))
-		} else {
-			cfg = fileCfg
-		}
+	if cleanupPrefix == "" && err == nil && installMetadata.ResourcePrefix != "" {
+		cleanupPrefix = installMetadata.ResourcePrefix
+		log.Info(fmt.Sprintf("Detected Resource Prefix: %s", cleanupPrefix))
+	}
+	if cleanupPrefix == "" {
+		cleanupPrefix = cleanupClusterName
 	}
+
+	// Load config to get the AWS profile and the notifications, environment
+	// variables overridden by the config file
+	cfg := &config.Config{}
+	cfg.MergeFrom(config.LoadFromEnv(), config.SourceEnv)
+	cfg.MergeFrom(loadConfigFile(log), "file "+configFilePath())
 	cfg.SetDefaults()
+	useServiceEndpoints(cfg)
 
 	// Validate AWS credentials before proceeding
-	log.Info(fmt.Sprintf("Validating AWS credentials for profile '%s'...", cfg.AwsProfile))
-	if err := util.ValidateAWSCredentials(cfg.AwsProfile); err != nil {
-		log.Error(fmt.Sprintf("AWS credential validation failed: %v", err))
-		os.Exit(1)
+	useVaultAWSCredentials(log, cfg)
+	validateAWSCredentials(log, cfg.AwsProfile)
+	assumeRole(log, cfg)
+
+	if cleanupDiscover {
+		discoverInfraID(log, cfg, clusterDir)
+	}
+
+	if cleanupDryRun {
+		dryRunCleanup(out, log, cfg, clusterDir)
+		return
 	}
-	log.Info("✓ AWS credentials are valid")
 
 	// Confirm with user
 	reader := bufio.NewReader(os.Stdin)
 	fmt.Printf("This will delete AWS resources for cluster '%s' in region '%s'.\n", cleanupClusterName, cleanupAwsRegion)
-	fmt.Print("Continue? (y/n): ")
-	response, _ := reader.ReadString('\n')
-	response = strings.TrimSpace(strings.ToLower(response))
-
-	if response != "y" && response != "yes" {
+	if !cleanupYes && !promptYes(reader, "Continue? (y/n): ") {
 		log.Info("Cleanup cancelled.")
 		return
 	}
 
-	executor := &util.RealExecutor{}
+	executor := logCommands(log, &util.RealExecutor{})
+	deleteClusterResources(log, executor, cfg, summary, clusterDir, releaseDigest, installMetadata != nil && installMetadata.ExternalIAM)
+	if summary.HasErrors() {
+		log.Info("You may need to manually delete AWS resources.")
+		finishCleanup(out, log, cfg, summary, clusterDir, started)
+		exit(cleanupExitCode(summary))
+	}
+	log.Info("All AWS resources have been deleted.")
+	cancelExpiry(log, clusterDir, installMetadata)
 
-	// Step 1: Run openshift-install destroy if we have the release image
-	if cleanupReleaseImage != "" {
-		versionArch, err := util.ExtractVersionArch(cleanupReleaseImage)
-		if err != nil {
-			log.Error(fmt.Sprintf("Failed to extract version from release image: %v", err))
+	// Prompt user to remove cluster artifacts directory
+	if util.DirExists(clusterDir) {
+		remove := cleanupRemoveDir
+		if !cleanupRemoveDir && !cleanupKeepDir && !cleanupYes {
+			remove = promptYes(reader, fmt.Sprintf("\nDo you want to remove the cluster artifacts directory at %s? (y/n): ", clusterDir))
+		}
+
+		if remove {
+			if err := os.RemoveAll(clusterDir); err != nil {
+				log.Error(fmt.Sprintf("Failed to remove cluster directory: %v", err))
+			} else {
+				log.Info(fmt.Sprintf("Removed cluster directory: %s", clusterDir))
+			}
 		} else {
-			stateFile := util.GetClusterPath(cleanupClusterName, ".openshift_install_state.json")
-			installBin := util.GetSharedBinaryPath(versionArch, "openshift-install")
-
-			// Check if state file exists
-			if util.FileExists(stateFile) {
-				log.StartStep("Destroying OpenShift infrastructure")
-
-				destroyArgs := []string{"destroy", "cluster", "--dir", clusterDir, "--log-level=debug"}
-
-				// Get AWS credentials from profile and pass them as environment variables
-				awsEnv, err := util.GetAWSEnvVars(cfg.AwsProfile)
-				if err != nil {
-					log.Debug(fmt.Sprintf("Could not read AWS credentials: %v", err))
-					log.Debug("Proceeding without explicit AWS credential injection")
-					if err := executor.ExecuteInteractive(installBin, destroyArgs...); err != nil {
-						log.FailStep("Destroy infrastructure")
-						log.Error(fmt.Sprintf("Failed to destroy infrastructure: %v", err))
-						log.Info("Continuing with ccoctl cleanup...")
-					} else {
-						log.CompleteStep("Destroy infrastructure")
-					}
-				} else {
-					if err := executor.ExecuteInteractiveWithEnv(installBin, awsEnv, destroyArgs...); err != nil {
-						log.FailStep("Destroy infrastructure")
-						log.Error(fmt.Sprintf("Failed to destroy infrastructure: %v", err))
-						log.Info("Continuing with ccoctl cleanup...")
-					} else {
-						log.CompleteStep("Destroy infrastructure")
-					}
-				}
+			log.Info(fmt.Sprintf("Cluster artifacts preserved at: %s", clusterDir))
+		}
+	}
+
+	finishCleanup(out, log, cfg, summary, clusterDir, started)
+}
+
+// deleteClusterResources destroys the infrastructure of the cluster, deletes
+// the ccoctl resources unless they were provided to the installation
+// (externalIAM), and looks for resources left over
+func deleteClusterResources(log *logger.Logger, executor util.CommandExecutor, cfg *config.Config, summary *errors.Summary, clusterDir, releaseDigest string, externalIAM bool) {
+	// Step 1: Run openshift-install destroy if we have the release image
+	destroyBin := "" // openshift-install binary the infrastructure can be destroyed with
+	installBin, err := cleanupInstallBinary(log, cfg, releaseDigest)
+	if err != nil {
+		log.Error(fmt.Sprintf("Failed to extract version from release image: %v", err))
+		summary.AddStep("destroy-infrastructure", "Destroy infrastructure", 0, err)
+	} else if installBin != "" {
+		stateFile := util.GetClusterPath(cleanupClusterName, ".openshift_install_state.json")
+
+		// metadata.json is all that destroy needs, rebuild it from the discovered infra ID
+		if cleanupDiscover && cleanupInfraID != "" && !util.FileExists(util.GetClusterPath(cleanupClusterName, "metadata.json")) {
+			metadata := &util.ClusterMetadata{ClusterName: cleanupClusterName, InfraID: cleanupInfraID}
+			metadata.AWS.Region = cleanupAwsRegion
+			metadata.AWS.Identifier = []map[string]string{{util.InfraTagKeyPrefix + cleanupInfraID: "owned"}}
+			if err := util.WriteClusterMetadata(clusterDir, metadata); err != nil {
+				log.Error(err.Error())
+			} else {
+				log.Info(fmt.Sprintf("Wrote %s for infra ID %s", util.GetClusterPath(cleanupClusterName, "metadata.json"), cleanupInfraID))
+			}
+		}
+
+		// Check if state file exists
+		if util.FileExists(stateFile) || (cleanupDiscover && util.FileExists(util.GetClusterPath(cleanupClusterName, "metadata.json"))) {
+			destroyBin = installBin
+			log.StartStep("Destroying OpenShift infrastructure")
+			destroyStarted := time.Now()
+			err := destroyInfrastructure(log, executor, cfg, installBin, clusterDir)
+			summary.AddStep("destroy-infrastructure", "Destroy infrastructure", time.Since(destroyStarted), err)
+			if err != nil {
+				log.FailStep("Destroy infrastructure")
+				log.Error(fmt.Sprintf("Failed to destroy infrastructure: %v", err))
+				log.Info("Continuing with ccoctl cleanup...")
 			} else {
-				log.Info(fmt.Sprintf("No state file found at %s", stateFile))
-				log.Info("⚠ Cannot destroy infrastructure without state file")
-				log.Info("If infrastructure still exists, you must manually delete it via AWS Console")
-				log.Info("Continuing with IAM roles and S3 bucket cleanup...")
+				log.CompleteStep("Destroy infrastructure")
 			}
+		} else {
+			log.Info(fmt.Sprintf("No state file found at %s", stateFile))
+			summary.AddSkipped("destroy-infrastructure", "Destroy infrastructure", "no state file")
+			log.Info("⚠ Cannot destroy infrastructure without state file")
+			log.Info("If infrastructure still exists, use --discover or manually delete it via AWS Console")
+			log.Info("Continuing with IAM roles and S3 bucket cleanup...")
 		}
 	} else {
 		log.Info("No release image available - cannot destroy infrastructure")
+		summary.AddSkipped("destroy-infrastructure", "Destroy infrastructure", "no release image")
 		log.Info("(Infrastructure must be manually destroyed if still present)")
 		log.Info("Continuing with IAM roles and S3 bucket cleanup...")
 	}
 
-	// Step 2: Run ccoctl aws delete to clean up IAM roles and S3 bucket
+	// Step 2: Run ccoctl aws delete to clean up IAM roles and S3 bucket, unless
+	// they were provided to the installation
+	if externalIAM {
+		log.Info("IAM roles and OIDC provider were not created by the installation: keeping them")
+		summary.AddSkipped("delete-iam-s3", "Cleanup IAM/S3", "existing IAM roles")
+	} else {
+		deleteCcoctlResources(log, executor, cfg, summary)
+	}
+
+	// Step 3: Look for resources left over by the above
+	verifyCleanup(log, executor, cfg, summary, clusterDir, destroyBin)
+}
+
+// deleteCcoctlResources runs ccoctl aws delete to delete the IAM roles, OIDC
+// provider and S3 bucket of the cluster
+func deleteCcoctlResources(log *logger.Logger, executor util.CommandExecutor, cfg *config.Config, summary *errors.Summary) {
 	log.StartStep("Cleaning up IAM roles and S3 bucket")
 
 	// Find ccoctl binary
 	ccoctlPath := "ccoctl"
 
-	// First, try to find it based on release image if provided
-	if cleanupReleaseImage != "" {
+	// A configured ccoctl comes first: deleting does not depend on the release
+	if cfg.Binaries.Ccoctl != "" {
+		ccoctlPath = cfg.Binaries.Ccoctl
+		log.Debug(fmt.Sprintf("Using configured ccoctl: %s", ccoctlPath))
+	}
+
+	// Then, try to find it based on release image if provided
+	if ccoctlPath == "ccoctl" && cleanupReleaseImage != "" {
 		versionArch, err := util.ExtractVersionArch(cleanupReleaseImage)
 		if err == nil {
 			sharedCcoctl := util.GetSharedBinaryPath(versionArch, "ccoctl")
@@ -191,68 +306,456 @@ func runCleanup(cmd *cobra.Command, args []string) {
 
 	// If not found and we don't have release image, try to find any ccoctl in shared artifacts
 	if ccoctlPath == "ccoctl" {
-		// Look for ccoctl in artifacts/shared/*/bin/ccoctl
-		sharedDir := "artifacts/shared"
-		if util.DirExists(sharedDir) {
-			entries, err := os.ReadDir(sharedDir)
-			if err == nil {
-				for _, entry := range entries {
-					if entry.IsDir() {
-						candidatePath := util.GetSharedBinaryPath(entry.Name(), "ccoctl")
-						if util.FileExists(candidatePath) {
-							ccoctlPath = candidatePath
-							log.Debug(fmt.Sprintf("Found ccoctl in shared artifacts: %s", ccoctlPath))
-							break
-						}
-					}
-				}
-			}
+		if sharedCcoctl := findSharedBinary(log, "ccoctl"); sharedCcoctl != "" {
+			ccoctlPath = sharedCcoctl
 		}
 	}
 
 	args_cleanup := []string{
 		"aws", "delete",
-		"--name", cleanupClusterName,
+		"--name", cleanupPrefix,
 		"--region", cleanupAwsRegion,
 	}
 
 	// Get AWS credentials from profile and pass them as environment variables
+	deleteStarted := time.Now()
 	awsEnv, err := util.GetAWSEnvVars(cfg.AwsProfile)
 	if err != nil {
 		log.Debug(fmt.Sprintf("Could not read AWS credentials: %v", err))
 		log.Debug("Proceeding without explicit AWS credential injection")
-		if err := util.RunCommand(executor, ccoctlPath, args_cleanup...); err != nil {
-			log.FailStep("Cleanup IAM/S3")
-			log.Error(fmt.Sprintf("Failed to clean up IAM/S3: %v", err))
-			log.Info("You may need to manually delete AWS resources.")
-			os.Exit(1)
-		}
+		err = util.RunCommand(executor, ccoctlPath, args_cleanup...)
+	} else {
+		err = util.RunCommandWithEnv(executor, awsEnv, ccoctlPath, args_cleanup...)
+	}
+	summary.AddStep("delete-iam-s3", "Cleanup IAM/S3", time.Since(deleteStarted), err)
+	if err != nil {
+		log.FailStep("Cleanup IAM/S3")
+		log.Error(fmt.Sprintf("Failed to clean up IAM/S3: %v", err))
 	} else {
-		if err := util.RunCommandWithEnv(executor, awsEnv, ccoctlPath, args_cleanup...); err != nil {
-			log.FailStep("Cleanup IAM/S3")
-			log.Error(fmt.Sprintf("Failed to clean up IAM/S3: %v", err))
-			log.Info("You may need to manually delete AWS resources.")
-			os.Exit(1)
+		log.CompleteStep("Cleanup IAM/S3")
+	}
+}
+
+// cleanupInstallBinary returns the openshift-install binary of the release the
+// cluster was installed with, or the configured one. With --discover and no
+// known release, any extracted openshift-install is used: destroying does not
+// depend on the release.
+func cleanupInstallBinary(log *logger.Logger, cfg *config.Config, releaseDigest string) (string, error) {
+	if cfg.Binaries.OpenshiftInstall != "" {
+		log.Debug(fmt.Sprintf("Using configured openshift-install: %s", cfg.Binaries.OpenshiftInstall))
+		return cfg.Binaries.OpenshiftInstall, nil
+	}
+	if cleanupReleaseImage == "" {
+		if cleanupDiscover {
+			return findSharedBinary(log, "openshift-install"), nil
 		}
+		return "", nil
 	}
 
-	log.CompleteStep("Cleanup IAM/S3")
-	log.Info("All AWS resources have been deleted.")
+	versionArch, err := util.ExtractVersionArch(cleanupReleaseImage)
+	if err != nil {
+		return "", err
+	}
+	installBin := util.GetSharedBinaryPath(versionArch, "openshift-install")
 
-	// Prompt user to remove cluster artifacts directory
-	if util.DirExists(clusterDir) {
-		fmt.Printf("\nDo you want to remove the cluster artifacts directory at %s? (y/n): ", clusterDir)
-		response, _ := reader.ReadString('\n')
-		response = strings.TrimSpace(strings.ToLower(response))
+	// The shared binaries must come from the release the cluster was installed with
+	if releaseDigest != "" && !util.VerifyArtifact(versionArch, releaseDigest, installBin) {
+		log.Info(fmt.Sprintf("⚠  %s was not extracted from release %s (or was modified)", installBin, releaseDigest))
+	}
+	return installBin, nil
+}
 
-		if response == "y" || response == "yes" {
-			if err := os.RemoveAll(clusterDir); err != nil {
-				log.Error(fmt.Sprintf("Failed to remove cluster directory: %v", err))
-			} else {
-				log.Info(fmt.Sprintf("Removed cluster directory: %s", clusterDir))
+// findSharedBinary returns the first binary with the given name found in the
+// shared artifacts of any release (artifacts/shared/*/bin), or "" if there is none
+func findSharedBinary(log *logger.Logger, name string) string {
+	entries, err := os.ReadDir("artifacts/shared")
+	if err != nil {
+		return ""
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			candidatePath := util.GetSharedBinaryPath(entry.Name(), name)
+			if util.FileExists(candidatePath) {
+				log.Debug(fmt.Sprintf("Found %s in shared artifacts: %s", name, candidatePath))
+				return candidatePath
 			}
-		} else {
-			log.Info(fmt.Sprintf("Cluster artifacts preserved at: %s", clusterDir))
 		}
 	}
+	return ""
+}
+
+// discoverInfraID finds the infra ID of the cluster from the tags of its
+// resources, for clusters whose metadata.json is gone
+func discoverInfraID(log *logger.Logger, cfg *config.Config, clusterDir string) {
+	if metadata, err := util.ReadClusterMetadata(clusterDir); err == nil && metadata.InfraID != "" {
+		log.Info(fmt.Sprintf("Infra ID: %s (from metadata.json)", metadata.InfraID))
+		cleanupInfraID = metadata.InfraID
+		return
+	}
+
+	infraIDs, err := util.DiscoverInfraIDs(logCommands(log, &util.RealExecutor{}), cfg.AwsProfile, cleanupAwsRegion, cleanupClusterName)
+	if err != nil {
+		log.Error(fmt.Sprintf("Failed to discover the infra ID: %v", err))
+		exit(1)
+	}
+	switch len(infraIDs) {
+	case 0:
+		log.Info(fmt.Sprintf("No infrastructure tagged for cluster '%s' found in region '%s'", cleanupClusterName, cleanupAwsRegion))
+	case 1:
+		log.Info(fmt.Sprintf("Discovered Infra ID: %s", infraIDs[0]))
+		cleanupInfraID = infraIDs[0]
+	default:
+		log.Error(fmt.Sprintf("Several clusters named '%s' found in region '%s': %s", cleanupClusterName, cleanupAwsRegion, strings.Join(infraIDs, ", ")))
+		log.Info("Destroy them one at a time with openshift-install destroy cluster, or via AWS Console")
+		exit(1)
+	}
+}
+
+// clusterCleanupResult is the outcome of the cleanup of one cluster with --all
+type clusterCleanupResult struct {
+	ClusterName string    `json:"clusterName"`
+	CreatedAt   time.Time `json:"createdAt"`
+	Status      string    `json:"status"`
+	ExitCode    int       `json:"exitCode,omitempty"`
+}
+
+// cleanupAllClusters cleans up the clusters with an artifacts directory created
+// longer ago than --older-than. Each cluster is cleaned up by its own cleanup
+// run, so that its lock, credentials and failures do not affect the others.
+func cleanupAllClusters(out *os.File, log *logger.Logger) {
+	// Destroying every cluster takes an explicit --older-than=0
+	if cleanupOlderThan == "" {
+		log.Error("--all requires --older-than (e.g. --older-than=7d, or --older-than=0 for every cluster)")
+		exit(1)
+	}
+	olderThan, err := util.ParseAge(cleanupOlderThan)
+	if err != nil {
+		log.Error(fmt.Sprintf("Invalid --older-than value: %v", err))
+		exit(1)
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		log.Error(fmt.Sprintf("Failed to find the executable: %v", err))
+		exit(1)
+	}
+
+	clusters, err := util.ListClusters()
+	if err != nil {
+		log.Error(err.Error())
+		exit(1)
+	}
+	now := time.Now()
+	var selected []clusterCleanupResult
+	log.Info("Clusters:")
+	for _, name := range clusters {
+		created, err := util.ClusterCreationTime(name)
+		if err != nil {
+			log.Info(fmt.Sprintf("  %-24s %8s  (%v, skipped)", name, "-", err))
+			continue
+		}
+		mark := ""
+		if now.Sub(created) >= olderThan {
+			mark = "  ← cleanup"
+			selected = append(selected, clusterCleanupResult{ClusterName: name, CreatedAt: created})
+		}
+		log.Info(fmt.Sprintf("  %-24s %8s  created %s%s", name, formatAge(now.Sub(created)), created.Local().Format("2006-01-02 15:04"), mark))
+	}
+	log.Info("")
+	if len(selected) == 0 {
+		log.Info("No clusters to clean up.")
+		return
+	}
+
+	reader := bufio.NewReader(os.Stdin)
+	failed := false
+	for i := range selected {
+		result := &selected[i]
+		if !cleanupYes && !cleanupDryRun && !promptYes(reader, fmt.Sprintf("Clean up cluster '%s' (created %s ago)? (y/n): ", result.ClusterName, formatAge(now.Sub(result.CreatedAt)))) {
+			result.Status = errors.StatusSkipped
+			continue
+		}
+
+		log.Info(fmt.Sprintf("=== Cleaning up cluster %s ===", result.ClusterName))
+		err := (&util.RealExecutor{}).ExecuteInteractive(executable, cleanupArgs(result.ClusterName)...)
+		result.Status = errors.StatusSucceeded
+		if err != nil {
+			result.Status, result.ExitCode = errors.StatusFailed, 1
+			var exitErr *exec.ExitError
+			if stderrors.As(err, &exitErr) {
+				result.ExitCode = exitErr.ExitCode()
+			}
+			failed = true
+		}
+	}
+
+	printCleanupResults(out, now, selected)
+	if failed {
+		exit(1)
+	}
+}
+
+// cleanupArgs returns the arguments of the cleanup run of one cluster of --all
+func cleanupArgs(clusterName string) []string {
+	args := []string{"cleanup", "--cluster-name", clusterName, "--yes"}
+	if cfgFile != "" {
+		args = append(args, "--config", cfgFile)
+	}
+	if configProfile != "" {
+		args = append(args, "--profile", configProfile)
+	}
+	for _, flag := range []struct {
+		name string
+		set  bool
+	}{
+		{"--verbose", verbose},
+		{"-q", quiet},
+		{"--dry-run", cleanupDryRun},
+		{"--force-purge", cleanupForcePurge},
+		{"--force-unlock", forceUnlock},
+		{"--remove-artifacts", cleanupRemoveDir},
+		{"--keep-artifacts", cleanupKeepDir},
+	} {
+		if flag.set {
+			args = append(args, flag.name)
+		}
+	}
+	return args
+}
+
+// printCleanupResults prints the outcome of the cleanup of each cluster of --all
+func printCleanupResults(out *os.File, now time.Time, results []clusterCleanupResult) {
+	if outputFormat == outputJSON {
+		data, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to encode summary: %v\n", err)
+			return
+		}
+		fmt.Fprintln(out, string(data))
+		return
+	}
+
+	fmt.Fprintln(out, "\n=== Cleanup Summary ===")
+	fmt.Fprintln(out)
+	fmt.Fprintf(out, "  %-24s %8s  %s\n", "CLUSTER", "AGE", "RESULT")
+	for _, result := range results {
+		status := result.Status
+		if result.ExitCode != 0 {
+			status = fmt.Sprintf("%s (%s)", status, cleanupExitReason(result.ExitCode))
+		}
+		fmt.Fprintf(out, "  %-24s %8s  %s\n", result.ClusterName, formatAge(now.Sub(result.CreatedAt)), status)
+	}
+}
+
+// cleanupExitReason describes the exit code of a cleanup run
+func cleanupExitReason(code int) string {
+	switch code {
+	case exitDestroyFailed:
+		return "destroy failed"
+	case exitIAMDeleteFailed:
+		return "IAM/S3 deletion failed"
+	case exitAllCleanupsFailed:
+		return "destroy and IAM/S3 deletion failed"
+	case exitLeftoverResources:
+		return "resources left over"
+	}
+	return fmt.Sprintf("exit code %d", code)
+}
+
+// formatAge returns a coarse age for display (e.g. 45m, 5h, 3d4h)
+func formatAge(d time.Duration) string {
+	switch {
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	}
+	days := int(d.Hours()) / 24
+	return fmt.Sprintf("%dd%dh", days, int(d.Hours())-days*24)
+}
+
+// destroyInfrastructure runs openshift-install destroy cluster, with the
+// credentials of the AWS profile
+func destroyInfrastructure(log *logger.Logger, executor util.CommandExecutor, cfg *config.Config, installBin, clusterDir string) error {
+	destroyArgs := []string{"destroy", "cluster", "--dir", clusterDir, "--log-level=debug"}
+
+	// Get AWS credentials from profile and pass them as environment variables
+	awsEnv, err := util.GetAWSEnvVars(cfg.AwsProfile)
+	if err != nil {
+		log.Debug(fmt.Sprintf("Could not read AWS credentials: %v", err))
+		log.Debug("Proceeding without explicit AWS credential injection")
+		return executor.ExecuteInteractive(installBin, destroyArgs...)
+	}
+	return executor.ExecuteInteractiveWithEnv(installBin, awsEnv, destroyArgs...)
+}
+
+// verifyCleanup looks for resources of the cluster left over by the cleanup
+// and, with --force-purge, deletes them. Infrastructure is purged by running
+// openshift-install destroy again, when destroyBin is set.
+func verifyCleanup(log *logger.Logger, executor util.CommandExecutor, cfg *config.Config, summary *errors.Summary, clusterDir, destroyBin string) {
+	log.StartStep("Verifying cleanup")
+	started := time.Now()
+	query := clusterResourceQuery(log, cfg, clusterDir)
+	resources, err := util.FindClusterResources(executor, query)
+	if err == nil && resources.Count() > 0 && cleanupForcePurge {
+		log.Info(fmt.Sprintf("Purging %d leftover AWS resources...", resources.Count()))
+		if len(resources.Infrastructure) > 0 && destroyBin != "" {
+			if err := destroyInfrastructure(log, executor, cfg, destroyBin, clusterDir); err != nil {
+				log.Error(fmt.Sprintf("Failed to destroy infrastructure: %v", err))
+			}
+		}
+		if err := util.PurgeClusterResources(executor, query, resources); err != nil {
+			log.Error(fmt.Sprintf("Failed to purge AWS resources: %v", err))
+		}
+		resources, err = util.FindClusterResources(executor, query)
+	}
+	if err == nil && resources.Count() > 0 {
+		err = fmt.Errorf("%d AWS resources left over", resources.Count())
+		log.FailStep("Verify cleanup")
+		log.Error(fmt.Sprintf("The following AWS resources of cluster '%s' still exist:", cleanupClusterName))
+		printClusterResources(log, resources)
+		log.Info("")
+		log.Info("Delete them by running cleanup again with --force-purge, or with:")
+		for _, hint := range util.DeleteHints(resources) {
+			log.Info("  " + hint)
+		}
+		if len(resources.Infrastructure) > 0 {
+			if destroyBin == "" {
+				destroyBin = "openshift-install"
+			}
+			log.Info(fmt.Sprintf("  %s destroy cluster --dir %s (infrastructure, or delete it from the AWS console)", destroyBin, clusterDir))
+		}
+	} else if err != nil {
+		log.FailStep("Verify cleanup")
+		log.Error(fmt.Sprintf("Failed to list AWS resources: %v", err))
+	} else {
+		log.CompleteStep("Verify cleanup")
+	}
+	summary.AddStep("verify-cleanup", "Verify cleanup", time.Since(started), err)
+}
+
+// promptYes asks a yes/no question and reports whether it was answered yes
+func promptYes(reader *bufio.Reader, prompt string) bool {
+	fmt.Print(prompt)
+	response, _ := reader.ReadString('\n')
+	response = strings.TrimSpace(strings.ToLower(response))
+	return response == "y" || response == "yes"
+}
+
+// cleanupExitCode tells apart in the exit code which of the destroy and the
+// IAM/S3 deletion failed, for scripts running the cleanup
+func cleanupExitCode(summary *errors.Summary) int {
+	failed := map[string]bool{}
+	for _, step := range summary.Steps {
+		if step.Status == errors.StatusFailed {
+			failed[step.ID] = true
+		}
+	}
+	switch {
+	case failed["destroy-infrastructure"] && failed["delete-iam-s3"]:
+		return exitAllCleanupsFailed
+	case failed["destroy-infrastructure"]:
+		return exitDestroyFailed
+	case failed["delete-iam-s3"]:
+		return exitIAMDeleteFailed
+	case failed["verify-cleanup"]:
+		return exitLeftoverResources
+	}
+	return 1
+}
+
+// dryRunCleanup lists the AWS resources of the cluster that a cleanup would delete
+func dryRunCleanup(out *os.File, log *logger.Logger, cfg *config.Config, clusterDir string) {
+	log.StartStep("Listing AWS resources")
+	resources, err := findClusterResources(log, cfg, clusterDir)
+	if err != nil {
+		log.FailStep("Listing AWS resources")
+		log.Error(fmt.Sprintf("Failed to list AWS resources: %v", err))
+		exit(1)
+	}
+	log.CompleteStep("Listing AWS resources")
+
+	if outputFormat == outputJSON {
+		data, err := json.MarshalIndent(resources, "", "  ")
+		if err != nil {
+			log.Error(fmt.Sprintf("Failed to encode resources: %v", err))
+			exit(1)
+		}
+		fmt.Fprintln(out, string(data))
+		return
+	}
+
+	if resources.Count() == 0 {
+		log.Info(fmt.Sprintf("No AWS resources found for cluster '%s' in region '%s'.", cleanupClusterName, cleanupAwsRegion))
+		return
+	}
+	log.Info(fmt.Sprintf("The following AWS resources of cluster '%s' would be deleted:", cleanupClusterName))
+	printClusterResources(log, resources)
+	log.Info("Dry run: nothing deleted.")
+}
+
+// findClusterResources looks up the AWS resources of the cluster being cleaned
+// up. The infra ID comes from metadata.json and the base domain from the
+// install-config backup (or the config file): without them, infrastructure and
+// DNS records cannot be told apart from those of other clusters.
+func findClusterResources(log *logger.Logger, cfg *config.Config, clusterDir string) (*util.ClusterResources, error) {
+	return util.FindClusterResources(logCommands(log, &util.RealExecutor{}), clusterResourceQuery(log, cfg, clusterDir))
+}
+
+func clusterResourceQuery(log *logger.Logger, cfg *config.Config, clusterDir string) util.ClusterResourceQuery {
+	query := util.ClusterResourceQuery{
+		Profile:        cfg.AwsProfile,
+		Region:         cleanupAwsRegion,
+		ClusterName:    cleanupClusterName,
+		ResourcePrefix: cleanupPrefix,
+		BaseDomain:     cfg.BaseDomain,
+	}
+	if metadata, err := util.ReadClusterMetadata(clusterDir); err == nil {
+		query.InfraID = metadata.InfraID
+	} else if cleanupInfraID != "" {
+		query.InfraID = cleanupInfraID
+	} else {
+		log.Info("⚠ metadata.json not found: infrastructure resources cannot be listed (see --discover)")
+	}
+	if fields, err := util.ExtractAllFields(util.GetInstallConfigPath("", cleanupClusterName) + ".backup"); err == nil && fields.BaseDomain != "" {
+		query.BaseDomain = fields.BaseDomain
+	}
+	if query.BaseDomain == "" {
+		log.Info("⚠ Base domain unknown: DNS records cannot be listed")
+	}
+	return query
+}
+
+// printClusterResources logs the resources grouped by kind
+func printClusterResources(log *logger.Logger, resources *util.ClusterResources) {
+	for _, group := range []struct {
+		kind  string
+		names []string
+	}{
+		{"IAM roles", resources.IAMRoles},
+		{"OIDC providers", resources.OIDCProviders},
+		{"S3 buckets", resources.S3Buckets},
+		{"Route53 records", resources.DNSRecords},
+		{"Infrastructure", resources.Infrastructure},
+	} {
+		if len(group.names) == 0 {
+			continue
+		}
+		log.Info(fmt.Sprintf("  %s (%d):", group.kind, len(group.names)))
+		for _, name := range group.names {
+			log.Info(fmt.Sprintf("    - %s", name))
+		}
+	}
+}
+
+// finishCleanup sends the notification and prints the JSON summary of the
+// cleanup. The text output is the log itself.
+func finishCleanup(out *os.File, log *logger.Logger, cfg *config.Config, summary *errors.Summary, clusterDir string, started time.Time) {
+	notify(log, cfg, "cleanup", cleanupClusterName, summary, started)
+	if outputFormat != outputJSON {
+		return
+	}
+	if util.DirExists(clusterDir) {
+		summary.AddArtifact("clusterDir", clusterDir)
+	}
+	printSummary(out, summary)
 }
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/clobrano/openshift-sts-wrapper/pkg/config"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/util"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// flagCompletions are the dynamic completions of the flags with the same
+// meaning in every command
+var flagCompletions = map[string]cobra.CompletionFunc{
+	"cluster-name":    completeClusterNames,
+	"start-from-step": completeStepNames,
+	"stop-after-step": completeStepNames,
+	"only-step":       completeStepNames,
+	"skip-steps":      completeStepList,
+	"aws-profile":     completeAWSProfiles,
+}
+
+// registerCompletions registers the dynamic flag completions of a command and
+// its subcommands. It runs once all the commands have defined their flags.
+func registerCompletions(cmd *cobra.Command) {
+	cmd.Flags().VisitAll(func(flag *pflag.Flag) {
+		if completion, ok := flagCompletions[flag.Name]; ok {
+			// Flags shared with another command (e.g. config explain) are registered once
+			if _, exists := cmd.GetFlagCompletionFunc(flag.Name); !exists {
+				checkErr(cmd.RegisterFlagCompletionFunc(flag.Name, completion))
+			}
+		}
+	})
+	for _, child := range cmd.Commands() {
+		registerCompletions(child)
+	}
+}
+
+// completeClusterNames completes the clusters with an artifacts directory
+func completeClusterNames(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	clusters, err := util.ListClusters()
+	if err != nil {
+		cobra.CompErrorln(err.Error())
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return clusters, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeStepNames completes the step names, with their number as description
+func completeStepNames(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	var names []string
+	for i, name := range config.StepNames {
+		names = append(names, fmt.Sprintf("%s\tstep %d", name, i+1))
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeStepList completes the last step of a comma-separated list
+func completeStepList(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	prefix := ""
+	if i := strings.LastIndex(toComplete, ","); i >= 0 {
+		prefix = toComplete[:i+1]
+	}
+	var names []string
+	for _, name := range config.StepNames {
+		names = append(names, prefix+name)
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveNoSpace
+}
+
+// completeAWSProfiles completes the profiles of ~/.aws/config
+func completeAWSProfiles(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	profiles, err := util.ListAWSProfiles()
+	if err != nil {
+		cobra.CompErrorln(err.Error())
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return profiles, cobra.ShellCompDirectiveNoFileComp
+}
@@ -0,0 +1,193 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/clobrano/openshift-sts-wrapper/pkg/config"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/logger"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/util"
+	"github.com/spf13/cobra"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect and edit the configuration file",
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate [file]",
+	Short: "Validate a configuration file",
+	Long: `Checks the configuration file (default: --config or ./openshift-sts-wrapper.yaml)
+for unknown keys, values of the wrong type and inconsistent settings.
+Exits with a non-zero status when errors are found.`,
+	Args: cobra.MaximumNArgs(1),
+	Run:  runConfigValidate,
+}
+
+var configExplainCmd = &cobra.Command{
+	Use:   "explain",
+	Short: "Show every effective setting and where it comes from",
+	Long: `Resolves the configuration exactly like install does (flags > config file
+profile > config file > environment > defaults) and prints every setting with
+its effective value and source. Accepts the same flags as install.`,
+	Args: cobra.NoArgs,
+	Run:  runConfigExplain,
+}
+
+var configGetCmd = &cobra.Command{
+	Use:   "get <key>",
+	Short: "Print a setting of the configuration file",
+	Long: `Prints the value of a setting of the configuration file (default: --config or
+./openshift-sts-wrapper.yaml). Nested settings use dotted keys, e.g.
+vault.address or tags.team. With --profile, the setting of the profile is
+printed.
+
+Only the file is read: use config explain for the effective value. Exits with
+a non-zero status when the setting is not in the file.`,
+	Args: cobra.ExactArgs(1),
+	Run:  runConfigGet,
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set <key> <value>",
+	Short: "Change a setting of the configuration file",
+	Long: `Sets a setting of the configuration file (default: --config or
+./openshift-sts-wrapper.yaml), creating the file if needed. Nested settings use
+dotted keys, e.g. vault.address or tags.team, and --profile changes the setting
+of a profile.
+
+The value must match the type of the setting (true/false, integers); lists are
+comma separated. The file is left unchanged when the new value makes it
+invalid. Comments are kept, settings such as computePools that hold a list of
+mappings must be edited in the file.`,
+	Example: `  openshift-sts-wrapper config set awsRegion us-east-2
+  openshift-sts-wrapper config set workerReplicas 2
+  openshift-sts-wrapper config set zones us-east-2a,us-east-2b
+  openshift-sts-wrapper config set --profile=prod tags.environment prod`,
+	Args: cobra.ExactArgs(2),
+	Run:  runConfigSet,
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configValidateCmd)
+	configCmd.AddCommand(configExplainCmd)
+	configCmd.AddCommand(configGetCmd)
+	configCmd.AddCommand(configSetCmd)
+}
+
+// configFilePath returns the config file used by the commands
+func configFilePath() string {
+	if cfgFile != "" {
+		return cfgFile
+	}
+	return "openshift-sts-wrapper.yaml"
+}
+
+// loadConfigFile loads the config file, if it exists, with the profile selected
+// by --profile applied. An unknown profile is a fatal error.
+func loadConfigFile(log *logger.Logger) *config.Config {
+	path := configFilePath()
+	if !util.FileExists(path) {
+		if configProfile != "" {
+			log.Error(fmt.Sprintf("Profile %q requested but config file %s does not exist", configProfile, path))
+			exit(1)
+		}
+		return &config.Config{}
+	}
+
+	fileCfg, err := config.LoadFromFile(path)
+	if err != nil {
+		log.Debug(fmt.Sprintf("Could not load config file: %v", err))
+		return &config.Config{}
+	}
+	if configProfile != "" {
+		if err := fileCfg.ApplyProfile(configProfile); err != nil {
+			log.Error(fmt.Sprintf("%s: %v", path, err))
+			exit(1)
+		}
+		log.Debug(fmt.Sprintf("Using profile %q from %s", configProfile, path))
+	}
+	return fileCfg
+}
+
+func runConfigValidate(cmd *cobra.Command, args []string) {
+	log := logger.New(logger.Level(getLogLevel()), nil)
+
+	path := configFilePath()
+	if len(args) == 1 {
+		path = args[0]
+	}
+
+	report, err := config.ValidateFile(path)
+	if err != nil {
+		log.Error(err.Error())
+		exit(1)
+	}
+
+	for _, warning := range report.Warnings {
+		log.Info(fmt.Sprintf("⚠  %s", warning))
+	}
+	for _, message := range report.Errors {
+		log.Error(fmt.Sprintf("✗ %s", message))
+	}
+	if !report.Valid() {
+		log.Error(fmt.Sprintf("%s: %d error(s)", path, len(report.Errors)))
+		exit(1)
+	}
+	log.Info(fmt.Sprintf("✓ %s is valid", path))
+}
+
+func runConfigExplain(cmd *cobra.Command, args []string) {
+	log := logger.New(logger.Level(getLogLevel()), nil)
+	cfg := loadConfig(log)
+
+	for _, value := range cfg.Explain() {
+		source := value.Source
+		if source != "" {
+			source = "(" + source + ")"
+		}
+		fmt.Println(strings.TrimSpace(fmt.Sprintf("%-22s %-40s %s", value.Key, value.Value, source)))
+	}
+}
+
+// configKey returns the key of the config file edited by get and set, in the
+// profile selected by --profile if any
+func configKey(key string) string {
+	if configProfile != "" {
+		return "profiles." + configProfile + "." + key
+	}
+	return key
+}
+
+func runConfigGet(cmd *cobra.Command, args []string) {
+	log := logger.New(logger.Level(getLogLevel()), nil)
+
+	value, err := config.GetKey(configFilePath(), configKey(args[0]))
+	if errors.Is(err, config.ErrKeyNotSet) {
+		log.Debug(err.Error())
+		exit(1)
+	}
+	if err != nil {
+		log.Error(err.Error())
+		exit(1)
+	}
+	fmt.Println(value)
+}
+
+func runConfigSet(cmd *cobra.Command, args []string) {
+	log := logger.New(logger.Level(getLogLevel()), nil)
+
+	path := configFilePath()
+	report, err := config.SetKey(path, configKey(args[0]), args[1])
+	if err != nil {
+		log.Error(err.Error())
+		exit(1)
+	}
+	for _, warning := range report.Warnings {
+		log.Info(fmt.Sprintf("⚠  %s", warning))
+	}
+	log.Info(fmt.Sprintf("✓ Set %s in %s", configKey(args[0]), path))
+}
@@ -0,0 +1,174 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/clobrano/openshift-sts-wrapper/pkg/config"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/logger"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/util"
+	"github.com/spf13/cobra"
+)
+
+var (
+	costClusterName string
+	costAwsRegion   string
+	costSince       string
+	costActivateTag bool
+)
+
+var costCmd = &cobra.Command{
+	Use:   "cost",
+	Short: "Report the AWS spend of a cluster",
+	Long: `Queries Cost Explorer for the spend of the resources tagged with the infra ID
+of a cluster (kubernetes.io/cluster/<infra-id>), and prints the total to date
+and a daily breakdown. The period starts when the cluster was installed,
+unless --since is set.
+
+The tag must be activated as a cost allocation tag for Cost Explorer to
+report it: use --activate-tag once, spend is attributed to it from about a
+day later. Each Cost Explorer request is charged by AWS ($0.01).`,
+	Run: runCost,
+}
+
+func init() {
+	rootCmd.AddCommand(costCmd)
+
+	costCmd.Flags().StringVar(&costClusterName, "cluster-name", "", "Cluster name (required)")
+	costCmd.Flags().StringVar(&costAwsRegion, "region", "", "AWS region, to discover the infra ID when metadata.json is missing (optional - will be read from the config)")
+	costCmd.Flags().StringVar(&costSince, "since", "", "First day of the report (YYYY-MM-DD, default is the installation day)")
+	costCmd.Flags().BoolVar(&costActivateTag, "activate-tag", false, "Activate the infra ID tag of the cluster as a cost allocation tag")
+}
+
+func runCost(cmd *cobra.Command, args []string) {
+	out := redirectOutput()
+	log := logger.New(logger.Level(getLogLevel()), nil)
+
+	if costClusterName == "" {
+		log.Error("Cluster name is required (use --cluster-name flag)")
+		exit(1)
+	}
+
+	cfg := &config.Config{}
+	cfg.Merge(config.LoadFromEnv())
+	cfg.Merge(loadConfigFile(log))
+	cfg.ClusterName = costClusterName
+	if costAwsRegion != "" {
+		cfg.AwsRegion = costAwsRegion
+	}
+	cfg.SetDefaults()
+	useServiceEndpoints(cfg)
+
+	start, err := costStartDate(costClusterName, costSince)
+	if err != nil {
+		log.Error(err.Error())
+		exit(1)
+	}
+	// Cost Explorer periods end on an exclusive day: include today
+	end := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, 1)
+	if !start.Before(end) {
+		log.Error(fmt.Sprintf("--since %s is in the future", start.Format("2006-01-02")))
+		exit(1)
+	}
+
+	useVaultAWSCredentials(log, cfg)
+	validateAWSCredentials(log, cfg.AwsProfile)
+	assumeRole(log, cfg)
+
+	executor := logCommands(log, &util.RealExecutor{})
+	infraID := costInfraID(log, executor, cfg)
+
+	if costActivateTag {
+		if err := util.ActivateCostAllocationTag(executor, cfg.AwsProfile, infraID); err != nil {
+			log.Error(err.Error())
+			exit(1)
+		}
+		log.Info(fmt.Sprintf("✓ Activated cost allocation tag %s%s (spend is reported from about a day later)", util.InfraTagKeyPrefix, infraID))
+	}
+
+	cost, err := util.GetClusterCost(executor, cfg.AwsProfile, infraID, start, end)
+	if err != nil {
+		log.Error(err.Error())
+		exit(1)
+	}
+	if cost.Total == 0 && !costActivateTag {
+		log.Info(fmt.Sprintf("⚠  No spend reported: make sure %s%s is an active cost allocation tag (see --activate-tag)", util.InfraTagKeyPrefix, infraID))
+	}
+	printClusterCost(out, costClusterName, cost)
+}
+
+// costStartDate returns the first day of the cost report: since, if set, or
+// the day the cluster was installed
+func costStartDate(clusterName, since string) (time.Time, error) {
+	if since != "" {
+		start, err := time.Parse("2006-01-02", since)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid --since %q: must be a date (YYYY-MM-DD)", since)
+		}
+		return start, nil
+	}
+	created, err := util.ClusterCreationTime(clusterName)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%v (use --since)", err)
+	}
+	return created.UTC().Truncate(24 * time.Hour), nil
+}
+
+// costInfraID returns the infra ID of the cluster from metadata.json, or
+// discovered from the tags of its resources in the configured region
+func costInfraID(log *logger.Logger, executor util.CommandExecutor, cfg *config.Config) string {
+	if metadata, err := util.ReadClusterMetadata(util.GetClusterPath(cfg.ClusterName, "")); err == nil && metadata.InfraID != "" {
+		log.Info(fmt.Sprintf("Infra ID: %s", metadata.InfraID))
+		return metadata.InfraID
+	}
+
+	if cfg.AwsRegion == "" {
+		log.Error(fmt.Sprintf("No metadata.json for cluster %s: the AWS region is required to discover its infra ID (use --region flag)", cfg.ClusterName))
+		exit(1)
+	}
+	infraIDs, err := util.DiscoverInfraIDs(executor, cfg.AwsProfile, cfg.AwsRegion, cfg.ClusterName)
+	if err != nil {
+		log.Error(fmt.Sprintf("Failed to discover the infra ID: %v", err))
+		exit(1)
+	}
+	switch len(infraIDs) {
+	case 0:
+		log.Error(fmt.Sprintf("No infrastructure tagged for cluster '%s' found in region '%s'", cfg.ClusterName, cfg.AwsRegion))
+		exit(1)
+	case 1:
+		log.Info(fmt.Sprintf("Discovered Infra ID: %s", infraIDs[0]))
+	default:
+		log.Error(fmt.Sprintf("Several clusters named '%s' found in region '%s': %s", cfg.ClusterName, cfg.AwsRegion, strings.Join(infraIDs, ", ")))
+		exit(1)
+	}
+	return infraIDs[0]
+}
+
+// printClusterCost prints the spend of the cluster in the selected output format
+func printClusterCost(out *os.File, clusterName string, cost *util.ClusterCost) {
+	if outputFormat == outputJSON {
+		data, err := json.MarshalIndent(cost, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to encode cost: %v\n", err)
+			return
+		}
+		fmt.Fprintln(out, string(data))
+		return
+	}
+
+	fmt.Fprintf(out, "\n=== Cost of %s (%s) ===\n", clusterName, cost.InfraID)
+	fmt.Fprintln(out)
+	fmt.Fprintf(out, "  %-12s %12s\n", "DATE", "COST")
+	for _, day := range cost.Daily {
+		estimated := ""
+		if day.Estimated {
+			estimated = "  (estimated)"
+		}
+		fmt.Fprintf(out, "  %-12s %12.2f%s\n", day.Date, day.Amount, estimated)
+	}
+	fmt.Fprintln(out)
+	fmt.Fprintf(out, "  Total: %.2f %s\n", cost.Total, cost.Currency)
+}
@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/clobrano/openshift-sts-wrapper/pkg/config"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/logger"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/util"
+)
+
+// validateAWSCredentials validates the credentials of the profile and exits on
+// failure. When an SSO-backed profile has no valid session, a new one is started
+// with `aws sso login` if a terminal is attached, otherwise the command to run
+// is printed.
+func validateAWSCredentials(log *logger.Logger, profile string) {
+	log.Info(fmt.Sprintf("Validating AWS credentials for profile '%s'...", profile))
+	err := util.ValidateAWSCredentials(profile)
+	if err != nil && !util.HasSessionCredentials() && util.IsSSOProfile(profile) {
+		loginCmd := util.SSOLoginCommand(profile)
+		log.Info(fmt.Sprintf("⚠  No valid SSO session for profile '%s'", profile))
+
+		if nonInteractive || !isTerminal(os.Stdin) {
+			log.Error(fmt.Sprintf("AWS credential validation failed: %v", err))
+			log.Info(fmt.Sprintf("Start a new SSO session with: %s", strings.Join(loginCmd, " ")))
+			exit(exitAWSAuth)
+		}
+
+		log.Info(fmt.Sprintf("Running '%s'...", strings.Join(loginCmd, " ")))
+		executor := logCommands(log, &util.RealExecutor{})
+		if loginErr := executor.ExecuteInteractive(loginCmd[0], loginCmd[1:]...); loginErr != nil {
+			log.Error(fmt.Sprintf("SSO login failed: %v", loginErr))
+			exit(exitAWSAuth)
+		}
+		util.ResetExportedCredentials(profile)
+		err = util.ValidateAWSCredentials(profile)
+	}
+	if err != nil {
+		log.Error(fmt.Sprintf("AWS credential validation failed: %v", err))
+		exit(exitAWSAuth)
+	}
+	log.Info("✓ AWS credentials are valid")
+}
+
+// maxRoleSession is the longest role session STS grants, requested when the
+// installation has no timeout
+const maxRoleSession = 12 * time.Hour
+
+// assumeRole switches every AWS call and child process to the temporary
+// credentials of the configured role, prompting for an MFA code if needed
+func assumeRole(log *logger.Logger, cfg *config.Config) {
+	if cfg.AssumeRoleARN == "" {
+		return
+	}
+
+	tokenCode := ""
+	if cfg.MFASerial != "" {
+		tokenCode = os.Getenv("OPENSHIFT_STS_MFA_TOKEN")
+		if tokenCode == "" && nonInteractive {
+			log.Error(fmt.Sprintf("An MFA code is required for %s: set OPENSHIFT_STS_MFA_TOKEN", cfg.MFASerial))
+			exit(exitAWSAuth)
+		}
+		if tokenCode == "" {
+			fmt.Printf("Enter MFA code for %s: ", cfg.MFASerial)
+			answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+			tokenCode = strings.TrimSpace(answer)
+		}
+	}
+
+	// The session must outlive the installation: child processes get the role
+	// credentials once and cannot refresh them
+	duration, _ := cfg.GetInstallTimeout()
+	if duration == 0 {
+		duration = maxRoleSession
+	}
+
+	log.Info(fmt.Sprintf("Assuming role %s...", cfg.AssumeRoleARN))
+	creds, err := util.AssumeRole(logCommands(log, &util.RealExecutor{}), cfg.AwsProfile, cfg.AssumeRoleARN, roleSessionName(cfg.ClusterName), cfg.MFASerial, tokenCode, duration)
+	if err != nil {
+		log.Error(err.Error())
+		exit(exitAWSAuth)
+	}
+	if !creds.Expiration.IsZero() && time.Until(creds.Expiration) < duration {
+		log.Info(fmt.Sprintf("⚠  The role session expires at %s, before the installation may complete: raise the maximum session duration of %s", creds.Expiration.Local().Format(time.RFC3339), cfg.AssumeRoleARN))
+	}
+	util.SetSessionCredentials(creds)
+
+	if err := util.ValidateAWSCredentials(cfg.AwsProfile); err != nil {
+		log.Error(fmt.Sprintf("Assumed role credentials validation failed: %v", err))
+		exit(exitAWSAuth)
+	}
+	log.Info(fmt.Sprintf("✓ Running as %s", cfg.AssumeRoleARN))
+}
+
+// useServiceEndpoints directs the aws CLI and ccoctl, run as child processes,
+// to the configured custom AWS service endpoints
+func useServiceEndpoints(cfg *config.Config) {
+	for _, variable := range cfg.ServiceEndpointEnv() {
+		name, value, _ := strings.Cut(variable, "=")
+		os.Setenv(name, value)
+	}
+}
+
+// roleSessionName returns the STS session name, which identifies the
+// installation in CloudTrail (at most 64 characters)
+func roleSessionName(clusterName string) string {
+	name := "openshift-sts-wrapper"
+	if clusterName != "" {
+		name += "-" + clusterName
+	}
+	if len(name) > 64 {
+		name = name[:64]
+	}
+	return name
+}
+
+// isTerminal reports whether the file is an interactive terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
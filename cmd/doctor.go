@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/clobrano/openshift-sts-wrapper/pkg/config"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/doctor"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/logger"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/util"
+	"github.com/spf13/cobra"
+)
+
+var (
+	doctorAwsProfile     string
+	doctorPullSecretPath string
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check that the environment is ready for an installation",
+	Long: `Diagnoses the environment the installation runs in, without changing it:
+  - oc and aws CLI presence and versions
+  - validity of the AWS credentials, and the identity they belong to
+  - disk space where the artifacts are written
+  - network access to quay.io and registry.redhat.io, and whether they
+    accept the credentials of the pull secret
+
+Each failed check is printed with a hint to fix it. The command exits with a
+non-zero status when any check fails (warnings don't).`,
+	Run: runDoctor,
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+
+	doctorCmd.Flags().StringVar(&doctorAwsProfile, "aws-profile", "", "AWS profile to check (optional - will be read from the config)")
+	doctorCmd.Flags().StringVar(&doctorPullSecretPath, "pull-secret", "", "Pull secret to check (optional - will be read from the config)")
+}
+
+func runDoctor(cmd *cobra.Command, args []string) {
+	out := redirectOutput()
+	log := logger.New(logger.Level(getLogLevel()), nil)
+
+	cfg := &config.Config{}
+	cfg.Merge(config.LoadFromEnv())
+	cfg.Merge(loadConfigFile(log))
+	cfg.Merge(&config.Config{AwsProfile: doctorAwsProfile, PullSecretPath: doctorPullSecretPath})
+	cfg.SetDefaults()
+	useServiceEndpoints(cfg)
+
+	results := doctor.NewEnv(cfg, logCommands(log, &util.RealExecutor{})).Run()
+	printDoctorResults(out, results)
+	if len(doctor.Failed(results)) > 0 {
+		exit(1)
+	}
+}
+
+// printDoctorResults prints the outcome of the checks in the selected output format
+func printDoctorResults(out *os.File, results []doctor.Result) {
+	if outputFormat == outputJSON {
+		data, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to encode results: %v\n", err)
+			return
+		}
+		fmt.Fprintln(out, string(data))
+		return
+	}
+
+	marks := map[string]string{
+		doctor.StatusPassed:  "✓",
+		doctor.StatusWarning: "⚠",
+		doctor.StatusFailed:  "✗",
+	}
+	for _, result := range results {
+		fmt.Fprintf(out, "%s %-28s %s\n", marks[result.Status], result.Name, result.Message)
+		if result.Hint != "" {
+			fmt.Fprintf(out, "  → %s\n", result.Hint)
+		}
+	}
+	fmt.Fprintln(out)
+	if failed := doctor.Failed(results); len(failed) > 0 {
+		fmt.Fprintf(out, "%d/%d checks failed\n", len(failed), len(results))
+		return
+	}
+	fmt.Fprintln(out, "The environment is ready")
+}
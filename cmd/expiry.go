@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/clobrano/openshift-sts-wrapper/pkg/config"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/logger"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/util"
+)
+
+// scheduleExpiry schedules the cleanup of a cluster installed with
+// --expires-in at its expiry, counted from the start of the installation, and
+// records it in the install metadata. The job of a previous run of the
+// installation is replaced.
+func scheduleExpiry(log *logger.Logger, cfg *config.Config, started time.Time, journal *util.Journal) {
+	lifetime, _ := cfg.GetExpiresIn()
+	if lifetime == 0 || !createdAWSResources(journal) {
+		return
+	}
+	clusterDir := util.GetClusterPath(cfg.ClusterName, "")
+	metadata, err := util.ReadInstallMetadata(clusterDir)
+	if err != nil {
+		log.Error(fmt.Sprintf("Could not schedule the expiry of the cluster: %v", err))
+		return
+	}
+
+	executor := logCommands(log, &util.RealExecutor{})
+	if metadata.ExpiryJob != nil {
+		if err := util.CancelExpiry(executor, metadata.ExpiryJob); err != nil {
+			log.Debug(fmt.Sprintf("Could not cancel the previous expiry job: %v", err))
+		}
+	}
+
+	expiresAt := started.Add(lifetime).UTC()
+	job, err := scheduleCleanup(executor, cfg, expiresAt)
+	if err != nil {
+		log.Error(fmt.Sprintf("Could not schedule the cleanup of the cluster at its expiry: %v", err))
+		log.Info(fmt.Sprintf("Destroy it at %s with: openshift-sts-wrapper cleanup --cluster-name=%s", expiresAt.Local().Format("2006-01-02 15:04"), cfg.ClusterName))
+	} else {
+		log.Info(fmt.Sprintf(">>> Cluster expires at %s (in %s): cleanup scheduled with %s job %s <<<",
+			expiresAt.Local().Format("2006-01-02 15:04"), formatAge(time.Until(expiresAt)), job.Scheduler, job.ID))
+	}
+	if err := util.RecordExpiry(clusterDir, &expiresAt, job); err != nil {
+		log.Error(fmt.Sprintf("Could not record the expiry of the cluster: %v", err))
+	}
+}
+
+// scheduleCleanup registers a job running cleanup --yes for a cluster at the
+// given time, from the current directory and with the same configuration: the
+// config file and profile, the environment, and the AWS settings given as
+// flags, which cleanup does not take
+func scheduleCleanup(executor util.CommandExecutor, cfg *config.Config, at time.Time) (*util.ExpiryJob, error) {
+	clusterName := cfg.ClusterName
+	executable, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("failed to find the executable: %w", err)
+	}
+	command := []string{executable, "cleanup", "--cluster-name", clusterName, "--yes"}
+	if cfgFile != "" {
+		command = append(command, "--config", cfgFile)
+	}
+	if configProfile != "" {
+		command = append(command, "--profile", configProfile)
+	}
+	env := util.ExpiryEnv(os.Environ(), map[string]string{
+		"OPENSHIFT_STS_AWS_PROFILE":     cfg.AwsProfile,
+		"OPENSHIFT_STS_ASSUME_ROLE_ARN": cfg.AssumeRoleARN,
+		"OPENSHIFT_STS_AWS_REGION":      cfg.AwsRegion,
+		"OPENSHIFT_STS_AWS_PARTITION":   cfg.AwsPartition,
+	})
+	script, err := util.WriteExpiryScript(clusterName, command, env)
+	if err != nil {
+		return nil, err
+	}
+	return util.ScheduleExpiry(executor, util.DetectScheduler(), clusterName, at, script)
+}
+
+// cancelExpiry removes the scheduled cleanup of a cluster that was cleaned up
+func cancelExpiry(log *logger.Logger, clusterDir string, metadata *util.InstallMetadata) {
+	if metadata == nil || metadata.ExpiryJob == nil {
+		return
+	}
+	if err := util.CancelExpiry(logCommands(log, &util.RealExecutor{}), metadata.ExpiryJob); err != nil {
+		log.Debug(fmt.Sprintf("Could not cancel the expiry job: %v", err))
+	} else {
+		log.Info(fmt.Sprintf("Cancelled the scheduled cleanup (%s job %s)", metadata.ExpiryJob.Scheduler, metadata.ExpiryJob.ID))
+	}
+	if err := util.RecordExpiry(clusterDir, nil, nil); err != nil {
+		log.Debug(fmt.Sprintf("Could not clear the expiry of the cluster: %v", err))
+	}
+}
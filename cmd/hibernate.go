@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/clobrano/openshift-sts-wrapper/pkg/config"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/logger"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/steps"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/util"
+	"github.com/spf13/cobra"
+)
+
+// certificateRotationAge is when a new cluster rotates its first, short-lived,
+// certificates: hibernated before, it may not recover
+const certificateRotationAge = 24 * time.Hour
+
+var (
+	hibernateClusterName string
+	hibernateAwsRegion   string
+	wakeTimeout          string
+)
+
+var hibernateCmd = &cobra.Command{
+	Use:   "hibernate",
+	Short: "Stop the instances of a cluster",
+	Long: `Stops the EC2 instances tagged with the infra ID of a cluster, so that only
+its volumes, load balancers and addresses are charged until it is woken up
+with the wake command.
+
+Don't hibernate a cluster in its first 24 hours: it rotates its first
+certificates then, and may not recover if they expire while it sleeps.`,
+	Run: runHibernate,
+}
+
+var wakeCmd = &cobra.Command{
+	Use:   "wake",
+	Short: "Start the instances of a hibernated cluster",
+	Long: `Starts the EC2 instances of a cluster stopped with the hibernate command, and
+waits for its nodes to be Ready, approving the certificate signing requests
+of the nodes whose certificates expired while they were stopped`,
+	Run: runWake,
+}
+
+func init() {
+	rootCmd.AddCommand(hibernateCmd)
+	rootCmd.AddCommand(wakeCmd)
+
+	for _, command := range []*cobra.Command{hibernateCmd, wakeCmd} {
+		command.Flags().StringVar(&hibernateClusterName, "cluster-name", "", "Cluster name (required)")
+		command.Flags().StringVar(&hibernateAwsRegion, "region", "", "AWS region, when metadata.json is missing (optional - will be read from the config)")
+		command.Flags().BoolVar(&forceUnlock, "force-unlock", false, "Remove the lock of a run against the cluster that is hung or stale")
+	}
+	wakeCmd.Flags().StringVar(&wakeTimeout, "timeout", "20m", "Maximum time to wait for the nodes to be Ready")
+}
+
+func runHibernate(cmd *cobra.Command, args []string) {
+	log := logger.New(logger.Level(getLogLevel()), nil)
+
+	if hibernateClusterName == "" {
+		log.Error("Cluster name is required (use --cluster-name flag)")
+		exit(1)
+	}
+
+	lock := lockCluster(log, hibernateClusterName)
+	defer lock.Unlock()
+
+	if created, err := util.ClusterCreationTime(hibernateClusterName); err == nil && time.Since(created) < certificateRotationAge {
+		log.Info(fmt.Sprintf("⚠  The cluster was installed %s ago: its first certificates rotate about 24h after the installation, if they expire while it is hibernated the cluster may not recover", formatAge(time.Since(created))))
+	}
+
+	cfg := hibernateConfig(log)
+	executor := logCommands(log, &util.RealExecutor{})
+	step := steps.NewHibernateCluster(cfg, log, executor, costInfraID(log, executor, cfg))
+	runDay2Step(log, cfg, step)
+	log.Info(fmt.Sprintf("Wake the cluster with: %s wake --cluster-name %s", os.Args[0], hibernateClusterName))
+}
+
+func runWake(cmd *cobra.Command, args []string) {
+	log := logger.New(logger.Level(getLogLevel()), nil)
+
+	if hibernateClusterName == "" {
+		log.Error("Cluster name is required (use --cluster-name flag)")
+		exit(1)
+	}
+	timeout, err := time.ParseDuration(wakeTimeout)
+	if err != nil || timeout <= 0 {
+		log.Error(fmt.Sprintf("Invalid --timeout %q: must be a positive duration (e.g. 20m)", wakeTimeout))
+		exit(1)
+	}
+
+	lock := lockCluster(log, hibernateClusterName)
+	defer lock.Unlock()
+
+	cfg := hibernateConfig(log)
+
+	// Ctrl-C stops waiting; the instances are still started
+	ctx, stop := interruptContext()
+	defer stop()
+
+	executor := logCommands(log, &util.RealExecutor{Context: ctx})
+	step := steps.NewWakeCluster(cfg, log, executor, costInfraID(log, executor, cfg), timeout)
+	runDay2Step(log, cfg, step)
+}
+
+// hibernateConfig returns the configuration of the cluster, in the region of
+// its metadata.json if any, with the AWS credentials validated
+func hibernateConfig(log *logger.Logger) *config.Config {
+	cfg := &config.Config{}
+	cfg.Merge(config.LoadFromEnv())
+	cfg.Merge(loadConfigFile(log))
+	cfg.ClusterName = hibernateClusterName
+	if hibernateAwsRegion != "" {
+		cfg.AwsRegion = hibernateAwsRegion
+	}
+	if metadata, err := util.ReadClusterMetadata(util.GetClusterPath(cfg.ClusterName, "")); err == nil && metadata.AWS.Region != "" {
+		cfg.AwsRegion = metadata.AWS.Region
+	}
+	cfg.SetDefaults()
+	useServiceEndpoints(cfg)
+
+	if cfg.AwsRegion == "" {
+		log.Error("AWS region is required (use --region flag)")
+		exit(1)
+	}
+
+	useVaultAWSCredentials(log, cfg)
+	validateAWSCredentials(log, cfg.AwsProfile)
+	assumeRole(log, cfg)
+	return cfg
+}
+
+// runDay2Step runs a step on an installed cluster, exiting if it fails
+func runDay2Step(log *logger.Logger, cfg *config.Config, step steps.Step) {
+	log.StartStep(step.Name())
+	if err := step.Execute(); err != nil {
+		log.FailStep(step.Name())
+		log.Error(err.Error())
+		runFailureHooks(log, cfg, step.Name(), err)
+		exit(1)
+	}
+	log.CompleteStep(step.Name())
+}
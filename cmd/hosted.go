@@ -0,0 +1,191 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/clobrano/openshift-sts-wrapper/pkg/config"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/errors"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/logger"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/steps"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/util"
+	"github.com/spf13/cobra"
+)
+
+var (
+	hostedAwsRegion            string
+	hostedBaseDomain           string
+	hostedManagementKubeconfig string
+	hostedNamespace            string
+	hostedRoleARN              string
+)
+
+var hostedClusterCmd = &cobra.Command{
+	Use:   "hosted-cluster",
+	Short: "Manage HyperShift hosted clusters with STS",
+}
+
+var hostedClusterCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Create a HyperShift hosted cluster with STS",
+	Long: `Creates a HyperShift hosted cluster on a management cluster running the
+HyperShift operator, with the hcp CLI:
+
+  1. Extracts the credentials requests of the release (install Step 1)
+  2. Extracts ccoctl (install Step 3)
+  3. Creates the OIDC provider and the IAM roles of the cluster with ccoctl (install Step 7)
+  4. Writes the temporary STS credentials hcp creates the infrastructure with
+  5. Creates the HostedCluster and its node pool with 'hcp create cluster aws',
+     using the service account issuer and signing key of step 3
+  6. Waits for the hosted control plane to be available and writes its kubeconfig
+  7. Creates the credentials secrets of step 3 the hosted control plane doesn't manage
+
+Completed steps are skipped when the command runs again. Destroy the hosted
+cluster with 'hcp destroy cluster aws', then delete the IAM roles and OIDC
+provider with 'cleanup'.`,
+	Args: cobra.NoArgs,
+	Run:  runHostedClusterCreate,
+}
+
+func init() {
+	rootCmd.AddCommand(hostedClusterCmd)
+	hostedClusterCmd.AddCommand(hostedClusterCreateCmd)
+
+	hostedClusterCreateCmd.Flags().StringVar(&hostedAwsRegion, "region", "", "AWS region of the hosted cluster (required)")
+	hostedClusterCreateCmd.Flags().StringVar(&hostedBaseDomain, "base-domain", "", "Base domain of the hosted cluster (required)")
+	hostedClusterCreateCmd.Flags().StringVar(&hostedManagementKubeconfig, "management-kubeconfig", "", "Kubeconfig of the management cluster running the HyperShift operator (required)")
+	hostedClusterCreateCmd.Flags().StringVar(&hostedNamespace, "namespace", "", "Namespace of the HostedCluster on the management cluster (default: clusters)")
+	hostedClusterCreateCmd.Flags().StringVar(&hostedRoleARN, "role-arn", "", "IAM role hcp assumes with the STS credentials to create the infrastructure (required)")
+}
+
+// addSharedInstallFlags adds the install flags of the settings shared with
+// install, which then resolve like they do for install
+func addSharedInstallFlags() {
+	for _, name := range []string{
+		"release-image", "version", "channel", "arch", "download-oc", "verify-binaries", "release-signing-key",
+		"oc-path", "ccoctl-path",
+		"cluster-name", "name-suffix", "aws-profile", "assume-role-arn", "mfa-serial", "pull-secret", "ocm-token",
+		"private-bucket", "iam-role", "existing-oidc-arn", "issuer-url", "oidc-signing-key",
+		"permissions-boundary-arn", "resource-prefix", "iam-role-path",
+		"instance-type", "worker-type", "worker-replicas", "zones", "tag", "force-unlock",
+	} {
+		hostedClusterCreateCmd.Flags().AddFlag(installCmd.Flags().Lookup(name))
+	}
+}
+
+func runHostedClusterCreate(cmd *cobra.Command, args []string) {
+	started := time.Now()
+	out := redirectOutput()
+	log := logger.New(logger.Level(getLogLevel()), nil)
+	summary := errors.NewSummary()
+
+	cfg := loadConfig(log)
+	cfg.MergeFrom(&config.Config{
+		AwsRegion:  hostedAwsRegion,
+		BaseDomain: hostedBaseDomain,
+		Hosted: config.HostedConfig{
+			ManagementKubeconfig: hostedManagementKubeconfig,
+			Namespace:            hostedNamespace,
+			RoleARN:              hostedRoleARN,
+		},
+	}, config.SourceFlag)
+	applyNameSuffix(log, cfg)
+
+	if cfg.ResolvesRelease() {
+		if err := resolveReleaseImage(log, cfg); err != nil {
+			log.Error(fmt.Sprintf("Failed to resolve release image: %v", err))
+			exit(exitConfigError)
+		}
+	}
+	if err := config.ValidateHostedConfig(cfg); err != nil {
+		log.Error(fmt.Sprintf("Configuration error: %v", err))
+		exit(exitConfigError)
+	}
+	checkPrerequisites(log, cfg)
+	if _, err := exec.LookPath("hcp"); err != nil {
+		log.Error("hcp not found in PATH: install the HyperShift CLI, downloadable from the console of the management cluster")
+		exit(exitConfigError)
+	}
+
+	lock := lockCluster(log, cfg.ClusterName)
+	defer lock.Unlock()
+
+	useVaultAWSCredentials(log, cfg)
+	vaultFiles := loadVaultFiles(log, cfg)
+	defer removeFiles(vaultFiles)
+	validateAWSCredentials(log, cfg.AwsProfile)
+	assumeRole(log, cfg)
+
+	if !util.FileExists(cfg.PullSecretPath) {
+		if tempFile := handleMissingPullSecret(log, cfg); tempFile != "" {
+			defer os.Remove(tempFile)
+		}
+	}
+	if err := config.ValidatePullSecret(cfg.PullSecretPath); err != nil {
+		log.Error(fmt.Sprintf("Pull secret validation failed: %v", err))
+		exit(exitConfigError)
+	}
+	recordHostedCluster(log, cfg)
+
+	// Ctrl-C stops waiting; the HostedCluster goes on being created
+	ctx, stop := interruptContext()
+	defer stop()
+
+	hostedSteps, err := steps.NewHostedClusterSteps(cfg, log, logCommands(log, &util.RealExecutor{Context: ctx}))
+	if err != nil {
+		log.Error(err.Error())
+		exit(1)
+	}
+
+	for i, step := range hostedSteps {
+		label := fmt.Sprintf("[Hosted %d/%d] %s", i+1, len(hostedSteps), step.Name())
+		log.StartStep(label)
+		stepStarted := time.Now()
+		err := step.Execute()
+		summary.AddStep("", label, time.Since(stepStarted), err)
+		if err != nil {
+			log.FailStep(label)
+			log.Error(err.Error())
+			runFailureHooks(log, cfg, label, err)
+			break
+		}
+		log.CompleteStep(label)
+	}
+
+	if !summary.HasErrors() {
+		log.Info(fmt.Sprintf("Access the hosted cluster with: export KUBECONFIG=%s", util.GetKubeconfigPath(cfg.ClusterName)))
+	}
+	notify(log, cfg, "hosted-cluster create", cfg.ClusterName, summary, started)
+	printSummary(out, summary)
+	if summary.HasErrors() {
+		exit(1)
+	}
+}
+
+// recordHostedCluster saves the installation metadata of the hosted cluster
+// before any AWS resource is created, so that cleanup finds the IAM roles and
+// OIDC provider of ccoctl
+func recordHostedCluster(log *logger.Logger, cfg *config.Config) {
+	clusterDir := util.GetClusterPath(cfg.ClusterName, "")
+	if err := util.EnsureDir(clusterDir); err != nil {
+		log.Error(fmt.Sprintf("Failed to create %s: %v", clusterDir, err))
+		exit(1)
+	}
+	if err := util.SaveInstallMetadata(clusterDir, cfg.ReleaseImage, cfg.ReleaseDigest); err != nil {
+		log.Debug(fmt.Sprintf("Could not save install metadata: %v", err))
+		return
+	}
+	if cfg.ResourcePrefix != "" {
+		if err := util.RecordResourcePrefix(clusterDir, cfg.ResourcePrefix); err != nil {
+			log.Debug(fmt.Sprintf("Could not record the resource prefix: %v", err))
+		}
+	}
+	// Cleanup must not delete roles it didn't create
+	if cfg.ExternalIAM() {
+		if err := util.RecordExternalIAM(clusterDir); err != nil {
+			log.Debug(fmt.Sprintf("Could not record the existing IAM roles: %v", err))
+		}
+	}
+}
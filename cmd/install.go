@@ -2,27 +2,87 @@ package cmd
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"sort"
 	"strings"
+	"time"
 
-	"github.com/spf13/cobra"
 	"github.com/clobrano/openshift-sts-wrapper/pkg/config"
-	"github.com/clobrano/openshift-sts-wrapper/pkg/errors"
 	"github.com/clobrano/openshift-sts-wrapper/pkg/logger"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/preflight"
 	"github.com/clobrano/openshift-sts-wrapper/pkg/steps"
 	"github.com/clobrano/openshift-sts-wrapper/pkg/util"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/wrapper"
+	"github.com/spf13/cobra"
 )
 
 var (
-	releaseImage    string
-	clusterName     string
-	awsProfile      string
-	pullSecretPath  string
-	privateBucket   bool
-	startFromStep   int
-	confirmEachStep bool
-	instanceType    string
+	releaseImage         string
+	clusterName          string
+	awsProfile           string
+	assumeRoleARN        string
+	mfaSerial            string
+	pullSecretPath       string
+	ocmToken             string
+	privateBucket        bool
+	iamRoles             map[string]string
+	oidcProviderARN      string
+	oidcIssuerURL        string
+	oidcSigningKey       string
+	permissionsBoundary  string
+	iamRolePath          string
+	resourcePrefix       string
+	startFromStep        string
+	stopAfterStep        string
+	onlyStep             string
+	skipSteps            []string
+	confirmEachStep      bool
+	nonInteractive       bool
+	instanceType         string
+	controlPlaneType     string
+	workerType           string
+	amiID                string
+	controlPlaneAMIID    string
+	workerAMIID          string
+	controlPlaneReplicas int
+	workerReplicas       int
+	installTimeout       string
+	releaseVersion       string
+	releaseChannel       string
+	releaseArch          string
+	subnets              []string
+	privateCluster       bool
+	userTags             map[string]string
+	zones                []string
+	maxMonthlyCost       float64
+	healthGateTimeout    string
+	installJUnit         string
+	ciMode               bool
+	recordPath           string
+	verifyBinaries       bool
+	cleanupOnFailure     bool
+	expiresIn            string
+	downloadOC           bool
+	releaseSigningKey    string
+	ocPath               string
+	openshiftInstallPath string
+	ccoctlPath           string
+	mirrorRegistry       string
+	mirrorCABundle       string
+	awsPartition         string
+	extraManifestsDir    string
+	postInstallManifests string
+	ntpServers           []string
+	kernelArguments      []string
+	insecureRegistries   []string
+	serviceEndpoints     map[string]string
+	replayPath           string
+	emitScriptPath       string
+	nameSuffix           string
+	installInteractive   bool
 )
 
 var installCmd = &cobra.Command{
@@ -35,58 +95,176 @@ var installCmd = &cobra.Command{
 func init() {
 	rootCmd.AddCommand(installCmd)
 
-	installCmd.Flags().StringVar(&releaseImage, "release-image", "", "OpenShift release image URL (required)")
+	installCmd.Flags().StringVar(&releaseImage, "release-image", "", "OpenShift release image URL (required unless --version or --channel is set)")
+	installCmd.Flags().StringVar(&releaseVersion, "version", "", "OpenShift version to install (e.g. 4.15.12), resolved to a release image via the update service")
+	installCmd.Flags().StringVar(&releaseChannel, "channel", "", "Update channel (e.g. stable-4.15); without --version the latest release in the channel is used")
+	installCmd.Flags().BoolVar(&downloadOC, "download-oc", false, "Download the oc client of the release into artifacts/shared/bin when oc is missing or too old, without asking")
+	installCmd.Flags().BoolVar(&verifyBinaries, "verify-binaries", false, "Verify that openshift-install and ccoctl come from the release image (by its digest)")
+	installCmd.Flags().StringVar(&releaseSigningKey, "release-signing-key", "", "GPG public key verifying the release image signature before extracting binaries (e.g. the Red Hat release key)")
+	installCmd.Flags().StringVar(&ocPath, "oc-path", "", "oc binary to use instead of the one in PATH (must be named oc)")
+	installCmd.Flags().StringVar(&openshiftInstallPath, "openshift-install-path", "", "openshift-install binary of the release to use instead of extracting it (skips Step 2)")
+	installCmd.Flags().StringVar(&ccoctlPath, "ccoctl-path", "", "ccoctl binary of the release to use instead of extracting it (skips Step 3)")
+	installCmd.Flags().StringVar(&mirrorRegistry, "mirror-registry", "", "Registry the release and the operators were mirrored to with 'mirror' (e.g. registry.example.com:5000/ocp), for disconnected installs")
+	installCmd.Flags().StringVar(&mirrorCABundle, "mirror-ca-bundle", "", "CA bundle of the mirror registry, trusted by the nodes")
+	installCmd.Flags().StringVar(&releaseArch, "arch", "", "Release architecture used with --version/--channel: x86_64, aarch64 or multi (default: host architecture)")
 	installCmd.Flags().StringVar(&clusterName, "cluster-name", "", "Cluster name (required)")
+	installCmd.Flags().StringVar(&nameSuffix, "name-suffix", "", "random: append a random suffix to the cluster name, so that installs from the same config don't collide (none: no suffix)")
 	installCmd.Flags().StringVar(&awsProfile, "aws-profile", "", "AWS profile name (default: default)")
+	installCmd.Flags().StringVar(&assumeRoleARN, "assume-role-arn", "", "IAM role assumed for the installation (openshift-install, ccoctl and AWS calls)")
+	installCmd.Flags().StringVar(&mfaSerial, "mfa-serial", "", "ARN of the MFA device required to assume the role (prompts for the code)")
 	installCmd.Flags().StringVar(&pullSecretPath, "pull-secret", "", "Path to pull secret file")
+	installCmd.Flags().StringVar(&ocmToken, "ocm-token", "", "Offline OCM token used to download the pull secret when the file is missing (https://console.redhat.com/openshift/token)")
 	installCmd.Flags().BoolVar(&privateBucket, "private-bucket", false, "Use private S3 bucket with CloudFront")
-	installCmd.Flags().IntVar(&startFromStep, "start-from-step", 0, "Start from specific step number")
+	installCmd.Flags().StringToStringVar(&iamRoles, "iam-role", nil, "Existing IAM role of a CredentialsRequest (namespace/name=arn, repeatable): Step 7 creates no AWS resource")
+	installCmd.Flags().StringVar(&oidcProviderARN, "existing-oidc-arn", "", "ARN of an existing OIDC provider, shared across clusters: Step 7 only creates the IAM roles of the cluster")
+	installCmd.Flags().StringVar(&oidcIssuerURL, "issuer-url", "", "Issuer URL of the existing OIDC provider (with --iam-role or --existing-oidc-arn)")
+	installCmd.Flags().StringVar(&permissionsBoundary, "permissions-boundary-arn", "", "Permissions boundary policy of the IAM roles created in Step 7")
+	installCmd.Flags().StringVar(&resourcePrefix, "resource-prefix", "", "Name of the IAM roles, OIDC provider and S3 bucket created by ccoctl (default: the cluster name)")
+	installCmd.Flags().StringVar(&iamRolePath, "iam-role-path", "", "IAM path of the IAM roles created in Step 7 (e.g. /openshift/)")
+	installCmd.Flags().StringVar(&oidcSigningKey, "oidc-signing-key", "", "Service account signing key of the existing OIDC provider (with --iam-role or --existing-oidc-arn)")
+	installCmd.Flags().StringVar(&extraManifestsDir, "extra-manifests-dir", "", "Directory of YAML manifests copied into the installer manifests after Step 8 (openshift/ subdirectory for the openshift/ directory)")
+	installCmd.Flags().StringVar(&postInstallManifests, "post-install-manifests-dir", "", "Directory of YAML manifests applied with 'oc apply' once the cluster is deployed")
+	installCmd.Flags().StringSliceVar(&ntpServers, "ntp-server", nil, "NTP server of the nodes, rendered into chrony MachineConfigs (repeatable)")
+	installCmd.Flags().StringArrayVar(&kernelArguments, "kernel-arg", nil, "Kernel argument of the nodes, rendered into MachineConfigs (repeatable)")
+	installCmd.Flags().StringSliceVar(&insecureRegistries, "insecure-registry", nil, "Registry the nodes pull from without TLS verification, rendered into MachineConfigs (repeatable)")
+	installCmd.Flags().StringVar(&awsPartition, "aws-partition", "", "AWS partition of the region (default derived from the region: aws, aws-us-gov, aws-cn...)")
+	installCmd.Flags().StringToStringVar(&serviceEndpoints, "service-endpoint", nil, "Custom AWS service endpoint (service=https://url, repeatable, e.g. ec2=https://ec2.us-gov-west-1.amazonaws.com)")
+	installCmd.Flags().StringVar(&startFromStep, "start-from-step", "", "Start from a specific step, by name (e.g. create-manifests) or number")
+	installCmd.Flags().StringVar(&stopAfterStep, "stop-after-step", "", "Stop after a specific step, by name (e.g. create-install-config) or number")
+	installCmd.Flags().StringVar(&onlyStep, "only-step", "", "Run a single step, by name (e.g. verify) or number, even if it looks completed")
+	installCmd.Flags().StringSliceVar(&skipSteps, "skip-steps", nil, "Steps to omit, by name (comma-separated, e.g. create-install-config,verify)")
+	installCmd.Flags().BoolVar(&forceUnlock, "force-unlock", false, "Remove the lock of a previous run against the cluster that is hung or stale")
 	installCmd.Flags().BoolVar(&confirmEachStep, "confirm-each-step", false, "Prompt for confirmation before executing each step")
-	installCmd.Flags().StringVar(&instanceType, "instance-type", "m5.4xlarge", "AWS instance type for controlPlane and compute pools")
+	installCmd.Flags().BoolVarP(&installInteractive, "interactive", "i", false, "Prompt for every missing setting, with pickers for the region, base domain and instance type, review them and offer to save them to the config file")
+	installCmd.Flags().BoolVar(&nonInteractive, "non-interactive", false, "Never prompt: use the saved configuration at Step 4, failing if it is incomplete")
+	installCmd.Flags().BoolVar(&ciMode, "ci", false, "Run in a pipeline: --non-interactive, JSON summary (unless --output is set), no colors or redrawn lines, and an exit code per failure class")
+	installCmd.Flags().StringVar(&instanceType, "instance-type", "", "AWS instance type for controlPlane and compute pools (default: m5.4xlarge, m6g.4xlarge for arm64 releases)")
+	installCmd.Flags().StringVar(&controlPlaneType, "control-plane-type", "", "AWS instance type for the controlPlane pool (default: --instance-type)")
+	installCmd.Flags().StringVar(&workerType, "worker-type", "", "AWS instance type for the compute pool (default: --instance-type)")
+	installCmd.Flags().StringVar(&amiID, "ami-id", "", "RHCOS AMI every machine boots from (default: the image of the release)")
+	installCmd.Flags().StringVar(&controlPlaneAMIID, "control-plane-ami-id", "", "RHCOS AMI of the controlPlane pool (default: --ami-id)")
+	installCmd.Flags().StringVar(&workerAMIID, "worker-ami-id", "", "RHCOS AMI of the compute pool (default: --ami-id)")
+	installCmd.Flags().IntVar(&controlPlaneReplicas, "control-plane-replicas", -1, "Number of control plane machines (default: 3)")
+	installCmd.Flags().IntVar(&workerReplicas, "worker-replicas", -1, "Number of compute machines (default: 3)")
+	installCmd.Flags().StringSliceVar(&subnets, "subnets", nil, "Existing subnet IDs to install into (comma-separated)")
+	installCmd.Flags().BoolVar(&privateCluster, "private", false, "Install a private cluster (publish: Internal) into existing private subnets")
+	installCmd.Flags().StringSliceVar(&zones, "zones", nil, "Availability zones for the control plane and compute pools (comma-separated)")
+	installCmd.Flags().Float64Var(&maxMonthlyCost, "max-monthly-cost", 0, "Refuse to deploy a cluster whose estimated cost exceeds this budget (USD per month)")
+	installCmd.Flags().StringToStringVar(&userTags, "tag", nil, "AWS tag applied to every created resource (key=value, repeatable)")
+	installCmd.Flags().StringVar(&healthGateTimeout, "health-gate-timeout", "", "Make Step 11 wait up to this for the cluster operators to be available and not degraded (e.g. 30m)")
+	installCmd.Flags().StringVar(&installJUnit, "junit", "", "Write the results of the Step 11 checks to this file as JUnit XML")
+	installCmd.Flags().StringVar(&recordPath, "record", "", "Record every external command and its output to this fixtures file")
+	installCmd.Flags().StringVar(&replayPath, "replay", "", "Serve the external commands from a fixtures file written by --record instead of running them")
+	installCmd.Flags().StringVar(&emitScriptPath, "emit-script", "", "Write the commands of the installation to this bash script for review, instead of running them")
+	installCmd.Flags().BoolVar(&cleanupOnFailure, "cleanup-on-failure", false, "When a step fails from Step 7 on, delete the AWS resources created by the installation (ccoctl resources, infrastructure)")
+	installCmd.Flags().StringVar(&expiresIn, "expires-in", "", "Lifetime of the cluster (e.g. 8h): a cleanup is scheduled at its expiry with systemd-run or at")
+	installCmd.Flags().StringVar(&installTimeout, "timeout", "", "Overall installation timeout (e.g. 3h); per-step timeouts are set via stepTimeouts in the config file")
+
+	// config explain resolves the configuration like install, so it accepts the same flags
+	configExplainCmd.Flags().AddFlagSet(installCmd.Flags())
+	// and hosted-cluster create shares most of them
+	addSharedInstallFlags()
 }
 
 func runInstall(cmd *cobra.Command, args []string) {
+	started := time.Now()
+	applyCIMode(cmd)
+	out := redirectOutput()
+
 	// Create logger
 	log := logger.New(logger.Level(getLogLevel()), nil)
+	log.SetPlain(ciMode)
 
-	// Check prerequisites
-	if err := config.CheckPrerequisites(); err != nil {
-		log.Error(fmt.Sprintf("Prerequisite check failed: %v", err))
-		os.Exit(1)
+	// Record or replay the external commands, logging the ones run in verbose mode
+	fixtures := fixturesExecutor(log)
+	wrapExecutor := func(executor util.CommandExecutor) util.CommandExecutor {
+		return fixtures(logCommands(log, executor))
 	}
 
 	// Load configuration with priority: flags > file > env > prompts
 	cfg := loadConfig(log)
+	if installInteractive {
+		runInstallWizard(log, cfg, wrapExecutor(&util.RealExecutor{}))
+	}
+	applyNameSuffix(log, cfg)
+
+	// Resolve the release image from a version number or channel
+	if cfg.ResolvesRelease() {
+		if err := resolveReleaseImage(log, cfg); err != nil {
+			log.Error(fmt.Sprintf("Failed to resolve release image: %v", err))
+			exit(exitConfigError)
+		}
+		// The default instance type follows the architecture of the release
+		cfg.SetDefaults()
+	}
 
 	// Validate configuration
 	if err := config.ValidateConfig(cfg); err != nil {
 		log.Error(fmt.Sprintf("Configuration error: %v", err))
-		os.Exit(1)
+		exit(exitConfigError)
+	}
+	if emitScriptPath != "" {
+		emitInstallScript(log, cfg)
+		return
 	}
+	if replayPath == "" {
+		// Check prerequisites, which a replay doesn't run
+		checkPrerequisites(log, cfg)
+	}
+	if cfg.ConfirmEachStep && nonInteractive {
+		log.Error("Configuration error: --confirm-each-step prompts before each step, it can't be used with --non-interactive or --ci")
+		exit(exitConfigError)
+	}
+
+	// Prevent concurrent runs against the same cluster
+	lock := lockCluster(log, cfg.ClusterName)
+	defer lock.Unlock()
+
+	// Read secrets stored in Vault
+	useVaultAWSCredentials(log, cfg)
+	vaultFiles := loadVaultFiles(log, cfg)
+	defer removeFiles(vaultFiles)
 
 	// Validate AWS credentials
-	log.Info(fmt.Sprintf("Validating AWS credentials for profile '%s'...", cfg.AwsProfile))
-	if err := util.ValidateAWSCredentials(cfg.AwsProfile); err != nil {
-		log.Error(fmt.Sprintf("AWS credential validation failed: %v", err))
-		os.Exit(1)
+	if replayPath == "" {
+		validateAWSCredentials(log, cfg.AwsProfile)
+		assumeRole(log, cfg)
 	}
-	log.Info("✓ AWS credentials are valid")
 
 	// Verify pull secret
 	if !util.FileExists(cfg.PullSecretPath) {
-		handleMissingPullSecret(log, cfg)
+		if tempFile := handleMissingPullSecret(log, cfg); tempFile != "" {
+			defer os.Remove(tempFile)
+		}
 	}
 
 	// Validate pull secret format
 	if err := config.ValidatePullSecret(cfg.PullSecretPath); err != nil {
 		log.Error(fmt.Sprintf("Pull secret validation failed: %v", err))
 		log.Info("Please ensure the pull secret is valid JSON format")
-		os.Exit(1)
+		exit(exitConfigError)
+	}
+
+	// Run preflight checks against the AWS account
+	var cost preflight.CostEstimate
+	if checks := preflightChecks(cfg, wrapExecutor(&util.RealExecutor{}), &cost); len(checks) > 0 {
+		log.Info("Running preflight checks...")
+		if err := preflight.RunChecks(log, checks); err != nil {
+			log.Error(err.Error())
+			exit(preflightExitCode(err))
+		}
+	}
+	if len(cost.Items) > 0 {
+		log.Info(fmt.Sprintf("Estimated cluster cost (on-demand): %s", &cost))
+		for _, item := range cost.Items {
+			log.Debug(fmt.Sprintf("  %s: $%.2f/month", item.Description, item.Monthly()))
+		}
 	}
 
-	// Check if cluster directory already exists
+	// Check if cluster directory already exists, unless resuming a previous run
+	// or skipping Step 4 to use an install-config.yaml provided in it
 	clusterDir := util.GetClusterPath(cfg.ClusterName, "")
-	if util.DirExists(clusterDir) {
+	if cfg.StartFromStep == 0 && cfg.OnlyStep == 0 && !cfg.StepSkipped(4) && util.DirExists(clusterDir) {
 		log.Error(fmt.Sprintf("Cluster directory already exists: %s", clusterDir))
 		log.Error(fmt.Sprintf("A cluster with name '%s' appears to already exist or was previously installed", cfg.ClusterName))
 		log.Info("")
@@ -94,15 +272,19 @@ func runInstall(cmd *cobra.Command, args []string) {
 		log.Info("  1. Use a different cluster name: --cluster-name=<new-name>")
 		log.Info("  2. Clean up the existing cluster first:")
 		log.Info("     openshift-sts-wrapper cleanup --help")
-		os.Exit(1)
+		exit(exitConfigError)
 	}
 
 	// Check configuration and get user's decision on interactive mode
 	// Only do this if we'll be executing Step 4 (not resuming from a later step)
-	if cfg.StartFromStep <= 4 {
+	if cfg.StepSelected(4) {
+		ensureSSHKey(log, cfg)
 		complete, missing := cfg.HasCompleteInstallConfigData()
 
-		if complete {
+		if complete && installInteractive {
+			// Already reviewed in the wizard
+			cfg.UseInteractiveMode = false
+		} else if complete {
 			// Show saved configuration
 			log.Info("")
 			log.Info("Found saved configuration:")
@@ -114,10 +296,13 @@ func runInstall(cmd *cobra.Command, args []string) {
 			log.Info("")
 
 			// Prompt to reuse configuration
-			reader := bufio.NewReader(os.Stdin)
-			fmt.Print("Reuse this configuration? [y/N]: ")
-			response, _ := reader.ReadString('\n')
-			response = strings.TrimSpace(strings.ToLower(response))
+			response := "y"
+			if !nonInteractive {
+				reader := bufio.NewReader(os.Stdin)
+				fmt.Print("Reuse this configuration? [y/N]: ")
+				response, _ = reader.ReadString('\n')
+				response = strings.TrimSpace(strings.ToLower(response))
+			}
 
 			if response == "y" || response == "yes" {
 				cfg.UseInteractiveMode = false
@@ -129,6 +314,10 @@ func runInstall(cmd *cobra.Command, args []string) {
 			log.Info("")
 		} else {
 			// Configuration incomplete - must use interactive mode
+			if nonInteractive {
+				log.Error(fmt.Sprintf("Configuration incomplete for a non-interactive install, missing: %s", strings.Join(missing, ", ")))
+				exit(exitConfigError)
+			}
 			log.Info("")
 			log.Info("⚠  Missing configuration fields:")
 			for _, field := range missing {
@@ -147,126 +336,113 @@ func runInstall(cmd *cobra.Command, args []string) {
 
 			if response == "n" || response == "no" {
 				log.Info("Installation cancelled.")
-				os.Exit(0)
+				exit(0)
 			}
 			log.Info("")
 		}
 	}
 
-	// Create command executor
-	executor := &util.RealExecutor{}
-
-	// Create step detector
-	detector := steps.NewDetector(cfg)
-
-	// Create error summary
-	summary := errors.NewSummary()
-
-	// Execute all steps
-	allSteps := []struct {
-		num     int
-		factory func(*config.Config, *logger.Logger, util.CommandExecutor) (steps.Step, error)
-	}{
-		{1, func(c *config.Config, l *logger.Logger, e util.CommandExecutor) (steps.Step, error) {
-			return steps.NewStep1(c, l, e)
-		}},
-		{2, func(c *config.Config, l *logger.Logger, e util.CommandExecutor) (steps.Step, error) {
-			return steps.NewStep2(c, l, e)
-		}},
-		{3, func(c *config.Config, l *logger.Logger, e util.CommandExecutor) (steps.Step, error) {
-			return steps.NewStep3(c, l, e)
-		}},
-		{4, func(c *config.Config, l *logger.Logger, e util.CommandExecutor) (steps.Step, error) {
-			return steps.NewStep4(c, l, e)
-		}},
-		{5, func(c *config.Config, l *logger.Logger, e util.CommandExecutor) (steps.Step, error) {
-			return steps.NewStep5(c, l, e)
-		}},
-		{6, func(c *config.Config, l *logger.Logger, e util.CommandExecutor) (steps.Step, error) {
-			return steps.NewStep6(c, l, e)
-		}},
-		{7, func(c *config.Config, l *logger.Logger, e util.CommandExecutor) (steps.Step, error) {
-			return steps.NewStep7(c, l, e)
-		}},
-		{8, func(c *config.Config, l *logger.Logger, e util.CommandExecutor) (steps.Step, error) {
-			return steps.NewStep8(c, l, e)
-		}},
-		{9, func(c *config.Config, l *logger.Logger, e util.CommandExecutor) (steps.Step, error) {
-			return steps.NewStep9(c, l, e)
-		}},
-		{10, func(c *config.Config, l *logger.Logger, e util.CommandExecutor) (steps.Step, error) {
-			return steps.NewStep10(c, l, e)
-		}},
-		{11, func(c *config.Config, l *logger.Logger, e util.CommandExecutor) (steps.Step, error) {
-			return steps.NewStep11(c, l, e)
-		}},
-	}
-
-	for _, stepDef := range allSteps {
-		// Create step to get its name
-		step, err := stepDef.factory(cfg, log, executor)
-		if err != nil {
-			log.Error(fmt.Sprintf("Failed to create step: %v", err))
-			summary.AddError(fmt.Sprintf("Step %d", stepDef.num), err)
-			continue
-		}
-
-		if detector.ShouldSkipStep(stepDef.num) {
-			log.Info(fmt.Sprintf("⏭  Skipping [Step %d] %s (already completed)", stepDef.num, step.Name()))
-			continue
-		}
+	// Without Step 4, the steps that edit and consume install-config.yaml need
+	// one provided by the user
+	installConfigPath := util.GetInstallConfigPath("", cfg.ClusterName)
+	if cfg.StepSkipped(4) && (cfg.StepSelected(5) || cfg.StepSelected(6)) && !util.FileExists(installConfigPath) {
+		log.Error(fmt.Sprintf("Step %s is skipped but %s does not exist", config.StepName(4), installConfigPath))
+		log.Info("Copy your install-config.yaml there, or don't skip the step")
+		exit(exitConfigError)
+	}
 
-		// Optionally confirm before executing the step
-		if cfg.ConfirmEachStep {
-			if !confirm(fmt.Sprintf("Proceed with [Step %d] %s? [y/N] ", stepDef.num, step.Name())) {
-				log.Info(fmt.Sprintf("⏭  Skipping [Step %d] %s (user choice)", stepDef.num, step.Name()))
-				continue
+	// Trace the step pipeline, if an OTLP collector is configured
+	tracer, rootSpan := startTrace(cfg)
+
+	// Run the installation, reacting to the steps as they complete
+	events := make(chan wrapper.Event)
+	handled := make(chan struct{})
+	go func() {
+		defer close(handled)
+		for event := range events {
+			switch {
+			case event.Type == wrapper.EventStepFailed && !errors.Is(event.Err, util.ErrInterrupted):
+				runFailureHooks(log, cfg, event.Label, event.Err)
+			case event.Type == wrapper.EventStepSucceeded && event.Number == 10:
+				desktopNotify(log, cfg, "Cluster deployed", fmt.Sprintf("Cluster %s is up, verifying the installation", cfg.ClusterName))
 			}
 		}
-
-		log.StartStep(fmt.Sprintf("[Step %d] %s", stepDef.num, step.Name()))
-
-		if err := step.Execute(); err != nil {
-			log.FailStep(fmt.Sprintf("[Step %d] %s", stepDef.num, step.Name()))
-			summary.AddError(fmt.Sprintf("[Step %d] %s", stepDef.num, step.Name()), err)
-			break
-		} else {
-			log.CompleteStep(fmt.Sprintf("[Step %d] %s", stepDef.num, step.Name()))
-			summary.AddSuccess(fmt.Sprintf("[Step %d] %s", stepDef.num, step.Name()))
-
-			// After Step 1, save installation metadata for cleanup purposes
-			if stepDef.num == 1 {
-				clusterDir := util.GetClusterPath(cfg.ClusterName, "")
-				if err := util.SaveInstallMetadata(clusterDir, cfg.ReleaseImage); err != nil {
-					log.Debug(fmt.Sprintf("Could not save install metadata: %v", err))
-				} else {
-					log.Debug(fmt.Sprintf("Saved installation metadata to %s/install-metadata.json", clusterDir))
-				}
+	}()
+	installer := &wrapper.Installer{
+		Config:       cfg,
+		Log:          log,
+		Events:       events,
+		Trace:        rootSpan,
+		WrapExecutor: wrapExecutor,
+		Confirm: func(label string) bool {
+			if len(cost.Items) > 0 && strings.HasPrefix(label, "[Step 10]") {
+				return confirm(fmt.Sprintf("Proceed with %s (estimated cost: %s)? [y/N] ", label, &cost))
 			}
-
-			// After Step 5, backup install-config.yaml before Step 6 consumes it
-			if stepDef.num == 5 {
-				versionArch, err := util.ExtractVersionArch(cfg.ReleaseImage)
-				if err == nil {
-					installConfigPath := util.GetInstallConfigPath(versionArch, cfg.ClusterName)
-					if util.FileExists(installConfigPath) {
-						backupPath := installConfigPath + ".backup"
-						if err := util.CopyFile(installConfigPath, backupPath); err != nil {
-							log.Debug(fmt.Sprintf("Could not backup install-config.yaml: %v", err))
-						} else {
-							log.Debug(fmt.Sprintf("Backed up install-config.yaml to %s", backupPath))
-						}
-					}
-				}
+			return confirm(fmt.Sprintf("Proceed with %s? [y/N] ", label))
+		},
+		ConfirmReleaseChange: func(previous, current string) bool {
+			log.Info(fmt.Sprintf("⚠  Earlier steps of cluster %s used release %s", cfg.ClusterName, previous))
+			return !nonInteractive && confirm(fmt.Sprintf("Continue with release %s? [y/N] ", current))
+		},
+	}
+	// Ctrl-C interrupts the running commands and stops the installation
+	ctx, stop := interruptContext()
+	defer stop()
+	result, err := installer.Run(ctx)
+	stop()
+	close(events)
+	<-handled
+	if result == nil {
+		log.Error(err.Error())
+		var releaseChanged *util.ReleaseChangedError
+		if errors.As(err, &releaseChanged) {
+			log.Info("Options:")
+			if releaseChanged.PreviousImage != releaseChanged.CurrentImage {
+				log.Info(fmt.Sprintf("  - Resume with the release of the earlier steps: --release-image %s", releaseChanged.PreviousImage))
 			}
+			log.Info("  - Clean up the cluster, then install it again: openshift-sts-wrapper cleanup --help")
+			exit(exitConfigError)
 		}
+		exit(1)
+	}
+	summary := result.Summary
+	if result.Interrupted {
+		restoreTerminal()
+		reportInterrupted(log, cfg, result.Journal)
+	}
+
+	if installJUnit != "" {
+		writeVerifyJUnit(log, result.Verification, installJUnit)
+	}
+
+	// Day-1 configuration of a newly deployed cluster
+	if result.Deployed && !summary.HasErrors() {
+		runPostInstall(log, cfg, summary)
+	}
+	rolledBack := false
+	if summary.HasErrors() && !result.Interrupted {
+		rolledBack = rollbackFailedInstall(log, wrapExecutor(&util.RealExecutor{}), cfg, summary, result.Journal)
+	}
+	if !rolledBack && !result.Interrupted && replayPath == "" {
+		scheduleExpiry(log, cfg, started, result.Journal)
 	}
 
 	// Print summary
-	fmt.Println(summary.String())
+	exportTrace(log, cfg, tracer, rootSpan, summary)
+	versionArch, _ := util.ExtractVersionArch(cfg.ReleaseImage)
+	publishMetrics(log, cfg, result.Journal, versionArch, summary, started)
+	notify(log, cfg, "install", cfg.ClusterName, summary, started)
+	if summary.HasErrors() && !result.Interrupted {
+		desktopNotify(log, cfg, "Installation failed", fmt.Sprintf("%s of cluster %s failed", summary.Failed[0].StepName, cfg.ClusterName))
+	}
+	printSummary(out, summary)
 
+	if result.Interrupted {
+		offerRollback(log, cfg, result.Journal, lock)
+		exit(exitInterrupted)
+	}
 	if summary.HasErrors() {
-		os.Exit(1)
+		exit(installExitCode(cfg, summary))
 	}
 }
 
@@ -274,45 +450,428 @@ func loadConfig(log *logger.Logger) *config.Config {
 	cfg := &config.Config{}
 
 	// 1. Load from environment variables
-	envCfg := config.LoadFromEnv()
-	cfg.Merge(envCfg)
-
-	// 2. Load from file
-	configFile := cfgFile
-	if configFile == "" {
-		configFile = "openshift-sts-wrapper.yaml"
-	}
-	if util.FileExists(configFile) {
-		fileCfg, err := config.LoadFromFile(configFile)
-		if err != nil {
-			log.Debug(fmt.Sprintf("Could not load config file: %v", err))
-		} else {
-			cfg.Merge(fileCfg)
+	if errs := config.EnvErrors(); len(errs) > 0 {
+		for _, err := range errs {
+			log.Error(fmt.Sprintf("Configuration error: %v", err))
 		}
+		exit(exitConfigError)
 	}
+	envCfg := config.LoadFromEnv()
+	cfg.MergeFrom(envCfg, config.SourceEnv)
+
+	// 2. Load from file, with the selected profile applied
+	cfg.MergeFrom(loadConfigFile(log), "file "+configFilePath())
 
 	// 3. Merge flags
 	flagCfg := &config.Config{
-		ReleaseImage:    releaseImage,
-		ClusterName:     clusterName,
-		AwsProfile:      awsProfile,
-		PullSecretPath:  pullSecretPath,
-		PrivateBucket:   privateBucket,
-		StartFromStep:   startFromStep,
-		ConfirmEachStep: confirmEachStep,
-		InstanceType:    instanceType,
+		ReleaseImage:            releaseImage,
+		ClusterName:             clusterName,
+		AwsProfile:              awsProfile,
+		AssumeRoleARN:           assumeRoleARN,
+		MFASerial:               mfaSerial,
+		PullSecretPath:          pullSecretPath,
+		OCMToken:                ocmToken,
+		PrivateBucket:           privateBucket,
+		IAMRoles:                iamRoles,
+		OIDCProviderARN:         oidcProviderARN,
+		OIDCIssuerURL:           oidcIssuerURL,
+		OIDCSigningKey:          oidcSigningKey,
+		PermissionsBoundary:     permissionsBoundary,
+		IAMRolePath:             iamRolePath,
+		ResourcePrefix:          resourcePrefix,
+		NameSuffix:              nameSuffix,
+		StartFromStep:           parseStepFlag(log, "start-from-step", startFromStep),
+		StopAfterStep:           parseStepFlag(log, "stop-after-step", stopAfterStep),
+		OnlyStep:                parseStepFlag(log, "only-step", onlyStep),
+		SkipSteps:               skipSteps,
+		ConfirmEachStep:         confirmEachStep,
+		InstanceType:            instanceType,
+		ControlPlaneType:        controlPlaneType,
+		WorkerType:              workerType,
+		AMIID:                   amiID,
+		ControlPlaneAMIID:       controlPlaneAMIID,
+		WorkerAMIID:             workerAMIID,
+		ControlPlaneReplicas:    optionalInt(controlPlaneReplicas),
+		WorkerReplicas:          optionalInt(workerReplicas),
+		InstallTimeout:          installTimeout,
+		HealthGateTimeout:       healthGateTimeout,
+		Version:                 releaseVersion,
+		Channel:                 releaseChannel,
+		Architecture:            releaseArch,
+		Subnets:                 subnets,
+		Private:                 privateCluster,
+		Tags:                    userTags,
+		Zones:                   zones,
+		MaxMonthlyCost:          maxMonthlyCost,
+		VerifyBinaries:          verifyBinaries,
+		CleanupOnFailure:        cleanupOnFailure,
+		ExpiresIn:               expiresIn,
+		ReleaseSigningKey:       releaseSigningKey,
+		AwsPartition:            awsPartition,
+		ExtraManifestsDir:       extraManifestsDir,
+		PostInstallManifestsDir: postInstallManifests,
+		NTPServers:              ntpServers,
+		KernelArguments:         kernelArguments,
+		InsecureRegistries:      insecureRegistries,
+		ServiceEndpoints:        serviceEndpointList(serviceEndpoints),
+		Binaries: config.BinariesConfig{
+			OC:               ocPath,
+			OpenshiftInstall: openshiftInstallPath,
+			Ccoctl:           ccoctlPath,
+		},
+		Mirror: config.MirrorConfig{
+			Registry: mirrorRegistry,
+			CABundle: mirrorCABundle,
+		},
 	}
-	cfg.Merge(flagCfg)
+	cfg.MergeFrom(flagCfg, config.SourceFlag)
 
 	// 4. Set defaults
 	cfg.SetDefaults()
+	useServiceEndpoints(cfg)
 
 	return cfg
 }
 
-func handleMissingPullSecret(log *logger.Logger, cfg *config.Config) {
+// applyNameSuffix appends a random suffix to the name of a new cluster, if
+// configured, and shows the name that resuming or cleaning up needs
+func applyNameSuffix(log *logger.Logger, cfg *config.Config) {
+	generated, err := cfg.ApplyNameSuffix()
+	if err != nil {
+		log.Error(err.Error())
+		exit(exitConfigError)
+	}
+	if !generated {
+		return
+	}
+	log.Info("")
+	log.Info(fmt.Sprintf(">>> Cluster name: %s <<<", cfg.ClusterName))
+	log.Info(fmt.Sprintf("Use --cluster-name=%s to resume, access or clean up this cluster", cfg.ClusterName))
+	log.Info("")
+}
+
+// parseStepFlag resolves a step flag given by name or number, exiting on
+// unknown steps (0 means the flag is not set)
+func parseStepFlag(log *logger.Logger, flag, value string) int {
+	if value == "" {
+		return 0
+	}
+	num, err := config.ParseStep(value)
+	if err != nil {
+		log.Error(fmt.Sprintf("Invalid --%s: %v", flag, err))
+		exit(exitConfigError)
+	}
+	return num
+}
+
+// serviceEndpointList converts the --service-endpoint flags to a list, sorted by service
+func serviceEndpointList(endpoints map[string]string) []config.ServiceEndpoint {
+	var list []config.ServiceEndpoint
+	for service, url := range endpoints {
+		list = append(list, config.ServiceEndpoint{Name: service, URL: url})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// optionalInt maps an unset integer flag (negative default) to nil
+func optionalInt(value int) *int {
+	if value < 0 {
+		return nil
+	}
+	return &value
+}
+
+// runFailureHooks runs the configured onFailure hooks, exposing the failed step
+// and error to the hook commands through environment variables
+func runFailureHooks(log *logger.Logger, cfg *config.Config, stepName string, stepErr error) {
+	if len(cfg.Hooks.OnFailure) == 0 {
+		return
+	}
+
+	log.Info("Running onFailure hooks...")
+	env := []string{
+		fmt.Sprintf("OPENSHIFT_STS_CLUSTER_NAME=%s", cfg.ClusterName),
+		fmt.Sprintf("OPENSHIFT_STS_FAILED_STEP=%s", stepName),
+		fmt.Sprintf("OPENSHIFT_STS_ERROR=%v", stepErr),
+	}
+	for _, err := range util.RunHooks(logCommands(log, &util.RealExecutor{}), cfg.Hooks.OnFailure, env) {
+		log.Error(fmt.Sprintf("Hook failed: %v", err))
+	}
+}
+
+// ensureSSHKey sets the SSH key of the nodes when none is configured: the
+// default key of the user, or else a key pair that Step 4 generates in the
+// cluster directory (after confirmation, unless running non-interactively)
+func ensureSSHKey(log *logger.Logger, cfg *config.Config) {
+	if cfg.SSHKeyPath != "" {
+		return
+	}
+	if path := util.FindDefaultSSHKey(); path != "" {
+		log.Info(fmt.Sprintf("Using SSH key %s", path))
+		cfg.SSHKeyPath = path
+		return
+	}
+
+	path := util.GetClusterPath(cfg.ClusterName, "ssh/id_ed25519.pub")
+	if !nonInteractive && !confirm(fmt.Sprintf("No SSH key found. Generate an ed25519 key pair in %s? [y/N] ", filepath.Dir(path))) {
+		return
+	}
+	cfg.SSHKeyPath = path
+	cfg.GenerateSSHKey = true
+}
+
+// checkPrerequisites exits unless the required tools are available. A missing or
+// too old oc client is replaced by the one of the release, downloaded into the
+// shared bin directory (after confirmation, unless --download-oc is set). A
+// configured oc is put first in PATH instead, and never replaced.
+func checkPrerequisites(log *logger.Logger, cfg *config.Config) {
+	if cfg.Binaries.OC != "" {
+		if err := util.UseOCBinary(cfg.Binaries.OC); err != nil {
+			log.Error(fmt.Sprintf("Prerequisite check failed: %v", err))
+			exit(exitConfigError)
+		}
+	} else if versionArch, err := util.ExtractVersionArch(cfg.ReleaseImage); err == nil {
+		// The oc client an earlier Step 2 extracted from the release, if any
+		if _, err := util.UseReleaseClients(versionArch, cfg.ReleaseDigest); err != nil {
+			log.Debug(fmt.Sprintf("Could not use the oc client of the release: %v", err))
+		}
+	}
+	err := config.CheckPrerequisites()
+	if err == nil {
+		return
+	}
+	log.Error(fmt.Sprintf("Prerequisite check failed: %v", err))
+	if !errors.Is(err, config.ErrOCUnusable) || cfg.Binaries.OC != "" {
+		exit(exitConfigError)
+	}
+
+	// Digest-only release images don't tell their version: use the latest stable client
+	version := ""
+	if !strings.Contains(cfg.ReleaseImage, "@") {
+		versionArch, _ := util.ExtractVersionArch(cfg.ReleaseImage)
+		version = util.ReleaseVersion(versionArch)
+	}
+	url := util.OCClientURL("", version)
+	if !downloadOC && (nonInteractive || !confirm(fmt.Sprintf("Download oc from %s into %s? [y/N] ", url, util.GetSharedBinDir()))) {
+		log.Info("Install the OpenShift CLI, or let the wrapper download it with --download-oc")
+		exit(exitConfigError)
+	}
+
+	log.Info(fmt.Sprintf("Downloading oc from %s...", url))
+	path, err := util.DownloadOC(url, util.GetSharedBinDir())
+	if err != nil {
+		log.Error(err.Error())
+		exit(exitConfigError)
+	}
+	if err := util.UseSharedBinaries(); err != nil {
+		log.Error(fmt.Sprintf("Failed to use %s: %v", path, err))
+		exit(exitConfigError)
+	}
+	if err := config.CheckPrerequisites(); err != nil {
+		log.Error(fmt.Sprintf("Prerequisite check failed with the downloaded oc: %v", err))
+		exit(exitConfigError)
+	}
+	log.Info(fmt.Sprintf("✓ Using %s", path))
+}
+
+// fixturesExecutor returns the wrapper of the executors recording the commands
+// with --record or replaying them with --replay (none without either flag)
+func fixturesExecutor(log *logger.Logger) func(util.CommandExecutor) util.CommandExecutor {
+	switch {
+	case recordPath != "" && replayPath != "":
+		log.Error("--record and --replay can't be used together")
+		exit(exitConfigError)
+	case recordPath != "":
+		log.Info(fmt.Sprintf("Recording the commands to %s", recordPath))
+		return util.NewRecorder(recordPath).Wrap
+	case replayPath != "":
+		fixtures, err := util.LoadFixtures(replayPath)
+		if err != nil {
+			log.Error(err.Error())
+			exit(exitConfigError)
+		}
+		log.Info(fmt.Sprintf("Replaying the commands of %s: no command is run, AWS credentials are not validated", replayPath))
+		return util.NewReplayer(fixtures).Wrap
+	}
+	return func(executor util.CommandExecutor) util.CommandExecutor { return executor }
+}
+
+// emitInstallScript writes the commands of the installation to the
+// --emit-script file. Nothing runs: neither the AWS credentials nor the
+// prerequisites are checked.
+func emitInstallScript(log *logger.Logger, cfg *config.Config) {
+	script, err := steps.InstallScript(cfg)
+	if err != nil {
+		log.Error(fmt.Sprintf("Failed to render the install script: %v", err))
+		exit(exitConfigError)
+	}
+	if err := os.WriteFile(emitScriptPath, []byte(script), 0755); err != nil {
+		log.Error(fmt.Sprintf("Failed to write the install script: %v", err))
+		exit(1)
+	}
+	log.Info(fmt.Sprintf("✓ Install script written to %s: review it, then run it with: bash %s", emitScriptPath, emitScriptPath))
+}
+
+// preflightChecks returns the preflight checks that apply to the configuration.
+// The cost estimate of the cluster is stored into cost.
+func preflightChecks(cfg *config.Config, executor util.CommandExecutor, cost *preflight.CostEstimate) []preflight.Check {
+	checks := []preflight.Check{
+		{
+			Name: "Instance type availability",
+			Run:  func() ([]string, error) { return preflight.CheckInstanceTypes(executor, cfg) },
+		},
+	}
+	// The quotas are only consumed when the cluster is going to be deployed
+	if cfg.StepSelected(10) {
+		checks = append(checks, preflight.Check{
+			Name: checkQuotas,
+			Run:  func() ([]string, error) { return preflight.CheckQuotas(executor, cfg) },
+		})
+	}
+	// DNS records are only missing until the cluster is deployed
+	if cfg.StepSelected(4) {
+		checks = append(checks, preflight.Check{
+			Name: checkBaseDomain,
+			Run:  func() ([]string, error) { return preflight.CheckBaseDomain(executor, cfg) },
+		})
+	}
+	// A missing AMI would only fail the deployment, after the AWS resources are created
+	if cfg.ControlPlaneAMI() != "" || cfg.WorkerAMI() != "" {
+		checks = append(checks, preflight.Check{
+			Name: "Boot images",
+			Run:  func() ([]string, error) { return preflight.CheckAMIs(executor, cfg) },
+		})
+	}
+	if cfg.ExternalIAM() && cfg.StepSelected(7) {
+		checks = append(checks, preflight.Check{
+			Name: "Existing IAM roles",
+			Run:  func() ([]string, error) { return preflight.CheckExternalIAM(executor, cfg) },
+		})
+	}
+	if cfg.SharedOIDCProvider() && cfg.StepSelected(7) {
+		checks = append(checks, preflight.Check{
+			Name: "Existing OIDC provider",
+			Run:  func() ([]string, error) { return preflight.CheckOIDCProvider(executor, cfg) },
+		})
+	}
+	if cfg.PermissionsBoundary != "" && cfg.StepSelected(7) {
+		checks = append(checks, preflight.Check{
+			Name: "Permissions boundary",
+			Run:  func() ([]string, error) { return preflight.CheckPermissionsBoundary(executor, cfg) },
+		})
+	}
+	if len(cfg.Subnets) > 0 {
+		checks = append(checks, preflight.Check{
+			Name: "Existing subnets",
+			Run:  func() ([]string, error) { return preflight.CheckSubnets(executor, cfg) },
+		})
+	}
+	// Invalid extra manifests would only fail Step 8, after the AWS resources are created
+	if cfg.ExtraManifestsDir != "" && cfg.StepSelected(8) {
+		checks = append(checks, preflight.Check{
+			Name: "Extra manifests",
+			Run: func() ([]string, error) {
+				_, err := util.ReadExtraManifests(cfg.ExtraManifestsDir)
+				return nil, err
+			},
+		})
+	}
+	// Invalid post-install manifests would only fail once the cluster is deployed
+	if cfg.PostInstallManifestsDir != "" && cfg.StepSelected(10) {
+		checks = append(checks, preflight.Check{
+			Name: "Post-install manifests",
+			Run: func() ([]string, error) {
+				_, err := util.ReadPostInstallManifests(cfg.PostInstallManifestsDir)
+				return nil, err
+			},
+		})
+	}
+	// The cost only matters when the cluster is going to be deployed
+	if cfg.StepSelected(10) {
+		checks = append(checks, preflight.Check{
+			Name: "Cost estimate",
+			Run:  func() ([]string, error) { return preflight.CheckCost(executor, cfg, cost) },
+		})
+	}
+	return checks
+}
+
+// resolveReleaseImage sets the release image (and its digest) from the
+// configured version and/or channel using the OpenShift update service
+func resolveReleaseImage(log *logger.Logger, cfg *config.Config) error {
+	if cfg.ReleaseImage != "" {
+		log.Info(fmt.Sprintf("⚠  Ignoring release image %s: the release is resolved from version/channel", cfg.ReleaseImage))
+	}
+
+	log.Info("Resolving release image from the update service...")
+	arch := cfg.Architecture
+	if arch == "" {
+		arch = util.HostReleaseArch()
+	}
+	release, err := util.ResolveRelease(util.DefaultUpdateServiceURL, cfg.Version, cfg.Channel, arch)
+	if err != nil {
+		return err
+	}
+
+	cfg.ReleaseImage = release.Image
+	cfg.ReleaseDigest = release.Digest
+	log.Info(fmt.Sprintf("✓ OpenShift %s (%s): %s", release.Version, release.Channel, release.Image))
+	log.Debug(fmt.Sprintf("Release digest: %s", release.Digest))
+	return nil
+}
+
+// handleMissingPullSecret sets cfg.PullSecretPath to an existing pull secret,
+// taken from the OS keyring, downloaded with an OCM token or provided by the
+// user. It returns the path of the temporary file to remove, if any.
+func handleMissingPullSecret(log *logger.Logger, cfg *config.Config) string {
+	// An OCM token given with --ocm-token (or the environment) takes precedence
+	// over the secrets stored in the OS keyring
+	if cfg.OCMToken == "" {
+		// A pull secret stored in the OS keyring is written to a private
+		// temporary file, since oc and the steps only accept a path
+		if pullSecret, err := util.KeyringGet(util.KeyringPullSecret); err != nil {
+			log.Debug(fmt.Sprintf("Could not read pull secret from the OS keyring: %v", err))
+		} else if pullSecret != "" {
+			path, err := util.WriteTempSecret("pull-secret-*.json", pullSecret)
+			if err != nil {
+				log.Error(err.Error())
+				exit(1)
+			}
+			atExit(func() { os.Remove(path) })
+			log.Info("✓ Using pull secret from the OS keyring")
+			cfg.PullSecretPath = path
+			return path
+		}
+
+		if token, err := util.KeyringGet(util.KeyringOCMToken); err != nil {
+			log.Debug(fmt.Sprintf("Could not read OCM token from the OS keyring: %v", err))
+		} else {
+			cfg.OCMToken = token
+		}
+	}
+
+	if cfg.OCMToken != "" {
+		logger.AddSecret(cfg.OCMToken)
+		log.Info("Downloading pull secret from console.redhat.com...")
+		pullSecret, err := util.FetchPullSecret("", "", cfg.OCMToken)
+		if err != nil {
+			log.Error(fmt.Sprintf("Failed to download pull secret: %v", err))
+			exit(1)
+		}
+		if err := util.SavePullSecret(cfg.PullSecretPath, pullSecret); err != nil {
+			log.Error(err.Error())
+			exit(1)
+		}
+		log.Info(fmt.Sprintf("✓ Pull secret saved to %s", cfg.PullSecretPath))
+		return ""
+	}
+
 	log.Error("Pull-secret is required but not found.")
 	log.Info("Please download it from: https://cloud.redhat.com/openshift/install/pull-secret")
+	if nonInteractive {
+		exit(exitConfigError)
+	}
 
 	// Try to open browser
 	if err := util.OpenBrowser("https://cloud.redhat.com/openshift/install/pull-secret"); err != nil {
@@ -327,10 +886,11 @@ func handleMissingPullSecret(log *logger.Logger, cfg *config.Config) {
 
 	if !util.FileExists(path) {
 		log.Error("File does not exist. Exiting.")
-		os.Exit(1)
+		exit(exitConfigError)
 	}
 
 	cfg.PullSecretPath = path
+	return ""
 }
 
 // confirm prompts the user with a yes/no question and returns true only for 'y' or 'Y'.
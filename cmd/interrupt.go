@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/clobrano/openshift-sts-wrapper/pkg/config"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/errors"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/logger"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/util"
+)
+
+// exitInterrupted is the conventional exit code of a process stopped by SIGINT
+const exitInterrupted = 130
+
+// interruptContext returns a context cancelled by Ctrl-C or SIGTERM. Once it
+// is cancelled, stop restores the default handling, so that a second Ctrl-C
+// exits immediately.
+func interruptContext() (ctx context.Context, stop context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+}
+
+// restoreTerminal resets the terminal modes, which an interrupted interactive
+// command (e.g. the install-config survey) may leave changed
+func restoreTerminal() {
+	if !isTerminal(os.Stdin) {
+		return
+	}
+	cmd := exec.Command("stty", "sane")
+	cmd.Stdin = os.Stdin
+	cmd.Run()
+}
+
+// reportInterrupted tells how to resume an interrupted installation from the
+// step that was running
+func reportInterrupted(log *logger.Logger, cfg *config.Config, journal *util.Journal) {
+	step := ""
+	for _, name := range config.StepNames {
+		if record, ok := journal.Record(name); ok && record.Status == util.StepInterrupted {
+			step = name
+			break
+		}
+	}
+
+	log.Info("")
+	if step == "" {
+		log.Info("⚠  Installation interrupted. Resume with:")
+		log.Info(fmt.Sprintf("  openshift-sts-wrapper install --cluster-name=%s", cfg.ClusterName))
+		return
+	}
+	log.Info(fmt.Sprintf("⚠  Installation interrupted during %s. Resume with:", step))
+	log.Info(fmt.Sprintf("  openshift-sts-wrapper install --cluster-name=%s --start-from-step=%s", cfg.ClusterName, step))
+}
+
+// createdAWSResources reports whether the installation may have created AWS
+// resources: ccoctl resources (Step 7) or cluster infrastructure (Step 10)
+func createdAWSResources(journal *util.Journal) bool {
+	for _, step := range []string{config.StepName(7), config.StepName(10)} {
+		if _, ok := journal.Record(step); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// offerRollback offers to remove the AWS resources of an interrupted
+// installation by running cleanup, which asks for confirmation. The cluster
+// lock is released first, since cleanup takes it.
+func offerRollback(log *logger.Logger, cfg *config.Config, journal *util.Journal, lock *util.ClusterLock) {
+	if nonInteractive || outputFormat == outputJSON || !isTerminal(os.Stdin) || !createdAWSResources(journal) {
+		return
+	}
+
+	log.Info("")
+	log.Info("AWS resources may have been created before the interruption. They can be rolled back now,")
+	log.Info("or later with: openshift-sts-wrapper cleanup --cluster-name=" + cfg.ClusterName)
+	lock.Unlock()
+	cleanupClusterName = cfg.ClusterName
+	cleanupAwsRegion = cfg.AwsRegion
+	cleanupReleaseImage = cfg.ReleaseImage
+	runCleanup(cleanupCmd, nil)
+}
+
+// rollbackFailedInstall deletes the AWS resources of an installation that
+// failed at or after Step 7, as cleanup --yes does, when --cleanup-on-failure
+// is set. The cluster directory is kept for its logs. The outcome is recorded
+// in the install summary as the rollback step. It returns whether every
+// resource was deleted.
+func rollbackFailedInstall(log *logger.Logger, executor util.CommandExecutor, cfg *config.Config, summary *errors.Summary, journal *util.Journal) bool {
+	if !cfg.CleanupOnFailure || !createdAWSResources(journal) {
+		return false
+	}
+	failedStep := 0
+	for _, step := range summary.Steps {
+		if step.Status == errors.StatusFailed {
+			failedStep, _ = config.ParseStep(step.ID)
+			break
+		}
+	}
+	if failedStep < 7 {
+		return false
+	}
+
+	log.Info("")
+	log.Info(fmt.Sprintf("Rolling back the AWS resources of cluster %s (--cleanup-on-failure)...", cfg.ClusterName))
+	cleanupClusterName = cfg.ClusterName
+	cleanupAwsRegion = cfg.AwsRegion
+	cleanupReleaseImage = cfg.ReleaseImage
+	cleanupPrefix = cfg.CcoctlName()
+
+	started := time.Now()
+	rollback := errors.NewSummary()
+	deleteClusterResources(log, executor, cfg, rollback, util.GetClusterPath(cfg.ClusterName, ""), cfg.ReleaseDigest, cfg.ExternalIAM())
+	var err error
+	if rollback.HasErrors() {
+		failed := rollback.Failed[0]
+		err = fmt.Errorf("%s failed: %v; delete the remaining resources with: openshift-sts-wrapper cleanup --cluster-name=%s", failed.StepName, failed.Error, cfg.ClusterName)
+		log.Error(fmt.Sprintf("Rollback failed: %v", err))
+	} else {
+		log.Info("All AWS resources of the installation have been deleted.")
+	}
+	summary.AddStep("rollback", "Rollback: delete AWS resources", time.Since(started), err)
+	return err == nil
+}
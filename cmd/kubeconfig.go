@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/clobrano/openshift-sts-wrapper/pkg/logger"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/util"
+	"github.com/spf13/cobra"
+)
+
+var (
+	kubeconfigClusterName string
+	kubeconfigExport      bool
+	kubeconfigMerge       bool
+	kubeconfigContext     string
+)
+
+var kubeconfigCmd = &cobra.Command{
+	Use:   "kubeconfig",
+	Short: "Print or merge the admin kubeconfig of a cluster",
+	Long: `Prints the path to the admin kubeconfig of an installed cluster.
+
+With --export, prints a command that sets KUBECONFIG, to be used with eval:
+
+  eval $(openshift-sts-wrapper kubeconfig --cluster-name=my-cluster --export)
+
+With --merge, adds the cluster to the default kubeconfig (the first file in
+KUBECONFIG, or ~/.kube/config) as a context named after the cluster`,
+	Run: runKubeconfig,
+}
+
+func init() {
+	rootCmd.AddCommand(kubeconfigCmd)
+
+	kubeconfigCmd.Flags().StringVar(&kubeconfigClusterName, "cluster-name", "", "Cluster name (required)")
+	kubeconfigCmd.Flags().BoolVar(&kubeconfigExport, "export", false, "Print 'export KUBECONFIG=...' instead of the path")
+	kubeconfigCmd.Flags().BoolVar(&kubeconfigMerge, "merge", false, "Merge the cluster into the default kubeconfig")
+	kubeconfigCmd.Flags().StringVar(&kubeconfigContext, "context", "", "Name of the merged context (default is the cluster name)")
+}
+
+func runKubeconfig(cmd *cobra.Command, args []string) {
+	log := logger.New(logger.Level(getLogLevel()), nil)
+
+	if kubeconfigClusterName == "" {
+		log.Error("Cluster name is required (use --cluster-name flag)")
+		exit(1)
+	}
+	if kubeconfigExport && kubeconfigMerge {
+		log.Error("--export and --merge cannot be combined")
+		exit(1)
+	}
+
+	path, err := filepath.Abs(util.GetKubeconfigPath(kubeconfigClusterName))
+	if err != nil {
+		log.Error(fmt.Sprintf("Failed to resolve kubeconfig path: %v", err))
+		exit(1)
+	}
+	if !util.FileExists(path) {
+		log.Error(fmt.Sprintf("kubeconfig not found at %s - cluster %s may not have been deployed", path, kubeconfigClusterName))
+		exit(1)
+	}
+
+	switch {
+	case kubeconfigExport:
+		fmt.Printf("export KUBECONFIG=%s\n", util.CommandLine(path))
+	case kubeconfigMerge:
+		contextName := kubeconfigContext
+		if contextName == "" {
+			contextName = kubeconfigClusterName
+		}
+		target, err := util.DefaultKubeconfigPath()
+		if err != nil {
+			log.Error(err.Error())
+			exit(1)
+		}
+		if err := util.MergeKubeconfig(path, target, contextName); err != nil {
+			log.Error(fmt.Sprintf("Failed to merge kubeconfig: %v", err))
+			exit(1)
+		}
+		log.Info(fmt.Sprintf("✓ Merged cluster %s into %s as context %s", kubeconfigClusterName, target, contextName))
+		log.Info(fmt.Sprintf("Switch to it with: kubectl config use-context %s", contextName))
+	default:
+		fmt.Println(path)
+	}
+}
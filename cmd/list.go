@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/clobrano/openshift-sts-wrapper/pkg/util"
+	"github.com/spf13/cobra"
+)
+
+var listCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the clusters with an artifacts directory",
+	Long: `Lists the clusters of artifacts/clusters with their age and, for clusters
+installed with --expires-in, the time remaining before their scheduled cleanup.`,
+	Args: cobra.NoArgs,
+	Run:  runList,
+}
+
+func init() {
+	rootCmd.AddCommand(listCmd)
+}
+
+// clusterListEntry is a cluster as listed by list
+type clusterListEntry struct {
+	ClusterName  string     `json:"clusterName"`
+	ReleaseImage string     `json:"releaseImage,omitempty"`
+	CreatedAt    *time.Time `json:"createdAt,omitempty"`
+	ExpiresAt    *time.Time `json:"expiresAt,omitempty"`
+}
+
+func runList(cmd *cobra.Command, args []string) {
+	out := redirectOutput()
+
+	clusters, err := util.ListClusters()
+	checkErr(err)
+	var entries []clusterListEntry
+	for _, name := range clusters {
+		entry := clusterListEntry{ClusterName: name}
+		if created, err := util.ClusterCreationTime(name); err == nil {
+			entry.CreatedAt = &created
+		}
+		if metadata, err := util.ReadInstallMetadata(util.GetClusterPath(name, "")); err == nil {
+			entry.ReleaseImage = metadata.ReleaseImage
+			entry.ExpiresAt = metadata.ExpiresAt
+		}
+		entries = append(entries, entry)
+	}
+	printClusterList(out, time.Now(), entries)
+}
+
+// printClusterList prints the clusters in the selected output format
+func printClusterList(out *os.File, now time.Time, entries []clusterListEntry) {
+	if outputFormat == outputJSON {
+		if entries == nil {
+			entries = []clusterListEntry{}
+		}
+		data, err := json.MarshalIndent(entries, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to encode cluster list: %v\n", err)
+			return
+		}
+		fmt.Fprintln(out, string(data))
+		return
+	}
+	if len(entries) == 0 {
+		fmt.Fprintln(out, "No clusters found.")
+		return
+	}
+
+	fmt.Fprintf(out, "%-24s %8s  %-10s  %s\n", "CLUSTER", "AGE", "EXPIRES IN", "RELEASE")
+	for _, entry := range entries {
+		age, expires := "-", "-"
+		if entry.CreatedAt != nil {
+			age = formatAge(now.Sub(*entry.CreatedAt))
+		}
+		if entry.ExpiresAt != nil {
+			expires = "expired"
+			if remaining := entry.ExpiresAt.Sub(now); remaining > 0 {
+				expires = formatAge(remaining)
+			}
+		}
+		fmt.Fprintf(out, "%-24s %8s  %-10s  %s\n", entry.ClusterName, age, expires, entry.ReleaseImage)
+	}
+}
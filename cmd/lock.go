@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/clobrano/openshift-sts-wrapper/pkg/logger"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/util"
+)
+
+// forceUnlock is shared by the commands that lock the cluster
+var forceUnlock bool
+
+// lockCluster takes the cluster lock, exiting if another run holds it. With
+// --force-unlock, a lock left by a hung or stale process is removed first.
+func lockCluster(log *logger.Logger, clusterName string) *util.ClusterLock {
+	if forceUnlock {
+		log.Info(fmt.Sprintf("⚠  Removing the lock of cluster '%s' (--force-unlock)", clusterName))
+		if err := util.ForceUnlockCluster(clusterName); err != nil {
+			log.Error(err.Error())
+			exit(1)
+		}
+	}
+
+	lock, err := util.LockCluster(clusterName)
+	if err != nil {
+		var lockedErr *util.LockedError
+		if !errors.As(err, &lockedErr) {
+			log.Error(fmt.Sprintf("Failed to lock cluster '%s': %v", clusterName, err))
+			exit(1)
+		}
+		log.Error(fmt.Sprintf("Another run against cluster '%s' is in progress: %v", clusterName, err))
+		if lockedErr.Stale {
+			log.Info("The lock looks stale. If no other run is in progress, retry with --force-unlock")
+		} else {
+			log.Info("Wait for it to finish, or retry with --force-unlock if it is hung")
+		}
+		exit(1)
+	}
+	return lock
+}
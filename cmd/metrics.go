@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/clobrano/openshift-sts-wrapper/pkg/config"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/errors"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/logger"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/util"
+)
+
+// publishMetrics pushes the metrics of an install run to the configured
+// Pushgateway and/or writes them for the node_exporter textfile collector.
+// Failures are reported but do not change the result of the run.
+func publishMetrics(log *logger.Logger, cfg *config.Config, journal *util.Journal, release string, summary *errors.Summary, started time.Time) {
+	if cfg.Metrics.PushgatewayURL == "" && cfg.Metrics.Textfile == "" {
+		return
+	}
+
+	metrics := util.FormatMetrics(journal, util.RunMetrics{
+		Release:    release,
+		Succeeded:  !summary.HasErrors(),
+		Duration:   time.Since(started),
+		FinishedAt: time.Now(),
+	})
+
+	if cfg.Metrics.PushgatewayURL != "" {
+		if err := util.PushMetrics(cfg.Metrics.PushgatewayURL, cfg.ClusterName, metrics); err != nil {
+			log.Error(fmt.Sprintf("Failed to push metrics: %v", err))
+		} else {
+			log.Debug("Metrics pushed to the Pushgateway")
+		}
+	}
+	if cfg.Metrics.Textfile != "" {
+		if err := util.WriteMetricsFile(cfg.Metrics.Textfile, metrics); err != nil {
+			log.Error(fmt.Sprintf("Failed to write metrics: %v", err))
+		} else {
+			log.Debug(fmt.Sprintf("Metrics written to %s", cfg.Metrics.Textfile))
+		}
+	}
+}
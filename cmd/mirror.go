@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/clobrano/openshift-sts-wrapper/pkg/config"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/logger"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/util"
+	"github.com/spf13/cobra"
+)
+
+var mirrorDryRun bool
+
+var mirrorCmd = &cobra.Command{
+	Use:   "mirror",
+	Short: "Mirror the release and the operators into a registry with oc-mirror",
+	Long: `Mirrors the release image and the operators of postInstall.operators into
+the registry of mirror.registry with oc-mirror (v2), for disconnected installs.
+
+The ImageSetConfiguration is written to artifacts/shared/mirror, the oc-mirror
+workspace kept between runs, and the cluster resources oc-mirror writes (image
+mirror sets and catalog sources) to artifacts/shared/<version-arch>/mirror.
+With mirror.registry set, install then:
+
+  - extracts the credentials requests and the binaries through the mirror
+  - sets the image mirrors (and mirror.caBundle) in install-config.yaml
+  - adds the ImageTagMirrorSets to the manifests
+  - replaces the default catalog sources with the mirrored ones after the
+    deployment, before installing the operators
+
+Only the releases of an update channel can be mirrored: set the release with
+--version/--channel or a release image tagged with its version.`,
+	Args: cobra.NoArgs,
+	Run:  runMirror,
+}
+
+func init() {
+	rootCmd.AddCommand(mirrorCmd)
+
+	mirrorCmd.Flags().BoolVar(&mirrorDryRun, "dry-run", false, "Print the ImageSetConfiguration and the oc-mirror command without running it")
+	// The release and the registry resolve like they do for install
+	for _, name := range []string{
+		"release-image", "version", "channel", "arch", "pull-secret", "ocm-token", "oc-path",
+		"mirror-registry", "mirror-ca-bundle",
+	} {
+		mirrorCmd.Flags().AddFlag(installCmd.Flags().Lookup(name))
+	}
+}
+
+func runMirror(cmd *cobra.Command, args []string) {
+	out := redirectOutput()
+	log := logger.New(logger.Level(getLogLevel()), nil)
+
+	cfg := loadConfig(log)
+	if cfg.ResolvesRelease() {
+		if err := resolveReleaseImage(log, cfg); err != nil {
+			log.Error(fmt.Sprintf("Failed to resolve release image: %v", err))
+			exit(exitConfigError)
+		}
+	}
+	if cfg.Mirror.Registry == "" {
+		log.Error("Configuration error: mirror.registry is required (--mirror-registry)")
+		exit(exitConfigError)
+	}
+	if errs := config.ConsistencyErrors(cfg); len(errs) > 0 {
+		for _, err := range errs {
+			log.Error(fmt.Sprintf("Configuration error: %v", err))
+		}
+		exit(exitConfigError)
+	}
+	versionArch, err := util.ExtractVersionArch(cfg.ReleaseImage)
+	if err != nil {
+		log.Error(fmt.Sprintf("Invalid release image: %v", err))
+		exit(exitConfigError)
+	}
+
+	var operators []util.MirrorOperator
+	for _, operator := range cfg.PostInstall.Operators {
+		catalog := operator.Source
+		if catalog == "" {
+			catalog = "redhat-operators"
+		}
+		operators = append(operators, util.MirrorOperator{Catalog: catalog, Package: operator.Name, Channel: operator.Channel})
+	}
+	imageSet, err := util.RenderImageSetConfiguration(versionArch, cfg.Channel, operators)
+	if err != nil {
+		log.Error(fmt.Sprintf("Configuration error: %v", err))
+		exit(exitConfigError)
+	}
+
+	workspace := util.GetMirrorWorkspacePath()
+	configPath := filepath.Join(workspace, "imageset-config.yaml")
+	if mirrorDryRun {
+		fmt.Fprintf(out, "# %s\n%s\n", configPath, imageSet)
+		fmt.Fprintf(out, "oc-mirror %s\n", strings.Join(ocMirrorArgs(configPath, workspace, cfg), " "))
+		return
+	}
+
+	checkPrerequisites(log, cfg)
+	if _, err := exec.LookPath("oc-mirror"); err != nil {
+		log.Error("oc-mirror not found in PATH: download it from the OpenShift mirror (clients/ocp/<version>/oc-mirror.tar.gz)")
+		exit(exitConfigError)
+	}
+	vaultFiles := loadVaultFiles(log, cfg)
+	defer removeFiles(vaultFiles)
+	if !util.FileExists(cfg.PullSecretPath) {
+		if tempFile := handleMissingPullSecret(log, cfg); tempFile != "" {
+			defer os.Remove(tempFile)
+		}
+	}
+	if err := config.ValidatePullSecret(cfg.PullSecretPath); err != nil {
+		log.Error(fmt.Sprintf("Pull secret validation failed: %v", err))
+		exit(exitConfigError)
+	}
+
+	if err := util.EnsureDir(workspace); err != nil {
+		log.Error(fmt.Sprintf("Failed to create %s: %v", workspace, err))
+		exit(1)
+	}
+	if err := os.WriteFile(configPath, imageSet, 0644); err != nil {
+		log.Error(fmt.Sprintf("Failed to write %s: %v", configPath, err))
+		exit(1)
+	}
+
+	ctx, stop := interruptContext()
+	defer stop()
+	log.Info(fmt.Sprintf("Mirroring OpenShift %s and %d operator(s) to %s...", versionArch, len(operators), cfg.Mirror.Registry))
+	executor := logCommands(log, &util.RealExecutor{Context: ctx})
+	if err := util.RunCommand(executor, "oc-mirror", ocMirrorArgs(configPath, workspace, cfg)...); err != nil {
+		log.Error(fmt.Sprintf("oc-mirror failed: %v", err))
+		exit(1)
+	}
+
+	copied, err := util.CopyMirrorResources(workspace, util.GetSharedMirrorPath(versionArch))
+	if err != nil {
+		log.Error(err.Error())
+		exit(1)
+	}
+	for _, path := range copied {
+		log.Info(fmt.Sprintf("✓ %s", path))
+	}
+	if _, err := util.ReadMirrorResources(util.GetSharedMirrorPath(versionArch)); err != nil {
+		log.Error(err.Error())
+		exit(1)
+	}
+	log.Info(fmt.Sprintf("✓ Release %s mirrored to %s", versionArch, cfg.Mirror.Registry))
+	log.Info(fmt.Sprintf("Install from the mirror with: openshift-sts-wrapper install --release-image=%s --mirror-registry=%s", cfg.ReleaseImage, cfg.Mirror.Registry))
+}
+
+// ocMirrorArgs returns the arguments of oc-mirror mirroring the image set of
+// configPath to the mirror registry, with the pull secret as credentials
+func ocMirrorArgs(configPath, workspace string, cfg *config.Config) []string {
+	absWorkspace, err := filepath.Abs(workspace)
+	checkErr(err)
+	return []string{
+		"--v2",
+		"--config", configPath,
+		"--workspace", "file://" + absWorkspace,
+		"--authfile", cfg.PullSecretPath,
+		"docker://" + cfg.Mirror.Registry,
+	}
+}
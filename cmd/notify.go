@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/clobrano/openshift-sts-wrapper/pkg/config"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/errors"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/logger"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/util"
+)
+
+// notification is the payload posted to the webhook at the end of a run. The
+// text field makes it readable by Slack incoming webhooks as is.
+type notification struct {
+	Text       string              `json:"text"`
+	Command    string              `json:"command"`
+	Cluster    string              `json:"cluster"`
+	Status     string              `json:"status"`
+	Duration   float64             `json:"durationSeconds"`
+	ConsoleURL string              `json:"consoleURL,omitempty"`
+	Errors     []notificationError `json:"errors,omitempty"`
+}
+
+type notificationError struct {
+	Step  string `json:"step"`
+	Error string `json:"error"`
+}
+
+// notify reports the end of an install or cleanup run to the configured webhook
+func notify(log *logger.Logger, cfg *config.Config, command, clusterName string, summary *errors.Summary, started time.Time) {
+	if cfg.Notifications.WebhookURL == "" {
+		return
+	}
+
+	payload := newNotification(command, clusterName, summary, time.Since(started))
+	if err := util.PostWebhook(cfg.Notifications.WebhookURL, payload); err != nil {
+		log.Error(fmt.Sprintf("Failed to send notification: %v", err))
+		return
+	}
+	log.Debug("Notification sent")
+}
+
+func newNotification(command, clusterName string, summary *errors.Summary, duration time.Duration) notification {
+	payload := notification{
+		Command:    command,
+		Cluster:    clusterName,
+		Status:     summary.Status(),
+		Duration:   duration.Seconds(),
+		ConsoleURL: summary.ConsoleURL,
+	}
+	for _, failed := range summary.Failed {
+		payload.Errors = append(payload.Errors, notificationError{Step: failed.StepName, Error: failed.Error.Error()})
+	}
+
+	if len(payload.Errors) > 0 {
+		var lines []string
+		for _, failed := range payload.Errors {
+			lines = append(lines, fmt.Sprintf("• %s: %s", failed.Step, failed.Error))
+		}
+		payload.Text = fmt.Sprintf("✗ %s of cluster %s failed after %s\n%s", capitalize(command), clusterName, util.FormatEstimate(duration), strings.Join(lines, "\n"))
+	} else {
+		payload.Text = fmt.Sprintf("✓ %s of cluster %s completed in %s", capitalize(command), clusterName, util.FormatEstimate(duration))
+		if payload.ConsoleURL != "" {
+			payload.Text += "\n" + payload.ConsoleURL
+		}
+	}
+	return payload
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+// desktopNotify shows a native desktop notification, if enabled
+func desktopNotify(log *logger.Logger, cfg *config.Config, title, message string) {
+	if !cfg.Notifications.Desktop {
+		return
+	}
+	if err := util.DesktopNotify(&util.RealExecutor{}, title, message); err != nil {
+		log.Debug(fmt.Sprintf("Could not show desktop notification: %v", err))
+	}
+}
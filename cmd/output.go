@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/clobrano/openshift-sts-wrapper/pkg/errors"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/logger"
+)
+
+// Output formats accepted by --output
+const (
+	outputText = "text"
+	outputJSON = "json"
+)
+
+var outputFormat string
+
+// redirectOutput prepares stdout for the selected output format and returns
+// the file the final summary is written to. With JSON output, everything else
+// written to stdout (logs, prompts, child processes) goes to stderr instead,
+// so that stdout only holds the JSON document.
+func redirectOutput() *os.File {
+	switch outputFormat {
+	case outputText, "":
+		return os.Stdout
+	case outputJSON:
+		out := os.Stdout
+		os.Stdout = os.Stderr
+		return out
+	default:
+		fmt.Fprintf(os.Stderr, "Error: invalid --output %q: must be %s or %s\n", outputFormat, outputText, outputJSON)
+		exit(1)
+		return nil
+	}
+}
+
+// printSummary writes the final summary in the selected output format, with
+// the secrets of the step errors redacted
+func printSummary(out *os.File, summary *errors.Summary) {
+	if outputFormat != outputJSON {
+		fmt.Fprintln(out, logger.Redact(summary.String()))
+		return
+	}
+	data, err := summary.JSON()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to encode summary: %v\n", err)
+		return
+	}
+	fmt.Fprintln(out, logger.Redact(string(data)))
+}
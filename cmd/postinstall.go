@@ -0,0 +1,189 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/clobrano/openshift-sts-wrapper/pkg/config"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/errors"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/logger"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/steps"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/util"
+	"github.com/spf13/cobra"
+)
+
+var (
+	postInstallClusterName     string
+	postInstallAdminUser       string
+	postInstallRemoveKubeadmin bool
+	postInstallManifestsDir    string
+)
+
+var postInstallCmd = &cobra.Command{
+	Use:   "post-install",
+	Short: "Day-1 configuration of an installed cluster",
+	Long:  `Configures an installed cluster. These tasks also run at the end of install when enabled in the config file`,
+}
+
+var postInstallIDPCmd = &cobra.Command{
+	Use:   "idp",
+	Short: "Create an htpasswd identity provider with a cluster-admin user",
+	Long: `Creates an htpasswd identity provider with an admin user and grants it
+cluster-admin. The password is read from OPENSHIFT_STS_ADMIN_PASSWORD, or
+generated and saved to artifacts/clusters/<name>/auth/<user>-password.
+
+With --remove-kubeadmin, the kubeadmin user is removed once the admin user
+can log in`,
+	Run: runPostInstallIDP,
+}
+
+var postInstallOperatorsCmd = &cobra.Command{
+	Use:   "operators",
+	Short: "Install the OLM operators of the config file",
+	Long: `Subscribes to the operators listed in postInstall.operators of the config
+file and waits for their ClusterServiceVersion to succeed`,
+	Run: runPostInstallOperators,
+}
+
+var postInstallManifestsCmd = &cobra.Command{
+	Use:   "manifests",
+	Short: "Apply the post-install manifests",
+	Long: `Applies the YAML manifests of postInstallManifestsDir (or --dir) with
+'oc apply', in the order of their file names, retrying each one that is
+rejected, e.g. because its CRD isn't installed yet`,
+	Run: runPostInstallManifests,
+}
+
+func init() {
+	rootCmd.AddCommand(postInstallCmd)
+	postInstallCmd.AddCommand(postInstallIDPCmd)
+	postInstallCmd.AddCommand(postInstallOperatorsCmd)
+	postInstallCmd.AddCommand(postInstallManifestsCmd)
+
+	postInstallIDPCmd.Flags().StringVar(&postInstallClusterName, "cluster-name", "", "Cluster name (required)")
+	postInstallIDPCmd.Flags().StringVar(&postInstallAdminUser, "admin-user", "", "Name of the admin user (default is postInstall.adminUser from the config file)")
+	postInstallIDPCmd.Flags().BoolVar(&postInstallRemoveKubeadmin, "remove-kubeadmin", false, "Remove kubeadmin once the admin user can log in")
+	postInstallOperatorsCmd.Flags().StringVar(&postInstallClusterName, "cluster-name", "", "Cluster name (required)")
+	postInstallManifestsCmd.Flags().StringVar(&postInstallClusterName, "cluster-name", "", "Cluster name (required)")
+	postInstallManifestsCmd.Flags().StringVar(&postInstallManifestsDir, "dir", "", "Directory of the manifests (default is postInstallManifestsDir from the config file)")
+}
+
+func runPostInstallIDP(cmd *cobra.Command, args []string) {
+	log := logger.New(logger.Level(getLogLevel()), nil)
+
+	if postInstallClusterName == "" {
+		log.Error("Cluster name is required (use --cluster-name flag)")
+		exit(1)
+	}
+
+	cfg := &config.Config{}
+	cfg.Merge(config.LoadFromEnv())
+	cfg.Merge(loadConfigFile(log))
+	cfg.Merge(&config.Config{
+		ClusterName: postInstallClusterName,
+		PostInstall: config.PostInstall{AdminUser: postInstallAdminUser, RemoveKubeadmin: postInstallRemoveKubeadmin},
+	})
+	if cfg.PostInstall.AdminUser == "" {
+		log.Error("Admin user is required (use --admin-user flag or postInstall.adminUser in the config file)")
+		exit(1)
+	}
+	if errs := config.ConsistencyErrors(cfg); len(errs) > 0 {
+		log.Error(fmt.Sprintf("Invalid configuration: %v", errs[0]))
+		exit(1)
+	}
+
+	step := steps.NewConfigureIDP(cfg, log, logCommands(log, &util.RealExecutor{}))
+	log.StartStep(step.Name())
+	if err := step.Execute(); err != nil {
+		log.FailStep(step.Name())
+		log.Error(err.Error())
+		exit(1)
+	}
+	log.CompleteStep(step.Name())
+}
+
+func runPostInstallOperators(cmd *cobra.Command, args []string) {
+	log := logger.New(logger.Level(getLogLevel()), nil)
+
+	if postInstallClusterName == "" {
+		log.Error("Cluster name is required (use --cluster-name flag)")
+		exit(1)
+	}
+
+	cfg := &config.Config{}
+	cfg.Merge(config.LoadFromEnv())
+	cfg.Merge(loadConfigFile(log))
+	cfg.ClusterName = postInstallClusterName
+	if len(cfg.PostInstall.Operators) == 0 {
+		log.Error("No operators to install (set postInstall.operators in the config file)")
+		exit(1)
+	}
+	if errs := config.ConsistencyErrors(cfg); len(errs) > 0 {
+		log.Error(fmt.Sprintf("Invalid configuration: %v", errs[0]))
+		exit(1)
+	}
+
+	if cfg.Mirror.Registry != "" {
+		runDay2Step(log, cfg, steps.NewConfigureMirrorCatalogs(cfg, log, logCommands(log, &util.RealExecutor{})))
+	}
+	runDay2Step(log, cfg, steps.NewInstallOperators(cfg, log, logCommands(log, &util.RealExecutor{})))
+}
+
+func runPostInstallManifests(cmd *cobra.Command, args []string) {
+	log := logger.New(logger.Level(getLogLevel()), nil)
+
+	if postInstallClusterName == "" {
+		log.Error("Cluster name is required (use --cluster-name flag)")
+		exit(1)
+	}
+
+	cfg := &config.Config{}
+	cfg.Merge(config.LoadFromEnv())
+	cfg.Merge(loadConfigFile(log))
+	cfg.Merge(&config.Config{ClusterName: postInstallClusterName, PostInstallManifestsDir: postInstallManifestsDir})
+	if cfg.PostInstallManifestsDir == "" {
+		log.Error("Manifests directory is required (use --dir flag or postInstallManifestsDir in the config file)")
+		exit(1)
+	}
+
+	runDay2Step(log, cfg, steps.NewApplyManifests(cfg, log, logCommands(log, &util.RealExecutor{})))
+}
+
+// runPostInstall runs the post-install tasks enabled in the configuration once
+// the cluster is deployed, recording them in the summary
+func runPostInstall(log *logger.Logger, cfg *config.Config, summary *errors.Summary) {
+	if cfg.PostInstall.AdminUser != "" {
+		step := steps.NewConfigureIDP(cfg, log, logCommands(log, &util.RealExecutor{}))
+		if runPostInstallTask(log, cfg, summary, "configure-idp", step) {
+			summary.AddArtifact("adminPassword", steps.GetAdminPasswordPath(cfg.ClusterName, cfg.PostInstall.AdminUser))
+		}
+	}
+	// The default catalog sources can't be reached from a disconnected cluster
+	if cfg.Mirror.Registry != "" {
+		runPostInstallTask(log, cfg, summary, "configure-mirror-catalogs", steps.NewConfigureMirrorCatalogs(cfg, log, logCommands(log, &util.RealExecutor{})))
+	}
+	if len(cfg.PostInstall.Operators) > 0 {
+		runPostInstallTask(log, cfg, summary, "install-operators", steps.NewInstallOperators(cfg, log, logCommands(log, &util.RealExecutor{})))
+	}
+	// After the operators, whose CRDs the manifests may use
+	if cfg.PostInstallManifestsDir != "" {
+		runPostInstallTask(log, cfg, summary, "apply-manifests", steps.NewApplyManifests(cfg, log, logCommands(log, &util.RealExecutor{})))
+	}
+}
+
+// runPostInstallTask runs a post-install step of install, recording it in the
+// summary, and reports whether it succeeded
+func runPostInstallTask(log *logger.Logger, cfg *config.Config, summary *errors.Summary, id string, step steps.Step) bool {
+	label := "[Post-install] " + step.Name()
+	log.StartStep(label)
+	started := time.Now()
+	err := step.Execute()
+	summary.AddStep(id, label, time.Since(started), err)
+	if err != nil {
+		log.FailStep(label)
+		runFailureHooks(log, cfg, label, err)
+		return false
+	}
+	log.CompleteStep(label)
+	return true
+}
@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/clobrano/openshift-sts-wrapper/pkg/config"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/logger"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/steps"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/util"
+	"github.com/spf13/cobra"
+)
+
+var (
+	refreshClusterName  string
+	refreshReleaseImage string
+	refreshAwsRegion    string
+	refreshDryRun       bool
+)
+
+var credentialsCmd = &cobra.Command{
+	Use:   "credentials",
+	Short: "Manage the cloud credentials of an installed cluster",
+}
+
+var credentialsRefreshCmd = &cobra.Command{
+	Use:   "refresh",
+	Short: "Prepare the IAM roles of a cluster for an upgrade",
+	Long: `Compares the CredentialsRequests of the installed release with those of the
+target release and runs ccoctl to create or update the IAM roles of the new or
+changed ones only. The resulting credentials secrets are applied to the cluster,
+which is then marked as upgradeable to the target version.
+
+Run it before upgrading an STS cluster:
+
+  openshift-sts-wrapper credentials refresh --cluster-name=my-cluster \
+    --release-image=quay.io/openshift-release-dev/ocp-release:4.13.0-x86_64`,
+	Run: runCredentialsRefresh,
+}
+
+func init() {
+	rootCmd.AddCommand(credentialsCmd)
+	credentialsCmd.AddCommand(credentialsRefreshCmd)
+
+	credentialsRefreshCmd.Flags().StringVar(&refreshClusterName, "cluster-name", "", "Cluster name (required)")
+	credentialsRefreshCmd.Flags().StringVar(&refreshReleaseImage, "release-image", "", "Release image the cluster will be upgraded to (required)")
+	credentialsRefreshCmd.Flags().StringVar(&refreshAwsRegion, "region", "", "AWS region (optional - will be read from metadata.json if not provided)")
+	credentialsRefreshCmd.Flags().BoolVar(&refreshDryRun, "dry-run", false, "Only show which IAM roles would be created or updated")
+	credentialsRefreshCmd.Flags().BoolVar(&forceUnlock, "force-unlock", false, "Remove the lock of a run against the cluster that is hung or stale")
+}
+
+func runCredentialsRefresh(cmd *cobra.Command, args []string) {
+	log := logger.New(logger.Level(getLogLevel()), nil)
+
+	if refreshClusterName == "" || refreshReleaseImage == "" {
+		log.Error("--cluster-name and --release-image are required")
+		exit(1)
+	}
+
+	lock := lockCluster(log, refreshClusterName)
+	defer lock.Unlock()
+
+	cfg := loadClusterConfig(log, refreshClusterName, refreshAwsRegion)
+	useVaultAWSCredentials(log, cfg)
+	validateAWSCredentials(log, cfg.AwsProfile)
+	assumeRole(log, cfg)
+
+	step, err := steps.NewRefreshCredentials(cfg, log, logCommands(log, &util.RealExecutor{}), refreshReleaseImage)
+	if err != nil {
+		log.Error(err.Error())
+		exit(1)
+	}
+	step.DryRun = refreshDryRun
+
+	log.StartStep(step.Name())
+	if err := step.Execute(); err != nil {
+		log.FailStep(step.Name())
+		log.Error(err.Error())
+		exit(1)
+	}
+	log.CompleteStep(step.Name())
+}
+
+// loadClusterConfig returns the configuration of an installed cluster: the
+// config file and environment, with the release image and region the cluster
+// was installed with. region, if set, overrides the detected region.
+func loadClusterConfig(log *logger.Logger, clusterName, region string) *config.Config {
+	cfg := &config.Config{}
+	cfg.Merge(config.LoadFromEnv())
+	cfg.Merge(loadConfigFile(log))
+	cfg.ClusterName = clusterName
+	cfg.SetDefaults()
+
+	clusterDir := util.GetClusterPath(clusterName, "")
+	metadata, err := util.ReadInstallMetadata(clusterDir)
+	if err != nil {
+		log.Error(fmt.Sprintf("Could not find the release of cluster %s: %v", clusterName, err))
+		exit(1)
+	}
+	cfg.ReleaseImage = metadata.ReleaseImage
+	cfg.ReleaseDigest = metadata.ReleaseDigest
+	if metadata.ResourcePrefix != "" {
+		cfg.ResourcePrefix = metadata.ResourcePrefix
+	}
+	log.Info(fmt.Sprintf("Installed Release Image: %s", cfg.ReleaseImage))
+
+	if region != "" {
+		cfg.AwsRegion = region
+	} else if clusterMetadata, err := util.ReadClusterMetadata(clusterDir); err == nil && clusterMetadata.AWS.Region != "" {
+		cfg.AwsRegion = clusterMetadata.AWS.Region
+	}
+	if cfg.AwsRegion == "" {
+		log.Error("AWS region is required (use --region flag)")
+		exit(1)
+	}
+	log.Info(fmt.Sprintf("AWS Region: %s", cfg.AwsRegion))
+	useServiceEndpoints(cfg)
+	return cfg
+}
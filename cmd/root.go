@@ -3,14 +3,22 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"strings"
+	"time"
 
+	"github.com/clobrano/openshift-sts-wrapper/pkg/logger"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/util"
 	"github.com/spf13/cobra"
 )
 
 var (
-	cfgFile string
-	verbose bool
-	quiet   bool
+	cfgFile       string
+	configProfile string
+	verbose       bool
+	quiet         bool
+	noColor       bool
+	// auditLog records the external commands run by the command of the wrapper
+	auditLog *util.AuditLog
 )
 
 var rootCmd = &cobra.Command{
@@ -18,17 +26,32 @@ var rootCmd = &cobra.Command{
 	Short: "OpenShift STS Installation Wrapper",
 	Long: `A CLI tool that automates the installation of OpenShift clusters
 with AWS Security Token Service (STS) authentication.`,
-	Version: "0.1.0",
+	Version: version,
+	// An oc client downloaded by install takes precedence over the one in PATH
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		// NO_COLOR is also honored by the tools the wrapper runs
+		if noColor {
+			os.Setenv("NO_COLOR", "1")
+		}
+		auditLog = util.NewAuditLog(strings.TrimPrefix(cmd.CommandPath(), cmd.Root().Name()+" "), time.Now())
+		if err := util.UseSharedBinaries(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to use the shared binaries: %v\n", err)
+		}
+	},
 }
 
 func Execute() error {
+	registerCompletions(rootCmd)
 	return rootCmd.Execute()
 }
 
 func init() {
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is ./openshift-sts-wrapper.yaml)")
-	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "verbose output")
-	rootCmd.PersistentFlags().BoolVarP(&quiet, "q", "q", false, "quiet output (errors only)")
+	rootCmd.PersistentFlags().StringVar(&configProfile, "profile", "", "named profile of the config file to apply")
+	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "verbose output, with the command line of every command run")
+	rootCmd.PersistentFlags().BoolVarP(&quiet, "quiet", "q", false, "quiet output (step results, errors and the final summary only)")
+	rootCmd.PersistentFlags().BoolVar(&noColor, "no-color", false, "disable colors (also disabled by the NO_COLOR environment variable and when the output is not a terminal)")
+	rootCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", outputText, "format of the final summary of install, install-batch, cleanup, cost, verify, doctor, list, status, version, credentials audit and audit show: text or json")
 }
 
 func getLogLevel() int {
@@ -41,9 +64,38 @@ func getLogLevel() int {
 	return 1 // LevelNormal
 }
 
+// logCommands wraps executor to record every command it runs in the audit log,
+// and to log its command line in verbose mode
+func logCommands(log *logger.Logger, executor util.CommandExecutor) util.CommandExecutor {
+	if auditLog != nil {
+		executor = auditLog.Wrap(executor)
+	}
+	if !log.Verbose() {
+		return executor
+	}
+	return util.CommandLog(log.Command).Wrap(executor)
+}
+
 func checkErr(err error) {
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+		exit(1)
+	}
+}
+
+// exitHooks clean up before the process exits: os.Exit skips deferred calls
+var exitHooks []func()
+
+// atExit registers a cleanup to run when a command exits through exit. Cleanups
+// that also run deferred must be safe to run twice.
+func atExit(hook func()) {
+	exitHooks = append(exitHooks, hook)
+}
+
+// exit runs the registered cleanups, most recent first, and exits with code
+func exit(code int) {
+	for i := len(exitHooks) - 1; i >= 0; i-- {
+		exitHooks[i]()
 	}
+	os.Exit(code)
 }
@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/clobrano/openshift-sts-wrapper/pkg/config"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/logger"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/util"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/wrapper"
+	"github.com/spf13/cobra"
+)
+
+// awsSteps are the steps calling AWS, whose credentials are validated first
+var awsSteps = map[int]bool{6: true, 7: true, 10: true}
+
+// pullSecretSteps are the steps reading the pull secret
+var pullSecretSteps = map[int]bool{3: true, 4: true}
+
+var runStepCmd = &cobra.Command{
+	Use:   "run-step <step>",
+	Short: "Run a single installation step",
+	Long: `Runs one installation step of a cluster, by name (see 'steps list') or
+number, even if it looks completed: e.g. copy-manifests and copy-tls to redo
+the copies, or verify to check the cluster again.
+
+The step runs with the current configuration, like 'install --only-step',
+without the checks and prompts of a full installation. It is recorded in the
+step journal of the cluster, and the onFailure hooks run if it fails. The
+release image defaults to the one the cluster was installed with.`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeStepNames,
+	Run:               runRunStep,
+}
+
+func init() {
+	rootCmd.AddCommand(runStepCmd)
+
+	// The cluster, its release and its credentials resolve like they do for install
+	for _, name := range []string{
+		"cluster-name", "release-image", "aws-profile", "assume-role-arn", "mfa-serial", "pull-secret", "force-unlock",
+		"oc-path", "openshift-install-path", "ccoctl-path",
+	} {
+		runStepCmd.Flags().AddFlag(installCmd.Flags().Lookup(name))
+	}
+}
+
+func runRunStep(cmd *cobra.Command, args []string) {
+	out := redirectOutput()
+	log := logger.New(logger.Level(getLogLevel()), nil)
+
+	num, err := config.ParseStep(args[0])
+	if err != nil {
+		log.Error(err.Error())
+		exit(exitConfigError)
+	}
+
+	cfg := loadConfig(log)
+	useClusterRelease(cfg)
+	// Only this step runs, whatever the configuration selects
+	cfg.OnlyStep = num
+	cfg.StartFromStep, cfg.StopAfterStep, cfg.SkipSteps = 0, 0, nil
+	cfg.ConfirmEachStep = false
+	if err := config.ValidateConfig(cfg); err != nil {
+		log.Error(fmt.Sprintf("Configuration error: %v", err))
+		exit(exitConfigError)
+	}
+	if !util.DirExists(util.GetClusterPath(cfg.ClusterName, "")) && num > 4 {
+		log.Error(fmt.Sprintf("Cluster '%s' not found in artifacts/clusters: its earlier steps never ran", cfg.ClusterName))
+		exit(exitConfigError)
+	}
+	if num == 4 {
+		ensureSSHKey(log, cfg)
+		if complete, missing := cfg.HasCompleteInstallConfigData(); !complete {
+			log.Error(fmt.Sprintf("Configuration incomplete for install-config.yaml, missing: %s", strings.Join(missing, ", ")))
+			exit(exitConfigError)
+		}
+	}
+	checkPrerequisites(log, cfg)
+
+	lock := lockCluster(log, cfg.ClusterName)
+	defer lock.Unlock()
+
+	useVaultAWSCredentials(log, cfg)
+	vaultFiles := loadVaultFiles(log, cfg)
+	defer removeFiles(vaultFiles)
+	if awsSteps[num] {
+		validateAWSCredentials(log, cfg.AwsProfile)
+		assumeRole(log, cfg)
+	}
+	if pullSecretSteps[num] {
+		if !util.FileExists(cfg.PullSecretPath) {
+			if tempFile := handleMissingPullSecret(log, cfg); tempFile != "" {
+				defer os.Remove(tempFile)
+			}
+		}
+		if err := config.ValidatePullSecret(cfg.PullSecretPath); err != nil {
+			log.Error(fmt.Sprintf("Pull secret validation failed: %v", err))
+			exit(exitConfigError)
+		}
+	}
+
+	events := make(chan wrapper.Event)
+	handled := make(chan struct{})
+	go func() {
+		defer close(handled)
+		for event := range events {
+			if event.Type == wrapper.EventStepFailed && !errors.Is(event.Err, util.ErrInterrupted) {
+				runFailureHooks(log, cfg, event.Label, event.Err)
+			}
+		}
+	}()
+	installer := &wrapper.Installer{
+		Config: cfg,
+		Log:    log,
+		Events: events,
+		WrapExecutor: func(executor util.CommandExecutor) util.CommandExecutor {
+			return logCommands(log, executor)
+		},
+	}
+	ctx, stop := interruptContext()
+	defer stop()
+	result, err := installer.Run(ctx)
+	stop()
+	close(events)
+	<-handled
+	if result == nil {
+		log.Error(err.Error())
+		exit(exitConfigError)
+	}
+
+	printSummary(out, result.Summary)
+	if result.Interrupted {
+		restoreTerminal()
+		exit(exitInterrupted)
+	}
+	if result.Summary.HasErrors() {
+		exit(installExitCode(cfg, result.Summary))
+	}
+}
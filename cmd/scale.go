@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/clobrano/openshift-sts-wrapper/pkg/config"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/logger"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/steps"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/util"
+	"github.com/spf13/cobra"
+)
+
+var (
+	scaleClusterName string
+	scaleWorkers     int
+	scaleTimeout     string
+)
+
+var scaleCmd = &cobra.Command{
+	Use:   "scale",
+	Short: "Change the number of worker nodes of a cluster",
+	Long: `Scales the worker MachineSets of an installed cluster so that it has the
+requested number of workers, spread across its availability zones, and waits
+for the worker nodes to be Ready`,
+	Run: runScale,
+}
+
+func init() {
+	rootCmd.AddCommand(scaleCmd)
+
+	scaleCmd.Flags().StringVar(&scaleClusterName, "cluster-name", "", "Cluster name (required)")
+	scaleCmd.Flags().IntVar(&scaleWorkers, "workers", -1, "Number of worker nodes (required)")
+	scaleCmd.Flags().StringVar(&scaleTimeout, "timeout", "30m", "Maximum time to wait for the worker nodes to be Ready")
+	scaleCmd.Flags().BoolVar(&forceUnlock, "force-unlock", false, "Remove the lock of a run against the cluster that is hung or stale")
+}
+
+func runScale(cmd *cobra.Command, args []string) {
+	log := logger.New(logger.Level(getLogLevel()), nil)
+
+	if scaleClusterName == "" || scaleWorkers < 0 {
+		log.Error("--cluster-name and --workers are required")
+		exit(1)
+	}
+	timeout, err := time.ParseDuration(scaleTimeout)
+	if err != nil || timeout <= 0 {
+		log.Error(fmt.Sprintf("Invalid --timeout %q: must be a positive duration (e.g. 30m)", scaleTimeout))
+		exit(1)
+	}
+
+	lock := lockCluster(log, scaleClusterName)
+	defer lock.Unlock()
+
+	cfg := &config.Config{}
+	cfg.Merge(config.LoadFromEnv())
+	cfg.Merge(loadConfigFile(log))
+	cfg.ClusterName = scaleClusterName
+
+	// Ctrl-C stops waiting; the machines are still created or deleted
+	ctx, stop := interruptContext()
+	defer stop()
+
+	step := steps.NewScaleWorkers(cfg, log, logCommands(log, &util.RealExecutor{Context: ctx}), scaleWorkers, timeout)
+	runDay2Step(log, cfg, step)
+}
@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/clobrano/openshift-sts-wrapper/pkg/config"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/logger"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/util"
+	"github.com/spf13/cobra"
+)
+
+var secretFromFile string
+
+var secretCmd = &cobra.Command{
+	Use:   "secret",
+	Short: "Manage secrets stored in the OS keyring",
+	Long: fmt.Sprintf(`Store and retrieve sensitive inputs in the OS keyring (Secret Service on Linux,
+Keychain on macOS) instead of plaintext files.
+
+Valid secrets: %s`, strings.Join(util.KeyringSecrets, ", ")),
+}
+
+var secretSetCmd = &cobra.Command{
+	Use:   "set <name>",
+	Short: "Store a secret in the OS keyring",
+	Long: `Stores a secret read from --from-file, or from standard input (prompted for
+when standard input is a terminal)`,
+	Args: cobra.ExactArgs(1),
+	Run:  runSecretSet,
+}
+
+var secretGetCmd = &cobra.Command{
+	Use:   "get <name>",
+	Short: "Print a secret stored in the OS keyring",
+	Args:  cobra.ExactArgs(1),
+	Run:   runSecretGet,
+}
+
+func init() {
+	rootCmd.AddCommand(secretCmd)
+	secretCmd.AddCommand(secretSetCmd)
+	secretCmd.AddCommand(secretGetCmd)
+
+	secretSetCmd.Flags().StringVar(&secretFromFile, "from-file", "", "Read the secret from a file (e.g. a downloaded pull-secret.json)")
+}
+
+func runSecretSet(cmd *cobra.Command, args []string) {
+	log := logger.New(logger.Level(getLogLevel()), nil)
+	name := args[0]
+
+	var value string
+	switch {
+	case secretFromFile != "":
+		data, err := os.ReadFile(secretFromFile)
+		if err != nil {
+			log.Error(fmt.Sprintf("Failed to read %s: %v", secretFromFile, err))
+			exit(1)
+		}
+		value = string(data)
+	case isTerminal(os.Stdin):
+		fmt.Printf("Enter %s: ", name)
+		line, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		value = line
+	default:
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			log.Error(fmt.Sprintf("Failed to read standard input: %v", err))
+			exit(1)
+		}
+		value = string(data)
+	}
+	value = strings.TrimSpace(value)
+
+	if value == "" {
+		log.Error("Refusing to store an empty secret")
+		exit(1)
+	}
+	if name == util.KeyringPullSecret {
+		if err := config.ValidatePullSecretContent([]byte(value)); err != nil {
+			log.Error(fmt.Sprintf("Invalid pull secret: %v", err))
+			exit(1)
+		}
+		// Stored on a single line, as the macOS Keychain requires
+		var compact bytes.Buffer
+		if err := json.Compact(&compact, []byte(value)); err == nil {
+			value = compact.String()
+		}
+	}
+
+	if err := util.KeyringSet(name, value); err != nil {
+		log.Error(err.Error())
+		exit(1)
+	}
+	log.Info(fmt.Sprintf("✓ %s stored in the OS keyring", name))
+}
+
+func runSecretGet(cmd *cobra.Command, args []string) {
+	log := logger.New(logger.Level(getLogLevel()), nil)
+
+	value, err := util.KeyringGet(args[0])
+	if err != nil {
+		log.Error(err.Error())
+		exit(1)
+	}
+	if value == "" {
+		log.Error(fmt.Sprintf("%s not found in the OS keyring", args[0]))
+		exit(1)
+	}
+	fmt.Println(value)
+}
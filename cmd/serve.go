@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/clobrano/openshift-sts-wrapper/pkg/logger"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/server"
+	"github.com/spf13/cobra"
+)
+
+var (
+	serveListen          string
+	serveToken           string
+	serveInsecureNoToken bool
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve a REST API to drive installs and cleanups remotely",
+	Long: `Starts an HTTP server exposing installs and cleanups:
+
+  GET  /v1/clusters                 List the clusters and their state
+  POST /v1/clusters                 Start an install: {"clusterName": "...", "config": {...}}
+  GET  /v1/clusters/{name}          Latest run and step journal of a cluster
+  GET  /v1/clusters/{name}/logs     Log of the latest run (?follow=true to stream it)
+  POST /v1/clusters/{name}/cleanup  Start a cleanup
+
+The "config" of an install takes a subset of the keys of the configuration
+file (version, channel, architecture, awsRegion, baseDomain, instance types
+and replicas, zones, private, tags, installTimeout, expiresIn) and overrides
+the configuration file of the server. Installs run non-interactively, so the
+configuration must be complete.
+
+Requests must carry "Authorization: Bearer <token>" (--token or
+OPENSHIFT_STS_SERVE_TOKEN). The server refuses to start without a token
+unless --insecure-no-token is set.`,
+	Run: runServe,
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+
+	serveCmd.Flags().StringVar(&serveListen, "listen", "127.0.0.1:8080", "Address the server listens on")
+	serveCmd.Flags().StringVar(&serveToken, "token", "", "Bearer token required by every request (default: $OPENSHIFT_STS_SERVE_TOKEN)")
+	serveCmd.Flags().BoolVar(&serveInsecureNoToken, "insecure-no-token", false, "Serve without a token: anyone who can reach the server can start installs")
+}
+
+func runServe(cmd *cobra.Command, args []string) {
+	log := logger.New(logger.Level(getLogLevel()), nil)
+
+	token := serveToken
+	if token == "" {
+		token = os.Getenv("OPENSHIFT_STS_SERVE_TOKEN")
+	}
+	if token == "" && !serveInsecureNoToken {
+		log.Error("A token is required: set --token or OPENSHIFT_STS_SERVE_TOKEN (or --insecure-no-token to serve without one)")
+		exit(exitConfigError)
+	}
+	if token == "" {
+		log.Info("⚠  No token set: anyone who can reach the server can start installs")
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		log.Error(fmt.Sprintf("Could not find the wrapper executable: %v", err))
+		exit(1)
+	}
+
+	// The runs read the environment themselves, so only the file is shared
+	base := loadConfigFile(log)
+
+	log.Info(fmt.Sprintf("Serving on http://%s", serveListen))
+	if err := http.ListenAndServe(serveListen, server.New(executable, base, token).Handler()); err != nil {
+		log.Error(err.Error())
+		exit(1)
+	}
+}
@@ -0,0 +1,218 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/clobrano/openshift-sts-wrapper/pkg/logger"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/util"
+	"github.com/spf13/cobra"
+)
+
+var (
+	statusClusterName string
+	statusWatch       bool
+	statusInterval    string
+)
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the state of a cluster installation",
+	Long: `Shows the steps of the latest run against a cluster, from its step journal,
+and, once its kubeconfig exists, the version and the cluster operators of the
+cluster.
+
+With --watch the status is refreshed until the cluster is fully available:
+its version rolled out and all of its cluster operators available and not
+degraded. Run it in a second terminal to follow an install.`,
+	Args: cobra.NoArgs,
+	Run:  runStatus,
+}
+
+func init() {
+	rootCmd.AddCommand(statusCmd)
+
+	statusCmd.Flags().StringVar(&statusClusterName, "cluster-name", "", "Cluster name (required)")
+	statusCmd.Flags().BoolVarP(&statusWatch, "watch", "w", false, "Refresh the status until the cluster is fully available")
+	statusCmd.Flags().StringVar(&statusInterval, "interval", "10s", "Time between refreshes with --watch")
+}
+
+// clusterStatus is the state of a cluster as shown by status
+type clusterStatus struct {
+	ClusterName string                       `json:"clusterName"`
+	Steps       []stepStatus                 `json:"steps"`
+	Version     *clusterVersionStatus        `json:"version,omitempty"`
+	Operators   []util.ClusterOperatorStatus `json:"operators,omitempty"`
+	Error       string                       `json:"error,omitempty"` // Why the cluster could not be queried
+	Available   bool                         `json:"available"`
+}
+
+// stepStatus is a step of the journal
+type stepStatus struct {
+	Name     string  `json:"name"`
+	Status   string  `json:"status"`
+	Duration float64 `json:"durationSeconds,omitempty"`
+	Error    string  `json:"error,omitempty"`
+}
+
+// clusterVersionStatus is the rollout of the cluster version
+type clusterVersionStatus struct {
+	Version   string `json:"version"`
+	Completed bool   `json:"completed"`
+	Progress  string `json:"progress,omitempty"`
+	Failing   string `json:"failing,omitempty"`
+}
+
+func runStatus(cmd *cobra.Command, args []string) {
+	out := redirectOutput()
+	log := logger.New(logger.Level(getLogLevel()), nil)
+
+	if statusClusterName == "" {
+		log.Error("Cluster name is required (use --cluster-name flag)")
+		exit(1)
+	}
+	interval, err := time.ParseDuration(statusInterval)
+	if err != nil || interval <= 0 {
+		log.Error(fmt.Sprintf("Invalid --interval %q: must be a positive duration (e.g. 10s)", statusInterval))
+		exit(1)
+	}
+	if statusWatch && outputFormat == outputJSON {
+		log.Error("--watch only supports the text output")
+		exit(1)
+	}
+	if !util.DirExists(util.GetClusterPath(statusClusterName, "")) {
+		log.Error(fmt.Sprintf("Cluster '%s' not found in artifacts/clusters", statusClusterName))
+		exit(1)
+	}
+
+	// Ctrl-C stops watching
+	ctx, stop := interruptContext()
+	defer stop()
+	// Not audited: the status is polled, and changes nothing
+	executor := &util.RealExecutor{Context: ctx}
+
+	terminal := logger.IsTerminal(out)
+	for {
+		status := readClusterStatus(executor, statusClusterName)
+		if statusWatch && terminal {
+			// Redraw from the top of a cleared screen
+			fmt.Fprint(out, "\033[H\033[2J")
+		}
+		printClusterStatus(out, status)
+		if !statusWatch {
+			return
+		}
+		if status.Available {
+			fmt.Fprintln(out, "\n✓ The cluster is fully available")
+			return
+		}
+		if !terminal {
+			fmt.Fprintln(out)
+		}
+		if err := util.Sleep(executor, interval); err != nil {
+			return
+		}
+	}
+}
+
+// readClusterStatus reads the step journal of the cluster and, once it has a
+// kubeconfig, its version and cluster operators
+func readClusterStatus(executor util.CommandExecutor, clusterName string) *clusterStatus {
+	status := &clusterStatus{ClusterName: clusterName, Steps: []stepStatus{}}
+	if journal, err := util.ReadJournal(clusterName); err == nil {
+		status.Steps = journalSteps(journal)
+	}
+
+	kubeconfigPath := util.GetKubeconfigPath(clusterName)
+	if !util.FileExists(kubeconfigPath) {
+		return status
+	}
+	version, err := util.GetClusterVersion(executor, kubeconfigPath)
+	if err != nil {
+		// The API server isn't up until the bootstrap is well under way
+		status.Error = err.Error()
+		return status
+	}
+	progress, _, _ := strings.Cut(version.Progress, "\n")
+	failing, _, _ := strings.Cut(version.Failing, "\n")
+	status.Version = &clusterVersionStatus{Version: version.Desired, Completed: version.Completed, Progress: progress, Failing: failing}
+	if status.Operators, err = util.GetClusterOperators(executor, kubeconfigPath); err != nil {
+		status.Error = err.Error()
+		return status
+	}
+	status.Available = version.Completed && len(status.Operators) > 0 && len(util.UnhealthyClusterOperators(status.Operators)) == 0
+	return status
+}
+
+// journalSteps returns the steps of the journal in the order they started
+func journalSteps(journal *util.Journal) []stepStatus {
+	names := make([]string, 0, len(journal.Steps))
+	for name := range journal.Steps {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return journal.Steps[names[i]].StartedAt.Before(journal.Steps[names[j]].StartedAt)
+	})
+
+	steps := make([]stepStatus, 0, len(names))
+	for _, name := range names {
+		record := journal.Steps[name]
+		steps = append(steps, stepStatus{Name: name, Status: record.Status, Duration: record.Duration, Error: record.Error})
+	}
+	return steps
+}
+
+// printClusterStatus prints the status in the selected output format
+func printClusterStatus(out *os.File, status *clusterStatus) {
+	if outputFormat == outputJSON {
+		data, err := json.MarshalIndent(status, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to encode status: %v\n", err)
+			return
+		}
+		fmt.Fprintln(out, string(data))
+		return
+	}
+
+	fmt.Fprintf(out, "=== %s (%s) ===\n", status.ClusterName, time.Now().Format("15:04:05"))
+	if len(status.Steps) == 0 {
+		fmt.Fprintln(out, "No steps recorded")
+	}
+	for _, step := range status.Steps {
+		line := fmt.Sprintf("  %-12s %s", step.Status, step.Name)
+		if step.Duration > 0 {
+			line += fmt.Sprintf(" (%s)", time.Duration(step.Duration*float64(time.Second)).Round(time.Second))
+		}
+		if step.Error != "" {
+			line += ": " + step.Error
+		}
+		fmt.Fprintln(out, line)
+	}
+
+	if status.Version != nil {
+		state := "progressing"
+		if status.Version.Completed {
+			state = "completed"
+		}
+		fmt.Fprintf(out, "\nVersion %s: %s\n", status.Version.Version, state)
+		if !status.Version.Completed && status.Version.Progress != "" {
+			fmt.Fprintf(out, "  %s\n", status.Version.Progress)
+		}
+		if status.Version.Failing != "" {
+			fmt.Fprintf(out, "  Failing: %s\n", status.Version.Failing)
+		}
+	}
+	if len(status.Operators) > 0 {
+		fmt.Fprintf(out, "\n%-40s %-9s %-8s %s\n", "OPERATOR", "AVAILABLE", "DEGRADED", "MESSAGE")
+		for _, operator := range status.Operators {
+			fmt.Fprintf(out, "%-40s %-9t %-8t %s\n", operator.Name, operator.Available, operator.Degraded, operator.Message)
+		}
+	}
+	if status.Error != "" {
+		fmt.Fprintf(out, "\nCluster not reachable yet: %s\n", status.Error)
+	}
+}
@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/clobrano/openshift-sts-wrapper/pkg/config"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/logger"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/steps"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/util"
+	"github.com/spf13/cobra"
+)
+
+var stepsCmd = &cobra.Command{
+	Use:   "steps",
+	Short: "Inspect the installation steps",
+}
+
+var stepsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the installation steps and whether they would be skipped",
+	Long: `Lists the installation steps with their name (for --start-from-step,
+--stop-after-step, --only-step and --skip-steps), what they do, and what is
+looked for to skip them as already completed. The step journal of the cluster,
+when it records a step, decides instead, except for the shared artifacts of
+Steps 1-3.
+
+With a cluster name and a release image, from the flags, the environment or
+the config file like install (the release image defaults to the one the
+cluster was installed with), each step shows whether install would skip it
+right now.`,
+	Args: cobra.NoArgs,
+	Run:  runStepsList,
+}
+
+func init() {
+	rootCmd.AddCommand(stepsCmd)
+	stepsCmd.AddCommand(stepsListCmd)
+
+	// The cluster and its release resolve like they do for install
+	for _, name := range []string{"cluster-name", "release-image", "start-from-step"} {
+		stepsListCmd.Flags().AddFlag(installCmd.Flags().Lookup(name))
+	}
+}
+
+// stepListEntry is a step as listed by steps list
+type stepListEntry struct {
+	steps.StepDescription
+	WouldSkip *bool `json:"wouldSkip,omitempty"` // Set for a cluster
+}
+
+func runStepsList(cmd *cobra.Command, args []string) {
+	out := redirectOutput()
+	log := logger.New(logger.Level(getLogLevel()), nil)
+
+	cfg := loadConfig(log)
+	useClusterRelease(cfg)
+
+	var detector *steps.Detector
+	if cfg.ClusterName != "" && cfg.ReleaseImage != "" {
+		if _, err := util.ExtractVersionArch(cfg.ReleaseImage); err != nil {
+			log.Error(fmt.Sprintf("Invalid release image: %v", err))
+			exit(exitConfigError)
+		}
+		detector = steps.NewDetector(cfg)
+		detector.ValidateBinaries(&util.RealExecutor{}, log)
+	}
+
+	var entries []stepListEntry
+	for _, description := range steps.DescribeSteps() {
+		entry := stepListEntry{StepDescription: description}
+		if detector != nil {
+			skip := detector.ShouldSkipStep(description.Number)
+			entry.WouldSkip = &skip
+		}
+		entries = append(entries, entry)
+	}
+
+	if outputFormat == outputJSON {
+		data, err := json.MarshalIndent(entries, "", "  ")
+		checkErr(err)
+		fmt.Fprintln(out, string(data))
+		return
+	}
+	if detector != nil {
+		fmt.Fprintf(out, "Steps of cluster %s, release %s:\n\n", cfg.ClusterName, cfg.ReleaseImage)
+	}
+	for _, entry := range entries {
+		status := ""
+		switch {
+		case entry.WouldSkip == nil:
+		case cfg.StartFromStep > 0 && entry.Number < cfg.StartFromStep:
+			status = "  [would skip: before --start-from-step]"
+		case *entry.WouldSkip:
+			status = "  [would skip]"
+		default:
+			status = "  [would run]"
+		}
+		fmt.Fprintf(out, "%2d  %-26s %s%s\n", entry.Number, entry.Name, entry.Title, status)
+		fmt.Fprintf(out, "    %s\n", entry.Description)
+		fmt.Fprintf(out, "    Skipped when: %s\n", entry.Evidence)
+	}
+}
+
+// useClusterRelease defaults the release image to the one the cluster was
+// installed with and, like install, reuses the digest recorded for it
+func useClusterRelease(cfg *config.Config) {
+	if cfg.ClusterName == "" {
+		return
+	}
+	metadata, err := util.ReadInstallMetadata(util.GetClusterPath(cfg.ClusterName, ""))
+	if err != nil {
+		return
+	}
+	if cfg.ReleaseImage == "" {
+		cfg.ReleaseImage = metadata.ReleaseImage
+	}
+	if cfg.ReleaseDigest == "" && metadata.ReleaseImage == cfg.ReleaseImage {
+		cfg.ReleaseDigest = metadata.ReleaseDigest
+	}
+}
@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/clobrano/openshift-sts-wrapper/pkg/config"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/errors"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/logger"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/util"
+)
+
+// startTrace starts the root span of an install run. Both return values are
+// nil when tracing is not configured, which disables tracing at every call site.
+func startTrace(cfg *config.Config) (*util.Tracer, *util.Span) {
+	if cfg.Tracing.OTLPEndpoint == "" {
+		return nil, nil
+	}
+	tracer := util.NewTracer()
+	root := tracer.StartSpan("install", nil)
+	root.SetAttribute("cluster.name", cfg.ClusterName)
+	root.SetAttribute("release.image", cfg.ReleaseImage)
+	return tracer, root
+}
+
+// exportTrace ends the root span and exports the trace to the OTLP collector,
+// recording its ID in the summary. Failures are reported but do not change the
+// result of the run.
+func exportTrace(log *logger.Logger, cfg *config.Config, tracer *util.Tracer, root *util.Span, summary *errors.Summary) {
+	if tracer == nil {
+		return
+	}
+
+	var err error
+	if summary.HasErrors() {
+		failed := summary.Failed[0]
+		err = fmt.Errorf("%s: %v", failed.StepName, failed.Error)
+	}
+	root.End(err)
+
+	if err := tracer.Export(cfg.Tracing.OTLPEndpoint); err != nil {
+		log.Error(fmt.Sprintf("Failed to export trace: %v", err))
+		return
+	}
+	summary.TraceID = tracer.TraceID()
+	log.Debug(fmt.Sprintf("Trace %s exported", tracer.TraceID()))
+}
@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/clobrano/openshift-sts-wrapper/pkg/errors"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/logger"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/steps"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/util"
+	"github.com/spf13/cobra"
+)
+
+var (
+	upgradeClusterName  string
+	upgradeReleaseImage string
+	upgradeAwsRegion    string
+	upgradeTimeout      string
+)
+
+var upgradeCmd = &cobra.Command{
+	Use:   "upgrade",
+	Short: "Upgrade an STS cluster to a new release",
+	Long: `Upgrades an installed cluster to a new release:
+
+  1. Verifies the target release and that it is an upgrade of the cluster
+  2. Refreshes the IAM roles for the target release (see 'credentials refresh')
+  3. Starts the upgrade with 'oc adm upgrade'
+  4. Waits for the upgrade to complete, showing its progress`,
+	Run: runUpgrade,
+}
+
+func init() {
+	rootCmd.AddCommand(upgradeCmd)
+
+	upgradeCmd.Flags().StringVar(&upgradeClusterName, "cluster-name", "", "Cluster name (required)")
+	upgradeCmd.Flags().StringVar(&upgradeReleaseImage, "release-image", "", "Release image to upgrade to (required)")
+	upgradeCmd.Flags().StringVar(&upgradeAwsRegion, "region", "", "AWS region (optional - will be read from metadata.json if not provided)")
+	upgradeCmd.Flags().StringVar(&upgradeTimeout, "timeout", "3h", "Maximum time to wait for the upgrade to complete")
+	upgradeCmd.Flags().BoolVar(&forceUnlock, "force-unlock", false, "Remove the lock of a run against the cluster that is hung or stale")
+}
+
+func runUpgrade(cmd *cobra.Command, args []string) {
+	started := time.Now()
+	out := redirectOutput()
+	log := logger.New(logger.Level(getLogLevel()), nil)
+	summary := errors.NewSummary()
+
+	if upgradeClusterName == "" || upgradeReleaseImage == "" {
+		log.Error("--cluster-name and --release-image are required")
+		exit(1)
+	}
+	timeout, err := time.ParseDuration(upgradeTimeout)
+	if err != nil || timeout <= 0 {
+		log.Error(fmt.Sprintf("Invalid --timeout %q: must be a positive duration (e.g. 3h)", upgradeTimeout))
+		exit(1)
+	}
+
+	lock := lockCluster(log, upgradeClusterName)
+	defer lock.Unlock()
+
+	cfg := loadClusterConfig(log, upgradeClusterName, upgradeAwsRegion)
+	useVaultAWSCredentials(log, cfg)
+	validateAWSCredentials(log, cfg.AwsProfile)
+	assumeRole(log, cfg)
+
+	// Ctrl-C stops waiting; the upgrade itself goes on in the cluster
+	ctx, stop := interruptContext()
+	defer stop()
+
+	upgradeSteps, err := steps.NewUpgradeSteps(cfg, log, logCommands(log, &util.RealExecutor{Context: ctx}), upgradeReleaseImage, timeout)
+	if err != nil {
+		log.Error(err.Error())
+		exit(1)
+	}
+
+	for i, step := range upgradeSteps {
+		label := fmt.Sprintf("[Upgrade %d/%d] %s", i+1, len(upgradeSteps), step.Name())
+		log.StartStep(label)
+		stepStarted := time.Now()
+		err := step.Execute()
+		summary.AddStep("", label, time.Since(stepStarted), err)
+		if err != nil {
+			log.FailStep(label)
+			log.Error(err.Error())
+			runFailureHooks(log, cfg, label, err)
+			break
+		}
+		log.CompleteStep(label)
+	}
+
+	notify(log, cfg, "upgrade", upgradeClusterName, summary, started)
+	printSummary(out, summary)
+	if summary.HasErrors() {
+		exit(1)
+	}
+}
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/clobrano/openshift-sts-wrapper/pkg/config"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/logger"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/util"
+)
+
+// newVaultClient returns a Vault client for the configuration, exiting on error
+func newVaultClient(log *logger.Logger, cfg *config.Config) *util.VaultClient {
+	client, err := util.NewVaultClient(cfg.Vault.Address)
+	if err != nil {
+		log.Error(fmt.Sprintf("Vault configuration error: %v", err))
+		exit(1)
+	}
+	return client
+}
+
+// useVaultAWSCredentials makes every AWS call and child process use the
+// credentials stored in Vault instead of the AWS profile
+func useVaultAWSCredentials(log *logger.Logger, cfg *config.Config) {
+	if cfg.Vault.AWSCredentials == "" {
+		return
+	}
+
+	creds, err := newVaultClient(log, cfg).ReadAWSCredentials(cfg.Vault.AWSCredentials)
+	if err != nil {
+		log.Error(fmt.Sprintf("Failed to read AWS credentials from Vault: %v", err))
+		exit(1)
+	}
+	util.SetSessionCredentials(creds)
+	log.Info(fmt.Sprintf("✓ Using AWS credentials from Vault (%s)", cfg.Vault.AWSCredentials))
+}
+
+// loadVaultFiles reads the pull secret and SSH public key from Vault into
+// private temporary files, since oc and openshift-install only accept paths.
+// It returns the files to remove when the installation ends; they are also
+// removed when the command exits early.
+func loadVaultFiles(log *logger.Logger, cfg *config.Config) []string {
+	if cfg.Vault.PullSecret == "" && cfg.Vault.SSHKey == "" {
+		return nil
+	}
+	client := newVaultClient(log, cfg)
+
+	var files []string
+	atExit(func() { removeFiles(files) })
+	load := func(ref, pattern, description string) string {
+		value, err := client.ReadField(ref)
+		if err != nil {
+			log.Error(fmt.Sprintf("Failed to read %s from Vault: %v", description, err))
+			exit(1)
+		}
+		path, err := util.WriteTempSecret(pattern, strings.TrimSpace(value)+"\n")
+		if err != nil {
+			log.Error(err.Error())
+			exit(1)
+		}
+		files = append(files, path)
+		log.Info(fmt.Sprintf("✓ Using %s from Vault (%s)", description, ref))
+		return path
+	}
+
+	if cfg.Vault.PullSecret != "" {
+		cfg.PullSecretPath = load(cfg.Vault.PullSecret, "pull-secret-*.json", "pull secret")
+	}
+	if cfg.Vault.SSHKey != "" {
+		cfg.SSHKeyPath = load(cfg.Vault.SSHKey, "ssh-key-*.pub", "SSH public key")
+	}
+	return files
+}
+
+func removeFiles(files []string) {
+	for _, file := range files {
+		os.Remove(file)
+	}
+}
@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/clobrano/openshift-sts-wrapper/pkg/logger"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/steps"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/util"
+	"github.com/spf13/cobra"
+)
+
+var (
+	verifyClusterName string
+	verifyAwsRegion   string
+	verifyChecks      []string
+	verifyHealthGate  string
+	verifyJUnit       string
+)
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Verify the STS configuration of an installed cluster",
+	Long: `Runs the post-install checks of Step 11 against an installed cluster, at any
+time. All checks run unless --checks selects some of them, except
+cluster-operators which only runs by default with a health gate timeout
+(--health-gate-timeout or healthGateTimeout in the config file). The command
+exits with a non-zero status when any check fails.
+
+Available checks:
+` + verifyChecksHelp(),
+	Run: runVerify,
+}
+
+func init() {
+	rootCmd.AddCommand(verifyCmd)
+
+	verifyCmd.Flags().StringVar(&verifyClusterName, "cluster-name", "", "Cluster name (required)")
+	verifyCmd.Flags().StringVar(&verifyAwsRegion, "region", "", "AWS region (optional - will be read from metadata.json if not provided)")
+	verifyCmd.Flags().StringVar(&verifyHealthGate, "health-gate-timeout", "", "Wait up to this for the cluster operators to be healthy (e.g. 30m)")
+	verifyCmd.Flags().StringVar(&verifyJUnit, "junit", "", "Also write the results to this file as JUnit XML")
+	verifyCmd.Flags().StringSliceVar(&verifyChecks, "checks", nil, "Checks to run (comma-separated, default all): "+strings.Join(steps.VerifyCheckIDs(), ", "))
+}
+
+// verifyChecksHelp lists the verification checks for the help of the command
+func verifyChecksHelp() string {
+	var help strings.Builder
+	for _, check := range steps.VerifyChecks {
+		fmt.Fprintf(&help, "  %-20s %s\n", check.ID, check.Description)
+	}
+	return strings.TrimRight(help.String(), "\n")
+}
+
+func runVerify(cmd *cobra.Command, args []string) {
+	out := redirectOutput()
+	log := logger.New(logger.Level(getLogLevel()), nil)
+
+	if verifyClusterName == "" {
+		log.Error("Cluster name is required (use --cluster-name flag)")
+		exit(1)
+	}
+
+	cfg := loadClusterConfig(log, verifyClusterName, verifyAwsRegion)
+	if verifyHealthGate != "" {
+		cfg.HealthGateTimeout = verifyHealthGate
+	}
+	if _, err := cfg.GetHealthGateTimeout(); err != nil {
+		log.Error(err.Error())
+		exit(1)
+	}
+
+	// Ctrl-C stops waiting for the cluster operators
+	ctx, stop := interruptContext()
+	defer stop()
+
+	step, err := steps.NewStep11(cfg, log, logCommands(log, &util.RealExecutor{Context: ctx}))
+	if err != nil {
+		log.Error(err.Error())
+		exit(1)
+	}
+	step.Checks = verifyChecks
+
+	report, err := step.Verify()
+	if err != nil {
+		log.Error(err.Error())
+		exit(1)
+	}
+	if verifyJUnit != "" {
+		writeVerifyJUnit(log, report, verifyJUnit)
+	}
+	printVerifyReport(out, report)
+	if len(report.Failed()) > 0 {
+		exit(1)
+	}
+}
+
+// writeVerifyJUnit writes the verification results as JUnit XML. A missing
+// report (verification not run) writes nothing.
+func writeVerifyJUnit(log *logger.Logger, report *steps.VerifyReport, path string) {
+	if report == nil {
+		log.Info(fmt.Sprintf("⚠  No verification results to write to %s", path))
+		return
+	}
+	if err := report.WriteJUnit(path); err != nil {
+		log.Error(err.Error())
+		return
+	}
+	log.Info(fmt.Sprintf("JUnit report written to %s", path))
+}
+
+// printVerifyReport prints the outcome of the checks in the selected output format
+func printVerifyReport(out *os.File, report *steps.VerifyReport) {
+	if outputFormat == outputJSON {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to encode report: %v\n", err)
+			return
+		}
+		fmt.Fprintln(out, string(data))
+		return
+	}
+
+	fmt.Fprintln(out, "\n=== Verification Summary ===")
+	fmt.Fprintln(out)
+	for _, result := range report.Checks {
+		fmt.Fprintf(out, "  %-20s %-7s %s\n", result.ID, result.Status, result.Message)
+	}
+	fmt.Fprintln(out)
+	fmt.Fprintf(out, "%d/%d checks passed\n", len(report.Checks)-len(report.Failed()), len(report.Checks))
+}
@@ -0,0 +1,175 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/clobrano/openshift-sts-wrapper/pkg/logger"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/util"
+	"github.com/spf13/cobra"
+)
+
+// Build information, set by make build with
+// -ldflags "-X github.com/clobrano/openshift-sts-wrapper/cmd.version=..."
+var (
+	version   = "0.1.0"
+	commit    = ""
+	buildDate = ""
+)
+
+var (
+	versionCheck      bool
+	versionSelfUpdate bool
+)
+
+var versionCmd = &cobra.Command{
+	Use:   "version",
+	Short: "Show the version of the wrapper",
+	Long: `Shows the version of the wrapper, the commit and the date it was built from,
+and the Go version and platform of the binary. Include it in bug reports.
+
+--check queries the GitHub releases of the wrapper for a newer version, and
+--self-update replaces the running binary with the one of the latest release
+for the platform, verified against its published .sha256 checksum. A release
+that does not publish the checksum of the binary is refused.`,
+	Args: cobra.NoArgs,
+	Run:  runVersion,
+}
+
+func init() {
+	rootCmd.AddCommand(versionCmd)
+
+	versionCmd.Flags().BoolVar(&versionCheck, "check", false, "Check the GitHub releases for a newer version")
+	versionCmd.Flags().BoolVar(&versionSelfUpdate, "self-update", false, "Replace the binary with the latest release, if newer")
+}
+
+// buildInfo is the version of the wrapper as shown by version
+type buildInfo struct {
+	Version         string `json:"version"`
+	Commit          string `json:"commit,omitempty"`
+	BuildDate       string `json:"buildDate,omitempty"`
+	GoVersion       string `json:"goVersion"`
+	Platform        string `json:"platform"`
+	Latest          string `json:"latest,omitempty"` // With --check
+	UpdateAvailable bool   `json:"updateAvailable,omitempty"`
+}
+
+func runVersion(cmd *cobra.Command, args []string) {
+	out := redirectOutput()
+	log := logger.New(logger.Level(getLogLevel()), nil)
+
+	info := readBuildInfo()
+	var latest *util.WrapperRelease
+	if versionCheck || versionSelfUpdate {
+		release, err := util.LatestWrapperRelease("")
+		if err != nil {
+			log.Error(err.Error())
+			exit(1)
+		}
+		latest = release
+		info.Latest = release.Version
+		info.UpdateAvailable = util.CompareVersions(release.Version, info.Version) > 0
+	}
+
+	if outputFormat == outputJSON {
+		data, err := json.MarshalIndent(info, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to encode version: %v\n", err)
+			exit(1)
+		}
+		fmt.Fprintln(out, string(data))
+	} else {
+		printBuildInfo(out, info, latest)
+	}
+
+	if versionSelfUpdate && info.UpdateAvailable {
+		selfUpdate(log, latest)
+	}
+}
+
+// readBuildInfo returns the build information of the binary. Binaries built
+// without the ldflags of make build fall back to the VCS information the Go
+// toolchain embeds.
+func readBuildInfo() buildInfo {
+	info := buildInfo{
+		Version:   version,
+		Commit:    commit,
+		BuildDate: buildDate,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+	}
+	if build, ok := debug.ReadBuildInfo(); ok && info.Commit == "" {
+		modified := false
+		for _, setting := range build.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				info.Commit = setting.Value
+			case "vcs.time":
+				if info.BuildDate == "" {
+					info.BuildDate = setting.Value
+				}
+			case "vcs.modified":
+				modified = setting.Value == "true"
+			}
+		}
+		if info.Commit != "" && modified {
+			info.Commit += "-dirty"
+		}
+	}
+	return info
+}
+
+// printBuildInfo prints the build information and, when checked, whether a
+// newer release is available
+func printBuildInfo(out *os.File, info buildInfo, latest *util.WrapperRelease) {
+	fmt.Fprintf(out, "openshift-sts-wrapper %s\n", info.Version)
+	if info.Commit != "" {
+		fmt.Fprintf(out, "  commit:     %s\n", info.Commit)
+	}
+	if info.BuildDate != "" {
+		fmt.Fprintf(out, "  built:      %s\n", info.BuildDate)
+	}
+	fmt.Fprintf(out, "  go version: %s\n", info.GoVersion)
+	fmt.Fprintf(out, "  platform:   %s\n", info.Platform)
+
+	if latest == nil {
+		return
+	}
+	if !info.UpdateAvailable {
+		fmt.Fprintf(out, "\n✓ Up to date (latest release: %s)\n", latest.Version)
+		return
+	}
+	fmt.Fprintf(out, "\n⚠  Version %s is available: %s\n", latest.Version, latest.URL)
+	if !versionSelfUpdate {
+		fmt.Fprintf(out, "Update with: %s version --self-update\n", os.Args[0])
+	}
+}
+
+// selfUpdate replaces the running binary with the one of the release
+func selfUpdate(log *logger.Logger, release *util.WrapperRelease) {
+	path, err := os.Executable()
+	if err == nil {
+		path, err = filepath.EvalSymlinks(path)
+	}
+	if err != nil {
+		log.Error(fmt.Sprintf("Cannot find the running binary: %v", err))
+		exit(1)
+	}
+
+	assetName := util.WrapperAssetName(runtime.GOOS, runtime.GOARCH)
+	log.Info(fmt.Sprintf("Downloading %s %s...", assetName, release.Version))
+	if err := util.ReplaceExecutable(release, assetName, path); err != nil {
+		log.Error(err.Error())
+		if errors.Is(err, fs.ErrPermission) {
+			log.Info(fmt.Sprintf("Run it again as a user that can write %s", path))
+		}
+		exit(1)
+	}
+	log.Info(fmt.Sprintf("✓ Updated %s to %s", path, release.Version))
+}
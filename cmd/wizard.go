@@ -0,0 +1,385 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/clobrano/openshift-sts-wrapper/pkg/config"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/logger"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/preflight"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/util"
+)
+
+// wizardInstanceTypes are offered by the instance type picker, when available
+// in the region
+var wizardInstanceTypes = []string{
+	"m5.xlarge", "m5.2xlarge", "m5.4xlarge",
+	"m6i.xlarge", "m6i.2xlarge", "m6i.4xlarge",
+	"m7i.xlarge", "m7i.2xlarge", "m7i.4xlarge",
+}
+
+// wizardArm64InstanceTypes are offered instead for arm64 releases
+var wizardArm64InstanceTypes = []string{
+	"m6g.xlarge", "m6g.2xlarge", "m6g.4xlarge",
+	"m7g.xlarge", "m7g.2xlarge", "m7g.4xlarge",
+}
+
+var (
+	// releaseVersionPattern matches the versions resolved by --version
+	releaseVersionPattern = regexp.MustCompile(`^\d+\.\d+\.\d+(-\S+)?$`)
+	// releaseChannelPattern matches the channels of --channel
+	releaseChannelPattern = regexp.MustCompile(`^(stable|fast|candidate|eus)-\d+\.\d+$`)
+	// instanceTypePattern matches EC2 instance types
+	instanceTypePattern = regexp.MustCompile(`^[a-z0-9-]+\.[a-z0-9-]+$`)
+)
+
+// wizard prompts for the settings of install --interactive
+type wizard struct {
+	log      *logger.Logger
+	cfg      *config.Config
+	executor util.CommandExecutor
+	reader   *bufio.Reader
+	answers  []wizardAnswer
+}
+
+// wizardAnswer is a setting answered in the wizard, as saved to the config file
+type wizardAnswer struct {
+	key   string
+	value string
+}
+
+// runInstallWizard prompts for every setting of the installation that no flag,
+// config file or environment variable sets, shows them for review, and offers
+// to save the answers to the config file. Step 4 then runs without the prompts
+// of openshift-install.
+func runInstallWizard(log *logger.Logger, cfg *config.Config, executor util.CommandExecutor) {
+	if nonInteractive {
+		log.Error("Configuration error: --interactive prompts for the settings, it can't be used with --non-interactive or --ci")
+		exit(exitConfigError)
+	}
+	if !logger.IsTerminal(os.Stdin) {
+		log.Error("--interactive needs a terminal")
+		exit(exitConfigError)
+	}
+
+	w := &wizard{log: log, cfg: cfg, executor: executor, reader: bufio.NewReader(os.Stdin)}
+	fmt.Println("Answer the questions below, Enter selects the [default].")
+	fmt.Println("Settings given with flags, the config file or the environment are not asked.")
+	fmt.Println()
+
+	w.askClusterName()
+	w.askRelease()
+	w.askAWSProfile()
+	w.askRegion()
+	w.askBaseDomain()
+	w.askInstanceType()
+	w.askWorkerReplicas()
+	w.askSSHKey()
+	w.askPullSecret()
+
+	w.review()
+	if !w.confirm("Start the installation with these settings?", true) {
+		log.Info("Installation cancelled.")
+		exit(0)
+	}
+	w.save()
+}
+
+// missing reports whether a setting has no value, or only its default
+func (w *wizard) missing(key, value string) bool {
+	return value == "" || w.cfg.Sources[key] == config.SourceDefault
+}
+
+// answer applies an answer to the configuration, recording its source
+func (w *wizard) answer(key, value string, answer *config.Config) {
+	w.cfg.MergeFrom(answer, config.SourcePrompt)
+	w.answers = append(w.answers, wizardAnswer{key: key, value: value})
+}
+
+func (w *wizard) askClusterName() {
+	if w.cfg.ClusterName != "" {
+		return
+	}
+	name := w.ask("Cluster name", "", func(value string) error {
+		return config.ValidateClusterName(value, w.cfg.BaseDomain, w.cfg.ResourcePrefix)
+	})
+	// Runtime only: not saved to the config file
+	w.cfg.ClusterName = name
+}
+
+func (w *wizard) askRelease() {
+	if w.cfg.ReleaseImage != "" || w.cfg.Version != "" || w.cfg.Channel != "" {
+		return
+	}
+	release := w.ask("OpenShift version (e.g. 4.15.12), channel (e.g. stable-4.15) or release image", "", func(value string) error {
+		if strings.Contains(value, "/") || releaseVersionPattern.MatchString(value) || releaseChannelPattern.MatchString(value) {
+			return nil
+		}
+		return fmt.Errorf("%q is not a version, a channel or a release image", value)
+	})
+	switch {
+	case strings.Contains(release, "/"):
+		w.answer("releaseImage", release, &config.Config{ReleaseImage: release})
+	case releaseChannelPattern.MatchString(release):
+		w.answer("channel", release, &config.Config{Channel: release})
+	default:
+		w.answer("version", release, &config.Config{Version: release})
+	}
+}
+
+func (w *wizard) askAWSProfile() {
+	if !w.missing("awsProfile", w.cfg.AwsProfile) {
+		return
+	}
+	profiles, err := util.ListAWSProfiles()
+	if err != nil {
+		w.log.Debug(fmt.Sprintf("Could not list the AWS profiles: %v", err))
+	}
+	profile := w.choose("AWS profile", profiles, w.cfg.AwsProfile, nil)
+	w.answer("awsProfile", profile, &config.Config{AwsProfile: profile})
+}
+
+func (w *wizard) askRegion() {
+	if !w.missing("awsRegion", w.cfg.AwsRegion) {
+		return
+	}
+	regions, err := preflight.ListRegions(w.executor, w.cfg)
+	if err != nil {
+		w.log.Debug(fmt.Sprintf("Could not list the AWS regions: %v", err))
+	}
+	region := w.choose("AWS region", regions, w.cfg.AwsRegion, config.ValidateRegion)
+	w.answer("awsRegion", region, &config.Config{AwsRegion: region})
+}
+
+func (w *wizard) askBaseDomain() {
+	if !w.missing("baseDomain", w.cfg.BaseDomain) {
+		return
+	}
+	domains, err := preflight.ListPublicDomains(w.executor, w.cfg)
+	if err != nil {
+		w.log.Debug(fmt.Sprintf("Could not list the Route53 hosted zones: %v", err))
+	}
+	domain := w.choose("Base domain (a public Route53 hosted zone)", domains, w.cfg.BaseDomain, func(value string) error {
+		return config.ValidateClusterName(w.cfg.ClusterName, value, w.cfg.ResourcePrefix)
+	})
+	w.answer("baseDomain", domain, &config.Config{BaseDomain: domain})
+}
+
+func (w *wizard) askInstanceType() {
+	if !w.missing("instanceType", w.cfg.InstanceType) || (w.cfg.ControlPlaneType != "" && w.cfg.WorkerType != "") {
+		return
+	}
+	// The default follows the architecture of the release just chosen
+	w.cfg.SetDefaults()
+	candidates := wizardInstanceTypes
+	if w.cfg.ClusterArchitecture() == "arm64" {
+		candidates = wizardArm64InstanceTypes
+	}
+	instanceTypes, err := preflight.OfferedInstanceTypes(w.executor, w.cfg, candidates)
+	if err != nil {
+		w.log.Debug(fmt.Sprintf("Could not list the instance types of %s: %v", w.cfg.AwsRegion, err))
+		instanceTypes = candidates
+	}
+	instanceType := w.choose("Instance type of the machines", instanceTypes, w.cfg.InstanceType, func(value string) error {
+		if !instanceTypePattern.MatchString(value) {
+			return fmt.Errorf("%q is not an instance type (e.g. m6i.xlarge)", value)
+		}
+		if arch := w.cfg.ClusterArchitecture(); arch != "" && config.InstanceTypeArchitecture(value) != arch {
+			return fmt.Errorf("%s is an %s instance type, but the release is %s", value, config.InstanceTypeArchitecture(value), arch)
+		}
+		return nil
+	})
+	w.answer("instanceType", instanceType, &config.Config{InstanceType: instanceType})
+}
+
+func (w *wizard) askWorkerReplicas() {
+	if w.cfg.WorkerPoolReplicas() != nil {
+		return
+	}
+	value := w.ask("Number of compute machines (0 for a compact cluster)", "3", func(value string) error {
+		if replicas, err := strconv.Atoi(value); err != nil || replicas < 0 {
+			return fmt.Errorf("%q is not a number of machines", value)
+		}
+		return nil
+	})
+	replicas, _ := strconv.Atoi(value)
+	w.answer("workerReplicas", value, &config.Config{WorkerReplicas: &replicas})
+}
+
+func (w *wizard) askSSHKey() {
+	if w.cfg.SSHKeyPath != "" {
+		return
+	}
+	label, defaultKey := "SSH public key of the nodes", util.FindDefaultSSHKey()
+	if defaultKey == "" {
+		label += " (empty to generate a key pair)"
+	}
+	path := w.ask(label, defaultKey, func(value string) error {
+		if value != "" && !util.FileExists(value) {
+			return fmt.Errorf("%s does not exist", value)
+		}
+		return nil
+	})
+	if path == "" {
+		// Generated by Step 4 in the cluster directory, like ensureSSHKey does
+		w.cfg.SSHKeyPath = util.GetClusterPath(w.cfg.ClusterName, "ssh/id_ed25519.pub")
+		w.cfg.GenerateSSHKey = true
+		return
+	}
+	w.answer("sshKeyPath", path, &config.Config{SSHKeyPath: path})
+}
+
+func (w *wizard) askPullSecret() {
+	if !w.missing("pullSecretPath", w.cfg.PullSecretPath) {
+		return
+	}
+	path := w.ask("Pull secret file (https://console.redhat.com/openshift/install/pull-secret)", w.cfg.PullSecretPath, func(value string) error {
+		if util.FileExists(value) {
+			return config.ValidatePullSecret(value)
+		}
+		if w.cfg.OCMToken == "" {
+			return fmt.Errorf("%s does not exist", value)
+		}
+		// Downloaded with the OCM token
+		return nil
+	})
+	w.answer("pullSecretPath", path, &config.Config{PullSecretPath: path})
+}
+
+// review prints the settings of the installation
+func (w *wizard) review() {
+	cfg := w.cfg
+	release := cfg.ReleaseImage
+	if release == "" {
+		release = strings.TrimSpace(cfg.Version + " " + cfg.Channel)
+	}
+	sshKey := cfg.SSHKeyPath
+	if cfg.GenerateSSHKey {
+		sshKey += " (generated)"
+	}
+	controlPlane, workers := 3, 3
+	if cfg.ControlPlaneReplicas != nil {
+		controlPlane = *cfg.ControlPlaneReplicas
+	}
+	if replicas := cfg.WorkerPoolReplicas(); replicas != nil {
+		workers = *replicas
+	}
+
+	fmt.Println()
+	fmt.Println("Review:")
+	for _, row := range [][2]string{
+		{"Cluster name", cfg.ClusterName},
+		{"Release", release},
+		{"AWS profile", cfg.AwsProfile},
+		{"AWS region", cfg.AwsRegion},
+		{"Base domain", cfg.BaseDomain},
+		{"Control plane", fmt.Sprintf("%d × %s", controlPlane, cfg.ControlPlaneInstanceType())},
+		{"Compute", fmt.Sprintf("%d × %s", workers, cfg.WorkerInstanceType())},
+		{"SSH key", sshKey},
+		{"Pull secret", cfg.PullSecretPath},
+	} {
+		fmt.Printf("  %-15s %s\n", row[0]+":", row[1])
+	}
+	fmt.Println()
+}
+
+// save offers to write the answers to the config file, in the profile
+// selected by --profile if any
+func (w *wizard) save() {
+	if len(w.answers) == 0 {
+		return
+	}
+	path := configFilePath()
+	if !w.confirm(fmt.Sprintf("Save the answers to %s?", path), false) {
+		return
+	}
+	for _, answer := range w.answers {
+		if _, err := config.SetKey(path, configKey(answer.key), answer.value); err != nil {
+			w.log.Info(fmt.Sprintf("⚠  Could not save %s: %v", answer.key, err))
+		}
+	}
+	w.log.Info(fmt.Sprintf("✓ Saved the answers to %s", path))
+}
+
+// ask prompts for a value until validate accepts it; an empty answer selects
+// the default
+func (w *wizard) ask(label, defaultValue string, validate func(string) error) string {
+	for {
+		if defaultValue != "" {
+			fmt.Printf("%s [%s]: ", label, defaultValue)
+		} else {
+			fmt.Printf("%s: ", label)
+		}
+		line, err := w.reader.ReadString('\n')
+		if err != nil && line == "" {
+			w.log.Error("No answer, installation cancelled")
+			exit(exitConfigError)
+		}
+		value := strings.TrimSpace(line)
+		if value == "" {
+			value = defaultValue
+		}
+		// Only the questions whose validation accepts it can be left empty
+		if value == "" && (validate == nil || validate("") != nil) {
+			fmt.Println("  A value is required")
+			continue
+		}
+		if validate != nil {
+			if err := validate(value); err != nil {
+				fmt.Printf("  %v\n", err)
+				continue
+			}
+		}
+		return value
+	}
+}
+
+// choose prompts for one of the numbered choices, or any other value accepted
+// by validate. Without choices, it is a plain question.
+func (w *wizard) choose(label string, choices []string, defaultValue string, validate func(string) error) string {
+	if len(choices) == 0 {
+		return w.ask(label, defaultValue, validate)
+	}
+	fmt.Printf("%s:\n", label)
+	for i, choice := range choices {
+		fmt.Printf("  %2d) %s\n", i+1, choice)
+	}
+	value := w.ask("Number or value", defaultValue, func(value string) error {
+		if index, err := strconv.Atoi(value); err == nil {
+			if index < 1 || index > len(choices) {
+				return fmt.Errorf("choose a number between 1 and %d", len(choices))
+			}
+			return nil
+		}
+		if validate != nil {
+			return validate(value)
+		}
+		return nil
+	})
+	if index, err := strconv.Atoi(value); err == nil {
+		return choices[index-1]
+	}
+	return value
+}
+
+// confirm prompts a yes/no question
+func (w *wizard) confirm(question string, defaultYes bool) bool {
+	options := "[y/N]"
+	if defaultYes {
+		options = "[Y/n]"
+	}
+	fmt.Printf("%s %s: ", question, options)
+	line, _ := w.reader.ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(line)) {
+	case "y", "yes":
+		return true
+	case "n", "no":
+		return false
+	default:
+		return defaultYes
+	}
+}
@@ -0,0 +1,335 @@
+// Package batch installs several clusters from one configuration at once, e.g.
+// a fleet of identical clusters for a test matrix.
+//
+// Like the server, every install runs the wrapper itself as a child process.
+// The shared artifacts of the release are extracted once, by the first
+// cluster, before the installs run concurrently.
+package batch
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/clobrano/openshift-sts-wrapper/pkg/config"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/errors"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/util"
+)
+
+// Cluster states
+const (
+	ClusterPending   = "pending"
+	ClusterRunning   = "running"
+	ClusterSucceeded = "succeeded"
+	ClusterFailed    = "failed"
+)
+
+// pollInterval is how often the journals of the running installs are read
+var pollInterval = 2 * time.Second
+
+// lastSharedStep is the last of the steps extracting the shared artifacts of
+// the release, which only the first cluster runs before the others start
+const lastSharedStep = 3
+
+// Cluster is the install of a cluster of the batch
+type Cluster struct {
+	Name       string          `json:"name"`
+	Status     string          `json:"status"`
+	Step       string          `json:"step,omitempty"` // Running or failed step, from the journal
+	StartedAt  *time.Time      `json:"startedAt,omitempty"`
+	FinishedAt *time.Time      `json:"finishedAt,omitempty"`
+	ExitCode   *int            `json:"exitCode,omitempty"`
+	Error      string          `json:"error,omitempty"`
+	LogPath    string          `json:"log"`
+	Summary    json.RawMessage `json:"summary,omitempty"` // JSON summary printed by install
+}
+
+// Elapsed returns how long the install has been running, or ran
+func (c *Cluster) Elapsed(now time.Time) time.Duration {
+	if c.StartedAt == nil {
+		return 0
+	}
+	if c.FinishedAt != nil {
+		now = *c.FinishedAt
+	}
+	return now.Sub(*c.StartedAt)
+}
+
+// FailedStep returns the first failed step of the summary of the install,
+// with its error and hint
+func (c *Cluster) FailedStep() (errors.StepResult, bool) {
+	var summary struct {
+		Steps []errors.StepResult `json:"steps"`
+	}
+	json.Unmarshal(c.Summary, &summary)
+	for _, step := range summary.Steps {
+		if step.Status == errors.StatusFailed {
+			return step, true
+		}
+	}
+	return errors.StepResult{}, false
+}
+
+// ConsoleURL returns the console URL of the summary of the install, if any
+func (c *Cluster) ConsoleURL() string {
+	var summary struct {
+		ConsoleURL string `json:"consoleURL"`
+	}
+	json.Unmarshal(c.Summary, &summary)
+	return summary.ConsoleURL
+}
+
+// GetBatchDir returns the directory of the logs of the batch installs
+func GetBatchDir() string {
+	return filepath.Join("artifacts", "batch")
+}
+
+// ClusterNames returns the names of count clusters: the prefix followed by
+// their number, starting from 1
+func ClusterNames(prefix string, count int) []string {
+	names := make([]string, count)
+	for i := range names {
+		names[i] = fmt.Sprintf("%s%d", prefix, i+1)
+	}
+	return names
+}
+
+// Batch runs the installs of a batch of clusters
+type Batch struct {
+	executable string
+	args       []string // Arguments of every install, e.g. --config
+	parallel   int
+
+	mu       sync.Mutex
+	clusters []*Cluster
+}
+
+// New creates the batch installing the named clusters with the given wrapper
+// executable. args are passed to every install, and at most parallel installs
+// run at a time (all of them if parallel is 0).
+func New(executable string, names []string, args []string, parallel int) *Batch {
+	if parallel <= 0 || parallel > len(names) {
+		parallel = len(names)
+	}
+	b := &Batch{executable: executable, args: args, parallel: parallel}
+	for _, name := range names {
+		b.clusters = append(b.clusters, &Cluster{
+			Name:    name,
+			Status:  ClusterPending,
+			LogPath: filepath.Join(GetBatchDir(), name+".log"),
+		})
+	}
+	return b
+}
+
+// Clusters returns a copy of the state of the installs
+func (b *Batch) Clusters() []Cluster {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	clusters := make([]Cluster, len(b.clusters))
+	for i, cluster := range b.clusters {
+		clusters[i] = *cluster
+	}
+	return clusters
+}
+
+// Failed returns the number of installs that failed
+func (b *Batch) Failed() int {
+	failed := 0
+	for _, cluster := range b.Clusters() {
+		if cluster.Status == ClusterFailed {
+			failed++
+		}
+	}
+	return failed
+}
+
+// Run installs the clusters and returns once every install is done. progress,
+// if set, is called with the state of the installs every time it changes.
+func (b *Batch) Run(progress func([]Cluster)) {
+	if err := util.EnsureDir(GetBatchDir()); err != nil {
+		b.failPending(err.Error())
+		return
+	}
+
+	stop := make(chan struct{})
+	watched := make(chan struct{})
+	go func() {
+		b.watch(stop, progress)
+		close(watched)
+	}()
+	defer func() {
+		close(stop)
+		<-watched
+	}()
+
+	// The first cluster extracts the shared artifacts, which the other
+	// installs then find and reuse
+	first := b.clusters[0]
+	if !b.prepare(first) {
+		b.failPending(fmt.Sprintf("the shared artifacts could not be extracted by %s", first.Name))
+		return
+	}
+
+	slots := make(chan struct{}, b.parallel)
+	var wg sync.WaitGroup
+	for i, cluster := range b.clusters {
+		var args []string
+		if i == 0 {
+			args = []string{"--start-from-step", config.StepName(lastSharedStep + 1)}
+		}
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(cluster *Cluster, args []string) {
+			defer wg.Done()
+			b.install(cluster, args...)
+			<-slots
+		}(cluster, args)
+	}
+	wg.Wait()
+}
+
+// prepare runs the steps of a cluster extracting the shared artifacts, and
+// returns whether they succeeded
+func (b *Batch) prepare(cluster *Cluster) bool {
+	b.start(cluster)
+	code, summary, err := b.run(cluster, "--stop-after-step", config.StepName(lastSharedStep))
+	if err == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.finish(cluster, code, summary, err)
+	return false
+}
+
+// install runs the install of a cluster
+func (b *Batch) install(cluster *Cluster, extraArgs ...string) {
+	b.start(cluster)
+	code, summary, err := b.run(cluster, extraArgs...)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.finish(cluster, code, summary, err)
+}
+
+// start marks an install as running. The log of a previous batch is removed
+// when the install starts.
+func (b *Batch) start(cluster *Cluster) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if cluster.StartedAt == nil {
+		now := time.Now().UTC()
+		cluster.StartedAt = &now
+		os.Remove(cluster.LogPath)
+	}
+	cluster.Status = ClusterRunning
+}
+
+// run runs the install of a cluster with extraArgs, its logs appended to
+// the log of the cluster, and returns its exit code and JSON summary
+func (b *Batch) run(cluster *Cluster, extraArgs ...string) (int, string, error) {
+	logFile, err := os.OpenFile(cluster.LogPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return -1, "", fmt.Errorf("failed to create log file: %w", err)
+	}
+	defer logFile.Close()
+
+	args := append([]string{"install", "--cluster-name", cluster.Name, "--non-interactive", "--output", "json", "--name-suffix", config.NameSuffixNone}, b.args...)
+	var summary strings.Builder
+	cmd := exec.Command(b.executable, append(args, extraArgs...)...)
+	cmd.Stdout = &summary
+	cmd.Stderr = logFile
+	if err := cmd.Run(); err != nil {
+		if cmd.ProcessState == nil {
+			return -1, "", fmt.Errorf("failed to start install: %w", err)
+		}
+		return cmd.ProcessState.ExitCode(), summary.String(), fmt.Errorf("install failed (%v), see %s", err, cluster.LogPath)
+	}
+	return 0, summary.String(), nil
+}
+
+// finish records the outcome of an install. The lock must be held.
+func (b *Batch) finish(cluster *Cluster, code int, summary string, err error) {
+	now := time.Now().UTC()
+	cluster.FinishedAt = &now
+	cluster.ExitCode = &code
+	if json.Valid([]byte(summary)) {
+		cluster.Summary = json.RawMessage(summary)
+	}
+	cluster.Status, cluster.Step = ClusterSucceeded, ""
+	if err != nil {
+		cluster.Status, cluster.Error = ClusterFailed, err.Error()
+		if step, ok := cluster.FailedStep(); ok {
+			cluster.Step = step.Name
+		}
+	}
+}
+
+// failPending fails the installs that did not start
+func (b *Batch) failPending(message string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, cluster := range b.clusters {
+		if cluster.Status == ClusterPending {
+			now := time.Now().UTC()
+			cluster.FinishedAt = &now
+			cluster.Status, cluster.Error = ClusterFailed, message
+		}
+	}
+}
+
+// watch updates the running step of the installs from their journal, and
+// reports every change of the state of the batch until stop is closed
+func (b *Batch) watch(stop chan struct{}, progress func([]Cluster)) {
+	var last string
+	report := func() {
+		b.updateSteps()
+		clusters := b.Clusters()
+		var state strings.Builder
+		for _, cluster := range clusters {
+			fmt.Fprintf(&state, "%s %s %s\n", cluster.Name, cluster.Status, cluster.Step)
+		}
+		if state.String() != last && progress != nil {
+			progress(clusters)
+		}
+		last = state.String()
+	}
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			report()
+			return
+		case <-ticker.C:
+			report()
+		}
+	}
+}
+
+// updateSteps sets the step of the running installs to the step running in
+// their journal
+func (b *Batch) updateSteps() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, cluster := range b.clusters {
+		if cluster.Status != ClusterRunning {
+			continue
+		}
+		journal, err := util.ReadJournal(cluster.Name)
+		if err != nil {
+			continue
+		}
+		for _, name := range config.StepNames {
+			if record, ok := journal.Steps[name]; ok && record.Status == util.StepRunning {
+				cluster.Step = name
+			}
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package batch
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeWrapper writes a script standing for the wrapper executable: it appends
+// its arguments to calls, logs to stderr and prints a JSON summary. The
+// install of failingCluster fails at Step 10.
+func fakeWrapper(t *testing.T, failingCluster string) string {
+	path := filepath.Join(t.TempDir(), "openshift-sts-wrapper")
+	script := `#!/bin/sh
+echo "$@" >> calls
+echo "installing $3" >&2
+if [ "$3" = "` + failingCluster + `" ]; then
+  echo '{"status": "partial-success", "steps": [{"name": "[Step 10] Deploy cluster", "status": "failed", "error": "timeout"}]}'
+  exit 1
+fi
+echo '{"status": "success", "steps": [], "consoleURL": "https://console-openshift-console.apps.'$3'.example.com"}'
+`
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatalf("Failed to write fake wrapper: %v", err)
+	}
+	return path
+}
+
+func setupDir(t *testing.T) {
+	tmpDir := t.TempDir()
+	originalWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	t.Cleanup(func() { os.Chdir(originalWd) })
+	pollInterval = 10 * time.Millisecond
+}
+
+func TestClusterNames(t *testing.T) {
+	names := ClusterNames("qe-", 3)
+	if strings.Join(names, ",") != "qe-1,qe-2,qe-3" {
+		t.Errorf("Unexpected names %v", names)
+	}
+}
+
+func TestBatchRun(t *testing.T) {
+	setupDir(t)
+
+	b := New(fakeWrapper(t, "qe-2"), ClusterNames("qe-", 3), []string{"--config", "fleet.yaml"}, 2)
+	var mu sync.Mutex
+	reports := 0
+	b.Run(func(clusters []Cluster) {
+		mu.Lock()
+		reports++
+		mu.Unlock()
+	})
+
+	data, err := os.ReadFile("calls")
+	if err != nil {
+		t.Fatalf("Failed to read calls: %v", err)
+	}
+	calls := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(calls) != 4 {
+		t.Fatalf("Expected 4 installs, got %v", calls)
+	}
+	// The shared artifacts are extracted first, by the first cluster only
+	if calls[0] != "install --cluster-name qe-1 --non-interactive --output json --name-suffix none --config fleet.yaml --stop-after-step extract-ccoctl" {
+		t.Errorf("Unexpected first call %q", calls[0])
+	}
+	if !strings.Contains(string(data), "install --cluster-name qe-1 --non-interactive --output json --name-suffix none --config fleet.yaml --start-from-step create-install-config\n") {
+		t.Errorf("Expected the first cluster to resume after the shared steps, got %v", calls)
+	}
+
+	clusters := b.Clusters()
+	if clusters[0].Status != ClusterSucceeded || clusters[2].Status != ClusterSucceeded {
+		t.Errorf("Expected qe-1 and qe-3 to succeed, got %+v", clusters)
+	}
+	if clusters[0].ConsoleURL() != "https://console-openshift-console.apps.qe-1.example.com" {
+		t.Errorf("Expected the summary of the install, got %s", clusters[0].Summary)
+	}
+	failed := clusters[1]
+	if failed.Status != ClusterFailed || *failed.ExitCode != 1 || failed.Step != "[Step 10] Deploy cluster" {
+		t.Errorf("Expected qe-2 to fail at Step 10, got %+v", failed)
+	}
+	if b.Failed() != 1 {
+		t.Errorf("Expected 1 failed install, got %d", b.Failed())
+	}
+	if log, _ := os.ReadFile(clusters[0].LogPath); strings.Count(string(log), "installing qe-1") != 2 {
+		t.Errorf("Expected the log of both runs of qe-1, got %q", log)
+	}
+	if reports == 0 {
+		t.Error("Expected progress reports")
+	}
+}
+
+func TestBatchRunSharedStepsFail(t *testing.T) {
+	setupDir(t)
+
+	b := New(fakeWrapper(t, "qe-1"), ClusterNames("qe-", 2), nil, 0)
+	b.Run(nil)
+
+	data, _ := os.ReadFile("calls")
+	if strings.Count(string(data), "\n") != 1 {
+		t.Errorf("Expected no install after the shared steps failed, got %s", data)
+	}
+	for _, cluster := range b.Clusters() {
+		if cluster.Status != ClusterFailed {
+			t.Errorf("Expected %s to fail, got %+v", cluster.Name, cluster)
+		}
+	}
+	if cluster := b.Clusters()[1]; !strings.Contains(cluster.Error, "shared artifacts could not be extracted by qe-1") {
+		t.Errorf("Unexpected error %q", cluster.Error)
+	}
+}
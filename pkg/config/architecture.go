@@ -0,0 +1,82 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Default instance types of the machine pools, by cluster architecture
+const (
+	DefaultInstanceType      = "m5.4xlarge"
+	DefaultArm64InstanceType = "m6g.4xlarge"
+)
+
+// gravitonPattern matches the instance families of the AWS Graviton (arm64)
+// processors: a generation followed by g (m6g, c7gn, r8gd, im4gn...), and a1
+var gravitonPattern = regexp.MustCompile(`^([a-z]+[0-9]+g[a-z]*|a1)\.`)
+
+// InstanceTypeArchitecture returns the architecture of an instance type, arm64
+// or amd64
+func InstanceTypeArchitecture(instanceType string) string {
+	if gravitonPattern.MatchString(instanceType) {
+		return "arm64"
+	}
+	return "amd64"
+}
+
+// DefaultInstanceTypeFor returns the default instance type of the machine
+// pools of a cluster architecture (amd64, arm64)
+func DefaultInstanceTypeFor(architecture string) string {
+	if architecture == "arm64" {
+		return DefaultArm64InstanceType
+	}
+	return DefaultInstanceType
+}
+
+// ClusterArchitecture returns the architecture of the nodes of the release,
+// amd64 or arm64, from the tag of the release image or else the configured
+// architecture. It is empty when unknown, or for a multi-arch release.
+func (c *Config) ClusterArchitecture() string {
+	releaseArch := c.Architecture
+	if image := c.ReleaseImage; image != "" && !strings.Contains(image, "@") {
+		tag := image[strings.LastIndex(image, ":")+1:]
+		for _, arch := range []string{"x86_64", "aarch64", "multi"} {
+			if strings.HasSuffix(tag, "-"+arch) {
+				releaseArch = arch
+			}
+		}
+	}
+	switch releaseArch {
+	case "x86_64":
+		return "amd64"
+	case "aarch64":
+		return "arm64"
+	}
+	return ""
+}
+
+// architectureErrors checks that the instance types of the machine pools run
+// the architecture of the release
+func architectureErrors(cfg *Config) []error {
+	arch := cfg.ClusterArchitecture()
+	if arch == "" {
+		return nil
+	}
+	types := []struct{ key, instanceType string }{
+		{"instanceType", cfg.InstanceType},
+		{"controlPlaneType", cfg.ControlPlaneType},
+		{"workerType", cfg.WorkerType},
+	}
+	for _, pool := range cfg.ComputePools {
+		types = append(types, struct{ key, instanceType string }{"computePools." + pool.Name + ".instanceType", pool.InstanceType})
+	}
+	var errs []error
+	for _, t := range types {
+		if t.instanceType != "" && InstanceTypeArchitecture(t.instanceType) != arch {
+			errs = append(errs, fmt.Errorf("%s %s is an %s instance type, but the release is %s (e.g. use %s)",
+				t.key, t.instanceType, InstanceTypeArchitecture(t.instanceType), arch, DefaultInstanceTypeFor(arch)))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,270 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// runtimeOnlyKeys are settings that exist as CLI flags but are ignored in config files
+var runtimeOnlyKeys = map[string]string{
+	"clusterName":     "--cluster-name",
+	"startFromStep":   "--start-from-step",
+	"stopAfterStep":   "--stop-after-step",
+	"onlyStep":        "--only-step",
+	"confirmEachStep": "--confirm-each-step",
+	"ocmToken":        "--ocm-token or OPENSHIFT_STS_OCM_TOKEN",
+}
+
+// unknownFieldPattern matches the yaml.v3 error for keys without a struct field
+var unknownFieldPattern = regexp.MustCompile(`^line (\d+): field (\S+) not found in type config\.(\w+)$`)
+
+// FileReport is the result of validating a config file
+type FileReport struct {
+	Errors   []string
+	Warnings []string
+}
+
+// Valid reports whether the file has no errors
+func (r *FileReport) Valid() bool {
+	return len(r.Errors) == 0
+}
+
+// ValidateFile checks a config file for unknown keys, values of the wrong type,
+// invalid values and inconsistent combinations of settings. Unlike ValidateConfig
+// it does not require runtime settings such as the cluster name.
+func ValidateFile(path string) (*FileReport, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	return validateData(data), nil
+}
+
+// validateData runs the checks of ValidateFile on the content of a config file
+func validateData(data []byte) *FileReport {
+	report := &FileReport{}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	var strict Config
+	if err := decoder.Decode(&strict); err != nil {
+		var typeErr *yaml.TypeError
+		if !errors.As(err, &typeErr) {
+			// Syntax errors prevent any further check
+			report.Errors = append(report.Errors, err.Error())
+			return report
+		}
+		invalidValues := false
+		for _, message := range typeErr.Errors {
+			report.Errors = append(report.Errors, describeDecodeError(message))
+			invalidValues = invalidValues || !unknownFieldPattern.MatchString(message)
+		}
+		// Values that failed to decode are zero, checking them would only
+		// report misleading errors
+		if invalidValues {
+			return report
+		}
+	}
+
+	// Decode leniently so that the remaining checks run despite unknown keys
+	var cfg Config
+	yaml.Unmarshal(data, &cfg)
+
+	checkConfig(report, &cfg, "")
+
+	// Every profile is checked as applied over the rest of the file
+	for _, name := range cfg.ProfileNames() {
+		if len(cfg.Profiles[name].Profiles) > 0 {
+			report.Errors = append(report.Errors, fmt.Sprintf("profile %s: profiles cannot be nested", name))
+			continue
+		}
+		var profileCfg Config
+		yaml.Unmarshal(data, &profileCfg)
+		profileCfg.ApplyProfile(name)
+		checkConfig(report, &profileCfg, fmt.Sprintf("profile %s: ", name))
+	}
+
+	return report
+}
+
+// checkConfig adds the errors and warnings of a decoded configuration to the
+// report, skipping the ones already reported
+func checkConfig(report *FileReport, cfg *Config, prefix string) {
+	var errs []string
+	for _, err := range ConsistencyErrors(cfg) {
+		errs = append(errs, err.Error())
+	}
+	errs = append(errs, fileConsistencyErrors(cfg)...)
+
+	report.Errors = appendNew(report.Errors, prefix, errs)
+	report.Warnings = appendNew(report.Warnings, prefix, fileWarnings(cfg))
+}
+
+// appendNew appends the prefixed messages not already in the list (without prefix)
+func appendNew(list []string, prefix string, messages []string) []string {
+	seen := map[string]bool{}
+	for _, message := range list {
+		seen[message] = true
+	}
+	for _, message := range messages {
+		if !seen[message] {
+			list = append(list, prefix+message)
+		}
+	}
+	return list
+}
+
+// fileConsistencyErrors returns checks that only apply to config files, which
+// must be self-contained: values such as the region cannot come from an
+// interactively created install-config.yaml
+func fileConsistencyErrors(cfg *Config) []string {
+	var errs []string
+	if cfg.AwsRegion == "" {
+		if cfg.PrivateBucket {
+			errs = append(errs, "privateBucket requires awsRegion")
+		}
+		if len(cfg.Subnets) > 0 || len(cfg.Zones) > 0 {
+			errs = append(errs, "vpcSubnets and zones require awsRegion")
+		}
+	}
+	for _, zone := range cfg.Zones {
+		if cfg.AwsRegion != "" && !strings.HasPrefix(zone, cfg.AwsRegion) {
+			errs = append(errs, fmt.Sprintf("zone %s is not in region %s", zone, cfg.AwsRegion))
+		}
+	}
+	for key, ref := range map[string]string{"vault.pullSecret": cfg.Vault.PullSecret, "vault.sshKey": cfg.Vault.SSHKey} {
+		if ref != "" && !strings.Contains(ref, "#") {
+			errs = append(errs, fmt.Sprintf("%s must have the form <path>#<field>", key))
+		}
+	}
+	return errs
+}
+
+// fileWarnings returns settings that are valid but likely not what was intended
+func fileWarnings(cfg *Config) []string {
+	var warnings []string
+	if cfg.ReleaseImage != "" && (cfg.Version != "" || cfg.Channel != "") {
+		warnings = append(warnings, "releaseImage is ignored when version or channel is set")
+	}
+	if cfg.Architecture != "" && cfg.Version == "" && cfg.Channel == "" {
+		warnings = append(warnings, "architecture is only used with version or channel")
+	}
+	if cfg.PullSecretPath != "" && cfg.Vault.PullSecret == "" {
+		if _, err := os.Stat(cfg.PullSecretPath); err != nil {
+			warnings = append(warnings, fmt.Sprintf("pullSecretPath %s does not exist", cfg.PullSecretPath))
+		}
+	}
+	if cfg.ExtraManifestsDir != "" {
+		if _, err := os.Stat(cfg.ExtraManifestsDir); err != nil {
+			warnings = append(warnings, fmt.Sprintf("extraManifestsDir %s does not exist", cfg.ExtraManifestsDir))
+		}
+	}
+	if cfg.PostInstallManifestsDir != "" {
+		if _, err := os.Stat(cfg.PostInstallManifestsDir); err != nil {
+			warnings = append(warnings, fmt.Sprintf("postInstallManifestsDir %s does not exist", cfg.PostInstallManifestsDir))
+		}
+	}
+	if cfg.OIDCSigningKey != "" {
+		if _, err := os.Stat(cfg.OIDCSigningKey); err != nil {
+			warnings = append(warnings, fmt.Sprintf("oidcSigningKey %s does not exist", cfg.OIDCSigningKey))
+		}
+	}
+	if cfg.SSHKeyPath != "" && cfg.Vault.SSHKey == "" {
+		if _, err := os.Stat(cfg.SSHKeyPath); err != nil {
+			warnings = append(warnings, fmt.Sprintf("sshKeyPath %s does not exist", cfg.SSHKeyPath))
+		}
+	}
+	return warnings
+}
+
+// describeDecodeError rewrites yaml.v3 unknown field errors in terms of config
+// keys, suggesting the closest known key for typos
+func describeDecodeError(message string) string {
+	match := unknownFieldPattern.FindStringSubmatch(message)
+	if match == nil {
+		return message
+	}
+	line, key, typeName := match[1], match[2], match[3]
+
+	if flag, ok := runtimeOnlyKeys[key]; ok && typeName == "Config" {
+		return fmt.Sprintf("line %s: %s cannot be set in the config file, use %s", line, key, flag)
+	}
+
+	description := fmt.Sprintf("line %s: unknown key %q", line, key)
+	if suggestion := closestKey(key, knownKeys(typeName)); suggestion != "" {
+		description += fmt.Sprintf(" (did you mean %q?)", suggestion)
+	}
+	return description
+}
+
+// knownKeys returns the YAML keys of a config struct type
+func knownKeys(typeName string) []string {
+	types := map[string]reflect.Type{
+		"Config":          reflect.TypeOf(Config{}),
+		"Hooks":           reflect.TypeOf(Hooks{}),
+		"VaultConfig":     reflect.TypeOf(VaultConfig{}),
+		"Notifications":   reflect.TypeOf(Notifications{}),
+		"MetricsConfig":   reflect.TypeOf(MetricsConfig{}),
+		"TracingConfig":   reflect.TypeOf(TracingConfig{}),
+		"PostInstall":     reflect.TypeOf(PostInstall{}),
+		"HostedConfig":    reflect.TypeOf(HostedConfig{}),
+		"BinariesConfig":  reflect.TypeOf(BinariesConfig{}),
+		"MirrorConfig":    reflect.TypeOf(MirrorConfig{}),
+		"Operator":        reflect.TypeOf(Operator{}),
+		"ComputePool":     reflect.TypeOf(ComputePool{}),
+		"ServiceEndpoint": reflect.TypeOf(ServiceEndpoint{}),
+	}
+	t, ok := types[typeName]
+	if !ok {
+		return nil
+	}
+
+	var keys []string
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("yaml"), ",")[0]
+		if name != "" && name != "-" {
+			keys = append(keys, name)
+		}
+	}
+	return keys
+}
+
+// closestKey returns the known key with the smallest edit distance, if close
+// enough to be a typo
+func closestKey(key string, known []string) string {
+	best, bestDistance := "", len(key)/2+1
+	for _, candidate := range known {
+		if distance := editDistance(strings.ToLower(key), strings.ToLower(candidate)); distance < bestDistance {
+			best, bestDistance = candidate, distance
+		}
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance between two strings
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current := make([]int, len(b)+1)
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous = current
+	}
+	return previous[len(b)]
+}
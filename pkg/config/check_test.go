@@ -0,0 +1,102 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateFile(t *testing.T) {
+	tests := []struct {
+		name           string
+		content        string
+		expectedErrors []string
+	}{
+		{
+			name:    "valid file",
+			content: "awsRegion: us-east-1\nprivateBucket: true\nzones:\n  - us-east-1a\n",
+		},
+		{
+			name:           "typo in key",
+			content:        "awsRegon: us-east-1\n",
+			expectedErrors: []string{`line 1: unknown key "awsRegon" (did you mean "awsRegion"?)`},
+		},
+		{
+			name:           "typo in nested key",
+			content:        "vault:\n  pullSecrets: secret/data/ocp#pullSecret\n",
+			expectedErrors: []string{`line 2: unknown key "pullSecrets" (did you mean "pullSecret"?)`},
+		},
+		{
+			name:           "runtime only key",
+			content:        "clusterName: my-cluster\n",
+			expectedErrors: []string{"line 1: clusterName cannot be set in the config file, use --cluster-name"},
+		},
+		{
+			name:           "wrong type",
+			content:        "workerReplicas: three\n",
+			expectedErrors: []string{"line 1: cannot unmarshal"},
+		},
+		{
+			name:    "cross-field errors",
+			content: "privateBucket: true\nprivate: true\ntags:\n  aws:team: qe\n",
+			expectedErrors: []string{
+				"private clusters must be installed into existing private subnets",
+				"aws: prefix",
+				"privateBucket requires awsRegion",
+			},
+		},
+		{
+			name:           "zone outside region",
+			content:        "awsRegion: us-east-1\nzones:\n  - us-west-2a\n",
+			expectedErrors: []string{"zone us-west-2a is not in region us-east-1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			os.WriteFile(path, []byte(tt.content), 0644)
+
+			report, err := ValidateFile(path)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(report.Errors) != len(tt.expectedErrors) {
+				t.Fatalf("Expected %d errors, got %v", len(tt.expectedErrors), report.Errors)
+			}
+			for i, expected := range tt.expectedErrors {
+				if !strings.Contains(report.Errors[i], expected) {
+					t.Errorf("Expected error %q to contain %q", report.Errors[i], expected)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateFileProfiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	os.WriteFile(path, []byte(`awsRegion: us-east-1
+instanceType: m5.xlarge
+profiles:
+  dev:
+    workerReplicas: 0
+  prod:
+    awsRegion: eu-west-1
+    zones:
+      - us-east-1a
+    workerReplicas: -1
+`), 0644)
+
+	report, err := ValidateFile(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := []string{
+		"profile prod: worker replicas cannot be negative",
+		"profile prod: zone us-east-1a is not in region eu-west-1",
+	}
+	if strings.Join(report.Errors, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Unexpected errors: %v", report.Errors)
+	}
+}
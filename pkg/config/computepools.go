@@ -0,0 +1,59 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// WorkerPoolName is the name of the compute pool of install-config.yaml
+const WorkerPoolName = "worker"
+
+// maxComputePoolNameLength keeps the name of the MachineSets of a pool,
+// <infra-id>-<pool>-<zone>, within the 63 characters of a label value
+const maxComputePoolNameLength = 16
+
+// ComputePool is a pool of compute machines. The pool named worker is the
+// compute pool of install-config.yaml, the others become MachineSets cloned
+// from its MachineSets.
+type ComputePool struct {
+	Name         string            `yaml:"name"`
+	InstanceType string            `yaml:"instanceType,omitempty"` // Default is the instance type of the worker pool
+	Replicas     *int              `yaml:"replicas,omitempty"`     // Required, except for the worker pool
+	Zones        []string          `yaml:"zones,omitempty"`        // Default is every zone of the worker pool
+	Labels       map[string]string `yaml:"labels,omitempty"`       // Labels of the nodes
+	Taints       []string          `yaml:"taints,omitempty"`       // Taints of the nodes, key[=value]:effect
+}
+
+// taintPattern matches the taints of a compute pool, key[=value]:effect
+var taintPattern = regexp.MustCompile(`^([a-z0-9.-]+/)?[A-Za-z0-9._-]+(=[A-Za-z0-9._-]*)?:(NoSchedule|PreferNoSchedule|NoExecute)$`)
+
+// computePoolErrors checks the names, replicas and taints of the compute pools
+func computePoolErrors(pools []ComputePool) []error {
+	var errs []error
+	seen := map[string]bool{}
+	for _, pool := range pools {
+		switch {
+		case pool.Name == "master" || pool.Name == "edge":
+			errs = append(errs, fmt.Errorf("compute pool name %q is reserved", pool.Name))
+		case len(pool.Name) > maxComputePoolNameLength || !clusterNamePattern.MatchString(pool.Name):
+			errs = append(errs, fmt.Errorf("invalid compute pool name %q: must be a DNS label of up to %d characters", pool.Name, maxComputePoolNameLength))
+		}
+		if seen[pool.Name] {
+			errs = append(errs, fmt.Errorf("compute pool %s is listed more than once", pool.Name))
+		}
+		seen[pool.Name] = true
+
+		if pool.Replicas == nil && pool.Name != WorkerPoolName {
+			errs = append(errs, fmt.Errorf("compute pool %s: replicas is required", pool.Name))
+		}
+		if pool.Replicas != nil && *pool.Replicas < 0 {
+			errs = append(errs, fmt.Errorf("compute pool %s: replicas cannot be negative", pool.Name))
+		}
+		for _, taint := range pool.Taints {
+			if !taintPattern.MatchString(taint) {
+				errs = append(errs, fmt.Errorf("compute pool %s: invalid taint %q (key[=value]:NoSchedule, PreferNoSchedule or NoExecute)", pool.Name, taint))
+			}
+		}
+	}
+	return errs
+}
@@ -1,25 +1,175 @@
 package config
 
 import (
+	"crypto/rand"
 	"fmt"
 	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
 type Config struct {
-	ReleaseImage      string `yaml:"releaseImage"`
-	ClusterName       string `yaml:"-"` // Not loaded from config file - must be provided via CLI flag
-	AwsRegion         string `yaml:"awsRegion"`
-	BaseDomain        string `yaml:"baseDomain"`
-	SSHKeyPath        string `yaml:"sshKeyPath,omitempty"`
-	AwsProfile        string `yaml:"awsProfile"`
-	PullSecretPath    string `yaml:"pullSecretPath"`
-	PrivateBucket     bool   `yaml:"privateBucket"`
-	StartFromStep     int    `yaml:"-"` // Runtime flag only - not loaded from config file
-	ConfirmEachStep   bool   `yaml:"-"` // Runtime flag only - not loaded from config file
-	UseInteractiveMode bool   `yaml:"-"` // Runtime decision - whether to run Step 4 interactively
-	InstanceType      string `yaml:"instanceType"`
+	ReleaseImage            string            `yaml:"releaseImage"`
+	ReleaseDigest           string            `yaml:"-"`                      // Runtime only - digest resolved from ReleaseImage
+	Version                 string            `yaml:"version,omitempty"`      // Resolved to ReleaseImage via the update service
+	Channel                 string            `yaml:"channel,omitempty"`      // Resolved to ReleaseImage via the update service
+	Architecture            string            `yaml:"architecture,omitempty"` // Release architecture used with version/channel (x86_64, aarch64, multi)
+	ClusterName             string            `yaml:"-"`                      // Not loaded from config file - must be provided via CLI flag
+	NameSuffix              string            `yaml:"nameSuffix,omitempty"`   // "random" appends a random suffix to the cluster name of new installs
+	AwsRegion               string            `yaml:"awsRegion"`
+	AwsPartition            string            `yaml:"awsPartition,omitempty"`     // Derived from the region when empty (aws, aws-us-gov, aws-cn...)
+	ServiceEndpoints        []ServiceEndpoint `yaml:"serviceEndpoints,omitempty"` // Merged into install-config.yaml (VPC endpoints, API proxies, FIPS endpoints)
+	BaseDomain              string            `yaml:"baseDomain"`
+	SSHKeyPath              string            `yaml:"sshKeyPath,omitempty"`
+	AwsProfile              string            `yaml:"awsProfile"`
+	AssumeRoleARN           string            `yaml:"assumeRoleArn,omitempty"` // Role assumed for the installation
+	MFASerial               string            `yaml:"mfaSerial,omitempty"`     // MFA device required by the assumed role
+	PullSecretPath          string            `yaml:"pullSecretPath"`
+	OCMToken                string            `yaml:"-"` // Offline OCM token used to download the pull secret; never saved to the config file
+	PrivateBucket           bool              `yaml:"privateBucket"`
+	IAMRoles                map[string]string `yaml:"iamRoles,omitempty"`        // CredentialsRequest (or secret) namespace/name -> existing role ARN: Step 7 creates no AWS resource
+	OIDCProviderARN         string            `yaml:"oidcProviderARN,omitempty"` // Existing OIDC provider the IAM roles created by ccoctl trust (shared across clusters)
+	OIDCIssuerURL           string            `yaml:"oidcIssuerURL,omitempty"`   // Issuer of the existing OIDC provider (iamRoles or oidcProviderARN)
+	OIDCSigningKey          string            `yaml:"oidcSigningKey,omitempty"`  // Private key the existing OIDC provider publishes
+	StartFromStep           int               `yaml:"-"`                         // Runtime flag only - not loaded from config file
+	StopAfterStep           int               `yaml:"-"`                         // Runtime flag only - not loaded from config file
+	OnlyStep                int               `yaml:"-"`                         // Runtime flag only - not loaded from config file
+	SkipSteps               []string          `yaml:"skipSteps,omitempty"`       // Step names (or numbers) never run
+	ConfirmEachStep         bool              `yaml:"-"`                         // Runtime flag only - not loaded from config file
+	UseInteractiveMode      bool              `yaml:"-"`                         // Runtime decision - whether to run Step 4 interactively
+	GenerateSSHKey          bool              `yaml:"-"`                         // Runtime decision - Step 4 generates the key pair of SSHKeyPath
+	InstanceType            string            `yaml:"instanceType"`
+	ControlPlaneType        string            `yaml:"controlPlaneType,omitempty"`     // Overrides InstanceType for the control plane
+	WorkerType              string            `yaml:"workerType,omitempty"`           // Overrides InstanceType for the compute pool
+	AMIID                   string            `yaml:"amiID,omitempty"`                // RHCOS boot image of every machine (platform.aws.amiID)
+	ControlPlaneAMIID       string            `yaml:"controlPlaneAMIID,omitempty"`    // Overrides AMIID for the control plane
+	WorkerAMIID             string            `yaml:"workerAMIID,omitempty"`          // Overrides AMIID for the compute pool
+	ControlPlaneReplicas    *int              `yaml:"controlPlaneReplicas,omitempty"` // nil keeps the install-config value
+	WorkerReplicas          *int              `yaml:"workerReplicas,omitempty"`       // nil keeps the install-config value
+	ComputePools            []ComputePool     `yaml:"computePools,omitempty"`         // Settings of the worker pool and additional compute pools
+	Subnets                 []string          `yaml:"vpcSubnets,omitempty"`           // Existing subnets to install into
+	Private                 bool              `yaml:"private,omitempty"`              // Private cluster (publish: Internal)
+	Zones                   []string          `yaml:"zones,omitempty"`                // Availability zones for the machine pools
+	Tags                    map[string]string `yaml:"tags,omitempty"`                 // AWS tags applied to every created resource
+	PermissionsBoundary     string            `yaml:"permissionsBoundary,omitempty"`  // Permissions boundary policy (ARN) of the IAM roles created in Step 7
+	IAMRolePath             string            `yaml:"iamRolePath,omitempty"`          // IAM path of the IAM roles created in Step 7 (e.g. /openshift/)
+	ResourcePrefix          string            `yaml:"resourcePrefix,omitempty"`       // Name of the ccoctl resources (--name), the cluster name if empty
+	MaxMonthlyCost          float64           `yaml:"maxMonthlyCost,omitempty"`       // Budget (USD): Step 10 refuses to deploy a cluster estimated to cost more
+	StepTimeouts            map[string]string `yaml:"stepTimeouts,omitempty"`         // Step name or number -> duration (e.g. deploy-cluster: 90m)
+	InstallTimeout          string            `yaml:"installTimeout,omitempty"`
+	ExpiresIn               string            `yaml:"expiresIn,omitempty"`               // Lifetime of the cluster (e.g. 8h): cleanup runs at its expiry
+	HealthGateTimeout       string            `yaml:"healthGateTimeout,omitempty"`       // Step 11 waits up to this for the ClusterOperators to be healthy
+	CleanupOnFailure        bool              `yaml:"cleanupOnFailure,omitempty"`        // A step failing from Step 7 on deletes the AWS resources of the install
+	VerifyBinaries          bool              `yaml:"verifyBinaries,omitempty"`          // Steps 2-3 verify the extracted binaries against the release metadata
+	ReleaseSigningKey       string            `yaml:"releaseSigningKey,omitempty"`       // GPG key verifying the release signature before Steps 2-3
+	ExtraManifestsDir       string            `yaml:"extraManifestsDir,omitempty"`       // YAML manifests Step 8 copies into manifests/ (and openshift/)
+	PostInstallManifestsDir string            `yaml:"postInstallManifestsDir,omitempty"` // YAML manifests applied once the cluster is deployed
+	NTPServers              []string          `yaml:"ntpServers,omitempty"`              // Rendered into chrony MachineConfigs by Step 8
+	KernelArguments         []string          `yaml:"kernelArguments,omitempty"`         // Rendered into MachineConfigs by Step 8
+	InsecureRegistries      []string          `yaml:"insecureRegistries,omitempty"`      // Rendered into registries.conf MachineConfigs by Step 8
+	Hooks                   Hooks             `yaml:"hooks,omitempty"`
+	Vault                   VaultConfig       `yaml:"vault,omitempty"`
+	Notifications           Notifications     `yaml:"notifications,omitempty"`
+	Metrics                 MetricsConfig     `yaml:"metrics,omitempty"`
+	Tracing                 TracingConfig     `yaml:"tracing,omitempty"`
+	PostInstall             PostInstall       `yaml:"postInstall,omitempty"`
+	Hosted                  HostedConfig      `yaml:"hosted,omitempty"`
+	Binaries                BinariesConfig    `yaml:"binaries,omitempty"`
+	Mirror                  MirrorConfig      `yaml:"mirror,omitempty"`
+	Profiles                map[string]Config `yaml:"profiles,omitempty"` // Named overrides selected with --profile
+	Sources                 map[string]string `yaml:"-"`                  // Runtime only - origin of each value, see MergeFrom
+}
+
+// Hooks holds shell commands run at specific points of the installation
+type Hooks struct {
+	OnFailure []string `yaml:"onFailure,omitempty"`
+}
+
+// VaultConfig holds the HashiCorp Vault paths sensitive inputs are read from.
+// Fields are <path>#<field> references, except AWSCredentials which is the path
+// of a secret holding the credentials (KV secret or AWS secrets engine role).
+type VaultConfig struct {
+	Address        string `yaml:"address,omitempty"` // Defaults to VAULT_ADDR
+	PullSecret     string `yaml:"pullSecret,omitempty"`
+	SSHKey         string `yaml:"sshKey,omitempty"`
+	AWSCredentials string `yaml:"awsCredentials,omitempty"`
+}
+
+// Notifications configures how the end of an install or cleanup is reported
+type Notifications struct {
+	WebhookURL string `yaml:"webhookUrl,omitempty"` // Receives a JSON payload (Slack incoming webhooks are supported)
+	Desktop    bool   `yaml:"desktop,omitempty"`    // Native desktop notification when the cluster is deployed or the install fails
+}
+
+// MetricsConfig configures where Prometheus metrics of install runs are published
+type MetricsConfig struct {
+	PushgatewayURL string `yaml:"pushgatewayUrl,omitempty"` // Pushgateway the metrics are pushed to
+	Textfile       string `yaml:"textfile,omitempty"`       // File written for the node_exporter textfile collector
+}
+
+// TracingConfig configures the export of the step pipeline as OpenTelemetry traces
+type TracingConfig struct {
+	OTLPEndpoint string `yaml:"otlpEndpoint,omitempty"` // OTLP/HTTP collector base URL (e.g. http://localhost:4318)
+}
+
+// PostInstall configures the day-1 setup done once the cluster is deployed
+type PostInstall struct {
+	AdminUser       string     `yaml:"adminUser,omitempty"`       // cluster-admin user of an htpasswd identity provider
+	RemoveKubeadmin bool       `yaml:"removeKubeadmin,omitempty"` // Remove kubeadmin once the admin user can log in
+	Operators       []Operator `yaml:"operators,omitempty"`       // OLM operators installed once the cluster is deployed
+}
+
+// Operator is an OLM operator subscribed to once the cluster is deployed
+type Operator struct {
+	Name      string `yaml:"name"`                // Package of the operator, also the name of its Subscription
+	Channel   string `yaml:"channel,omitempty"`   // Default is the default channel of the package
+	Namespace string `yaml:"namespace,omitempty"` // Default is openshift-operators, watching all namespaces
+	Source    string `yaml:"source,omitempty"`    // CatalogSource in openshift-marketplace, default is redhat-operators
+}
+
+// HostedConfig configures the HyperShift hosted clusters created by hosted-cluster create
+type HostedConfig struct {
+	ManagementKubeconfig string `yaml:"managementKubeconfig,omitempty"` // Kubeconfig of the management cluster running the HyperShift operator
+	Namespace            string `yaml:"namespace,omitempty"`            // Namespace of the HostedCluster (default: clusters)
+	RoleARN              string `yaml:"roleArn,omitempty"`              // Role hcp assumes with the STS credentials to create the infrastructure
+}
+
+// BinariesConfig points to binaries already on the host (e.g. mirrored on an
+// air-gapped host), used instead of the ones found in PATH or extracted from
+// the release image
+type BinariesConfig struct {
+	OC               string `yaml:"oc,omitempty"`               // oc used instead of the one in PATH, must be named oc
+	OpenshiftInstall string `yaml:"openshiftInstall,omitempty"` // openshift-install of the release: Step 2 is skipped
+	Ccoctl           string `yaml:"ccoctl,omitempty"`           // ccoctl of the release: Step 3 is skipped
+}
+
+// MirrorConfig configures disconnected installs from a registry the mirror
+// command populated with oc-mirror
+type MirrorConfig struct {
+	Registry string `yaml:"registry,omitempty"` // Registry (and path) the release and operators are mirrored to, e.g. registry.example.com:5000/ocp
+	CABundle string `yaml:"caBundle,omitempty"` // PEM CA certificates of the registry, trusted by the cluster
+}
+
+// DefaultHostedNamespace is the namespace of the HostedClusters when none is configured
+const DefaultHostedNamespace = "clusters"
+
+// HostedNamespace returns the namespace of the HostedCluster
+func (h HostedConfig) HostedNamespace() string {
+	if h.Namespace == "" {
+		return DefaultHostedNamespace
+	}
+	return h.Namespace
+}
+
+// Enabled reports whether any secret is read from Vault
+func (v VaultConfig) Enabled() bool {
+	return v.PullSecret != "" || v.SSHKey != "" || v.AWSCredentials != ""
 }
 
 // LoadFromFile loads configuration from a YAML file
@@ -37,20 +187,140 @@ func LoadFromFile(path string) (*Config, error) {
 	return &cfg, nil
 }
 
-// LoadFromEnv loads configuration from environment variables
+// ApplyProfile merges the named profile over the rest of the configuration
+func (c *Config) ApplyProfile(name string) error {
+	profile, ok := c.Profiles[name]
+	if !ok {
+		return fmt.Errorf("profile %q not found (available profiles: %s)", name, strings.Join(c.ProfileNames(), ", "))
+	}
+	c.MergeFrom(&profile, "profile "+name)
+	return nil
+}
+
+// ProfileNames returns the names of the profiles defined in the configuration
+func (c *Config) ProfileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LoadFromEnv loads configuration from environment variables. Values that
+// can't be parsed are left unset, see EnvErrors.
 func LoadFromEnv() *Config {
+	maxMonthlyCost, _ := parseFloat(os.Getenv("OPENSHIFT_STS_MAX_MONTHLY_COST"))
 	return &Config{
 		ReleaseImage: os.Getenv("OPENSHIFT_STS_RELEASE_IMAGE"),
 		// ClusterName is not loaded from env - must be provided via CLI flag
-		AwsRegion:      os.Getenv("OPENSHIFT_STS_AWS_REGION"),
-		BaseDomain:     os.Getenv("OPENSHIFT_STS_BASE_DOMAIN"),
-		SSHKeyPath:     os.Getenv("OPENSHIFT_STS_SSH_KEY_PATH"),
-		AwsProfile:     os.Getenv("OPENSHIFT_STS_AWS_PROFILE"),
-		PullSecretPath: os.Getenv("OPENSHIFT_STS_PULL_SECRET_PATH"),
-		PrivateBucket:  os.Getenv("OPENSHIFT_STS_PRIVATE_BUCKET") == "true",
-		// StartFromStep and ConfirmEachStep are runtime flags only
-		InstanceType: os.Getenv("OPENSHIFT_STS_INSTANCE_TYPE"),
+		AwsRegion:       os.Getenv("OPENSHIFT_STS_AWS_REGION"),
+		AwsPartition:    os.Getenv("OPENSHIFT_STS_AWS_PARTITION"),
+		BaseDomain:      os.Getenv("OPENSHIFT_STS_BASE_DOMAIN"),
+		SSHKeyPath:      os.Getenv("OPENSHIFT_STS_SSH_KEY_PATH"),
+		AwsProfile:      os.Getenv("OPENSHIFT_STS_AWS_PROFILE"),
+		AssumeRoleARN:   os.Getenv("OPENSHIFT_STS_ASSUME_ROLE_ARN"),
+		MFASerial:       os.Getenv("OPENSHIFT_STS_MFA_SERIAL"),
+		PullSecretPath:  os.Getenv("OPENSHIFT_STS_PULL_SECRET_PATH"),
+		OCMToken:        os.Getenv("OPENSHIFT_STS_OCM_TOKEN"),
+		PrivateBucket:   os.Getenv("OPENSHIFT_STS_PRIVATE_BUCKET") == "true",
+		OIDCProviderARN: os.Getenv("OPENSHIFT_STS_OIDC_PROVIDER_ARN"),
+		OIDCIssuerURL:   os.Getenv("OPENSHIFT_STS_OIDC_ISSUER_URL"),
+		OIDCSigningKey:  os.Getenv("OPENSHIFT_STS_OIDC_SIGNING_KEY"),
+		// IAM roles created in Step 7
+		PermissionsBoundary: os.Getenv("OPENSHIFT_STS_PERMISSIONS_BOUNDARY"),
+		IAMRolePath:         os.Getenv("OPENSHIFT_STS_IAM_ROLE_PATH"),
+		ResourcePrefix:      os.Getenv("OPENSHIFT_STS_RESOURCE_PREFIX"),
+		NameSuffix:          os.Getenv("OPENSHIFT_STS_NAME_SUFFIX"),
+		// Step selection and ConfirmEachStep are runtime flags only
+		InstanceType:            os.Getenv("OPENSHIFT_STS_INSTANCE_TYPE"),
+		ControlPlaneType:        os.Getenv("OPENSHIFT_STS_CONTROL_PLANE_TYPE"),
+		WorkerType:              os.Getenv("OPENSHIFT_STS_WORKER_TYPE"),
+		AMIID:                   os.Getenv("OPENSHIFT_STS_AMI_ID"),
+		ControlPlaneAMIID:       os.Getenv("OPENSHIFT_STS_CONTROL_PLANE_AMI_ID"),
+		WorkerAMIID:             os.Getenv("OPENSHIFT_STS_WORKER_AMI_ID"),
+		InstallTimeout:          os.Getenv("OPENSHIFT_STS_INSTALL_TIMEOUT"),
+		ExpiresIn:               os.Getenv("OPENSHIFT_STS_EXPIRES_IN"),
+		HealthGateTimeout:       os.Getenv("OPENSHIFT_STS_HEALTH_GATE_TIMEOUT"),
+		Version:                 os.Getenv("OPENSHIFT_STS_VERSION"),
+		Channel:                 os.Getenv("OPENSHIFT_STS_CHANNEL"),
+		Architecture:            os.Getenv("OPENSHIFT_STS_ARCHITECTURE"),
+		Subnets:                 splitList(os.Getenv("OPENSHIFT_STS_SUBNETS")),
+		Private:                 os.Getenv("OPENSHIFT_STS_PRIVATE") == "true",
+		Zones:                   splitList(os.Getenv("OPENSHIFT_STS_ZONES")),
+		SkipSteps:               splitList(os.Getenv("OPENSHIFT_STS_SKIP_STEPS")),
+		MaxMonthlyCost:          maxMonthlyCost,
+		VerifyBinaries:          os.Getenv("OPENSHIFT_STS_VERIFY_BINARIES") == "true",
+		CleanupOnFailure:        os.Getenv("OPENSHIFT_STS_CLEANUP_ON_FAILURE") == "true",
+		ReleaseSigningKey:       os.Getenv("OPENSHIFT_STS_RELEASE_SIGNING_KEY"),
+		ExtraManifestsDir:       os.Getenv("OPENSHIFT_STS_EXTRA_MANIFESTS_DIR"),
+		PostInstallManifestsDir: os.Getenv("OPENSHIFT_STS_POST_INSTALL_MANIFESTS_DIR"),
+		NTPServers:              splitList(os.Getenv("OPENSHIFT_STS_NTP_SERVERS")),
+		KernelArguments:         strings.Fields(os.Getenv("OPENSHIFT_STS_KERNEL_ARGUMENTS")),
+		InsecureRegistries:      splitList(os.Getenv("OPENSHIFT_STS_INSECURE_REGISTRIES")),
+		Notifications: Notifications{
+			WebhookURL: os.Getenv("OPENSHIFT_STS_WEBHOOK_URL"),
+			Desktop:    os.Getenv("OPENSHIFT_STS_DESKTOP_NOTIFY") == "true",
+		},
+		Metrics: MetricsConfig{
+			PushgatewayURL: os.Getenv("OPENSHIFT_STS_PUSHGATEWAY_URL"),
+			Textfile:       os.Getenv("OPENSHIFT_STS_METRICS_TEXTFILE"),
+		},
+		Tracing: TracingConfig{
+			OTLPEndpoint: os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"),
+		},
+		PostInstall: PostInstall{
+			AdminUser:       os.Getenv("OPENSHIFT_STS_ADMIN_USER"),
+			RemoveKubeadmin: os.Getenv("OPENSHIFT_STS_REMOVE_KUBEADMIN") == "true",
+		},
+		Hosted: HostedConfig{
+			ManagementKubeconfig: os.Getenv("OPENSHIFT_STS_HOSTED_MANAGEMENT_KUBECONFIG"),
+			Namespace:            os.Getenv("OPENSHIFT_STS_HOSTED_NAMESPACE"),
+			RoleARN:              os.Getenv("OPENSHIFT_STS_HOSTED_ROLE_ARN"),
+		},
+		Binaries: BinariesConfig{
+			OC:               os.Getenv("OPENSHIFT_STS_OC_PATH"),
+			OpenshiftInstall: os.Getenv("OPENSHIFT_STS_OPENSHIFT_INSTALL_PATH"),
+			Ccoctl:           os.Getenv("OPENSHIFT_STS_CCOCTL_PATH"),
+		},
+		Mirror: MirrorConfig{
+			Registry: os.Getenv("OPENSHIFT_STS_MIRROR_REGISTRY"),
+			CABundle: os.Getenv("OPENSHIFT_STS_MIRROR_CA_BUNDLE"),
+		},
+	}
+}
+
+// splitList splits a comma-separated list, ignoring empty items
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// EnvErrors returns the environment variables LoadFromEnv could not parse
+func EnvErrors() []error {
+	var errs []error
+	if _, err := parseFloat(os.Getenv("OPENSHIFT_STS_MAX_MONTHLY_COST")); err != nil {
+		errs = append(errs, fmt.Errorf("invalid OPENSHIFT_STS_MAX_MONTHLY_COST: %w", err))
+	}
+	return errs
+}
+
+// parseFloat parses a number, returning 0 when it is empty
+func parseFloat(value string) (float64, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, nil
+	}
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a number", value)
 	}
+	return number, nil
 }
 
 // Merge merges another config into this one, with the other config taking precedence
@@ -65,6 +335,12 @@ func (c *Config) Merge(other *Config) {
 	if other.AwsRegion != "" {
 		c.AwsRegion = other.AwsRegion
 	}
+	if other.AwsPartition != "" {
+		c.AwsPartition = other.AwsPartition
+	}
+	if len(other.ServiceEndpoints) > 0 {
+		c.ServiceEndpoints = MergeServiceEndpoints(c.ServiceEndpoints, other.ServiceEndpoints)
+	}
 	if other.BaseDomain != "" {
 		c.BaseDomain = other.BaseDomain
 	}
@@ -74,46 +350,753 @@ func (c *Config) Merge(other *Config) {
 	if other.AwsProfile != "" {
 		c.AwsProfile = other.AwsProfile
 	}
+	if other.AssumeRoleARN != "" {
+		c.AssumeRoleARN = other.AssumeRoleARN
+	}
+	if other.MFASerial != "" {
+		c.MFASerial = other.MFASerial
+	}
 	if other.PullSecretPath != "" {
 		c.PullSecretPath = other.PullSecretPath
 	}
+	if other.OCMToken != "" {
+		c.OCMToken = other.OCMToken
+	}
 	if other.PrivateBucket {
 		c.PrivateBucket = other.PrivateBucket
 	}
-	// StartFromStep and ConfirmEachStep are explicitly set from CLI flags only
+	// Step selection and ConfirmEachStep are explicitly set from CLI flags only
 	if other.StartFromStep > 0 {
 		c.StartFromStep = other.StartFromStep
 	}
+	if other.StopAfterStep > 0 {
+		c.StopAfterStep = other.StopAfterStep
+	}
+	if other.OnlyStep > 0 {
+		c.OnlyStep = other.OnlyStep
+	}
+	if len(other.SkipSteps) > 0 {
+		c.SkipSteps = other.SkipSteps
+	}
 	if other.ConfirmEachStep {
 		c.ConfirmEachStep = other.ConfirmEachStep
 	}
 	if other.InstanceType != "" {
 		c.InstanceType = other.InstanceType
 	}
+	if other.ControlPlaneType != "" {
+		c.ControlPlaneType = other.ControlPlaneType
+	}
+	if other.WorkerType != "" {
+		c.WorkerType = other.WorkerType
+	}
+	if other.AMIID != "" {
+		c.AMIID = other.AMIID
+	}
+	if other.ControlPlaneAMIID != "" {
+		c.ControlPlaneAMIID = other.ControlPlaneAMIID
+	}
+	if other.WorkerAMIID != "" {
+		c.WorkerAMIID = other.WorkerAMIID
+	}
+	if other.ControlPlaneReplicas != nil {
+		c.ControlPlaneReplicas = other.ControlPlaneReplicas
+	}
+	if other.WorkerReplicas != nil {
+		c.WorkerReplicas = other.WorkerReplicas
+	}
+	if len(other.ComputePools) > 0 {
+		c.ComputePools = other.ComputePools
+	}
+	if len(other.Subnets) > 0 {
+		c.Subnets = other.Subnets
+	}
+	if other.Private {
+		c.Private = other.Private
+	}
+	if len(other.Zones) > 0 {
+		c.Zones = other.Zones
+	}
+	for key, value := range other.IAMRoles {
+		if c.IAMRoles == nil {
+			c.IAMRoles = map[string]string{}
+		}
+		c.IAMRoles[key] = value
+	}
+	if other.OIDCProviderARN != "" {
+		c.OIDCProviderARN = other.OIDCProviderARN
+	}
+	if other.OIDCIssuerURL != "" {
+		c.OIDCIssuerURL = other.OIDCIssuerURL
+	}
+	if other.OIDCSigningKey != "" {
+		c.OIDCSigningKey = other.OIDCSigningKey
+	}
+	if other.PermissionsBoundary != "" {
+		c.PermissionsBoundary = other.PermissionsBoundary
+	}
+	if other.IAMRolePath != "" {
+		c.IAMRolePath = other.IAMRolePath
+	}
+	if other.ResourcePrefix != "" {
+		c.ResourcePrefix = other.ResourcePrefix
+	}
+	if other.NameSuffix != "" {
+		c.NameSuffix = other.NameSuffix
+	}
+	for key, value := range other.Tags {
+		if c.Tags == nil {
+			c.Tags = map[string]string{}
+		}
+		c.Tags[key] = value
+	}
+	if other.MaxMonthlyCost != 0 {
+		c.MaxMonthlyCost = other.MaxMonthlyCost
+	}
+	for step, timeout := range other.StepTimeouts {
+		if c.StepTimeouts == nil {
+			c.StepTimeouts = map[string]string{}
+		}
+		c.StepTimeouts[step] = timeout
+	}
+	if other.Version != "" {
+		c.Version = other.Version
+	}
+	if other.Channel != "" {
+		c.Channel = other.Channel
+	}
+	if other.Architecture != "" {
+		c.Architecture = other.Architecture
+	}
+	if other.InstallTimeout != "" {
+		c.InstallTimeout = other.InstallTimeout
+	}
+	if other.ExpiresIn != "" {
+		c.ExpiresIn = other.ExpiresIn
+	}
+	if other.HealthGateTimeout != "" {
+		c.HealthGateTimeout = other.HealthGateTimeout
+	}
+	if other.VerifyBinaries {
+		c.VerifyBinaries = other.VerifyBinaries
+	}
+	if other.CleanupOnFailure {
+		c.CleanupOnFailure = other.CleanupOnFailure
+	}
+	if other.ReleaseSigningKey != "" {
+		c.ReleaseSigningKey = other.ReleaseSigningKey
+	}
+	if other.ExtraManifestsDir != "" {
+		c.ExtraManifestsDir = other.ExtraManifestsDir
+	}
+	if other.PostInstallManifestsDir != "" {
+		c.PostInstallManifestsDir = other.PostInstallManifestsDir
+	}
+	if len(other.NTPServers) > 0 {
+		c.NTPServers = other.NTPServers
+	}
+	if len(other.KernelArguments) > 0 {
+		c.KernelArguments = other.KernelArguments
+	}
+	if len(other.InsecureRegistries) > 0 {
+		c.InsecureRegistries = other.InsecureRegistries
+	}
+	if len(other.Hooks.OnFailure) > 0 {
+		c.Hooks.OnFailure = other.Hooks.OnFailure
+	}
+	if other.Vault.Address != "" {
+		c.Vault.Address = other.Vault.Address
+	}
+	if other.Vault.PullSecret != "" {
+		c.Vault.PullSecret = other.Vault.PullSecret
+	}
+	if other.Vault.SSHKey != "" {
+		c.Vault.SSHKey = other.Vault.SSHKey
+	}
+	if other.Vault.AWSCredentials != "" {
+		c.Vault.AWSCredentials = other.Vault.AWSCredentials
+	}
+	if other.Notifications.WebhookURL != "" {
+		c.Notifications.WebhookURL = other.Notifications.WebhookURL
+	}
+	if other.Notifications.Desktop {
+		c.Notifications.Desktop = other.Notifications.Desktop
+	}
+	if other.Metrics.PushgatewayURL != "" {
+		c.Metrics.PushgatewayURL = other.Metrics.PushgatewayURL
+	}
+	if other.Metrics.Textfile != "" {
+		c.Metrics.Textfile = other.Metrics.Textfile
+	}
+	if other.Tracing.OTLPEndpoint != "" {
+		c.Tracing.OTLPEndpoint = other.Tracing.OTLPEndpoint
+	}
+	if other.PostInstall.AdminUser != "" {
+		c.PostInstall.AdminUser = other.PostInstall.AdminUser
+	}
+	if other.PostInstall.RemoveKubeadmin {
+		c.PostInstall.RemoveKubeadmin = other.PostInstall.RemoveKubeadmin
+	}
+	if len(other.PostInstall.Operators) > 0 {
+		c.PostInstall.Operators = other.PostInstall.Operators
+	}
+	if other.Hosted.ManagementKubeconfig != "" {
+		c.Hosted.ManagementKubeconfig = other.Hosted.ManagementKubeconfig
+	}
+	if other.Hosted.Namespace != "" {
+		c.Hosted.Namespace = other.Hosted.Namespace
+	}
+	if other.Hosted.RoleARN != "" {
+		c.Hosted.RoleARN = other.Hosted.RoleARN
+	}
+	if other.Binaries.OC != "" {
+		c.Binaries.OC = other.Binaries.OC
+	}
+	if other.Binaries.OpenshiftInstall != "" {
+		c.Binaries.OpenshiftInstall = other.Binaries.OpenshiftInstall
+	}
+	if other.Binaries.Ccoctl != "" {
+		c.Binaries.Ccoctl = other.Binaries.Ccoctl
+	}
+	if other.Mirror.Registry != "" {
+		c.Mirror.Registry = other.Mirror.Registry
+	}
+	if other.Mirror.CABundle != "" {
+		c.Mirror.CABundle = other.Mirror.CABundle
+	}
 }
 
 // ValidateConfig validates that required fields are set
 func ValidateConfig(cfg *Config) error {
 	if cfg.ReleaseImage == "" {
-		return fmt.Errorf("release image is required")
+		return fmt.Errorf("release image is required (use --release-image, --version or --channel)")
 	}
 	if cfg.ClusterName == "" {
 		return fmt.Errorf("cluster name is required (use --cluster-name flag)")
 	}
+	if err := ValidateClusterName(cfg.ClusterName, cfg.BaseDomain, cfg.ResourcePrefix); err != nil {
+		return err
+	}
 	// AwsRegion is optional - can be read from install-config.yaml
+	if errs := ConsistencyErrors(cfg); len(errs) > 0 {
+		return errs[0]
+	}
 	return nil
 }
 
+// ValidateHostedConfig validates that the fields a hosted cluster needs, on
+// top of the ones of ValidateConfig, are set
+func ValidateHostedConfig(cfg *Config) error {
+	if err := ValidateConfig(cfg); err != nil {
+		return err
+	}
+	switch {
+	case cfg.Hosted.ManagementKubeconfig == "":
+		return fmt.Errorf("the kubeconfig of the management cluster is required (use --management-kubeconfig)")
+	case cfg.Hosted.RoleARN == "":
+		return fmt.Errorf("the role hcp creates the infrastructure with is required (use --role-arn)")
+	case cfg.AwsRegion == "":
+		return fmt.Errorf("AWS region is required (use --region)")
+	case cfg.BaseDomain == "":
+		return fmt.Errorf("base domain is required (use --base-domain)")
+	}
+	return nil
+}
+
+// ConsistencyErrors returns every invalid value or inconsistent combination of
+// fields in the configuration, regardless of whether required fields are set
+func ConsistencyErrors(cfg *Config) []error {
+	var errs []error
+	if cfg.Private && len(cfg.Subnets) == 0 {
+		errs = append(errs, fmt.Errorf("private clusters must be installed into existing private subnets (use --subnets)"))
+	}
+	if cfg.MFASerial != "" && cfg.AssumeRoleARN == "" {
+		errs = append(errs, fmt.Errorf("an MFA serial requires a role to assume (use --assume-role-arn)"))
+	}
+	if cfg.ControlPlaneReplicas != nil && *cfg.ControlPlaneReplicas < 1 {
+		errs = append(errs, fmt.Errorf("control plane replicas must be at least 1"))
+	}
+	if cfg.WorkerReplicas != nil && *cfg.WorkerReplicas < 0 {
+		errs = append(errs, fmt.Errorf("worker replicas cannot be negative"))
+	}
+	errs = append(errs, computePoolErrors(cfg.ComputePools)...)
+	if cfg.MaxMonthlyCost < 0 {
+		errs = append(errs, fmt.Errorf("maxMonthlyCost cannot be negative"))
+	}
+	for _, key := range sortedKeys(cfg.Tags) {
+		if err := validateTag(key, cfg.Tags[key]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if cfg.NameSuffix != "" && cfg.NameSuffix != NameSuffixRandom && cfg.NameSuffix != NameSuffixNone {
+		errs = append(errs, fmt.Errorf("invalid nameSuffix %q: must be %s or %s", cfg.NameSuffix, NameSuffixRandom, NameSuffixNone))
+	}
+	errs = append(errs, partitionErrors(cfg)...)
+	errs = append(errs, serviceEndpointErrors(cfg.ServiceEndpoints)...)
+	errs = append(errs, nodeCustomizationErrors(cfg)...)
+	errs = append(errs, amiErrors(cfg)...)
+	errs = append(errs, architectureErrors(cfg)...)
+	errs = append(errs, externalIAMErrors(cfg)...)
+	errs = append(errs, iamRoleSettingsErrors(cfg)...)
+	if webhook := cfg.Notifications.WebhookURL; webhook != "" && !strings.HasPrefix(webhook, "https://") && !strings.HasPrefix(webhook, "http://") {
+		errs = append(errs, fmt.Errorf("notifications.webhookUrl must be an http(s) URL"))
+	}
+	if gateway := cfg.Metrics.PushgatewayURL; gateway != "" && !strings.HasPrefix(gateway, "https://") && !strings.HasPrefix(gateway, "http://") {
+		errs = append(errs, fmt.Errorf("metrics.pushgatewayUrl must be an http(s) URL"))
+	}
+	if endpoint := cfg.Tracing.OTLPEndpoint; endpoint != "" && !strings.HasPrefix(endpoint, "https://") && !strings.HasPrefix(endpoint, "http://") {
+		errs = append(errs, fmt.Errorf("tracing.otlpEndpoint must be an http(s) URL"))
+	}
+	if cfg.PostInstall.RemoveKubeadmin && cfg.PostInstall.AdminUser == "" {
+		errs = append(errs, fmt.Errorf("postInstall.removeKubeadmin requires postInstall.adminUser"))
+	}
+	if user := cfg.PostInstall.AdminUser; user == "kubeadmin" || strings.ContainsAny(user, ": \t") {
+		errs = append(errs, fmt.Errorf("invalid postInstall.adminUser %q", user))
+	}
+	for i, operator := range cfg.PostInstall.Operators {
+		if operator.Name == "" {
+			errs = append(errs, fmt.Errorf("postInstall.operators[%d]: name is required", i))
+		}
+		if ns := operator.Namespace; ns != "" && (len(ns) > 63 || !clusterNamePattern.MatchString(ns)) {
+			errs = append(errs, fmt.Errorf("postInstall.operators[%d]: invalid namespace %q", i, operator.Namespace))
+		}
+	}
+	if ns := cfg.Hosted.Namespace; ns != "" && (len(ns) > 63 || !clusterNamePattern.MatchString(ns)) {
+		errs = append(errs, fmt.Errorf("invalid hosted.namespace %q", ns))
+	}
+	if arn := cfg.Hosted.RoleARN; arn != "" && !iamRoleARNPattern.MatchString(arn) {
+		errs = append(errs, fmt.Errorf("invalid hosted.roleArn %q (e.g. arn:aws:iam::123456789012:role/hcp-cli-role)", arn))
+	}
+	if oc := cfg.Binaries.OC; oc != "" && filepath.Base(oc) != "oc" {
+		// Its directory is put first in PATH: oc is run by name
+		errs = append(errs, fmt.Errorf("binaries.oc %q must be named oc", oc))
+	}
+	if registry := cfg.Mirror.Registry; strings.Contains(registry, "://") || strings.HasSuffix(registry, "/") {
+		errs = append(errs, fmt.Errorf("invalid mirror.registry %q: expected a registry and optional path, e.g. registry.example.com:5000/ocp", registry))
+	}
+	if cfg.Mirror.CABundle != "" && cfg.Mirror.Registry == "" {
+		errs = append(errs, fmt.Errorf("mirror.caBundle requires mirror.registry"))
+	}
+	if _, err := cfg.GetInstallTimeout(); err != nil {
+		errs = append(errs, err)
+	}
+	if _, err := cfg.GetHealthGateTimeout(); err != nil {
+		errs = append(errs, err)
+	}
+	if _, err := cfg.GetExpiresIn(); err != nil {
+		errs = append(errs, err)
+	}
+	if cfg.OnlyStep > 0 && (cfg.StartFromStep > 0 || cfg.StopAfterStep > 0) {
+		errs = append(errs, fmt.Errorf("--only-step cannot be combined with --start-from-step or --stop-after-step"))
+	}
+	if cfg.StartFromStep > 0 && cfg.StopAfterStep > 0 && cfg.StopAfterStep < cfg.StartFromStep {
+		errs = append(errs, fmt.Errorf("--stop-after-step %s comes before --start-from-step %s", StepName(cfg.StopAfterStep), StepName(cfg.StartFromStep)))
+	}
+	for _, step := range cfg.SkipSteps {
+		num, err := ParseStep(step)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid step in skipSteps: %w", err))
+			continue
+		}
+		if num == cfg.OnlyStep {
+			errs = append(errs, fmt.Errorf("--only-step %s is also listed in skipSteps", StepName(num)))
+		}
+	}
+	for _, step := range sortedKeys(cfg.StepTimeouts) {
+		num, err := ParseStep(step)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid step in stepTimeouts: %w", err))
+			continue
+		}
+		if _, err := cfg.GetStepTimeout(num); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// nodeCustomizationErrors checks the values rendered into MachineConfigs
+func nodeCustomizationErrors(cfg *Config) []error {
+	var errs []error
+	for _, server := range cfg.NTPServers {
+		if server == "" || strings.ContainsAny(server, " \t\n/") {
+			errs = append(errs, fmt.Errorf("invalid NTP server %q in ntpServers", server))
+		}
+	}
+	for _, arg := range cfg.KernelArguments {
+		if arg == "" || strings.ContainsAny(arg, " \t\n") {
+			errs = append(errs, fmt.Errorf("invalid kernel argument %q in kernelArguments (one argument per item)", arg))
+		}
+	}
+	for _, registry := range cfg.InsecureRegistries {
+		if registry == "" || strings.Contains(registry, "://") || strings.ContainsAny(registry, " \t\n\"") {
+			errs = append(errs, fmt.Errorf("invalid registry %q in insecureRegistries (host[:port][/path], without scheme)", registry))
+		}
+	}
+	return errs
+}
+
+// iamRoleARNPattern matches IAM role ARNs, with an optional path
+var iamRoleARNPattern = regexp.MustCompile(`^arn:aws[a-z-]*:iam::\d{12}:role/[\w+=,.@/-]+$`)
+
+// oidcProviderARNPattern matches IAM OIDC provider ARNs
+var oidcProviderARNPattern = regexp.MustCompile(`^arn:aws[a-z-]*:iam::\d{12}:oidc-provider/(\S+)$`)
+
+// ExternalIAM reports whether the IAM roles and the OIDC provider are created
+// outside of the wrapper
+func (c *Config) ExternalIAM() bool {
+	return len(c.IAMRoles) > 0
+}
+
+// SharedOIDCProvider reports whether the cluster uses an existing OIDC
+// provider, shared with other clusters, for the IAM roles created by ccoctl
+func (c *Config) SharedOIDCProvider() bool {
+	return c.OIDCProviderARN != ""
+}
+
+// externalIAMErrors checks the existing IAM roles and OIDC provider
+func externalIAMErrors(cfg *Config) []error {
+	var mode string
+	switch {
+	case cfg.ExternalIAM() && cfg.SharedOIDCProvider():
+		return []error{fmt.Errorf("iamRoles and oidcProviderARN cannot be used together: the existing roles already trust their OIDC provider")}
+	case cfg.ExternalIAM():
+		mode = "iamRoles"
+	case cfg.SharedOIDCProvider():
+		mode = "oidcProviderARN"
+	default:
+		if cfg.OIDCIssuerURL != "" || cfg.OIDCSigningKey != "" {
+			return []error{fmt.Errorf("oidcIssuerURL and oidcSigningKey are only used with iamRoles or oidcProviderARN")}
+		}
+		return nil
+	}
+
+	var errs []error
+	for _, key := range sortedKeys(cfg.IAMRoles) {
+		if namespace, name, ok := strings.Cut(key, "/"); !ok || namespace == "" || name == "" || strings.Contains(name, "/") {
+			errs = append(errs, fmt.Errorf("invalid iamRoles key %q (namespace/name of a CredentialsRequest or of its secret)", key))
+		}
+		if arn := cfg.IAMRoles[key]; !iamRoleARNPattern.MatchString(arn) {
+			errs = append(errs, fmt.Errorf("invalid role ARN %q for %s in iamRoles", arn, key))
+		}
+	}
+	if cfg.SharedOIDCProvider() {
+		match := oidcProviderARNPattern.FindStringSubmatch(cfg.OIDCProviderARN)
+		if match == nil {
+			errs = append(errs, fmt.Errorf("invalid oidcProviderARN %q (e.g. arn:aws:iam::123456789012:oidc-provider/oidc.example.com)", cfg.OIDCProviderARN))
+		} else if issuer := strings.TrimSuffix(cfg.OIDCIssuerURL, "/"); issuer != "" && issuer != "https://"+match[1] {
+			errs = append(errs, fmt.Errorf("oidcIssuerURL %s is not the issuer of the OIDC provider %s", cfg.OIDCIssuerURL, cfg.OIDCProviderARN))
+		}
+	}
+	if !strings.HasPrefix(cfg.OIDCIssuerURL, "https://") {
+		errs = append(errs, fmt.Errorf("%s requires the https:// oidcIssuerURL of the existing OIDC provider", mode))
+	}
+	if cfg.OIDCSigningKey == "" {
+		errs = append(errs, fmt.Errorf("%s requires the oidcSigningKey of the existing OIDC provider", mode))
+	}
+	if cfg.PrivateBucket {
+		errs = append(errs, fmt.Errorf("privateBucket cannot be used with %s: no OIDC bucket is created", mode))
+	}
+	return errs
+}
+
+// permissionsBoundaryPattern matches IAM managed policy ARNs
+var permissionsBoundaryPattern = regexp.MustCompile(`^arn:aws[a-z-]*:iam::(\d{12}|aws):policy/.+$`)
+
+// iamRolePathPattern matches IAM paths, which start and end with a slash
+var iamRolePathPattern = regexp.MustCompile(`^/([\w+=,.@-]+/)*$`)
+
+// iamRoleSettingsErrors checks the settings of the IAM roles created in Step 7
+func iamRoleSettingsErrors(cfg *Config) []error {
+	var errs []error
+	if cfg.PermissionsBoundary != "" && !permissionsBoundaryPattern.MatchString(cfg.PermissionsBoundary) {
+		errs = append(errs, fmt.Errorf("invalid permissionsBoundary %q (e.g. arn:aws:iam::123456789012:policy/openshift-boundary)", cfg.PermissionsBoundary))
+	}
+	if cfg.IAMRolePath != "" && (!iamRolePathPattern.MatchString(cfg.IAMRolePath) || len(cfg.IAMRolePath) > 512) {
+		errs = append(errs, fmt.Errorf("invalid iamRolePath %q (e.g. /openshift/)", cfg.IAMRolePath))
+	}
+	if cfg.ExternalIAM() && (cfg.PermissionsBoundary != "" || cfg.IAMRolePath != "") {
+		errs = append(errs, fmt.Errorf("permissionsBoundary and iamRolePath cannot be used with iamRoles: no IAM role is created"))
+	}
+	if err := ValidateResourcePrefix(cfg.ResourcePrefix); cfg.ResourcePrefix != "" && err != nil {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// MaxResourcePrefixLength is the longest resource prefix: ccoctl names the IAM
+// roles <prefix>-<namespace>-<secret>, truncated to the 64 characters allowed
+// by IAM, so a longer prefix would leave too little to tell the roles apart
+const MaxResourcePrefixLength = 32
+
+// resourcePrefixPattern matches the names S3 accepts for the <prefix>-oidc bucket
+var resourcePrefixPattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?$`)
+
+// ValidateResourcePrefix checks that a resource prefix can name the IAM roles,
+// OIDC provider and S3 bucket created by ccoctl
+func ValidateResourcePrefix(prefix string) error {
+	if len(prefix) > MaxResourcePrefixLength {
+		return fmt.Errorf("resourcePrefix %q is longer than %d characters", prefix, MaxResourcePrefixLength)
+	}
+	if !resourcePrefixPattern.MatchString(prefix) {
+		return fmt.Errorf("invalid resourcePrefix %q: only lowercase letters, digits and hyphens are allowed, starting and ending with a letter or digit", prefix)
+	}
+	return nil
+}
+
+// Values of NameSuffix
+const (
+	NameSuffixRandom = "random"
+	NameSuffixNone   = "none" // Overrides a random suffix of the config file
+)
+
+// nameSuffixLength is the length of a random name suffix, as the one of the
+// infra ID of openshift-install
+const nameSuffixLength = 5
+
+// ApplyNameSuffix appends a random suffix to the cluster name of a new install
+// when NameSuffix is random, and returns whether it did. The suffix is not
+// appended when resuming an install (from a step, or without Step 4), which
+// must name the cluster with its suffix. NameSuffix is cleared, so the suffix
+// is only appended once.
+func (c *Config) ApplyNameSuffix() (bool, error) {
+	if c.NameSuffix != NameSuffixRandom || c.ClusterName == "" || c.StartFromStep != 0 || c.OnlyStep != 0 || c.StepSkipped(4) {
+		return false, nil
+	}
+	const alphabet = "abcdefghijklmnopqrstuvwxyz0123456789"
+	suffix := make([]byte, nameSuffixLength)
+	if _, err := rand.Read(suffix); err != nil {
+		return false, fmt.Errorf("failed to generate a name suffix: %w", err)
+	}
+	for i := range suffix {
+		suffix[i] = alphabet[int(suffix[i])%len(alphabet)]
+	}
+	c.ClusterName = c.ClusterName + "-" + string(suffix)
+	c.NameSuffix = ""
+	return true, nil
+}
+
+// CcoctlName returns the name of the AWS resources created by ccoctl (its
+// --name): the resource prefix, or the cluster name
+func (c *Config) CcoctlName() string {
+	if c.ResourcePrefix != "" {
+		return c.ResourcePrefix
+	}
+	return c.ClusterName
+}
+
+// amiIDPattern matches EC2 image IDs
+var amiIDPattern = regexp.MustCompile(`^ami-([0-9a-f]{8}|[0-9a-f]{17})$`)
+
+// amiErrors checks the format of the boot image IDs
+func amiErrors(cfg *Config) []error {
+	var errs []error
+	for _, ami := range []struct{ key, id string }{
+		{"amiID", cfg.AMIID},
+		{"controlPlaneAMIID", cfg.ControlPlaneAMIID},
+		{"workerAMIID", cfg.WorkerAMIID},
+	} {
+		if ami.id != "" && !amiIDPattern.MatchString(ami.id) {
+			errs = append(errs, fmt.Errorf("invalid %s %q (e.g. ami-0123456789abcdef0)", ami.key, ami.id))
+		}
+	}
+	return errs
+}
+
+// validateTag checks a user tag against the AWS tagging rules
+func validateTag(key, value string) error {
+	switch {
+	case key == "":
+		return fmt.Errorf("tag keys cannot be empty")
+	case len(key) > 128:
+		return fmt.Errorf("tag key %q is longer than 128 characters", key)
+	case len(value) > 256:
+		return fmt.Errorf("value of tag %q is longer than 256 characters", key)
+	case strings.HasPrefix(strings.ToLower(key), "aws:"):
+		return fmt.Errorf("tag key %q uses the reserved aws: prefix", key)
+	case strings.HasPrefix(key, "kubernetes.io/cluster/") || strings.HasPrefix(key, "openshift.io/"):
+		return fmt.Errorf("tag key %q uses a prefix reserved for the installer", key)
+	}
+	return nil
+}
+
+// GetInstallTimeout returns the overall installation timeout (0 means no timeout)
+func (c *Config) GetInstallTimeout() (time.Duration, error) {
+	if c.InstallTimeout == "" {
+		return 0, nil
+	}
+	timeout, err := time.ParseDuration(c.InstallTimeout)
+	if err != nil || timeout < 0 {
+		return 0, fmt.Errorf("invalid installTimeout %q: must be a positive duration (e.g. 2h30m)", c.InstallTimeout)
+	}
+	return timeout, nil
+}
+
+// GetExpiresIn returns the lifetime of the cluster (0 means it never expires)
+func (c *Config) GetExpiresIn() (time.Duration, error) {
+	if c.ExpiresIn == "" {
+		return 0, nil
+	}
+	lifetime, err := time.ParseDuration(c.ExpiresIn)
+	if err != nil || lifetime <= 0 {
+		return 0, fmt.Errorf("invalid expiresIn %q: must be a positive duration (e.g. 8h)", c.ExpiresIn)
+	}
+	return lifetime, nil
+}
+
+// GetHealthGateTimeout returns how long Step 11 waits for the ClusterOperators
+// to be healthy (0 means the health gate is disabled)
+func (c *Config) GetHealthGateTimeout() (time.Duration, error) {
+	if c.HealthGateTimeout == "" {
+		return 0, nil
+	}
+	timeout, err := time.ParseDuration(c.HealthGateTimeout)
+	if err != nil || timeout <= 0 {
+		return 0, fmt.Errorf("invalid healthGateTimeout %q: must be a positive duration (e.g. 30m)", c.HealthGateTimeout)
+	}
+	return timeout, nil
+}
+
+// GetStepTimeout returns the timeout configured for a step, by name or number
+// (0 means no timeout)
+func (c *Config) GetStepTimeout(stepNum int) (time.Duration, error) {
+	value, ok := c.StepTimeouts[StepName(stepNum)]
+	if !ok {
+		value, ok = c.StepTimeouts[strconv.Itoa(stepNum)]
+	}
+	if !ok || value == "" {
+		return 0, nil
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout < 0 {
+		return 0, fmt.Errorf("invalid timeout %q for step %d: must be a positive duration (e.g. 90m)", value, stepNum)
+	}
+	return timeout, nil
+}
+
+// ControlPlaneInstanceType returns the instance type of the control plane pool
+func (c *Config) ControlPlaneInstanceType() string {
+	if c.ControlPlaneType != "" {
+		return c.ControlPlaneType
+	}
+	return c.InstanceType
+}
+
+// WorkerInstanceType returns the instance type of the compute pool
+func (c *Config) WorkerInstanceType() string {
+	if pool := c.WorkerPool(); pool != nil && pool.InstanceType != "" {
+		return pool.InstanceType
+	}
+	if c.WorkerType != "" {
+		return c.WorkerType
+	}
+	return c.InstanceType
+}
+
+// WorkerPoolReplicas returns the number of workers (nil keeps the
+// install-config value)
+func (c *Config) WorkerPoolReplicas() *int {
+	if pool := c.WorkerPool(); pool != nil && pool.Replicas != nil {
+		return pool.Replicas
+	}
+	return c.WorkerReplicas
+}
+
+// WorkerPool returns the computePools entry of the worker pool, if any
+func (c *Config) WorkerPool() *ComputePool {
+	for i := range c.ComputePools {
+		if c.ComputePools[i].Name == WorkerPoolName {
+			return &c.ComputePools[i]
+		}
+	}
+	return nil
+}
+
+// AdditionalComputePools returns the compute pools other than the worker pool
+func (c *Config) AdditionalComputePools() []ComputePool {
+	var pools []ComputePool
+	for _, pool := range c.ComputePools {
+		if pool.Name != WorkerPoolName {
+			pools = append(pools, pool)
+		}
+	}
+	return pools
+}
+
+// ComputePoolInstanceType returns the instance type of an additional compute
+// pool, the worker one unless it sets its own
+func (c *Config) ComputePoolInstanceType(pool ComputePool) string {
+	if pool.InstanceType != "" {
+		return pool.InstanceType
+	}
+	return c.WorkerInstanceType()
+}
+
+// ControlPlaneAMI returns the AMI of the control plane pool (empty for the
+// RHCOS image of the release)
+func (c *Config) ControlPlaneAMI() string {
+	if c.ControlPlaneAMIID != "" {
+		return c.ControlPlaneAMIID
+	}
+	return c.AMIID
+}
+
+// WorkerAMI returns the AMI of the compute pool (empty for the RHCOS image of
+// the release)
+func (c *Config) WorkerAMI() string {
+	if c.WorkerAMIID != "" {
+		return c.WorkerAMIID
+	}
+	return c.AMIID
+}
+
+// Publish returns the install-config publish strategy
+func (c *Config) Publish() string {
+	if c.Private {
+		return "Internal"
+	}
+	return "External"
+}
+
+// PullSpec returns the release image pinned to its digest (repo@sha256:...) when
+// the digest is known, so that every command operates on the same content even
+// if the tag is moved. Otherwise it returns ReleaseImage unchanged.
+func (c *Config) PullSpec() string {
+	if c.ReleaseDigest == "" || strings.Contains(c.ReleaseImage, "@") {
+		return c.ReleaseImage
+	}
+
+	repo := c.ReleaseImage
+	if i := strings.LastIndex(repo, ":"); i > strings.LastIndex(repo, "/") {
+		repo = repo[:i]
+	}
+	return repo + "@" + c.ReleaseDigest
+}
+
 // SetDefaults sets default values for optional fields
 func (c *Config) SetDefaults() {
 	if c.PullSecretPath == "" {
 		c.PullSecretPath = "pull-secret.json"
+		c.setSource("pullSecretPath", SourceDefault)
 	}
 	if c.AwsProfile == "" {
 		c.AwsProfile = "default"
+		c.setSource("awsProfile", SourceDefault)
 	}
-	if c.InstanceType == "" {
-		c.InstanceType = "m5.4xlarge"
+	// The default follows the architecture of the release, which may only be
+	// known once it is resolved: SetDefaults runs again then
+	if c.InstanceType == "" || c.Sources["instanceType"] == SourceDefault {
+		c.InstanceType = DefaultInstanceTypeFor(c.ClusterArchitecture())
+		c.setSource("instanceType", SourceDefault)
 	}
 }
 
@@ -3,7 +3,10 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestLoadConfigFromFile(t *testing.T) {
@@ -43,9 +46,17 @@ privateBucket: true
 func TestLoadConfigFromEnv(t *testing.T) {
 	os.Setenv("OPENSHIFT_STS_RELEASE_IMAGE", "quay.io/test:4.11.0-x86_64")
 	os.Setenv("OPENSHIFT_STS_AWS_REGION", "us-west-2")
+	os.Setenv("OPENSHIFT_STS_VERIFY_BINARIES", "true")
+	os.Setenv("OPENSHIFT_STS_CLEANUP_ON_FAILURE", "true")
+	os.Setenv("OPENSHIFT_STS_POST_INSTALL_MANIFESTS_DIR", "./post-install")
+	os.Setenv("OPENSHIFT_STS_CCOCTL_PATH", "/opt/mirror/bin/ccoctl")
 	defer func() {
 		os.Unsetenv("OPENSHIFT_STS_RELEASE_IMAGE")
 		os.Unsetenv("OPENSHIFT_STS_AWS_REGION")
+		os.Unsetenv("OPENSHIFT_STS_VERIFY_BINARIES")
+		os.Unsetenv("OPENSHIFT_STS_CLEANUP_ON_FAILURE")
+		os.Unsetenv("OPENSHIFT_STS_POST_INSTALL_MANIFESTS_DIR")
+		os.Unsetenv("OPENSHIFT_STS_CCOCTL_PATH")
 	}()
 
 	cfg := LoadFromEnv()
@@ -60,6 +71,33 @@ func TestLoadConfigFromEnv(t *testing.T) {
 	if cfg.AwsRegion != "us-west-2" {
 		t.Errorf("Expected AwsRegion from env, got %q", cfg.AwsRegion)
 	}
+	if !cfg.VerifyBinaries {
+		t.Error("Expected VerifyBinaries from env")
+	}
+	if !cfg.CleanupOnFailure {
+		t.Error("Expected CleanupOnFailure from env")
+	}
+	if cfg.PostInstallManifestsDir != "./post-install" {
+		t.Errorf("Expected PostInstallManifestsDir from env, got %q", cfg.PostInstallManifestsDir)
+	}
+	if cfg.Binaries.Ccoctl != "/opt/mirror/bin/ccoctl" {
+		t.Errorf("Expected Binaries.Ccoctl from env, got %q", cfg.Binaries.Ccoctl)
+	}
+}
+
+func TestEnvErrors(t *testing.T) {
+	t.Setenv("OPENSHIFT_STS_MAX_MONTHLY_COST", "1500")
+	if errs := EnvErrors(); len(errs) != 0 {
+		t.Errorf("Unexpected errors: %v", errs)
+	}
+	if cfg := LoadFromEnv(); cfg.MaxMonthlyCost != 1500 {
+		t.Errorf("Expected MaxMonthlyCost from env, got %v", cfg.MaxMonthlyCost)
+	}
+
+	t.Setenv("OPENSHIFT_STS_MAX_MONTHLY_COST", "$1500")
+	if errs := EnvErrors(); len(errs) != 1 || !strings.Contains(errs[0].Error(), "OPENSHIFT_STS_MAX_MONTHLY_COST") {
+		t.Errorf("Expected an invalid OPENSHIFT_STS_MAX_MONTHLY_COST error, got %v", errs)
+	}
 }
 
 func TestConfigMerge(t *testing.T) {
@@ -102,6 +140,125 @@ func TestValidateConfig(t *testing.T) {
 			},
 			shouldError: false,
 		},
+		{
+			name: "x86 instance type for an arm64 release",
+			config: Config{
+				ReleaseImage: "quay.io/test:4.15.0-aarch64",
+				ClusterName:  "test-cluster",
+				InstanceType: "m5.4xlarge",
+			},
+			shouldError: true,
+		},
+		{
+			name: "arm64 worker type for an x86 release",
+			config: Config{
+				ReleaseImage: "quay.io/test:4.15.0-x86_64",
+				ClusterName:  "test-cluster",
+				WorkerType:   "m7gd.2xlarge",
+			},
+			shouldError: true,
+		},
+		{
+			name: "any instance type for a multi-arch release",
+			config: Config{
+				ReleaseImage: "quay.io/test:4.15.0-multi",
+				ClusterName:  "test-cluster",
+				InstanceType: "m6g.4xlarge",
+			},
+			shouldError: false,
+		},
+		{
+			name: "stop after step before start from step",
+			config: Config{
+				ReleaseImage:  "quay.io/test:4.12.0-x86_64",
+				ClusterName:   "test-cluster",
+				StartFromStep: 7,
+				StopAfterStep: 5,
+			},
+			shouldError: true,
+		},
+		{
+			name: "only step with start from step",
+			config: Config{
+				ReleaseImage:  "quay.io/test:4.12.0-x86_64",
+				ClusterName:   "test-cluster",
+				StartFromStep: 7,
+				OnlyStep:      9,
+			},
+			shouldError: true,
+		},
+		{
+			name: "unknown skipped step",
+			config: Config{
+				ReleaseImage: "quay.io/test:4.12.0-x86_64",
+				ClusterName:  "test-cluster",
+				SkipSteps:    []string{"verification"},
+			},
+			shouldError: true,
+		},
+		{
+			name: "webhook URL without scheme",
+			config: Config{
+				ReleaseImage:  "quay.io/test:4.12.0-x86_64",
+				ClusterName:   "test-cluster",
+				Notifications: Notifications{WebhookURL: "hooks.slack.com/services/T000/B000/XXXX"},
+			},
+			shouldError: true,
+		},
+		{
+			name: "Pushgateway URL without scheme",
+			config: Config{
+				ReleaseImage: "quay.io/test:4.12.0-x86_64",
+				ClusterName:  "test-cluster",
+				Metrics:      MetricsConfig{PushgatewayURL: "pushgateway:9091"},
+			},
+			shouldError: true,
+		},
+		{
+			name: "OTLP endpoint without scheme",
+			config: Config{
+				ReleaseImage: "quay.io/test:4.12.0-x86_64",
+				ClusterName:  "test-cluster",
+				Tracing:      TracingConfig{OTLPEndpoint: "localhost:4318"},
+			},
+			shouldError: true,
+		},
+		{
+			name: "remove kubeadmin without admin user",
+			config: Config{
+				ReleaseImage: "quay.io/test:4.12.0-x86_64",
+				ClusterName:  "test-cluster",
+				PostInstall:  PostInstall{RemoveKubeadmin: true},
+			},
+			shouldError: true,
+		},
+		{
+			name: "operator without name",
+			config: Config{
+				ReleaseImage: "quay.io/test:4.12.0-x86_64",
+				ClusterName:  "test-cluster",
+				PostInstall:  PostInstall{Operators: []Operator{{Channel: "stable"}}},
+			},
+			shouldError: true,
+		},
+		{
+			name: "additional compute pool without replicas",
+			config: Config{
+				ReleaseImage: "quay.io/test:4.12.0-x86_64",
+				ClusterName:  "test-cluster",
+				ComputePools: []ComputePool{{Name: "gpu", InstanceType: "g5.xlarge"}},
+			},
+			shouldError: true,
+		},
+		{
+			name: "compute pool with invalid taint",
+			config: Config{
+				ReleaseImage: "quay.io/test:4.12.0-x86_64",
+				ClusterName:  "test-cluster",
+				ComputePools: []ComputePool{{Name: WorkerPoolName, Taints: []string{"dedicated"}}},
+			},
+			shouldError: true,
+		},
 		{
 			name: "missing release image",
 			config: Config{
@@ -120,6 +277,305 @@ func TestValidateConfig(t *testing.T) {
 			},
 			shouldError: true,
 		},
+		{
+			name: "private cluster without subnets",
+			config: Config{
+				ReleaseImage: "quay.io/test:4.12.0-x86_64",
+				ClusterName:  "test-cluster",
+				Private:      true,
+			},
+			shouldError: true,
+		},
+		{
+			name: "private cluster with subnets",
+			config: Config{
+				ReleaseImage: "quay.io/test:4.12.0-x86_64",
+				ClusterName:  "test-cluster",
+				Private:      true,
+				Subnets:      []string{"subnet-a"},
+			},
+			shouldError: false,
+		},
+		{
+			name: "zero control plane replicas",
+			config: Config{
+				ReleaseImage:         "quay.io/test:4.12.0-x86_64",
+				ClusterName:          "test-cluster",
+				ControlPlaneReplicas: intPtr(0),
+			},
+			shouldError: true,
+		},
+		{
+			name: "compact cluster without workers",
+			config: Config{
+				ReleaseImage:   "quay.io/test:4.12.0-x86_64",
+				ClusterName:    "test-cluster",
+				WorkerReplicas: intPtr(0),
+			},
+			shouldError: false,
+		},
+		{
+			name: "reserved tag prefix",
+			config: Config{
+				ReleaseImage: "quay.io/test:4.12.0-x86_64",
+				ClusterName:  "test-cluster",
+				Tags:         map[string]string{"aws:owner": "me"},
+			},
+			shouldError: true,
+		},
+		{
+			name: "invalid health gate timeout",
+			config: Config{
+				ReleaseImage:      "quay.io/test:4.12.0-x86_64",
+				ClusterName:       "test-cluster",
+				HealthGateTimeout: "soon",
+			},
+			shouldError: true,
+		},
+		{
+			name: "node customizations",
+			config: Config{
+				ReleaseImage:       "quay.io/test:4.12.0-x86_64",
+				ClusterName:        "test-cluster",
+				NTPServers:         []string{"ntp.example.com"},
+				KernelArguments:    []string{"nosmt"},
+				InsecureRegistries: []string{"registry.example.com:5000"},
+			},
+			shouldError: false,
+		},
+		{
+			name: "kernel arguments in a single item",
+			config: Config{
+				ReleaseImage:    "quay.io/test:4.12.0-x86_64",
+				ClusterName:     "test-cluster",
+				KernelArguments: []string{"nosmt mitigations=auto"},
+			},
+			shouldError: true,
+		},
+		{
+			name: "insecure registry with scheme",
+			config: Config{
+				ReleaseImage:       "quay.io/test:4.12.0-x86_64",
+				ClusterName:        "test-cluster",
+				InsecureRegistries: []string{"http://registry.example.com"},
+			},
+			shouldError: true,
+		},
+		{
+			name: "pinned boot images",
+			config: Config{
+				ReleaseImage:      "quay.io/test:4.12.0-x86_64",
+				ClusterName:       "test-cluster",
+				AMIID:             "ami-0123456789abcdef0",
+				ControlPlaneAMIID: "ami-12345678",
+			},
+			shouldError: false,
+		},
+		{
+			name: "invalid worker AMI",
+			config: Config{
+				ReleaseImage: "quay.io/test:4.12.0-x86_64",
+				ClusterName:  "test-cluster",
+				WorkerAMIID:  "rhcos-4.12",
+			},
+			shouldError: true,
+		},
+		{
+			name: "existing IAM roles",
+			config: Config{
+				ReleaseImage:   "quay.io/test:4.12.0-x86_64",
+				ClusterName:    "test-cluster",
+				IAMRoles:       map[string]string{"openshift-ingress-operator/cloud-credentials": "arn:aws:iam::123456789012:role/ocp/ingress"},
+				OIDCIssuerURL:  "https://oidc.example.com",
+				OIDCSigningKey: "bound-service-account-signing-key.key",
+			},
+			shouldError: false,
+		},
+		{
+			name: "existing IAM roles without OIDC provider",
+			config: Config{
+				ReleaseImage: "quay.io/test:4.12.0-x86_64",
+				ClusterName:  "test-cluster",
+				IAMRoles:     map[string]string{"openshift-ingress-operator/cloud-credentials": "arn:aws:iam::123456789012:role/ocp/ingress"},
+			},
+			shouldError: true,
+		},
+		{
+			name: "shared OIDC provider",
+			config: Config{
+				ReleaseImage:    "quay.io/test:4.12.0-x86_64",
+				ClusterName:     "test-cluster",
+				OIDCProviderARN: "arn:aws:iam::123456789012:oidc-provider/oidc.example.com",
+				OIDCIssuerURL:   "https://oidc.example.com/",
+				OIDCSigningKey:  "bound-service-account-signing-key.key",
+			},
+			shouldError: false,
+		},
+		{
+			name: "shared OIDC provider of another issuer",
+			config: Config{
+				ReleaseImage:    "quay.io/test:4.12.0-x86_64",
+				ClusterName:     "test-cluster",
+				OIDCProviderARN: "arn:aws:iam::123456789012:oidc-provider/oidc.example.com",
+				OIDCIssuerURL:   "https://other.example.com",
+				OIDCSigningKey:  "bound-service-account-signing-key.key",
+			},
+			shouldError: true,
+		},
+		{
+			name: "shared OIDC provider with private bucket",
+			config: Config{
+				ReleaseImage:    "quay.io/test:4.12.0-x86_64",
+				ClusterName:     "test-cluster",
+				OIDCProviderARN: "arn:aws:iam::123456789012:oidc-provider/oidc.example.com",
+				OIDCIssuerURL:   "https://oidc.example.com",
+				OIDCSigningKey:  "bound-service-account-signing-key.key",
+				PrivateBucket:   true,
+			},
+			shouldError: true,
+		},
+		{
+			name: "permissions boundary and IAM path",
+			config: Config{
+				ReleaseImage:        "quay.io/test:4.12.0-x86_64",
+				ClusterName:         "test-cluster",
+				PermissionsBoundary: "arn:aws:iam::123456789012:policy/openshift-boundary",
+				IAMRolePath:         "/openshift/sts/",
+			},
+			shouldError: false,
+		},
+		{
+			name: "IAM path without trailing slash",
+			config: Config{
+				ReleaseImage: "quay.io/test:4.12.0-x86_64",
+				ClusterName:  "test-cluster",
+				IAMRolePath:  "/openshift",
+			},
+			shouldError: true,
+		},
+		{
+			name: "permissions boundary not a policy",
+			config: Config{
+				ReleaseImage:        "quay.io/test:4.12.0-x86_64",
+				ClusterName:         "test-cluster",
+				PermissionsBoundary: "arn:aws:iam::123456789012:role/boundary",
+			},
+			shouldError: true,
+		},
+		{
+			name: "resource prefix",
+			config: Config{
+				ReleaseImage:   "quay.io/test:4.12.0-x86_64",
+				ClusterName:    "team-a-openshift-conformance-cluster-1",
+				ResourcePrefix: "team-a-ocp1",
+			},
+			shouldError: false,
+		},
+		{
+			name: "resource prefix with uppercase letters",
+			config: Config{
+				ReleaseImage:   "quay.io/test:4.12.0-x86_64",
+				ClusterName:    "test-cluster",
+				ResourcePrefix: "Team-A",
+			},
+			shouldError: true,
+		},
+		{
+			name: "resource prefix too long",
+			config: Config{
+				ReleaseImage:   "quay.io/test:4.12.0-x86_64",
+				ClusterName:    "test-cluster",
+				ResourcePrefix: "team-a-openshift-sts-cluster-number-one",
+			},
+			shouldError: true,
+		},
+		{
+			name: "negative budget",
+			config: Config{
+				ReleaseImage:   "quay.io/test:4.12.0-x86_64",
+				ClusterName:    "test-cluster",
+				MaxMonthlyCost: -100,
+			},
+			shouldError: true,
+		},
+		{
+			name: "GovCloud region",
+			config: Config{
+				ReleaseImage:     "quay.io/test:4.12.0-x86_64",
+				ClusterName:      "test-cluster",
+				AwsRegion:        "us-gov-west-1",
+				ServiceEndpoints: []ServiceEndpoint{{Name: "ec2", URL: "https://ec2.us-gov-west-1.amazonaws.com"}},
+			},
+			shouldError: false,
+		},
+		{
+			name: "invalid aws region",
+			config: Config{
+				ReleaseImage: "quay.io/test:4.12.0-x86_64",
+				ClusterName:  "test-cluster",
+				AwsRegion:    "us_east_1",
+			},
+			shouldError: true,
+		},
+		{
+			name: "partition not matching the region",
+			config: Config{
+				ReleaseImage: "quay.io/test:4.12.0-x86_64",
+				ClusterName:  "test-cluster",
+				AwsRegion:    "us-gov-west-1",
+				AwsPartition: "aws-cn",
+			},
+			shouldError: true,
+		},
+		{
+			name: "private bucket in GovCloud",
+			config: Config{
+				ReleaseImage:  "quay.io/test:4.12.0-x86_64",
+				ClusterName:   "test-cluster",
+				AwsRegion:     "us-gov-east-1",
+				PrivateBucket: true,
+			},
+			shouldError: true,
+		},
+		{
+			name: "plain http service endpoint",
+			config: Config{
+				ReleaseImage:     "quay.io/test:4.12.0-x86_64",
+				ClusterName:      "test-cluster",
+				ServiceEndpoints: []ServiceEndpoint{{Name: "iam", URL: "http://iam.example.com"}},
+			},
+			shouldError: true,
+		},
+		{
+			name: "duplicate service endpoint",
+			config: Config{
+				ReleaseImage: "quay.io/test:4.12.0-x86_64",
+				ClusterName:  "test-cluster",
+				ServiceEndpoints: []ServiceEndpoint{
+					{Name: "s3", URL: "https://s3.example.com"},
+					{Name: "s3", URL: "https://s3-proxy.example.com"},
+				},
+			},
+			shouldError: true,
+		},
+		{
+			name: "invalid hosted role ARN",
+			config: Config{
+				ReleaseImage: "quay.io/test:4.12.0-x86_64",
+				ClusterName:  "test-cluster",
+				Hosted:       HostedConfig{RoleARN: "hcp-cli-role"},
+			},
+			shouldError: true,
+		},
+		{
+			name: "oc binary not named oc",
+			config: Config{
+				ReleaseImage: "quay.io/test:4.12.0-x86_64",
+				ClusterName:  "test-cluster",
+				Binaries:     BinariesConfig{OC: "/opt/mirror/bin/oc-4.12"},
+			},
+			shouldError: true,
+		},
 		{
 			name: "missing aws region is ok",
 			config: Config{
@@ -143,3 +599,265 @@ func TestValidateConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateHostedConfig(t *testing.T) {
+	cfg := &Config{
+		ReleaseImage: "quay.io/test:4.12.0-x86_64",
+		ClusterName:  "test-cluster",
+		AwsRegion:    "us-east-2",
+		BaseDomain:   "example.com",
+		Hosted:       HostedConfig{ManagementKubeconfig: "management-kubeconfig"},
+	}
+	if err := ValidateHostedConfig(cfg); err == nil || !strings.Contains(err.Error(), "--role-arn") {
+		t.Errorf("Expected the missing role to be reported, got %v", err)
+	}
+
+	cfg.Hosted.RoleARN = "arn:aws:iam::123456789012:role/hcp-cli-role"
+	if err := ValidateHostedConfig(cfg); err != nil {
+		t.Errorf("Expected no error but got: %v", err)
+	}
+	if namespace := cfg.Hosted.HostedNamespace(); namespace != DefaultHostedNamespace {
+		t.Errorf("Expected the default namespace, got %q", namespace)
+	}
+}
+
+func TestTimeouts(t *testing.T) {
+	cfg := &Config{
+		ReleaseImage:      "quay.io/test:4.12.0-x86_64",
+		ClusterName:       "test-cluster",
+		InstallTimeout:    "3h",
+		HealthGateTimeout: "30m",
+		StepTimeouts:      map[string]string{"10": "90m"},
+	}
+
+	if err := ValidateConfig(cfg); err != nil {
+		t.Fatalf("Expected valid timeouts, got: %v", err)
+	}
+
+	installTimeout, _ := cfg.GetInstallTimeout()
+	if installTimeout != 3*time.Hour {
+		t.Errorf("Expected install timeout 3h, got %s", installTimeout)
+	}
+
+	healthGateTimeout, _ := cfg.GetHealthGateTimeout()
+	if healthGateTimeout != 30*time.Minute {
+		t.Errorf("Expected health gate timeout 30m, got %s", healthGateTimeout)
+	}
+
+	stepTimeout, _ := cfg.GetStepTimeout(10)
+	if stepTimeout != 90*time.Minute {
+		t.Errorf("Expected step 10 timeout 90m, got %s", stepTimeout)
+	}
+
+	noTimeout, _ := cfg.GetStepTimeout(7)
+	if noTimeout != 0 {
+		t.Errorf("Expected no timeout for step 7, got %s", noTimeout)
+	}
+
+	cfg.StepTimeouts["10"] = "forever"
+	if err := ValidateConfig(cfg); err == nil {
+		t.Error("Expected error for invalid step timeout")
+	}
+
+	cfg.StepTimeouts = map[string]string{"create-aws-resources": "20m"}
+	if stepTimeout, _ := cfg.GetStepTimeout(7); stepTimeout != 20*time.Minute {
+		t.Errorf("Expected create-aws-resources timeout 20m, got %s", stepTimeout)
+	}
+
+	cfg.StepTimeouts = map[string]string{"deploy": "90m"}
+	if err := ValidateConfig(cfg); err == nil {
+		t.Error("Expected error for unknown step key")
+	}
+}
+
+func TestPullSpec(t *testing.T) {
+	tests := []struct {
+		name     string
+		image    string
+		digest   string
+		expected string
+	}{
+		{"no digest", "quay.io/openshift-release-dev/ocp-release:4.12.0-x86_64", "", "quay.io/openshift-release-dev/ocp-release:4.12.0-x86_64"},
+		{"tag pinned to digest", "quay.io/openshift-release-dev/ocp-release:4.12.0-x86_64", "sha256:abc", "quay.io/openshift-release-dev/ocp-release@sha256:abc"},
+		{"registry with port", "registry.local:5000/ocp-release:4.12.0-x86_64", "sha256:abc", "registry.local:5000/ocp-release@sha256:abc"},
+		{"already a digest", "quay.io/ocp-release@sha256:def", "sha256:abc", "quay.io/ocp-release@sha256:def"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{ReleaseImage: tt.image, ReleaseDigest: tt.digest}
+			if got := cfg.PullSpec(); got != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func intPtr(value int) *int {
+	return &value
+}
+
+func TestApplyProfile(t *testing.T) {
+	cfg := &Config{
+		AwsRegion:    "us-east-1",
+		InstanceType: "m5.xlarge",
+		Tags:         map[string]string{"team": "qe"},
+		Profiles: map[string]Config{
+			"prod": {
+				AwsRegion:      "eu-west-1",
+				WorkerType:     "m5.4xlarge",
+				WorkerReplicas: intPtr(5),
+				Tags:           map[string]string{"env": "prod"},
+			},
+		},
+	}
+
+	if err := cfg.ApplyProfile("staging"); err == nil {
+		t.Error("Expected error for an unknown profile")
+	}
+
+	if err := cfg.ApplyProfile("prod"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if cfg.AwsRegion != "eu-west-1" || cfg.WorkerType != "m5.4xlarge" || *cfg.WorkerReplicas != 5 {
+		t.Errorf("Profile values not applied: %+v", cfg)
+	}
+	if cfg.InstanceType != "m5.xlarge" {
+		t.Errorf("Values not set by the profile should be kept, got instance type %s", cfg.InstanceType)
+	}
+	if cfg.Tags["team"] != "qe" || cfg.Tags["env"] != "prod" {
+		t.Errorf("Expected tags to be merged, got %v", cfg.Tags)
+	}
+}
+
+func TestPartition(t *testing.T) {
+	tests := []struct {
+		region    string
+		partition string
+		expected  string
+	}{
+		{"us-east-2", "", "aws"},
+		{"us-gov-west-1", "", "aws-us-gov"},
+		{"cn-northwest-1", "", "aws-cn"},
+		{"us-isob-east-1", "", "aws-iso-b"},
+		{"us-iso-east-1", "", "aws-iso"},
+		{"", "", "aws"},
+		{"xx-custom-1", "aws-custom", "aws-custom"},
+	}
+	for _, tt := range tests {
+		cfg := &Config{AwsRegion: tt.region, AwsPartition: tt.partition}
+		if got := cfg.Partition(); got != tt.expected {
+			t.Errorf("Partition() of %q/%q = %q, expected %q", tt.region, tt.partition, got, tt.expected)
+		}
+	}
+}
+
+func TestInstanceTypeArchitecture(t *testing.T) {
+	for instanceType, expected := range map[string]string{
+		"m5.4xlarge": "amd64", "m6i.2xlarge": "amd64", "g5.xlarge": "amd64", "g4dn.xlarge": "amd64", "m7i-flex.large": "amd64",
+		"m6g.4xlarge": "arm64", "c7gn.large": "arm64", "r8gd.xlarge": "arm64", "im4gn.large": "arm64", "g5g.xlarge": "arm64", "a1.large": "arm64",
+	} {
+		if got := InstanceTypeArchitecture(instanceType); got != expected {
+			t.Errorf("InstanceTypeArchitecture(%q) = %q, expected %q", instanceType, got, expected)
+		}
+	}
+}
+
+func TestDefaultInstanceTypeFollowsRelease(t *testing.T) {
+	cfg := &Config{Version: "4.15.0", Architecture: "aarch64"}
+	cfg.SetDefaults()
+	if cfg.InstanceType != DefaultArm64InstanceType {
+		t.Errorf("Expected %s for an arm64 release, got %s", DefaultArm64InstanceType, cfg.InstanceType)
+	}
+
+	// The release is resolved after the defaults are set
+	cfg = &Config{Version: "4.15.0"}
+	cfg.SetDefaults()
+	if cfg.InstanceType != DefaultInstanceType {
+		t.Errorf("Expected %s without architecture, got %s", DefaultInstanceType, cfg.InstanceType)
+	}
+	cfg.ReleaseImage = "quay.io/openshift-release-dev/ocp-release:4.15.0-aarch64"
+	cfg.SetDefaults()
+	if cfg.InstanceType != DefaultArm64InstanceType {
+		t.Errorf("Expected the default to follow the resolved release, got %s", cfg.InstanceType)
+	}
+
+	// A configured instance type is kept
+	cfg = &Config{ReleaseImage: "quay.io/test:4.15.0-aarch64", InstanceType: "c7g.4xlarge"}
+	cfg.SetDefaults()
+	if cfg.InstanceType != "c7g.4xlarge" {
+		t.Errorf("Expected the configured instance type to be kept, got %s", cfg.InstanceType)
+	}
+}
+
+func TestServiceEndpointEnv(t *testing.T) {
+	cfg := &Config{ServiceEndpoints: []ServiceEndpoint{
+		{Name: "s3", URL: "https://s3.example.com"},
+		{Name: "elasticloadbalancing", URL: "https://elb.example.com"},
+	}}
+	expected := []string{
+		"AWS_ENDPOINT_URL_S3=https://s3.example.com",
+		"AWS_ENDPOINT_URL_ELASTIC_LOAD_BALANCING_V2=https://elb.example.com",
+	}
+	if got := cfg.ServiceEndpointEnv(); strings.Join(got, " ") != strings.Join(expected, " ") {
+		t.Errorf("ServiceEndpointEnv() = %v, expected %v", got, expected)
+	}
+}
+
+func TestMergeServiceEndpoints(t *testing.T) {
+	base := []ServiceEndpoint{{Name: "ec2", URL: "https://ec2.example.com"}, {Name: "s3", URL: "https://s3.example.com"}}
+	merged := MergeServiceEndpoints(base, []ServiceEndpoint{{Name: "s3", URL: "https://vpce-s3.example.com"}, {Name: "iam", URL: "https://iam.example.com"}})
+
+	expected := []ServiceEndpoint{
+		{Name: "ec2", URL: "https://ec2.example.com"},
+		{Name: "s3", URL: "https://vpce-s3.example.com"},
+		{Name: "iam", URL: "https://iam.example.com"},
+	}
+	if len(merged) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, merged)
+	}
+	for i := range expected {
+		if merged[i] != expected[i] {
+			t.Errorf("Expected %v, got %v", expected, merged)
+		}
+	}
+	if base[1].URL != "https://s3.example.com" {
+		t.Error("Expected the base endpoints to be left unchanged")
+	}
+}
+
+func TestApplyNameSuffix(t *testing.T) {
+	cfg := &Config{ClusterName: "ci", NameSuffix: NameSuffixRandom}
+	generated, err := cfg.ApplyNameSuffix()
+	if err != nil || !generated {
+		t.Fatalf("Expected a suffix to be generated, got %v, %v", generated, err)
+	}
+	if !regexp.MustCompile(`^ci-[a-z0-9]{5}$`).MatchString(cfg.ClusterName) {
+		t.Errorf("Unexpected cluster name %q", cfg.ClusterName)
+	}
+	if err := ValidateClusterName(cfg.ClusterName, "", ""); err != nil {
+		t.Errorf("Expected a valid cluster name, got %v", err)
+	}
+
+	// The suffix is appended once
+	name := cfg.ClusterName
+	if generated, _ := cfg.ApplyNameSuffix(); generated || cfg.ClusterName != name {
+		t.Errorf("Expected the suffix to be appended once, got %q", cfg.ClusterName)
+	}
+
+	// Resumed installs are named with their suffix
+	for _, resumed := range []*Config{
+		{ClusterName: "ci-x7k2p", NameSuffix: NameSuffixRandom, StartFromStep: 7},
+		{ClusterName: "ci-x7k2p", NameSuffix: NameSuffixRandom, OnlyStep: 11},
+		{ClusterName: "ci-x7k2p", NameSuffix: NameSuffixRandom, SkipSteps: []string{"create-install-config"}},
+		{ClusterName: "ci-x7k2p", NameSuffix: NameSuffixNone},
+	} {
+		if generated, _ := resumed.ApplyNameSuffix(); generated || resumed.ClusterName != "ci-x7k2p" {
+			t.Errorf("Expected no suffix for %+v", resumed)
+		}
+	}
+
+	if errs := ConsistencyErrors(&Config{NameSuffix: "uuid"}); len(errs) == 0 {
+		t.Error("Expected an error for an unknown name suffix")
+	}
+}
@@ -0,0 +1,280 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrKeyNotSet is returned by GetKey for a valid key missing from the file
+var ErrKeyNotSet = errors.New("key not set")
+
+// GetKey returns the value of a dotted key (e.g. vault.address) of a config
+// file. Scalars are returned as they are, lists and mappings as YAML.
+func GetKey(path, key string) (string, error) {
+	if _, err := keyType(key); err != nil {
+		return "", err
+	}
+	doc, err := readDocument(path)
+	if err != nil {
+		return "", err
+	}
+
+	node := doc.Content[0]
+	for _, segment := range strings.Split(key, ".") {
+		if node = mappingValue(node, segment); node == nil {
+			return "", fmt.Errorf("%s: %w", key, ErrKeyNotSet)
+		}
+	}
+	if node.Kind == yaml.ScalarNode {
+		if node.Tag == "!!null" {
+			return "", fmt.Errorf("%s: %w", key, ErrKeyNotSet)
+		}
+		return node.Value, nil
+	}
+	data, err := encodeNode(node)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(string(data), "\n"), nil
+}
+
+// SetKey sets a dotted key of a config file, creating the file if needed, and
+// returns the report of the updated file. The value is converted to the type
+// of the setting (lists are comma separated), and the file is left unchanged
+// when the new value makes it invalid. Comments and the order of the keys are
+// kept.
+func SetKey(path, key, value string) (*FileReport, error) {
+	t, err := keyType(key)
+	if err != nil {
+		return nil, err
+	}
+	valueNode, err := newValueNode(t, value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value for %s: %w", key, err)
+	}
+
+	before := []byte("{}\n")
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		if before, err = os.ReadFile(path); err != nil {
+			return nil, fmt.Errorf("failed to read config file: %w", err)
+		}
+		mode = info.Mode().Perm()
+	}
+	doc, err := readDocument(path)
+	if err != nil {
+		return nil, err
+	}
+
+	node := doc.Content[0]
+	segments := strings.Split(key, ".")
+	for _, segment := range segments[:len(segments)-1] {
+		next := mappingValue(node, segment)
+		if next == nil || next.Kind != yaml.MappingNode {
+			// Missing, or an empty value such as "vault:"
+			next = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			setMappingValue(node, segment, next)
+		}
+		node = next
+	}
+	setMappingValue(node, segments[len(segments)-1], valueNode)
+
+	after, err := encodeNode(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	// Errors already in the file don't prevent changing other keys
+	report := validateData(after)
+	existing := map[string]bool{}
+	for _, message := range validateData(before).Errors {
+		existing[message] = true
+	}
+	var introduced []string
+	for _, message := range report.Errors {
+		if !existing[message] {
+			introduced = append(introduced, message)
+		}
+	}
+	if len(introduced) > 0 {
+		return report, fmt.Errorf("%s=%s makes the config invalid: %s", key, value, strings.Join(introduced, "; "))
+	}
+
+	if err := os.WriteFile(path, after, mode); err != nil {
+		return nil, fmt.Errorf("failed to write config file: %w", err)
+	}
+	return report, nil
+}
+
+// keyType returns the type of the setting at a dotted key, with the keys of
+// mappings such as tags and profiles in between
+func keyType(key string) (reflect.Type, error) {
+	if key == "" {
+		return nil, fmt.Errorf("empty key")
+	}
+	if hint, ok := runtimeOnlyKeys[key]; ok {
+		return nil, fmt.Errorf("%s is a runtime setting, ignored in config files (use %s)", key, hint)
+	}
+
+	t := reflect.TypeOf(Config{})
+	var path []string
+	for _, segment := range strings.Split(key, ".") {
+		if segment == "" {
+			return nil, fmt.Errorf("invalid key %q", key)
+		}
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		switch t.Kind() {
+		case reflect.Struct:
+			field, ok := yamlField(t, segment)
+			if !ok {
+				message := fmt.Sprintf("unknown key %q", strings.Join(append(path, segment), "."))
+				if suggestion := closestKey(segment, knownKeys(t.Name())); suggestion != "" {
+					message += fmt.Sprintf(" (did you mean %q?)", suggestion)
+				}
+				return nil, errors.New(message)
+			}
+			t = field.Type
+		case reflect.Map:
+			t = t.Elem()
+		default:
+			return nil, fmt.Errorf("%s is not a mapping", strings.Join(path, "."))
+		}
+		path = append(path, segment)
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t, nil
+}
+
+// yamlField returns the field of a struct with the given YAML key
+func yamlField(t reflect.Type, key string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if name := strings.Split(field.Tag.Get("yaml"), ",")[0]; name == key && name != "-" {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// newValueNode converts a command line value to a YAML node of the type of a
+// setting
+func newValueNode(t reflect.Type, value string) (*yaml.Node, error) {
+	scalar := func(tag, value string) *yaml.Node {
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: value}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return scalar("!!str", value), nil
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a boolean (true or false)", value)
+		}
+		return scalar("!!bool", strconv.FormatBool(b)), nil
+	case reflect.Int:
+		i, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not an integer", value)
+		}
+		return scalar("!!int", strconv.Itoa(i)), nil
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a number", value)
+		}
+		return scalar("!!float", strconv.FormatFloat(f, 'f', -1, 64)), nil
+	case reflect.Slice:
+		if t.Elem().Kind() != reflect.String {
+			break
+		}
+		list := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list.Content = append(list.Content, scalar("!!str", item))
+			}
+		}
+		if len(list.Content) == 0 {
+			list.Style = yaml.FlowStyle
+		}
+		return list, nil
+	}
+	return nil, fmt.Errorf("not a single value, edit the config file instead")
+}
+
+// readDocument parses a config file, an empty document when it is missing or
+// empty
+func readDocument(path string) (*yaml.Node, error) {
+	empty := &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return empty, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+	if doc.Kind == 0 {
+		return empty, nil
+	}
+	if len(doc.Content) != 1 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("config file %s is not a mapping", path)
+	}
+	return &doc, nil
+}
+
+// mappingValue returns the value of a key of a mapping node, nil if missing
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// setMappingValue replaces the value of a key of a mapping node, appending the
+// key when missing
+func setMappingValue(node *yaml.Node, key string, value *yaml.Node) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			// Keep the comments of the old value
+			value.LineComment = node.Content[i+1].LineComment
+			node.Content[i+1] = value
+			return
+		}
+	}
+	node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, value)
+}
+
+// encodeNode encodes a YAML node with the indentation of the examples
+func encodeNode(node *yaml.Node) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(node); err != nil {
+		return nil, fmt.Errorf("failed to encode config: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encode config: %w", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,116 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSetKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	os.WriteFile(path, []byte("# Lab account\nawsRegion: us-east-1 # closest\ntags:\n  team: qe\n"), 0600)
+
+	for _, kv := range [][2]string{
+		{"awsRegion", "us-east-2"},
+		{"workerReplicas", "2"},
+		{"privateBucket", "true"},
+		{"zones", "us-east-2a, us-east-2b"},
+		{"tags.owner", "me"},
+		{"baseDomain", "123"},
+		{"vault.address", "https://vault.example.com"},
+		{"profiles.prod.workerType", "m6i.2xlarge"},
+	} {
+		if _, err := SetKey(path, kv[0], kv[1]); err != nil {
+			t.Fatalf("Unexpected error setting %s: %v", kv[0], err)
+		}
+	}
+
+	data, _ := os.ReadFile(path)
+	for _, expected := range []string{"# Lab account", "awsRegion: us-east-2 # closest", "workerReplicas: 2", "privateBucket: true", "  - us-east-2b", "  owner: me", `baseDomain: "123"`, "    workerType: m6i.2xlarge"} {
+		if !strings.Contains(string(data), expected) {
+			t.Errorf("Expected %q in:\n%s", expected, data)
+		}
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0600 {
+		t.Errorf("Expected the mode of the file to be kept, got %s", info.Mode())
+	}
+
+	cfg, err := LoadFromFile(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if cfg.BaseDomain != "123" || *cfg.WorkerReplicas != 2 || len(cfg.Zones) != 2 || cfg.Tags["team"] != "qe" || cfg.Profiles["prod"].WorkerType != "m6i.2xlarge" {
+		t.Errorf("Unexpected config %+v", cfg)
+	}
+}
+
+func TestSetKeyNewFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+
+	if _, err := SetKey(path, "awsRegion", "eu-west-1"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "awsRegion: eu-west-1\n" {
+		t.Errorf("Unexpected content %q", data)
+	}
+}
+
+func TestSetKeyErrors(t *testing.T) {
+	tests := []struct {
+		key, value, expected string
+	}{
+		{"awsRegon", "us-east-1", `did you mean "awsRegion"`},
+		{"clusterName", "test", "runtime setting"},
+		{"workerReplicas", "three", "not an integer"},
+		{"private", "maybe", "not a boolean"},
+		{"computePools", "gpu", "edit the config file"},
+		{"awsRegion.name", "x", "not a mapping"},
+		{"zones", "us-west-2a", "zone us-west-2a is not in region us-east-1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			content := "awsRegion: us-east-1\n"
+			os.WriteFile(path, []byte(content), 0644)
+
+			_, err := SetKey(path, tt.key, tt.value)
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("Expected an error containing %q, got %v", tt.expected, err)
+			}
+			if data, _ := os.ReadFile(path); string(data) != content {
+				t.Errorf("Expected the file to be unchanged, got %q", data)
+			}
+		})
+	}
+}
+
+func TestGetKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	os.WriteFile(path, []byte("awsRegion: us-east-1\nworkerReplicas: 0\ntags:\n  team: qe\nvault:\n"), 0644)
+
+	tests := []struct {
+		key, expected string
+	}{
+		{"awsRegion", "us-east-1"},
+		{"workerReplicas", "0"},
+		{"tags.team", "qe"},
+		{"tags", "team: qe"},
+	}
+	for _, tt := range tests {
+		if value, err := GetKey(path, tt.key); err != nil || value != tt.expected {
+			t.Errorf("%s: expected %q, got %q (%v)", tt.key, tt.expected, value, err)
+		}
+	}
+
+	for _, key := range []string{"baseDomain", "vault", "vault.address", "tags.owner"} {
+		if _, err := GetKey(path, key); !errors.Is(err, ErrKeyNotSet) {
+			t.Errorf("%s: expected ErrKeyNotSet, got %v", key, err)
+		}
+	}
+	if _, err := GetKey(path, "awsRegon"); err == nil || errors.Is(err, ErrKeyNotSet) {
+		t.Errorf("Expected an unknown key error, got %v", err)
+	}
+}
@@ -0,0 +1,78 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ServiceEndpoint is a custom AWS service endpoint, as listed in the
+// platform.aws.serviceEndpoints of install-config.yaml
+type ServiceEndpoint struct {
+	Name string `yaml:"name"` // AWS service name (e.g. ec2, iam, s3)
+	URL  string `yaml:"url"`
+}
+
+var servicePattern = regexp.MustCompile(`^[a-z0-9-]+$`)
+
+// MergeServiceEndpoints returns the endpoints of base with the ones of
+// overrides: an override replaces the endpoint of the same service, the
+// others are appended
+func MergeServiceEndpoints(base, overrides []ServiceEndpoint) []ServiceEndpoint {
+	merged := append([]ServiceEndpoint{}, base...)
+	for _, override := range overrides {
+		found := false
+		for i := range merged {
+			if merged[i].Name == override.Name {
+				merged[i].URL, found = override.URL, true
+			}
+		}
+		if !found {
+			merged = append(merged, override)
+		}
+	}
+	return merged
+}
+
+// serviceEndpointErrors checks the service names and URLs of the endpoints
+func serviceEndpointErrors(endpoints []ServiceEndpoint) []error {
+	var errs []error
+	seen := map[string]bool{}
+	for _, endpoint := range endpoints {
+		if !servicePattern.MatchString(endpoint.Name) {
+			errs = append(errs, fmt.Errorf("invalid service %q in serviceEndpoints (e.g. ec2, iam, s3)", endpoint.Name))
+		}
+		if seen[endpoint.Name] {
+			errs = append(errs, fmt.Errorf("service %s is listed more than once in serviceEndpoints", endpoint.Name))
+		}
+		seen[endpoint.Name] = true
+		// The installer only accepts https endpoints
+		if !strings.HasPrefix(endpoint.URL, "https://") {
+			errs = append(errs, fmt.Errorf("the serviceEndpoints URL of %s must be an https URL", endpoint.Name))
+		}
+	}
+	return errs
+}
+
+// serviceEndpointIDs maps the install-config service names whose AWS SDK
+// service ID differs from the name
+var serviceEndpointIDs = map[string]string{
+	"elasticloadbalancing": "ELASTIC_LOAD_BALANCING_V2",
+	"tagging":              "RESOURCE_GROUPS_TAGGING_API",
+	"servicequotas":        "SERVICE_QUOTAS",
+}
+
+// ServiceEndpointEnv returns the AWS_ENDPOINT_URL_<SERVICE> environment
+// variables directing the aws CLI and the AWS SDKs (used by ccoctl) to the
+// custom service endpoints
+func (c *Config) ServiceEndpointEnv() []string {
+	env := make([]string, 0, len(c.ServiceEndpoints))
+	for _, endpoint := range c.ServiceEndpoints {
+		id, ok := serviceEndpointIDs[endpoint.Name]
+		if !ok {
+			id = strings.ToUpper(strings.ReplaceAll(endpoint.Name, "-", "_"))
+		}
+		env = append(env, fmt.Sprintf("AWS_ENDPOINT_URL_%s=%s", id, endpoint.URL))
+	}
+	return env
+}
@@ -0,0 +1,86 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// DefaultPartition is the AWS commercial partition
+const DefaultPartition = "aws"
+
+var (
+	regionPattern    = regexp.MustCompile(`^[a-z]{2}(-[a-z]+)+-\d+$`)
+	partitionPattern = regexp.MustCompile(`^aws(-[a-z]+)*$`)
+)
+
+// regionPartitions maps region prefixes to the partition they belong to,
+// longest prefixes first
+var regionPartitions = []struct {
+	prefix    string
+	partition string
+}{
+	{"us-isob-", "aws-iso-b"},
+	{"us-iso-", "aws-iso"},
+	{"eu-isoe-", "aws-iso-e"},
+	{"us-isof-", "aws-iso-f"},
+	{"us-gov-", "aws-us-gov"},
+	{"cn-", "aws-cn"},
+}
+
+// RegionPartition returns the AWS partition of a region (aws for the
+// commercial regions and for unknown ones)
+func RegionPartition(region string) string {
+	for _, known := range regionPartitions {
+		if strings.HasPrefix(region, known.prefix) {
+			return known.partition
+		}
+	}
+	return DefaultPartition
+}
+
+// Partition returns the AWS partition of the installation: the configured one,
+// or the one of the region
+func (c *Config) Partition() string {
+	if c.AwsPartition != "" {
+		return c.AwsPartition
+	}
+	return RegionPartition(c.AwsRegion)
+}
+
+// CustomPartition reports whether the configured partition is not the one of
+// the region, which ccoctl derives the ARNs it writes from
+func (c *Config) CustomPartition() bool {
+	return c.Partition() != RegionPartition(c.AwsRegion)
+}
+
+// ValidateRegion checks the format of an AWS region
+func ValidateRegion(region string) error {
+	if !regionPattern.MatchString(region) {
+		return fmt.Errorf("invalid AWS region %q (e.g. us-east-2, us-gov-west-1)", region)
+	}
+	return nil
+}
+
+// partitionErrors checks the region and partition
+func partitionErrors(cfg *Config) []error {
+	var errs []error
+	if cfg.AwsRegion != "" {
+		if err := ValidateRegion(cfg.AwsRegion); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if cfg.AwsPartition != "" {
+		if !partitionPattern.MatchString(cfg.AwsPartition) {
+			errs = append(errs, fmt.Errorf("invalid AWS partition %q (e.g. aws, aws-us-gov, aws-cn)", cfg.AwsPartition))
+		} else if partition := RegionPartition(cfg.AwsRegion); partition != DefaultPartition && partition != cfg.AwsPartition {
+			errs = append(errs, fmt.Errorf("region %s is in the %s partition, not %s", cfg.AwsRegion, partition, cfg.AwsPartition))
+		}
+	}
+	// ccoctl serves the OIDC documents of a private bucket through CloudFront,
+	// which is only available in the commercial partition
+	if cfg.PrivateBucket && cfg.Partition() != DefaultPartition {
+		errs = append(errs, fmt.Errorf("privateBucket is not supported in the %s partition", cfg.Partition()))
+	}
+	return errs
+}
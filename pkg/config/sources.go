@@ -0,0 +1,180 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Value sources reported by Explain
+const (
+	SourceDefault = "default"
+	SourceEnv     = "env"
+	SourceFlag    = "flag"
+	SourcePrompt  = "prompt" // Answered in install --interactive
+)
+
+// hiddenKeys are runtime-only fields that are not user settings
+var hiddenKeys = map[string]bool{
+	"releaseDigest":      true,
+	"useInteractiveMode": true,
+	"generateSSHKey":     true,
+	"sources":            true,
+	"profiles":           true,
+}
+
+// sensitiveKeys are masked by Explain
+var sensitiveKeys = map[string]bool{
+	"ocmToken":   true,
+	"webhookUrl": true, // Webhook URLs embed their credentials
+}
+
+// ExplainedValue is an effective configuration value and where it comes from
+type ExplainedValue struct {
+	Key    string
+	Value  string
+	Source string
+}
+
+// fieldKey returns the name of a Config field as written in the config file,
+// or as a lowerCamelCase name for runtime-only fields
+func fieldKey(field reflect.StructField) string {
+	if name := strings.Split(field.Tag.Get("yaml"), ",")[0]; name != "" && name != "-" {
+		return name
+	}
+	runes := []rune(field.Name)
+	for i := 0; i < len(runes) && unicode.IsUpper(runes[i]); i++ {
+		// Lowercase the leading acronym too (OCMToken -> ocmToken)
+		if i > 0 && i+1 < len(runes) && unicode.IsLower(runes[i+1]) {
+			break
+		}
+		runes[i] = unicode.ToLower(runes[i])
+	}
+	return string(runes)
+}
+
+// MergeFrom merges another config like Merge, recording source as the origin
+// of every value it sets. Values the other config already has a source for
+// (e.g. set by a profile) keep it.
+func (c *Config) MergeFrom(other *Config, source string) {
+	value := reflect.ValueOf(other).Elem()
+	for i := 0; i < value.NumField(); i++ {
+		key := fieldKey(value.Type().Field(i))
+		if hiddenKeys[key] || value.Field(i).IsZero() {
+			continue
+		}
+		if origin, ok := other.Sources[key]; ok {
+			c.setSource(key, origin)
+		} else {
+			c.setSource(key, source)
+		}
+	}
+	c.Merge(other)
+}
+
+func (c *Config) setSource(key, source string) {
+	if c.Sources == nil {
+		c.Sources = map[string]string{}
+	}
+	c.Sources[key] = source
+}
+
+// Explain returns every setting with its effective value and source
+func (c *Config) Explain() []ExplainedValue {
+	var values []ExplainedValue
+	value := reflect.ValueOf(c).Elem()
+	for i := 0; i < value.NumField(); i++ {
+		key := fieldKey(value.Type().Field(i))
+		if hiddenKeys[key] {
+			continue
+		}
+
+		explained := ExplainedValue{Key: key, Value: formatValue(value.Field(i)), Source: c.Sources[key]}
+		if value.Field(i).IsZero() && value.Field(i).Kind() == reflect.Bool {
+			explained.Source = SourceDefault
+		} else if value.Field(i).IsZero() {
+			explained.Value, explained.Source = "(unset)", ""
+		} else if sensitiveKeys[key] {
+			explained.Value = "********"
+		}
+		values = append(values, explained)
+	}
+	return values
+}
+
+// formatValue renders a config value on a single line
+func formatValue(value reflect.Value) string {
+	switch value.Kind() {
+	case reflect.Ptr:
+		if value.IsNil() {
+			return ""
+		}
+		return formatValue(value.Elem())
+	case reflect.Slice:
+		items := make([]string, value.Len())
+		for i := range items {
+			items[i] = formatValue(value.Index(i))
+		}
+		return strings.Join(items, ",")
+	case reflect.Map:
+		var items []string
+		for _, key := range value.MapKeys() {
+			items = append(items, fmt.Sprintf("%v=%s", key.Interface(), formatValue(value.MapIndex(key))))
+		}
+		sort.Strings(items)
+		return strings.Join(items, ",")
+	case reflect.Struct:
+		var items []string
+		for i := 0; i < value.NumField(); i++ {
+			if value.Field(i).IsZero() {
+				continue
+			}
+			key := fieldKey(value.Type().Field(i))
+			if sensitiveKeys[key] {
+				items = append(items, key+"=********")
+			} else {
+				items = append(items, fmt.Sprintf("%s=%s", key, formatValue(value.Field(i))))
+			}
+		}
+		return strings.Join(items, " ")
+	default:
+		return fmt.Sprintf("%v", value.Interface())
+	}
+}
+
+// sourceRank orders the sources by precedence: defaults, environment, config
+// file (and its profiles), then flags and prompts
+func sourceRank(source string) int {
+	switch source {
+	case "", SourceDefault:
+		return 0
+	case SourceEnv:
+		return 1
+	case SourceFlag, SourcePrompt:
+		return 3
+	}
+	return 2
+}
+
+// ResolvesRelease reports whether the release image is to be resolved from
+// the version and/or channel: they are set, and the release image isn't set
+// with a higher precedence (e.g. by --release-image over a version of the
+// config file)
+func (c *Config) ResolvesRelease() bool {
+	if c.Version == "" && c.Channel == "" {
+		return false
+	}
+	if c.ReleaseImage == "" {
+		return true
+	}
+	rank := 0
+	if c.Version != "" {
+		rank = sourceRank(c.Sources["version"])
+	}
+	if c.Channel != "" {
+		rank = max(rank, sourceRank(c.Sources["channel"]))
+	}
+	return sourceRank(c.Sources["releaseImage"]) <= rank
+}
@@ -0,0 +1,71 @@
+package config
+
+import "testing"
+
+func TestExplain(t *testing.T) {
+	cfg := &Config{}
+	cfg.MergeFrom(&Config{AwsProfile: "env-profile", AwsRegion: "us-east-1"}, SourceEnv)
+
+	fileCfg := &Config{
+		AwsRegion: "us-east-2",
+		Profiles:  map[string]Config{"prod": {WorkerType: "m5.4xlarge"}},
+	}
+	if err := fileCfg.ApplyProfile("prod"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	fileCfg.Notifications.WebhookURL = "https://hooks.slack.com/services/T000/B000/XXXX"
+	cfg.MergeFrom(fileCfg, "file config.yaml")
+	cfg.MergeFrom(&Config{AwsProfile: "flag-profile", OCMToken: "secret"}, SourceFlag)
+	cfg.SetDefaults()
+
+	expected := map[string]ExplainedValue{
+		"awsProfile":     {"awsProfile", "flag-profile", SourceFlag},
+		"awsRegion":      {"awsRegion", "us-east-2", "file config.yaml"},
+		"workerType":     {"workerType", "m5.4xlarge", "profile prod"},
+		"instanceType":   {"instanceType", "m5.4xlarge", SourceDefault},
+		"ocmToken":       {"ocmToken", "********", SourceFlag},
+		"privateBucket":  {"privateBucket", "false", SourceDefault},
+		"baseDomain":     {"baseDomain", "(unset)", ""},
+		"workerReplicas": {"workerReplicas", "(unset)", ""},
+		"notifications":  {"notifications", "webhookUrl=********", "file config.yaml"},
+	}
+
+	found := 0
+	for _, value := range cfg.Explain() {
+		if want, ok := expected[value.Key]; ok {
+			found++
+			if value != want {
+				t.Errorf("Expected %+v, got %+v", want, value)
+			}
+		}
+		if value.Key == "profiles" || value.Key == "sources" {
+			t.Errorf("Unexpected key %s", value.Key)
+		}
+	}
+	if found != len(expected) {
+		t.Errorf("Expected %d keys, found %d", len(expected), found)
+	}
+}
+
+func TestResolvesRelease(t *testing.T) {
+	cfg := &Config{}
+	cfg.MergeFrom(&Config{Version: "4.15.12"}, "file config.yaml")
+	if !cfg.ResolvesRelease() {
+		t.Error("Expected a version to be resolved")
+	}
+
+	cfg.MergeFrom(&Config{ReleaseImage: "quay.io/openshift-release-dev/ocp-release:4.16.0-x86_64"}, SourceEnv)
+	if !cfg.ResolvesRelease() {
+		t.Error("Expected a version of the config file to override a release image of the environment")
+	}
+
+	cfg.MergeFrom(&Config{ReleaseImage: "quay.io/openshift-release-dev/ocp-release:4.16.0-x86_64"}, SourceFlag)
+	if cfg.ResolvesRelease() {
+		t.Error("Expected --release-image to override a version of the config file")
+	}
+
+	cfg.MergeFrom(&Config{Channel: "stable-4.16"}, SourceFlag)
+	if !cfg.ResolvesRelease() {
+		t.Error("Expected --channel to be resolved along with --release-image")
+	}
+}
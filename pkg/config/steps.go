@@ -0,0 +1,74 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// StepNames are the stable names of the installation steps, in execution
+// order. Step N is StepNames[N-1]; names keep working when steps are inserted.
+var StepNames = []string{
+	"extract-credreqs",
+	"extract-openshift-install",
+	"extract-ccoctl",
+	"create-install-config",
+	"set-credentials-mode",
+	"create-manifests",
+	"create-aws-resources",
+	"copy-manifests",
+	"copy-tls",
+	"deploy-cluster",
+	"verify",
+}
+
+// StepName returns the stable name of a step number
+func StepName(num int) string {
+	if num < 1 || num > len(StepNames) {
+		return ""
+	}
+	return StepNames[num-1]
+}
+
+// ParseStep resolves a step name, or a step number for compatibility, to the
+// step number
+func ParseStep(value string) (int, error) {
+	value = strings.TrimSpace(value)
+	for i, name := range StepNames {
+		if value == name {
+			return i + 1, nil
+		}
+	}
+	if num, err := strconv.Atoi(value); err == nil && StepName(num) != "" {
+		return num, nil
+	}
+	return 0, fmt.Errorf("unknown step %q (valid steps: %s)", value, strings.Join(StepNames, ", "))
+}
+
+// StepSelected reports whether a step is within the segment of the flow
+// selected with StartFromStep, StopAfterStep and OnlyStep, and not skipped
+func (c *Config) StepSelected(num int) bool {
+	if c.StepSkipped(num) {
+		return false
+	}
+	if c.OnlyStep > 0 {
+		return num == c.OnlyStep
+	}
+	if c.StartFromStep > 0 && num < c.StartFromStep {
+		return false
+	}
+	if c.StopAfterStep > 0 && num > c.StopAfterStep {
+		return false
+	}
+	return true
+}
+
+// StepSkipped reports whether a step is listed in SkipSteps
+func (c *Config) StepSkipped(num int) bool {
+	for _, step := range c.SkipSteps {
+		if skipped, err := ParseStep(step); err == nil && skipped == num {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,65 @@
+package config
+
+import "testing"
+
+func TestParseStep(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    int
+		wantErr bool
+	}{
+		{"extract-credreqs", 1, false},
+		{"create-aws-resources", 7, false},
+		{"verify", 11, false},
+		{"6", 6, false},
+		{"0", 0, true},
+		{"12", 0, true},
+		{"deploy", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseStep(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseStep(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseStep(%q) = %d, want %d", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestStepSelected(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+		want []int
+	}{
+		{"all steps", Config{}, []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11}},
+		{"start from", Config{StartFromStep: 9}, []int{9, 10, 11}},
+		{"stop after", Config{StopAfterStep: 3}, []int{1, 2, 3}},
+		{"segment", Config{StartFromStep: 6, StopAfterStep: 7}, []int{6, 7}},
+		{"only", Config{OnlyStep: 11}, []int{11}},
+		{"skip", Config{SkipSteps: []string{"create-install-config", "11"}}, []int{1, 2, 3, 5, 6, 7, 8, 9, 10}},
+		{"skip within segment", Config{StartFromStep: 9, SkipSteps: []string{"verify"}}, []int{9, 10}},
+	}
+
+	for _, tt := range tests {
+		var got []int
+		for num := 1; num <= len(StepNames); num++ {
+			if tt.cfg.StepSelected(num) {
+				got = append(got, num)
+			}
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: selected steps %v, want %v", tt.name, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s: selected steps %v, want %v", tt.name, got, tt.want)
+				break
+			}
+		}
+	}
+}
@@ -2,11 +2,75 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
 )
 
+// MaxClusterNameLength is the longest cluster name: it is a DNS label
+const MaxClusterNameLength = 63
+
+// maxClusterDomainLength is the longest <cluster name>.<base domain>: the
+// longest DNS name of the cluster, api-int.<cluster domain>, must fit the 253
+// characters of a DNS name
+const maxClusterDomainLength = 253 - len("api-int.")
+
+// clusterNamePattern matches RFC 1123 DNS labels
+var clusterNamePattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+// clusterNameInvalidChars matches the runs of characters a DNS label can't hold
+var clusterNameInvalidChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// ValidateClusterName checks that a cluster name is accepted by openshift-install
+// (an RFC 1123 DNS label, whose cluster domain is a valid DNS name with the
+// base domain, when known) and, when ccoctl names the AWS resources after the
+// cluster (no resource prefix), that it fits the IAM role names. The error
+// suggests a valid name.
+func ValidateClusterName(name, baseDomain, resourcePrefix string) error {
+	maxLength := MaxClusterNameLength
+	reason := fmt.Sprintf("longer than %d characters", MaxClusterNameLength)
+	if baseDomain != "" && maxClusterDomainLength-len(baseDomain)-1 < maxLength {
+		maxLength = maxClusterDomainLength - len(baseDomain) - 1
+		reason = fmt.Sprintf("too long for the base domain %s (%d characters at most)", baseDomain, maxLength)
+	}
+	// The suggested name also fits the names of the ccoctl resources
+	suggestionLength := maxLength
+	if resourcePrefix == "" && MaxResourcePrefixLength < suggestionLength {
+		suggestionLength = MaxResourcePrefixLength
+	}
+
+	if !clusterNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid cluster name %q: only lowercase letters, digits and hyphens are allowed, starting and ending with a letter or digit%s",
+			name, clusterNameSuggestion(name, suggestionLength))
+	}
+	if len(name) > maxLength {
+		return fmt.Errorf("invalid cluster name %q: %s%s", name, reason, clusterNameSuggestion(name, suggestionLength))
+	}
+	if resourcePrefix == "" && len(name) > MaxResourcePrefixLength {
+		return fmt.Errorf("cluster name %q is longer than %d characters, the longest name of the IAM roles, OIDC provider and S3 bucket created by ccoctl: use a shorter name%s, or name them with --resource-prefix",
+			name, MaxResourcePrefixLength, clusterNameSuggestion(name, suggestionLength))
+	}
+	return nil
+}
+
+// clusterNameSuggestion returns a valid cluster name close to name, as a
+// suggestion to append to an error, "" if there is none
+func clusterNameSuggestion(name string, maxLength int) string {
+	suggestion := clusterNameInvalidChars.ReplaceAllString(strings.ToLower(name), "-")
+	if len(suggestion) > maxLength {
+		suggestion = suggestion[:maxLength]
+	}
+	suggestion = strings.Trim(suggestion, "-")
+	if suggestion == "" || suggestion == name {
+		return ""
+	}
+	return fmt.Sprintf(" (e.g. %s)", suggestion)
+}
+
 // ValidatePullSecret checks if the pull secret file exists and is valid JSON
 func ValidatePullSecret(path string) error {
 	if path == "" {
@@ -18,6 +82,11 @@ func ValidatePullSecret(path string) error {
 		return fmt.Errorf("failed to read pull secret: %w", err)
 	}
 
+	return ValidatePullSecretContent(data)
+}
+
+// ValidatePullSecretContent checks that a pull secret is valid JSON
+func ValidatePullSecretContent(data []byte) error {
 	var js interface{}
 	if err := json.Unmarshal(data, &js); err != nil {
 		return fmt.Errorf("pull secret is not valid JSON: %w", err)
@@ -26,12 +95,56 @@ func ValidatePullSecret(path string) error {
 	return nil
 }
 
+// MinOCVersion is the oldest supported oc client (major.minor)
+const MinOCVersion = "4.10"
+
+// ErrOCUnusable is returned by CheckPrerequisites when oc is missing or older
+// than MinOCVersion
+var ErrOCUnusable = errors.New("no usable oc client")
+
 // CheckPrerequisites validates that required tools are available
 func CheckPrerequisites() error {
 	// Check for oc command
 	if _, err := exec.LookPath("oc"); err != nil {
-		return fmt.Errorf("'oc' command not found in PATH. Please install OpenShift CLI")
+		return fmt.Errorf("%w: 'oc' command not found in PATH. Please install OpenShift CLI", ErrOCUnusable)
+	}
+
+	// Clients built from source may not report a release version: they are accepted
+	output, err := exec.Command("oc", "version", "--client", "--output=json").Output()
+	if err == nil {
+		if version := OCClientVersion(output); version != "" && VersionOlder(version, MinOCVersion) {
+			return fmt.Errorf("%w: oc %s is older than %s", ErrOCUnusable, version, MinOCVersion)
+		}
 	}
 
 	return nil
 }
+
+// OCClientVersion returns the release version of the oc client from the output
+// of 'oc version --client --output=json', "" if unknown
+func OCClientVersion(output []byte) string {
+	var version struct {
+		ReleaseClientVersion string `json:"releaseClientVersion"`
+	}
+	if err := json.Unmarshal(output, &version); err != nil {
+		return ""
+	}
+	return version.ReleaseClientVersion
+}
+
+// VersionOlder reports whether the major.minor of version is older than the
+// one of min (e.g. "4.9.12" is older than "4.10")
+func VersionOlder(version, min string) bool {
+	parse := func(v string) (int, int) {
+		parts := strings.SplitN(strings.TrimPrefix(v, "v"), ".", 3)
+		if len(parts) < 2 {
+			return 0, 0
+		}
+		major, _ := strconv.Atoi(parts[0])
+		minor, _ := strconv.Atoi(parts[1])
+		return major, minor
+	}
+	major, minor := parse(version)
+	minMajor, minMinor := parse(min)
+	return major < minMajor || (major == minMajor && minor < minMinor)
+}
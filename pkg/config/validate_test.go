@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -60,3 +61,53 @@ func TestValidatePullSecretEmptyPath(t *testing.T) {
 		t.Error("Expected error for empty path")
 	}
 }
+
+func TestOCClientVersion(t *testing.T) {
+	output := []byte(`{"clientVersion": {"major": "", "minor": "", "gitVersion": "4.9.0-202111151318.p0.g96e95ce.assembly.stream-96e95ce"}, "releaseClientVersion": "4.9.12"}`)
+	version := OCClientVersion(output)
+	if version != "4.9.12" || !VersionOlder(version, MinOCVersion) {
+		t.Errorf("Expected oc 4.9.12 to be too old, got %q", version)
+	}
+	for _, version := range []string{"4.10.0", "4.15.3", "5.0.0"} {
+		if VersionOlder(version, MinOCVersion) {
+			t.Errorf("Expected oc %s to be supported", version)
+		}
+	}
+	if version := OCClientVersion([]byte(`{"clientVersion": {"gitVersion": "v4.2.0-alpha.0"}}`)); version != "" {
+		t.Errorf("Expected no release version for a client built from source, got %q", version)
+	}
+}
+
+func TestValidateClusterName(t *testing.T) {
+	long := strings.Repeat("a", 40)
+	tests := []struct {
+		name           string
+		clusterName    string
+		baseDomain     string
+		resourcePrefix string
+		wantErr        string // Substring of the error, "" if valid
+	}{
+		{"valid", "my-cluster", "example.com", "", ""},
+		{"uppercase and underscore", "My_Cluster", "", "", `only lowercase letters, digits and hyphens are allowed, starting and ending with a letter or digit (e.g. my-cluster)`},
+		{"leading hyphen", "-cluster", "", "", "(e.g. cluster)"},
+		{"dots", "team.cluster", "", "", "(e.g. team-cluster)"},
+		{"too long", strings.Repeat("a", 64), "", "team-a", "longer than 63 characters (e.g. " + strings.Repeat("a", 63) + ")"},
+		{"too long for the base domain", long, strings.Repeat("b", 210) + ".com", "team-a", "too long for the base domain"},
+		{"too long for the ccoctl resources", long, "example.com", "", "use a shorter name (e.g. " + strings.Repeat("a", 32) + "), or name them with --resource-prefix"},
+		{"long with a resource prefix", long, "example.com", "team-a", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateClusterName(tt.clusterName, tt.baseDomain, tt.resourcePrefix)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Expected %q to be valid, got %v", tt.clusterName, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected an error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
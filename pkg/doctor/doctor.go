@@ -0,0 +1,335 @@
+// Package doctor diagnoses the environment the wrapper runs in: the tools it
+// needs, the AWS credentials, the pull secret, disk space and network access.
+package doctor
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/clobrano/openshift-sts-wrapper/pkg/config"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/util"
+)
+
+// Outcomes of a check
+const (
+	StatusPassed  = "passed"
+	StatusWarning = "warning"
+	StatusFailed  = "failed"
+)
+
+// Disk space thresholds of the artifacts directory: a release takes about
+// 1 GiB of binaries, and clusters keep their logs and manifests
+const (
+	minFreeDisk  = 2 << 30
+	warnFreeDisk = 10 << 30
+)
+
+// Result is the outcome of a check, with how to fix it when it didn't pass
+type Result struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message"`
+	Hint    string `json:"hint,omitempty"`
+}
+
+// Registries the installation pulls from
+var Registries = []string{"quay.io", "registry.redhat.io"}
+
+// Env is the environment under diagnosis
+type Env struct {
+	Executor       util.CommandExecutor
+	AwsProfile     string
+	PullSecretPath string
+	ArtifactsDir   string
+	// RegistryURL returns the base URL of a registry (https://<registry> by default)
+	RegistryURL func(registry string) string
+	HTTPClient  *http.Client
+}
+
+// NewEnv returns the environment of a configuration
+func NewEnv(cfg *config.Config, executor util.CommandExecutor) *Env {
+	return &Env{
+		Executor:       executor,
+		AwsProfile:     cfg.AwsProfile,
+		PullSecretPath: cfg.PullSecretPath,
+		ArtifactsDir:   "artifacts",
+	}
+}
+
+// Run runs every check, in order
+func (e *Env) Run() []Result {
+	results := []Result{
+		e.CheckOC(),
+		e.CheckAWSCLI(),
+		e.CheckAWSCredentials(),
+		e.CheckDiskSpace(),
+	}
+	for _, registry := range Registries {
+		results = append(results, e.CheckRegistry(registry))
+	}
+	return results
+}
+
+// Failed returns the names of the checks that failed
+func Failed(results []Result) []string {
+	var failed []string
+	for _, result := range results {
+		if result.Status == StatusFailed {
+			failed = append(failed, result.Name)
+		}
+	}
+	return failed
+}
+
+// CheckOC checks that oc is installed and recent enough
+func (e *Env) CheckOC() Result {
+	result := Result{Name: "oc"}
+	if _, err := exec.LookPath("oc"); err != nil {
+		result.Status, result.Message = StatusFailed, "oc not found in PATH"
+		result.Hint = "Install the OpenShift CLI from https://mirror.openshift.com/pub/openshift-v4/clients/ocp/stable/, or let install download it with --download-oc"
+		return result
+	}
+
+	output, err := e.Executor.Execute("oc", "version", "--client", "--output=json")
+	if err != nil {
+		result.Status, result.Message = StatusFailed, fmt.Sprintf("oc version failed: %v", err)
+		result.Hint = "Reinstall the OpenShift CLI"
+		return result
+	}
+	version := config.OCClientVersion([]byte(output))
+	switch {
+	case version == "":
+		result.Status, result.Message = StatusWarning, "oc does not report its release version (built from source?)"
+	case config.VersionOlder(version, config.MinOCVersion):
+		result.Status, result.Message = StatusFailed, fmt.Sprintf("oc %s is older than %s", version, config.MinOCVersion)
+		result.Hint = "Update the OpenShift CLI, or let install download the one of the release with --download-oc"
+	default:
+		result.Status, result.Message = StatusPassed, "oc "+version
+	}
+	return result
+}
+
+// awsVersionPattern matches the version of the aws CLI in 'aws --version'
+var awsVersionPattern = regexp.MustCompile(`aws-cli/(\d+)\.(\S+)`)
+
+// CheckAWSCLI checks that the aws CLI v2 is installed
+func (e *Env) CheckAWSCLI() Result {
+	result := Result{Name: "aws CLI"}
+	hint := "Install the AWS CLI v2: https://docs.aws.amazon.com/cli/latest/userguide/getting-started-install.html"
+	if _, err := exec.LookPath("aws"); err != nil {
+		result.Status, result.Message, result.Hint = StatusFailed, "aws not found in PATH", hint
+		return result
+	}
+
+	output, err := e.Executor.Execute("aws", "--version")
+	match := awsVersionPattern.FindStringSubmatch(output)
+	if err != nil || match == nil {
+		result.Status, result.Message, result.Hint = StatusFailed, fmt.Sprintf("could not get the aws CLI version: %s", strings.TrimSpace(output)), hint
+		return result
+	}
+	if major, _ := strconv.Atoi(match[1]); major < 2 {
+		result.Status, result.Message, result.Hint = StatusFailed, fmt.Sprintf("aws CLI %s.%s is not supported", match[1], match[2]), hint
+		return result
+	}
+	result.Status, result.Message = StatusPassed, fmt.Sprintf("aws CLI %s.%s", match[1], match[2])
+	return result
+}
+
+// CheckAWSCredentials checks that the credentials of the profile are valid,
+// and reports the identity they belong to
+func (e *Env) CheckAWSCredentials() Result {
+	result := Result{Name: "AWS credentials"}
+	output, err := util.RunAWSCLI(e.Executor, e.AwsProfile, "", "sts", "get-caller-identity")
+	if err != nil {
+		result.Status, result.Message = StatusFailed, fmt.Sprintf("profile '%s': %v", e.AwsProfile, err)
+		if util.IsSSOProfile(e.AwsProfile) {
+			result.Hint = fmt.Sprintf("Start a new SSO session with: %s", strings.Join(util.SSOLoginCommand(e.AwsProfile), " "))
+		} else {
+			result.Hint = fmt.Sprintf("Configure the credentials of the profile with: aws configure --profile %s", e.AwsProfile)
+		}
+		return result
+	}
+
+	var identity struct {
+		Account string `json:"Account"`
+		Arn     string `json:"Arn"`
+	}
+	json.Unmarshal([]byte(output), &identity)
+	result.Status, result.Message = StatusPassed, fmt.Sprintf("profile '%s' is %s (account %s)", e.AwsProfile, identity.Arn, identity.Account)
+	return result
+}
+
+// CheckDiskSpace checks the free space where the artifacts are written
+func (e *Env) CheckDiskSpace() Result {
+	result := Result{Name: "Disk space"}
+	// The artifacts directory is only created by the first installation
+	dir := e.ArtifactsDir
+	if !util.DirExists(dir) {
+		dir = "."
+	}
+
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(dir, &stat); err != nil {
+		result.Status, result.Message = StatusWarning, fmt.Sprintf("could not get the free space of %s: %v", dir, err)
+		return result
+	}
+	free := stat.Bavail * uint64(stat.Bsize)
+	result.Message = fmt.Sprintf("%.1f GiB free in %s", float64(free)/(1<<30), dir)
+	switch {
+	case free < minFreeDisk:
+		result.Status = StatusFailed
+		result.Hint = "Free some space, e.g. remove the artifacts of old releases with: openshift-sts-wrapper artifacts prune"
+	case free < warnFreeDisk:
+		result.Status = StatusWarning
+		result.Hint = "Each release needs about 1 GiB of binaries: consider freeing some space"
+	default:
+		result.Status = StatusPassed
+	}
+	return result
+}
+
+// CheckRegistry checks that a registry can be reached, and that the pull
+// secret holds valid credentials for it
+func (e *Env) CheckRegistry(registry string) Result {
+	result := Result{Name: "Registry " + registry}
+	base := "https://" + registry
+	if e.RegistryURL != nil {
+		base = e.RegistryURL(registry)
+	}
+	client := e.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 15 * time.Second}
+	}
+
+	// The registry answers 401 with the token endpoint to authenticate with
+	resp, err := client.Get(base + "/v2/")
+	if err != nil {
+		result.Status, result.Message = StatusFailed, fmt.Sprintf("%s is not reachable: %v", registry, err)
+		result.Hint = fmt.Sprintf("Check the network access to %s (proxy, firewall, DNS)", registry)
+		return result
+	}
+	resp.Body.Close()
+	realm, service := bearerChallenge(resp.Header.Get("WWW-Authenticate"))
+
+	auth, err := pullSecretAuth(e.PullSecretPath, registry)
+	if err != nil {
+		result.Status, result.Message = StatusFailed, fmt.Sprintf("%s is reachable, but %v", registry, err)
+		result.Hint = "Download a pull secret from https://console.redhat.com/openshift/install/pull-secret"
+		return result
+	}
+	if realm == "" {
+		result.Status, result.Message = StatusPassed, fmt.Sprintf("%s is reachable", registry)
+		return result
+	}
+
+	// The credentials only go to a token endpoint of the registry itself
+	if err := checkRealm(realm, base, registry); err != nil {
+		result.Status, result.Message = StatusWarning, fmt.Sprintf("%s is reachable, but the credentials of the pull secret were not checked: %v", registry, err)
+		return result
+	}
+	query := url.Values{}
+	query.Set("service", service)
+	req, err := http.NewRequest(http.MethodGet, realm+"?"+query.Encode(), nil)
+	if err != nil {
+		result.Status, result.Message = StatusWarning, fmt.Sprintf("%s is reachable, but its token endpoint is invalid: %v", registry, err)
+		return result
+	}
+	req.Header.Set("Authorization", "Basic "+auth)
+	resp, err = client.Do(req)
+	if err != nil {
+		result.Status, result.Message = StatusWarning, fmt.Sprintf("%s is reachable, but its token endpoint is not: %v", registry, err)
+		return result
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		result.Status, result.Message = StatusFailed, fmt.Sprintf("%s rejects the credentials of the pull secret (%s)", registry, resp.Status)
+		result.Hint = "The pull secret may be expired or revoked: download a new one from https://console.redhat.com/openshift/install/pull-secret"
+		return result
+	}
+	result.Status, result.Message = StatusPassed, fmt.Sprintf("%s is reachable and accepts the pull secret", registry)
+	return result
+}
+
+// registryAuthHosts are the token endpoints of the registries that
+// authenticate on another host
+var registryAuthHosts = map[string]string{
+	"docker.io":            "auth.docker.io",
+	"registry-1.docker.io": "auth.docker.io",
+}
+
+// checkRealm checks that the token endpoint a registry (served at base)
+// challenges with can be sent the credentials of the registry: it must use
+// https, on the host of the registry or its known token endpoint
+func checkRealm(realm, base, registry string) error {
+	realmURL, err := url.Parse(realm)
+	if err != nil {
+		return fmt.Errorf("invalid token endpoint %q: %w", realm, err)
+	}
+	if realmURL.Scheme != "https" {
+		return fmt.Errorf("token endpoint %s does not use https", realm)
+	}
+	baseURL, err := url.Parse(base)
+	if err != nil {
+		return err
+	}
+	if realmURL.Host != baseURL.Host && realmURL.Host != registryAuthHosts[registry] {
+		return fmt.Errorf("token endpoint %s is not on %s", realm, registry)
+	}
+	return nil
+}
+
+// challengeParamPattern matches the parameters of a WWW-Authenticate challenge
+var challengeParamPattern = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// bearerChallenge returns the token endpoint and service of a Bearer challenge
+func bearerChallenge(header string) (realm, service string) {
+	if !strings.HasPrefix(strings.ToLower(header), "bearer ") {
+		return "", ""
+	}
+	for _, match := range challengeParamPattern.FindAllStringSubmatch(header, -1) {
+		switch strings.ToLower(match[1]) {
+		case "realm":
+			realm = match[2]
+		case "service":
+			service = match[2]
+		}
+	}
+	return realm, service
+}
+
+// pullSecretAuth returns the base64 encoded credentials of the pull secret for a registry
+func pullSecretAuth(path, registry string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("the pull secret can't be read: %v", err)
+	}
+	if err := config.ValidatePullSecretContent(data); err != nil {
+		return "", err
+	}
+	var pullSecret struct {
+		Auths map[string]struct {
+			Auth string `json:"auth"`
+		} `json:"auths"`
+	}
+	if err := json.Unmarshal(data, &pullSecret); err != nil {
+		return "", fmt.Errorf("the pull secret is invalid: %v", err)
+	}
+	auth := pullSecret.Auths[registry].Auth
+	if auth == "" {
+		return "", fmt.Errorf("the pull secret %s has no credentials for it", path)
+	}
+	if _, err := base64.StdEncoding.DecodeString(auth); err != nil {
+		return "", fmt.Errorf("the credentials for it in the pull secret are not base64 encoded")
+	}
+	return auth, nil
+}
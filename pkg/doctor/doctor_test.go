@@ -0,0 +1,120 @@
+package doctor
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/clobrano/openshift-sts-wrapper/pkg/util"
+)
+
+func TestCheckTools(t *testing.T) {
+	// Fake oc and aws binaries in PATH
+	binDir := t.TempDir()
+	for _, name := range []string{"oc", "aws"} {
+		os.WriteFile(filepath.Join(binDir, name), []byte("#!/bin/sh\n"), 0755)
+	}
+	t.Setenv("PATH", binDir)
+
+	executor := util.NewMockExecutor()
+	env := &Env{Executor: executor, AwsProfile: "default"}
+	executor.SetOutput("oc version --client --output=json", `{"releaseClientVersion": "4.9.12"}`)
+	executor.SetOutput("aws --version", "aws-cli/1.18.69 Python/3.8.10 Linux/5.4.0 botocore/1.17.42")
+
+	if result := env.CheckOC(); result.Status != StatusFailed || result.Hint == "" {
+		t.Errorf("Expected an old oc to fail with a hint, got %+v", result)
+	}
+	if result := env.CheckAWSCLI(); result.Status != StatusFailed || !strings.Contains(result.Message, "1.18.69") {
+		t.Errorf("Expected the aws CLI v1 to fail, got %+v", result)
+	}
+
+	executor.SetOutput("oc version --client --output=json", `{"releaseClientVersion": "4.15.3"}`)
+	executor.SetOutput("aws --version", "aws-cli/2.15.30 Python/3.11.8 Linux/6.5.0 exe/x86_64")
+	if result := env.CheckOC(); result.Status != StatusPassed || result.Message != "oc 4.15.3" {
+		t.Errorf("Expected oc to pass, got %+v", result)
+	}
+	if result := env.CheckAWSCLI(); result.Status != StatusPassed {
+		t.Errorf("Expected the aws CLI to pass, got %+v", result)
+	}
+
+	t.Setenv("PATH", t.TempDir())
+	if result := env.CheckOC(); result.Status != StatusFailed || !strings.Contains(result.Hint, "--download-oc") {
+		t.Errorf("Expected a missing oc to fail, got %+v", result)
+	}
+}
+
+func TestCheckAWSCredentials(t *testing.T) {
+	executor := util.NewMockExecutor()
+	env := &Env{Executor: executor, AwsProfile: "test-profile"}
+	command := "aws sts get-caller-identity --output json --profile test-profile"
+
+	executor.SetOutput(command, `{"UserId": "AIDA", "Account": "123456789012", "Arn": "arn:aws:iam::123456789012:user/installer"}`)
+	if result := env.CheckAWSCredentials(); result.Status != StatusPassed || !strings.Contains(result.Message, "arn:aws:iam::123456789012:user/installer") {
+		t.Errorf("Expected the identity to be reported, got %+v", result)
+	}
+
+	executor.SetError(command, errors.New("ExpiredToken"))
+	if result := env.CheckAWSCredentials(); result.Status != StatusFailed || !strings.Contains(result.Hint, "aws configure --profile test-profile") {
+		t.Errorf("Expected invalid credentials to fail with a hint, got %+v", result)
+	}
+}
+
+func TestCheckRegistry(t *testing.T) {
+	validAuth := base64.StdEncoding.EncodeToString([]byte("user:password"))
+	var server *httptest.Server
+	realm := ""
+	server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/":
+			w.Header().Set("WWW-Authenticate", `Bearer realm="`+realm+`",service="test-registry"`)
+			w.WriteHeader(http.StatusUnauthorized)
+		case "/auth":
+			if r.URL.Query().Get("service") != "test-registry" || r.Header.Get("Authorization") != "Basic "+validAuth {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte(`{"token": "abc"}`))
+		}
+	}))
+	defer server.Close()
+	realm = server.URL + "/auth"
+
+	pullSecretPath := filepath.Join(t.TempDir(), "pull-secret.json")
+	env := &Env{
+		PullSecretPath: pullSecretPath,
+		RegistryURL:    func(string) string { return server.URL },
+		HTTPClient:     server.Client(),
+	}
+
+	os.WriteFile(pullSecretPath, []byte(`{"auths": {"quay.io": {"auth": "`+validAuth+`"}}}`), 0600)
+	if result := env.CheckRegistry("quay.io"); result.Status != StatusPassed {
+		t.Errorf("Expected the registry to accept the pull secret, got %+v", result)
+	}
+	if result := env.CheckRegistry("registry.redhat.io"); result.Status != StatusFailed || !strings.Contains(result.Message, "no credentials") {
+		t.Errorf("Expected missing credentials to fail, got %+v", result)
+	}
+
+	os.WriteFile(pullSecretPath, []byte(`{"auths": {"quay.io": {"auth": "`+base64.StdEncoding.EncodeToString([]byte("user:revoked"))+`"}}}`), 0600)
+	if result := env.CheckRegistry("quay.io"); result.Status != StatusFailed || !strings.Contains(result.Message, "rejects") {
+		t.Errorf("Expected rejected credentials to fail, got %+v", result)
+	}
+
+	// The credentials are not sent to a token endpoint on another host, or
+	// without https
+	for _, other := range []string{"https://attacker.example.com/auth", strings.Replace(server.URL, "https://", "http://", 1) + "/auth"} {
+		realm = other
+		if result := env.CheckRegistry("quay.io"); result.Status != StatusWarning || !strings.Contains(result.Message, "not checked") {
+			t.Errorf("Expected the credentials not to be sent to %s, got %+v", other, result)
+		}
+	}
+
+	env.RegistryURL = func(string) string { return "http://127.0.0.1:1" }
+	if result := env.CheckRegistry("quay.io"); result.Status != StatusFailed || !strings.Contains(result.Message, "not reachable") {
+		t.Errorf("Expected an unreachable registry to fail, got %+v", result)
+	}
+}
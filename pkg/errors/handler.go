@@ -1,47 +1,134 @@
 package errors
 
 import (
+	"encoding/json"
 	"fmt"
 	"strings"
+	"time"
+)
+
+// Step statuses reported by the JSON summary
+const (
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+	StatusSkipped   = "skipped"
 )
 
 type StepError struct {
 	StepName string
 	Error    error
+	Hint     string // How to fix the failure, if it is a common one
+}
+
+// StepResult is the outcome of a step as reported by the JSON summary
+type StepResult struct {
+	ID       string  `json:"id,omitempty"` // Stable step name, if any
+	Name     string  `json:"name"`
+	Status   string  `json:"status"`
+	Duration float64 `json:"durationSeconds"`
+	Error    string  `json:"error,omitempty"`
+	Reason   string  `json:"reason,omitempty"` // Why the step was skipped
+	Hint     string  `json:"hint,omitempty"`   // How to fix the failure, if it is a common one
 }
 
 type Summary struct {
-	Successful []string
-	Failed     []StepError
+	ClusterName string // Cluster the run installed, if any
+	Successful  []string
+	Failed      []StepError
+	Steps       []StepResult
+	Artifacts   map[string]string // Artifact name -> path
+	ConsoleURL  string
+	TraceID     string // OpenTelemetry trace of the run, if exported
 }
 
 func NewSummary() *Summary {
 	return &Summary{
 		Successful: []string{},
 		Failed:     []StepError{},
+		Steps:      []StepResult{},
+		Artifacts:  map[string]string{},
 	}
 }
 
 func (s *Summary) AddSuccess(stepName string) {
-	s.Successful = append(s.Successful, stepName)
+	s.AddStep("", stepName, 0, nil)
 }
 
 func (s *Summary) AddError(stepName string, err error) {
-	s.Failed = append(s.Failed, StepError{
-		StepName: stepName,
-		Error:    err,
-	})
+	s.AddStep("", stepName, 0, err)
+}
+
+// AddStep records the outcome of a step identified by a stable ID, with its duration
+func (s *Summary) AddStep(id, stepName string, duration time.Duration, err error) {
+	result := StepResult{ID: id, Name: stepName, Status: StatusSucceeded, Duration: duration.Seconds()}
+	if err != nil {
+		result.Status = StatusFailed
+		result.Error = err.Error()
+		result.Hint = Hint(err)
+		s.Failed = append(s.Failed, StepError{
+			StepName: stepName,
+			Error:    err,
+			Hint:     result.Hint,
+		})
+	} else {
+		s.Successful = append(s.Successful, stepName)
+	}
+	s.Steps = append(s.Steps, result)
+}
+
+// AddSkipped records a step that was not run
+func (s *Summary) AddSkipped(id, stepName, reason string) {
+	s.Steps = append(s.Steps, StepResult{ID: id, Name: stepName, Status: StatusSkipped, Reason: reason})
+}
+
+// AddArtifact records the path of a file produced by the run
+func (s *Summary) AddArtifact(name, path string) {
+	s.Artifacts[name] = path
 }
 
 func (s *Summary) HasErrors() bool {
 	return len(s.Failed) > 0
 }
 
+// Status returns the overall status of the run
+func (s *Summary) Status() string {
+	if s.HasErrors() {
+		return "partial-success"
+	} else if len(s.Successful) > 0 {
+		return "success"
+	}
+	return "no-steps-executed"
+}
+
+// JSON returns the machine-readable summary
+func (s *Summary) JSON() ([]byte, error) {
+	document := struct {
+		ClusterName string            `json:"clusterName,omitempty"`
+		Status      string            `json:"status"`
+		Steps       []StepResult      `json:"steps"`
+		Artifacts   map[string]string `json:"artifacts"`
+		ConsoleURL  string            `json:"consoleURL,omitempty"`
+		TraceID     string            `json:"traceId,omitempty"`
+	}{
+		ClusterName: s.ClusterName,
+		Status:      s.Status(),
+		Steps:       s.Steps,
+		Artifacts:   s.Artifacts,
+		ConsoleURL:  s.ConsoleURL,
+		TraceID:     s.TraceID,
+	}
+	return json.MarshalIndent(document, "", "  ")
+}
+
 func (s *Summary) String() string {
 	var sb strings.Builder
 
 	sb.WriteString("\n=== Installation Summary ===\n\n")
 
+	if s.ClusterName != "" {
+		sb.WriteString(fmt.Sprintf("Cluster: %s\n\n", s.ClusterName))
+	}
+
 	if len(s.Successful) > 0 {
 		sb.WriteString("✓ Successful steps:\n")
 		for _, step := range s.Successful {
@@ -54,10 +141,21 @@ func (s *Summary) String() string {
 		sb.WriteString("✗ Failed steps:\n")
 		for _, stepErr := range s.Failed {
 			sb.WriteString(fmt.Sprintf("  - %s: %v\n", stepErr.StepName, stepErr.Error))
+			if stepErr.Hint != "" {
+				sb.WriteString(fmt.Sprintf("    Hint: %s\n", stepErr.Hint))
+			}
 		}
 		sb.WriteString("\n")
 	}
 
+	if s.ConsoleURL != "" {
+		sb.WriteString(fmt.Sprintf("Console: %s\n\n", s.ConsoleURL))
+	}
+
+	if s.TraceID != "" {
+		sb.WriteString(fmt.Sprintf("Trace ID: %s\n\n", s.TraceID))
+	}
+
 	if s.HasErrors() {
 		sb.WriteString("Overall status: PARTIAL SUCCESS (some steps failed)\n")
 	} else if len(s.Successful) > 0 {
@@ -1,9 +1,11 @@
 package errors
 
 import (
+	"encoding/json"
 	"errors"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestErrorSummary(t *testing.T) {
@@ -61,3 +63,59 @@ func TestEmptySummary(t *testing.T) {
 		t.Error("Empty summary should have no successful steps")
 	}
 }
+
+func TestSummaryJSON(t *testing.T) {
+	summary := NewSummary()
+	summary.AddSkipped("extract-credreqs", "[Step 1] Extract credentials requests", "already completed")
+	summary.AddStep("create-manifests", "[Step 6] Create manifests", 90*time.Second, nil)
+	summary.AddStep("create-aws-resources", "[Step 7] Create AWS resources", time.Second, errors.New("ccoctl failed"))
+	summary.AddArtifact("kubeconfig", "artifacts/clusters/test/auth/kubeconfig")
+	summary.TraceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	summary.ClusterName = "test-x7k2p"
+
+	data, err := summary.JSON()
+	if err != nil {
+		t.Fatalf("Failed to encode summary: %v", err)
+	}
+
+	var document struct {
+		ClusterName string            `json:"clusterName"`
+		Status      string            `json:"status"`
+		Steps       []StepResult      `json:"steps"`
+		Artifacts   map[string]string `json:"artifacts"`
+		TraceID     string            `json:"traceId"`
+	}
+	if err := json.Unmarshal(data, &document); err != nil {
+		t.Fatalf("Summary is not valid JSON: %v", err)
+	}
+
+	if document.Status != "partial-success" {
+		t.Errorf("Expected status partial-success, got %q", document.Status)
+	}
+	if len(document.Steps) != 3 {
+		t.Fatalf("Expected 3 steps, got %d", len(document.Steps))
+	}
+	if step := document.Steps[0]; step.Status != StatusSkipped || step.Reason != "already completed" {
+		t.Errorf("Unexpected skipped step: %+v", step)
+	}
+	if step := document.Steps[1]; step.ID != "create-manifests" || step.Status != StatusSucceeded || step.Duration != 90 {
+		t.Errorf("Unexpected successful step: %+v", step)
+	}
+	if step := document.Steps[2]; step.Status != StatusFailed || step.Error != "ccoctl failed" {
+		t.Errorf("Unexpected failed step: %+v", step)
+	}
+	if document.Artifacts["kubeconfig"] != "artifacts/clusters/test/auth/kubeconfig" {
+		t.Errorf("Expected kubeconfig artifact, got %v", document.Artifacts)
+	}
+	if document.TraceID != summary.TraceID {
+		t.Errorf("Expected trace ID %s, got %q", summary.TraceID, document.TraceID)
+	}
+	if document.ClusterName != "test-x7k2p" {
+		t.Errorf("Expected cluster name test-x7k2p, got %q", document.ClusterName)
+	}
+
+	// Skipped steps are not successes
+	if len(summary.Successful) != 1 || len(summary.Failed) != 1 {
+		t.Errorf("Expected 1 successful and 1 failed step, got %d and %d", len(summary.Successful), len(summary.Failed))
+	}
+}
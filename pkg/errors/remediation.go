@@ -0,0 +1,56 @@
+package errors
+
+import (
+	"regexp"
+)
+
+// remediation is a common failure, recognized by the message of its error,
+// and how to fix it
+type remediation struct {
+	signature *regexp.Regexp
+	hint      string
+}
+
+// remediations are the failures the child tools report with cryptic errors,
+// most specific first
+var remediations = []remediation{
+	{
+		signature: regexp.MustCompile(`(?is)AccessDenied.*CreateOpenIDConnectProvider|CreateOpenIDConnectProvider.*AccessDenied|not authorized to perform: iam:CreateOpenIDConnectProvider`),
+		hint:      "The AWS identity is not allowed to create OIDC providers: grant it iam:CreateOpenIDConnectProvider and iam:TagOpenIDConnectProvider, or reuse an existing provider with --existing-oidc-arn",
+	},
+	{
+		signature: regexp.MustCompile(`(?i)ExpiredToken|RequestExpired|security token included in the request is expired`),
+		hint:      "The AWS credentials expired: renew them (e.g. aws sso login --profile <profile>) and resume the installation",
+	},
+	{
+		signature: regexp.MustCompile(`(?i)already has record sets|record set.*but it already exists|already contains record sets`),
+		hint:      "The hosted zone still holds the DNS records of a previous cluster with the same name: remove them with 'openshift-sts-wrapper cleanup --cluster-name=<name>', or choose another cluster name",
+	},
+	{
+		signature: regexp.MustCompile(`(?i)invalid pull secret|pull secret.*(invalid|expired)|pullSecret: Invalid value|unauthorized: authentication required|unauthorized to access repository`),
+		hint:      "The pull secret is invalid or expired: download a new one from https://console.redhat.com/openshift/install/pull-secret and check it with 'openshift-sts-wrapper doctor'",
+	},
+	{
+		signature: regexp.MustCompile(`(?i)bootstrap failed to complete|waiting for bootstrapping to complete|bootstrap.*(timed out|timeout)`),
+		hint:      "The cluster did not finish bootstrapping: gather the bootstrap logs with 'openshift-install gather bootstrap --dir artifacts/clusters/<name>', and check that the machines can pull from quay.io and registry.redhat.io",
+	},
+	{
+		signature: regexp.MustCompile(`(?i)VcpuLimitExceeded|LimitExceeded|exceeded.*quota`),
+		hint:      "An AWS service quota is exhausted: request an increase in the Service Quotas console, or remove unused resources",
+	},
+}
+
+// Hint returns how to fix a common failure, from the message of its error. It
+// returns "" for an unknown failure.
+func Hint(err error) string {
+	if err == nil {
+		return ""
+	}
+	message := err.Error()
+	for _, r := range remediations {
+		if r.signature.MatchString(message) {
+			return r.hint
+		}
+	}
+	return ""
+}
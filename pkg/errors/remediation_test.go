@@ -0,0 +1,72 @@
+package errors
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestHint(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected string // Part of the expected hint, "" for none
+	}{
+		{
+			name:     "OIDC provider access denied",
+			err:      errors.New("command failed: ccoctl [aws create-all]: exit status 1\nOutput: failed to create Identity provider: AccessDenied: User: arn:aws:iam::123456789012:user/dev is not authorized to perform: iam:CreateOpenIDConnectProvider"),
+			expected: "--existing-oidc-arn",
+		},
+		{
+			name:     "record sets",
+			err:      errors.New("openshift-install create cluster failed: exit status 1\nTried to create resource record set [name='api.test.example.com.', type='A'] but it already exists"),
+			expected: "cleanup --cluster-name",
+		},
+		{
+			name:     "bootstrap timeout",
+			err:      errors.New("openshift-install create cluster failed: exit status 5\nBootstrap failed to complete: timed out waiting for the condition"),
+			expected: "gather bootstrap",
+		},
+		{
+			name:     "invalid pull secret",
+			err:      errors.New("error: unable to read image quay.io/openshift-release-dev/ocp-release:4.15.0: unauthorized: authentication required"),
+			expected: "console.redhat.com",
+		},
+		{
+			name:     "expired credentials",
+			err:      errors.New("An error occurred (ExpiredToken) when calling the GetCallerIdentity operation"),
+			expected: "credentials expired",
+		},
+		{
+			name: "unknown failure",
+			err:  errors.New("disk full"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hint := Hint(tt.err)
+			if tt.expected == "" && hint != "" {
+				t.Errorf("Expected no hint, got %q", hint)
+			}
+			if !strings.Contains(hint, tt.expected) {
+				t.Errorf("Expected a hint containing %q, got %q", tt.expected, hint)
+			}
+		})
+	}
+}
+
+func TestSummaryHints(t *testing.T) {
+	summary := NewSummary()
+	summary.AddStep("deploy-cluster", "[Step 10] Deploy cluster", 0, errors.New("Bootstrap failed to complete: timed out waiting for the condition"))
+	summary.AddError("Extract binaries", errors.New("download failed"))
+
+	if summary.Steps[0].Hint == "" || summary.Failed[0].Hint != summary.Steps[0].Hint {
+		t.Errorf("Expected a hint for the bootstrap timeout, got %+v", summary.Steps[0])
+	}
+	if summary.Steps[1].Hint != "" {
+		t.Errorf("Expected no hint for an unknown failure, got %q", summary.Steps[1].Hint)
+	}
+	if output := summary.String(); strings.Count(output, "Hint: ") != 1 || !strings.Contains(output, "gather bootstrap") {
+		t.Errorf("Expected the summary to show the hint once, got:\n%s", output)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"sync"
 )
 
 type Level int
@@ -14,11 +15,28 @@ const (
 	LevelVerbose
 )
 
+// Logger is safe for concurrent use: each message is written atomically
 type Logger struct {
+	mu     sync.Mutex
 	level  Level
 	writer io.Writer
+	// redrawn is set while a spinner line without newline is on the terminal
+	redrawn bool
+	// plain disables the terminal control sequences
+	plain bool
+	// color highlights the step results and the errors
+	color bool
 }
 
+// ANSI colors of the messages
+const (
+	colorRed   = "\033[31m"
+	colorGreen = "\033[32m"
+	colorReset = "\033[0m"
+)
+
+// New creates a logger writing to writer (stdout if nil). Colors are used
+// when writer is a terminal and NO_COLOR is not set (https://no-color.org).
 func New(level Level, writer io.Writer) *Logger {
 	if writer == nil {
 		writer = os.Stdout
@@ -26,37 +44,100 @@ func New(level Level, writer io.Writer) *Logger {
 	return &Logger{
 		level:  level,
 		writer: writer,
+		color:  os.Getenv("NO_COLOR") == "" && IsTerminal(writer),
+	}
+}
+
+// SetPlain makes the logger write like to a non-interactive output (e.g. CI
+// logs) even on a terminal: spinners print their status changes instead of
+// being redrawn in place, and no colors are used
+func (l *Logger) SetPlain(plain bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.plain = plain
+	if plain {
+		l.color = false
 	}
 }
 
+// SetColor enables or disables the colors
+func (l *Logger) SetColor(color bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.color = color
+}
+
+// Verbose reports whether debug messages are shown
+func (l *Logger) Verbose() bool {
+	return l.level >= LevelVerbose
+}
+
 func (l *Logger) Info(msg string) {
 	if l.level >= LevelNormal {
-		fmt.Fprintln(l.writer, msg)
+		l.printf("%s\n", msg)
 	}
 }
 
 func (l *Logger) Debug(msg string) {
 	if l.level >= LevelVerbose {
-		fmt.Fprintln(l.writer, msg)
+		l.printf("%s\n", msg)
 	}
 }
 
 func (l *Logger) Error(msg string) {
-	fmt.Fprintln(l.writer, msg)
+	l.printf("%s\n", l.paint(colorRed, msg))
+}
+
+// Command reports the full command line of a child process, in verbose mode
+func (l *Logger) Command(commandLine string) {
+	if l.level >= LevelVerbose {
+		l.printf("+ %s\n", commandLine)
+	}
 }
 
 func (l *Logger) StartStep(name string) {
 	if l.level >= LevelNormal {
-		fmt.Fprintf(l.writer, "⏳ %s...\n", name)
+		l.printf("⏳ %s...\n", name)
 	}
 }
 
+// CompleteStep reports a step result, which quiet loggers show too
 func (l *Logger) CompleteStep(name string) {
-	if l.level >= LevelNormal {
-		fmt.Fprintf(l.writer, "✓ %s\n", name)
-	}
+	l.printf("%s %s\n", l.paint(colorGreen, "✓"), name)
 }
 
 func (l *Logger) FailStep(name string) {
-	fmt.Fprintf(l.writer, "✗ %s\n", name)
+	l.printf("%s %s\n", l.paint(colorRed, "✗"), name)
+}
+
+// paint wraps text in a color, when colors are enabled
+func (l *Logger) paint(color, text string) string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.color {
+		return text
+	}
+	return color + text + colorReset
+}
+
+// printf serializes writes so that concurrent steps never interleave their
+// lines. Secrets are redacted at every level.
+func (l *Logger) printf(format string, args ...interface{}) {
+	message := Redact(fmt.Sprintf(format, args...))
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.redrawn {
+		// Messages replace the spinner line, which is redrawn below them
+		fmt.Fprint(l.writer, "\r\033[K")
+		l.redrawn = false
+	}
+	fmt.Fprint(l.writer, message)
+}
+
+// redraw replaces the current terminal line
+func (l *Logger) redraw(line string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	fmt.Fprint(l.writer, "\r\033[K"+Redact(line))
+	l.redrawn = line != ""
 }
@@ -3,6 +3,7 @@ package logger
 import (
 	"bytes"
 	"strings"
+	"sync"
 	"testing"
 )
 
@@ -93,3 +94,86 @@ func TestProgressIndicators(t *testing.T) {
 		t.Error("FailStep should show X mark")
 	}
 }
+
+func TestQuietLoggerStepResults(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(LevelQuiet, &buf)
+
+	logger.StartStep("Testing step")
+	logger.CompleteStep("Passed step")
+	logger.FailStep("Failed step")
+
+	output := buf.String()
+	if strings.Contains(output, "Testing step") {
+		t.Error("Quiet logger should not show the start of the steps")
+	}
+	if !strings.Contains(output, "✓ Passed step") || !strings.Contains(output, "✗ Failed step") {
+		t.Errorf("Quiet logger should show the step results, got %q", output)
+	}
+}
+
+func TestCommandLines(t *testing.T) {
+	var buf bytes.Buffer
+	New(LevelNormal, &buf).Command("aws sts get-caller-identity")
+	if buf.Len() != 0 {
+		t.Errorf("Normal logger should not show the command lines, got %q", buf.String())
+	}
+
+	New(LevelVerbose, &buf).Command("aws sts get-caller-identity")
+	if buf.String() != "+ aws sts get-caller-identity\n" {
+		t.Errorf("Verbose logger should show the command lines, got %q", buf.String())
+	}
+}
+
+func TestColors(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(LevelNormal, &buf)
+	logger.CompleteStep("Testing step")
+	if strings.Contains(buf.String(), "\033[") {
+		t.Errorf("Logger should not use colors when not writing to a terminal, got %q", buf.String())
+	}
+
+	buf.Reset()
+	logger.SetColor(true)
+	logger.CompleteStep("Testing step")
+	logger.Error("error message")
+	if !strings.Contains(buf.String(), colorGreen+"✓"+colorReset+" Testing step") {
+		t.Errorf("CompleteStep should show a green checkmark, got %q", buf.String())
+	}
+	if !strings.Contains(buf.String(), colorRed+"error message"+colorReset) {
+		t.Errorf("Error should show a red message, got %q", buf.String())
+	}
+
+	buf.Reset()
+	logger.SetPlain(true)
+	logger.FailStep("Testing step")
+	if buf.String() != "✗ Testing step\n" {
+		t.Errorf("Plain logger should not use colors, got %q", buf.String())
+	}
+}
+
+func TestConcurrentLogging(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(LevelNormal, &buf)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			logger.StartStep("parallel step")
+			logger.CompleteStep("parallel step")
+		}()
+	}
+	wg.Wait()
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 20 {
+		t.Fatalf("Expected 20 lines, got %d", len(lines))
+	}
+	for _, line := range lines {
+		if line != "⏳ parallel step..." && line != "✓ parallel step" {
+			t.Errorf("Unexpected interleaved line: %q", line)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package logger
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Redacted replaces the secrets in the messages
+const Redacted = "[REDACTED]"
+
+// minSecretLength keeps short values (e.g. an empty session token) from being
+// redacted everywhere they appear
+const minSecretLength = 8
+
+// secretPatterns match the secrets that can be recognized by their format or
+// by the name they are given. The groups before the secret are kept.
+var secretPatterns = []*regexp.Regexp{
+	// AWS access key IDs, long-term (AKIA) and temporary (ASIA)
+	regexp.MustCompile(`()\b(?:AKIA|ASIA)[A-Z0-9]{16}\b`),
+	// AWS credentials as environment variables or in ~/.aws/credentials
+	regexp.MustCompile(`(?i)(\b(?:aws_secret_access_key|aws_session_token|aws_security_token)\s*[=:]\s*"?)[^\s"]+`),
+	// AWS credentials in the aws CLI output, pull secret auths and passwords in JSON
+	regexp.MustCompile(`(?i)("(?:SecretAccessKey|SessionToken|auth|password)"\s*:\s*")[^"]+`),
+	// kubeadmin passwords, e.g. as printed by openshift-install
+	regexp.MustCompile(`()\b[A-Za-z0-9]{5}(?:-[A-Za-z0-9]{5}){3}\b`),
+}
+
+var (
+	secretsMu sync.RWMutex
+	// secrets are the values read at runtime that no pattern may match
+	secrets = map[string]bool{}
+)
+
+// AddSecret registers a value (e.g. credentials read from a profile) to be
+// redacted from every message, wherever it appears
+func AddSecret(secret string) {
+	secret = strings.TrimSpace(secret)
+	if len(secret) < minSecretLength {
+		return
+	}
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+	secrets[secret] = true
+}
+
+// Redact replaces the secrets of text (AWS keys and session tokens, pull secret
+// auths, kubeadmin passwords and the registered secrets) with Redacted
+func Redact(text string) string {
+	secretsMu.RLock()
+	values := make([]string, 0, len(secrets))
+	for secret := range secrets {
+		values = append(values, secret)
+	}
+	secretsMu.RUnlock()
+	// Longer secrets first, so that a secret containing another is fully redacted
+	sort.Slice(values, func(i, j int) bool { return len(values[i]) > len(values[j]) })
+	for _, secret := range values {
+		text = strings.ReplaceAll(text, secret, Redacted)
+	}
+
+	for _, pattern := range secretPatterns {
+		text = pattern.ReplaceAllString(text, "${1}"+Redacted)
+	}
+	return text
+}
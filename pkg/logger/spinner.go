@@ -0,0 +1,124 @@
+package logger
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// spinnerInterval is how often the spinner is redrawn and its status refreshed
+var spinnerInterval = 100 * time.Millisecond
+
+var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+// maxStatusLength keeps the spinner on a single terminal line
+const maxStatusLength = 80
+
+// Spinner shows that a long-running operation is in progress, with the elapsed
+// time and a status (e.g. the current install phase)
+type Spinner struct {
+	logger *Logger
+	label  string
+	status func() string
+	start  time.Time
+	done   chan struct{}
+	wg     sync.WaitGroup
+}
+
+// StartSpinner starts a spinner for the operation. On a terminal the spinner is
+// redrawn in place; elsewhere (e.g. CI logs) a line is printed every time the
+// status changes. Quiet loggers show nothing. status may be nil.
+func (l *Logger) StartSpinner(label string, status func() string) *Spinner {
+	s := &Spinner{
+		logger: l,
+		label:  label,
+		status: status,
+		start:  time.Now(),
+		done:   make(chan struct{}),
+	}
+	if l.level < LevelNormal {
+		return s
+	}
+
+	s.wg.Add(1)
+	go s.run(l.isTerminal())
+	return s
+}
+
+// Stop stops the spinner and clears its line
+func (s *Spinner) Stop() {
+	select {
+	case <-s.done:
+		return
+	default:
+	}
+	close(s.done)
+	s.wg.Wait()
+}
+
+func (s *Spinner) run(terminal bool) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(spinnerInterval)
+	defer ticker.Stop()
+
+	lastStatus := ""
+	for frame := 0; ; frame++ {
+		status := s.currentStatus()
+		elapsed := time.Since(s.start).Round(time.Second)
+
+		if terminal {
+			line := fmt.Sprintf("%s %s (%s)", spinnerFrames[frame%len(spinnerFrames)], s.label, elapsed)
+			if status != "" {
+				line += " - " + status
+			}
+			s.logger.redraw(line)
+		} else if status != "" && status != lastStatus {
+			s.logger.printf("  %s (%s): %s\n", s.label, elapsed, status)
+		}
+		lastStatus = status
+
+		select {
+		case <-s.done:
+			if terminal {
+				s.logger.redraw("")
+			}
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Spinner) currentStatus() string {
+	if s.status == nil {
+		return ""
+	}
+	status := s.status()
+	if runes := []rune(status); len(runes) > maxStatusLength {
+		status = string(runes[:maxStatusLength-1]) + "…"
+	}
+	return status
+}
+
+// isTerminal reports whether the logger writes to an interactive terminal
+func (l *Logger) isTerminal() bool {
+	l.mu.Lock()
+	plain := l.plain
+	l.mu.Unlock()
+	return !plain && IsTerminal(l.writer)
+}
+
+// IsTerminal reports whether w is an interactive terminal
+func IsTerminal(w io.Writer) bool {
+	file, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := file.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,90 @@
+package logger
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// syncBuffer is a bytes.Buffer safe for the concurrent reads of the test
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestSpinnerWithoutTerminal(t *testing.T) {
+	original := spinnerInterval
+	spinnerInterval = time.Millisecond
+	defer func() { spinnerInterval = original }()
+
+	var buf syncBuffer
+	logger := New(LevelNormal, &buf)
+
+	var mu sync.Mutex
+	status := "Creating infrastructure resources..."
+	spinner := logger.StartSpinner("Deploying cluster", func() string {
+		mu.Lock()
+		defer mu.Unlock()
+		return status
+	})
+
+	time.Sleep(20 * time.Millisecond)
+	mu.Lock()
+	status = "Waiting for the Kubernetes API"
+	mu.Unlock()
+	time.Sleep(20 * time.Millisecond)
+	spinner.Stop()
+	spinner.Stop()
+
+	output := buf.String()
+	if strings.Contains(output, "\r") {
+		t.Error("Spinner should not redraw lines outside a terminal")
+	}
+	// Each status is printed once
+	for _, expected := range []string{"Creating infrastructure resources...", "Waiting for the Kubernetes API"} {
+		if count := strings.Count(output, expected); count != 1 {
+			t.Errorf("Expected status %q to be printed once, got %d times in:\n%s", expected, count, output)
+		}
+	}
+}
+
+func TestSpinnerQuiet(t *testing.T) {
+	var buf syncBuffer
+	logger := New(LevelQuiet, &buf)
+
+	spinner := logger.StartSpinner("Deploying cluster", func() string { return "status" })
+	time.Sleep(10 * time.Millisecond)
+	spinner.Stop()
+
+	if buf.String() != "" {
+		t.Errorf("Quiet logger should not show the spinner, got %q", buf.String())
+	}
+}
+
+func TestMessagesReplaceSpinnerLine(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(LevelNormal, &buf)
+
+	logger.redraw("⠋ Deploying cluster (1s)")
+	logger.Info("  [10:05:00] Kubernetes API up")
+	logger.Info("next")
+
+	expected := "\r\033[K⠋ Deploying cluster (1s)\r\033[K  [10:05:00] Kubernetes API up\nnext\n"
+	if buf.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, buf.String())
+	}
+}
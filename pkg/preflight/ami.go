@@ -0,0 +1,84 @@
+package preflight
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/clobrano/openshift-sts-wrapper/pkg/config"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/util"
+)
+
+// imageArchitectures maps the release architectures to the EC2 image ones
+var imageArchitectures = map[string]string{
+	"x86_64":  "x86_64",
+	"aarch64": "arm64",
+}
+
+// CheckAMIs validates that the pinned boot images exist in the region, are
+// available, and match the architecture of the release
+func CheckAMIs(executor util.CommandExecutor, cfg *config.Config) ([]string, error) {
+	if cfg.AwsRegion == "" {
+		return []string{"AWS region not configured yet, skipping AMI validation"}, nil
+	}
+
+	var ids []string
+	for _, id := range []string{cfg.ControlPlaneAMI(), cfg.WorkerAMI()} {
+		if id != "" && (len(ids) == 0 || ids[0] != id) {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	args := append([]string{"ec2", "describe-images", "--image-ids"}, ids...)
+	output, err := util.RunAWSCLI(executor, cfg.AwsProfile, cfg.AwsRegion, args...)
+	if err != nil {
+		// EC2 fails the whole call when any image doesn't exist
+		if strings.Contains(err.Error(), "InvalidAMIID") {
+			return nil, fmt.Errorf("AMI %s not found in %s: %w", strings.Join(ids, ", "), cfg.AwsRegion, err)
+		}
+		return nil, err
+	}
+
+	var result struct {
+		Images []struct {
+			ImageId      string `json:"ImageId"`
+			State        string `json:"State"`
+			Architecture string `json:"Architecture"`
+		} `json:"Images"`
+	}
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		return nil, fmt.Errorf("failed to parse describe-images output: %w", err)
+	}
+
+	found := map[string]bool{}
+	var problems []string
+	// Multi-arch payloads boot any architecture
+	var expectedArch string
+	if versionArch, err := util.ExtractVersionArch(cfg.ReleaseImage); err == nil {
+		expectedArch = imageArchitectures[util.ReleaseArch(versionArch)]
+	}
+	for _, image := range result.Images {
+		found[image.ImageId] = true
+		if image.State != "available" {
+			problems = append(problems, fmt.Sprintf("%s is %s", image.ImageId, image.State))
+		}
+		if expectedArch != "" && image.Architecture != "" && image.Architecture != expectedArch {
+			problems = append(problems, fmt.Sprintf("%s is a %s image, the release is %s", image.ImageId, image.Architecture, expectedArch))
+		}
+	}
+	for _, id := range ids {
+		if !found[id] {
+			problems = append(problems, fmt.Sprintf("%s not found in %s (or not shared with the account)", id, cfg.AwsRegion))
+		}
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return nil, fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+	return nil, nil
+}
@@ -0,0 +1,64 @@
+package preflight
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/clobrano/openshift-sts-wrapper/pkg/config"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/util"
+)
+
+func TestCheckAMIs(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	cmd := "aws ec2 describe-images --image-ids ami-0123456789abcdef0 ami-0fedcba9876543210 --output json --profile default --region us-east-1"
+
+	tests := []struct {
+		name     string
+		output   string
+		err      error
+		contains string
+	}{
+		{"available images", `{"Images": [
+			{"ImageId": "ami-0123456789abcdef0", "State": "available", "Architecture": "x86_64"},
+			{"ImageId": "ami-0fedcba9876543210", "State": "available", "Architecture": "x86_64"}]}`, nil, ""},
+		{"image not found", "", fmt.Errorf("An error occurred (InvalidAMIID.NotFound) when calling the DescribeImages operation"), "not found in us-east-1"},
+		{"image not shared", `{"Images": [
+			{"ImageId": "ami-0123456789abcdef0", "State": "available", "Architecture": "x86_64"}]}`, nil, "ami-0fedcba9876543210 not found"},
+		{"pending image", `{"Images": [
+			{"ImageId": "ami-0123456789abcdef0", "State": "available", "Architecture": "x86_64"},
+			{"ImageId": "ami-0fedcba9876543210", "State": "pending", "Architecture": "x86_64"}]}`, nil, "ami-0fedcba9876543210 is pending"},
+		{"wrong architecture", `{"Images": [
+			{"ImageId": "ami-0123456789abcdef0", "State": "available", "Architecture": "arm64"},
+			{"ImageId": "ami-0fedcba9876543210", "State": "available", "Architecture": "x86_64"}]}`, nil, "arm64 image"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			executor := util.NewMockExecutor()
+			executor.SetOutput(cmd, tt.output)
+			if tt.err != nil {
+				executor.SetError(cmd, tt.err)
+			}
+			cfg := &config.Config{
+				ReleaseImage: "quay.io/openshift-release-dev/ocp-release:4.15.0-x86_64",
+				AwsProfile:   "default",
+				AwsRegion:    "us-east-1",
+				AMIID:        "ami-0123456789abcdef0",
+				WorkerAMIID:  "ami-0fedcba9876543210",
+			}
+
+			_, err := CheckAMIs(executor, cfg)
+			if tt.contains == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.contains) {
+				t.Errorf("Expected an error containing %q, got %v", tt.contains, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package preflight
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/clobrano/openshift-sts-wrapper/pkg/config"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/util"
+)
+
+// ListRegions returns the regions enabled in the AWS account, sorted
+func ListRegions(executor util.CommandExecutor, cfg *config.Config) ([]string, error) {
+	output, err := util.RunAWSCLI(executor, cfg.AwsProfile, cfg.AwsRegion, "ec2", "describe-regions")
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Regions []struct {
+			RegionName string `json:"RegionName"`
+		} `json:"Regions"`
+	}
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		return nil, fmt.Errorf("failed to parse describe-regions output: %w", err)
+	}
+
+	regions := make([]string, 0, len(result.Regions))
+	for _, region := range result.Regions {
+		regions = append(regions, region.RegionName)
+	}
+	sort.Strings(regions)
+	return regions, nil
+}
+
+// ListPublicDomains returns the domains of the public Route53 hosted zones of
+// the AWS account, the candidate base domains, sorted
+func ListPublicDomains(executor util.CommandExecutor, cfg *config.Config) ([]string, error) {
+	output, err := util.RunAWSCLI(executor, cfg.AwsProfile, "", "route53", "list-hosted-zones")
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		HostedZones []hostedZone `json:"HostedZones"`
+	}
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		return nil, fmt.Errorf("failed to parse list-hosted-zones output: %w", err)
+	}
+
+	var domains []string
+	for _, zone := range result.HostedZones {
+		if !zone.Config.PrivateZone {
+			domains = append(domains, dnsName(zone.Name))
+		}
+	}
+	sort.Strings(domains)
+	return domains, nil
+}
+
+// OfferedInstanceTypes returns the instance types among candidates that are
+// offered in the region, in the order of candidates
+func OfferedInstanceTypes(executor util.CommandExecutor, cfg *config.Config, candidates []string) ([]string, error) {
+	offerings, err := DescribeInstanceTypeOfferings(executor, cfg, nil, candidates...)
+	if err != nil {
+		return nil, err
+	}
+	offered := offeredLocations(offerings)
+
+	var instanceTypes []string
+	for _, candidate := range candidates {
+		if offered[candidate][cfg.AwsRegion] {
+			instanceTypes = append(instanceTypes, candidate)
+		}
+	}
+	return instanceTypes, nil
+}
@@ -0,0 +1,60 @@
+package preflight
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/clobrano/openshift-sts-wrapper/pkg/config"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/util"
+)
+
+func TestListRegions(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	executor := util.NewMockExecutor()
+	executor.SetOutput("aws ec2 describe-regions --output json --profile default",
+		`{"Regions": [{"RegionName": "us-west-2"}, {"RegionName": "eu-west-1"}, {"RegionName": "us-east-1"}]}`)
+
+	regions, err := ListRegions(executor, &config.Config{AwsProfile: "default"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if expected := []string{"eu-west-1", "us-east-1", "us-west-2"}; !reflect.DeepEqual(regions, expected) {
+		t.Errorf("Expected %v, got %v", expected, regions)
+	}
+}
+
+func TestListPublicDomains(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	executor := util.NewMockExecutor()
+	executor.SetOutput("aws route53 list-hosted-zones --output json --profile default", `{"HostedZones": [
+		{"Id": "/hostedzone/Z2", "Name": "lab.example.com.", "Config": {"PrivateZone": false}},
+		{"Id": "/hostedzone/Z3", "Name": "internal.example.com.", "Config": {"PrivateZone": true}},
+		{"Id": "/hostedzone/Z1", "Name": "example.com.", "Config": {"PrivateZone": false}}]}`)
+
+	domains, err := ListPublicDomains(executor, &config.Config{AwsProfile: "default"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if expected := []string{"example.com", "lab.example.com"}; !reflect.DeepEqual(domains, expected) {
+		t.Errorf("Expected %v, got %v", expected, domains)
+	}
+}
+
+func TestOfferedInstanceTypes(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	executor := util.NewMockExecutor()
+	executor.SetOutput("aws ec2 describe-instance-type-offerings --location-type region --filters Name=instance-type,Values=m5.xlarge,m7i.xlarge,m6i.xlarge --output json --profile default --region eu-south-2",
+		`{"InstanceTypeOfferings": [{"InstanceType": "m6i.xlarge", "Location": "eu-south-2"}, {"InstanceType": "m5.xlarge", "Location": "eu-south-2"}]}`)
+
+	cfg := &config.Config{AwsProfile: "default", AwsRegion: "eu-south-2"}
+	instanceTypes, err := OfferedInstanceTypes(executor, cfg, []string{"m5.xlarge", "m7i.xlarge", "m6i.xlarge"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if expected := []string{"m5.xlarge", "m6i.xlarge"}; !reflect.DeepEqual(instanceTypes, expected) {
+		t.Errorf("Expected %v, got %v", expected, instanceTypes)
+	}
+}
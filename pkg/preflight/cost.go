@@ -0,0 +1,209 @@
+package preflight
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/clobrano/openshift-sts-wrapper/pkg/config"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/util"
+)
+
+// pricingRegion is a region serving the AWS Price List API
+const pricingRegion = "us-east-1"
+
+// hoursPerMonth is the number of hours AWS bills per month
+const hoursPerMonth = 730
+
+// rootVolumeSize is the installer default root volume of the machines (gp3, GiB)
+const rootVolumeSize = 120
+
+// CostItem is the hourly on-demand cost of a kind of resource of the cluster
+type CostItem struct {
+	Description string
+	Hourly      float64
+}
+
+// Monthly returns the cost of the item per month, in USD
+func (i CostItem) Monthly() float64 {
+	return i.Hourly * hoursPerMonth
+}
+
+// CostEstimate is the estimated on-demand cost of the cluster. Data transfer,
+// load balancers and the temporary bootstrap machine are not included.
+type CostEstimate struct {
+	Items []CostItem
+}
+
+// Hourly returns the estimated cost per hour, in USD
+func (e *CostEstimate) Hourly() float64 {
+	total := 0.0
+	for _, item := range e.Items {
+		total += item.Hourly
+	}
+	return total
+}
+
+// Daily returns the estimated cost per day, in USD
+func (e *CostEstimate) Daily() float64 {
+	return e.Hourly() * 24
+}
+
+// Monthly returns the estimated cost per month, in USD
+func (e *CostEstimate) Monthly() float64 {
+	return e.Hourly() * hoursPerMonth
+}
+
+func (e *CostEstimate) String() string {
+	return fmt.Sprintf("$%.2f/day, $%.2f/month", e.Daily(), e.Monthly())
+}
+
+// EstimateCost estimates the on-demand cost of the cluster from the AWS Price
+// List API: its machines, their root volumes and the NAT gateways
+func EstimateCost(executor util.CommandExecutor, cfg *config.Config) (*CostEstimate, error) {
+	estimate := &CostEstimate{}
+
+	volumes := 0
+	pools := append([]machinePool{{"control plane", replicas(cfg.ControlPlaneReplicas), cfg.ControlPlaneInstanceType()}}, computePools(cfg)...)
+	for _, pool := range pools {
+		if pool.count == 0 {
+			continue
+		}
+		volumes += pool.count
+		price, err := getOnDemandPrice(executor, cfg, "Hrs", map[string]string{
+			"instanceType":    pool.instanceType,
+			"operatingSystem": "Linux", // RHCOS has no license charge
+			"tenancy":         "Shared",
+			"preInstalledSw":  "NA",
+			"capacitystatus":  "Used",
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the price of %s: %w", pool.instanceType, err)
+		}
+		estimate.Items = append(estimate.Items, CostItem{
+			Description: fmt.Sprintf("%d × %s (%s)", pool.count, pool.instanceType, pool.name),
+			Hourly:      float64(pool.count) * price,
+		})
+	}
+
+	volumePrice, err := getOnDemandPrice(executor, cfg, "GB-Mo", map[string]string{
+		"productFamily": "Storage",
+		"volumeApiName": "gp3",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the price of gp3 volumes: %w", err)
+	}
+	estimate.Items = append(estimate.Items, CostItem{
+		Description: fmt.Sprintf("%d × %d GiB gp3 root volumes", volumes, rootVolumeSize),
+		Hourly:      float64(volumes*rootVolumeSize) * volumePrice / hoursPerMonth,
+	})
+
+	// Installing into existing subnets creates no NAT gateway, otherwise one per zone
+	if len(cfg.Subnets) == 0 {
+		zones := len(cfg.Zones)
+		if zones == 0 {
+			zones, err = countAWSResources(executor, cfg, "AvailabilityZones", "",
+				"ec2", "describe-availability-zones", "--filters", "Name=zone-type,Values=availability-zone", "Name=state,Values=available")
+			if err != nil {
+				return nil, err
+			}
+		}
+		natPrice, err := getOnDemandPrice(executor, cfg, "Hrs", map[string]string{"productFamily": "NAT Gateway"})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the price of NAT gateways: %w", err)
+		}
+		estimate.Items = append(estimate.Items, CostItem{
+			Description: fmt.Sprintf("%d × NAT gateway", zones),
+			Hourly:      float64(zones) * natPrice,
+		})
+	}
+
+	return estimate, nil
+}
+
+// CheckCost estimates the cost of the cluster into estimate, and validates it
+// against the configured budget (maxMonthlyCost). Without a budget, a cost that
+// can't be estimated is a warning; with one, it is an error, since the budget
+// can't be enforced.
+func CheckCost(executor util.CommandExecutor, cfg *config.Config, estimate *CostEstimate) ([]string, error) {
+	unavailable := func(reason string) ([]string, error) {
+		if cfg.MaxMonthlyCost > 0 {
+			return nil, fmt.Errorf("%s, cannot enforce the budget of $%.2f/month (unset maxMonthlyCost to install without a budget)", reason, cfg.MaxMonthlyCost)
+		}
+		return []string{reason + ", skipping cost estimate"}, nil
+	}
+
+	if cfg.AwsRegion == "" {
+		return unavailable("AWS region not configured yet")
+	}
+	// The Price List API is only served in the commercial partition
+	if partition := cfg.Partition(); partition != config.DefaultPartition {
+		return unavailable(fmt.Sprintf("prices are not available in the %s partition", partition))
+	}
+
+	result, err := EstimateCost(executor, cfg)
+	if err != nil {
+		return unavailable(fmt.Sprintf("cannot estimate the cost (%v)", err))
+	}
+	*estimate = *result
+
+	if cfg.MaxMonthlyCost > 0 && estimate.Monthly() > cfg.MaxMonthlyCost {
+		return nil, fmt.Errorf("estimated cost of %s exceeds the budget of $%.2f/month (maxMonthlyCost)", estimate, cfg.MaxMonthlyCost)
+	}
+	return nil, nil
+}
+
+// getOnDemandPrice returns the on-demand price per unit, in USD, of the EC2
+// product of the configured region matching the attribute filters
+func getOnDemandPrice(executor util.CommandExecutor, cfg *config.Config, unit string, attributes map[string]string) (float64, error) {
+	args := []string{"pricing", "get-products", "--service-code", "AmazonEC2", "--filters",
+		"Type=TERM_MATCH,Field=regionCode,Value=" + cfg.AwsRegion}
+	fields := make([]string, 0, len(attributes))
+	for field := range attributes {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		args = append(args, fmt.Sprintf("Type=TERM_MATCH,Field=%s,Value=%s", field, attributes[field]))
+	}
+
+	output, err := util.RunAWSCLI(executor, cfg.AwsProfile, pricingRegion, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	// Each product is itself a JSON document
+	var result struct {
+		PriceList []string `json:"PriceList"`
+	}
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		return 0, fmt.Errorf("failed to parse get-products output: %w", err)
+	}
+	for _, item := range result.PriceList {
+		var product struct {
+			Terms struct {
+				OnDemand map[string]struct {
+					PriceDimensions map[string]struct {
+						Unit         string            `json:"unit"`
+						PricePerUnit map[string]string `json:"pricePerUnit"`
+					} `json:"priceDimensions"`
+				} `json:"OnDemand"`
+			} `json:"terms"`
+		}
+		if err := json.Unmarshal([]byte(item), &product); err != nil {
+			return 0, fmt.Errorf("failed to parse get-products output: %w", err)
+		}
+		for _, term := range product.Terms.OnDemand {
+			for _, dimension := range term.PriceDimensions {
+				if dimension.Unit != unit {
+					continue
+				}
+				if price, err := strconv.ParseFloat(dimension.PricePerUnit["USD"], 64); err == nil && price > 0 {
+					return price, nil
+				}
+			}
+		}
+	}
+	return 0, fmt.Errorf("no on-demand price found in %s", cfg.AwsRegion)
+}
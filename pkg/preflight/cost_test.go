@@ -0,0 +1,141 @@
+package preflight
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/clobrano/openshift-sts-wrapper/pkg/config"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/util"
+)
+
+// priceList returns a get-products output with a product priced per unit
+func priceList(unit, price string) string {
+	product := fmt.Sprintf(`{"terms": {"OnDemand": {"T1": {"priceDimensions": {"D1": {"unit": %q, "pricePerUnit": {"USD": %q}}}}}}}`, unit, price)
+	output, _ := json.Marshal(map[string][]string{"PriceList": {product}})
+	return string(output)
+}
+
+func setPricingOutputs(executor *util.MockExecutor) {
+	instance := "aws pricing get-products --service-code AmazonEC2 --filters Type=TERM_MATCH,Field=regionCode,Value=us-east-1 " +
+		"Type=TERM_MATCH,Field=capacitystatus,Value=Used Type=TERM_MATCH,Field=instanceType,Value=%s Type=TERM_MATCH,Field=operatingSystem,Value=Linux " +
+		"Type=TERM_MATCH,Field=preInstalledSw,Value=NA Type=TERM_MATCH,Field=tenancy,Value=Shared" + awsSuffix
+	executor.SetOutput(fmt.Sprintf(instance, "m5.xlarge"), priceList("Hrs", "0.1920000000"))
+	executor.SetOutput(fmt.Sprintf(instance, "m5.2xlarge"), priceList("Hrs", "0.3840000000"))
+	executor.SetOutput("aws pricing get-products --service-code AmazonEC2 --filters Type=TERM_MATCH,Field=regionCode,Value=us-east-1 "+
+		"Type=TERM_MATCH,Field=productFamily,Value=Storage Type=TERM_MATCH,Field=volumeApiName,Value=gp3"+awsSuffix, priceList("GB-Mo", "0.0800000000"))
+	executor.SetOutput("aws pricing get-products --service-code AmazonEC2 --filters Type=TERM_MATCH,Field=regionCode,Value=us-east-1 "+
+		"Type=TERM_MATCH,Field=productFamily,Value=NAT Gateway"+awsSuffix, priceList("Hrs", "0.0450000000"))
+}
+
+func TestEstimateCost(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	executor := util.NewMockExecutor()
+	setPricingOutputs(executor)
+	workers := 2
+	cfg := &config.Config{
+		AwsProfile:       "default",
+		AwsRegion:        "us-east-1",
+		ControlPlaneType: "m5.2xlarge",
+		WorkerType:       "m5.xlarge",
+		WorkerReplicas:   &workers,
+		Zones:            []string{"us-east-1a", "us-east-1b"},
+	}
+
+	estimate, err := EstimateCost(executor, cfg)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(estimate.Items) != 4 {
+		t.Fatalf("Expected 4 cost items, got %+v", estimate.Items)
+	}
+	if estimate.Items[3].Description != "2 × NAT gateway" {
+		t.Errorf("Expected a NAT gateway per zone, got %q", estimate.Items[3].Description)
+	}
+
+	// 3 * 0.384 + 2 * 0.192 + 0.09 NAT = 1.626/h, plus 5 * 120 GiB * 0.08 = $48/month of volumes
+	expected := 1.626*hoursPerMonth + 48
+	if math.Abs(estimate.Monthly()-expected) > 0.01 {
+		t.Errorf("Expected $%.2f/month, got $%.2f/month", expected, estimate.Monthly())
+	}
+
+	// Existing subnets come with their own NAT gateways
+	cfg.Subnets = []string{"subnet-1"}
+	estimate, err = EstimateCost(executor, cfg)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(estimate.Items) != 3 {
+		t.Errorf("Expected no NAT gateway cost with existing subnets, got %+v", estimate.Items)
+	}
+}
+
+func TestCheckCost(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	tests := []struct {
+		name           string
+		instanceType   string
+		maxMonthlyCost float64
+		shouldError    string
+		shouldWarn     bool
+	}{
+		{"no budget", "m5.xlarge", 0, "", false},
+		{"within budget", "m5.xlarge", 1000, "", false},
+		{"over budget", "m5.xlarge", 500, "exceeds the budget", false},
+		{"price not found", "x9.unknown", 0, "", true},
+		{"price not found with a budget", "x9.unknown", 500, "cannot enforce the budget", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			executor := util.NewMockExecutor()
+			setPricingOutputs(executor)
+			cfg := &config.Config{
+				AwsProfile:     "default",
+				AwsRegion:      "us-east-1",
+				InstanceType:   tt.instanceType,
+				Zones:          []string{"us-east-1a"},
+				MaxMonthlyCost: tt.maxMonthlyCost,
+			}
+
+			var estimate CostEstimate
+			warnings, err := CheckCost(executor, cfg, &estimate)
+			if tt.shouldError != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.shouldError) {
+					t.Errorf("Expected an error containing %q, got %v", tt.shouldError, err)
+				}
+				return
+			} else if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			if tt.shouldWarn != (len(warnings) > 0) {
+				t.Errorf("Expected warnings: %v, got %v", tt.shouldWarn, warnings)
+			}
+			if !tt.shouldWarn && len(estimate.Items) == 0 {
+				t.Error("Expected the estimate to be stored")
+			}
+		})
+	}
+}
+
+func TestCheckCostUnavailable(t *testing.T) {
+	for _, cfg := range []*config.Config{
+		{AwsProfile: "default"},
+		{AwsProfile: "default", AwsRegion: "us-gov-west-1"},
+	} {
+		var estimate CostEstimate
+		warnings, err := CheckCost(util.NewMockExecutor(), cfg, &estimate)
+		if err != nil || len(warnings) != 1 {
+			t.Errorf("Expected a warning without a budget, got %v (%v)", warnings, err)
+		}
+
+		cfg.MaxMonthlyCost = 500
+		if _, err := CheckCost(util.NewMockExecutor(), cfg, &estimate); err == nil || !strings.Contains(err.Error(), "cannot enforce the budget") {
+			t.Errorf("Expected an error with a budget, got %v", err)
+		}
+	}
+}
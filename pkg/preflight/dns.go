@@ -0,0 +1,118 @@
+package preflight
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/clobrano/openshift-sts-wrapper/pkg/config"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/util"
+)
+
+type hostedZone struct {
+	ID     string `json:"Id"`
+	Name   string `json:"Name"`
+	Config struct {
+		PrivateZone bool `json:"PrivateZone"`
+	} `json:"Config"`
+}
+
+// FindPublicHostedZone returns the public Route53 hosted zone of the domain, or
+// nil when the account has none
+func FindPublicHostedZone(executor util.CommandExecutor, cfg *config.Config, domain string) (*hostedZone, error) {
+	output, err := util.RunAWSCLI(executor, cfg.AwsProfile, "",
+		"route53", "list-hosted-zones-by-name", "--dns-name", domain)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		HostedZones []hostedZone `json:"HostedZones"`
+	}
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		return nil, fmt.Errorf("failed to parse list-hosted-zones-by-name output: %w", err)
+	}
+
+	// Zones are listed in name order starting from the domain, so other
+	// domains may follow the one we are looking for
+	for _, zone := range result.HostedZones {
+		if dnsName(zone.Name) == dnsName(domain) && !zone.Config.PrivateZone {
+			return &zone, nil
+		}
+	}
+	return nil, nil
+}
+
+// recordExists reports whether the hosted zone has a record with the given name
+func recordExists(executor util.CommandExecutor, cfg *config.Config, zoneID, name string) (bool, error) {
+	output, err := util.RunAWSCLI(executor, cfg.AwsProfile, "",
+		"route53", "list-resource-record-sets", "--hosted-zone-id", zoneID,
+		"--start-record-name", name, "--max-items", "1")
+	if err != nil {
+		return false, err
+	}
+
+	var result struct {
+		ResourceRecordSets []struct {
+			Name string `json:"Name"`
+		} `json:"ResourceRecordSets"`
+	}
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		return false, fmt.Errorf("failed to parse list-resource-record-sets output: %w", err)
+	}
+
+	// The listing starts at the first record >= name, which may be a different one
+	for _, record := range result.ResourceRecordSets {
+		if dnsName(record.Name) == dnsName(name) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// dnsName normalizes a Route53 name for comparison: lowercase, no trailing dot
+// and the octal escape of the wildcard label decoded
+func dnsName(name string) string {
+	name = strings.ReplaceAll(name, `\052`, "*")
+	return strings.ToLower(strings.TrimSuffix(name, "."))
+}
+
+// CheckBaseDomain validates that the base domain has a public hosted zone in
+// the account, and that the cluster API and ingress records do not exist yet
+func CheckBaseDomain(executor util.CommandExecutor, cfg *config.Config) ([]string, error) {
+	if cfg.BaseDomain == "" {
+		return []string{"base domain not configured yet, skipping hosted zone validation"}, nil
+	}
+	// Private clusters get a private hosted zone created by the installer
+	if cfg.Private {
+		return nil, nil
+	}
+
+	zone, err := FindPublicHostedZone(executor, cfg, cfg.BaseDomain)
+	if err != nil {
+		return nil, err
+	}
+	if zone == nil {
+		return nil, fmt.Errorf("no public Route53 hosted zone found for base domain %s in this account", cfg.BaseDomain)
+	}
+
+	var conflicts []string
+	for _, name := range []string{
+		fmt.Sprintf("api.%s.%s", cfg.ClusterName, cfg.BaseDomain),
+		fmt.Sprintf("*.apps.%s.%s", cfg.ClusterName, cfg.BaseDomain),
+	} {
+		exists, err := recordExists(executor, cfg, zone.ID, name)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			conflicts = append(conflicts, name)
+		}
+	}
+	if len(conflicts) > 0 {
+		return nil, fmt.Errorf("DNS records already exist in hosted zone %s: %s (choose another cluster name or remove the records of the previous cluster)",
+			cfg.BaseDomain, strings.Join(conflicts, ", "))
+	}
+
+	return nil, nil
+}
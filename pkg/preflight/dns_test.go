@@ -0,0 +1,54 @@
+package preflight
+
+import (
+	"testing"
+
+	"github.com/clobrano/openshift-sts-wrapper/pkg/config"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/util"
+)
+
+func TestCheckBaseDomain(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	zonesCmd := "aws route53 list-hosted-zones-by-name --dns-name example.com --output json --profile default"
+	apiCmd := "aws route53 list-resource-record-sets --hosted-zone-id /hostedzone/Z1 --start-record-name api.my-cluster.example.com --max-items 1 --output json --profile default"
+	appsCmd := "aws route53 list-resource-record-sets --hosted-zone-id /hostedzone/Z1 --start-record-name *.apps.my-cluster.example.com --max-items 1 --output json --profile default"
+	publicZone := `{"HostedZones": [{"Id": "/hostedzone/Z1", "Name": "example.com.", "Config": {"PrivateZone": false}}]}`
+	noRecord := `{"ResourceRecordSets": [{"Name": "api.other.example.com."}]}`
+
+	tests := []struct {
+		name        string
+		zones       string
+		api         string
+		apps        string
+		shouldError bool
+	}{
+		{"valid", publicZone, noRecord, noRecord, false},
+		{"no hosted zone", `{"HostedZones": [{"Id": "/hostedzone/Z2", "Name": "example.company.com."}]}`, noRecord, noRecord, true},
+		{"only private zone", `{"HostedZones": [{"Id": "/hostedzone/Z1", "Name": "example.com.", "Config": {"PrivateZone": true}}]}`, noRecord, noRecord, true},
+		{"api record exists", publicZone, `{"ResourceRecordSets": [{"Name": "api.my-cluster.example.com."}]}`, noRecord, true},
+		{"apps record exists", publicZone, noRecord, `{"ResourceRecordSets": [{"Name": "\\052.apps.my-cluster.example.com."}]}`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			executor := util.NewMockExecutor()
+			executor.SetOutput(zonesCmd, tt.zones)
+			executor.SetOutput(apiCmd, tt.api)
+			executor.SetOutput(appsCmd, tt.apps)
+			cfg := &config.Config{
+				AwsProfile:  "default",
+				ClusterName: "my-cluster",
+				BaseDomain:  "example.com",
+			}
+
+			_, err := CheckBaseDomain(executor, cfg)
+			if tt.shouldError && err == nil {
+				t.Error("Expected error but got nil")
+			}
+			if !tt.shouldError && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
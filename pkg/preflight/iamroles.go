@@ -0,0 +1,98 @@
+package preflight
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/clobrano/openshift-sts-wrapper/pkg/config"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/util"
+)
+
+// CheckExternalIAM validates that the existing IAM roles the cluster uses
+// exist and trust the configured OIDC provider
+func CheckExternalIAM(executor util.CommandExecutor, cfg *config.Config) ([]string, error) {
+	provider := "oidc-provider/" + strings.TrimSuffix(strings.TrimPrefix(cfg.OIDCIssuerURL, "https://"), "/")
+
+	// Several CredentialsRequests may share a role
+	roles := map[string]bool{}
+	for _, arn := range cfg.IAMRoles {
+		roles[arn] = true
+	}
+	arns := make([]string, 0, len(roles))
+	for arn := range roles {
+		arns = append(arns, arn)
+	}
+	sort.Strings(arns)
+
+	var problems []string
+	for _, arn := range arns {
+		name := arn[strings.LastIndex(arn, "/")+1:]
+		output, err := util.RunAWSCLI(executor, cfg.AwsProfile, "", "iam", "get-role", "--role-name", name)
+		if err != nil {
+			if strings.Contains(err.Error(), "NoSuchEntity") {
+				problems = append(problems, fmt.Sprintf("role %s does not exist", arn))
+				continue
+			}
+			return nil, err
+		}
+
+		var result struct {
+			Role struct {
+				Arn                      string          `json:"Arn"`
+				AssumeRolePolicyDocument json.RawMessage `json:"AssumeRolePolicyDocument"`
+			} `json:"Role"`
+		}
+		if err := json.Unmarshal([]byte(output), &result); err != nil {
+			return nil, fmt.Errorf("failed to parse get-role output: %w", err)
+		}
+		if result.Role.Arn != arn {
+			problems = append(problems, fmt.Sprintf("role %s is %s", arn, result.Role.Arn))
+		} else if !strings.Contains(string(result.Role.AssumeRolePolicyDocument), provider) {
+			problems = append(problems, fmt.Sprintf("role %s does not trust %s", arn, provider))
+		}
+	}
+
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+	return nil, nil
+}
+
+// CheckOIDCProvider validates that the existing OIDC provider the IAM roles of
+// the cluster will trust exists, and is the one of the configured issuer
+func CheckOIDCProvider(executor util.CommandExecutor, cfg *config.Config) ([]string, error) {
+	output, err := util.RunAWSCLI(executor, cfg.AwsProfile, "", "iam", "get-open-id-connect-provider", "--open-id-connect-provider-arn", cfg.OIDCProviderARN)
+	if err != nil {
+		if strings.Contains(err.Error(), "NoSuchEntity") {
+			return nil, fmt.Errorf("OIDC provider %s does not exist", cfg.OIDCProviderARN)
+		}
+		return nil, err
+	}
+
+	var provider struct {
+		Url string `json:"Url"`
+	}
+	if err := json.Unmarshal([]byte(output), &provider); err != nil {
+		return nil, fmt.Errorf("failed to parse get-open-id-connect-provider output: %w", err)
+	}
+	// The provider URL has no scheme
+	issuer := strings.TrimSuffix(strings.TrimPrefix(cfg.OIDCIssuerURL, "https://"), "/")
+	if strings.TrimPrefix(provider.Url, "https://") != issuer {
+		return nil, fmt.Errorf("OIDC provider %s is for %s, not %s", cfg.OIDCProviderARN, provider.Url, cfg.OIDCIssuerURL)
+	}
+	return nil, nil
+}
+
+// CheckPermissionsBoundary validates that the permissions boundary policy of the
+// IAM roles exists: creating the roles would fail only in Step 7 otherwise
+func CheckPermissionsBoundary(executor util.CommandExecutor, cfg *config.Config) ([]string, error) {
+	if _, err := util.RunAWSCLI(executor, cfg.AwsProfile, "", "iam", "get-policy", "--policy-arn", cfg.PermissionsBoundary); err != nil {
+		if strings.Contains(err.Error(), "NoSuchEntity") {
+			return nil, fmt.Errorf("permissions boundary policy %s does not exist", cfg.PermissionsBoundary)
+		}
+		return nil, err
+	}
+	return nil, nil
+}
@@ -0,0 +1,122 @@
+package preflight
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/clobrano/openshift-sts-wrapper/pkg/config"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/util"
+)
+
+func TestCheckExternalIAM(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	trusted := `{"Role": {"Arn": "arn:aws:iam::123456789012:role/ocp/%s", "AssumeRolePolicyDocument": {"Statement": [{
+		"Principal": {"Federated": "arn:aws:iam::123456789012:oidc-provider/%s"}}]}}}`
+	ingressCmd := "aws iam get-role --role-name ingress --output json --profile default"
+	registryCmd := "aws iam get-role --role-name registry --output json --profile default"
+
+	tests := []struct {
+		name          string
+		registryRole  string
+		registryError error
+		contains      string
+	}{
+		{"roles trust the provider", fmt.Sprintf(trusted, "registry", "oidc.example.com"), nil, ""},
+		{"role trusts another provider", fmt.Sprintf(trusted, "registry", "other.example.com"), nil, "ocp/registry does not trust oidc-provider/oidc.example.com"},
+		{"missing role", "", fmt.Errorf("An error occurred (NoSuchEntity) when calling the GetRole operation"), "ocp/registry does not exist"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			executor := util.NewMockExecutor()
+			executor.SetOutput(ingressCmd, fmt.Sprintf(trusted, "ingress", "oidc.example.com"))
+			executor.SetOutput(registryCmd, tt.registryRole)
+			if tt.registryError != nil {
+				executor.SetError(registryCmd, tt.registryError)
+			}
+			cfg := &config.Config{
+				AwsProfile: "default",
+				IAMRoles: map[string]string{
+					"openshift-ingress-operator/cloud-credentials":         "arn:aws:iam::123456789012:role/ocp/ingress",
+					"openshift-image-registry/installer-cloud-credentials": "arn:aws:iam::123456789012:role/ocp/registry",
+				},
+				OIDCIssuerURL: "https://oidc.example.com",
+			}
+
+			_, err := CheckExternalIAM(executor, cfg)
+			if tt.contains == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.contains) {
+				t.Errorf("Expected an error containing %q, got %v", tt.contains, err)
+			}
+		})
+	}
+}
+
+func TestCheckOIDCProvider(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	arn := "arn:aws:iam::123456789012:oidc-provider/oidc.example.com"
+	providerCmd := "aws iam get-open-id-connect-provider --open-id-connect-provider-arn " + arn + " --output json --profile default"
+
+	tests := []struct {
+		name     string
+		output   string
+		err      error
+		contains string
+	}{
+		{"provider of the issuer", `{"Url": "oidc.example.com", "ClientIDList": ["openshift", "sts.amazonaws.com"]}`, nil, ""},
+		{"provider of another issuer", `{"Url": "other.example.com"}`, nil, "is for other.example.com"},
+		{"missing provider", "", fmt.Errorf("An error occurred (NoSuchEntity) when calling the GetOpenIDConnectProvider operation"), "does not exist"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			executor := util.NewMockExecutor()
+			executor.SetOutput(providerCmd, tt.output)
+			if tt.err != nil {
+				executor.SetError(providerCmd, tt.err)
+			}
+			cfg := &config.Config{
+				AwsProfile:      "default",
+				OIDCProviderARN: arn,
+				OIDCIssuerURL:   "https://oidc.example.com/",
+			}
+
+			_, err := CheckOIDCProvider(executor, cfg)
+			if tt.contains == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.contains) {
+				t.Errorf("Expected an error containing %q, got %v", tt.contains, err)
+			}
+		})
+	}
+}
+
+func TestCheckPermissionsBoundary(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	cfg := &config.Config{AwsProfile: "default", PermissionsBoundary: "arn:aws:iam::123456789012:policy/boundary"}
+	policyCmd := "aws iam get-policy --policy-arn arn:aws:iam::123456789012:policy/boundary --output json --profile default"
+
+	executor := util.NewMockExecutor()
+	executor.SetOutput(policyCmd, `{"Policy": {"PolicyName": "boundary"}}`)
+	if _, err := CheckPermissionsBoundary(executor, cfg); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	executor.SetError(policyCmd, fmt.Errorf("An error occurred (NoSuchEntity) when calling the GetPolicy operation"))
+	if _, err := CheckPermissionsBoundary(executor, cfg); err == nil || !strings.Contains(err.Error(), "does not exist") {
+		t.Errorf("Expected a missing policy error, got %v", err)
+	}
+}
@@ -0,0 +1,147 @@
+package preflight
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/clobrano/openshift-sts-wrapper/pkg/config"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/util"
+)
+
+// maxAlternatives limits the number of alternative instance types suggested
+const maxAlternatives = 5
+
+type instanceTypeOffering struct {
+	InstanceType string `json:"InstanceType"`
+	Location     string `json:"Location"`
+}
+
+// DescribeInstanceTypeOfferings returns the offerings of the instance types
+// matching the given filter values (wildcards allowed). Offerings are listed per
+// availability zone when zones are given, per region otherwise.
+func DescribeInstanceTypeOfferings(executor util.CommandExecutor, cfg *config.Config, zones []string, instanceTypes ...string) ([]instanceTypeOffering, error) {
+	args := []string{"ec2", "describe-instance-type-offerings"}
+	filters := []string{"Name=instance-type,Values=" + strings.Join(instanceTypes, ",")}
+	if len(zones) > 0 {
+		args = append(args, "--location-type", "availability-zone")
+		filters = append(filters, "Name=location,Values="+strings.Join(zones, ","))
+	} else {
+		args = append(args, "--location-type", "region")
+	}
+	args = append(args, "--filters")
+	args = append(args, filters...)
+
+	output, err := util.RunAWSCLI(executor, cfg.AwsProfile, cfg.AwsRegion, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		InstanceTypeOfferings []instanceTypeOffering `json:"InstanceTypeOfferings"`
+	}
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		return nil, fmt.Errorf("failed to parse describe-instance-type-offerings output: %w", err)
+	}
+	return result.InstanceTypeOfferings, nil
+}
+
+// CheckInstanceTypes validates that the control plane and compute instance types
+// are offered in the region (or in every configured zone), and suggests
+// alternatives of the same size when they are not
+func CheckInstanceTypes(executor util.CommandExecutor, cfg *config.Config) ([]string, error) {
+	if cfg.AwsRegion == "" {
+		return []string{"AWS region not configured yet, skipping instance type validation"}, nil
+	}
+
+	instanceTypes := []string{cfg.ControlPlaneInstanceType()}
+	seen := map[string]bool{instanceTypes[0]: true}
+	for _, pool := range computePools(cfg) {
+		if !seen[pool.instanceType] {
+			seen[pool.instanceType] = true
+			instanceTypes = append(instanceTypes, pool.instanceType)
+		}
+	}
+
+	offerings, err := DescribeInstanceTypeOfferings(executor, cfg, cfg.Zones, instanceTypes...)
+	if err != nil {
+		return nil, err
+	}
+	offered := offeredLocations(offerings)
+
+	var problems []string
+	for _, instanceType := range instanceTypes {
+		missing := missingLocations(offered[instanceType], cfg.AwsRegion, cfg.Zones)
+		if len(missing) == 0 {
+			continue
+		}
+
+		problem := fmt.Sprintf("%s is not offered in %s", instanceType, strings.Join(missing, ", "))
+		if alternatives := suggestInstanceTypes(executor, cfg, instanceType); len(alternatives) > 0 {
+			problem += fmt.Sprintf(" (alternatives: %s)", strings.Join(alternatives, ", "))
+		}
+		problems = append(problems, problem)
+	}
+
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+	return nil, nil
+}
+
+// offeredLocations maps every instance type to the set of locations it is offered in
+func offeredLocations(offerings []instanceTypeOffering) map[string]map[string]bool {
+	offered := map[string]map[string]bool{}
+	for _, offering := range offerings {
+		if offered[offering.InstanceType] == nil {
+			offered[offering.InstanceType] = map[string]bool{}
+		}
+		offered[offering.InstanceType][offering.Location] = true
+	}
+	return offered
+}
+
+// missingLocations returns the requested locations (zones, or the region when
+// no zone is requested) that are not in the offered set
+func missingLocations(offered map[string]bool, region string, zones []string) []string {
+	locations := zones
+	if len(locations) == 0 {
+		locations = []string{region}
+	}
+
+	var missing []string
+	for _, location := range locations {
+		if !offered[location] {
+			missing = append(missing, location)
+		}
+	}
+	return missing
+}
+
+// suggestInstanceTypes returns instance types of the same class and size (e.g.
+// m6i.4xlarge for m5.4xlarge) offered in every requested location. Lookup
+// failures are ignored: suggestions are best effort.
+func suggestInstanceTypes(executor util.CommandExecutor, cfg *config.Config, instanceType string) []string {
+	family, size, ok := strings.Cut(instanceType, ".")
+	if !ok || family == "" {
+		return nil
+	}
+
+	offerings, err := DescribeInstanceTypeOfferings(executor, cfg, cfg.Zones, family[:1]+"*."+size)
+	if err != nil {
+		return nil
+	}
+
+	var alternatives []string
+	for candidate, locations := range offeredLocations(offerings) {
+		if candidate != instanceType && len(missingLocations(locations, cfg.AwsRegion, cfg.Zones)) == 0 {
+			alternatives = append(alternatives, candidate)
+		}
+	}
+	sort.Strings(alternatives)
+	if len(alternatives) > maxAlternatives {
+		alternatives = alternatives[:maxAlternatives]
+	}
+	return alternatives
+}
@@ -0,0 +1,79 @@
+package preflight
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/clobrano/openshift-sts-wrapper/pkg/config"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/util"
+)
+
+func TestCheckInstanceTypes(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	zones := []string{"us-east-1a", "us-east-1b"}
+	cmd := "aws ec2 describe-instance-type-offerings --location-type availability-zone --filters Name=instance-type,Values=m5.4xlarge Name=location,Values=us-east-1a,us-east-1b --output json --profile default --region us-east-1"
+	alternativesCmd := "aws ec2 describe-instance-type-offerings --location-type availability-zone --filters Name=instance-type,Values=m*.4xlarge Name=location,Values=us-east-1a,us-east-1b --output json --profile default --region us-east-1"
+
+	tests := []struct {
+		name        string
+		output      string
+		zones       []string
+		shouldError bool
+	}{
+		{"offered in every zone", `{"InstanceTypeOfferings": [
+			{"InstanceType": "m5.4xlarge", "Location": "us-east-1a"},
+			{"InstanceType": "m5.4xlarge", "Location": "us-east-1b"}]}`, zones, false},
+		{"missing in one zone", `{"InstanceTypeOfferings": [
+			{"InstanceType": "m5.4xlarge", "Location": "us-east-1a"}]}`, zones, true},
+		{"not offered at all", `{"InstanceTypeOfferings": []}`, zones, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			executor := util.NewMockExecutor()
+			executor.SetOutput(cmd, tt.output)
+			executor.SetOutput(alternativesCmd, `{"InstanceTypeOfferings": [
+				{"InstanceType": "m6i.4xlarge", "Location": "us-east-1a"},
+				{"InstanceType": "m6i.4xlarge", "Location": "us-east-1b"},
+				{"InstanceType": "m5a.4xlarge", "Location": "us-east-1a"}]}`)
+			cfg := &config.Config{
+				AwsProfile:   "default",
+				AwsRegion:    "us-east-1",
+				InstanceType: "m5.4xlarge",
+				Zones:        tt.zones,
+			}
+
+			_, err := CheckInstanceTypes(executor, cfg)
+			if tt.shouldError && err == nil {
+				t.Error("Expected error but got nil")
+			}
+			if !tt.shouldError && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			// Only types offered in every requested zone are suggested
+			if err != nil && (!strings.Contains(err.Error(), "alternatives: m6i.4xlarge") || strings.Contains(err.Error(), "m5a")) {
+				t.Errorf("Unexpected alternatives in %q", err.Error())
+			}
+		})
+	}
+}
+
+func TestCheckInstanceTypesPerPool(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	executor := util.NewMockExecutor()
+	executor.SetOutput("aws ec2 describe-instance-type-offerings --location-type region --filters Name=instance-type,Values=m5.2xlarge,m5.xlarge --output json --profile default --region us-east-1",
+		`{"InstanceTypeOfferings": [{"InstanceType": "m5.2xlarge", "Location": "us-east-1"}]}`)
+	cfg := &config.Config{
+		AwsProfile:       "default",
+		AwsRegion:        "us-east-1",
+		ControlPlaneType: "m5.2xlarge",
+		WorkerType:       "m5.xlarge",
+	}
+
+	_, err := CheckInstanceTypes(executor, cfg)
+	if err == nil || !strings.Contains(err.Error(), "m5.xlarge is not offered in us-east-1") {
+		t.Errorf("Expected compute instance type to be reported, got %v", err)
+	}
+}
@@ -0,0 +1,57 @@
+package preflight
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/clobrano/openshift-sts-wrapper/pkg/logger"
+)
+
+// Check is a single validation run before the installation starts. It returns
+// non-fatal warnings, and an error when the installation cannot succeed.
+type Check struct {
+	Name string
+	Run  func() ([]string, error)
+}
+
+// ChecksFailedError lists the preflight checks that failed
+type ChecksFailedError struct {
+	Checks []string
+}
+
+func (e *ChecksFailedError) Error() string {
+	return fmt.Sprintf("preflight checks failed: %s", strings.Join(e.Checks, ", "))
+}
+
+// Failed reports whether the named check is among the failed ones
+func (e *ChecksFailedError) Failed(name string) bool {
+	for _, check := range e.Checks {
+		if check == name {
+			return true
+		}
+	}
+	return false
+}
+
+// RunChecks runs every check, logging the outcome of each one, and returns a
+// *ChecksFailedError listing all the failed checks
+func RunChecks(log *logger.Logger, checks []Check) error {
+	var failed []string
+	for _, check := range checks {
+		warnings, err := check.Run()
+		for _, warning := range warnings {
+			log.Info(fmt.Sprintf("⚠  %s: %s", check.Name, warning))
+		}
+		if err != nil {
+			log.Error(fmt.Sprintf("✗ %s: %v", check.Name, err))
+			failed = append(failed, check.Name)
+			continue
+		}
+		log.Info(fmt.Sprintf("✓ %s", check.Name))
+	}
+
+	if len(failed) > 0 {
+		return &ChecksFailedError{Checks: failed}
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+package preflight
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/clobrano/openshift-sts-wrapper/pkg/logger"
+)
+
+func TestRunChecks(t *testing.T) {
+	log := logger.New(logger.LevelQuiet, nil)
+	checks := []Check{
+		{Name: "Service quotas", Run: func() ([]string, error) { return nil, errors.New("not enough vCPUs") }},
+		{Name: "Base domain", Run: func() ([]string, error) { return []string{"zone is private"}, nil }},
+	}
+
+	err := RunChecks(log, checks)
+	var failed *ChecksFailedError
+	if !errors.As(err, &failed) || !failed.Failed("Service quotas") || failed.Failed("Base domain") {
+		t.Fatalf("Expected only the quotas check to fail, got %v", err)
+	}
+	if err.Error() != "preflight checks failed: Service quotas" {
+		t.Errorf("Unexpected error message: %v", err)
+	}
+
+	if err := RunChecks(log, checks[1:]); err != nil {
+		t.Errorf("Expected warnings not to fail the checks, got %v", err)
+	}
+}
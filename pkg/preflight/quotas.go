@@ -0,0 +1,403 @@
+package preflight
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/clobrano/openshift-sts-wrapper/pkg/config"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/util"
+)
+
+// Service quota codes checked before the installation
+const (
+	quotaStandardVCPUs = "L-1216C47A" // ec2: Running On-Demand Standard (A, C, D, H, I, M, R, T, Z) instances
+	quotaElasticIPs    = "L-0263D0A3" // ec2: EC2-VPC Elastic IPs
+	quotaVPCs          = "L-F678F1CE" // vpc: VPCs per Region
+	quotaNATGateways   = "L-FE5A380F" // vpc: NAT gateways per Availability Zone
+)
+
+// bootstrapVCPUs is the size of the temporary bootstrap machine
+const bootstrapVCPUs = 4
+
+// defaultReplicas is the installer default for both machine pools
+const defaultReplicas = 3
+
+// quotaRequirement is the amount of a quota the installation consumes
+type quotaRequirement struct {
+	name     string
+	service  string
+	code     string
+	required int
+	used     func() (int, error)
+}
+
+// CheckQuotas validates that the EIP, VPC, NAT gateway and on-demand standard
+// vCPU quotas leave enough room for the installation. The resources of the
+// cluster itself, left by a previous deployment, are not counted as used.
+func CheckQuotas(executor util.CommandExecutor, cfg *config.Config) ([]string, error) {
+	if cfg.AwsRegion == "" {
+		return []string{"AWS region not configured yet, skipping quota validation"}, nil
+	}
+
+	requirements, warnings, err := quotaRequirements(executor, cfg)
+	if err != nil {
+		return warnings, err
+	}
+
+	var shortfalls []string
+	for _, req := range requirements {
+		limit, err := getServiceQuota(executor, cfg, req.service, req.code)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("cannot read %s quota, skipping: %v", req.name, err))
+			continue
+		}
+		used, err := req.used()
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("cannot read %s usage, skipping: %v", req.name, err))
+			continue
+		}
+		if available := limit - used; available < req.required {
+			shortfalls = append(shortfalls, fmt.Sprintf("%s: %d required, %d available (%d used of %d), request an increase at %s",
+				req.name, req.required, available, used, limit, quotaRequestURL(cfg.AwsRegion, req.service, req.code)))
+		}
+	}
+
+	if len(shortfalls) > 0 {
+		return warnings, fmt.Errorf("insufficient service quotas:\n  %s", strings.Join(shortfalls, "\n  "))
+	}
+	return warnings, nil
+}
+
+// quotaRequirements returns the quotas consumed by the installation. Installing
+// into existing subnets creates no VPC, NAT gateway or Elastic IP.
+func quotaRequirements(executor util.CommandExecutor, cfg *config.Config) ([]quotaRequirement, []string, error) {
+	var requirements []quotaRequirement
+	var warnings []string
+	ownerTag := clusterOwnerTag(cfg)
+
+	pools := append([]machinePool{{"control plane", replicas(cfg.ControlPlaneReplicas), cfg.ControlPlaneInstanceType()}}, computePools(cfg)...)
+	standard := true
+	var instanceTypes []string
+	for _, pool := range pools {
+		standard = standard && isStandardInstanceType(pool.instanceType)
+		instanceTypes = append(instanceTypes, pool.instanceType)
+	}
+	if standard {
+		vcpus, err := describeVCPUs(executor, cfg, instanceTypes)
+		if err != nil {
+			return nil, warnings, err
+		}
+		required := bootstrapVCPUs
+		for _, pool := range pools {
+			required += pool.count * vcpus[pool.instanceType]
+		}
+		requirements = append(requirements, quotaRequirement{
+			name:     "On-Demand Standard vCPUs",
+			service:  "ec2",
+			code:     quotaStandardVCPUs,
+			required: required,
+			used:     func() (int, error) { return standardVCPUsInUse(executor, cfg, ownerTag) },
+		})
+	} else {
+		warnings = append(warnings, "non-standard instance types configured, skipping vCPU quota validation")
+	}
+
+	if len(cfg.Subnets) > 0 {
+		return requirements, warnings, nil
+	}
+
+	zones := len(cfg.Zones)
+	if zones == 0 {
+		available, err := countAWSResources(executor, cfg, "AvailabilityZones", "",
+			"ec2", "describe-availability-zones", "--filters", "Name=zone-type,Values=availability-zone", "Name=state,Values=available")
+		if err != nil {
+			return nil, warnings, err
+		}
+		zones = available
+	}
+
+	requirements = append(requirements,
+		quotaRequirement{
+			name:     "VPCs",
+			service:  "vpc",
+			code:     quotaVPCs,
+			required: 1,
+			used: func() (int, error) {
+				return countAWSResources(executor, cfg, "Vpcs", ownerTag, "ec2", "describe-vpcs")
+			},
+		},
+		// One NAT gateway, with its Elastic IP, per zone
+		quotaRequirement{
+			name:     "Elastic IPs",
+			service:  "ec2",
+			code:     quotaElasticIPs,
+			required: zones,
+			used: func() (int, error) {
+				return countAWSResources(executor, cfg, "Addresses", ownerTag, "ec2", "describe-addresses")
+			},
+		},
+		quotaRequirement{
+			name:     "NAT gateways per Availability Zone",
+			service:  "vpc",
+			code:     quotaNATGateways,
+			required: 1,
+			used:     func() (int, error) { return natGatewaysPerZone(executor, cfg, ownerTag) },
+		},
+	)
+	return requirements, warnings, nil
+}
+
+func replicas(value *int) int {
+	if value == nil {
+		return defaultReplicas
+	}
+	return *value
+}
+
+// machinePool is a pool of machines of the same instance type
+type machinePool struct {
+	name         string
+	count        int
+	instanceType string
+}
+
+// computePools returns the worker pool followed by the additional compute pools
+func computePools(cfg *config.Config) []machinePool {
+	pools := []machinePool{{"compute", replicas(cfg.WorkerPoolReplicas()), cfg.WorkerInstanceType()}}
+	for _, pool := range cfg.AdditionalComputePools() {
+		pools = append(pools, machinePool{pool.Name + " compute pool", *pool.Replicas, cfg.ComputePoolInstanceType(pool)})
+	}
+	return pools
+}
+
+// isStandardInstanceType reports whether the instance type counts against the
+// on-demand standard instances quota (A, C, D, H, I, M, R, T and Z families)
+func isStandardInstanceType(instanceType string) bool {
+	return instanceType != "" && strings.ContainsRune("acdhimrtz", rune(instanceType[0]))
+}
+
+// quotaRequestURL returns the Service Quotas console page to request an increase
+func quotaRequestURL(region, service, code string) string {
+	return fmt.Sprintf("https://%s.console.aws.amazon.com/servicequotas/home/services/%s/quotas/%s", region, service, code)
+}
+
+// getServiceQuota returns the current value of a service quota
+func getServiceQuota(executor util.CommandExecutor, cfg *config.Config, service, code string) (int, error) {
+	output, err := util.RunAWSCLI(executor, cfg.AwsProfile, cfg.AwsRegion,
+		"service-quotas", "get-service-quota", "--service-code", service, "--quota-code", code)
+	if err != nil {
+		return 0, err
+	}
+
+	var result struct {
+		Quota struct {
+			Value float64 `json:"Value"`
+		} `json:"Quota"`
+	}
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		return 0, fmt.Errorf("failed to parse get-service-quota output: %w", err)
+	}
+	return int(result.Quota.Value), nil
+}
+
+// clusterOwnerTag returns the tag key of the resources of the cluster, from
+// the infra ID in its metadata.json. It is empty before the cluster is
+// deployed.
+func clusterOwnerTag(cfg *config.Config) string {
+	metadata, err := util.ReadClusterMetadata(util.GetClusterPath(cfg.ClusterName, ""))
+	if err != nil || metadata.InfraID == "" {
+		return ""
+	}
+	return util.InfraTagKeyPrefix + metadata.InfraID
+}
+
+// awsTag is a tag of an AWS resource in the aws CLI output
+type awsTag struct {
+	Key string `json:"Key"`
+}
+
+// hasTag reports whether the tags hold the key (never for an empty key)
+func hasTag(tags []awsTag, key string) bool {
+	for _, tag := range tags {
+		if key != "" && tag.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// countAWSResources runs an aws CLI describe command and returns the number of
+// items in the given top-level list, except the ones tagged with ownerTag
+func countAWSResources(executor util.CommandExecutor, cfg *config.Config, key, ownerTag string, args ...string) (int, error) {
+	output, err := util.RunAWSCLI(executor, cfg.AwsProfile, cfg.AwsRegion, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	var result map[string][]struct {
+		Tags []awsTag `json:"Tags"`
+	}
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		return 0, fmt.Errorf("failed to parse %s output: %w", args[1], err)
+	}
+	count := 0
+	for _, item := range result[key] {
+		if !hasTag(item.Tags, ownerTag) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// describeVCPUs returns the default number of vCPUs of each instance type
+func describeVCPUs(executor util.CommandExecutor, cfg *config.Config, instanceTypes []string) (map[string]int, error) {
+	unique := map[string]bool{}
+	for _, instanceType := range instanceTypes {
+		unique[instanceType] = true
+	}
+	args := []string{"ec2", "describe-instance-types", "--instance-types"}
+	args = append(args, sortedKeys(unique)...)
+
+	output, err := util.RunAWSCLI(executor, cfg.AwsProfile, cfg.AwsRegion, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		InstanceTypes []struct {
+			InstanceType string `json:"InstanceType"`
+			VCPUInfo     struct {
+				DefaultVCPUs int `json:"DefaultVCpus"`
+			} `json:"VCpuInfo"`
+		} `json:"InstanceTypes"`
+	}
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		return nil, fmt.Errorf("failed to parse describe-instance-types output: %w", err)
+	}
+
+	vcpus := map[string]int{}
+	for _, info := range result.InstanceTypes {
+		vcpus[info.InstanceType] = info.VCPUInfo.DefaultVCPUs
+	}
+	for instanceType := range unique {
+		if _, ok := vcpus[instanceType]; !ok {
+			return nil, fmt.Errorf("unknown instance type %s", instanceType)
+		}
+	}
+	return vcpus, nil
+}
+
+// standardVCPUsInUse returns the vCPUs of the pending and running on-demand
+// standard instances in the region, except the ones tagged with ownerTag
+func standardVCPUsInUse(executor util.CommandExecutor, cfg *config.Config, ownerTag string) (int, error) {
+	output, err := util.RunAWSCLI(executor, cfg.AwsProfile, cfg.AwsRegion,
+		"ec2", "describe-instances", "--filters", "Name=instance-state-name,Values=pending,running")
+	if err != nil {
+		return 0, err
+	}
+
+	var result struct {
+		Reservations []struct {
+			Instances []struct {
+				InstanceType      string   `json:"InstanceType"`
+				InstanceLifecycle string   `json:"InstanceLifecycle"`
+				Tags              []awsTag `json:"Tags"`
+			} `json:"Instances"`
+		} `json:"Reservations"`
+	}
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		return 0, fmt.Errorf("failed to parse describe-instances output: %w", err)
+	}
+
+	counts := map[string]int{}
+	for _, reservation := range result.Reservations {
+		for _, instance := range reservation.Instances {
+			// Spot instances have their own quota
+			if instance.InstanceLifecycle == "" && isStandardInstanceType(instance.InstanceType) && !hasTag(instance.Tags, ownerTag) {
+				counts[instance.InstanceType]++
+			}
+		}
+	}
+	if len(counts) == 0 {
+		return 0, nil
+	}
+
+	types := make([]string, 0, len(counts))
+	for instanceType := range counts {
+		types = append(types, instanceType)
+	}
+	vcpus, err := describeVCPUs(executor, cfg, types)
+	if err != nil {
+		return 0, err
+	}
+
+	used := 0
+	for instanceType, count := range counts {
+		used += count * vcpus[instanceType]
+	}
+	return used, nil
+}
+
+// natGatewaysPerZone returns the highest number of NAT gateways in a single
+// zone, except the ones tagged with ownerTag
+func natGatewaysPerZone(executor util.CommandExecutor, cfg *config.Config, ownerTag string) (int, error) {
+	output, err := util.RunAWSCLI(executor, cfg.AwsProfile, cfg.AwsRegion,
+		"ec2", "describe-nat-gateways", "--filter", "Name=state,Values=pending,available")
+	if err != nil {
+		return 0, err
+	}
+
+	var result struct {
+		NatGateways []struct {
+			SubnetID string   `json:"SubnetId"`
+			Tags     []awsTag `json:"Tags"`
+		} `json:"NatGateways"`
+	}
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		return 0, fmt.Errorf("failed to parse describe-nat-gateways output: %w", err)
+	}
+	gateways := result.NatGateways[:0]
+	for _, gateway := range result.NatGateways {
+		if !hasTag(gateway.Tags, ownerTag) {
+			gateways = append(gateways, gateway)
+		}
+	}
+	if len(gateways) == 0 {
+		return 0, nil
+	}
+
+	subnetIDs := map[string]bool{}
+	for _, gateway := range gateways {
+		subnetIDs[gateway.SubnetID] = true
+	}
+	subnets, err := DescribeSubnets(executor, cfg, sortedKeys(subnetIDs))
+	if err != nil {
+		return 0, err
+	}
+	subnetZones := map[string]string{}
+	for _, subnet := range subnets {
+		subnetZones[subnet.SubnetID] = subnet.AvailabilityZone
+	}
+
+	perZone := map[string]int{}
+	highest := 0
+	for _, gateway := range gateways {
+		zone := subnetZones[gateway.SubnetID]
+		perZone[zone]++
+		if perZone[zone] > highest {
+			highest = perZone[zone]
+		}
+	}
+	return highest, nil
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
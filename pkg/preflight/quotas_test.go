@@ -0,0 +1,118 @@
+package preflight
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/clobrano/openshift-sts-wrapper/pkg/config"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/util"
+)
+
+const awsSuffix = " --output json --profile default --region us-east-1"
+
+func setQuotaOutputs(executor *util.MockExecutor, vcpuQuota string) {
+	executor.SetOutput("aws service-quotas get-service-quota --service-code ec2 --quota-code L-1216C47A"+awsSuffix, `{"Quota": {"Value": `+vcpuQuota+`}}`)
+	executor.SetOutput("aws service-quotas get-service-quota --service-code ec2 --quota-code L-0263D0A3"+awsSuffix, `{"Quota": {"Value": 5.0}}`)
+	executor.SetOutput("aws service-quotas get-service-quota --service-code vpc --quota-code L-F678F1CE"+awsSuffix, `{"Quota": {"Value": 5.0}}`)
+	executor.SetOutput("aws service-quotas get-service-quota --service-code vpc --quota-code L-FE5A380F"+awsSuffix, `{"Quota": {"Value": 5.0}}`)
+	executor.SetOutput("aws ec2 describe-instance-types --instance-types m5.4xlarge"+awsSuffix,
+		`{"InstanceTypes": [{"InstanceType": "m5.4xlarge", "VCpuInfo": {"DefaultVCpus": 16}}]}`)
+	executor.SetOutput("aws ec2 describe-instances --filters Name=instance-state-name,Values=pending,running"+awsSuffix,
+		`{"Reservations": [{"Instances": [
+			{"InstanceType": "m5.4xlarge"},
+			{"InstanceType": "m5.4xlarge", "InstanceLifecycle": "spot"},
+			{"InstanceType": "p3.2xlarge"}]}]}`)
+	executor.SetOutput("aws ec2 describe-vpcs"+awsSuffix, `{"Vpcs": [{}, {}]}`)
+	executor.SetOutput("aws ec2 describe-addresses"+awsSuffix, `{"Addresses": [{}, {}, {}]}`)
+	executor.SetOutput("aws ec2 describe-nat-gateways --filter Name=state,Values=pending,available"+awsSuffix, `{"NatGateways": []}`)
+}
+
+func TestCheckQuotas(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	tests := []struct {
+		name        string
+		vcpuQuota   string
+		zones       []string
+		subnets     []string
+		shouldError []string
+	}{
+		// 4 bootstrap + 6 * 16 cluster + 16 in use = 116
+		{"enough quota", "116.0", []string{"us-east-1a", "us-east-1b"}, nil, nil},
+		{"not enough vCPUs", "100.0", []string{"us-east-1a"}, nil, []string{"On-Demand Standard vCPUs: 100 required, 84 available"}},
+		// 3 EIPs in use, one per zone is required
+		{"not enough Elastic IPs", "200.0", []string{"us-east-1a", "us-east-1b", "us-east-1c"}, nil, []string{"Elastic IPs: 3 required, 2 available", "servicequotas/home/services/ec2/quotas/L-0263D0A3"}},
+		{"existing subnets need no Elastic IPs", "200.0", []string{"us-east-1a", "us-east-1b", "us-east-1c"}, []string{"subnet-1"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			executor := util.NewMockExecutor()
+			setQuotaOutputs(executor, tt.vcpuQuota)
+			cfg := &config.Config{
+				AwsProfile:   "default",
+				AwsRegion:    "us-east-1",
+				InstanceType: "m5.4xlarge",
+				Zones:        tt.zones,
+				Subnets:      tt.subnets,
+			}
+
+			_, err := CheckQuotas(executor, cfg)
+			if len(tt.shouldError) == 0 {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("Expected error but got nil")
+			}
+			for _, expected := range tt.shouldError {
+				if !strings.Contains(err.Error(), expected) {
+					t.Errorf("Expected %q in error: %v", expected, err)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckQuotasIgnoresClusterResources(t *testing.T) {
+	tmpDir := t.TempDir()
+	originalWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(originalWd)
+
+	clusterDir := util.GetClusterPath("my-cluster", "")
+	os.MkdirAll(clusterDir, 0755)
+	os.WriteFile(filepath.Join(clusterDir, "metadata.json"), []byte(`{"clusterName": "my-cluster", "infraID": "my-cluster-x7k2p"}`), 0644)
+
+	// The previous deployment of the cluster holds 3 EIPs, a VPC and an
+	// instance: only the ones of other clusters count
+	executor := util.NewMockExecutor()
+	setQuotaOutputs(executor, "116.0")
+	owned := `"Tags": [{"Key": "kubernetes.io/cluster/my-cluster-x7k2p", "Value": "owned"}]`
+	executor.SetOutput("aws ec2 describe-addresses"+awsSuffix, `{"Addresses": [{}, {}, {`+owned+`}, {`+owned+`}, {`+owned+`}]}`)
+	executor.SetOutput("aws ec2 describe-vpcs"+awsSuffix, `{"Vpcs": [{}, {}, {}, {}, {`+owned+`}]}`)
+	executor.SetOutput("aws ec2 describe-instances --filters Name=instance-state-name,Values=pending,running"+awsSuffix,
+		`{"Reservations": [{"Instances": [
+			{"InstanceType": "m5.4xlarge"},
+			{"InstanceType": "m5.4xlarge", `+owned+`}]}]}`)
+	cfg := &config.Config{
+		ClusterName:  "my-cluster",
+		AwsProfile:   "default",
+		AwsRegion:    "us-east-1",
+		InstanceType: "m5.4xlarge",
+		Zones:        []string{"us-east-1a", "us-east-1b", "us-east-1c"},
+	}
+	if _, err := CheckQuotas(executor, cfg); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	// Without metadata.json, nothing is known to belong to the cluster
+	os.Remove(filepath.Join(clusterDir, "metadata.json"))
+	if _, err := CheckQuotas(executor, cfg); err == nil {
+		t.Error("Expected the resources to count without metadata.json")
+	}
+}
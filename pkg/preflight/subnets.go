@@ -0,0 +1,124 @@
+package preflight
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/clobrano/openshift-sts-wrapper/pkg/config"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/util"
+)
+
+type ec2Subnet struct {
+	SubnetID            string `json:"SubnetId"`
+	VpcID               string `json:"VpcId"`
+	AvailabilityZone    string `json:"AvailabilityZone"`
+	MapPublicIPOnLaunch bool   `json:"MapPublicIpOnLaunch"`
+	Tags                []struct {
+		Key   string `json:"Key"`
+		Value string `json:"Value"`
+	} `json:"Tags"`
+}
+
+func (s ec2Subnet) tag(key string) (string, bool) {
+	for _, tag := range s.Tags {
+		if tag.Key == key {
+			return tag.Value, true
+		}
+	}
+	return "", false
+}
+
+// DescribeSubnets returns the subnets with the given IDs
+func DescribeSubnets(executor util.CommandExecutor, cfg *config.Config, subnetIDs []string) ([]ec2Subnet, error) {
+	args := append([]string{"ec2", "describe-subnets", "--subnet-ids"}, subnetIDs...)
+	output, err := util.RunAWSCLI(executor, cfg.AwsProfile, cfg.AwsRegion, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Subnets []ec2Subnet `json:"Subnets"`
+	}
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		return nil, fmt.Errorf("failed to parse describe-subnets output: %w", err)
+	}
+	return result.Subnets, nil
+}
+
+// CheckSubnets validates that the configured subnets exist in the region, belong
+// to a single VPC, are not owned by another cluster, and cover the requested zones
+func CheckSubnets(executor util.CommandExecutor, cfg *config.Config) ([]string, error) {
+	if cfg.AwsRegion == "" {
+		return []string{"AWS region not configured yet, skipping subnet validation"}, nil
+	}
+
+	subnets, err := DescribeSubnets(executor, cfg, cfg.Subnets)
+	if err != nil {
+		return nil, err
+	}
+
+	found := map[string]ec2Subnet{}
+	for _, subnet := range subnets {
+		found[subnet.SubnetID] = subnet
+	}
+
+	var warnings []string
+	vpcs := map[string]bool{}
+	zones := map[string]bool{}
+	public, private := 0, 0
+	for _, id := range cfg.Subnets {
+		subnet, ok := found[id]
+		if !ok {
+			return nil, fmt.Errorf("subnet %s not found in region %s", id, cfg.AwsRegion)
+		}
+		vpcs[subnet.VpcID] = true
+		zones[subnet.AvailabilityZone] = true
+
+		for _, tag := range subnet.Tags {
+			if strings.HasPrefix(tag.Key, "kubernetes.io/cluster/") && tag.Value == "owned" {
+				return nil, fmt.Errorf("subnet %s is owned by another cluster (%s)", id, tag.Key)
+			}
+		}
+
+		_, elb := subnet.tag("kubernetes.io/role/elb")
+		_, internalELB := subnet.tag("kubernetes.io/role/internal-elb")
+		switch {
+		case elb || (subnet.MapPublicIPOnLaunch && !internalELB):
+			public++
+		default:
+			private++
+		}
+		if !elb && !internalELB {
+			warnings = append(warnings, fmt.Sprintf("subnet %s has neither the kubernetes.io/role/elb nor the kubernetes.io/role/internal-elb tag", id))
+		}
+	}
+
+	if len(vpcs) > 1 {
+		return warnings, fmt.Errorf("subnets belong to %d different VPCs, they must all be in the same VPC", len(vpcs))
+	}
+	if private == 0 {
+		return warnings, fmt.Errorf("no private subnet found: at least one private subnet is required")
+	}
+	if cfg.Private {
+		if public > 0 {
+			warnings = append(warnings, fmt.Sprintf("%d public subnet(s) configured for a private cluster, only private subnets are needed", public))
+		}
+	} else if public == 0 {
+		return warnings, fmt.Errorf("no public subnet found: external clusters require public subnets")
+	}
+
+	var missing []string
+	for _, zone := range cfg.Zones {
+		if !zones[zone] {
+			missing = append(missing, zone)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return warnings, fmt.Errorf("no subnet in requested zones: %s", strings.Join(missing, ", "))
+	}
+
+	return warnings, nil
+}
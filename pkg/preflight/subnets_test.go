@@ -0,0 +1,88 @@
+package preflight
+
+import (
+	"testing"
+
+	"github.com/clobrano/openshift-sts-wrapper/pkg/config"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/util"
+)
+
+const describeSubnetsOutput = `{"Subnets": [
+	{"SubnetId": "subnet-public", "VpcId": "vpc-1", "AvailabilityZone": "us-east-1a", "MapPublicIpOnLaunch": true,
+	 "Tags": [{"Key": "kubernetes.io/role/elb", "Value": "1"}]},
+	{"SubnetId": "subnet-private", "VpcId": "vpc-1", "AvailabilityZone": "us-east-1b",
+	 "Tags": [{"Key": "kubernetes.io/role/internal-elb", "Value": "1"}]}
+]}`
+
+func TestCheckSubnets(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	cmd := "aws ec2 describe-subnets --subnet-ids subnet-public subnet-private --output json --profile default --region us-east-1"
+
+	tests := []struct {
+		name        string
+		output      string
+		zones       []string
+		shouldError bool
+	}{
+		{"valid subnets", describeSubnetsOutput, []string{"us-east-1a", "us-east-1b"}, false},
+		{"missing zone", describeSubnetsOutput, []string{"us-east-1c"}, true},
+		{"subnet not found", `{"Subnets": [{"SubnetId": "subnet-public", "VpcId": "vpc-1"}]}`, nil, true},
+		{"different VPCs", `{"Subnets": [
+			{"SubnetId": "subnet-public", "VpcId": "vpc-1", "MapPublicIpOnLaunch": true},
+			{"SubnetId": "subnet-private", "VpcId": "vpc-2"}]}`, nil, true},
+		{"owned by another cluster", `{"Subnets": [
+			{"SubnetId": "subnet-public", "VpcId": "vpc-1", "MapPublicIpOnLaunch": true},
+			{"SubnetId": "subnet-private", "VpcId": "vpc-1", "Tags": [{"Key": "kubernetes.io/cluster/other-x7k2p", "Value": "owned"}]}]}`, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			executor := util.NewMockExecutor()
+			executor.SetOutput(cmd, tt.output)
+			cfg := &config.Config{
+				AwsProfile: "default",
+				AwsRegion:  "us-east-1",
+				Subnets:    []string{"subnet-public", "subnet-private"},
+				Zones:      tt.zones,
+			}
+
+			_, err := CheckSubnets(executor, cfg)
+			if tt.shouldError && err == nil {
+				t.Error("Expected error but got none")
+			}
+			if !tt.shouldError && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestCheckSubnetsPrivateCluster(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	executor := util.NewMockExecutor()
+	executor.SetOutput("aws ec2 describe-subnets --subnet-ids subnet-private --output json --profile default --region us-east-1",
+		`{"Subnets": [{"SubnetId": "subnet-private", "VpcId": "vpc-1", "Tags": [{"Key": "kubernetes.io/role/internal-elb", "Value": "1"}]}]}`)
+
+	cfg := &config.Config{AwsProfile: "default", AwsRegion: "us-east-1", Subnets: []string{"subnet-private"}}
+	if _, err := CheckSubnets(executor, cfg); err == nil {
+		t.Error("External cluster without public subnets should fail")
+	}
+
+	cfg.Private = true
+	if _, err := CheckSubnets(executor, cfg); err != nil {
+		t.Errorf("Private cluster with only private subnets should pass: %v", err)
+	}
+}
+
+func TestCheckSubnetsWithoutRegion(t *testing.T) {
+	executor := util.NewMockExecutor()
+	warnings, err := CheckSubnets(executor, &config.Config{Subnets: []string{"subnet-a"}})
+	if err != nil || len(warnings) != 1 {
+		t.Errorf("Expected a single warning and no error, got %v / %v", warnings, err)
+	}
+	if len(executor.Commands) != 0 {
+		t.Error("No AWS call expected without a region")
+	}
+}
@@ -0,0 +1,424 @@
+// Package server exposes installs and cleanups over a small REST API, so that
+// the wrapper can back a self-service portal.
+//
+// Every install or cleanup runs the wrapper itself as a child process, watched
+// by a goroutine: the commands exit the process on failure, and the step
+// journal already persists their progress.
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/clobrano/openshift-sts-wrapper/pkg/config"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/util"
+	"gopkg.in/yaml.v3"
+)
+
+// Run states
+const (
+	RunRunning   = "running"
+	RunSucceeded = "succeeded"
+	RunFailed    = "failed"
+)
+
+// logFollowInterval is how often a followed log is checked for new output
+var logFollowInterval = 500 * time.Millisecond
+
+// clusterNamePattern matches the cluster names OpenShift accepts (DNS labels)
+var clusterNamePattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+// Run is an install or cleanup started by the server
+type Run struct {
+	Command    string          `json:"command"` // install or cleanup
+	Status     string          `json:"status"`
+	StartedAt  time.Time       `json:"startedAt"`
+	FinishedAt *time.Time      `json:"finishedAt,omitempty"`
+	ExitCode   *int            `json:"exitCode,omitempty"`
+	Error      string          `json:"error,omitempty"`
+	Summary    json.RawMessage `json:"summary,omitempty"` // JSON summary printed by the command
+
+	logPath string
+	done    chan struct{}
+}
+
+// ClusterStatus is the state of a cluster returned by the API
+type ClusterStatus struct {
+	Name  string                      `json:"name"`
+	Run   *Run                        `json:"run,omitempty"`   // Latest run started by the server
+	Steps map[string]*util.StepRecord `json:"steps,omitempty"` // Step journal
+}
+
+// maxRequestSize bounds the body of a request
+const maxRequestSize = 1 << 20
+
+// InstallRequest is the body of an install request: the cluster name and the
+// settings of the install, which override the server configuration
+type InstallRequest struct {
+	ClusterName string          `yaml:"clusterName"`
+	Config      InstallSettings `yaml:"config"`
+}
+
+// InstallSettings are the settings of the configuration file a client may set.
+// Clients choose what to install; how it is installed (credentials, files and
+// binaries of the server, hooks, notifications...) is up to the server
+// configuration. The release comes from the update service, so that clients
+// can't make the server run the binaries of any image.
+type InstallSettings struct {
+	Version              string            `yaml:"version,omitempty"`
+	Channel              string            `yaml:"channel,omitempty"`
+	Architecture         string            `yaml:"architecture,omitempty"`
+	AwsRegion            string            `yaml:"awsRegion,omitempty"`
+	BaseDomain           string            `yaml:"baseDomain,omitempty"`
+	InstanceType         string            `yaml:"instanceType,omitempty"`
+	ControlPlaneType     string            `yaml:"controlPlaneType,omitempty"`
+	WorkerType           string            `yaml:"workerType,omitempty"`
+	ControlPlaneReplicas *int              `yaml:"controlPlaneReplicas,omitempty"`
+	WorkerReplicas       *int              `yaml:"workerReplicas,omitempty"`
+	Zones                []string          `yaml:"zones,omitempty"`
+	Private              bool              `yaml:"private,omitempty"`
+	Tags                 map[string]string `yaml:"tags,omitempty"`
+	InstallTimeout       string            `yaml:"installTimeout,omitempty"`
+	ExpiresIn            string            `yaml:"expiresIn,omitempty"`
+}
+
+// config returns the settings as a configuration to merge
+func (s InstallSettings) config() *config.Config {
+	return &config.Config{
+		Version:              s.Version,
+		Channel:              s.Channel,
+		Architecture:         s.Architecture,
+		AwsRegion:            s.AwsRegion,
+		BaseDomain:           s.BaseDomain,
+		InstanceType:         s.InstanceType,
+		ControlPlaneType:     s.ControlPlaneType,
+		WorkerType:           s.WorkerType,
+		ControlPlaneReplicas: s.ControlPlaneReplicas,
+		WorkerReplicas:       s.WorkerReplicas,
+		Zones:                s.Zones,
+		Private:              s.Private,
+		Tags:                 s.Tags,
+		InstallTimeout:       s.InstallTimeout,
+		ExpiresIn:            s.ExpiresIn,
+	}
+}
+
+// Server serves the API. Runs are kept in memory; steps are read from the
+// journal, so their state survives a restart of the server.
+type Server struct {
+	executable string
+	base       *config.Config
+	token      string
+
+	mu   sync.Mutex
+	runs map[string]*Run // Cluster name -> latest run
+}
+
+// New creates a server running the given wrapper executable. base holds the
+// settings shared by every install (e.g. the pull secret and AWS profile), and
+// token, when set, is required as a bearer token by every request.
+func New(executable string, base *config.Config, token string) *Server {
+	if base == nil {
+		base = &config.Config{}
+	}
+	return &Server{executable: executable, base: base, token: token, runs: map[string]*Run{}}
+}
+
+// GetServeDir returns the directory holding the configuration and logs of the
+// runs of a cluster. It is separate from the cluster directory, which install
+// requires not to exist.
+func GetServeDir(clusterName string) string {
+	return filepath.Join("artifacts", "serve", clusterName)
+}
+
+// Handler returns the HTTP handler of the API
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/clusters", s.listClusters)
+	mux.HandleFunc("POST /v1/clusters", s.startInstall)
+	mux.HandleFunc("GET /v1/clusters/{name}", s.getCluster)
+	mux.HandleFunc("GET /v1/clusters/{name}/logs", s.streamLogs)
+	mux.HandleFunc("POST /v1/clusters/{name}/cleanup", s.startCleanup)
+	return s.authenticate(mux)
+}
+
+// authenticate rejects requests without the bearer token, if one is set
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.token != "" {
+			token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+				writeError(w, http.StatusUnauthorized, errors.New("missing or invalid bearer token"))
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) listClusters(w http.ResponseWriter, r *http.Request) {
+	names, err := util.ListClusters()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	// Clusters being installed have no directory until Step 4
+	s.mu.Lock()
+	for name := range s.runs {
+		if !contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	s.mu.Unlock()
+	sort.Strings(names)
+
+	clusters := []ClusterStatus{}
+	for _, name := range names {
+		clusters = append(clusters, s.status(name))
+	}
+	writeJSON(w, http.StatusOK, clusters)
+}
+
+func (s *Server) getCluster(w http.ResponseWriter, r *http.Request) {
+	name, ok := clusterName(w, r)
+	if !ok {
+		return
+	}
+	status := s.status(name)
+	if status.Run == nil && !util.DirExists(util.GetClusterPath(name, "")) {
+		writeError(w, http.StatusNotFound, fmt.Errorf("cluster %s not found", name))
+		return
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+func (s *Server) startInstall(w http.ResponseWriter, r *http.Request) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "application/json" && mediaType != "application/yaml" {
+		writeError(w, http.StatusUnsupportedMediaType, errors.New("install requests must be application/json or application/yaml"))
+		return
+	}
+
+	var request InstallRequest
+	decoder := yaml.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestSize))
+	decoder.KnownFields(true)
+	// JSON is YAML, and decoding YAML applies the keys of the configuration file
+	if err := decoder.Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid install request: %w", err))
+		return
+	}
+	if !clusterNamePattern.MatchString(request.ClusterName) {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid cluster name %q: must be a DNS label", request.ClusterName))
+		return
+	}
+
+	cfg := &config.Config{}
+	cfg.Merge(s.base)
+	cfg.Merge(request.Config.config())
+	if errs := config.ConsistencyErrors(cfg); len(errs) > 0 {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid configuration: %v", errs[0]))
+		return
+	}
+
+	dir := GetServeDir(request.ClusterName)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	configPath := filepath.Join(dir, "config.yaml")
+	if err := config.SaveToFile(configPath, cfg); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	s.start(w, request.ClusterName, "install", "--config", configPath, "--cluster-name", request.ClusterName, "--non-interactive", "--name-suffix", config.NameSuffixNone)
+}
+
+func (s *Server) startCleanup(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if !clusterNamePattern.MatchString(name) || !util.DirExists(util.GetClusterPath(name, "")) {
+		writeError(w, http.StatusNotFound, fmt.Errorf("cluster %s not found", name))
+		return
+	}
+
+	// The cluster directory is kept, so that the cluster and its journal can still be looked up
+	args := []string{"--cluster-name", name, "--yes"}
+	// Clean up with the configuration of the install, e.g. its AWS profile
+	if configPath := filepath.Join(GetServeDir(name), "config.yaml"); util.FileExists(configPath) {
+		args = append(args, "--config", configPath)
+	}
+	s.start(w, name, "cleanup", args...)
+}
+
+// start runs a command of the wrapper against a cluster and replies with the
+// status of the cluster
+func (s *Server) start(w http.ResponseWriter, clusterName, command string, args ...string) {
+	s.mu.Lock()
+	if run, ok := s.runs[clusterName]; ok && run.Status == RunRunning {
+		s.mu.Unlock()
+		writeError(w, http.StatusConflict, fmt.Errorf("%s of cluster %s is still running", run.Command, clusterName))
+		return
+	}
+
+	run := &Run{
+		Command:   command,
+		Status:    RunRunning,
+		StartedAt: time.Now().UTC(),
+		logPath:   filepath.Join(GetServeDir(clusterName), command+".log"),
+		done:      make(chan struct{}),
+	}
+	if err := s.launch(run, append([]string{command, "--output", "json"}, args...)); err != nil {
+		s.mu.Unlock()
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	s.runs[clusterName] = run
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusAccepted, s.status(clusterName))
+}
+
+// launch starts the child process of a run and a goroutine recording its outcome.
+// The logs go to the log file and the JSON summary, printed on stdout, to the run.
+func (s *Server) launch(run *Run, args []string) error {
+	if err := os.MkdirAll(filepath.Dir(run.logPath), 0700); err != nil {
+		return err
+	}
+	logFile, err := os.OpenFile(run.logPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create log file: %w", err)
+	}
+
+	var summary strings.Builder
+	cmd := exec.Command(s.executable, args...)
+	cmd.Stdout = &summary
+	cmd.Stderr = logFile
+	if err := cmd.Start(); err != nil {
+		logFile.Close()
+		return fmt.Errorf("failed to start %s: %w", args[0], err)
+	}
+
+	go func() {
+		err := cmd.Wait()
+		logFile.Close()
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		finishedAt := time.Now().UTC()
+		run.FinishedAt = &finishedAt
+		run.Status = RunSucceeded
+		if err != nil {
+			run.Status = RunFailed
+			run.Error = err.Error()
+		}
+		code := cmd.ProcessState.ExitCode()
+		run.ExitCode = &code
+		if json.Valid([]byte(summary.String())) {
+			run.Summary = json.RawMessage(summary.String())
+		}
+		close(run.done)
+	}()
+	return nil
+}
+
+// streamLogs writes the log of the latest run of a cluster. With ?follow=true
+// the response streams new output until the run ends.
+func (s *Server) streamLogs(w http.ResponseWriter, r *http.Request) {
+	name, ok := clusterName(w, r)
+	if !ok {
+		return
+	}
+	s.mu.Lock()
+	run, ok := s.runs[name]
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no run of cluster %s", name))
+		return
+	}
+
+	file, err := os.Open(run.logPath)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	defer file.Close()
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	follow := r.URL.Query().Get("follow") == "true"
+	flusher, _ := w.(http.Flusher)
+	for {
+		if _, err := io.Copy(w, file); err != nil || !follow {
+			return
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+
+		select {
+		case <-run.done:
+			io.Copy(w, file)
+			return
+		case <-r.Context().Done():
+			return
+		case <-time.After(logFollowInterval):
+		}
+	}
+}
+
+// status returns the state of a cluster: its latest run and step journal
+func (s *Server) status(clusterName string) ClusterStatus {
+	status := ClusterStatus{Name: clusterName}
+	s.mu.Lock()
+	if run, ok := s.runs[clusterName]; ok {
+		copied := *run
+		status.Run = &copied
+	}
+	s.mu.Unlock()
+	if journal, err := util.ReadJournal(clusterName); err == nil {
+		status.Steps = journal.Steps
+	}
+	return status
+}
+
+// clusterName returns the cluster name of the request path, replying with an
+// error when it is not a valid cluster name
+func clusterName(w http.ResponseWriter, r *http.Request) (string, bool) {
+	name := r.PathValue("name")
+	if !clusterNamePattern.MatchString(name) {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid cluster name %q: must be a DNS label", name))
+		return "", false
+	}
+	return name, true
+}
+
+func writeJSON(w http.ResponseWriter, code int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(value)
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, map[string]string{"error": err.Error()})
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
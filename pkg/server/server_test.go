@@ -0,0 +1,198 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/clobrano/openshift-sts-wrapper/pkg/config"
+)
+
+// fakeWrapper writes a script standing for the wrapper executable: it records
+// its arguments, logs to stderr and prints a JSON summary
+func fakeWrapper(t *testing.T, exitCode string) string {
+	path := filepath.Join(t.TempDir(), "openshift-sts-wrapper")
+	script := `#!/bin/sh
+echo "$@" > args
+echo "[Step 1] Extract credentials requests" >&2
+echo '{"steps": []}'
+exit ` + exitCode + "\n"
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatalf("Failed to write fake wrapper: %v", err)
+	}
+	return path
+}
+
+func setupServer(t *testing.T, exitCode, token string) (*Server, *httptest.Server) {
+	tmpDir := t.TempDir()
+	originalWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	t.Cleanup(func() { os.Chdir(originalWd) })
+
+	s := New(fakeWrapper(t, exitCode), &config.Config{PullSecretPath: "/etc/pull-secret.json"}, token)
+	ts := httptest.NewServer(s.Handler())
+	t.Cleanup(ts.Close)
+	return s, ts
+}
+
+func waitForRun(t *testing.T, s *Server, clusterName string) {
+	s.mu.Lock()
+	run := s.runs[clusterName]
+	s.mu.Unlock()
+	select {
+	case <-run.done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("Run did not finish")
+	}
+}
+
+func getStatus(t *testing.T, url string) ClusterStatus {
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	var status ClusterStatus
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		t.Fatalf("Failed to decode status: %v", err)
+	}
+	return status
+}
+
+func TestInstall(t *testing.T) {
+	s, ts := setupServer(t, "0", "")
+
+	body := `{"clusterName": "test", "config": {"version": "4.15.0", "awsRegion": "us-east-2"}}`
+	resp, err := http.Post(ts.URL+"/v1/clusters", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("Expected 202, got %d", resp.StatusCode)
+	}
+	waitForRun(t, s, "test")
+
+	args, _ := os.ReadFile("args")
+	expected := "install --output json --config artifacts/serve/test/config.yaml --cluster-name test --non-interactive --name-suffix none"
+	if strings.TrimSpace(string(args)) != expected {
+		t.Errorf("Expected args %q, got %q", expected, args)
+	}
+
+	// The request settings are merged over the server configuration
+	cfg, err := config.LoadFromFile("artifacts/serve/test/config.yaml")
+	if err != nil {
+		t.Fatalf("Failed to load run configuration: %v", err)
+	}
+	if cfg.Version != "4.15.0" || cfg.AwsRegion != "us-east-2" || cfg.PullSecretPath != "/etc/pull-secret.json" {
+		t.Errorf("Unexpected run configuration: %+v", cfg)
+	}
+
+	status := getStatus(t, ts.URL+"/v1/clusters/test")
+	if status.Run == nil || status.Run.Status != RunSucceeded || *status.Run.ExitCode != 0 {
+		t.Fatalf("Expected a succeeded run, got %+v", status.Run)
+	}
+	if string(status.Run.Summary) != `{"steps":[]}` {
+		t.Errorf("Expected the JSON summary of the run, got %s", status.Run.Summary)
+	}
+
+	resp, err = http.Get(ts.URL + "/v1/clusters/test/logs?follow=true")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	logs, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(logs), "[Step 1] Extract credentials requests") {
+		t.Errorf("Expected the run log, got %q", logs)
+	}
+}
+
+func TestInstallFailure(t *testing.T) {
+	s, ts := setupServer(t, "3", "")
+
+	resp, err := http.Post(ts.URL+"/v1/clusters", "application/json", strings.NewReader(`{"clusterName": "test"}`))
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	waitForRun(t, s, "test")
+
+	status := getStatus(t, ts.URL+"/v1/clusters/test")
+	if status.Run == nil || status.Run.Status != RunFailed || *status.Run.ExitCode != 3 {
+		t.Errorf("Expected a failed run with exit code 3, got %+v", status.Run)
+	}
+}
+
+func TestInvalidRequests(t *testing.T) {
+	_, ts := setupServer(t, "0", "")
+
+	tests := []struct {
+		name, path, contentType, body string
+		expected                      int
+	}{
+		{"unknown config key", "/v1/clusters", "application/json", `{"clusterName": "test", "config": {"awsRegoin": "us-east-2"}}`, http.StatusBadRequest},
+		{"server-only config key", "/v1/clusters", "application/json", `{"clusterName": "test", "config": {"hooks": {"onFailure": ["touch pwned"]}}}`, http.StatusBadRequest},
+		{"release image", "/v1/clusters", "application/json", `{"clusterName": "test", "config": {"releaseImage": "quay.io/evil/release:latest"}}`, http.StatusBadRequest},
+		{"invalid cluster name", "/v1/clusters", "application/json", `{"clusterName": "../test"}`, http.StatusBadRequest},
+		{"inconsistent config", "/v1/clusters", "application/json", `{"clusterName": "test", "config": {"installTimeout": "soon"}}`, http.StatusBadRequest},
+		{"form content type", "/v1/clusters", "application/x-www-form-urlencoded", `{"clusterName": "test"}`, http.StatusUnsupportedMediaType},
+		{"cleanup of unknown cluster", "/v1/clusters/missing/cleanup", "application/json", ``, http.StatusNotFound},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resp, err := http.Post(ts.URL+test.path, test.contentType, strings.NewReader(test.body))
+			if err != nil {
+				t.Fatalf("Request failed: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != test.expected {
+				t.Errorf("Expected %d, got %d", test.expected, resp.StatusCode)
+			}
+		})
+	}
+}
+
+func TestInvalidClusterName(t *testing.T) {
+	_, ts := setupServer(t, "0", "")
+
+	for _, path := range []string{"/v1/clusters/Not_A_Name", "/v1/clusters/Not_A_Name/logs"} {
+		resp, err := http.Get(ts.URL + path)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %d", path, resp.StatusCode)
+		}
+	}
+}
+
+func TestToken(t *testing.T) {
+	_, ts := setupServer(t, "0", "secret")
+
+	resp, err := http.Get(ts.URL + "/v1/clusters")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without token, got %d", resp.StatusCode)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, ts.URL+"/v1/clusters", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected 200 with token, got %d", resp.StatusCode)
+	}
+}
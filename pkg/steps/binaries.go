@@ -0,0 +1,93 @@
+package steps
+
+import (
+	"fmt"
+
+	"github.com/clobrano/openshift-sts-wrapper/pkg/config"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/util"
+)
+
+// ExternalBinary returns the binary configured in binaries in place of the one
+// step num (2 or 3) extracts, or "" if there is none
+func ExternalBinary(cfg *config.Config, stepNum int) string {
+	switch stepNum {
+	case 2:
+		return cfg.Binaries.OpenshiftInstall
+	case 3:
+		return cfg.Binaries.Ccoctl
+	}
+	return ""
+}
+
+// BinaryPath returns the openshift-install or ccoctl binary of the release
+// being installed: the one configured in binaries, or else the one Step 2 or 3
+// extracts into the shared artifacts
+func BinaryPath(cfg *config.Config, versionArch, name string) string {
+	configured := ""
+	switch name {
+	case "openshift-install":
+		configured = ExternalBinary(cfg, 2)
+	case "ccoctl":
+		configured = ExternalBinary(cfg, 3)
+	}
+	if configured != "" {
+		return configured
+	}
+	return util.GetSharedBinaryPath(versionArch, name)
+}
+
+// VerifyExternalBinaries checks the binaries configured in place of the ones
+// of Steps 2 and 3, which are then skipped: they must run and, for
+// openshift-install, be built for the release (by its version, and by its
+// digest with verifyBinaries)
+func VerifyExternalBinaries(cfg *config.Config, executor util.CommandExecutor) error {
+	versionArch, err := util.ExtractVersionArch(cfg.ReleaseImage)
+	if err != nil {
+		return err
+	}
+	for _, num := range []int{2, 3} {
+		if err := verifyExternalBinary(cfg, executor, versionArch, num); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// verifyExternalBinary checks the binary configured in place of the one of
+// step num, if any
+func verifyExternalBinary(cfg *config.Config, executor util.CommandExecutor, versionArch string, num int) error {
+	binary := ExternalBinary(cfg, num)
+	if binary == "" {
+		return nil
+	}
+	if !util.FileExists(binary) {
+		return fmt.Errorf("binary %s not found", binary)
+	}
+	if num == 3 {
+		return util.VerifyCcoctl(executor, binary)
+	}
+	if err := util.VerifyInstallerVersion(executor, binary, versionArch); err != nil {
+		return err
+	}
+	if cfg.VerifyBinaries {
+		if err := util.VerifyInstallerRelease(executor, binary, cfg.ReleaseDigest); err != nil {
+			return fmt.Errorf("openshift-install verification failed: %w", err)
+		}
+	}
+	return nil
+}
+
+// clientsMirror is where the client tarballs and their checksums are
+// downloaded from (the official mirror when empty)
+var clientsMirror = ""
+
+// verifyClientChecksum checks a binary extracted from the release against the
+// checksums published for the clients of the release (signed by the release
+// signing key, when configured), commandOS selecting the tarball of the host
+func verifyClientChecksum(cfg *config.Config, executor util.CommandExecutor, versionArch, command, commandOS, path string) error {
+	checksums, err := util.ClientChecksums(executor, clientsMirror, cfg.ReleaseSigningKey, versionArch)
+	if err != nil {
+		return err
+	}
+	return util.VerifyClientBinary(clientsMirror, versionArch, checksums, util.ClientTarball(command, commandOS, versionArch), path)
+}
@@ -0,0 +1,74 @@
+package steps
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/clobrano/openshift-sts-wrapper/pkg/config"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/util"
+)
+
+func TestVerifyExternalBinaries(t *testing.T) {
+	dir := t.TempDir()
+	installBin := filepath.Join(dir, "openshift-install")
+	ccoctlBin := filepath.Join(dir, "ccoctl")
+	os.WriteFile(installBin, []byte("fake"), 0755)
+	os.WriteFile(ccoctlBin, []byte("fake"), 0755)
+	cfg := &config.Config{
+		ReleaseImage: "quay.io/test:4.14.0-x86_64",
+		Binaries:     config.BinariesConfig{OpenshiftInstall: installBin, Ccoctl: ccoctlBin},
+	}
+
+	executor := util.NewMockExecutor()
+	executor.SetOutput(installBin+" version", installBin+" 4.14.0\nrelease image quay.io/test@sha256:1111\n")
+	if err := VerifyExternalBinaries(cfg, executor); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !executor.WasExecuted(ccoctlBin + " --help") {
+		t.Error("Expected ccoctl to be run")
+	}
+
+	executor.SetOutput(installBin+" version", installBin+" 4.13.0\nrelease image quay.io/test@sha256:1111\n")
+	if err := VerifyExternalBinaries(cfg, executor); err == nil {
+		t.Error("Expected an error for an openshift-install of another release")
+	}
+
+	executor.SetOutput(installBin+" version", installBin+" 4.14.0\nrelease image quay.io/test@sha256:1111\n")
+	executor.SetError(ccoctlBin+" --help", errors.New("exec format error"))
+	if err := VerifyExternalBinaries(cfg, executor); err == nil {
+		t.Error("Expected an error for a ccoctl that does not run")
+	}
+
+	cfg.Binaries.Ccoctl = filepath.Join(dir, "missing")
+	if err := VerifyExternalBinaries(cfg, util.NewMockExecutor()); err == nil {
+		t.Error("Expected an error for a missing binary")
+	}
+}
+
+func TestExternalBinariesSkipExtraction(t *testing.T) {
+	tmpDir := t.TempDir()
+	originalWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(originalWd)
+
+	cfg := &config.Config{
+		ReleaseImage: "quay.io/test:4.14.0-x86_64",
+		Binaries:     config.BinariesConfig{Ccoctl: "/opt/mirror/bin/ccoctl"},
+	}
+	detector := NewDetector(cfg)
+	if !detector.ShouldSkipStep(3) {
+		t.Error("Step 3 should be skipped when ccoctl is configured")
+	}
+	if detector.ShouldSkipStep(2) {
+		t.Error("Step 2 should not be skipped without a configured or extracted openshift-install")
+	}
+
+	if path := BinaryPath(cfg, "4.14.0-x86_64", "ccoctl"); path != "/opt/mirror/bin/ccoctl" {
+		t.Errorf("Expected the configured ccoctl, got %s", path)
+	}
+	if path := BinaryPath(cfg, "4.14.0-x86_64", "openshift-install"); path != util.GetSharedBinaryPath("4.14.0-x86_64", "openshift-install") {
+		t.Errorf("Expected the shared openshift-install, got %s", path)
+	}
+}
@@ -0,0 +1,215 @@
+package steps
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/clobrano/openshift-sts-wrapper/pkg/config"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/logger"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/util"
+)
+
+// RefreshCredentials prepares the IAM roles of an STS cluster for an upgrade:
+// it compares the CredentialsRequests of the installed and target releases and
+// runs ccoctl for the new or changed ones (and for roles that are missing),
+// applies the resulting secrets and marks the cluster as upgradeable to the
+// target version. The cluster's release is cfg.ReleaseImage.
+type RefreshCredentials struct {
+	*BaseStep
+	targetRelease     string
+	targetVersionArch string
+	DryRun            bool // Only report the changes
+}
+
+func NewRefreshCredentials(cfg *config.Config, log *logger.Logger, executor util.CommandExecutor, targetRelease string) (*RefreshCredentials, error) {
+	base, err := newBaseStep(cfg, log, executor)
+	if err != nil {
+		return nil, err
+	}
+	targetVersionArch, err := util.ExtractVersionArch(targetRelease)
+	if err != nil {
+		return nil, err
+	}
+	return &RefreshCredentials{BaseStep: base, targetRelease: targetRelease, targetVersionArch: targetVersionArch}, nil
+}
+
+func (s *RefreshCredentials) Name() string {
+	return "Refresh credentials"
+}
+
+func (s *RefreshCredentials) Execute() error {
+	kubeconfigPath := util.GetKubeconfigPath(s.cfg.ClusterName)
+	if !util.FileExists(kubeconfigPath) {
+		return fmt.Errorf("kubeconfig not found at %s - cluster may not have been deployed successfully", kubeconfigPath)
+	}
+	if s.cfg.AwsRegion == "" {
+		return fmt.Errorf("AWS region is required")
+	}
+
+	resources, err := util.ReadCcoctlResources(util.GetClusterPath(s.cfg.ClusterName, "ccoctl-output/manifests"), s.cfg.CcoctlName())
+	if err != nil {
+		return err
+	}
+	if resources.OIDCProviderARN == "" {
+		return fmt.Errorf("could not find the OIDC provider of the cluster in the ccoctl output")
+	}
+
+	// Extract what the installed and the target releases need, unless cached
+	targetCfg := *s.cfg
+	targetCfg.ReleaseImage = s.targetRelease
+	targetCfg.ReleaseDigest = ""
+	if err := EnsureCredentialsRequests(s.cfg, s.log, s.executor); err != nil {
+		return err
+	}
+	if err := EnsureCredentialsRequests(&targetCfg, s.log, s.executor); err != nil {
+		return err
+	}
+	if err := s.ensureCcoctl(&targetCfg); err != nil {
+		return err
+	}
+
+	current, err := util.ReadCredentialsRequests(util.GetSharedCredReqsPath(s.versionArch))
+	if err != nil {
+		return err
+	}
+	target, err := util.ReadCredentialsRequests(util.GetSharedCredReqsPath(s.targetVersionArch))
+	if err != nil {
+		return err
+	}
+	diff := util.DiffCredentialsRequests(current, target)
+
+	// Unchanged requests still need a role, e.g. if it was deleted by hand
+	var missing []util.CredentialsRequest
+	for _, request := range diff.Unchanged {
+		exists, err := util.RoleExists(s.executor, s.cfg.AwsProfile, request.RoleName(s.cfg.CcoctlName()))
+		if err != nil {
+			return err
+		}
+		if !exists {
+			missing = append(missing, request)
+		}
+	}
+
+	refresh := append(append(append([]util.CredentialsRequest{}, diff.Added...), diff.Changed...), missing...)
+	s.logPlan(diff, missing)
+	if s.DryRun {
+		return nil
+	}
+
+	envVars := []string{fmt.Sprintf("KUBECONFIG=%s", kubeconfigPath)}
+	if len(refresh) > 0 {
+		if err := s.createRoles(refresh, resources.OIDCProviderARN, envVars); err != nil {
+			return err
+		}
+	} else {
+		s.log.Info("✓ IAM roles are up to date")
+	}
+
+	// The Cloud Credential Operator blocks upgrades of manual mode clusters
+	// until the credentials are marked as updated for the target version
+	version := util.ReleaseVersion(s.targetVersionArch)
+	if err := util.RunCommandWithEnv(s.executor, envVars, "oc", "annotate", "cloudcredential.operator.openshift.io/cluster",
+		"cloudcredential.openshift.io/upgradeable-to="+version, "--overwrite"); err != nil {
+		return fmt.Errorf("failed to mark the cluster as upgradeable to %s: %w", version, err)
+	}
+	s.log.Info(fmt.Sprintf("✓ Cluster credentials are ready for %s", version))
+	return nil
+}
+
+// logPlan reports the CredentialsRequests that changed between the releases
+func (s *RefreshCredentials) logPlan(diff util.CredentialsDiff, missing []util.CredentialsRequest) {
+	s.log.Info(fmt.Sprintf("CredentialsRequests %s -> %s:", s.versionArch, s.targetVersionArch))
+	for _, request := range diff.Added {
+		s.log.Info(fmt.Sprintf("  + %s (new role %s)", request.Key(), request.RoleName(s.cfg.CcoctlName())))
+	}
+	for _, request := range diff.Changed {
+		s.log.Info(fmt.Sprintf("  ~ %s (update role %s)", request.Key(), request.RoleName(s.cfg.CcoctlName())))
+	}
+	for _, request := range missing {
+		s.log.Info(fmt.Sprintf("  ! %s (missing role %s)", request.Key(), request.RoleName(s.cfg.CcoctlName())))
+	}
+	for _, request := range diff.Removed {
+		s.log.Info(fmt.Sprintf("  - %s (role %s is kept, delete it after the upgrade)", request.Key(), request.RoleName(s.cfg.CcoctlName())))
+	}
+	s.log.Info(fmt.Sprintf("  %d unchanged", len(diff.Unchanged)-len(missing)))
+}
+
+// createRoles runs ccoctl for the given CredentialsRequests only, then applies
+// the credentials secrets it generated to the cluster
+func (s *RefreshCredentials) createRoles(requests []util.CredentialsRequest, oidcProviderARN string, envVars []string) error {
+	requestsDir := util.GetClusterPath(s.cfg.ClusterName, "credreqs-"+s.targetVersionArch)
+	outputDir := util.GetClusterPath(s.cfg.ClusterName, "ccoctl-output-"+s.targetVersionArch)
+	for _, dir := range []string{requestsDir, outputDir} {
+		if err := os.RemoveAll(dir); err != nil {
+			return fmt.Errorf("failed to clean %s: %w", dir, err)
+		}
+	}
+	if err := util.EnsureDir(requestsDir); err != nil {
+		return fmt.Errorf("failed to create credentials requests directory: %w", err)
+	}
+	sourceDir := util.GetSharedCredReqsPath(s.targetVersionArch)
+	for _, request := range requests {
+		if err := util.CopyFile(filepath.Join(sourceDir, request.File), filepath.Join(requestsDir, request.File)); err != nil {
+			return fmt.Errorf("failed to copy %s: %w", request.File, err)
+		}
+	}
+
+	s.log.Info(fmt.Sprintf("Creating or updating %d IAM roles...", len(requests)))
+	if err := s.createIAMRoles(util.GetSharedBinaryPath(s.targetVersionArch, "ccoctl"), requestsDir, oidcProviderARN, outputDir); err != nil {
+		return err
+	}
+
+	manifestsDir := filepath.Join(outputDir, "manifests")
+	if len(s.cfg.Tags) > 0 {
+		created, err := util.ReadCcoctlResources(manifestsDir, s.cfg.CcoctlName())
+		if err != nil {
+			return err
+		}
+		roles := &util.CcoctlResources{RoleNames: created.RoleNames}
+		if err := util.TagCcoctlResources(s.executor, s.cfg.AwsProfile, s.cfg.AwsRegion, roles, s.cfg.Tags); err != nil {
+			return err
+		}
+	}
+
+	if err := util.RunCommandWithEnv(s.executor, envVars, "oc", "apply", "-f", manifestsDir); err != nil {
+		return fmt.Errorf("failed to apply credentials secrets: %w", err)
+	}
+	s.log.Info(fmt.Sprintf("✓ %d IAM roles refreshed", len(requests)))
+	return nil
+}
+
+// EnsureCredentialsRequests extracts the CredentialsRequests of the release of
+// cfg, unless already extracted
+func EnsureCredentialsRequests(cfg *config.Config, log *logger.Logger, executor util.CommandExecutor) error {
+	versionArch, err := util.ExtractVersionArch(cfg.ReleaseImage)
+	if err != nil {
+		return err
+	}
+	if util.DirExistsWithFiles(util.GetSharedCredReqsPath(versionArch)) {
+		return nil
+	}
+	log.Info(fmt.Sprintf("Extracting credentials requests of %s...", versionArch))
+	step, err := NewStep1(cfg, log, executor)
+	if err != nil {
+		return err
+	}
+	return step.Execute()
+}
+
+// ensureCcoctl extracts the ccoctl binary of a release, unless already extracted
+func (s *RefreshCredentials) ensureCcoctl(cfg *config.Config) error {
+	ccoctlPath := util.GetSharedBinaryPath(s.targetVersionArch, "ccoctl")
+	if util.FileExists(ccoctlPath) {
+		return nil
+	}
+	if err := util.EnsureDir(filepath.Dir(ccoctlPath)); err != nil {
+		return fmt.Errorf("failed to create bin directory: %w", err)
+	}
+	s.log.Info(fmt.Sprintf("Extracting ccoctl of %s...", s.targetVersionArch))
+	step, err := NewStep3(cfg, s.log, s.executor)
+	if err != nil {
+		return err
+	}
+	return step.Execute()
+}
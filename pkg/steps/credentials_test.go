@@ -0,0 +1,108 @@
+package steps
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/clobrano/openshift-sts-wrapper/pkg/config"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/logger"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/util"
+)
+
+func writeCredentialsRequest(dir, name, action string) {
+	os.MkdirAll(dir, 0755)
+	os.WriteFile(filepath.Join(dir, name+".yaml"), []byte(fmt.Sprintf(`kind: CredentialsRequest
+metadata:
+  name: %s
+  namespace: openshift-cloud-credential-operator
+spec:
+  providerSpec:
+    statementEntries:
+    - action:
+      - %s
+  secretRef:
+    name: %s-credentials
+    namespace: openshift-%s
+`, name, action, name, name)), 0644)
+}
+
+// setupRefreshCluster creates a deployed cluster installed with 4.12.0, and
+// the credentials requests of 4.12.0 and 4.13.0 (see setupDeployedCluster)
+func setupRefreshCluster(t *testing.T, durations map[*time.Duration]time.Duration) *util.MockExecutor {
+	executor := setupDeployedCluster(t, durations)
+	os.MkdirAll("artifacts/clusters/test-cluster/ccoctl-output/manifests", 0755)
+	os.WriteFile("artifacts/clusters/test-cluster/ccoctl-output/manifests/cluster-authentication-02-config.yaml",
+		[]byte("spec:\n  serviceAccountIssuer: https://test-cluster-oidc.s3.us-east-2.amazonaws.com\n"), 0644)
+	os.WriteFile("artifacts/clusters/test-cluster/ccoctl-output/manifests/ebs-credentials.yaml",
+		[]byte("role_arn = arn:aws:iam::123456789012:role/test-cluster-openshift-ebs-ebs-credentials\n"), 0644)
+
+	writeCredentialsRequest("artifacts/shared/4.12.0-x86_64/credreqs", "ebs", "ec2:AttachVolume")
+	writeCredentialsRequest("artifacts/shared/4.12.0-x86_64/credreqs", "registry", "s3:CreateBucket")
+	writeCredentialsRequest("artifacts/shared/4.13.0-x86_64/credreqs", "ebs", "ec2:AttachVolume")
+	writeCredentialsRequest("artifacts/shared/4.13.0-x86_64/credreqs", "registry", "s3:PutBucketTagging")
+	writeCredentialsRequest("artifacts/shared/4.13.0-x86_64/credreqs", "new", "ec2:DescribeRegions")
+	os.MkdirAll("artifacts/shared/4.13.0-x86_64/bin", 0755)
+	os.WriteFile("artifacts/shared/4.13.0-x86_64/bin/ccoctl", []byte("ccoctl"), 0755)
+	return executor
+}
+
+func TestRefreshCredentials(t *testing.T) {
+	executor := setupRefreshCluster(t, nil)
+
+	cfg := &config.Config{
+		ReleaseImage: "quay.io/test:4.12.0-x86_64",
+		ClusterName:  "test-cluster",
+		AwsRegion:    "us-east-2",
+	}
+	step, err := NewRefreshCredentials(cfg, logger.New(logger.LevelQuiet, nil), executor, "quay.io/test:4.13.0-x86_64")
+	if err != nil {
+		t.Fatalf("Failed to create step: %v", err)
+	}
+	if err := step.Execute(); err != nil {
+		t.Fatalf("Step execution failed: %v", err)
+	}
+
+	// Only the new and changed requests are passed to ccoctl
+	entries, _ := os.ReadDir("artifacts/clusters/test-cluster/credreqs-4.13.0-x86_64")
+	if len(entries) != 2 || entries[0].Name() != "new.yaml" || entries[1].Name() != "registry.yaml" {
+		t.Errorf("Expected new.yaml and registry.yaml to be refreshed, got %v", entries)
+	}
+	if !executor.WasExecutedContaining("ccoctl aws create-iam-roles --name test-cluster --region us-east-2 --credentials-requests-dir artifacts/clusters/test-cluster/credreqs-4.13.0-x86_64 " +
+		"--identity-provider-arn arn:aws:iam::123456789012:oidc-provider/test-cluster-oidc.s3.us-east-2.amazonaws.com") {
+		t.Errorf("Expected ccoctl create-iam-roles to be executed, got %v", executor.Commands)
+	}
+	if !executor.WasExecuted("oc apply -f artifacts/clusters/test-cluster/ccoctl-output-4.13.0-x86_64/manifests") {
+		t.Error("Expected the credentials secrets to be applied")
+	}
+	if !executor.WasExecuted("oc annotate cloudcredential.operator.openshift.io/cluster cloudcredential.openshift.io/upgradeable-to=4.13.0 --overwrite") {
+		t.Error("Expected the cluster to be marked as upgradeable")
+	}
+	if executor.WasExecutedContaining("adm release extract") {
+		t.Error("Expected cached credentials requests to be reused")
+	}
+}
+
+func TestRefreshCredentialsDryRun(t *testing.T) {
+	executor := setupRefreshCluster(t, nil)
+
+	cfg := &config.Config{
+		ReleaseImage: "quay.io/test:4.12.0-x86_64",
+		ClusterName:  "test-cluster",
+		AwsRegion:    "us-east-2",
+	}
+	step, _ := NewRefreshCredentials(cfg, logger.New(logger.LevelQuiet, nil), executor, "quay.io/test:4.13.0-x86_64")
+	step.DryRun = true
+	if err := step.Execute(); err != nil {
+		t.Fatalf("Step execution failed: %v", err)
+	}
+
+	if !executor.WasExecutedContaining("iam get-role --role-name test-cluster-openshift-ebs-ebs-credentials") {
+		t.Error("Expected the role of the unchanged request to be checked")
+	}
+	if executor.WasExecutedContaining("ccoctl") || executor.WasExecutedContaining("oc ") {
+		t.Errorf("Expected no changes in dry run, got %v", executor.Commands)
+	}
+}
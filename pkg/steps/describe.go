@@ -0,0 +1,91 @@
+package steps
+
+import "github.com/clobrano/openshift-sts-wrapper/pkg/config"
+
+// StepDescription describes an installation step for users choosing where to
+// resume an installation
+type StepDescription struct {
+	Number      int    `json:"number"`
+	Name        string `json:"name"`  // Stable name, e.g. create-aws-resources
+	Title       string `json:"title"` // Name() of the step
+	Description string `json:"description"`
+	Evidence    string `json:"evidence"` // What the Detector looks for to skip the step
+}
+
+// stepDescriptions are the titles, descriptions and completion evidence of
+// the installation steps, in execution order
+var stepDescriptions = []struct {
+	title, description, evidence string
+}{
+	{
+		"Extract credentials requests",
+		"Extracts the AWS CredentialsRequests of the release with 'oc adm release extract' (shared by the clusters of the release)",
+		"artifacts/shared/<version>/credreqs matches the checksums recorded in cache.json when it was extracted",
+	},
+	{
+		"Extract openshift-install and oc binaries",
+		"Extracts openshift-install and the oc and kubectl clients for the host OS and architecture from the release (shared by the clusters of the release); the next steps run that oc",
+		"openshift-install is configured (binaries.openshiftInstall), or the extracted binaries match their recorded checksums and openshift-install runs and reports the version of the release",
+	},
+	{
+		"Extract ccoctl binary",
+		"Extracts ccoctl from the cloud-credential-operator image of the release (shared by the clusters of the release)",
+		"ccoctl is configured (binaries.ccoctl), or the extracted binary matches its recorded checksum and runs",
+	},
+	{
+		"Create install-config.yaml",
+		"Writes install-config.yaml from the saved configuration, or runs 'openshift-install create install-config' interactively",
+		"install-config.yaml exists in the cluster directory",
+	},
+	{
+		"Set credentialsMode to Manual",
+		"Sets credentialsMode: Manual in install-config.yaml, so the cluster uses the STS roles instead of long-lived credentials",
+		"install-config.yaml contains credentialsMode: Manual",
+	},
+	{
+		"Create manifests",
+		"Runs 'openshift-install create manifests' in the cluster directory",
+		"ccoctl-output/manifests has files",
+	},
+	{
+		"Create AWS resources",
+		"Creates the key pair, the OIDC identity provider and the IAM roles of the cluster with ccoctl",
+		"the outputs of every sub-step (key pair, identity provider, IAM roles) are in ccoctl-output",
+	},
+	{
+		"Copy manifests",
+		"Copies the manifests of ccoctl, and the machine configs, compute pools and extra manifests of the configuration, into the manifests of the cluster",
+		"ccoctl-output/manifests is gone (Step 9 removes ccoctl-output)",
+	},
+	{
+		"Copy TLS files",
+		"Copies the bound service account signing key of ccoctl into the tls directory of the cluster, then removes ccoctl-output",
+		"ccoctl-output/tls is gone",
+	},
+	{
+		"Deploy cluster",
+		"Runs 'openshift-install create cluster' and waits for the installation to complete",
+		"never skipped by detection: only the journal recording a successful deployment skips it",
+	},
+	{
+		"Verify installation",
+		"Checks the cluster operators, the credentials secrets and the STS configuration of the cluster",
+		"never skipped",
+	},
+}
+
+// DescribeSteps returns the descriptions of the installation steps, in
+// execution order
+func DescribeSteps() []StepDescription {
+	descriptions := make([]StepDescription, 0, len(stepDescriptions))
+	for i, step := range stepDescriptions {
+		descriptions = append(descriptions, StepDescription{
+			Number:      i + 1,
+			Name:        config.StepName(i + 1),
+			Title:       step.title,
+			Description: step.description,
+			Evidence:    step.evidence,
+		})
+	}
+	return descriptions
+}
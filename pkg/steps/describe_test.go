@@ -0,0 +1,30 @@
+package steps
+
+import (
+	"testing"
+
+	"github.com/clobrano/openshift-sts-wrapper/pkg/config"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/logger"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/util"
+)
+
+func TestDescribeSteps(t *testing.T) {
+	descriptions := DescribeSteps()
+	if len(descriptions) != len(config.StepNames) {
+		t.Fatalf("Expected a description for each of the %d steps, got %d", len(config.StepNames), len(descriptions))
+	}
+
+	cfg := &config.Config{ReleaseImage: "quay.io/test:4.12.0-x86_64", ClusterName: "test-cluster"}
+	for _, description := range descriptions {
+		step, err := NewInstallStep(description.Number, cfg, logger.New(logger.LevelQuiet, nil), util.NewMockExecutor())
+		if err != nil {
+			t.Fatalf("Failed to create step %d: %v", description.Number, err)
+		}
+		if description.Title != step.Name() {
+			t.Errorf("Step %d: expected title %q, got %q", description.Number, step.Name(), description.Title)
+		}
+		if description.Name != config.StepName(description.Number) || description.Description == "" || description.Evidence == "" {
+			t.Errorf("Incomplete description %+v", description)
+		}
+	}
+}
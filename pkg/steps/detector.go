@@ -1,40 +1,81 @@
 package steps
 
 import (
+	"fmt"
+
 	"github.com/clobrano/openshift-sts-wrapper/pkg/config"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/logger"
 	"github.com/clobrano/openshift-sts-wrapper/pkg/util"
 )
 
 type Detector struct {
 	cfg         *config.Config
 	versionArch string
+	journal     *util.Journal
+
+	// Set by ValidateBinaries
+	executor util.CommandExecutor
+	log      *logger.Logger
+	usable   map[int]bool
+	signed   error // Outcome of the release signature verification
+	verified bool  // Whether the release signature verification ran
 }
 
 func NewDetector(cfg *config.Config) *Detector {
 	versionArch, _ := util.ExtractVersionArch(cfg.ReleaseImage)
+	journal, _ := util.ReadJournal(cfg.ClusterName)
 	return &Detector{
 		cfg:         cfg,
 		versionArch: versionArch,
+		journal:     journal,
 	}
 }
 
+// ValidateBinaries makes the detector run the cached openshift-install and
+// ccoctl before skipping Steps 2 and 3: intact checksums don't prove that a
+// binary runs on this host, nor that the file is the one of the release. With
+// verifyBinaries (and releaseSigningKey), the cached binaries are verified as
+// Steps 2 and 3 verify the binaries they extract.
+func (d *Detector) ValidateBinaries(executor util.CommandExecutor, log *logger.Logger) {
+	d.executor = executor
+	d.log = log
+	d.usable = map[int]bool{}
+}
+
 func (d *Detector) ShouldSkipStep(stepNum int) bool {
 	// If StartFromStep is set, skip all steps before it
 	if d.cfg.StartFromStep > 0 && stepNum < d.cfg.StartFromStep {
 		return true
 	}
 
+	// Steps 2 and 3 have nothing to extract when their binary is configured
+	if ExternalBinary(d.cfg, stepNum) != "" {
+		return true
+	}
+
+	// The step journal, when it has a record of the step, is authoritative
+	if skip, ok := d.journalSaysCompleted(stepNum); ok {
+		return skip
+	}
+
 	// Otherwise, check for evidence of completion
 	switch stepNum {
 	case 1:
-		// Step 1: Extract credentials requests (shared)
-		return util.DirExistsWithFiles(util.GetSharedCredReqsPath(d.versionArch))
+		// Step 1: Extract credentials requests (shared, verified against recorded checksums)
+		return util.DirExistsWithFiles(util.GetSharedCredReqsPath(d.versionArch)) &&
+			util.VerifyArtifact(d.versionArch, d.cfg.ReleaseDigest, util.GetSharedCredReqsPath(d.versionArch))
 	case 2:
-		// Step 2: Extract openshift-install binary (shared)
-		return util.FileExists(util.GetSharedBinaryPath(d.versionArch, "openshift-install"))
+		// Step 2: Extract openshift-install and the clients (shared, verified against recorded checksums)
+		for _, path := range CachedArtifacts(d.versionArch, stepNum) {
+			if !util.VerifyArtifact(d.versionArch, d.cfg.ReleaseDigest, path) {
+				return false
+			}
+		}
+		return d.binaryUsable(stepNum)
 	case 3:
-		// Step 3: Extract ccoctl binary (shared)
-		return util.FileExists(util.GetSharedBinaryPath(d.versionArch, "ccoctl"))
+		// Step 3: Extract ccoctl binary (shared, verified against recorded checksums)
+		return util.VerifyArtifact(d.versionArch, d.cfg.ReleaseDigest, util.GetSharedBinaryPath(d.versionArch, "ccoctl")) &&
+			d.binaryUsable(stepNum)
 	case 4:
 		// Step 4: Create install-config.yaml (cluster-specific)
 		return util.FileExists(util.GetInstallConfigPath(d.versionArch, d.cfg.ClusterName))
@@ -45,9 +86,13 @@ func (d *Detector) ShouldSkipStep(stepNum int) bool {
 		// Step 6: Create manifests (cluster-specific)
 		return util.DirExistsWithFiles(util.GetClusterPath(d.cfg.ClusterName, "ccoctl-output/manifests"))
 	case 7:
-		// Step 7: Create AWS resources (cluster-specific)
-		return util.DirExistsWithFiles(util.GetClusterPath(d.cfg.ClusterName, "ccoctl-output/manifests")) &&
-			util.DirExistsWithFiles(util.GetClusterPath(d.cfg.ClusterName, "ccoctl-output/tls"))
+		// Step 7: Create AWS resources (cluster-specific), when every sub-step is complete
+		for _, subStep := range AWSResourcesSubSteps {
+			if !AWSResourcesSubStepDone(d.cfg.ClusterName, d.versionArch, subStep) {
+				return false
+			}
+		}
+		return true
 	case 8:
 		// Step 8: Copy manifests (cluster-specific)
 		return !util.DirExistsWithFiles(util.GetClusterPath(d.cfg.ClusterName, "ccoctl-output/manifests"))
@@ -66,3 +111,80 @@ func (d *Detector) ShouldSkipStep(stepNum int) bool {
 		return false
 	}
 }
+
+// binaryUsable reports whether the binary extracted by Step 2 or 3 runs and,
+// for openshift-install, reports the version of the release. With
+// verifyBinaries, the binary is verified as the step verifies the one it
+// extracts: the installer must be built for the release digest, the release
+// signature is checked when a signing key is configured, and the binary must
+// match the published checksums. The outcome is kept, so that each binary
+// runs (and is reported) once.
+func (d *Detector) binaryUsable(stepNum int) bool {
+	if d.executor == nil {
+		return true
+	}
+	if usable, ok := d.usable[stepNum]; ok {
+		return usable
+	}
+
+	command, commandOS := "ccoctl", util.HostImageFilter()
+	if stepNum == 2 {
+		command, commandOS = "openshift-install", util.HostCommandOS()
+	}
+	binary := util.GetSharedBinaryPath(d.versionArch, command)
+
+	var err error
+	if stepNum == 2 {
+		err = util.VerifyInstallerVersion(d.executor, binary, d.versionArch)
+		if err == nil && d.cfg.VerifyBinaries {
+			err = util.VerifyInstallerRelease(d.executor, binary, d.cfg.ReleaseDigest)
+		}
+	} else {
+		err = util.VerifyCcoctl(d.executor, binary)
+	}
+	if err == nil && d.cfg.VerifyBinaries {
+		err = d.verifyReleaseSignature()
+	}
+	if err == nil && d.cfg.VerifyBinaries {
+		err = verifyClientChecksum(d.cfg, d.executor, d.versionArch, command, commandOS, binary)
+	}
+	if err != nil {
+		d.log.Info(fmt.Sprintf("⚠  Cached %s is not usable, extracting it again: %v", binary, err))
+	}
+	d.usable[stepNum] = err == nil
+	return err == nil
+}
+
+// verifyReleaseSignature verifies the signature of the release the cached
+// binaries were recorded for, once, when a signing key is configured
+func (d *Detector) verifyReleaseSignature() error {
+	if d.cfg.ReleaseSigningKey == "" {
+		return nil
+	}
+	if !d.verified {
+		d.signed = util.VerifyReleaseSignature(d.executor, releaseSignatureStore, d.cfg.ReleaseSigningKey, d.cfg.ReleaseDigest)
+		d.verified = true
+	}
+	if d.signed != nil {
+		return fmt.Errorf("release signature verification failed: %w", d.signed)
+	}
+	return nil
+}
+
+// journalSaysCompleted reports whether the step journal records the step as
+// succeeded for the release being installed. Shared artifacts can be pruned or
+// replaced independently of the cluster, so the steps extracting them always
+// rely on checksum verification; verification always runs.
+func (d *Detector) journalSaysCompleted(stepNum int) (completed bool, ok bool) {
+	if d.journal == nil || stepNum == 11 || len(CachedArtifacts(d.versionArch, stepNum)) > 0 {
+		return false, false
+	}
+	if !d.journal.SameRelease(d.cfg.ReleaseImage, d.cfg.ReleaseDigest) {
+		return false, false
+	}
+	record, ok := d.journal.Record(config.StepName(stepNum))
+	if !ok {
+		return false, false
+	}
+	return record.Status == util.StepSucceeded, true
+}
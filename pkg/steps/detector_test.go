@@ -1,13 +1,48 @@
 package steps
 
 import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/clobrano/openshift-sts-wrapper/pkg/config"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/logger"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/util"
 )
 
+const testCredReq = `apiVersion: cloudcredential.openshift.io/v1
+kind: CredentialsRequest
+metadata:
+  name: openshift-ingress
+  namespace: openshift-cloud-credential-operator
+spec:
+  secretRef:
+    name: cloud-credentials
+    namespace: openshift-ingress-operator
+`
+
+// writeCcoctlOutputs writes the outputs of the Step 7 sub-steps for testCredReq
+func writeCcoctlOutputs(clusterName string) {
+	for file, content := range map[string]string{
+		"ccoctl-output/tls/" + util.SigningKeyFile:                                              "private",
+		"ccoctl-output/manifests/" + util.AuthenticationConfigFile:                              "spec:\n  serviceAccountIssuer: https://oidc.example.com\n",
+		"ccoctl-output/manifests/openshift-ingress-operator-cloud-credentials-credentials.yaml": "role_arn = arn:aws:iam::123456789012:role/ingress\n",
+	} {
+		path := util.GetClusterPath(clusterName, file)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, []byte(content), 0644)
+	}
+}
+
 func TestShouldSkipStep(t *testing.T) {
 	tmpDir := t.TempDir()
 	originalWd, _ := os.Getwd()
@@ -17,8 +52,9 @@ func TestShouldSkipStep(t *testing.T) {
 	versionArch := "4.12.0-x86_64"
 	clusterName := "test-cluster"
 	cfg := &config.Config{
-		ReleaseImage: "quay.io/test:4.12.0-x86_64",
-		ClusterName:  clusterName,
+		ReleaseImage:  "quay.io/test:4.12.0-x86_64",
+		ReleaseDigest: "sha256:1111",
+		ClusterName:   clusterName,
 	}
 
 	detector := NewDetector(cfg)
@@ -33,9 +69,14 @@ func TestShouldSkipStep(t *testing.T) {
 	// Create credreqs directory with a file (step 1) - shared path
 	credreqsPath := filepath.Join("artifacts", "shared", versionArch, "credreqs")
 	os.MkdirAll(credreqsPath, 0755)
-	os.WriteFile(filepath.Join(credreqsPath, "test.yaml"), []byte("test"), 0644)
+	os.WriteFile(filepath.Join(credreqsPath, "test.yaml"), []byte(testCredReq), 0644)
 
 	detector = NewDetector(cfg) // Refresh detector
+	if detector.ShouldSkipStep(1) {
+		t.Error("Step 1 should not be skipped when credreqs checksums were never recorded")
+	}
+
+	util.RecordArtifacts(versionArch, cfg.ReleaseImage, cfg.ReleaseDigest, credreqsPath)
 	if !detector.ShouldSkipStep(1) {
 		t.Error("Step 1 should be skipped when credreqs exists")
 	}
@@ -45,8 +86,16 @@ func TestShouldSkipStep(t *testing.T) {
 	os.MkdirAll(binPath, 0755)
 	os.WriteFile(filepath.Join(binPath, "openshift-install"), []byte("fake"), 0755)
 	os.WriteFile(filepath.Join(binPath, "ccoctl"), []byte("fake"), 0755)
+	util.RecordArtifacts(versionArch, cfg.ReleaseImage, cfg.ReleaseDigest, filepath.Join(binPath, "openshift-install"), filepath.Join(binPath, "ccoctl"))
 
 	detector = NewDetector(cfg)
+	if detector.ShouldSkipStep(2) {
+		t.Error("Step 2 should not be skipped when the oc client of the release is missing")
+	}
+
+	os.WriteFile(filepath.Join(binPath, "oc"), []byte("fake"), 0755)
+	os.Symlink("oc", filepath.Join(binPath, "kubectl"))
+	util.RecordArtifacts(versionArch, cfg.ReleaseImage, cfg.ReleaseDigest, filepath.Join(binPath, "oc"), filepath.Join(binPath, "kubectl"))
 	if !detector.ShouldSkipStep(2) {
 		t.Error("Step 2 should be skipped when binaries exist")
 	}
@@ -54,6 +103,12 @@ func TestShouldSkipStep(t *testing.T) {
 		t.Error("Step 3 should be skipped when ccoctl binary exists")
 	}
 
+	// A truncated binary must not be reused
+	os.WriteFile(filepath.Join(binPath, "openshift-install"), []byte("fa"), 0755)
+	if detector.ShouldSkipStep(2) {
+		t.Error("Step 2 should not be skipped when openshift-install checksum does not match")
+	}
+
 	// Create install-config.yaml (step 4) - cluster-specific path
 	configPath := filepath.Join("artifacts", "clusters", clusterName, "install-config.yaml")
 	os.MkdirAll(filepath.Dir(configPath), 0755)
@@ -84,9 +139,13 @@ func TestShouldSkipStep(t *testing.T) {
 		t.Error("Step 6 should be skipped when ccoctl-output/manifests exists")
 	}
 
-	// Step 7 should be skipped when both manifests and tls exist in ccoctl-output
+	// Step 7 is only complete when every sub-step left its outputs
+	if detector.ShouldSkipStep(7) {
+		t.Error("Step 7 should not be skipped when ccoctl-output has partial manifests and tls")
+	}
+	writeCcoctlOutputs(clusterName)
 	if !detector.ShouldSkipStep(7) {
-		t.Error("Step 7 should be skipped when ccoctl-output has manifests and tls")
+		t.Error("Step 7 should be skipped when ccoctl-output has the outputs of every sub-step")
 	}
 
 	// Step 8 checks that ccoctl-output/manifests does NOT exist (we just created it, so step 8 should NOT be skipped)
@@ -143,3 +202,166 @@ func TestShouldSkipStepWithStartFromOverride(t *testing.T) {
 		t.Error("Step 6 should not be skipped with StartFromStep=5")
 	}
 }
+
+func TestShouldSkipStepWithJournal(t *testing.T) {
+	tmpDir := t.TempDir()
+	originalWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(originalWd)
+
+	clusterName := "test-cluster"
+	cfg := &config.Config{
+		ReleaseImage:  "quay.io/test:4.12.0-x86_64",
+		ReleaseDigest: "sha256:aaa",
+		ClusterName:   clusterName,
+	}
+
+	// A complete ccoctl output (e.g. left by a run interrupted while tagging)
+	credreqsPath := util.GetSharedCredReqsPath("4.12.0-x86_64")
+	os.MkdirAll(credreqsPath, 0755)
+	os.WriteFile(filepath.Join(credreqsPath, "test.yaml"), []byte(testCredReq), 0644)
+	writeCcoctlOutputs(clusterName)
+	if !NewDetector(cfg).ShouldSkipStep(7) {
+		t.Fatal("Step 7 should be skipped by the heuristics without a journal")
+	}
+
+	journal := util.OpenJournal(clusterName, cfg.ReleaseImage, cfg.ReleaseDigest)
+	journal.StartStep("create-manifests")
+	journal.FinishStep("create-manifests", nil)
+	journal.StartStep("create-aws-resources")
+	journal.FinishStep("create-aws-resources", errors.New("interrupted"))
+	journal.StartStep("deploy-cluster")
+	journal.FinishStep("deploy-cluster", nil)
+	journal.StartStep("verify")
+	journal.FinishStep("verify", nil)
+
+	detector := NewDetector(cfg)
+	if detector.ShouldSkipStep(7) {
+		t.Error("Step 7 should not be skipped when the journal records a failure")
+	}
+	if !detector.ShouldSkipStep(6) {
+		t.Error("Step 6 should be skipped when the journal records a success")
+	}
+	if !detector.ShouldSkipStep(10) {
+		t.Error("Step 10 should be skipped when the journal records a success")
+	}
+	if detector.ShouldSkipStep(11) {
+		t.Error("Step 11 should always run")
+	}
+	if detector.ShouldSkipStep(1) {
+		t.Error("Step 1 should rely on artifact checksums, not the journal")
+	}
+
+	// The journal of a different release is ignored
+	cfg.ReleaseDigest = "sha256:bbb"
+	if !NewDetector(cfg).ShouldSkipStep(7) {
+		t.Error("Step 7 should fall back to the heuristics for a different release")
+	}
+}
+
+func TestShouldSkipStepValidatesBinaries(t *testing.T) {
+	tmpDir := t.TempDir()
+	originalWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(originalWd)
+
+	versionArch := "4.14.0-x86_64"
+	cfg := &config.Config{ReleaseImage: "quay.io/test:4.14.0-x86_64", ReleaseDigest: "sha256:1111"}
+	installBin := util.GetSharedBinaryPath(versionArch, "openshift-install")
+	ccoctlBin := util.GetSharedBinaryPath(versionArch, "ccoctl")
+	os.MkdirAll(filepath.Dir(installBin), 0755)
+	ocBin := util.GetSharedBinaryPath(versionArch, "oc")
+	kubectlBin := util.GetSharedBinaryPath(versionArch, "kubectl")
+	os.WriteFile(installBin, []byte("fake"), 0755)
+	os.WriteFile(ccoctlBin, []byte("fake"), 0755)
+	os.WriteFile(ocBin, []byte("fake"), 0755)
+	os.WriteFile(kubectlBin, []byte("fake"), 0755)
+	util.RecordArtifacts(versionArch, cfg.ReleaseImage, cfg.ReleaseDigest, installBin, ccoctlBin, ocBin, kubectlBin)
+
+	// The checksums match, but the installer is the one of another release
+	executor := util.NewMockExecutor()
+	executor.SetOutput(installBin+" version", installBin+" 4.13.0\nrelease image quay.io/test@sha256:1111\n")
+	executor.SetError(ccoctlBin+" --help", errors.New("exec format error"))
+	detector := NewDetector(cfg)
+	detector.ValidateBinaries(executor, logger.New(logger.LevelQuiet, nil))
+	if detector.ShouldSkipStep(2) {
+		t.Error("Step 2 should not be skipped when openshift-install reports another version")
+	}
+	if detector.ShouldSkipStep(3) {
+		t.Error("Step 3 should not be skipped when ccoctl does not run")
+	}
+
+	executor = util.NewMockExecutor()
+	executor.SetOutput(installBin+" version", installBin+" 4.14.0\nrelease image quay.io/test@sha256:1111\n")
+	detector = NewDetector(cfg)
+	detector.ValidateBinaries(executor, logger.New(logger.LevelQuiet, nil))
+	if !detector.ShouldSkipStep(2) || !detector.ShouldSkipStep(3) {
+		t.Error("Steps 2 and 3 should be skipped when the binaries are usable")
+	}
+	detector.ShouldSkipStep(2)
+	if len(executor.Commands) != 2 {
+		t.Errorf("Expected each binary to run once, got %v", executor.Commands)
+	}
+
+	// With verifyBinaries, the cached binaries are verified like the extracted
+	// ones, against the checksums published on the mirror
+	cfg.VerifyBinaries = true
+	clientsMirror = serveClients(t, "4.14.0", map[string]string{
+		util.ClientTarball("openshift-install", util.HostCommandOS(), versionArch): "fake",
+		util.ClientTarball("ccoctl", util.HostImageFilter(), versionArch):          "other",
+	})
+	defer func() { clientsMirror = "" }()
+	executor = util.NewMockExecutor()
+	executor.SetOutput(installBin+" version", installBin+" 4.14.0\nrelease image quay.io/test@sha256:2222\n")
+	detector = NewDetector(cfg)
+	detector.ValidateBinaries(executor, logger.New(logger.LevelQuiet, nil))
+	if detector.ShouldSkipStep(2) {
+		t.Error("Step 2 should not be skipped when the cached openshift-install is built for another release")
+	}
+
+	executor.SetOutput(installBin+" version", installBin+" 4.14.0\nrelease image quay.io/test@sha256:1111\n")
+	detector = NewDetector(cfg)
+	detector.ValidateBinaries(executor, logger.New(logger.LevelQuiet, nil))
+	if !detector.ShouldSkipStep(2) {
+		t.Error("Step 2 should be skipped when the cached openshift-install is verified")
+	}
+	if detector.ShouldSkipStep(3) {
+		t.Error("Step 3 should not be skipped when ccoctl does not match the published checksums")
+	}
+}
+
+// serveClients serves the client tarballs of a release, each holding its
+// command with the given content, and their checksums, returning the mirror URL
+func serveClients(t *testing.T, version string, contents map[string]string) string {
+	t.Helper()
+	tarballs := map[string][]byte{}
+	checksums := ""
+	for tarball, content := range contents {
+		var archive bytes.Buffer
+		gz := gzip.NewWriter(&archive)
+		tw := tar.NewWriter(gz)
+		command, _, _ := strings.Cut(tarball, "-linux")
+		command, _, _ = strings.Cut(command, "-mac")
+		tw.WriteHeader(&tar.Header{Name: command, Mode: 0755, Size: int64(len(content)), Typeflag: tar.TypeReg})
+		tw.Write([]byte(content))
+		tw.Close()
+		gz.Close()
+		tarballs[tarball] = archive.Bytes()
+		sum := sha256.Sum256(archive.Bytes())
+		checksums += hex.EncodeToString(sum[:]) + "  " + tarball + "\n"
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name, found := strings.CutPrefix(r.URL.Path, "/x86_64/clients/ocp/"+version+"/")
+		switch {
+		case found && name == "sha256sum.txt":
+			w.Write([]byte(checksums))
+		case found && tarballs[name] != nil:
+			w.Write(tarballs[name])
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server.URL
+}
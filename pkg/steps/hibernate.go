@@ -0,0 +1,166 @@
+package steps
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/clobrano/openshift-sts-wrapper/pkg/config"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/logger"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/util"
+)
+
+// wakePollInterval is how often the nodes are checked after a wake
+var wakePollInterval = 15 * time.Second
+
+// HibernateCluster is a day-2 step that stops the EC2 instances of a cluster:
+// only its volumes, load balancers and addresses are charged while it sleeps
+type HibernateCluster struct {
+	*BaseStep
+	infraID string
+}
+
+// NewHibernateCluster creates the hibernation step. cfg.AwsRegion must be
+// the region of the cluster.
+func NewHibernateCluster(cfg *config.Config, log *logger.Logger, executor util.CommandExecutor, infraID string) *HibernateCluster {
+	return &HibernateCluster{BaseStep: &BaseStep{cfg: cfg, log: log, executor: executor}, infraID: infraID}
+}
+
+func (s *HibernateCluster) Name() string {
+	return "Hibernate cluster"
+}
+
+func (s *HibernateCluster) Execute() error {
+	instances, err := util.FindClusterInstances(s.executor, s.cfg.AwsProfile, s.cfg.AwsRegion, s.infraID)
+	if err != nil {
+		return err
+	}
+	if len(instances) == 0 {
+		return fmt.Errorf("no instances of %s found in region %s", s.infraID, s.cfg.AwsRegion)
+	}
+
+	ids := instanceIDsIn(instances, "pending", "running")
+	if len(ids) == 0 {
+		s.log.Info("✓ All instances are already stopped")
+		return nil
+	}
+
+	spinner := s.log.StartSpinner(fmt.Sprintf("Stopping %d instances", len(ids)), nil)
+	defer spinner.Stop()
+	if err := util.StopInstances(s.executor, s.cfg.AwsProfile, s.cfg.AwsRegion, ids); err != nil {
+		return err
+	}
+	spinner.Stop()
+
+	s.log.Info(fmt.Sprintf("✓ Stopped %d instances", len(ids)))
+	return nil
+}
+
+// WakeCluster is a day-2 step that starts the EC2 instances of a hibernated
+// cluster, and waits for its nodes to be Ready. Nodes whose certificates
+// expired while they were stopped ask for new ones: their certificate signing
+// requests are approved while waiting.
+type WakeCluster struct {
+	*BaseStep
+	infraID string
+	timeout time.Duration
+}
+
+// NewWakeCluster creates the wake step. cfg.AwsRegion must be the region of
+// the cluster.
+func NewWakeCluster(cfg *config.Config, log *logger.Logger, executor util.CommandExecutor, infraID string, timeout time.Duration) *WakeCluster {
+	return &WakeCluster{BaseStep: &BaseStep{cfg: cfg, log: log, executor: executor}, infraID: infraID, timeout: timeout}
+}
+
+func (s *WakeCluster) Name() string {
+	return "Wake cluster"
+}
+
+func (s *WakeCluster) Execute() error {
+	instances, err := util.FindClusterInstances(s.executor, s.cfg.AwsProfile, s.cfg.AwsRegion, s.infraID)
+	if err != nil {
+		return err
+	}
+	if len(instances) == 0 {
+		return fmt.Errorf("no instances of %s found in region %s", s.infraID, s.cfg.AwsRegion)
+	}
+	if stopping := instanceIDsIn(instances, "stopping"); len(stopping) > 0 {
+		return fmt.Errorf("%d instances are still stopping: wait for the hibernation to complete", len(stopping))
+	}
+
+	if ids := instanceIDsIn(instances, "stopped"); len(ids) > 0 {
+		spinner := s.log.StartSpinner(fmt.Sprintf("Starting %d instances", len(ids)), nil)
+		err := util.StartInstances(s.executor, s.cfg.AwsProfile, s.cfg.AwsRegion, ids)
+		spinner.Stop()
+		if err != nil {
+			return err
+		}
+		s.log.Info(fmt.Sprintf("✓ Started %d instances", len(ids)))
+	} else {
+		s.log.Info("✓ All instances are already running")
+	}
+
+	kubeconfigPath := util.GetKubeconfigPath(s.cfg.ClusterName)
+	if !util.FileExists(kubeconfigPath) {
+		s.log.Info(fmt.Sprintf("⚠  No kubeconfig at %s: not waiting for the nodes, approve their pending certificate signing requests by hand (oc get csr)", kubeconfigPath))
+		return nil
+	}
+	return s.waitForNodes(kubeconfigPath)
+}
+
+// waitForNodes approves the pending certificate signing requests until all
+// the nodes are Ready. The API server may take minutes to answer again.
+func (s *WakeCluster) waitForNodes(kubeconfigPath string) error {
+	progress := "waiting for the API server"
+	spinner := s.log.StartSpinner("Waiting for the nodes", func() string { return progress })
+	defer spinner.Stop()
+
+	approved := 0
+	deadline := time.Now().Add(s.timeout)
+	for {
+		pending, err := util.GetPendingCSRs(s.executor, kubeconfigPath)
+		if err != nil {
+			s.log.Debug(fmt.Sprintf("Could not get certificate signing requests: %v", err))
+		} else if err := util.ApproveCSRs(s.executor, kubeconfigPath, pending); err != nil {
+			s.log.Debug(err.Error())
+		} else {
+			approved += len(pending)
+		}
+
+		status, err := util.GetNodeStatus(s.executor, kubeconfigPath)
+		if err != nil {
+			s.log.Debug(fmt.Sprintf("Could not get nodes: %v", err))
+		} else {
+			progress = fmt.Sprintf("%d/%d nodes ready", status.Ready, status.Total)
+			if status.Total > 0 && status.Ready == status.Total {
+				break
+			}
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("nodes not ready within %s: %s", s.timeout, progress)
+		}
+		if err := util.Sleep(s.executor, wakePollInterval); err != nil {
+			return err
+		}
+	}
+	spinner.Stop()
+
+	if approved > 0 {
+		s.log.Info(fmt.Sprintf("✓ Approved %d certificate signing requests", approved))
+	}
+	s.log.Info(fmt.Sprintf("✓ %s", progress))
+	return nil
+}
+
+// instanceIDsIn returns the IDs of the instances in one of the states
+func instanceIDsIn(instances []util.ClusterInstance, states ...string) []string {
+	var ids []string
+	for _, instance := range instances {
+		for _, state := range states {
+			if instance.State == state {
+				ids = append(ids, instance.ID)
+			}
+		}
+	}
+	return ids
+}
@@ -0,0 +1,71 @@
+package steps
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/clobrano/openshift-sts-wrapper/pkg/config"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/logger"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/util"
+)
+
+const describeClusterInstances = "aws ec2 describe-instances --filters Name=tag:kubernetes.io/cluster/test-x7k2p,Values=owned Name=instance-state-name,Values=pending,running,stopping,stopped --output json --region us-east-2"
+
+const clusterInstances = `{"Reservations": [{"Instances": [
+  {"InstanceId": "i-0a", "State": {"Name": "%s"}, "Tags": [{"Key": "Name", "Value": "test-x7k2p-master-0"}]},
+  {"InstanceId": "i-0b", "State": {"Name": "stopped"}, "Tags": [{"Key": "Name", "Value": "test-x7k2p-worker-us-east-2a-abcde"}]}
+]}]}`
+
+// setupHibernateCluster creates a deployed cluster with two instances, the
+// first one in the given state and the second one stopped
+func setupHibernateCluster(t *testing.T, state string) *util.MockExecutor {
+	executor := setupDeployedCluster(t, map[*time.Duration]time.Duration{&wakePollInterval: time.Millisecond})
+	executor.SetOutput(describeClusterInstances, strings.Replace(clusterInstances, "%s", state, 1))
+	return executor
+}
+
+func TestHibernateCluster(t *testing.T) {
+	executor := setupHibernateCluster(t, "running")
+	cfg := &config.Config{ClusterName: "test-cluster", AwsRegion: "us-east-2"}
+
+	step := NewHibernateCluster(cfg, logger.New(logger.LevelQuiet, nil), executor, "test-x7k2p")
+	if err := step.Execute(); err != nil {
+		t.Fatalf("Step execution failed: %v", err)
+	}
+	if !executor.WasExecuted("aws ec2 stop-instances --instance-ids i-0a --output json --region us-east-2") {
+		t.Errorf("Expected only the running instance to be stopped, got %v", executor.Commands)
+	}
+}
+
+func TestWakeCluster(t *testing.T) {
+	executor := setupHibernateCluster(t, "stopped")
+	executor.SetOutput("oc get csr -o json", `{"items": [{"metadata": {"name": "csr-x"}, "status": {}}]}`)
+	executor.SetOutput("oc get nodes -o json", `{"items": [{"status": {"conditions": [{"type": "Ready", "status": "True"}]}}]}`)
+	cfg := &config.Config{ClusterName: "test-cluster", AwsRegion: "us-east-2"}
+
+	step := NewWakeCluster(cfg, logger.New(logger.LevelQuiet, nil), executor, "test-x7k2p", time.Second)
+	if err := step.Execute(); err != nil {
+		t.Fatalf("Step execution failed: %v", err)
+	}
+	if !executor.WasExecuted("aws ec2 start-instances --instance-ids i-0a i-0b --output json --region us-east-2") {
+		t.Errorf("Expected the stopped instances to be started, got %v", executor.Commands)
+	}
+	if !executor.WasExecuted("oc adm certificate approve csr-x") {
+		t.Errorf("Expected the pending CSR to be approved, got %v", executor.Commands)
+	}
+}
+
+func TestWakeClusterStillStopping(t *testing.T) {
+	executor := setupHibernateCluster(t, "stopping")
+	cfg := &config.Config{ClusterName: "test-cluster", AwsRegion: "us-east-2"}
+
+	step := NewWakeCluster(cfg, logger.New(logger.LevelQuiet, nil), executor, "test-x7k2p", time.Second)
+	err := step.Execute()
+	if err == nil || !strings.Contains(err.Error(), "still stopping") {
+		t.Fatalf("Expected still stopping error, got %v", err)
+	}
+	if executor.WasExecutedContaining("start-instances") {
+		t.Errorf("Expected no instance to be started, got %v", executor.Commands)
+	}
+}
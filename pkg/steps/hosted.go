@@ -0,0 +1,300 @@
+package steps
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/clobrano/openshift-sts-wrapper/pkg/config"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/logger"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/util"
+)
+
+// hostedPollInterval is how often the HostedCluster is checked while its
+// control plane comes up
+var hostedPollInterval = 30 * time.Second
+
+// hostedClusterTimeout is how long the hosted control plane has to become available
+var hostedClusterTimeout = 45 * time.Minute
+
+// hosted is the state shared by the steps creating a hosted cluster
+type hosted struct {
+	*BaseStep
+}
+
+func (h *hosted) managementEnv() []string {
+	return []string{fmt.Sprintf("KUBECONFIG=%s", h.cfg.Hosted.ManagementKubeconfig)}
+}
+
+// NewHostedClusterSteps returns the steps creating a HyperShift hosted cluster
+// with STS: the extraction of the CredentialsRequests and ccoctl of the
+// release (Steps 1 and 3), the OIDC provider and IAM roles of the cluster
+// (Step 7), then the STS credentials of hcp, the HostedCluster, and the
+// credentials secrets the hosted control plane doesn't manage
+func NewHostedClusterSteps(cfg *config.Config, log *logger.Logger, executor util.CommandExecutor) ([]Step, error) {
+	base, err := newBaseStep(cfg, log, executor)
+	if err != nil {
+		return nil, err
+	}
+	step1, err := NewStep1(cfg, log, executor)
+	if err != nil {
+		return nil, err
+	}
+	step3, err := NewStep3(cfg, log, executor)
+	if err != nil {
+		return nil, err
+	}
+	step7, err := NewStep7(cfg, log, executor)
+	if err != nil {
+		return nil, err
+	}
+
+	h := &hosted{BaseStep: base}
+	return []Step{
+		&extractionStep{BaseStep: base, Step: step1, num: 1},
+		&extractionStep{BaseStep: base, Step: step3, num: 3},
+		step7,
+		&WriteSTSCredentials{h},
+		&CreateHostedCluster{h},
+		&WaitForHostedCluster{h},
+		&ApplyCredentialsSecrets{h},
+	}, nil
+}
+
+// extractionStep runs an extraction step of the installation unless its shared
+// artifacts are intact, and records their checksums like the installer does
+type extractionStep struct {
+	*BaseStep
+	Step
+	num int
+}
+
+func (s *extractionStep) Name() string {
+	return s.Step.Name()
+}
+
+func (s *extractionStep) Execute() error {
+	if binary := ExternalBinary(s.cfg, s.num); binary != "" {
+		if err := verifyExternalBinary(s.cfg, s.executor, s.versionArch, s.num); err != nil {
+			return err
+		}
+		s.log.Info(fmt.Sprintf("✓ Using %s, skipping", binary))
+		return nil
+	}
+	artifacts := CachedArtifacts(s.versionArch, s.num)
+	detector := NewDetector(s.cfg)
+	detector.ValidateBinaries(s.executor, s.log)
+	if detector.ShouldSkipStep(s.num) {
+		s.log.Info(fmt.Sprintf("✓ %s already extracted, skipping", strings.Join(artifacts, ", ")))
+		return nil
+	}
+	if err := s.Step.Execute(); err != nil {
+		return err
+	}
+	if err := util.RecordArtifacts(s.versionArch, s.cfg.ReleaseImage, s.cfg.ReleaseDigest, artifacts...); err != nil {
+		s.log.Debug(fmt.Sprintf("Could not record artifact checksums: %v", err))
+	}
+	return nil
+}
+
+// WriteSTSCredentials writes the temporary credentials hcp creates the
+// infrastructure of the hosted cluster with
+type WriteSTSCredentials struct {
+	*hosted
+}
+
+func (s *WriteSTSCredentials) Name() string {
+	return "Write STS credentials"
+}
+
+func (s *WriteSTSCredentials) Execute() error {
+	path := util.GetClusterPath(s.cfg.ClusterName, util.STSCredentialsFile)
+	if err := util.WriteSTSCredentials(s.executor, s.cfg.AwsProfile, path); err != nil {
+		return err
+	}
+	s.log.Info(fmt.Sprintf("✓ STS credentials written to %s", path))
+	return nil
+}
+
+// CreateHostedCluster creates the HostedCluster and its node pool with hcp,
+// using the service account issuer and signing key of Step 7
+type CreateHostedCluster struct {
+	*hosted
+}
+
+func (s *CreateHostedCluster) Name() string {
+	return "Create hosted cluster"
+}
+
+func (s *CreateHostedCluster) Execute() error {
+	namespace := s.cfg.Hosted.HostedNamespace()
+	existing, err := util.GetHostedCluster(s.executor, s.cfg.Hosted.ManagementKubeconfig, namespace, s.cfg.ClusterName)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		s.log.Info(fmt.Sprintf("✓ Hosted cluster %s/%s already exists, skipping", namespace, s.cfg.ClusterName))
+		return nil
+	}
+
+	outputDir := util.GetClusterPath(s.cfg.ClusterName, "ccoctl-output")
+	resources, err := util.ReadCcoctlResources(filepath.Join(outputDir, "manifests"), s.cfg.CcoctlName())
+	if err != nil {
+		return err
+	}
+	if resources.IssuerURL == "" {
+		return fmt.Errorf("no service account issuer in %s: run Step 7 again", filepath.Join(outputDir, "manifests", util.AuthenticationConfigFile))
+	}
+
+	args := []string{
+		"create", "cluster", "aws",
+		"--name", s.cfg.ClusterName,
+		"--namespace", namespace,
+		"--base-domain", s.cfg.BaseDomain,
+		"--region", s.cfg.AwsRegion,
+		"--pull-secret", s.cfg.PullSecretPath,
+		"--release-image", s.cfg.PullSpec(),
+		"--sts-creds", util.GetClusterPath(s.cfg.ClusterName, util.STSCredentialsFile),
+		"--role-arn", s.cfg.Hosted.RoleARN,
+		"--issuer-url", resources.IssuerURL,
+		"--service-account-signing-key-path", filepath.Join(outputDir, "tls", util.SigningKeyFile),
+		"--instance-type", s.cfg.WorkerInstanceType(),
+	}
+	if replicas := s.cfg.WorkerPoolReplicas(); replicas != nil {
+		args = append(args, "--node-pool-replicas", strconv.Itoa(*replicas))
+	}
+	if len(s.cfg.Zones) > 0 {
+		args = append(args, "--zones", strings.Join(s.cfg.Zones, ","))
+	}
+	if s.cfg.SSHKeyPath != "" {
+		args = append(args, "--ssh-key", s.cfg.SSHKeyPath)
+	} else {
+		args = append(args, "--generate-ssh")
+	}
+	keys := make([]string, 0, len(s.cfg.Tags))
+	for key := range s.cfg.Tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		args = append(args, "--additional-tags", key+"="+s.cfg.Tags[key])
+	}
+	return util.RunCommandWithEnv(s.executor, s.managementEnv(), "hcp", args...)
+}
+
+// WaitForHostedCluster waits for the hosted control plane to be available,
+// then writes the admin kubeconfig of the hosted cluster
+type WaitForHostedCluster struct {
+	*hosted
+}
+
+func (s *WaitForHostedCluster) Name() string {
+	return "Wait for hosted cluster"
+}
+
+func (s *WaitForHostedCluster) Execute() error {
+	namespace := s.cfg.Hosted.HostedNamespace()
+	progress := "waiting for the HyperShift operator"
+	spinner := s.log.StartSpinner("Creating hosted control plane", func() string { return progress })
+	defer spinner.Stop()
+
+	var status *util.HostedClusterStatus
+	deadline := time.Now().Add(hostedClusterTimeout)
+	for {
+		current, err := util.GetHostedCluster(s.executor, s.cfg.Hosted.ManagementKubeconfig, namespace, s.cfg.ClusterName)
+		if err != nil {
+			s.log.Debug(fmt.Sprintf("Could not get hosted cluster: %v", err))
+		} else if current == nil {
+			return fmt.Errorf("hosted cluster %s/%s not found on the management cluster", namespace, s.cfg.ClusterName)
+		} else {
+			status = current
+			if status.Progress != "" {
+				progress = status.Progress
+			}
+			if status.Available {
+				break
+			}
+		}
+
+		if time.Now().After(deadline) {
+			if status != nil && status.Degraded != "" {
+				return fmt.Errorf("hosted control plane not available within %s: %s", hostedClusterTimeout, status.Degraded)
+			}
+			return fmt.Errorf("hosted control plane not available within %s", hostedClusterTimeout)
+		}
+		if err := util.Sleep(s.executor, hostedPollInterval); err != nil {
+			return err
+		}
+	}
+	spinner.Stop()
+
+	kubeconfigPath := util.GetKubeconfigPath(s.cfg.ClusterName)
+	if err := util.WriteHostedKubeconfig(s.executor, s.cfg.Hosted.ManagementKubeconfig, namespace, s.cfg.ClusterName, kubeconfigPath); err != nil {
+		return err
+	}
+	s.log.Info(fmt.Sprintf("✓ Hosted control plane available, kubeconfig written to %s", kubeconfigPath))
+	return nil
+}
+
+// ApplyCredentialsSecrets creates in the hosted cluster the credentials
+// secrets of Step 7 that the hosted control plane doesn't manage: the secrets
+// it already has, and the ones of namespaces that don't exist (operators not
+// deployed in hosted clusters), are left alone
+type ApplyCredentialsSecrets struct {
+	*hosted
+}
+
+func (s *ApplyCredentialsSecrets) Name() string {
+	return "Apply credentials secrets"
+}
+
+func (s *ApplyCredentialsSecrets) Execute() error {
+	requests, err := util.ReadCredentialsRequests(util.GetSharedCredReqsPath(s.versionArch))
+	if err != nil {
+		return err
+	}
+	keys := make([]string, 0, len(requests))
+	for key := range requests {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	env := []string{fmt.Sprintf("KUBECONFIG=%s", util.GetKubeconfigPath(s.cfg.ClusterName))}
+	manifestsDir := util.GetClusterPath(s.cfg.ClusterName, "ccoctl-output/manifests")
+	created := 0
+	for _, key := range keys {
+		request := requests[key]
+		secret := request.SecretNamespace + "/" + request.SecretName
+		manifest := filepath.Join(manifestsDir, request.SecretFile())
+		if !util.FileExists(manifest) {
+			continue
+		}
+
+		output, err := s.executor.ExecuteWithEnv("oc", env, "get", "namespace", request.SecretNamespace, "-o", "name", "--ignore-not-found")
+		if err != nil {
+			return fmt.Errorf("failed to look up namespace %s: %w", request.SecretNamespace, err)
+		}
+		if strings.TrimSpace(output) == "" {
+			s.log.Debug(fmt.Sprintf("Namespace %s not in the hosted cluster, skipping %s", request.SecretNamespace, secret))
+			continue
+		}
+		output, err = s.executor.ExecuteWithEnv("oc", env, "get", "secret", request.SecretName, "-n", request.SecretNamespace, "-o", "name", "--ignore-not-found")
+		if err != nil {
+			return fmt.Errorf("failed to look up secret %s: %w", secret, err)
+		}
+		if strings.TrimSpace(output) != "" {
+			s.log.Debug(fmt.Sprintf("Secret %s managed by the hosted control plane, skipping", secret))
+			continue
+		}
+
+		if err := util.RunCommandWithEnv(s.executor, env, "oc", "apply", "-f", manifest); err != nil {
+			return fmt.Errorf("failed to create secret %s: %w", secret, err)
+		}
+		created++
+	}
+	s.log.Info(fmt.Sprintf("✓ Created %d credentials secrets in the hosted cluster", created))
+	return nil
+}
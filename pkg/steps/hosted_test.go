@@ -0,0 +1,137 @@
+package steps
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/clobrano/openshift-sts-wrapper/pkg/config"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/logger"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/util"
+)
+
+const getHostedCluster = "oc get hostedcluster test-cluster -n clusters -o json --ignore-not-found"
+
+// setupHostedCluster runs Step 7 for a hosted cluster and returns its steps
+func setupHostedCluster(t *testing.T) (*ccoctlExecutor, []Step) {
+	tmpDir := t.TempDir()
+	originalWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	t.Cleanup(func() { os.Chdir(originalWd) })
+
+	hostedPollInterval = time.Millisecond
+	t.Cleanup(func() { hostedPollInterval = 30 * time.Second })
+
+	cfg := &config.Config{
+		ReleaseImage:   "quay.io/test:4.12.0-x86_64",
+		ClusterName:    "test-cluster",
+		AwsRegion:      "us-east-2",
+		BaseDomain:     "example.com",
+		PullSecretPath: "pull-secret.json",
+		InstanceType:   "m5.xlarge",
+		WorkerReplicas: intPtr(2),
+		Tags:           map[string]string{"team": "edge"},
+		Hosted: config.HostedConfig{
+			ManagementKubeconfig: "/tmp/management-kubeconfig",
+			RoleARN:              "arn:aws:iam::123456789012:role/hcp-cli-role",
+		},
+	}
+	executor := setupStep7(t)
+	hostedSteps, err := NewHostedClusterSteps(cfg, logger.New(logger.LevelQuiet, nil), executor)
+	if err != nil {
+		t.Fatalf("Failed to create steps: %v", err)
+	}
+	if err := hostedSteps[2].Execute(); err != nil {
+		t.Fatalf("Step 7 failed: %v", err)
+	}
+	return executor, hostedSteps
+}
+
+func intPtr(value int) *int {
+	return &value
+}
+
+func TestCreateHostedCluster(t *testing.T) {
+	executor, hostedSteps := setupHostedCluster(t)
+	util.SetSessionCredentials(&util.AWSCredentials{AccessKeyID: "ASIAROLE", SecretAccessKey: "secret", SessionToken: "token"})
+	defer util.SetSessionCredentials(nil)
+
+	for _, step := range hostedSteps[3:5] {
+		if err := step.Execute(); err != nil {
+			t.Fatalf("%s failed: %v", step.Name(), err)
+		}
+	}
+
+	if !util.FileExists(util.GetClusterPath("test-cluster", util.STSCredentialsFile)) {
+		t.Error("Expected the STS credentials in the cluster directory")
+	}
+	for _, arg := range []string{
+		"hcp create cluster aws --name test-cluster --namespace clusters --base-domain example.com --region us-east-2",
+		"--release-image quay.io/test:4.12.0-x86_64",
+		"--sts-creds artifacts/clusters/test-cluster/sts-creds.json --role-arn arn:aws:iam::123456789012:role/hcp-cli-role",
+		"--issuer-url https://test-cluster-oidc.s3.us-east-2.amazonaws.com",
+		"--service-account-signing-key-path artifacts/clusters/test-cluster/ccoctl-output/tls/bound-service-account-signing-key.key",
+		"--instance-type m5.xlarge --node-pool-replicas 2 --generate-ssh --additional-tags team=edge",
+	} {
+		if !executor.WasExecutedContaining(arg) {
+			t.Errorf("Expected %q in the commands, got %v", arg, executor.Commands)
+		}
+	}
+
+	// An existing HostedCluster is left as it is
+	executor.SetOutput(getHostedCluster, `{"status": {}}`)
+	executor.Commands = nil
+	if err := hostedSteps[4].Execute(); err != nil {
+		t.Fatalf("Step execution failed: %v", err)
+	}
+	if executor.WasExecutedContaining("hcp create cluster") {
+		t.Errorf("Expected the existing hosted cluster to be kept, got %v", executor.Commands)
+	}
+}
+
+func TestWaitForHostedCluster(t *testing.T) {
+	executor, hostedSteps := setupHostedCluster(t)
+	executor.SetOutput(getHostedCluster, `{"status": {"conditions": [{"type": "Available", "status": "True"}]}}`)
+	executor.SetOutput("hcp create kubeconfig --name test-cluster --namespace clusters", "apiVersion: v1\nkind: Config\n")
+
+	if err := hostedSteps[5].Execute(); err != nil {
+		t.Fatalf("Step execution failed: %v", err)
+	}
+	if !util.FileContains(util.GetKubeconfigPath("test-cluster"), "kind: Config") {
+		t.Error("Expected the kubeconfig of the hosted cluster to be written")
+	}
+}
+
+func TestApplyCredentialsSecrets(t *testing.T) {
+	executor, hostedSteps := setupHostedCluster(t)
+	const getNamespace = "oc get namespace openshift-ingress-operator -o name --ignore-not-found"
+	const getSecret = "oc get secret cloud-credentials -n openshift-ingress-operator -o name --ignore-not-found"
+	const apply = "oc apply -f artifacts/clusters/test-cluster/ccoctl-output/manifests/openshift-ingress-operator-cloud-credentials-credentials.yaml"
+
+	// The namespace doesn't exist in the hosted cluster
+	if err := hostedSteps[6].Execute(); err != nil {
+		t.Fatalf("Step execution failed: %v", err)
+	}
+	if executor.WasExecuted(apply) {
+		t.Error("Expected the secret of a missing namespace to be skipped")
+	}
+
+	// The hosted control plane manages the secret
+	executor.SetOutput(getNamespace, "namespace/openshift-ingress-operator")
+	executor.SetOutput(getSecret, "secret/cloud-credentials")
+	if err := hostedSteps[6].Execute(); err != nil {
+		t.Fatalf("Step execution failed: %v", err)
+	}
+	if executor.WasExecuted(apply) {
+		t.Error("Expected the existing secret to be kept")
+	}
+
+	executor.SetOutput(getSecret, "")
+	if err := hostedSteps[6].Execute(); err != nil {
+		t.Fatalf("Step execution failed: %v", err)
+	}
+	if !executor.WasExecuted(apply) {
+		t.Errorf("Expected the secret to be created, got %v", strings.Join(executor.Commands, "\n"))
+	}
+}
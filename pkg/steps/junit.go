@@ -0,0 +1,82 @@
+package steps
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// junitTestSuites is the root of a JUnit XML report
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Time      string          `xml:"time,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// JUnit returns the report as JUnit XML, with a test case per check, for CI
+// systems to display the verification like test results
+func (r *VerifyReport) JUnit() ([]byte, error) {
+	suite := junitTestSuite{Name: "openshift-sts-wrapper verify " + r.ClusterName}
+	total := 0.0
+	for _, result := range r.Checks {
+		testCase := junitTestCase{
+			Name:      fmt.Sprintf("[%s] %s", result.ID, result.Description),
+			ClassName: "verify",
+			Time:      fmt.Sprintf("%.3f", result.DurationSeconds),
+		}
+		if result.Status == VerifyFailed {
+			testCase.Failure = &junitFailure{Message: result.Message, Text: result.Message}
+			suite.Failures++
+		} else {
+			testCase.SystemOut = result.Message
+		}
+		suite.TestCases = append(suite.TestCases, testCase)
+		total += result.DurationSeconds
+	}
+	suite.Tests = len(suite.TestCases)
+	suite.Time = fmt.Sprintf("%.3f", total)
+
+	data, err := xml.MarshalIndent(junitTestSuites{Suites: []junitTestSuite{suite}}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), append(data, '\n')...), nil
+}
+
+// WriteJUnit writes the report as JUnit XML to path, creating its directory
+func (r *VerifyReport) WriteJUnit(path string) error {
+	data, err := r.JUnit()
+	if err != nil {
+		return fmt.Errorf("failed to encode JUnit report: %w", err)
+	}
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create %s: %w", dir, err)
+		}
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write JUnit report: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package steps
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/clobrano/openshift-sts-wrapper/pkg/config"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/logger"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/util"
+)
+
+// Retries of a manifest that can't be applied yet, e.g. because its CRD comes
+// from an operator that is still rolling out
+var (
+	manifestsTimeout  = 10 * time.Minute
+	manifestsInterval = 15 * time.Second
+)
+
+// ApplyManifests is a post-install step that applies the manifests of
+// postInstallManifestsDir, for resources that can't be day-1 manifests
+type ApplyManifests struct {
+	*BaseStep
+}
+
+// NewApplyManifests creates the manifests step. Unlike the install steps, it
+// doesn't need the release image.
+func NewApplyManifests(cfg *config.Config, log *logger.Logger, executor util.CommandExecutor) *ApplyManifests {
+	return &ApplyManifests{BaseStep: &BaseStep{cfg: cfg, log: log, executor: executor}}
+}
+
+func (s *ApplyManifests) Name() string {
+	return "Apply post-install manifests"
+}
+
+func (s *ApplyManifests) Execute() error {
+	kubeconfigPath := util.GetKubeconfigPath(s.cfg.ClusterName)
+	if !util.FileExists(kubeconfigPath) {
+		return fmt.Errorf("kubeconfig not found at %s - cluster may not have been deployed successfully", kubeconfigPath)
+	}
+	envVars := []string{fmt.Sprintf("KUBECONFIG=%s", kubeconfigPath)}
+
+	manifests, err := util.ReadPostInstallManifests(s.cfg.PostInstallManifestsDir)
+	if err != nil {
+		return err
+	}
+
+	// The manifests are applied in order, each one retried until it is accepted
+	deadline := time.Now().Add(manifestsTimeout)
+	for _, manifest := range manifests {
+		for {
+			// Strict validation makes the API server reject unknown and duplicate fields
+			_, err := s.executor.ExecuteWithEnv("oc", envVars, "apply", "--validate=strict", "-f", manifest)
+			if err == nil {
+				s.log.Info(fmt.Sprintf("✓ Applied %s", manifest))
+				break
+			}
+			if time.Now().After(deadline) {
+				return fmt.Errorf("failed to apply %s within %s: %w", manifest, manifestsTimeout, err)
+			}
+			s.log.Debug(fmt.Sprintf("Could not apply %s, retrying: %v", manifest, err))
+			if err := util.Sleep(s.executor, manifestsInterval); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
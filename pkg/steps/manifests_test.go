@@ -0,0 +1,56 @@
+package steps
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/clobrano/openshift-sts-wrapper/pkg/config"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/logger"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/util"
+)
+
+const testNamespaceManifest = "apiVersion: v1\nkind: Namespace\nmetadata:\n  name: demo\n"
+
+func setupManifestsCluster(t *testing.T) *util.MockExecutor {
+	executor := setupDeployedCluster(t, map[*time.Duration]time.Duration{
+		&manifestsTimeout:  0,
+		&manifestsInterval: time.Millisecond,
+	})
+	os.MkdirAll("post-install", 0755)
+	os.WriteFile("post-install/10-second.yaml", []byte(testNamespaceManifest), 0644)
+	os.WriteFile("post-install/00-first.yaml", []byte(testNamespaceManifest), 0644)
+	return executor
+}
+
+func TestApplyManifests(t *testing.T) {
+	executor := setupManifestsCluster(t)
+	cfg := &config.Config{ClusterName: "test-cluster", PostInstallManifestsDir: "post-install"}
+
+	if err := NewApplyManifests(cfg, logger.New(logger.LevelQuiet, nil), executor).Execute(); err != nil {
+		t.Fatalf("Step execution failed: %v", err)
+	}
+	expected := []string{
+		"oc apply --validate=strict -f post-install/00-first.yaml",
+		"oc apply --validate=strict -f post-install/10-second.yaml",
+	}
+	if strings.Join(executor.Commands, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected %v, got %v", expected, executor.Commands)
+	}
+}
+
+func TestApplyManifestsTimeout(t *testing.T) {
+	executor := setupManifestsCluster(t)
+	executor.SetError("oc apply --validate=strict -f post-install/00-first.yaml", errors.New("no matches for kind"))
+	cfg := &config.Config{ClusterName: "test-cluster", PostInstallManifestsDir: "post-install"}
+
+	err := NewApplyManifests(cfg, logger.New(logger.LevelQuiet, nil), executor).Execute()
+	if err == nil || !strings.Contains(err.Error(), "failed to apply post-install/00-first.yaml") {
+		t.Fatalf("Expected apply error, got %v", err)
+	}
+	if executor.WasExecutedContaining("10-second.yaml") {
+		t.Error("Expected the following manifests not to be applied")
+	}
+}
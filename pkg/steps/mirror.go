@@ -0,0 +1,88 @@
+package steps
+
+import (
+	"fmt"
+
+	"github.com/clobrano/openshift-sts-wrapper/pkg/config"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/logger"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/util"
+)
+
+// clusterMirrorResources returns the mirror resources of the release the
+// cluster was installed with, nil if it is not installed from a mirror
+func clusterMirrorResources(cfg *config.Config) (*util.MirrorResources, error) {
+	if cfg.Mirror.Registry == "" {
+		return nil, nil
+	}
+	releaseImage := cfg.ReleaseImage
+	if metadata, err := util.ReadInstallMetadata(util.GetClusterPath(cfg.ClusterName, "")); err == nil && metadata.ReleaseImage != "" {
+		releaseImage = metadata.ReleaseImage
+	}
+	versionArch, err := util.ExtractVersionArch(releaseImage)
+	if err != nil {
+		return nil, err
+	}
+	return util.ReadMirrorResources(util.GetSharedMirrorPath(versionArch))
+}
+
+// mirroredCatalogs returns the CatalogSources of the mirror replacing the
+// default catalog sources of OpenShift, by default catalog source
+func mirroredCatalogs(resources *util.MirrorResources) map[string]string {
+	catalogs := map[string]string{}
+	if resources == nil {
+		return catalogs
+	}
+	for _, catalog := range resources.CatalogSources {
+		if catalog.Default != "" {
+			catalogs[catalog.Default] = catalog.Name
+		}
+	}
+	return catalogs
+}
+
+// ConfigureMirrorCatalogs is a post-install step of clusters installed from a
+// mirror: it disables the default catalog sources, which can't be reached,
+// and creates the CatalogSources of the mirrored catalogs
+type ConfigureMirrorCatalogs struct {
+	*BaseStep
+}
+
+// NewConfigureMirrorCatalogs creates the catalogs step. Unlike the install
+// steps, it doesn't need the release image.
+func NewConfigureMirrorCatalogs(cfg *config.Config, log *logger.Logger, executor util.CommandExecutor) *ConfigureMirrorCatalogs {
+	return &ConfigureMirrorCatalogs{BaseStep: &BaseStep{cfg: cfg, log: log, executor: executor}}
+}
+
+func (s *ConfigureMirrorCatalogs) Name() string {
+	return "Configure mirrored catalogs"
+}
+
+func (s *ConfigureMirrorCatalogs) Execute() error {
+	kubeconfigPath := util.GetKubeconfigPath(s.cfg.ClusterName)
+	if !util.FileExists(kubeconfigPath) {
+		return fmt.Errorf("kubeconfig not found at %s - cluster may not have been deployed successfully", kubeconfigPath)
+	}
+	envVars := []string{fmt.Sprintf("KUBECONFIG=%s", kubeconfigPath)}
+
+	resources, err := clusterMirrorResources(s.cfg)
+	if err != nil {
+		return err
+	}
+	if resources == nil {
+		return fmt.Errorf("mirror.registry is not configured")
+	}
+
+	if err := util.RunCommandWithEnv(s.executor, envVars, "oc", "patch", "operatorhub", "cluster", "--type=merge",
+		"--patch", `{"spec":{"disableAllDefaultSources":true}}`); err != nil {
+		return fmt.Errorf("failed to disable the default catalog sources: %w", err)
+	}
+	s.log.Info("✓ Default catalog sources disabled")
+
+	for _, catalog := range resources.CatalogSources {
+		if err := util.RunCommandWithEnv(s.executor, envVars, "oc", "apply", "-f", catalog.Path); err != nil {
+			return fmt.Errorf("failed to create CatalogSource %s: %w", catalog.Name, err)
+		}
+		s.log.Info(fmt.Sprintf("✓ CatalogSource %s created for %s", catalog.Name, catalog.Image))
+	}
+	return nil
+}
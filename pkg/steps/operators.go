@@ -0,0 +1,212 @@
+package steps
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/clobrano/openshift-sts-wrapper/pkg/config"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/logger"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/util"
+)
+
+// Defaults of the operators of postInstall.operators
+const (
+	defaultOperatorNamespace = "openshift-operators"
+	defaultOperatorSource    = "redhat-operators"
+)
+
+// Polling of the ClusterServiceVersions of the subscribed operators
+var (
+	operatorsTimeout      = 20 * time.Minute
+	operatorsPollInterval = 15 * time.Second
+)
+
+// InstallOperators is a post-install step that subscribes to the OLM
+// operators of the configuration and waits for their ClusterServiceVersion to
+// succeed
+type InstallOperators struct {
+	*BaseStep
+}
+
+// NewInstallOperators creates the operators step. Unlike the install steps,
+// it doesn't need the release image.
+func NewInstallOperators(cfg *config.Config, log *logger.Logger, executor util.CommandExecutor) *InstallOperators {
+	return &InstallOperators{BaseStep: &BaseStep{cfg: cfg, log: log, executor: executor}}
+}
+
+func (s *InstallOperators) Name() string {
+	return "Install operators"
+}
+
+func (s *InstallOperators) Execute() error {
+	kubeconfigPath := util.GetKubeconfigPath(s.cfg.ClusterName)
+	if !util.FileExists(kubeconfigPath) {
+		return fmt.Errorf("kubeconfig not found at %s - cluster may not have been deployed successfully", kubeconfigPath)
+	}
+	envVars := []string{fmt.Sprintf("KUBECONFIG=%s", kubeconfigPath)}
+
+	// Clusters installed from a mirror subscribe from the mirrored catalogs
+	resources, err := clusterMirrorResources(s.cfg)
+	if err != nil {
+		return err
+	}
+	manifestPath, err := writeOperatorsManifest(util.GetClusterPath(s.cfg.ClusterName, ""), s.cfg.PostInstall.Operators, mirroredCatalogs(resources))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(manifestPath)
+
+	s.log.Info(fmt.Sprintf("Subscribing to %s...", operatorNames(s.cfg.PostInstall.Operators)))
+	if err := util.RunCommandWithEnv(s.executor, envVars, "oc", "apply", "-f", manifestPath); err != nil {
+		return fmt.Errorf("failed to create the subscriptions: %w", err)
+	}
+	return s.waitForOperators(envVars)
+}
+
+// waitForOperators waits until the ClusterServiceVersion installed by every
+// subscription has succeeded
+func (s *InstallOperators) waitForOperators(envVars []string) error {
+	operators := s.cfg.PostInstall.Operators
+	progress := ""
+	spinner := s.log.StartSpinner("Waiting for operators", func() string { return progress })
+	defer spinner.Stop()
+
+	succeeded := map[string]string{} // Operator -> CSV
+	deadline := time.Now().Add(operatorsTimeout)
+	for {
+		var waiting []string
+		for _, operator := range operators {
+			if _, ok := succeeded[operator.Name]; ok {
+				continue
+			}
+			csv, phase := s.operatorPhase(envVars, operator)
+			if phase == "Succeeded" {
+				succeeded[operator.Name] = csv
+				continue
+			}
+			if phase == "" {
+				phase = "pending"
+			}
+			waiting = append(waiting, fmt.Sprintf("%s (%s)", operator.Name, phase))
+		}
+		if len(waiting) == 0 {
+			break
+		}
+		progress = fmt.Sprintf("%d/%d ready, waiting for %s", len(succeeded), len(operators), strings.Join(waiting, ", "))
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("operators not ready within %s: %s", operatorsTimeout, strings.Join(waiting, ", "))
+		}
+		if err := util.Sleep(s.executor, operatorsPollInterval); err != nil {
+			return err
+		}
+	}
+	spinner.Stop()
+
+	for _, operator := range operators {
+		s.log.Info(fmt.Sprintf("✓ %s installed (%s)", operator.Name, succeeded[operator.Name]))
+	}
+	return nil
+}
+
+// operatorPhase returns the ClusterServiceVersion installed by the
+// subscription of an operator and its phase, empty until they exist
+func (s *InstallOperators) operatorPhase(envVars []string, operator config.Operator) (csv, phase string) {
+	namespace := operatorNamespace(operator)
+	output, err := s.executor.ExecuteWithEnv("oc", envVars, "get", "subscription", operator.Name, "-n", namespace, "-o", "jsonpath={.status.installedCSV}")
+	if err != nil {
+		s.log.Debug(fmt.Sprintf("Could not get subscription %s: %v", operator.Name, err))
+		return "", ""
+	}
+	csv = strings.TrimSpace(output)
+	if csv == "" {
+		return "", ""
+	}
+	output, err = s.executor.ExecuteWithEnv("oc", envVars, "get", "csv", csv, "-n", namespace, "-o", "jsonpath={.status.phase}")
+	if err != nil {
+		s.log.Debug(fmt.Sprintf("Could not get ClusterServiceVersion %s: %v", csv, err))
+		return csv, ""
+	}
+	return csv, strings.TrimSpace(output)
+}
+
+// operatorNamespace returns the namespace an operator is installed in
+func operatorNamespace(operator config.Operator) string {
+	if operator.Namespace != "" {
+		return operator.Namespace
+	}
+	return defaultOperatorNamespace
+}
+
+// operatorNames returns the names of the operators, for messages
+func operatorNames(operators []config.Operator) string {
+	names := make([]string, len(operators))
+	for i, operator := range operators {
+		names[i] = operator.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+// writeOperatorsManifest writes the Subscriptions of the operators to a file
+// in dir, returning its path. Operators outside openshift-operators get their
+// namespace and an OperatorGroup watching it. Catalog sources in catalogs are
+// replaced by their value (e.g. by the catalog mirroring them).
+func writeOperatorsManifest(dir string, operators []config.Operator, catalogs map[string]string) (string, error) {
+	var documents []string
+	namespaces := map[string]bool{}
+	for _, operator := range operators {
+		namespace := operatorNamespace(operator)
+		if namespace != defaultOperatorNamespace && !namespaces[namespace] {
+			namespaces[namespace] = true
+			documents = append(documents, fmt.Sprintf(`apiVersion: v1
+kind: Namespace
+metadata:
+  name: %[1]s
+`, namespace), fmt.Sprintf(`apiVersion: operators.coreos.com/v1
+kind: OperatorGroup
+metadata:
+  name: %[1]s
+  namespace: %[1]s
+spec:
+  targetNamespaces:
+  - %[1]s
+`, namespace))
+		}
+
+		source := operator.Source
+		if source == "" {
+			source = defaultOperatorSource
+		}
+		if mirrored, ok := catalogs[source]; ok {
+			source = mirrored
+		}
+		subscription := fmt.Sprintf(`apiVersion: operators.coreos.com/v1alpha1
+kind: Subscription
+metadata:
+  name: %s
+  namespace: %s
+spec:
+  name: %s
+  source: %s
+  sourceNamespace: openshift-marketplace
+  installPlanApproval: Automatic
+`, operator.Name, namespace, operator.Name, source)
+		if operator.Channel != "" {
+			subscription += fmt.Sprintf("  channel: %s\n", operator.Channel)
+		}
+		documents = append(documents, subscription)
+	}
+
+	file, err := os.CreateTemp(dir, "operators-*.yaml")
+	if err != nil {
+		return "", fmt.Errorf("failed to write operators manifest: %w", err)
+	}
+	defer file.Close()
+	if _, err := file.WriteString(strings.Join(documents, "---\n")); err != nil {
+		os.Remove(file.Name())
+		return "", fmt.Errorf("failed to write operators manifest: %w", err)
+	}
+	return file.Name(), nil
+}
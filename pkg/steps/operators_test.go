@@ -0,0 +1,81 @@
+package steps
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/clobrano/openshift-sts-wrapper/pkg/config"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/logger"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/util"
+)
+
+func setupOperatorsCluster(t *testing.T) *util.MockExecutor {
+	return setupDeployedCluster(t, map[*time.Duration]time.Duration{
+		&operatorsTimeout:      0,
+		&operatorsPollInterval: time.Millisecond,
+	})
+}
+
+func TestInstallOperators(t *testing.T) {
+	executor := setupOperatorsCluster(t)
+	executor.SetOutput("oc get subscription web-terminal -n openshift-operators -o jsonpath={.status.installedCSV}", "web-terminal.v1.9.0")
+	executor.SetOutput("oc get csv web-terminal.v1.9.0 -n openshift-operators -o jsonpath={.status.phase}", "Succeeded")
+	cfg := &config.Config{
+		ClusterName: "test-cluster",
+		PostInstall: config.PostInstall{Operators: []config.Operator{{Name: "web-terminal", Channel: "fast"}}},
+	}
+
+	step := NewInstallOperators(cfg, logger.New(logger.LevelQuiet, nil), executor)
+	if err := step.Execute(); err != nil {
+		t.Fatalf("Step execution failed: %v", err)
+	}
+	if !executor.WasExecutedContaining("oc apply -f artifacts/clusters/test-cluster/operators-") {
+		t.Errorf("Expected the subscriptions to be applied, got %v", executor.Commands)
+	}
+}
+
+func TestInstallOperatorsTimeout(t *testing.T) {
+	executor := setupOperatorsCluster(t)
+	executor.SetOutput("oc get subscription local-storage-operator -n openshift-local-storage -o jsonpath={.status.installedCSV}", "local-storage-operator.v4.14.0")
+	executor.SetOutput("oc get csv local-storage-operator.v4.14.0 -n openshift-local-storage -o jsonpath={.status.phase}", "Installing")
+	cfg := &config.Config{
+		ClusterName: "test-cluster",
+		PostInstall: config.PostInstall{Operators: []config.Operator{{Name: "local-storage-operator", Namespace: "openshift-local-storage"}}},
+	}
+
+	err := NewInstallOperators(cfg, logger.New(logger.LevelQuiet, nil), executor).Execute()
+	if err == nil || !strings.Contains(err.Error(), "local-storage-operator (Installing)") {
+		t.Fatalf("Expected timeout error, got %v", err)
+	}
+}
+
+func TestWriteOperatorsManifest(t *testing.T) {
+	path, err := writeOperatorsManifest(t.TempDir(), []config.Operator{
+		{Name: "web-terminal"},
+		{Name: "local-storage-operator", Namespace: "openshift-local-storage", Channel: "stable", Source: "my-catalog"},
+		{Name: "cert-manager", Source: "certified-operators"},
+	}, map[string]string{"certified-operators": "cs-certified-operator-index-v4-15"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	manifest := string(data)
+
+	if strings.Count(manifest, "kind: OperatorGroup") != 1 {
+		t.Errorf("Expected an OperatorGroup for openshift-local-storage only, got:\n%s", manifest)
+	}
+	for _, expected := range []string{
+		"  name: web-terminal\n  namespace: openshift-operators\n",
+		"  source: redhat-operators\n",
+		"  source: my-catalog\n",
+		"  source: cs-certified-operator-index-v4-15\n",
+		"  channel: stable\n",
+		"  targetNamespaces:\n  - openshift-local-storage\n",
+	} {
+		if !strings.Contains(manifest, expected) {
+			t.Errorf("Expected manifest to contain %q, got:\n%s", expected, manifest)
+		}
+	}
+}
@@ -0,0 +1,267 @@
+package steps
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/clobrano/openshift-sts-wrapper/pkg/config"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/logger"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/util"
+)
+
+// htpasswdSecretName is the secret in openshift-config holding the htpasswd file
+const htpasswdSecretName = "htpasswd-secret"
+
+// Polling of the admin login before the kubeadmin secret is removed. The OAuth
+// server takes a few minutes to roll out the new identity provider.
+var (
+	loginTimeout  = 10 * time.Minute
+	loginInterval = 20 * time.Second
+)
+
+// ConfigureIDP is a post-install step that creates an htpasswd identity
+// provider with an admin user, grants it cluster-admin and optionally removes
+// the kubeadmin user
+type ConfigureIDP struct {
+	*BaseStep
+}
+
+// NewConfigureIDP creates the identity provider step. Unlike the install steps,
+// it doesn't need the release image.
+func NewConfigureIDP(cfg *config.Config, log *logger.Logger, executor util.CommandExecutor) *ConfigureIDP {
+	return &ConfigureIDP{BaseStep: &BaseStep{cfg: cfg, log: log, executor: executor}}
+}
+
+func (s *ConfigureIDP) Name() string {
+	return "Configure htpasswd identity provider"
+}
+
+// GetAdminPasswordPath returns the file the password of the admin user is saved to
+func GetAdminPasswordPath(clusterName, user string) string {
+	return util.GetClusterPath(clusterName, filepath.Join("auth", user+"-password"))
+}
+
+func (s *ConfigureIDP) Execute() error {
+	user := s.cfg.PostInstall.AdminUser
+	kubeconfigPath := util.GetKubeconfigPath(s.cfg.ClusterName)
+	if !util.FileExists(kubeconfigPath) {
+		return fmt.Errorf("kubeconfig not found at %s - cluster may not have been deployed successfully", kubeconfigPath)
+	}
+	envVars := []string{fmt.Sprintf("KUBECONFIG=%s", kubeconfigPath)}
+
+	password, err := s.adminPassword(user)
+	if err != nil {
+		return err
+	}
+	entry, err := util.HtpasswdEntry(user, password)
+	if err != nil {
+		return err
+	}
+
+	manifestPath, err := writeHtpasswdSecret(util.GetClusterPath(s.cfg.ClusterName, ""), entry)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(manifestPath)
+
+	s.log.Info(fmt.Sprintf("Creating htpasswd identity provider with user %s...", user))
+	if err := util.RunCommandWithEnv(s.executor, envVars, "oc", "apply", "-f", manifestPath); err != nil {
+		return fmt.Errorf("failed to create identity provider: %w", err)
+	}
+	if err := s.addIdentityProvider(envVars); err != nil {
+		return err
+	}
+	if err := util.RunCommandWithEnv(s.executor, envVars, "oc", "adm", "policy", "add-cluster-role-to-user", "cluster-admin", user); err != nil {
+		return fmt.Errorf("failed to grant cluster-admin to %s: %w", user, err)
+	}
+	s.log.Info(fmt.Sprintf("✓ %s is cluster-admin. Password saved to %s", user, GetAdminPasswordPath(s.cfg.ClusterName, user)))
+
+	if !s.cfg.PostInstall.RemoveKubeadmin {
+		return nil
+	}
+
+	// Never remove kubeadmin before the new user can log in, or the cluster
+	// would be left without a working admin login
+	s.log.Info(fmt.Sprintf("Waiting for %s to be able to log in before removing kubeadmin...", user))
+	if err := s.waitForLogin(envVars, kubeconfigPath, user, password); err != nil {
+		return err
+	}
+	if err := util.RunCommandWithEnv(s.executor, envVars, "oc", "delete", "secret", "kubeadmin", "-n", "kube-system", "--ignore-not-found"); err != nil {
+		return fmt.Errorf("failed to remove kubeadmin: %w", err)
+	}
+	s.log.Info("✓ kubeadmin removed")
+	return nil
+}
+
+// adminPassword returns the password of the admin user: from
+// OPENSHIFT_STS_ADMIN_PASSWORD, from a previous run, or newly generated. The
+// password is saved next to the kubeadmin password.
+func (s *ConfigureIDP) adminPassword(user string) (string, error) {
+	path := GetAdminPasswordPath(s.cfg.ClusterName, user)
+	password := os.Getenv("OPENSHIFT_STS_ADMIN_PASSWORD")
+	if password == "" {
+		if data, err := os.ReadFile(path); err == nil {
+			password = strings.TrimSpace(string(data))
+			logger.AddSecret(password)
+			return password, nil
+		}
+		generated, err := util.RandomString(23, "abcdefghijkmnopqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789")
+		if err != nil {
+			return "", err
+		}
+		password = generated
+	}
+	logger.AddSecret(password)
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", fmt.Errorf("failed to create auth directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(password), 0600); err != nil {
+		return "", fmt.Errorf("failed to save admin password: %w", err)
+	}
+	return password, nil
+}
+
+// requestOAuthToken logs in through the OAuth server; replaced in tests
+var requestOAuthToken = util.RequestOAuthToken
+
+// waitForLogin polls until the user can log in through the new identity
+// provider. The password is sent to the OAuth server directly rather than on
+// the command line of oc, and the login is checked with a separate kubeconfig
+// holding the token, so that the admin one is untouched.
+func (s *ConfigureIDP) waitForLogin(envVars []string, kubeconfigPath, user, password string) error {
+	server, caBundle, err := util.KubeconfigServer(kubeconfigPath)
+	if err != nil {
+		return err
+	}
+	// The OAuth route is signed by the ingress CA, not by the API server ones
+	ingressCA, err := s.executor.ExecuteWithEnv("oc", envVars, "get", "configmap", "default-ingress-cert", "-n", "openshift-config-managed", "-o", `jsonpath={.data.ca-bundle\.crt}`)
+	if err != nil {
+		return fmt.Errorf("failed to get the ingress CA: %w", err)
+	}
+	caBundle = append(append(caBundle, '\n'), ingressCA...)
+
+	loginConfig, err := os.CreateTemp("", "login-kubeconfig-")
+	if err != nil {
+		return fmt.Errorf("failed to create login kubeconfig: %w", err)
+	}
+	loginConfig.Close()
+	defer os.Remove(loginConfig.Name())
+
+	loginEnv := []string{fmt.Sprintf("KUBECONFIG=%s", loginConfig.Name())}
+	deadline := time.Now().Add(loginTimeout)
+	for {
+		err := s.login(loginConfig.Name(), loginEnv, server, caBundle, user, password)
+		if err == nil {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%s could not log in after %s, kubeadmin was not removed: %w", user, loginTimeout, err)
+		}
+		s.log.Debug(fmt.Sprintf("Login failed, retrying: %v", err))
+		if err := util.Sleep(s.executor, loginInterval); err != nil {
+			return err
+		}
+	}
+}
+
+// login gets a token for the user and checks that the API server accepts it.
+// The token is revoked afterwards.
+func (s *ConfigureIDP) login(kubeconfigPath string, loginEnv []string, server string, caBundle []byte, user, password string) error {
+	token, err := requestOAuthToken(server, caBundle, user, password)
+	if err != nil {
+		return err
+	}
+	logger.AddSecret(token)
+	if err := util.WriteTokenKubeconfig(kubeconfigPath, server, caBundle, user, token); err != nil {
+		return err
+	}
+	output, err := s.executor.ExecuteWithEnv("oc", loginEnv, "whoami")
+	if err != nil {
+		return fmt.Errorf("token of %s rejected: %w", user, err)
+	}
+	if strings.TrimSpace(output) != user {
+		return fmt.Errorf("logged in as %q instead of %s", strings.TrimSpace(output), user)
+	}
+	if _, err := s.executor.ExecuteWithEnv("oc", loginEnv, "logout"); err != nil {
+		s.log.Debug(fmt.Sprintf("Failed to revoke the login token: %v", err))
+	}
+	return nil
+}
+
+// htpasswdIdentityProvider is the entry of the OAuth configuration using the
+// htpasswd secret
+var htpasswdIdentityProvider = map[string]interface{}{
+	"name":          "htpasswd",
+	"mappingMethod": "claim",
+	"type":          "HTPasswd",
+	"htpasswd":      map[string]interface{}{"fileData": map[string]interface{}{"name": htpasswdSecretName}},
+}
+
+// addIdentityProvider adds the htpasswd identity provider to the OAuth
+// configuration, keeping the identity providers already configured
+func (s *ConfigureIDP) addIdentityProvider(envVars []string) error {
+	output, err := s.executor.ExecuteWithEnv("oc", envVars, "get", "oauth", "cluster", "-o", "jsonpath={.spec.identityProviders[*].name}")
+	if err != nil {
+		return fmt.Errorf("failed to read the OAuth configuration: %w", err)
+	}
+	patch, err := identityProviderPatch(strings.Fields(output))
+	if err != nil {
+		return err
+	}
+	if err := util.RunCommandWithEnv(s.executor, envVars, "oc", "patch", "oauth", "cluster", "--type=json", "-p", patch); err != nil {
+		return fmt.Errorf("failed to configure the identity provider: %w", err)
+	}
+	return nil
+}
+
+// identityProviderPatch returns the JSON patch adding the htpasswd identity
+// provider to the identity providers of the given names, or replacing it
+func identityProviderPatch(names []string) (string, error) {
+	operation := map[string]interface{}{"op": "add", "path": "/spec/identityProviders/-", "value": htpasswdIdentityProvider}
+	if len(names) == 0 {
+		operation["path"] = "/spec/identityProviders"
+		operation["value"] = []interface{}{htpasswdIdentityProvider}
+	}
+	for i, name := range names {
+		if name == htpasswdIdentityProvider["name"] {
+			operation["op"] = "replace"
+			operation["path"] = fmt.Sprintf("/spec/identityProviders/%d", i)
+		}
+	}
+	patch, err := json.Marshal([]interface{}{operation})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal the OAuth patch: %w", err)
+	}
+	return string(patch), nil
+}
+
+// writeHtpasswdSecret writes the htpasswd secret to a private file in dir,
+// returning its path
+func writeHtpasswdSecret(dir, htpasswd string) (string, error) {
+	manifest := fmt.Sprintf(`apiVersion: v1
+kind: Secret
+metadata:
+  name: %s
+  namespace: openshift-config
+type: Opaque
+data:
+  htpasswd: %s
+`, htpasswdSecretName, base64.StdEncoding.EncodeToString([]byte(htpasswd+"\n")))
+
+	file, err := os.CreateTemp(dir, "idp-*.yaml")
+	if err != nil {
+		return "", fmt.Errorf("failed to write identity provider manifest: %w", err)
+	}
+	defer file.Close()
+	if _, err := file.WriteString(manifest); err != nil {
+		os.Remove(file.Name())
+		return "", fmt.Errorf("failed to write identity provider manifest: %w", err)
+	}
+	return file.Name(), nil
+}
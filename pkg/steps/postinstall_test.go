@@ -0,0 +1,190 @@
+package steps
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/clobrano/openshift-sts-wrapper/pkg/config"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/logger"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/util"
+)
+
+func TestConfigureIDP(t *testing.T) {
+	tmpDir := t.TempDir()
+	originalWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(originalWd)
+
+	loginTimeout, loginInterval = 0, time.Millisecond
+	defer func() { loginTimeout, loginInterval = 10*time.Minute, 20*time.Second }()
+	t.Setenv("OPENSHIFT_STS_ADMIN_PASSWORD", "")
+
+	cfg := &config.Config{
+		ClusterName: "test-cluster",
+		PostInstall: config.PostInstall{AdminUser: "admin", RemoveKubeadmin: true},
+	}
+	log := logger.New(logger.LevelQuiet, nil)
+	executor := util.NewMockExecutor()
+
+	writeTestKubeconfig(t)
+	executor.SetOutput(`oc get configmap default-ingress-cert -n openshift-config-managed -o jsonpath={.data.ca-bundle\.crt}`, "ingress-ca")
+	executor.SetOutput("oc get oauth cluster -o jsonpath={.spec.identityProviders[*].name}", "")
+	executor.SetOutput("oc whoami", "admin\n")
+
+	var logins []string
+	original := requestOAuthToken
+	requestOAuthToken = func(server string, caBundle []byte, user, password string) (string, error) {
+		logins = append(logins, fmt.Sprintf("%s %s %s %s", server, caBundle, user, password))
+		return "sha256~token", nil
+	}
+	defer func() { requestOAuthToken = original }()
+
+	step := NewConfigureIDP(cfg, log, executor)
+	if err := step.Execute(); err != nil {
+		t.Fatalf("Step execution failed: %v", err)
+	}
+
+	password, err := os.ReadFile(GetAdminPasswordPath("test-cluster", "admin"))
+	if err != nil || len(password) == 0 {
+		t.Fatalf("Expected the generated password to be saved: %v", err)
+	}
+	if !executor.WasExecutedContaining("oc apply -f artifacts/clusters/test-cluster/idp-") {
+		t.Error("Expected the identity provider manifest to be applied")
+	}
+	if !executor.WasExecutedContaining(`oc patch oauth cluster --type=json -p [{"op":"add","path":"/spec/identityProviders","value":[{`) {
+		t.Errorf("Expected the identity provider to be added, got %v", executor.Commands)
+	}
+	if !executor.WasExecuted("oc adm policy add-cluster-role-to-user cluster-admin admin") {
+		t.Error("Expected cluster-admin to be granted to admin")
+	}
+	// The password never shows on a command line, and the cluster CAs are trusted
+	if len(logins) != 1 || logins[0] != fmt.Sprintf("https://api.test-cluster.example.com:6443 api-ca\ningress-ca admin %s", password) {
+		t.Errorf("Expected admin to log in with the cluster CAs, got %v", logins)
+	}
+	for _, command := range executor.Commands {
+		if strings.Contains(command, string(password)) || strings.Contains(command, "insecure") {
+			t.Errorf("Unexpected command %s", command)
+		}
+	}
+	if !executor.WasExecuted("oc whoami") || !executor.WasExecuted("oc logout") {
+		t.Errorf("Expected the token to be checked and revoked, got %v", executor.Commands)
+	}
+	if !executor.WasExecuted("oc delete secret kubeadmin -n kube-system --ignore-not-found") {
+		t.Error("Expected kubeadmin to be removed")
+	}
+
+	// The password of a previous run is reused
+	executor = util.NewMockExecutor()
+	cfg.PostInstall.RemoveKubeadmin = false
+	if err := NewConfigureIDP(cfg, log, executor).Execute(); err != nil {
+		t.Fatalf("Step execution failed: %v", err)
+	}
+	if again, _ := os.ReadFile(GetAdminPasswordPath("test-cluster", "admin")); string(again) != string(password) {
+		t.Errorf("Expected password %s to be reused, got %s", password, again)
+	}
+	if executor.WasExecutedContaining("kubeadmin") {
+		t.Error("Expected kubeadmin to be kept")
+	}
+}
+
+func TestConfigureIDPKeepsKubeadminWhenLoginFails(t *testing.T) {
+	tmpDir := t.TempDir()
+	originalWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(originalWd)
+
+	loginTimeout, loginInterval = 0, time.Millisecond
+	defer func() { loginTimeout, loginInterval = 10*time.Minute, 20*time.Second }()
+	t.Setenv("OPENSHIFT_STS_ADMIN_PASSWORD", "secret")
+
+	cfg := &config.Config{
+		ClusterName: "test-cluster",
+		PostInstall: config.PostInstall{AdminUser: "admin", RemoveKubeadmin: true},
+	}
+	executor := util.NewMockExecutor()
+	writeTestKubeconfig(t)
+
+	original := requestOAuthToken
+	requestOAuthToken = func(server string, caBundle []byte, user, password string) (string, error) {
+		return "", fmt.Errorf("failed to log in as admin: 401 Unauthorized")
+	}
+	defer func() { requestOAuthToken = original }()
+
+	err := NewConfigureIDP(cfg, logger.New(logger.LevelQuiet, nil), executor).Execute()
+	if err == nil || !strings.Contains(err.Error(), "kubeadmin was not removed") {
+		t.Errorf("Expected login failure, got %v", err)
+	}
+	if executor.WasExecutedContaining("oc delete secret kubeadmin") {
+		t.Error("kubeadmin must not be removed when the admin user cannot log in")
+	}
+}
+
+// writeTestKubeconfig writes the admin kubeconfig of test-cluster, with "api-ca"
+// as CA bundle
+func writeTestKubeconfig(t *testing.T) {
+	t.Helper()
+	os.MkdirAll("artifacts/clusters/test-cluster/auth", 0755)
+	kubeconfig := fmt.Sprintf(`apiVersion: v1
+clusters:
+- name: test-cluster
+  cluster:
+    server: https://api.test-cluster.example.com:6443
+    certificate-authority-data: %s
+contexts:
+- name: admin
+  context:
+    cluster: test-cluster
+    user: admin
+current-context: admin
+users:
+- name: admin
+  user:
+    client-certificate-data: Y2VydA==
+`, base64.StdEncoding.EncodeToString([]byte("api-ca")))
+	if err := os.WriteFile("artifacts/clusters/test-cluster/auth/kubeconfig", []byte(kubeconfig), 0600); err != nil {
+		t.Fatalf("Failed to write kubeconfig: %v", err)
+	}
+}
+
+func TestIdentityProviderPatch(t *testing.T) {
+	tests := []struct {
+		names    []string
+		expected string
+	}{
+		{nil, `[{"op":"add","path":"/spec/identityProviders","value":[{`},
+		{[]string{"ldap"}, `[{"op":"add","path":"/spec/identityProviders/-","value":{`},
+		{[]string{"ldap", "htpasswd"}, `[{"op":"replace","path":"/spec/identityProviders/1","value":{`},
+	}
+	for _, tt := range tests {
+		patch, err := identityProviderPatch(tt.names)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !strings.HasPrefix(patch, tt.expected) || !strings.Contains(patch, `"fileData":{"name":"htpasswd-secret"}`) {
+			t.Errorf("identityProviderPatch(%v) = %s", tt.names, patch)
+		}
+	}
+}
+
+func TestWriteHtpasswdSecret(t *testing.T) {
+	path, err := writeHtpasswdSecret(t.TempDir(), "admin:$apr1$abcdEFGH$ejwdyPabFjRPBqGaObU/N0")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	manifest := string(data)
+
+	encoded := base64.StdEncoding.EncodeToString([]byte("admin:$apr1$abcdEFGH$ejwdyPabFjRPBqGaObU/N0\n"))
+	for _, expected := range []string{"namespace: openshift-config", "htpasswd: " + encoded, "name: htpasswd-secret"} {
+		if !strings.Contains(manifest, expected) {
+			t.Errorf("Expected manifest to contain %q, got:\n%s", expected, manifest)
+		}
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0600 {
+		t.Errorf("Expected manifest permissions 0600, got %v", info.Mode().Perm())
+	}
+}
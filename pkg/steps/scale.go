@@ -0,0 +1,96 @@
+package steps
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/clobrano/openshift-sts-wrapper/pkg/config"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/logger"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/util"
+)
+
+// scalePollInterval is how often the MachineSets are checked while scaling
+var scalePollInterval = 15 * time.Second
+
+// ScaleWorkers is a day-2 step that sets the number of worker nodes, spread
+// across the worker MachineSets, and waits for them to be Ready
+type ScaleWorkers struct {
+	*BaseStep
+	workers int
+	timeout time.Duration
+}
+
+// NewScaleWorkers creates the scaling step. Like the post-install steps, it
+// doesn't need the release image.
+func NewScaleWorkers(cfg *config.Config, log *logger.Logger, executor util.CommandExecutor, workers int, timeout time.Duration) *ScaleWorkers {
+	return &ScaleWorkers{BaseStep: &BaseStep{cfg: cfg, log: log, executor: executor}, workers: workers, timeout: timeout}
+}
+
+func (s *ScaleWorkers) Name() string {
+	return fmt.Sprintf("Scale workers to %d", s.workers)
+}
+
+func (s *ScaleWorkers) Execute() error {
+	kubeconfigPath := util.GetKubeconfigPath(s.cfg.ClusterName)
+	if !util.FileExists(kubeconfigPath) {
+		return fmt.Errorf("kubeconfig not found at %s - cluster may not have been deployed successfully", kubeconfigPath)
+	}
+
+	machineSets, err := util.GetWorkerMachineSets(s.executor, kubeconfigPath)
+	if err != nil {
+		return err
+	}
+	if len(machineSets) == 0 {
+		return fmt.Errorf("no worker MachineSets found in the cluster")
+	}
+
+	for i, replicas := range util.DistributeReplicas(s.workers, len(machineSets)) {
+		if machineSets[i].Replicas == replicas {
+			continue
+		}
+		s.log.Info(fmt.Sprintf("Scaling %s from %d to %d", machineSets[i].Name, machineSets[i].Replicas, replicas))
+		if err := util.ScaleMachineSet(s.executor, kubeconfigPath, machineSets[i].Name, replicas); err != nil {
+			return fmt.Errorf("failed to scale %s: %w", machineSets[i].Name, err)
+		}
+	}
+
+	return s.waitForWorkers(kubeconfigPath)
+}
+
+// waitForWorkers waits until the worker MachineSets have as many machines as
+// desired and all of their nodes are Ready
+func (s *ScaleWorkers) waitForWorkers(kubeconfigPath string) error {
+	progress := "waiting for machines"
+	spinner := s.log.StartSpinner("Waiting for worker nodes", func() string { return progress })
+	defer spinner.Stop()
+
+	deadline := time.Now().Add(s.timeout)
+	for {
+		machineSets, err := util.GetWorkerMachineSets(s.executor, kubeconfigPath)
+		if err != nil {
+			s.log.Debug(fmt.Sprintf("Could not get machinesets: %v", err))
+		} else {
+			current, ready := 0, 0
+			for _, machineSet := range machineSets {
+				current += machineSet.Current
+				ready += machineSet.ReadyReplicas
+			}
+			progress = fmt.Sprintf("%d/%d workers ready, %d machines", ready, s.workers, current)
+			// Scaling down, the machines being deleted still count until they're gone
+			if current == s.workers && ready == s.workers {
+				break
+			}
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("workers not ready within %s: %s", s.timeout, progress)
+		}
+		if err := util.Sleep(s.executor, scalePollInterval); err != nil {
+			return err
+		}
+	}
+	spinner.Stop()
+
+	s.log.Info(fmt.Sprintf("✓ %d worker nodes ready", s.workers))
+	return nil
+}
@@ -0,0 +1,75 @@
+package steps
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/clobrano/openshift-sts-wrapper/pkg/config"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/logger"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/util"
+)
+
+const workerMachineSets = `{"items": [
+  {"metadata": {"name": "test-x7k2p-worker-us-east-2a"},
+   "spec": {"replicas": %d, "template": {"metadata": {"labels": {"machine.openshift.io/cluster-api-machine-role": "worker"}}}},
+   "status": {"replicas": %d, "readyReplicas": %d}},
+  {"metadata": {"name": "test-x7k2p-worker-us-east-2b"},
+   "spec": {"replicas": %d, "template": {"metadata": {"labels": {"machine.openshift.io/cluster-api-machine-role": "worker"}}}},
+   "status": {"replicas": %d, "readyReplicas": %d}}
+]}`
+
+// setupScaleCluster creates a deployed cluster whose two worker MachineSets
+// report the given replicas, all of them Ready
+func setupScaleCluster(t *testing.T, replicasA, replicasB int) *util.MockExecutor {
+	executor := setupDeployedCluster(t, map[*time.Duration]time.Duration{&scalePollInterval: time.Millisecond})
+	executor.SetOutput("oc get machinesets -n openshift-machine-api -o json",
+		fmt.Sprintf(workerMachineSets, replicasA, replicasA, replicasA, replicasB, replicasB, replicasB))
+	return executor
+}
+
+func TestScaleWorkers(t *testing.T) {
+	executor := setupScaleCluster(t, 2, 1)
+	cfg := &config.Config{ClusterName: "test-cluster"}
+
+	step := NewScaleWorkers(cfg, logger.New(logger.LevelQuiet, nil), executor, 3, time.Second)
+	if err := step.Execute(); err != nil {
+		t.Fatalf("Step execution failed: %v", err)
+	}
+	if executor.WasExecutedContaining("oc scale") {
+		t.Errorf("Expected MachineSets already at the desired size not to be scaled, got %v", executor.Commands)
+	}
+}
+
+func TestScaleWorkersTimeout(t *testing.T) {
+	executor := setupScaleCluster(t, 1, 1)
+	cfg := &config.Config{ClusterName: "test-cluster"}
+
+	// The mocked MachineSets never reach the new size
+	step := NewScaleWorkers(cfg, logger.New(logger.LevelQuiet, nil), executor, 5, 0)
+	err := step.Execute()
+	if err == nil || !strings.Contains(err.Error(), "workers not ready") {
+		t.Fatalf("Expected timeout error, got %v", err)
+	}
+	if !executor.WasExecuted("oc scale machineset test-x7k2p-worker-us-east-2a -n openshift-machine-api --replicas=3") {
+		t.Errorf("Expected the first MachineSet to get the extra worker, got %v", executor.Commands)
+	}
+	if !executor.WasExecuted("oc scale machineset test-x7k2p-worker-us-east-2b -n openshift-machine-api --replicas=2") {
+		t.Errorf("Expected the second MachineSet to be scaled, got %v", executor.Commands)
+	}
+}
+
+func TestScaleWorkersNotDeployed(t *testing.T) {
+	tmpDir := t.TempDir()
+	originalWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(originalWd)
+
+	cfg := &config.Config{ClusterName: "test-cluster"}
+	step := NewScaleWorkers(cfg, logger.New(logger.LevelQuiet, nil), util.NewMockExecutor(), 3, time.Second)
+	if err := step.Execute(); err == nil || !strings.Contains(err.Error(), "kubeconfig not found") {
+		t.Errorf("Expected missing kubeconfig error, got %v", err)
+	}
+}
@@ -0,0 +1,313 @@
+package steps
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/clobrano/openshift-sts-wrapper/pkg/config"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/util"
+)
+
+// Placeholders of the secrets in the install-config rendered by InstallScript,
+// replaced by the script with the content of their files
+const (
+	pullSecretPlaceholder = "__PULL_SECRET__"
+	sshKeyPlaceholder     = "__SSH_KEY__"
+)
+
+// InstallScript renders the commands of the installation steps selected by the
+// configuration as a standalone bash script, to be reviewed (e.g. by a change
+// review board) before anything runs against the AWS account. The
+// install-config is rendered like Steps 4 and 5 do; the pull secret and the SSH
+// key are read by the script when it runs, so that it holds no secret.
+func InstallScript(cfg *config.Config) (string, error) {
+	if unsupported := scriptUnsupported(cfg); len(unsupported) > 0 {
+		return "", fmt.Errorf("the install script can't reproduce %s", strings.Join(unsupported, ", "))
+	}
+	versionArch, err := util.ExtractVersionArch(cfg.ReleaseImage)
+	if err != nil {
+		return "", err
+	}
+
+	w := &scriptWriter{}
+	w.line("#!/usr/bin/env bash")
+	w.line("# Installation of cluster %s with release %s, rendered by", cfg.ClusterName, cfg.PullSpec())
+	w.line("# 'openshift-sts-wrapper install --emit-script'. Run it from the directory the")
+	w.line("# wrapper runs from: the paths are relative to it.")
+	w.line("set -euo pipefail")
+	w.line("")
+	w.line("export AWS_PROFILE=%s", shellQuote(cfg.AwsProfile))
+	w.line("export AWS_REGION=%s", shellQuote(cfg.AwsRegion))
+	w.line("PULL_SECRET=%s", shellQuote(cfg.PullSecretPath))
+	w.line("SSH_KEY=%s", shellQuote(cfg.SSHKeyPath))
+	if cfg.Binaries.OC != "" {
+		w.line(`export PATH=%s:"$PATH"`, shellQuote(filepath.Dir(cfg.Binaries.OC)))
+	}
+
+	for num := 1; num <= len(config.StepNames); num++ {
+		if !cfg.StepSelected(num) {
+			continue
+		}
+		step, err := NewInstallStep(num, cfg, nil, nil)
+		if err != nil {
+			return "", err
+		}
+		w.line("")
+		w.line("# [Step %d] %s", num, step.Name())
+		if err := writeStepScript(w, cfg, versionArch, num); err != nil {
+			return "", fmt.Errorf("step %d: %w", num, err)
+		}
+	}
+	return w.String(), nil
+}
+
+// scriptUnsupported returns the settings whose behavior the install script
+// can't reproduce with plain commands
+func scriptUnsupported(cfg *config.Config) []string {
+	var unsupported []string
+	settings := []struct {
+		name string
+		set  bool
+	}{
+		{"assumeRoleARN", cfg.AssumeRoleARN != ""},
+		{"vault", cfg.Vault.Enabled()},
+		{"iamRoles", cfg.ExternalIAM()},
+		{"iamRolePath", cfg.IAMRolePath != ""},
+		{"awsPartition", cfg.CustomPartition()},
+		{"tags", len(cfg.Tags) > 0},
+		{"extraManifestsDir", cfg.ExtraManifestsDir != ""},
+		{"releaseSigningKey", cfg.ReleaseSigningKey != ""},
+		{"verifyBinaries", cfg.VerifyBinaries},
+		{"postInstall", cfg.PostInstall.AdminUser != "" || len(cfg.PostInstall.Operators) > 0},
+		{"postInstallManifestsDir", cfg.PostInstallManifestsDir != ""},
+		{"mirror", cfg.Mirror.Registry != ""},
+		{"computePools", len(cfg.AdditionalComputePools()) > 0 || (cfg.WorkerPool() != nil && (len(cfg.WorkerPool().Labels) > 0 || len(cfg.WorkerPool().Taints) > 0))},
+	}
+	for _, setting := range settings {
+		if setting.set {
+			unsupported = append(unsupported, setting.name)
+		}
+	}
+	return unsupported
+}
+
+// writeStepScript writes the commands of step num
+func writeStepScript(w *scriptWriter, cfg *config.Config, versionArch string, num int) error {
+	clusterDir := util.GetClusterPath(cfg.ClusterName, "")
+	outputDir := util.GetClusterPath(cfg.ClusterName, "ccoctl-output")
+	credreqsPath := util.GetSharedCredReqsPath(versionArch)
+	installBin := BinaryPath(cfg, versionArch, "openshift-install")
+	ccoctlBin := BinaryPath(cfg, versionArch, "ccoctl")
+
+	if binary := ExternalBinary(cfg, num); binary != "" {
+		w.line("# Skipped: using %s", binary)
+		return nil
+	}
+
+	switch num {
+	case 1:
+		w.command("mkdir", "-p", credreqsPath)
+		w.command("oc", "adm", "release", "extract", "--credentials-requests", "--cloud=aws", "--to="+credreqsPath, cfg.PullSpec())
+
+	case 2:
+		w.command("mkdir", "-p", filepath.Dir(installBin))
+		w.command("oc", "adm", "release", "extract", "--command=openshift-install", "--command-os="+util.HostCommandOS(), "--to="+filepath.Dir(installBin), cfg.PullSpec())
+		w.command("chmod", "+x", installBin)
+		w.command("oc", "adm", "release", "extract", "--command=oc", "--command-os="+util.HostCommandOS(), "--to="+filepath.Dir(installBin), cfg.PullSpec())
+		if cfg.Binaries.OC == "" {
+			w.line(`export PATH="$PWD"/%s:"$PATH"`, shellQuote(filepath.Dir(installBin)))
+		}
+
+	case 3:
+		w.line("CCO_IMAGE=$(%s)", util.CommandLine("oc", "adm", "release", "info", "--image-for=cloud-credential-operator", cfg.PullSpec()))
+		w.command("mkdir", "-p", filepath.Dir(ccoctlBin))
+		w.line(`oc image extract "$CCO_IMAGE" --path=/usr/bin/ccoctl:%s --confirm --filter-by-os=%s --registry-config="$PULL_SECRET"`, shellQuote(filepath.Dir(ccoctlBin)), shellQuote(util.HostImageFilter()))
+		w.command("chmod", "+x", ccoctlBin)
+
+	case 4:
+		if cfg.GenerateSSHKey {
+			w.line(`[ -f "$SSH_KEY" ] || ssh-keygen -t ed25519 -N '' -C %s -f "${SSH_KEY%%.pub}"`, shellQuote("openshift-sts-wrapper-"+cfg.ClusterName))
+		}
+		content, err := scriptInstallConfig(cfg)
+		if err != nil {
+			return err
+		}
+		w.command("mkdir", "-p", clusterDir)
+		w.line("cat > %s <<EOF", shellQuote(util.GetInstallConfigPath(versionArch, cfg.ClusterName)))
+		content = heredocEscape(content)
+		content = strings.Replace(content, pullSecretPlaceholder, `'$(tr -d '\n' < "$PULL_SECRET")'`, 1)
+		content = strings.Replace(content, sshKeyPlaceholder, `'$(cat "$SSH_KEY")'`, 1)
+		w.text(content)
+		w.line("EOF")
+
+	case 5:
+		w.line("# credentialsMode: Manual and the machine pool settings are in the install-config of Step 4")
+
+	case 6:
+		w.command(installBin, "create", "manifests", "--dir", clusterDir)
+
+	case 7:
+		writeAWSResourcesScript(w, cfg, ccoctlBin, credreqsPath, outputDir)
+
+	case 8:
+		w.command("mkdir", "-p", filepath.Join(clusterDir, "manifests"))
+		w.line("cp -r %s/. %s/", shellQuote(filepath.Join(outputDir, "manifests")), shellQuote(filepath.Join(clusterDir, "manifests")))
+		return writeMachineConfigsScript(w, cfg)
+
+	case 9:
+		w.command("mkdir", "-p", filepath.Join(clusterDir, "tls"))
+		w.line("cp -r %s/. %s/", shellQuote(filepath.Join(outputDir, "tls")), shellQuote(filepath.Join(clusterDir, "tls")))
+		w.command("rm", "-rf", outputDir)
+
+	case 10:
+		w.command(installBin, "create", "cluster", "--dir", clusterDir)
+
+	case 11:
+		w.line("export KUBECONFIG=%s", shellQuote(util.GetClusterPath(cfg.ClusterName, "auth/kubeconfig")))
+		w.command("oc", "get", "clusterversion")
+		w.command("oc", "get", "clusteroperators")
+	}
+	return nil
+}
+
+// writeAWSResourcesScript writes the ccoctl commands of the sub-steps of Step 7
+func writeAWSResourcesScript(w *scriptWriter, cfg *config.Config, ccoctlBin, credreqsPath, outputDir string) {
+	signingKey := filepath.Join(outputDir, "tls", util.SigningKeyFile)
+	w.command("mkdir", "-p", filepath.Dir(signingKey))
+
+	providerARN := shellQuote(cfg.OIDCProviderARN)
+	if cfg.SharedOIDCProvider() {
+		w.command("cp", cfg.OIDCSigningKey, signingKey)
+		w.command("mkdir", "-p", filepath.Join(outputDir, "manifests"))
+		w.line("cat > %s <<'EOF'", shellQuote(filepath.Join(outputDir, "manifests", util.AuthenticationConfigFile)))
+		w.text(util.AuthenticationConfig(cfg.OIDCIssuerURL))
+		w.line("EOF")
+	} else {
+		w.command(ccoctlBin, "aws", "create-key-pair", "--output-dir", outputDir)
+		w.line("[ -f %s ] || cp %s %s", shellQuote(signingKey), shellQuote(filepath.Join(outputDir, "serviceaccount-signer.private")), shellQuote(signingKey))
+
+		args := []string{
+			"aws", "create-identity-provider",
+			"--name", cfg.CcoctlName(),
+			"--region", cfg.AwsRegion,
+			"--public-key-file", filepath.Join(outputDir, "serviceaccount-signer.public"),
+			"--output-dir", outputDir,
+		}
+		if cfg.PrivateBucket {
+			args = append(args, "--create-private-s3-bucket")
+		}
+		w.command(ccoctlBin, args...)
+
+		// The ARN of the identity provider is made of the account and the issuer
+		w.line("ISSUER=$(awk '/serviceAccountIssuer:/ {print $2}' %s)", shellQuote(filepath.Join(outputDir, "manifests", util.AuthenticationConfigFile)))
+		w.line("ACCOUNT=$(aws sts get-caller-identity --query Account --output text)")
+		w.line(`PROVIDER_ARN="arn:%s:iam::${ACCOUNT}:oidc-provider/${ISSUER#https://}"`, cfg.Partition())
+		providerARN = `"$PROVIDER_ARN"`
+	}
+
+	args := []string{
+		"aws", "create-iam-roles",
+		"--name", cfg.CcoctlName(),
+		"--region", cfg.AwsRegion,
+		"--credentials-requests-dir", credreqsPath,
+		"--output-dir", outputDir,
+	}
+	if cfg.PermissionsBoundary != "" {
+		args = append(args, "--permissions-boundary-arn", cfg.PermissionsBoundary)
+	}
+	w.line("%s --identity-provider-arn %s", util.CommandLine(ccoctlBin, args...), providerARN)
+}
+
+// writeMachineConfigsScript writes the MachineConfigs of the node
+// customizations, which Step 8 renders into the openshift/ directory
+func writeMachineConfigsScript(w *scriptWriter, cfg *config.Config) error {
+	manifests, err := util.RenderMachineConfigs(util.NodeCustomizations{
+		NTPServers:         cfg.NTPServers,
+		KernelArguments:    cfg.KernelArguments,
+		InsecureRegistries: cfg.InsecureRegistries,
+	})
+	if err != nil || len(manifests) == 0 {
+		return err
+	}
+
+	names := make([]string, 0, len(manifests))
+	for name := range manifests {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	dir := util.GetClusterPath(cfg.ClusterName, "openshift")
+	w.command("mkdir", "-p", dir)
+	for _, name := range names {
+		w.line("cat > %s <<'EOF'", shellQuote(filepath.Join(dir, name)))
+		w.text(string(manifests[name]))
+		w.line("EOF")
+	}
+	return nil
+}
+
+// scriptInstallConfig renders the install-config of Steps 4 and 5, with
+// placeholders instead of the pull secret and the SSH key
+func scriptInstallConfig(cfg *config.Config) (string, error) {
+	versionArch, err := util.ExtractVersionArch(cfg.ReleaseImage)
+	if err != nil {
+		return "", err
+	}
+	content, err := util.RenderInstallConfig(
+		cfg.ClusterName,
+		cfg.BaseDomain,
+		cfg.AwsRegion,
+		sshKeyPlaceholder,
+		pullSecretPlaceholder,
+		cfg.InstanceType,
+		util.ClusterArchitecture(util.ReleaseArch(versionArch)),
+	)
+	if err != nil {
+		return "", err
+	}
+	if cfg.StepSelected(5) {
+		step, err := NewStep5(cfg, nil, nil)
+		if err != nil {
+			return "", err
+		}
+		if content, err = step.patch(content); err != nil {
+			return "", err
+		}
+	}
+	// The SSH key is substituted as a quoted scalar, not a literal block
+	return strings.Replace(string(content), "|\n    "+sshKeyPlaceholder, sshKeyPlaceholder, 1), nil
+}
+
+// scriptWriter accumulates the lines of a script
+type scriptWriter struct {
+	strings.Builder
+}
+
+func (w *scriptWriter) line(format string, args ...interface{}) {
+	fmt.Fprintf(w, format+"\n", args...)
+}
+
+// text writes a block of text, e.g. the content of a heredoc
+func (w *scriptWriter) text(text string) {
+	w.WriteString(text)
+	if !strings.HasSuffix(text, "\n") {
+		w.WriteString("\n")
+	}
+}
+
+// command writes a command, quoting its arguments
+func (w *scriptWriter) command(name string, args ...string) {
+	w.line("%s", util.CommandLine(name, args...))
+}
+
+// shellQuote quotes a word for the shell, when needed
+func shellQuote(word string) string {
+	return util.CommandLine(word)
+}
+
+// heredocEscape escapes the characters the shell expands in an unquoted heredoc
+func heredocEscape(text string) string {
+	return strings.NewReplacer(`\`, `\\`, "$", `\$`, "`", "\\`").Replace(text)
+}
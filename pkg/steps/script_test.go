@@ -0,0 +1,118 @@
+package steps
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/clobrano/openshift-sts-wrapper/pkg/config"
+	"gopkg.in/yaml.v3"
+)
+
+func scriptConfig() *config.Config {
+	replicas := 2
+	return &config.Config{
+		ReleaseImage:   "quay.io/openshift-release-dev/ocp-release:4.15.0-x86_64",
+		ClusterName:    "test-cluster",
+		AwsRegion:      "us-east-2",
+		AwsProfile:     "default",
+		BaseDomain:     "example.com",
+		PullSecretPath: "pull-secret.json",
+		SSHKeyPath:     "id_ed25519.pub",
+		PrivateBucket:  true,
+		WorkerReplicas: &replicas,
+		NTPServers:     []string{"ntp.example.com"},
+	}
+}
+
+func TestInstallScript(t *testing.T) {
+	script, err := InstallScript(scriptConfig())
+	if err != nil {
+		t.Fatalf("Failed to render the script: %v", err)
+	}
+
+	for _, expected := range []string{
+		"set -euo pipefail",
+		"# [Step 1] Extract credentials requests",
+		"oc adm release extract --credentials-requests --cloud=aws --to=artifacts/shared/4.15.0-x86_64/credreqs quay.io/openshift-release-dev/ocp-release:4.15.0-x86_64",
+		"artifacts/shared/4.15.0-x86_64/bin/openshift-install create manifests --dir artifacts/clusters/test-cluster",
+		"artifacts/shared/4.15.0-x86_64/bin/ccoctl aws create-identity-provider --name test-cluster --region us-east-2",
+		"--create-private-s3-bucket",
+		`--identity-provider-arn "$PROVIDER_ARN"`,
+		"artifacts/clusters/test-cluster/openshift/99-master-chrony.yaml",
+		"artifacts/shared/4.15.0-x86_64/bin/openshift-install create cluster --dir artifacts/clusters/test-cluster",
+	} {
+		if !strings.Contains(script, expected) {
+			t.Errorf("Expected the script to contain %q, got:\n%s", expected, script)
+		}
+	}
+	if strings.Contains(script, pullSecretPlaceholder) || strings.Contains(script, sshKeyPlaceholder) {
+		t.Errorf("Expected the placeholders to be replaced, got:\n%s", script)
+	}
+}
+
+func TestInstallScriptInstallConfig(t *testing.T) {
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash not found")
+	}
+	tmpDir := t.TempDir()
+	originalWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(originalWd)
+
+	cfg := scriptConfig()
+	cfg.StartFromStep, cfg.StopAfterStep = 4, 5
+	script, err := InstallScript(cfg)
+	if err != nil {
+		t.Fatalf("Failed to render the script: %v", err)
+	}
+	os.WriteFile("pull-secret.json", []byte("{\n  \"auths\": {\"quay.io\": {\"auth\": \"dXNlcjpwYXNz\"}}\n}\n"), 0600)
+	os.WriteFile("id_ed25519.pub", []byte("ssh-ed25519 AAAAC3NzaC1lZDI1NTE5 user@host\n"), 0644)
+	os.WriteFile("install.sh", []byte(script), 0755)
+	if output, err := exec.Command(bash, "install.sh").CombinedOutput(); err != nil {
+		t.Fatalf("The script failed: %v\n%s", err, output)
+	}
+
+	data, err := os.ReadFile(filepath.Join("artifacts", "clusters", "test-cluster", "install-config.yaml"))
+	if err != nil {
+		t.Fatalf("Expected the script to write the install-config: %v", err)
+	}
+	var installConfig struct {
+		CredentialsMode string `yaml:"credentialsMode"`
+		PullSecret      string `yaml:"pullSecret"`
+		SSHKey          string `yaml:"sshKey"`
+		Compute         []struct {
+			Replicas int `yaml:"replicas"`
+		} `yaml:"compute"`
+	}
+	if err := yaml.Unmarshal(data, &installConfig); err != nil {
+		t.Fatalf("Invalid install-config: %v\n%s", err, data)
+	}
+	if installConfig.CredentialsMode != "Manual" {
+		t.Errorf("Expected credentialsMode Manual, got %q", installConfig.CredentialsMode)
+	}
+	if !strings.Contains(installConfig.PullSecret, `"auth": "dXNlcjpwYXNz"`) {
+		t.Errorf("Expected the pull secret of the file, got %q", installConfig.PullSecret)
+	}
+	if installConfig.SSHKey != "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5 user@host" {
+		t.Errorf("Expected the SSH key of the file, got %q", installConfig.SSHKey)
+	}
+	if len(installConfig.Compute) != 1 || installConfig.Compute[0].Replicas != 2 {
+		t.Errorf("Expected 2 compute replicas, got %+v", installConfig.Compute)
+	}
+}
+
+func TestInstallScriptUnsupported(t *testing.T) {
+	cfg := scriptConfig()
+	cfg.Tags = map[string]string{"team": "qe"}
+	cfg.IAMRolePath = "/openshift/"
+	cfg.AwsPartition = "aws-custom"
+
+	_, err := InstallScript(cfg)
+	if err == nil || !strings.Contains(err.Error(), "iamRolePath, awsPartition, tags") {
+		t.Errorf("Expected the unsupported settings to be reported, got %v", err)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
 
 	"github.com/clobrano/openshift-sts-wrapper/pkg/config"
@@ -21,6 +22,79 @@ type Step interface {
 	Execute() error
 }
 
+// ParallelSteps lists the steps that are independent of each other and can be
+// executed concurrently (they only extract artifacts from the release image)
+var ParallelSteps = map[int]bool{1: true, 2: true, 3: true}
+
+// CachedArtifacts returns the shared artifacts produced by a step, whose checksums
+// are recorded after the step succeeds so that later runs can verify them
+func CachedArtifacts(versionArch string, stepNum int) []string {
+	switch stepNum {
+	case 1:
+		return []string{util.GetSharedCredReqsPath(versionArch)}
+	case 2:
+		return []string{
+			util.GetSharedBinaryPath(versionArch, "openshift-install"),
+			util.GetSharedBinaryPath(versionArch, "oc"),
+			util.GetSharedBinaryPath(versionArch, "kubectl"),
+		}
+	case 3:
+		return []string{util.GetSharedBinaryPath(versionArch, "ccoctl")}
+	}
+	return nil
+}
+
+// PartialOutputs returns the outputs a step leaves behind when it is
+// interrupted, which are removed so that the step starts over on resume.
+// Step 7 resumes at its first incomplete sub-step: its outputs are only
+// removed while the key pair, which nothing in AWS depends on yet, is missing.
+func PartialOutputs(clusterName string, stepNum int) []string {
+	switch stepNum {
+	case 7:
+		if !AWSResourcesSubStepDone(clusterName, "", SubStepKeyPair) {
+			return []string{util.GetClusterPath(clusterName, "ccoctl-output")}
+		}
+	}
+	return nil
+}
+
+// NewInstallStep creates installation step num, from 1 (extract-credreqs) to
+// len(config.StepNames) (verify)
+func NewInstallStep(num int, cfg *config.Config, log *logger.Logger, executor util.CommandExecutor) (Step, error) {
+	var step Step
+	var err error
+	switch num {
+	case 1:
+		step, err = NewStep1(cfg, log, executor)
+	case 2:
+		step, err = NewStep2(cfg, log, executor)
+	case 3:
+		step, err = NewStep3(cfg, log, executor)
+	case 4:
+		step, err = NewStep4(cfg, log, executor)
+	case 5:
+		step, err = NewStep5(cfg, log, executor)
+	case 6:
+		step, err = NewStep6(cfg, log, executor)
+	case 7:
+		step, err = NewStep7(cfg, log, executor)
+	case 8:
+		step, err = NewStep8(cfg, log, executor)
+	case 9:
+		step, err = NewStep9(cfg, log, executor)
+	case 10:
+		step, err = NewStep10(cfg, log, executor)
+	case 11:
+		step, err = NewStep11(cfg, log, executor)
+	default:
+		return nil, fmt.Errorf("unknown step %d", num)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return step, nil
+}
+
 // BaseStep contains common fields for all steps
 type BaseStep struct {
 	cfg         *config.Config
@@ -43,6 +117,36 @@ func newBaseStep(cfg *config.Config, log *logger.Logger, executor util.CommandEx
 	}, nil
 }
 
+// mirrorArgs returns the arguments making oc pull the images of the release
+// through the mirror registry, when installing from one
+func (s *BaseStep) mirrorArgs() ([]string, error) {
+	if s.cfg.Mirror.Registry == "" {
+		return nil, nil
+	}
+	resources, err := util.ReadMirrorResources(util.GetSharedMirrorPath(s.versionArch))
+	if err != nil {
+		return nil, fmt.Errorf("%w (run 'openshift-sts-wrapper mirror' first)", err)
+	}
+	return resources.OCMirrorArgs(), nil
+}
+
+// releaseSignatureStore is where release signatures are downloaded from (the
+// official store when empty)
+var releaseSignatureStore = ""
+
+// verifyReleaseSignature checks the signature of the release image with the
+// configured signing key, if any, before binaries are extracted from it
+func (s *BaseStep) verifyReleaseSignature() error {
+	if s.cfg.ReleaseSigningKey == "" {
+		return nil
+	}
+	if err := util.VerifyReleaseSignature(s.executor, releaseSignatureStore, s.cfg.ReleaseSigningKey, s.cfg.ReleaseDigest); err != nil {
+		return fmt.Errorf("release signature verification failed: %w", err)
+	}
+	s.log.Info(fmt.Sprintf("✓ Release %s signature verified", s.cfg.ReleaseDigest))
+	return nil
+}
+
 // Step1ExtractCredReqs extracts credentials requests from the release image
 type Step1ExtractCredReqs struct {
 	*BaseStep
@@ -66,18 +170,23 @@ func (s *Step1ExtractCredReqs) Execute() error {
 		return fmt.Errorf("failed to create credreqs directory: %w", err)
 	}
 
+	mirrorArgs, err := s.mirrorArgs()
+	if err != nil {
+		return err
+	}
 	args := []string{
 		"adm", "release", "extract",
 		"--credentials-requests",
 		"--cloud=aws",
 		"--to=" + credreqsPath,
-		s.cfg.ReleaseImage,
 	}
+	args = append(args, mirrorArgs...)
 
-	return util.RunCommand(s.executor, "oc", args...)
+	return util.RunCommand(s.executor, "oc", append(args, s.cfg.PullSpec())...)
 }
 
-// Step2ExtractOpenshiftInstall extracts openshift-install binary
+// Step2ExtractOpenshiftInstall extracts openshift-install binary, and the oc
+// and kubectl clients of the release
 type Step2ExtractOpenshiftInstall struct {
 	*BaseStep
 }
@@ -91,10 +200,19 @@ func NewStep2(cfg *config.Config, log *logger.Logger, executor util.CommandExecu
 }
 
 func (s *Step2ExtractOpenshiftInstall) Name() string {
-	return "Extract openshift-install binary"
+	return "Extract openshift-install and oc binaries"
 }
 
 func (s *Step2ExtractOpenshiftInstall) Execute() error {
+	if err := s.verifyReleaseSignature(); err != nil {
+		return err
+	}
+
+	mirrorArgs, err := s.mirrorArgs()
+	if err != nil {
+		return err
+	}
+
 	binPath := filepath.Join("artifacts", "shared", s.versionArch, "bin")
 	if err := util.EnsureDir(binPath); err != nil {
 		return fmt.Errorf("failed to create bin directory: %w", err)
@@ -102,12 +220,15 @@ func (s *Step2ExtractOpenshiftInstall) Execute() error {
 
 	// Extract openshift-install
 	installBinPath := util.GetSharedBinaryPath(s.versionArch, "openshift-install")
+	// Extract the binary built for the host OS/architecture, which may differ
+	// from the cluster architecture (e.g. x86_64 cluster from an Apple Silicon laptop)
 	args := []string{
 		"adm", "release", "extract",
 		"--command=openshift-install",
+		"--command-os=" + util.HostCommandOS(),
 		"--to=" + binPath,
-		s.cfg.ReleaseImage,
 	}
+	args = append(append(args, mirrorArgs...), s.cfg.PullSpec())
 	if err := util.RunCommand(s.executor, "oc", args...); err != nil {
 		return fmt.Errorf("failed to extract openshift-install: %w", err)
 	}
@@ -115,6 +236,42 @@ func (s *Step2ExtractOpenshiftInstall) Execute() error {
 	// Make it executable
 	os.Chmod(installBinPath, 0755)
 
+	// The installer embeds the release image it installs
+	if s.cfg.VerifyBinaries {
+		if err := util.VerifyInstallerRelease(s.executor, installBinPath, s.cfg.ReleaseDigest); err != nil {
+			return fmt.Errorf("openshift-install verification failed: %w", err)
+		}
+		s.log.Info(fmt.Sprintf("✓ openshift-install built for release %s", s.cfg.ReleaseDigest))
+		if err := verifyClientChecksum(s.cfg, s.executor, s.versionArch, "openshift-install", util.HostCommandOS(), installBinPath); err != nil {
+			return fmt.Errorf("openshift-install verification failed: %w", err)
+		}
+		s.log.Info("✓ openshift-install matches the published checksum")
+	}
+
+	// The oc client of the release is then preferred to the system one,
+	// whose version may skew from the release
+	args = []string{
+		"adm", "release", "extract",
+		"--command=oc",
+		"--command-os=" + util.HostCommandOS(),
+		"--to=" + binPath,
+	}
+	args = append(append(args, mirrorArgs...), s.cfg.PullSpec())
+	if err := util.RunCommand(s.executor, "oc", args...); err != nil {
+		return fmt.Errorf("failed to extract oc: %w", err)
+	}
+	ocBinPath := util.GetSharedBinaryPath(s.versionArch, "oc")
+	os.Chmod(ocBinPath, 0755)
+
+	// oc writes kubectl next to it on most platforms; oc run as kubectl
+	// behaves like it
+	kubectlBinPath := util.GetSharedBinaryPath(s.versionArch, "kubectl")
+	if util.FileExists(ocBinPath) && !util.FileExists(kubectlBinPath) {
+		if err := os.Symlink("oc", kubectlBinPath); err != nil {
+			return fmt.Errorf("failed to create kubectl: %w", err)
+		}
+	}
+
 	return nil
 }
 
@@ -136,10 +293,19 @@ func (s *Step3ExtractCcoctl) Name() string {
 }
 
 func (s *Step3ExtractCcoctl) Execute() error {
+	if err := s.verifyReleaseSignature(); err != nil {
+		return err
+	}
+
 	ccoctlPath := util.GetSharedBinaryPath(s.versionArch, "ccoctl")
+	mirrorArgs, err := s.mirrorArgs()
+	if err != nil {
+		return err
+	}
 
 	// Get CCO image
-	ccoImageArgs := []string{"adm", "release", "info", "--image-for=cloud-credential-operator", s.cfg.ReleaseImage}
+	ccoImageArgs := append([]string{"adm", "release", "info", "--image-for=cloud-credential-operator"}, mirrorArgs...)
+	ccoImageArgs = append(ccoImageArgs, s.cfg.PullSpec())
 	ccoImage, err := s.executor.Execute("oc", ccoImageArgs...)
 	if err != nil {
 		return fmt.Errorf("failed to get CCO image: %w", err)
@@ -148,25 +314,65 @@ func (s *Step3ExtractCcoctl) Execute() error {
 	// Trim whitespace from CCO image reference
 	ccoImage = strings.TrimSpace(ccoImage)
 
-	// Extract ccoctl from CCO image (extracts to current directory)
+	// ccoctl comes from the CCO image of the release metadata, whose content is
+	// verified against its digest when extracted
+	if s.cfg.VerifyBinaries {
+		if s.cfg.ReleaseDigest == "" {
+			return fmt.Errorf("ccoctl verification failed: the release image digest is unknown")
+		}
+		if err := util.VerifyPinnedImage(ccoImage); err != nil {
+			return fmt.Errorf("ccoctl verification failed: %w", err)
+		}
+	}
+
+	// ccoctl is only shipped as a Linux binary inside the CCO image
+	if runtime.GOOS != "linux" {
+		s.log.Info(fmt.Sprintf("⚠  ccoctl is only available for Linux; the extracted binary may not run on %s", runtime.GOOS))
+	}
+
+	// Step 2 runs alongside and may not have created the bin directory yet
+	binDir := filepath.Dir(ccoctlPath)
+	if err := util.EnsureDir(binDir); err != nil {
+		return fmt.Errorf("failed to create bin directory: %w", err)
+	}
+	// Extract into a directory of this run, as other installations of the
+	// same release share the bin directory
+	extractDir, err := os.MkdirTemp(binDir, ".ccoctl-")
+	if err != nil {
+		return fmt.Errorf("failed to create extraction directory: %w", err)
+	}
+	defer os.RemoveAll(extractDir)
+
+	// Extract ccoctl from CCO image, selecting the host architecture from
+	// multi-arch images
 	extractArgs := []string{
 		"image", "extract",
 		ccoImage,
-		"--file=/usr/bin/ccoctl",
+		"--path=/usr/bin/ccoctl:" + extractDir,
+		"--confirm",
+		"--filter-by-os=" + util.HostImageFilter(),
 		"--registry-config=" + s.cfg.PullSecretPath,
 	}
+	extractArgs = append(extractArgs, mirrorArgs...)
 	if err := util.RunCommand(s.executor, "oc", extractArgs...); err != nil {
 		return fmt.Errorf("failed to extract ccoctl: %w", err)
 	}
 
 	// Move ccoctl to the bin directory
-	if err := os.Rename("ccoctl", ccoctlPath); err != nil {
+	if err := os.Rename(filepath.Join(extractDir, "ccoctl"), ccoctlPath); err != nil {
 		return fmt.Errorf("failed to move ccoctl to bin directory: %w", err)
 	}
 
 	// Make it executable
 	os.Chmod(ccoctlPath, 0755)
 
+	if s.cfg.VerifyBinaries {
+		if err := verifyClientChecksum(s.cfg, s.executor, s.versionArch, "ccoctl", util.HostImageFilter(), ccoctlPath); err != nil {
+			return fmt.Errorf("ccoctl verification failed: %w", err)
+		}
+		s.log.Info("✓ ccoctl matches the published checksum")
+	}
+
 	return nil
 }
 
@@ -207,6 +413,14 @@ func (s *Step4CreateConfig) Execute() error {
 			return fmt.Errorf("cannot read pull secret file: %w", err)
 		}
 
+		// Generate the SSH key pair, when decided at startup
+		if s.cfg.GenerateSSHKey && !util.FileExists(s.cfg.SSHKeyPath) {
+			if _, err := util.GenerateSSHKeyPair(filepath.Dir(s.cfg.SSHKeyPath), "openshift-sts-wrapper-"+s.cfg.ClusterName); err != nil {
+				return err
+			}
+			s.log.Info(fmt.Sprintf("✓ Generated SSH key pair %s", strings.TrimSuffix(s.cfg.SSHKeyPath, ".pub")))
+		}
+
 		// Read SSH key from file
 		sshKeyContent, err := os.ReadFile(s.cfg.SSHKeyPath)
 		if err != nil {
@@ -229,6 +443,7 @@ func (s *Step4CreateConfig) Execute() error {
 			strings.TrimSpace(string(sshKeyContent)),
 			compactPullSecret,
 			s.cfg.InstanceType,
+			util.ClusterArchitecture(util.ReleaseArch(s.versionArch)),
 		)
 		if err != nil {
 			return fmt.Errorf("failed to generate install-config.yaml: %w", err)
@@ -241,7 +456,7 @@ func (s *Step4CreateConfig) Execute() error {
 	s.log.Debug("Running interactive mode (decision from startup)")
 
 	// Run openshift-install create install-config (interactive)
-	installBin := util.GetSharedBinaryPath(s.versionArch, "openshift-install")
+	installBin := BinaryPath(s.cfg, s.versionArch, "openshift-install")
 	args := []string{"create", "install-config", "--dir", clusterDir}
 
 	// Get AWS credentials from profile and pass them as environment variables
@@ -307,9 +522,23 @@ func (s *Step5SetCredentialsMode) Execute() error {
 		return fmt.Errorf("failed to read install-config.yaml: %w", err)
 	}
 
+	out, err := s.patch(content)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(configPath, out, 0644); err != nil {
+		return fmt.Errorf("failed to write install-config.yaml: %w", err)
+	}
+
+	return nil
+}
+
+// patch sets the credentials mode and the configured machine pools, network
+// and AWS settings into the content of an install-config.yaml
+func (s *Step5SetCredentialsMode) patch(content []byte) ([]byte, error) {
 	var doc map[string]interface{}
 	if err := yaml.Unmarshal(content, &doc); err != nil {
-		return fmt.Errorf("failed to parse install-config.yaml: %w", err)
+		return nil, fmt.Errorf("failed to parse install-config.yaml: %w", err)
 	}
 
 	// Ensure credentialsMode: Manual exists at top-level
@@ -317,41 +546,54 @@ func (s *Step5SetCredentialsMode) Execute() error {
 		doc["credentialsMode"] = "Manual"
 	}
 
-	// Helper to ensure platform.aws.type is set in a machine pool-like object
-	desiredType := s.cfg.InstanceType
-	if strings.TrimSpace(desiredType) == "" {
-		desiredType = "m5.4xlarge"
+	// Helper to ensure platform.aws.type is set in a machine pool-like object.
+	// Pool-specific types always win over the type already in the file.
+	defaultType := s.cfg.InstanceType
+	if strings.TrimSpace(defaultType) == "" {
+		defaultType = config.DefaultInstanceTypeFor(util.ClusterArchitecture(util.ReleaseArch(s.versionArch)))
 	}
 
-	ensurePoolType := func(pool map[string]interface{}) {
-		platform, ok := pool["platform"].(map[string]interface{})
-		if !ok {
-			platform = map[string]interface{}{}
-			pool["platform"] = platform
+	ensurePool := func(pool map[string]interface{}, poolType, poolAMI string, replicas *int, zones []string) {
+		aws := platformAWS(pool)
+		if poolType != "" {
+			aws["type"] = poolType
+		} else if _, ok := aws["type"]; !ok || aws["type"] == "" {
+			aws["type"] = defaultType
+		}
+		if poolAMI != "" {
+			aws["amiID"] = poolAMI
 		}
-		aws, ok := platform["aws"].(map[string]interface{})
-		if !ok {
-			aws = map[string]interface{}{}
-			platform["aws"] = aws
+		if len(zones) > 0 {
+			aws["zones"] = toInterfaceSlice(zones)
 		}
-		if _, ok := aws["type"]; !ok || aws["type"] == "" {
-			aws["type"] = desiredType
+		if replicas != nil {
+			pool["replicas"] = *replicas
 		}
 	}
 
 	// controlPlane
 	if cpRaw, ok := doc["controlPlane"]; ok {
 		if cp, ok := cpRaw.(map[string]interface{}); ok {
-			ensurePoolType(cp)
+			ensurePool(cp, s.cfg.ControlPlaneType, s.cfg.ControlPlaneAMIID, s.cfg.ControlPlaneReplicas, s.cfg.Zones)
 		}
 	}
 
-	// compute (list of pools)
+	// compute (list of pools). The installer only accepts the worker pool:
+	// the additional compute pools are MachineSets written by Step 8.
+	workerType, workerZones := s.cfg.WorkerType, s.cfg.Zones
+	if pool := s.cfg.WorkerPool(); pool != nil {
+		if pool.InstanceType != "" {
+			workerType = pool.InstanceType
+		}
+		if len(pool.Zones) > 0 {
+			workerZones = pool.Zones
+		}
+	}
 	if compsRaw, ok := doc["compute"]; ok {
 		if comps, ok := compsRaw.([]interface{}); ok {
 			for i := range comps {
 				if pool, ok := comps[i].(map[string]interface{}); ok {
-					ensurePoolType(pool)
+					ensurePool(pool, workerType, s.cfg.WorkerAMIID, s.cfg.WorkerPoolReplicas(), workerZones)
 				}
 			}
 			// assign back in case underlying slice was modified
@@ -359,18 +601,121 @@ func (s *Step5SetCredentialsMode) Execute() error {
 		}
 	}
 
+	// Private clusters only expose their endpoints inside the VPC
+	if s.cfg.Private {
+		doc["publish"] = s.cfg.Publish()
+	}
+
+	// User tags are applied by the installer to every resource it creates
+	if len(s.cfg.Tags) > 0 {
+		userTags := map[string]interface{}{}
+		for key, value := range s.cfg.Tags {
+			userTags[key] = value
+		}
+		platformAWS(doc)["userTags"] = userTags
+	}
+
+	// Golden RHCOS image of every machine, pools may override it
+	if s.cfg.AMIID != "" {
+		platformAWS(doc)["amiID"] = s.cfg.AMIID
+	}
+
+	// Install into existing subnets
+	if len(s.cfg.Subnets) > 0 {
+		platformAWS(doc)["subnets"] = toInterfaceSlice(s.cfg.Subnets)
+	}
+
+	// Custom service endpoints (VPC endpoints, API proxies, GovCloud FIPS endpoints)
+	if len(s.cfg.ServiceEndpoints) > 0 {
+		mergeServiceEndpoints(platformAWS(doc), s.cfg.ServiceEndpoints)
+	}
+
+	// Disconnected installs pull the release (and operators) from the mirror
+	if s.cfg.Mirror.Registry != "" {
+		if err := s.patchMirror(doc); err != nil {
+			return nil, err
+		}
+	}
+
 	// Marshal back to YAML
 	out, err := yaml.Marshal(doc)
 	if err != nil {
-		return fmt.Errorf("failed to serialize install-config.yaml: %w", err)
+		return nil, fmt.Errorf("failed to serialize install-config.yaml: %w", err)
 	}
-	if err := os.WriteFile(configPath, out, 0644); err != nil {
-		return fmt.Errorf("failed to write install-config.yaml: %w", err)
+	return out, nil
+}
+
+// patchMirror sets the image mirrors written by oc-mirror, and the CA of the
+// mirror registry, into an install-config document
+func (s *Step5SetCredentialsMode) patchMirror(doc map[string]interface{}) error {
+	resources, err := util.ReadMirrorResources(util.GetSharedMirrorPath(s.versionArch))
+	if err != nil {
+		return fmt.Errorf("%w (run 'openshift-sts-wrapper mirror' first)", err)
+	}
+	// imageContentSources is deprecated from 4.14, which replaced it
+	key := "imageDigestSources"
+	if config.VersionOlder(util.ReleaseVersion(s.versionArch), "4.14") {
+		key = "imageContentSources"
 	}
+	doc[key] = resources.DigestSources
 
+	if s.cfg.Mirror.CABundle != "" {
+		bundle, err := os.ReadFile(s.cfg.Mirror.CABundle)
+		if err != nil {
+			return fmt.Errorf("failed to read mirror CA bundle: %w", err)
+		}
+		doc["additionalTrustBundle"] = string(bundle)
+		// The nodes pull from the registry, not only the proxy
+		doc["additionalTrustBundlePolicy"] = "Always"
+	}
 	return nil
 }
 
+// platformAWS returns the platform.aws section of an install-config document
+// (or of a machine pool), creating it if needed
+func platformAWS(doc map[string]interface{}) map[string]interface{} {
+	platform, ok := doc["platform"].(map[string]interface{})
+	if !ok {
+		platform = map[string]interface{}{}
+		doc["platform"] = platform
+	}
+	aws, ok := platform["aws"].(map[string]interface{})
+	if !ok {
+		aws = map[string]interface{}{}
+		platform["aws"] = aws
+	}
+	return aws
+}
+
+// mergeServiceEndpoints merges the configured service endpoints into the ones
+// of an install-config platform.aws section
+func mergeServiceEndpoints(aws map[string]interface{}, endpoints []config.ServiceEndpoint) {
+	var existing []config.ServiceEndpoint
+	items, _ := aws["serviceEndpoints"].([]interface{})
+	for _, item := range items {
+		if endpoint, ok := item.(map[string]interface{}); ok {
+			name, _ := endpoint["name"].(string)
+			url, _ := endpoint["url"].(string)
+			existing = append(existing, config.ServiceEndpoint{Name: name, URL: url})
+		}
+	}
+
+	merged := config.MergeServiceEndpoints(existing, endpoints)
+	list := make([]interface{}, len(merged))
+	for i, endpoint := range merged {
+		list[i] = map[string]interface{}{"name": endpoint.Name, "url": endpoint.URL}
+	}
+	aws["serviceEndpoints"] = list
+}
+
+func toInterfaceSlice(values []string) []interface{} {
+	items := make([]interface{}, len(values))
+	for i, value := range values {
+		items[i] = value
+	}
+	return items
+}
+
 // Step6CreateManifests runs openshift-install create manifests
 type Step6CreateManifests struct {
 	*BaseStep
@@ -390,7 +735,7 @@ func (s *Step6CreateManifests) Name() string {
 
 func (s *Step6CreateManifests) Execute() error {
 	clusterDir := util.GetClusterPath(s.cfg.ClusterName, "")
-	installBin := util.GetSharedBinaryPath(s.versionArch, "openshift-install")
+	installBin := BinaryPath(s.cfg, s.versionArch, "openshift-install")
 	args := []string{"create", "manifests", "--dir", clusterDir}
 
 	// Get AWS credentials from profile and pass them as environment variables
@@ -3,8 +3,10 @@ package steps
 import (
 	"fmt"
 	"io"
+	"net"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/clobrano/openshift-sts-wrapper/pkg/config"
 	"github.com/clobrano/openshift-sts-wrapper/pkg/logger"
@@ -14,6 +16,7 @@ import (
 // Step7CreateAWSResources runs ccoctl to create AWS resources
 type Step7CreateAWSResources struct {
 	*BaseStep
+	resuming bool // A previous run created the key pair, and may have left AWS resources
 }
 
 func NewStep7(cfg *config.Config, log *logger.Logger, executor util.CommandExecutor) (*Step7CreateAWSResources, error) {
@@ -29,9 +32,6 @@ func (s *Step7CreateAWSResources) Name() string {
 }
 
 func (s *Step7CreateAWSResources) Execute() error {
-	ccoctlBin := util.GetSharedBinaryPath(s.versionArch, "ccoctl")
-	credreqsPath := util.GetSharedCredReqsPath(s.versionArch)
-
 	// Cluster name is required from CLI flag
 	if s.cfg.ClusterName == "" {
 		return fmt.Errorf("cluster name is required (use --cluster-name flag)")
@@ -43,27 +43,310 @@ func (s *Step7CreateAWSResources) Execute() error {
 	}
 
 	outputDir := util.GetClusterPath(s.cfg.ClusterName, "ccoctl-output")
-	args := []string{
-		"aws", "create-all",
-		"--name", s.cfg.ClusterName,
-		"--region", s.cfg.AwsRegion,
-		"--credentials-requests-dir", credreqsPath,
-		"--output-dir", outputDir,
+	if s.cfg.ExternalIAM() {
+		return s.useExternalIAM(util.GetSharedCredReqsPath(s.versionArch), outputDir)
+	}
+
+	// Each sub-step is skipped when a previous run completed it, so that a
+	// failed run resumes where it stopped
+	s.resuming = AWSResourcesSubStepDone(s.cfg.ClusterName, s.versionArch, SubStepKeyPair)
+	for _, subStep := range AWSResourcesSubSteps {
+		if AWSResourcesSubStepDone(s.cfg.ClusterName, s.versionArch, subStep) {
+			s.log.Info(fmt.Sprintf("✓ %s already completed, skipping", subStep))
+			continue
+		}
+		s.log.Info(fmt.Sprintf("Running %s...", subStep))
+		if err := s.runSubStep(subStep, outputDir); err != nil {
+			return fmt.Errorf("%s failed: %w", subStep, err)
+		}
+	}
+
+	return s.tagResources(filepath.Join(outputDir, "manifests"))
+}
+
+// Sub-steps of Step 7, run with the ccoctl aws create-<sub-step> command
+const (
+	SubStepKeyPair          = "key-pair"
+	SubStepIdentityProvider = "identity-provider"
+	SubStepIAMRoles         = "iam-roles"
+)
+
+// AWSResourcesSubSteps lists the sub-steps of Step 7, in execution order
+var AWSResourcesSubSteps = []string{SubStepKeyPair, SubStepIdentityProvider, SubStepIAMRoles}
+
+// AWSResourcesSubStepDone reports whether a sub-step of Step 7 left all its
+// outputs in the ccoctl output directory of the cluster: the service account
+// signing key, the authentication config pointing to the identity provider,
+// and the credentials secret of every CredentialsRequest of the release
+func AWSResourcesSubStepDone(clusterName, versionArch, subStep string) bool {
+	outputDir := util.GetClusterPath(clusterName, "ccoctl-output")
+	switch subStep {
+	case SubStepKeyPair:
+		return util.FileExists(filepath.Join(outputDir, "tls", util.SigningKeyFile))
+	case SubStepIdentityProvider:
+		return util.FileExists(filepath.Join(outputDir, "manifests", util.AuthenticationConfigFile))
+	case SubStepIAMRoles:
+		requests, err := util.ReadCredentialsRequests(util.GetSharedCredReqsPath(versionArch))
+		if err != nil || len(requests) == 0 {
+			return false
+		}
+		for _, request := range requests {
+			if !util.FileExists(filepath.Join(outputDir, "manifests", request.SecretFile())) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// runSubStep runs the ccoctl command of a sub-step
+func (s *Step7CreateAWSResources) runSubStep(subStep, outputDir string) error {
+	if s.cfg.SharedOIDCProvider() {
+		return s.runSharedProviderSubStep(subStep, outputDir)
+	}
+
+	publicKey := filepath.Join(outputDir, "serviceaccount-signer.public")
+	switch subStep {
+	case SubStepKeyPair:
+		if err := s.runCcoctl("aws", "create-key-pair", "--output-dir", outputDir); err != nil {
+			return err
+		}
+		// The installer reads the private key from tls/
+		signingKey := filepath.Join(outputDir, "tls", util.SigningKeyFile)
+		if !util.FileExists(signingKey) {
+			if err := util.EnsureDir(filepath.Dir(signingKey)); err != nil {
+				return err
+			}
+			return util.CopyFile(filepath.Join(outputDir, "serviceaccount-signer.private"), signingKey)
+		}
+		return nil
+
+	case SubStepIdentityProvider:
+		if s.resuming {
+			existing := s.findExistingResources()
+			if len(existing.S3Buckets) > 0 || len(existing.OIDCProviders) > 0 {
+				return s.completeIdentityProvider(existing, publicKey, outputDir)
+			}
+		}
+		args := []string{
+			"aws", "create-identity-provider",
+			"--name", s.cfg.CcoctlName(),
+			"--region", s.cfg.AwsRegion,
+			"--public-key-file", publicKey,
+			"--output-dir", outputDir,
+		}
+		if s.cfg.PrivateBucket {
+			args = append(args, "--create-private-s3-bucket")
+		}
+		return s.runCcoctl(args...)
+
+	case SubStepIAMRoles:
+		resources, err := util.ReadCcoctlResources(filepath.Join(outputDir, "manifests"), s.cfg.CcoctlName())
+		if err != nil {
+			return err
+		}
+		providerARN, err := util.FindOIDCProviderARN(s.executor, s.cfg.AwsProfile, resources.IssuerURL)
+		if err != nil {
+			return err
+		}
+		return s.createIAMRoles(providerARN, outputDir)
 	}
+	return fmt.Errorf("unknown sub-step %s", subStep)
+}
+
+// runSharedProviderSubStep runs a sub-step against the existing OIDC provider:
+// its signing key and issuer are used as they are, and only the IAM roles of
+// the cluster are created
+func (s *Step7CreateAWSResources) runSharedProviderSubStep(subStep, outputDir string) error {
+	switch subStep {
+	case SubStepKeyPair:
+		signingKey := filepath.Join(outputDir, "tls", util.SigningKeyFile)
+		if err := util.EnsureDir(filepath.Dir(signingKey)); err != nil {
+			return err
+		}
+		return util.CopyFile(s.cfg.OIDCSigningKey, signingKey)
+
+	case SubStepIdentityProvider:
+		s.log.Info(fmt.Sprintf("Using the existing OIDC provider %s", s.cfg.OIDCProviderARN))
+		return util.WriteAuthenticationConfig(filepath.Join(outputDir, "manifests"), s.cfg.OIDCIssuerURL)
+
+	case SubStepIAMRoles:
+		return s.createIAMRoles(s.cfg.OIDCProviderARN, outputDir)
+	}
+	return fmt.Errorf("unknown sub-step %s", subStep)
+}
+
+// createIAMRoles creates the IAM roles of the CredentialsRequests of the release,
+// trusting the given OIDC provider. ccoctl fails on the roles a previous run
+// already created, so when some exist they are created (or updated) with the
+// aws CLI instead.
+func (s *Step7CreateAWSResources) createIAMRoles(providerARN, outputDir string) error {
+	if s.resuming && s.cfg.IAMRolePath == "" {
+		if existing := s.findExistingResources(); len(existing.IAMRoles) > 0 {
+			s.log.Info(fmt.Sprintf("Found %d IAM roles created by a previous run: creating the missing ones and updating the others", len(existing.IAMRoles)))
+			return util.CreateIAMRoles(s.executor, s.cfg.AwsProfile, util.GetSharedCredReqsPath(s.versionArch), outputDir, util.IAMRoleOptions{
+				Name:                s.cfg.CcoctlName(),
+				ProviderARN:         providerARN,
+				PermissionsBoundary: s.cfg.PermissionsBoundary,
+			})
+		}
+	}
+	return s.BaseStep.createIAMRoles(BinaryPath(s.cfg, s.versionArch, "ccoctl"), util.GetSharedCredReqsPath(s.versionArch), providerARN, outputDir)
+}
 
+// findExistingResources looks up the ccoctl resources of the cluster that a
+// previous run left in AWS. A failed lookup is reported as nothing found, so
+// that ccoctl runs as usual.
+func (s *Step7CreateAWSResources) findExistingResources() *util.ClusterResources {
+	resources, err := util.FindClusterResources(s.executor, util.ClusterResourceQuery{
+		Profile:        s.cfg.AwsProfile,
+		Region:         s.cfg.AwsRegion,
+		ClusterName:    s.cfg.ClusterName,
+		ResourcePrefix: s.cfg.ResourcePrefix,
+	})
+	if err != nil {
+		s.log.Info(fmt.Sprintf("⚠  Could not look up the AWS resources of a previous run: %v", err))
+		return &util.ClusterResources{}
+	}
+	return resources
+}
+
+// completeIdentityProvider completes the identity provider a previous run
+// started creating: ccoctl creates the S3 bucket, uploads the OIDC documents,
+// then creates the OIDC provider, and fails on the bucket or provider when run
+// again. The documents are generated by ccoctl --dry-run, uploaded to the
+// existing bucket, and the OIDC provider is created unless it exists.
+func (s *Step7CreateAWSResources) completeIdentityProvider(existing *util.ClusterResources, publicKey, outputDir string) error {
+	bucket := s.cfg.CcoctlName() + "-oidc"
+	if len(existing.S3Buckets) == 0 {
+		return fmt.Errorf("the OIDC provider %s of a previous run exists without its S3 bucket %s: delete it with cleanup, then run Step 7 again", existing.OIDCProviders[0], bucket)
+	}
 	if s.cfg.PrivateBucket {
-		args = append(args, "--create-private-s3-bucket")
+		return fmt.Errorf("a previous run left the S3 bucket %s of a private identity provider, which can't be completed: delete it with cleanup, then run Step 7 again", bucket)
+	}
+	s.log.Info(fmt.Sprintf("Found the S3 bucket %s created by a previous run: completing the identity provider", bucket))
+
+	dryRunDir := filepath.Join(outputDir, "identity-provider-dry-run")
+	defer os.RemoveAll(dryRunDir)
+	if err := s.runCcoctl("aws", "create-identity-provider",
+		"--name", s.cfg.CcoctlName(),
+		"--region", s.cfg.AwsRegion,
+		"--public-key-file", publicKey,
+		"--output-dir", dryRunDir,
+		"--dry-run",
+	); err != nil {
+		return err
+	}
+	files, err := util.ReadIdentityProviderFiles(dryRunDir)
+	if err != nil {
+		return err
 	}
 
+	if err := util.UploadOIDCDocuments(s.executor, s.cfg.AwsProfile, s.cfg.AwsRegion, bucket, files); err != nil {
+		return err
+	}
+	if len(existing.OIDCProviders) > 0 {
+		s.log.Info(fmt.Sprintf("✓ OIDC provider %s already exists", existing.OIDCProviders[0]))
+	} else {
+		providerARN, err := util.CreateOIDCProvider(s.executor, s.cfg.AwsProfile, files)
+		if err != nil {
+			return err
+		}
+		s.log.Info(fmt.Sprintf("Created the OIDC provider %s", providerARN))
+	}
+	return util.WriteAuthenticationConfig(filepath.Join(outputDir, "manifests"), files.IssuerURL)
+}
+
+// runCcoctl runs the ccoctl of the release
+func (s *Step7CreateAWSResources) runCcoctl(args ...string) error {
+	return s.runCcoctlBinary(BinaryPath(s.cfg, s.versionArch, "ccoctl"), args...)
+}
+
+// runCcoctlBinary runs ccoctl with the credentials of the AWS profile
+func (b *BaseStep) runCcoctlBinary(ccoctlBin string, args ...string) error {
 	// Get AWS credentials from profile and set as environment variables
-	awsEnv, err := util.GetAWSEnvVars(s.cfg.AwsProfile)
+	awsEnv, err := util.GetAWSEnvVars(b.cfg.AwsProfile)
 	if err != nil {
-		s.log.Debug(fmt.Sprintf("Could not read AWS credentials from profile '%s': %v", s.cfg.AwsProfile, err))
-		s.log.Debug("Proceeding without setting AWS credentials from profile")
-		return util.RunCommand(s.executor, ccoctlBin, args...)
+		b.log.Debug(fmt.Sprintf("Could not read AWS credentials from profile '%s': %v", b.cfg.AwsProfile, err))
+		b.log.Debug("Proceeding without setting AWS credentials from profile")
+		return util.RunCommand(b.executor, ccoctlBin, args...)
+	}
+	return util.RunCommandWithEnv(b.executor, awsEnv, ccoctlBin, args...)
+}
+
+// createIAMRoles creates the IAM roles of the CredentialsRequests of
+// credreqsDir, trusting the given OIDC provider, and writes their credentials
+// secrets into <outputDir>/manifests. ccoctl cannot create the roles under an
+// IAM path, nor in a partition other than the one of the region (it takes no
+// partition): with iamRolePath or a custom awsPartition, they are created with
+// the aws CLI instead.
+func (b *BaseStep) createIAMRoles(ccoctlBin, credreqsDir, providerARN, outputDir string) error {
+	if b.cfg.IAMRolePath != "" || b.cfg.CustomPartition() {
+		if b.cfg.IAMRolePath != "" {
+			b.log.Info(fmt.Sprintf("Creating the IAM roles under the IAM path %s...", b.cfg.IAMRolePath))
+		} else {
+			b.log.Info(fmt.Sprintf("Creating the IAM roles in the %s partition...", b.cfg.Partition()))
+		}
+		return util.CreateIAMRoles(b.executor, b.cfg.AwsProfile, credreqsDir, outputDir, util.IAMRoleOptions{
+			Name:                b.cfg.CcoctlName(),
+			ProviderARN:         providerARN,
+			Path:                b.cfg.IAMRolePath,
+			PermissionsBoundary: b.cfg.PermissionsBoundary,
+		})
+	}
+
+	args := []string{
+		"aws", "create-iam-roles",
+		"--name", b.cfg.CcoctlName(),
+		"--region", b.cfg.AwsRegion,
+		"--credentials-requests-dir", credreqsDir,
+		"--identity-provider-arn", providerARN,
+		"--output-dir", outputDir,
+	}
+	if b.cfg.PermissionsBoundary != "" {
+		args = append(args, "--permissions-boundary-arn", b.cfg.PermissionsBoundary)
 	}
+	return b.runCcoctlBinary(ccoctlBin, args...)
+}
 
-	return util.RunCommandWithEnv(s.executor, awsEnv, ccoctlBin, args...)
+// useExternalIAM generates the manifests ccoctl would have, pointing to the
+// configured IAM roles and OIDC provider. Nothing is created (nor tagged) in AWS.
+func (s *Step7CreateAWSResources) useExternalIAM(credreqsPath, outputDir string) error {
+	s.log.Info(fmt.Sprintf("Using %d existing IAM roles and the OIDC provider of %s", len(s.cfg.IAMRoles), s.cfg.OIDCIssuerURL))
+	unused, err := util.WriteExternalIAMManifests(credreqsPath, outputDir, util.ExternalIAM{
+		Roles:      s.cfg.IAMRoles,
+		IssuerURL:  s.cfg.OIDCIssuerURL,
+		SigningKey: s.cfg.OIDCSigningKey,
+	})
+	if err != nil {
+		return err
+	}
+	for _, key := range unused {
+		s.log.Info(fmt.Sprintf("⚠  iamRoles entry %s matches no CredentialsRequest of the release", key))
+	}
+	return nil
+}
+
+// tagResources applies the configured user tags to the resources created by ccoctl
+func (s *Step7CreateAWSResources) tagResources(manifestsDir string) error {
+	if len(s.cfg.Tags) == 0 {
+		return nil
+	}
+
+	resources, err := util.ReadCcoctlResources(manifestsDir, s.cfg.CcoctlName())
+	if err != nil {
+		return err
+	}
+
+	if s.cfg.SharedOIDCProvider() {
+		// The shared OIDC provider belongs to no cluster, and there is no bucket
+		resources.OIDCProviderARN, resources.BucketName = "", ""
+		s.log.Info(fmt.Sprintf("Tagging %d IAM roles...", len(resources.RoleNames)))
+	} else {
+		s.log.Info(fmt.Sprintf("Tagging %d IAM roles, the OIDC provider and the S3 bucket...", len(resources.RoleNames)))
+	}
+	return util.TagCcoctlResources(s.executor, s.cfg.AwsProfile, s.cfg.AwsRegion, resources, s.cfg.Tags)
 }
 
 // Step8CopyManifests copies manifests from _output to manifests/
@@ -91,7 +374,116 @@ func (s *Step8CopyManifests) Execute() error {
 		return err
 	}
 
-	return copyDir(srcDir, dstDir)
+	if err := copyDir(srcDir, dstDir); err != nil {
+		return err
+	}
+	machineConfigs, err := s.writeMachineConfigs()
+	if err != nil {
+		return err
+	}
+	if err := s.writeComputePools(); err != nil {
+		return err
+	}
+	if err := s.copyTagMirrorSets(); err != nil {
+		return err
+	}
+	return s.copyExtraManifests(machineConfigs)
+}
+
+// copyTagMirrorSets copies the ImageTagMirrorSets written by oc-mirror into
+// the manifests: install-config.yaml only has the mirrors of images pulled by
+// digest
+func (s *Step8CopyManifests) copyTagMirrorSets() error {
+	if s.cfg.Mirror.Registry == "" {
+		return nil
+	}
+	resources, err := util.ReadMirrorResources(util.GetSharedMirrorPath(s.versionArch))
+	if err != nil {
+		return err
+	}
+	for _, path := range resources.TagMirrorSets {
+		target := util.GetClusterPath(s.cfg.ClusterName, filepath.Join("manifests", filepath.Base(path)))
+		if err := util.CopyFile(path, target); err != nil {
+			return fmt.Errorf("failed to copy %s: %w", path, err)
+		}
+		s.log.Debug(fmt.Sprintf("Copied %s to manifests/", path))
+	}
+	return nil
+}
+
+// writeComputePools adds the node labels and taints of the worker pool to its
+// MachineSets, and writes the MachineSets of the additional compute pools
+func (s *Step8CopyManifests) writeComputePools() error {
+	dir := util.GetClusterPath(s.cfg.ClusterName, "openshift")
+	if pool := s.cfg.WorkerPool(); pool != nil && (len(pool.Labels) > 0 || len(pool.Taints) > 0) {
+		if err := util.LabelWorkerMachineSets(dir, pool.Labels, pool.Taints); err != nil {
+			return err
+		}
+		s.log.Info("✓ Added the node labels and taints of the worker pool")
+	}
+
+	for _, pool := range s.cfg.AdditionalComputePools() {
+		instanceType := s.cfg.ComputePoolInstanceType(pool)
+		paths, err := util.WriteComputePoolMachineSets(dir, util.MachinePool{
+			Name:         pool.Name,
+			InstanceType: instanceType,
+			Replicas:     *pool.Replicas,
+			Zones:        pool.Zones,
+			Labels:       pool.Labels,
+			Taints:       pool.Taints,
+		})
+		if err != nil {
+			return err
+		}
+		s.log.Info(fmt.Sprintf("✓ Wrote %d MachineSets for the %s compute pool (%d × %s)", len(paths), pool.Name, *pool.Replicas, instanceType))
+	}
+	return nil
+}
+
+// writeMachineConfigs renders the configured node customizations into
+// MachineConfigs in the openshift/ directory, and returns their paths relative
+// to the cluster directory (extra manifests of the same name replace them)
+func (s *Step8CopyManifests) writeMachineConfigs() (map[string]bool, error) {
+	manifests, err := util.RenderMachineConfigs(util.NodeCustomizations{
+		NTPServers:         s.cfg.NTPServers,
+		KernelArguments:    s.cfg.KernelArguments,
+		InsecureRegistries: s.cfg.InsecureRegistries,
+	})
+	if err != nil || len(manifests) == 0 {
+		return nil, err
+	}
+
+	dir := util.GetClusterPath(s.cfg.ClusterName, "openshift")
+	if err := util.EnsureDir(dir); err != nil {
+		return nil, err
+	}
+	written := map[string]bool{}
+	for name, data := range manifests {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+			return nil, fmt.Errorf("failed to write MachineConfig %s: %w", name, err)
+		}
+		written[filepath.Join("openshift", name)] = true
+	}
+	s.log.Info(fmt.Sprintf("✓ Wrote %d MachineConfigs for the node customizations", len(manifests)))
+	return written, nil
+}
+
+// copyExtraManifests copies the manifests of the configured extra manifests
+// directory, which openshift-install applies with its own in Step 10. Only the
+// rendered MachineConfigs can be replaced.
+func (s *Step8CopyManifests) copyExtraManifests(machineConfigs map[string]bool) error {
+	if s.cfg.ExtraManifestsDir == "" {
+		return nil
+	}
+	manifests, err := util.CopyExtraManifests(s.cfg.ExtraManifestsDir, util.GetClusterPath(s.cfg.ClusterName, ""), machineConfigs)
+	if err != nil {
+		return err
+	}
+	for _, manifest := range manifests {
+		s.log.Debug(fmt.Sprintf("Copied %s to %s/", manifest.Path, manifest.Target))
+	}
+	s.log.Info(fmt.Sprintf("✓ Copied %d extra manifests from %s", len(manifests), s.cfg.ExtraManifestsDir))
+	return nil
 }
 
 // Step9CopyTLS copies TLS files from _output to ./
@@ -153,26 +545,67 @@ func (s *Step10DeployCluster) Name() string {
 
 func (s *Step10DeployCluster) Execute() error {
 	clusterDir := util.GetClusterPath(s.cfg.ClusterName, "")
-	installBin := util.GetSharedBinaryPath(s.versionArch, "openshift-install")
-	args := []string{"create", "cluster", "--dir", clusterDir, "--log-level=debug"}
+	installBin := BinaryPath(s.cfg, s.versionArch, "openshift-install")
+	args := []string{"create", "cluster", "--dir", clusterDir}
+
+	// openshift-install writes its full debug log in the cluster directory, so
+	// its output is not streamed: a spinner shows the current install phase
+	logPath := util.GetInstallLogPath(s.cfg.ClusterName)
+	s.log.Info(fmt.Sprintf("Deploying the cluster takes 30-45 minutes. Follow the installer log with: tail -f %s", logPath))
+	milestones := util.NewInstallLogWatcher(logPath)
+	spinner := s.log.StartSpinner("Deploying cluster", func() string {
+		s.logMilestones(milestones)
+		return util.LastInstallLogMessage(logPath)
+	})
 
 	// Get AWS credentials from profile and set as environment variables
+	var output string
 	awsEnv, err := util.GetAWSEnvVars(s.cfg.AwsProfile)
 	if err != nil {
 		s.log.Debug(fmt.Sprintf("Could not read AWS credentials from profile '%s': %v", s.cfg.AwsProfile, err))
 		s.log.Debug("Proceeding without setting AWS credentials from profile")
-		// Use interactive execution to stream output in real-time
-		return s.executor.ExecuteInteractive(installBin, args...)
+		output, err = s.executor.Execute(installBin, args...)
+	} else {
+		output, err = s.executor.ExecuteWithEnv(installBin, awsEnv, args...)
+	}
+	spinner.Stop()
+	s.logMilestones(milestones)
+
+	if err != nil {
+		return fmt.Errorf("openshift-install create cluster failed: %w\n%s\nSee %s for the full log", err, lastLines(output, 10), logPath)
 	}
 
-	// TODO: do not print the output stream in real-time anymore. Show a clear message to where finding the logs (suggest use `tail -f` maybe), but show a dynamic symbol to show that the process is running
-	// Use interactive execution with env vars to stream output in real-time
-	return s.executor.ExecuteInteractiveWithEnv(installBin, awsEnv, args...)
+	// Show how to access the cluster, as reported by the installer
+	if index := strings.Index(output, "Install complete!"); index >= 0 {
+		start := strings.LastIndex(output[:index], "\n") + 1
+		for _, line := range strings.Split(strings.TrimSpace(output[start:]), "\n") {
+			s.log.Info(line)
+		}
+	}
+	return nil
+}
+
+// logMilestones shows the install milestones reached since the previous call
+func (s *Step10DeployCluster) logMilestones(watcher *util.InstallLogWatcher) {
+	for _, milestone := range watcher.Poll() {
+		s.log.Info(fmt.Sprintf("  [%s] %s", milestone.Time.Local().Format("15:04:05"), milestone.Name))
+	}
+}
+
+// lastLines returns the last n lines of a command output
+func lastLines(output string, n int) string {
+	lines := strings.Split(strings.TrimRight(output, "\n"), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
 }
 
 // Step11Verify performs post-install verification
 type Step11Verify struct {
 	*BaseStep
+	Checks []string      // IDs of the checks to run (see VerifyChecks), all when empty
+	Report *VerifyReport // Outcome of the last execution
 }
 
 func NewStep11(cfg *config.Config, log *logger.Logger, executor util.CommandExecutor) (*Step11Verify, error) {
@@ -188,36 +621,63 @@ func (s *Step11Verify) Name() string {
 }
 
 func (s *Step11Verify) Execute() error {
-	// Set KUBECONFIG environment variable to point to the kubeconfig file
-	kubeconfigPath := util.GetClusterPath(s.cfg.ClusterName, "auth/kubeconfig")
-	if !util.FileExists(kubeconfigPath) {
-		return fmt.Errorf("kubeconfig not found at %s - cluster may not have been deployed successfully", kubeconfigPath)
+	report, err := s.Verify()
+	if err != nil {
+		return err
 	}
+	if failed := report.Failed(); len(failed) > 0 {
+		return fmt.Errorf("verification failed: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
 
-	envVars := []string{fmt.Sprintf("KUBECONFIG=%s", kubeconfigPath)}
+// Verify runs the selected checks against the cluster and returns their
+// outcome. The error is only set when the checks cannot run at all.
+func (s *Step11Verify) Verify() (*VerifyReport, error) {
+	checks, err := SelectVerifyChecks(s.Checks, s.cfg)
+	if err != nil {
+		return nil, err
+	}
 
-	// Check 1: Root credentials should not exist
-	_, err := s.executor.ExecuteWithEnv("oc", envVars, "get", "secrets", "-n", "kube-system", "aws-creds")
-	if err == nil {
-		s.log.Error("WARNING: Root credentials secret exists (expected it to not exist)")
-	} else {
-		s.log.Info("✓ Root credentials secret does not exist (as expected)")
+	// Set KUBECONFIG environment variable to point to the kubeconfig file
+	kubeconfigPath := util.GetKubeconfigPath(s.cfg.ClusterName)
+	if !util.FileExists(kubeconfigPath) {
+		return nil, fmt.Errorf("kubeconfig not found at %s - cluster may not have been deployed successfully", kubeconfigPath)
 	}
+	c := &verifyContext{BaseStep: s.BaseStep, envVars: []string{fmt.Sprintf("KUBECONFIG=%s", kubeconfigPath)}}
 
-	// Check 2: Components should use IAM roles
-	output, err := s.executor.ExecuteWithEnv("oc", envVars, "get", "secrets", "-n", "openshift-image-registry",
-		"installer-cloud-credentials", "-o", "json")
-	if err != nil {
-		return fmt.Errorf("failed to check IAM role usage: %w", err)
+	// Private clusters are verified through the internal API endpoint
+	if s.cfg.Private {
+		server, err := s.internalAPIServer()
+		if err != nil {
+			return nil, err
+		}
+		s.log.Info(fmt.Sprintf("Private cluster: verifying through the internal API endpoint %s", server))
+		c.serverArgs = []string{"--server=" + server}
 	}
 
-	if len(output) > 0 && (contains(output, "role_arn") || contains(output, "web_identity_token_file")) {
-		s.log.Info("✓ Components are using IAM roles")
-	} else {
-		s.log.Error("WARNING: Components may not be using IAM roles correctly")
+	s.Report = runVerifyChecks(c, checks)
+	return s.Report, nil
+}
+
+// internalAPIServer returns the internal API endpoint of the cluster, failing
+// when it can't be resolved (i.e. the wrapper is not running inside the VPC)
+func (s *Step11Verify) internalAPIServer() (string, error) {
+	baseDomain := s.cfg.BaseDomain
+	if baseDomain == "" {
+		// install-config.yaml is consumed by Step 6, read the backup taken after Step 5
+		fields, err := util.ExtractAllFields(util.GetInstallConfigPath(s.versionArch, s.cfg.ClusterName) + ".backup")
+		if err != nil {
+			return "", fmt.Errorf("cannot determine base domain for the internal API endpoint: %w", err)
+		}
+		baseDomain = fields.BaseDomain
 	}
 
-	return nil
+	host := fmt.Sprintf("api-int.%s.%s", s.cfg.ClusterName, baseDomain)
+	if _, err := net.LookupHost(host); err != nil {
+		return "", fmt.Errorf("internal API endpoint %s cannot be resolved: private clusters can only be verified from inside the VPC (e.g. through a VPN or bastion host)", host)
+	}
+	return fmt.Sprintf("https://%s:6443", host), nil
 }
 
 // Helper function to copy directories
@@ -264,17 +724,3 @@ func copyFile(src, dst string) error {
 	_, err = io.Copy(destFile, sourceFile)
 	return err
 }
-
-func contains(haystack, needle string) bool {
-	return len(haystack) >= len(needle) && haystack != "" && needle != "" &&
-		findSubstring(haystack, needle)
-}
-
-func findSubstring(haystack, needle string) bool {
-	for i := 0; i <= len(haystack)-len(needle); i++ {
-		if haystack[i:i+len(needle)] == needle {
-			return true
-		}
-	}
-	return false
-}
@@ -2,6 +2,8 @@ package steps
 
 import (
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/clobrano/openshift-sts-wrapper/pkg/config"
@@ -9,6 +11,82 @@ import (
 	"github.com/clobrano/openshift-sts-wrapper/pkg/util"
 )
 
+// ccoctlExecutor writes the outputs of the ccoctl aws create-* commands, and
+// lists the OIDC provider created with the aws CLI
+type ccoctlExecutor struct {
+	*util.MockExecutor
+}
+
+func (e *ccoctlExecutor) Execute(name string, args ...string) (string, error) {
+	return e.ExecuteWithEnv(name, nil, args...)
+}
+
+func (e *ccoctlExecutor) ExecuteWithEnv(name string, env []string, args ...string) (string, error) {
+	output, err := e.MockExecutor.ExecuteWithEnv(name, env, args...)
+	if name == "aws" && len(args) > 1 && args[1] == "create-open-id-connect-provider" && err == nil {
+		// The created provider is listed from now on
+		e.SetOutput("aws iam list-open-id-connect-providers --output json", `{"OpenIDConnectProviderList": [
+		{"Arn": "arn:aws:iam::123456789012:oidc-provider/test-cluster-oidc.s3.us-east-2.amazonaws.com"}]}`)
+	}
+	if filepath.Base(name) != "ccoctl" || len(args) < 2 || err != nil {
+		return output, err
+	}
+	var outputDir string
+	for i := range args[:len(args)-1] {
+		if args[i] == "--output-dir" {
+			outputDir = args[i+1]
+		}
+	}
+	switch args[1] {
+	case "create-key-pair":
+		os.MkdirAll(outputDir, 0755)
+		os.WriteFile(filepath.Join(outputDir, "serviceaccount-signer.private"), []byte("private"), 0600)
+		os.WriteFile(filepath.Join(outputDir, "serviceaccount-signer.public"), []byte("public"), 0644)
+	case "create-identity-provider":
+		if args[len(args)-1] == "--dry-run" {
+			os.MkdirAll(outputDir, 0755)
+			os.WriteFile(filepath.Join(outputDir, "02-openid-configuration"), []byte(`{"issuer": "https://test-cluster-oidc.s3.us-east-2.amazonaws.com"}`), 0644)
+			os.WriteFile(filepath.Join(outputDir, "03-keys.json"), []byte(`{"keys": []}`), 0644)
+			os.WriteFile(filepath.Join(outputDir, "04-iam-identity-provider.json"), []byte(`{"Url": "https://test-cluster-oidc.s3.us-east-2.amazonaws.com"}`), 0644)
+			break
+		}
+		os.MkdirAll(filepath.Join(outputDir, "manifests"), 0755)
+		os.WriteFile(filepath.Join(outputDir, "manifests", util.AuthenticationConfigFile),
+			[]byte("spec:\n  serviceAccountIssuer: https://test-cluster-oidc.s3.us-east-2.amazonaws.com\n"), 0644)
+	case "create-iam-roles":
+		os.WriteFile(filepath.Join(outputDir, "manifests", "openshift-ingress-operator-cloud-credentials-credentials.yaml"),
+			[]byte("role_arn = arn:aws:iam::123456789012:role/test-cluster-openshift-ingress-operator-cloud-credentials\n"), 0644)
+	}
+	return output, nil
+}
+
+// setupStep7 writes the CredentialsRequests of the release and returns an
+// executor simulating ccoctl
+func setupStep7(t *testing.T) *ccoctlExecutor {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	credreqsPath := util.GetSharedCredReqsPath("4.12.0-x86_64")
+	os.MkdirAll(credreqsPath, 0755)
+	os.WriteFile(filepath.Join(credreqsPath, "0000_50_ingress.yaml"), []byte(`apiVersion: cloudcredential.openshift.io/v1
+kind: CredentialsRequest
+metadata:
+  name: openshift-ingress
+  namespace: openshift-cloud-credential-operator
+spec:
+  secretRef:
+    name: cloud-credentials
+    namespace: openshift-ingress-operator
+`), 0644)
+
+	executor := &ccoctlExecutor{MockExecutor: util.NewMockExecutor()}
+	executor.SetOutput("aws iam list-open-id-connect-providers --output json", `{"OpenIDConnectProviderList": [
+		{"Arn": "arn:aws:iam::123456789012:oidc-provider/other-oidc.s3.us-east-2.amazonaws.com"},
+		{"Arn": "arn:aws:iam::123456789012:oidc-provider/test-cluster-oidc.s3.us-east-2.amazonaws.com"}]}`)
+	executor.SetOutput("aws s3api get-bucket-tagging --bucket test-cluster-oidc --output json --region us-east-2",
+		`{"TagSet": [{"Key": "openshift.io/cloud-credential-operator/test-cluster", "Value": "owned"}]}`)
+	return executor
+}
+
 func TestStep7CreateAWSResources(t *testing.T) {
 	tmpDir := t.TempDir()
 	originalWd, _ := os.Getwd()
@@ -21,11 +99,7 @@ func TestStep7CreateAWSResources(t *testing.T) {
 		AwsRegion:    "us-east-2",
 	}
 	log := logger.New(logger.LevelQuiet, nil)
-	executor := util.NewMockExecutor()
-
-	// Create required directories
-	os.MkdirAll("artifacts/4.12.0-x86_64/bin", 0755)
-	os.MkdirAll("artifacts/4.12.0-x86_64/credreqs", 0755)
+	executor := setupStep7(t)
 
 	step, err := NewStep7(cfg, log, executor)
 	if err != nil {
@@ -37,14 +111,170 @@ func TestStep7CreateAWSResources(t *testing.T) {
 		t.Fatalf("Step execution failed: %v", err)
 	}
 
-	if !executor.WasExecutedContaining("ccoctl") {
-		t.Error("Expected ccoctl command to be executed")
+	for _, command := range []string{
+		"aws create-key-pair",
+		"aws create-identity-provider --name test-cluster --region us-east-2",
+		"--identity-provider-arn arn:aws:iam::123456789012:oidc-provider/test-cluster-oidc.s3.us-east-2.amazonaws.com",
+	} {
+		if !executor.WasExecutedContaining(command) {
+			t.Errorf("Expected %q in the commands, got %v", command, executor.Commands)
+		}
+	}
+	if !util.FileExists(util.GetClusterPath("test-cluster", "ccoctl-output/tls/"+util.SigningKeyFile)) {
+		t.Error("Expected the private key in ccoctl-output/tls")
+	}
+	if !NewDetector(cfg).ShouldSkipStep(7) {
+		t.Error("Step 7 should be detected as completed")
+	}
+}
+
+func TestStep7Resume(t *testing.T) {
+	tmpDir := t.TempDir()
+	originalWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(originalWd)
+
+	cfg := &config.Config{
+		ReleaseImage: "quay.io/test:4.12.0-x86_64",
+		ClusterName:  "test-cluster",
+		AwsRegion:    "us-east-2",
+	}
+	log := logger.New(logger.LevelQuiet, nil)
+	executor := setupStep7(t)
+
+	// A previous run created the key pair and the identity provider, then failed
+	outputDir := util.GetClusterPath("test-cluster", "ccoctl-output")
+	executor.ExecuteWithEnv("ccoctl", nil, "aws", "create-key-pair", "--output-dir", outputDir)
+	executor.ExecuteWithEnv("ccoctl", nil, "aws", "create-identity-provider", "--output-dir", outputDir)
+	os.MkdirAll(filepath.Join(outputDir, "tls"), 0755)
+	os.WriteFile(filepath.Join(outputDir, "tls", util.SigningKeyFile), []byte("private"), 0600)
+	executor.Commands = nil
+	if NewDetector(cfg).ShouldSkipStep(7) {
+		t.Fatal("Step 7 should not be detected as completed without the IAM roles")
+	}
+	if len(PartialOutputs("test-cluster", 7)) != 0 {
+		t.Error("The outputs of the completed sub-steps should be kept on interruption")
+	}
+
+	step, err := NewStep7(cfg, log, executor)
+	if err != nil {
+		t.Fatalf("Failed to create step: %v", err)
+	}
+	if err := step.Execute(); err != nil {
+		t.Fatalf("Step execution failed: %v", err)
 	}
-	if !executor.WasExecutedContaining("aws create-all") {
-		t.Error("Expected 'aws create-all' in command")
+
+	if executor.WasExecutedContaining("create-key-pair") || executor.WasExecutedContaining("create-identity-provider") {
+		t.Errorf("Completed sub-steps should be skipped, got %v", executor.Commands)
 	}
-	if !executor.WasExecutedContaining("--name test-cluster") {
-		t.Error("Expected cluster name in command")
+	if !executor.WasExecutedContaining("aws create-iam-roles") {
+		t.Errorf("Expected the IAM roles to be created, got %v", executor.Commands)
+	}
+}
+
+func TestStep7ResumeExistingIdentityProviderBucket(t *testing.T) {
+	tmpDir := t.TempDir()
+	originalWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(originalWd)
+
+	cfg := &config.Config{
+		ReleaseImage: "quay.io/test:4.12.0-x86_64",
+		ClusterName:  "test-cluster",
+		AwsRegion:    "us-east-2",
+	}
+	log := logger.New(logger.LevelQuiet, nil)
+	executor := setupStep7(t)
+
+	// A previous run created the key pair and the S3 bucket, then failed (e.g.
+	// throttled) before creating the OIDC provider
+	outputDir := util.GetClusterPath("test-cluster", "ccoctl-output")
+	executor.ExecuteWithEnv("ccoctl", nil, "aws", "create-key-pair", "--output-dir", outputDir)
+	os.MkdirAll(filepath.Join(outputDir, "tls"), 0755)
+	os.WriteFile(filepath.Join(outputDir, "tls", util.SigningKeyFile), []byte("private"), 0600)
+	executor.Commands = nil
+	executor.SetOutput("aws iam list-roles --output json", `{"Roles": []}`)
+	executor.SetOutput("aws iam list-open-id-connect-providers --output json", `{"OpenIDConnectProviderList": [
+		{"Arn": "arn:aws:iam::123456789012:oidc-provider/other-oidc.s3.us-east-2.amazonaws.com"}]}`)
+	providerInput := filepath.Join(outputDir, "identity-provider-dry-run", "04-iam-identity-provider.json")
+	executor.SetOutput("aws iam create-open-id-connect-provider --cli-input-json file://"+providerInput+" --output json",
+		`{"OpenIDConnectProviderArn": "arn:aws:iam::123456789012:oidc-provider/test-cluster-oidc.s3.us-east-2.amazonaws.com"}`)
+
+	step, err := NewStep7(cfg, log, executor)
+	if err != nil {
+		t.Fatalf("Failed to create step: %v", err)
+	}
+	if err := step.Execute(); err != nil {
+		t.Fatalf("Step execution failed: %v", err)
+	}
+
+	for _, command := range []string{
+		"--output-dir " + filepath.Join(outputDir, "identity-provider-dry-run") + " --dry-run",
+		"aws s3api put-object --bucket test-cluster-oidc --key .well-known/openid-configuration",
+		"aws s3api put-object --bucket test-cluster-oidc --key keys.json",
+		"aws iam create-open-id-connect-provider",
+		"aws create-iam-roles",
+	} {
+		if !executor.WasExecutedContaining(command) {
+			t.Errorf("Expected %q in the commands, got %v", command, executor.Commands)
+		}
+	}
+	if executor.WasExecutedContaining("--output-dir " + outputDir + " ") {
+		t.Errorf("ccoctl should not create the identity provider again, got %v", executor.Commands)
+	}
+	content, err := os.ReadFile(filepath.Join(outputDir, "manifests", util.AuthenticationConfigFile))
+	if err != nil || !strings.Contains(string(content), "https://test-cluster-oidc.s3.us-east-2.amazonaws.com") {
+		t.Errorf("Expected the authentication config with the issuer of the bucket, got %q (%v)", content, err)
+	}
+	if util.DirExists(filepath.Join(outputDir, "identity-provider-dry-run")) {
+		t.Error("The dry run output should be removed")
+	}
+}
+
+func TestStep7ResumeExistingIAMRoles(t *testing.T) {
+	tmpDir := t.TempDir()
+	originalWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(originalWd)
+
+	cfg := &config.Config{
+		ReleaseImage: "quay.io/test:4.12.0-x86_64",
+		ClusterName:  "test-cluster",
+		AwsRegion:    "us-east-2",
+	}
+	log := logger.New(logger.LevelQuiet, nil)
+	executor := setupStep7(t)
+
+	// A previous run created the identity provider and some of the IAM roles
+	outputDir := util.GetClusterPath("test-cluster", "ccoctl-output")
+	executor.ExecuteWithEnv("ccoctl", nil, "aws", "create-key-pair", "--output-dir", outputDir)
+	executor.ExecuteWithEnv("ccoctl", nil, "aws", "create-identity-provider", "--output-dir", outputDir)
+	os.MkdirAll(filepath.Join(outputDir, "tls"), 0755)
+	os.WriteFile(filepath.Join(outputDir, "tls", util.SigningKeyFile), []byte("private"), 0600)
+	executor.Commands = nil
+	role := "test-cluster-openshift-ingress-operator-cloud-credentials"
+	executor.SetOutput("aws iam list-roles --output json", `{"Roles": [{"RoleName": "`+role+`"}]}`)
+	executor.SetOutput("aws iam list-role-tags --role-name "+role+" --output json",
+		`{"Tags": [{"Key": "openshift.io/cloud-credential-operator/test-cluster", "Value": "owned"}]}`)
+
+	step, err := NewStep7(cfg, log, executor)
+	if err != nil {
+		t.Fatalf("Failed to create step: %v", err)
+	}
+	if err := step.Execute(); err != nil {
+		t.Fatalf("Step execution failed: %v", err)
+	}
+
+	if executor.WasExecutedContaining("aws create-iam-roles") {
+		t.Errorf("ccoctl should not create the IAM roles again, got %v", executor.Commands)
+	}
+	for _, command := range []string{"aws iam create-role --role-name " + role, "aws iam put-role-policy --role-name " + role} {
+		if !executor.WasExecutedContaining(command) {
+			t.Errorf("Expected %q in the commands, got %v", command, executor.Commands)
+		}
+	}
+	if !NewDetector(cfg).ShouldSkipStep(7) {
+		t.Error("Step 7 should be detected as completed")
 	}
 }
 
@@ -61,10 +291,7 @@ func TestStep7WithPrivateBucket(t *testing.T) {
 		PrivateBucket: true,
 	}
 	log := logger.New(logger.LevelQuiet, nil)
-	executor := util.NewMockExecutor()
-
-	os.MkdirAll("artifacts/4.12.0-x86_64/bin", 0755)
-	os.MkdirAll("artifacts/4.12.0-x86_64/credreqs", 0755)
+	executor := setupStep7(t)
 
 	step, err := NewStep7(cfg, log, executor)
 	if err != nil {
@@ -81,6 +308,189 @@ func TestStep7WithPrivateBucket(t *testing.T) {
 	}
 }
 
+func TestStep7WithResourcePrefix(t *testing.T) {
+	tmpDir := t.TempDir()
+	originalWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(originalWd)
+
+	cfg := &config.Config{
+		ReleaseImage:   "quay.io/test:4.12.0-x86_64",
+		ClusterName:    "test-cluster",
+		AwsRegion:      "us-east-2",
+		ResourcePrefix: "team-a",
+	}
+	log := logger.New(logger.LevelQuiet, nil)
+	executor := setupStep7(t)
+
+	step, err := NewStep7(cfg, log, executor)
+	if err != nil {
+		t.Fatalf("Failed to create step: %v", err)
+	}
+	if err := step.Execute(); err != nil {
+		t.Fatalf("Step execution failed: %v", err)
+	}
+
+	for _, command := range []string{"aws create-identity-provider --name team-a --region", "aws create-iam-roles --name team-a --region"} {
+		if !executor.WasExecutedContaining(command) {
+			t.Errorf("Expected %q in the commands, got %v", command, executor.Commands)
+		}
+	}
+	// The artifacts are still kept under the cluster name
+	if !util.FileExists(util.GetClusterPath("test-cluster", "ccoctl-output/tls/"+util.SigningKeyFile)) {
+		t.Error("Expected the ccoctl output in the cluster directory")
+	}
+}
+
+func TestStep7WithPermissionsBoundary(t *testing.T) {
+	tmpDir := t.TempDir()
+	originalWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(originalWd)
+
+	cfg := &config.Config{
+		ReleaseImage:        "quay.io/test:4.12.0-x86_64",
+		ClusterName:         "test-cluster",
+		AwsRegion:           "us-east-2",
+		PermissionsBoundary: "arn:aws:iam::123456789012:policy/boundary",
+	}
+	log := logger.New(logger.LevelQuiet, nil)
+	executor := setupStep7(t)
+
+	step, err := NewStep7(cfg, log, executor)
+	if err != nil {
+		t.Fatalf("Failed to create step: %v", err)
+	}
+	if err := step.Execute(); err != nil {
+		t.Fatalf("Step execution failed: %v", err)
+	}
+
+	if !executor.WasExecutedContaining("--permissions-boundary-arn arn:aws:iam::123456789012:policy/boundary") {
+		t.Errorf("Expected the permissions boundary to be passed to ccoctl, got %v", executor.Commands)
+	}
+
+	// ccoctl has no IAM path option: the roles are created with the aws CLI
+	cfg.IAMRolePath = "/openshift/"
+	os.RemoveAll(util.GetClusterPath("test-cluster", "ccoctl-output/manifests/openshift-ingress-operator-cloud-credentials-credentials.yaml"))
+	executor.Commands = nil
+	if err := step.Execute(); err != nil {
+		t.Fatalf("Step execution failed: %v", err)
+	}
+	if executor.WasExecutedContaining("create-iam-roles") || !executor.WasExecutedContaining("aws iam create-role --role-name test-cluster-openshift-ingress-operator-cloud-credentials --path /openshift/") {
+		t.Errorf("Expected the roles to be created under the IAM path, got %v", executor.Commands)
+	}
+
+	// ccoctl takes no partition: the roles of a custom one are created with the aws CLI
+	cfg.IAMRolePath = ""
+	cfg.AwsPartition = "aws-custom"
+	os.RemoveAll(util.GetClusterPath("test-cluster", "ccoctl-output/manifests/openshift-ingress-operator-cloud-credentials-credentials.yaml"))
+	executor.Commands = nil
+	if err := step.Execute(); err != nil {
+		t.Fatalf("Step execution failed: %v", err)
+	}
+	if executor.WasExecutedContaining("create-iam-roles") || !executor.WasExecutedContaining("aws iam create-role --role-name test-cluster-openshift-ingress-operator-cloud-credentials") {
+		t.Errorf("Expected the roles of the custom partition to be created with the aws CLI, got %v", executor.Commands)
+	}
+}
+
+func TestStep7ExternalIAM(t *testing.T) {
+	tmpDir := t.TempDir()
+	originalWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(originalWd)
+
+	cfg := &config.Config{
+		ReleaseImage:   "quay.io/test:4.12.0-x86_64",
+		ClusterName:    "test-cluster",
+		AwsRegion:      "us-east-2",
+		IAMRoles:       map[string]string{"openshift-ingress-operator/cloud-credentials": "arn:aws:iam::123456789012:role/ingress"},
+		OIDCIssuerURL:  "https://oidc.example.com",
+		OIDCSigningKey: "signing.key",
+	}
+	log := logger.New(logger.LevelQuiet, nil)
+	executor := util.NewMockExecutor()
+
+	credreqsPath := util.GetSharedCredReqsPath("4.12.0-x86_64")
+	os.MkdirAll(credreqsPath, 0755)
+	os.WriteFile(filepath.Join(credreqsPath, "0000_50_ingress.yaml"), []byte(`apiVersion: cloudcredential.openshift.io/v1
+kind: CredentialsRequest
+metadata:
+  name: openshift-ingress
+  namespace: openshift-cloud-credential-operator
+spec:
+  secretRef:
+    name: cloud-credentials
+    namespace: openshift-ingress-operator
+`), 0644)
+	os.WriteFile("signing.key", []byte("key"), 0600)
+
+	step, err := NewStep7(cfg, log, executor)
+	if err != nil {
+		t.Fatalf("Failed to create step: %v", err)
+	}
+	if err := step.Execute(); err != nil {
+		t.Fatalf("Step execution failed: %v", err)
+	}
+
+	if executor.WasExecutedContaining("ccoctl") {
+		t.Error("Expected no ccoctl command with existing IAM roles")
+	}
+	if !util.FileContains(util.GetClusterPath("test-cluster", "ccoctl-output/manifests/openshift-ingress-operator-cloud-credentials-credentials.yaml"), "role/ingress") {
+		t.Error("Expected the credentials secret of the existing role")
+	}
+	if !util.FileExists(util.GetClusterPath("test-cluster", "ccoctl-output/tls/bound-service-account-signing-key.key")) {
+		t.Error("Expected the service account signing key")
+	}
+}
+
+func TestStep7SharedOIDCProvider(t *testing.T) {
+	tmpDir := t.TempDir()
+	originalWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(originalWd)
+
+	cfg := &config.Config{
+		ReleaseImage:    "quay.io/test:4.12.0-x86_64",
+		ClusterName:     "test-cluster",
+		AwsRegion:       "us-east-2",
+		OIDCProviderARN: "arn:aws:iam::123456789012:oidc-provider/oidc.example.com",
+		OIDCIssuerURL:   "https://oidc.example.com",
+		OIDCSigningKey:  "signing.key",
+		Tags:            map[string]string{"team": "qe"},
+	}
+	log := logger.New(logger.LevelQuiet, nil)
+	executor := setupStep7(t)
+	os.WriteFile("signing.key", []byte("shared signing key"), 0600)
+
+	step, err := NewStep7(cfg, log, executor)
+	if err != nil {
+		t.Fatalf("Failed to create step: %v", err)
+	}
+	if err := step.Execute(); err != nil {
+		t.Fatalf("Step execution failed: %v", err)
+	}
+
+	if executor.WasExecutedContaining("create-key-pair") || executor.WasExecutedContaining("create-identity-provider") {
+		t.Errorf("Expected no key pair nor identity provider to be created, got %v", executor.Commands)
+	}
+	if !executor.WasExecutedContaining("aws create-iam-roles --name test-cluster --region us-east-2") ||
+		!executor.WasExecutedContaining("--identity-provider-arn "+cfg.OIDCProviderARN) {
+		t.Errorf("Expected the IAM roles to trust the existing OIDC provider, got %v", executor.Commands)
+	}
+	if executor.WasExecutedContaining("tag-open-id-connect-provider") || executor.WasExecutedContaining("put-bucket-tagging") {
+		t.Errorf("Expected only the IAM roles to be tagged, got %v", executor.Commands)
+	}
+	if !util.FileContains(util.GetClusterPath("test-cluster", "ccoctl-output/manifests/"+util.AuthenticationConfigFile), "serviceAccountIssuer: https://oidc.example.com") {
+		t.Error("Expected the authentication config to point to the existing issuer")
+	}
+	if !util.FileContains(util.GetClusterPath("test-cluster", "ccoctl-output/tls/"+util.SigningKeyFile), "shared") {
+		t.Error("Expected the signing key of the existing OIDC provider")
+	}
+	if !NewDetector(cfg).ShouldSkipStep(7) {
+		t.Error("Step 7 should be detected as completed")
+	}
+}
+
 func TestStep8CopyManifests(t *testing.T) {
 	tmpDir := t.TempDir()
 	originalWd, _ := os.Getwd()
@@ -113,6 +523,47 @@ func TestStep8CopyManifests(t *testing.T) {
 	}
 }
 
+func TestStep8ExtraManifestsAndMachineConfigs(t *testing.T) {
+	tmpDir := t.TempDir()
+	originalWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(originalWd)
+
+	cfg := &config.Config{
+		ReleaseImage:      "quay.io/test:4.12.0-x86_64",
+		ClusterName:       "test-cluster",
+		ExtraManifestsDir: "extra",
+		NTPServers:        []string{"ntp.example.com"},
+	}
+	log := logger.New(logger.LevelQuiet, nil)
+
+	os.MkdirAll("artifacts/clusters/test-cluster/ccoctl-output/manifests", 0755)
+	os.WriteFile("artifacts/clusters/test-cluster/ccoctl-output/manifests/cco-secret.yaml", []byte("apiVersion: v1\nkind: Secret\n"), 0644)
+	os.MkdirAll("extra/openshift", 0755)
+	os.WriteFile("extra/cluster-monitoring-config.yaml", []byte("apiVersion: v1\nkind: ConfigMap\n"), 0644)
+	os.WriteFile("extra/openshift/99-worker-kargs.yaml", []byte("apiVersion: machineconfiguration.openshift.io/v1\nkind: MachineConfig\n"), 0644)
+
+	step, err := NewStep8(cfg, log, util.NewMockExecutor())
+	if err != nil {
+		t.Fatalf("Failed to create step: %v", err)
+	}
+	if err := step.Execute(); err != nil {
+		t.Fatalf("Step execution failed: %v", err)
+	}
+	for _, path := range []string{"manifests/cco-secret.yaml", "manifests/cluster-monitoring-config.yaml", "openshift/99-worker-kargs.yaml",
+		"openshift/99-master-chrony.yaml", "openshift/99-worker-chrony.yaml"} {
+		if !util.FileExists(util.GetClusterPath("test-cluster", path)) {
+			t.Errorf("Expected %s to be copied", path)
+		}
+	}
+
+	// Invalid manifests fail the step
+	os.WriteFile("extra/broken.yaml", []byte("kind: [ConfigMap"), 0644)
+	if err := step.Execute(); err == nil {
+		t.Error("Expected an invalid extra manifest to fail the step")
+	}
+}
+
 func TestStep9CopyTLS(t *testing.T) {
 	tmpDir := t.TempDir()
 	originalWd, _ := os.Getwd()
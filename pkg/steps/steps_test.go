@@ -3,13 +3,37 @@ package steps
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/clobrano/openshift-sts-wrapper/pkg/config"
 	"github.com/clobrano/openshift-sts-wrapper/pkg/logger"
 	"github.com/clobrano/openshift-sts-wrapper/pkg/util"
+	"gopkg.in/yaml.v3"
 )
 
+// setupDeployedCluster changes to a temporary directory holding the kubeconfig
+// of the deployed cluster test-cluster. The package variables in durations,
+// poll intervals and timeouts, are set to the given values and restored when
+// the test ends.
+func setupDeployedCluster(t *testing.T, durations map[*time.Duration]time.Duration) *util.MockExecutor {
+	tmpDir := t.TempDir()
+	originalWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	t.Cleanup(func() { os.Chdir(originalWd) })
+
+	for variable, value := range durations {
+		original := *variable
+		*variable = value
+		t.Cleanup(func() { *variable = original })
+	}
+
+	os.MkdirAll("artifacts/clusters/test-cluster/auth", 0755)
+	os.WriteFile("artifacts/clusters/test-cluster/auth/kubeconfig", []byte("kubeconfig"), 0600)
+	return util.NewMockExecutor()
+}
+
 func TestStep1ExtractCredReqs(t *testing.T) {
 	tmpDir := t.TempDir()
 	originalWd, _ := os.Getwd()
@@ -42,7 +66,7 @@ func TestStep1ExtractCredReqs(t *testing.T) {
 	}
 
 	// Verify directory was created
-	credreqsPath := util.GetCredReqsPath("4.12.0-x86_64")
+	credreqsPath := util.GetSharedCredReqsPath("4.12.0-x86_64")
 	if _, err := os.Stat(credreqsPath); os.IsNotExist(err) {
 		t.Error("Credreqs directory was not created")
 	}
@@ -78,11 +102,71 @@ func TestStep2ExtractBinaries(t *testing.T) {
 	if !executor.WasExecutedContaining("oc adm release extract --command=openshift-install") {
 		t.Error("Expected openshift-install extraction command")
 	}
+	if !executor.WasExecutedContaining("oc adm release extract --command=oc") {
+		t.Error("Expected oc extraction command")
+	}
 	if !executor.WasExecutedContaining("oc image extract") {
 		t.Error("Expected ccoctl extraction command")
 	}
 }
 
+// imageExtractExecutor writes the files extracted by oc image extract --path
+type imageExtractExecutor struct {
+	*util.MockExecutor
+}
+
+func (e *imageExtractExecutor) Execute(name string, args ...string) (string, error) {
+	output, err := e.MockExecutor.Execute(name, args...)
+	if name != "oc" || len(args) < 2 || args[0] != "image" || args[1] != "extract" || err != nil {
+		return output, err
+	}
+	for _, arg := range args {
+		if path, ok := strings.CutPrefix(arg, "--path="); ok {
+			source, dir, _ := strings.Cut(path, ":")
+			if err := os.WriteFile(filepath.Join(dir, filepath.Base(source)), []byte("binary"), 0644); err != nil {
+				return "", err
+			}
+		}
+	}
+	return output, nil
+}
+
+func TestStep3ExtractCcoctl(t *testing.T) {
+	tmpDir := t.TempDir()
+	originalWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(originalWd)
+
+	cfg := &config.Config{
+		ReleaseImage:   "quay.io/test:4.12.0-x86_64",
+		PullSecretPath: "pull-secret.json",
+	}
+	log := logger.New(logger.LevelQuiet, nil)
+	executor := &imageExtractExecutor{util.NewMockExecutor()}
+	executor.SetOutput("oc adm release info --image-for=cloud-credential-operator quay.io/test:4.12.0-x86_64",
+		"quay.io/openshift-release-dev/ocp-v4.0-art-dev@sha256:abc123\n")
+
+	// Step 2 has not created the bin directory yet
+	step, err := NewStep3(cfg, log, executor)
+	if err != nil {
+		t.Fatalf("Failed to create step: %v", err)
+	}
+	if err := step.Execute(); err != nil {
+		t.Fatalf("Step execution failed: %v", err)
+	}
+
+	ccoctlPath := util.GetSharedBinaryPath("4.12.0-x86_64", "ccoctl")
+	if info, err := os.Stat(ccoctlPath); err != nil || info.Mode()&0100 == 0 {
+		t.Errorf("Expected an executable ccoctl in the bin directory, got %v", err)
+	}
+	if util.FileExists("ccoctl") {
+		t.Error("Expected nothing to be extracted in the working directory")
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(ccoctlPath)); len(entries) != 1 {
+		t.Errorf("Expected the extraction directory to be removed, got %v", entries)
+	}
+}
+
 func TestStep3CreateConfig(t *testing.T) {
 	tmpDir := t.TempDir()
 	originalWd, _ := os.Getwd()
@@ -117,6 +201,42 @@ func TestStep3CreateConfig(t *testing.T) {
 	}
 }
 
+func TestStep4GenerateSSHKey(t *testing.T) {
+	tmpDir := t.TempDir()
+	originalWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(originalWd)
+
+	os.WriteFile("pull-secret.json", []byte(`{"auths": {"quay.io": {"auth": "dXNlcjpwYXNzd29yZA=="}}}`), 0600)
+	cfg := &config.Config{
+		ReleaseImage:   "quay.io/test:4.12.0-x86_64",
+		ClusterName:    "test-cluster",
+		AwsRegion:      "us-east-2",
+		BaseDomain:     "example.com",
+		PullSecretPath: "pull-secret.json",
+		SSHKeyPath:     util.GetClusterPath("test-cluster", "ssh/id_ed25519.pub"),
+		GenerateSSHKey: true,
+	}
+	log := logger.New(logger.LevelQuiet, nil)
+
+	step, err := NewStep4(cfg, log, util.NewMockExecutor())
+	if err != nil {
+		t.Fatalf("Failed to create step: %v", err)
+	}
+	if err := step.Execute(); err != nil {
+		t.Fatalf("Step execution failed: %v", err)
+	}
+
+	publicKey, err := os.ReadFile(cfg.SSHKeyPath)
+	if err != nil {
+		t.Fatalf("Expected the SSH key pair to be generated: %v", err)
+	}
+	content, _ := os.ReadFile(util.GetInstallConfigPath("4.12.0-x86_64", "test-cluster"))
+	if !strings.Contains(string(content), strings.TrimSpace(string(publicKey))) {
+		t.Errorf("Expected the generated public key in install-config.yaml, got %s", content)
+	}
+}
+
 func TestStep4SetCredentialsMode(t *testing.T) {
 	tmpDir := t.TempDir()
 	originalWd, _ := os.Getwd()
@@ -178,3 +298,176 @@ func TestStep5CreateManifests(t *testing.T) {
 		t.Error("Expected 'create manifests' command")
 	}
 }
+
+func TestStep5SetMachinePools(t *testing.T) {
+	tmpDir := t.TempDir()
+	originalWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(originalWd)
+
+	workerReplicas := 0
+	cfg := &config.Config{
+		ReleaseImage:     "quay.io/test:4.12.0-x86_64",
+		ClusterName:      "test-cluster",
+		InstanceType:     "m5.4xlarge",
+		ControlPlaneType: "m5.2xlarge",
+		WorkerReplicas:   &workerReplicas,
+		AMIID:            "ami-0123456789abcdef0",
+		WorkerAMIID:      "ami-0fedcba9876543210",
+		ServiceEndpoints: []config.ServiceEndpoint{
+			{Name: "ec2", URL: "https://vpce-ec2.us-gov-west-1.amazonaws.com"},
+			{Name: "iam", URL: "https://iam.us-gov.amazonaws.com"},
+		},
+	}
+	log := logger.New(logger.LevelQuiet, nil)
+	executor := util.NewMockExecutor()
+
+	configPath := util.GetInstallConfigPath("4.12.0-x86_64", "test-cluster")
+	os.MkdirAll(filepath.Dir(configPath), 0755)
+	os.WriteFile(configPath, []byte(`apiVersion: v1
+controlPlane:
+  name: master
+  platform:
+    aws:
+      type: m5.4xlarge
+  replicas: 3
+compute:
+- name: worker
+  platform:
+    aws:
+      type: m5.4xlarge
+  replicas: 3
+platform:
+  aws:
+    region: us-gov-west-1
+    serviceEndpoints:
+    - name: ec2
+      url: https://ec2.us-gov-west-1.amazonaws.com
+    - name: s3
+      url: https://s3.us-gov-west-1.amazonaws.com
+`), 0644)
+
+	step, err := NewStep5(cfg, log, executor)
+	if err != nil {
+		t.Fatalf("Failed to create step: %v", err)
+	}
+	if err := step.Execute(); err != nil {
+		t.Fatalf("Step execution failed: %v", err)
+	}
+
+	var doc struct {
+		ControlPlane struct {
+			Platform struct {
+				AWS struct {
+					Type  string `yaml:"type"`
+					AMIID string `yaml:"amiID"`
+				} `yaml:"aws"`
+			} `yaml:"platform"`
+			Replicas int `yaml:"replicas"`
+		} `yaml:"controlPlane"`
+		Compute []struct {
+			Platform struct {
+				AWS struct {
+					Type  string `yaml:"type"`
+					AMIID string `yaml:"amiID"`
+				} `yaml:"aws"`
+			} `yaml:"platform"`
+			Replicas int `yaml:"replicas"`
+		} `yaml:"compute"`
+		Platform struct {
+			AWS struct {
+				AMIID            string `yaml:"amiID"`
+				ServiceEndpoints []struct {
+					Name string `yaml:"name"`
+					URL  string `yaml:"url"`
+				} `yaml:"serviceEndpoints"`
+			} `yaml:"aws"`
+		} `yaml:"platform"`
+	}
+	content, _ := os.ReadFile(configPath)
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		t.Fatalf("Failed to parse install-config.yaml: %v", err)
+	}
+
+	if doc.ControlPlane.Platform.AWS.Type != "m5.2xlarge" || doc.ControlPlane.Replicas != 3 {
+		t.Errorf("Unexpected control plane pool. Content: %s", string(content))
+	}
+	if doc.Compute[0].Platform.AWS.Type != "m5.4xlarge" || doc.Compute[0].Replicas != 0 {
+		t.Errorf("Unexpected compute pool. Content: %s", string(content))
+	}
+	// The default AMI applies to every pool without its own
+	if doc.Platform.AWS.AMIID != "ami-0123456789abcdef0" || doc.ControlPlane.Platform.AWS.AMIID != "" ||
+		doc.Compute[0].Platform.AWS.AMIID != "ami-0fedcba9876543210" {
+		t.Errorf("Unexpected AMIs. Content: %s", string(content))
+	}
+	// The configured endpoints override the ones of install-config.yaml
+	if endpoints := doc.Platform.AWS.ServiceEndpoints; len(endpoints) != 3 ||
+		endpoints[0].URL != "https://vpce-ec2.us-gov-west-1.amazonaws.com" || endpoints[1].Name != "s3" || endpoints[2].Name != "iam" {
+		t.Errorf("Unexpected service endpoints. Content: %s", string(content))
+	}
+}
+
+func TestStep5SetMirror(t *testing.T) {
+	tmpDir := t.TempDir()
+	originalWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(originalWd)
+
+	mirrorDir := util.GetSharedMirrorPath("4.15.12-x86_64")
+	os.MkdirAll(mirrorDir, 0755)
+	os.WriteFile(filepath.Join(mirrorDir, "idms-oc-mirror.yaml"), []byte(`kind: ImageDigestMirrorSet
+spec:
+  imageDigestMirrors:
+  - mirrors:
+    - registry.example.com:5000/ocp/openshift/release
+    source: quay.io/openshift-release-dev/ocp-v4.0-art-dev
+`), 0644)
+	os.WriteFile("ca.pem", []byte("-----BEGIN CERTIFICATE-----\n"), 0644)
+
+	cfg := &config.Config{
+		ReleaseImage: "quay.io/openshift-release-dev/ocp-release:4.15.12-x86_64",
+		ClusterName:  "test-cluster",
+		Mirror:       config.MirrorConfig{Registry: "registry.example.com:5000/ocp", CABundle: "ca.pem"},
+	}
+	configPath := util.GetInstallConfigPath("4.15.12-x86_64", "test-cluster")
+	os.MkdirAll(filepath.Dir(configPath), 0755)
+	os.WriteFile(configPath, []byte("apiVersion: v1\nplatform:\n  aws:\n    region: us-east-1\n"), 0644)
+
+	step, err := NewStep5(cfg, logger.New(logger.LevelQuiet, nil), util.NewMockExecutor())
+	if err != nil {
+		t.Fatalf("Failed to create step: %v", err)
+	}
+	if err := step.Execute(); err != nil {
+		t.Fatalf("Step execution failed: %v", err)
+	}
+
+	var doc struct {
+		ImageDigestSources          []util.ImageDigestSource `yaml:"imageDigestSources"`
+		AdditionalTrustBundle       string                   `yaml:"additionalTrustBundle"`
+		AdditionalTrustBundlePolicy string                   `yaml:"additionalTrustBundlePolicy"`
+	}
+	data, _ := os.ReadFile(configPath)
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("Failed to parse install-config.yaml: %v", err)
+	}
+	if len(doc.ImageDigestSources) != 1 || doc.ImageDigestSources[0].Mirrors[0] != "registry.example.com:5000/ocp/openshift/release" {
+		t.Errorf("Unexpected imageDigestSources %+v", doc.ImageDigestSources)
+	}
+	if !strings.Contains(doc.AdditionalTrustBundle, "BEGIN CERTIFICATE") || doc.AdditionalTrustBundlePolicy != "Always" {
+		t.Errorf("Expected the CA bundle to be trusted, got %q (%s)", doc.AdditionalTrustBundle, doc.AdditionalTrustBundlePolicy)
+	}
+}
+
+func TestNewInstallStep(t *testing.T) {
+	cfg := &config.Config{ReleaseImage: "quay.io/openshift-release-dev/ocp-release:4.15.0-x86_64", ClusterName: "test-cluster"}
+	log := logger.New(logger.LevelQuiet, nil)
+
+	for num := 1; num <= len(config.StepNames); num++ {
+		if _, err := NewInstallStep(num, cfg, log, util.NewMockExecutor()); err != nil {
+			t.Errorf("Failed to create step %d: %v", num, err)
+		}
+	}
+	if _, err := NewInstallStep(len(config.StepNames)+1, cfg, log, util.NewMockExecutor()); err == nil {
+		t.Error("Expected an error for an unknown step")
+	}
+}
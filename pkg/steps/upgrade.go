@@ -0,0 +1,157 @@
+package steps
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/clobrano/openshift-sts-wrapper/pkg/config"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/logger"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/util"
+)
+
+// upgradePollInterval is how often the ClusterVersion is checked during an upgrade
+var upgradePollInterval = 30 * time.Second
+
+// upgrade is the state shared by the steps of an upgrade
+type upgrade struct {
+	*BaseStep
+	targetRelease string
+	targetVersion string
+	targetDigest  string
+	timeout       time.Duration
+}
+
+func (u *upgrade) kubeconfigEnv() []string {
+	return []string{fmt.Sprintf("KUBECONFIG=%s", util.GetKubeconfigPath(u.cfg.ClusterName))}
+}
+
+// NewUpgradeSteps returns the steps upgrading an STS cluster, installed with
+// cfg.ReleaseImage, to targetRelease: verify the target release, refresh the
+// IAM roles, start the upgrade and wait up to timeout for it to complete
+func NewUpgradeSteps(cfg *config.Config, log *logger.Logger, executor util.CommandExecutor, targetRelease string, timeout time.Duration) ([]Step, error) {
+	base, err := newBaseStep(cfg, log, executor)
+	if err != nil {
+		return nil, err
+	}
+	targetVersionArch, err := util.ExtractVersionArch(targetRelease)
+	if err != nil {
+		return nil, err
+	}
+	refresh, err := NewRefreshCredentials(cfg, log, executor, targetRelease)
+	if err != nil {
+		return nil, err
+	}
+
+	u := &upgrade{BaseStep: base, targetRelease: targetRelease, targetVersion: util.ReleaseVersion(targetVersionArch), timeout: timeout}
+	return []Step{&VerifyUpgrade{u}, refresh, &StartUpgrade{u}, &WaitForUpgrade{u}}, nil
+}
+
+// VerifyUpgrade checks that the cluster can be upgraded to the target release
+type VerifyUpgrade struct {
+	*upgrade
+}
+
+func (s *VerifyUpgrade) Name() string {
+	return "Verify target release"
+}
+
+func (s *VerifyUpgrade) Execute() error {
+	kubeconfigPath := util.GetKubeconfigPath(s.cfg.ClusterName)
+	if !util.FileExists(kubeconfigPath) {
+		return fmt.Errorf("kubeconfig not found at %s - cluster may not have been deployed successfully", kubeconfigPath)
+	}
+
+	// Upgrades to an explicit image must be pinned to its digest
+	digest, err := util.GetReleaseDigest(s.executor, s.targetRelease)
+	if err != nil {
+		return fmt.Errorf("failed to verify target release %s: %w", s.targetRelease, err)
+	}
+	s.targetDigest = digest
+
+	status, err := util.GetClusterVersion(s.executor, kubeconfigPath)
+	if err != nil {
+		return err
+	}
+	if !status.Completed {
+		return fmt.Errorf("an update to %s is already in progress: %s", status.Desired, status.Progress)
+	}
+	if util.CompareVersions(s.targetVersion, status.Desired) <= 0 {
+		return fmt.Errorf("cluster is at %s, %s is not an upgrade", status.Desired, s.targetVersion)
+	}
+	if status.Upgradeable != "" {
+		// Usually the Cloud Credential Operator, cleared by refreshing the credentials
+		s.log.Info(fmt.Sprintf("⚠  Cluster is not upgradeable yet: %s", status.Upgradeable))
+	}
+	s.log.Info(fmt.Sprintf("✓ Upgrading from %s to %s (%s)", status.Desired, s.targetVersion, digest))
+	return nil
+}
+
+// StartUpgrade asks the cluster version operator to upgrade to the target release
+type StartUpgrade struct {
+	*upgrade
+}
+
+func (s *StartUpgrade) Name() string {
+	return "Start upgrade"
+}
+
+func (s *StartUpgrade) Execute() error {
+	target := &config.Config{ReleaseImage: s.targetRelease, ReleaseDigest: s.targetDigest}
+	return util.RunCommandWithEnv(s.executor, s.kubeconfigEnv(), "oc", "adm", "upgrade",
+		"--to-image="+target.PullSpec(), "--allow-explicit-upgrade")
+}
+
+// WaitForUpgrade waits for the upgrade to roll out, showing its progress
+type WaitForUpgrade struct {
+	*upgrade
+}
+
+func (s *WaitForUpgrade) Name() string {
+	return "Wait for upgrade"
+}
+
+func (s *WaitForUpgrade) Execute() error {
+	kubeconfigPath := util.GetKubeconfigPath(s.cfg.ClusterName)
+	s.log.Info(fmt.Sprintf("Upgrades usually take 1-2 hours. Follow them with: KUBECONFIG=%s oc adm upgrade", kubeconfigPath))
+
+	var status *util.ClusterVersionStatus
+	progress := "waiting for the cluster version operator"
+	spinner := s.log.StartSpinner("Upgrading cluster", func() string { return progress })
+	defer spinner.Stop()
+
+	deadline := time.Now().Add(s.timeout)
+	for {
+		current, err := util.GetClusterVersion(s.executor, kubeconfigPath)
+		if err != nil {
+			// The API server restarts during the upgrade
+			s.log.Debug(fmt.Sprintf("Could not get cluster version: %v", err))
+		} else {
+			status = current
+			progress = status.Progress
+			if status.Failing != "" {
+				progress += " (failing: " + status.Failing + ")"
+			}
+			if status.Desired == s.targetVersion && status.Completed {
+				break
+			}
+		}
+
+		if time.Now().After(deadline) {
+			if status != nil && status.Failing != "" {
+				return fmt.Errorf("upgrade did not complete within %s: %s", s.timeout, status.Failing)
+			}
+			return fmt.Errorf("upgrade did not complete within %s", s.timeout)
+		}
+		if err := util.Sleep(s.executor, upgradePollInterval); err != nil {
+			return err
+		}
+	}
+	spinner.Stop()
+
+	// Later refreshes and cleanups use the upgraded release
+	if err := util.SaveInstallMetadata(util.GetClusterPath(s.cfg.ClusterName, ""), s.targetRelease, s.targetDigest); err != nil {
+		s.log.Debug(fmt.Sprintf("Could not update install metadata: %v", err))
+	}
+	s.log.Info(fmt.Sprintf("✓ Cluster upgraded to %s", s.targetVersion))
+	return nil
+}
@@ -0,0 +1,87 @@
+package steps
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/clobrano/openshift-sts-wrapper/pkg/config"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/logger"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/util"
+)
+
+const clusterVersionCommand = "oc get clusterversion version -o json"
+
+func clusterVersionJSON(version, state string) string {
+	return fmt.Sprintf(`{"status": {"desired": {"version": %q}, "history": [{"state": %q, "version": %q}],
+  "conditions": [{"type": "Progressing", "status": "True", "message": "Working towards %s"}]}}`, version, state, version, version)
+}
+
+func TestUpgradeSteps(t *testing.T) {
+	executor := setupRefreshCluster(t, map[*time.Duration]time.Duration{&upgradePollInterval: time.Millisecond})
+
+	cfg := &config.Config{
+		ReleaseImage: "quay.io/test:4.12.0-x86_64",
+		ClusterName:  "test-cluster",
+		AwsRegion:    "us-east-2",
+	}
+	executor.SetOutput("oc adm release info quay.io/test:4.13.0-x86_64 --output=json", `{"digest": "sha256:bbb"}`)
+	executor.SetOutput(clusterVersionCommand, clusterVersionJSON("4.12.0", "Completed"))
+
+	upgradeSteps, err := NewUpgradeSteps(cfg, logger.New(logger.LevelQuiet, nil), executor, "quay.io/test:4.13.0-x86_64", time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to create steps: %v", err)
+	}
+	if len(upgradeSteps) != 4 {
+		t.Fatalf("Expected 4 steps, got %d", len(upgradeSteps))
+	}
+
+	for _, step := range upgradeSteps[:3] {
+		if err := step.Execute(); err != nil {
+			t.Fatalf("%s failed: %v", step.Name(), err)
+		}
+	}
+	if !executor.WasExecuted("oc adm upgrade --to-image=quay.io/test@sha256:bbb --allow-explicit-upgrade") {
+		t.Errorf("Expected the upgrade to be started by digest, got %v", executor.Commands)
+	}
+
+	executor.SetOutput(clusterVersionCommand, clusterVersionJSON("4.13.0", "Completed"))
+	if err := upgradeSteps[3].Execute(); err != nil {
+		t.Fatalf("%s failed: %v", upgradeSteps[3].Name(), err)
+	}
+	metadata, err := util.ReadInstallMetadata(util.GetClusterPath("test-cluster", ""))
+	if err != nil || metadata.ReleaseImage != "quay.io/test:4.13.0-x86_64" || metadata.ReleaseDigest != "sha256:bbb" {
+		t.Errorf("Expected install metadata to record the upgraded release, got %+v (%v)", metadata, err)
+	}
+}
+
+func TestVerifyUpgradeRejectsDowngrade(t *testing.T) {
+	executor := setupRefreshCluster(t, nil)
+
+	cfg := &config.Config{ReleaseImage: "quay.io/test:4.12.0-x86_64", ClusterName: "test-cluster"}
+	executor.SetOutput("oc adm release info quay.io/test:4.11.0-x86_64 --output=json", `{"digest": "sha256:aaa"}`)
+	executor.SetOutput(clusterVersionCommand, clusterVersionJSON("4.12.0", "Completed"))
+
+	upgradeSteps, _ := NewUpgradeSteps(cfg, logger.New(logger.LevelQuiet, nil), executor, "quay.io/test:4.11.0-x86_64", time.Minute)
+	if err := upgradeSteps[0].Execute(); err == nil || !strings.Contains(err.Error(), "not an upgrade") {
+		t.Errorf("Expected downgrade to be rejected, got %v", err)
+	}
+
+	executor.SetOutput(clusterVersionCommand, clusterVersionJSON("4.12.0", "Partial"))
+	if err := upgradeSteps[0].Execute(); err == nil || !strings.Contains(err.Error(), "already in progress") {
+		t.Errorf("Expected an upgrade in progress to be rejected, got %v", err)
+	}
+}
+
+func TestWaitForUpgradeTimeout(t *testing.T) {
+	executor := setupRefreshCluster(t, map[*time.Duration]time.Duration{&upgradePollInterval: time.Millisecond})
+
+	cfg := &config.Config{ReleaseImage: "quay.io/test:4.12.0-x86_64", ClusterName: "test-cluster"}
+	executor.SetOutput(clusterVersionCommand, clusterVersionJSON("4.13.0", "Partial"))
+
+	upgradeSteps, _ := NewUpgradeSteps(cfg, logger.New(logger.LevelQuiet, nil), executor, "quay.io/test:4.13.0-x86_64", 10*time.Millisecond)
+	if err := upgradeSteps[3].Execute(); err == nil || !strings.Contains(err.Error(), "did not complete") {
+		t.Errorf("Expected timeout, got %v", err)
+	}
+}
@@ -0,0 +1,366 @@
+package steps
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/clobrano/openshift-sts-wrapper/pkg/config"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/logger"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/util"
+)
+
+// Statuses of a verification check
+const (
+	VerifyPassed = "passed"
+	VerifyFailed = "failed"
+)
+
+// VerifyCheck is a post-install check of the cluster, run by Step 11 and by
+// the verify command
+type VerifyCheck struct {
+	ID          string
+	Description string
+	// run returns a message describing what was verified, or why the check failed
+	run func(c *verifyContext) (string, error)
+	// enabled reports whether the check runs by default (always when nil)
+	enabled func(cfg *config.Config) bool
+}
+
+// VerifyChecks are the available verification checks, in the order they run
+var VerifyChecks = []VerifyCheck{
+	{ID: "root-credentials", Description: "Root credentials removed", run: verifyNoRootCredentials},
+	{ID: "credentials-mode", Description: "Cloud credential operator in Manual mode", run: verifyCredentialsMode},
+	{ID: "iam-roles", Description: "Components use IAM roles", run: verifyIAMRoles},
+	{ID: "credentials-secrets", Description: "Every CredentialsRequest secret uses an IAM role", run: verifyCredentialsSecrets},
+	{ID: "pod-identity-webhook", Description: "Pod identity webhook running", run: verifyPodIdentityWebhook},
+	{ID: "oidc-issuer", Description: "OIDC issuer matches the created endpoint", run: verifyOIDCIssuer},
+	{ID: "cluster-operators", Description: "Cluster operators healthy", run: verifyClusterOperators,
+		enabled: func(cfg *config.Config) bool { return cfg.HealthGateTimeout != "" }},
+}
+
+// roleARNPartitionPattern matches the partition of the role in AWS credentials
+var roleARNPartitionPattern = regexp.MustCompile(`role_arn\s*=\s*arn:([^:\s]+):`)
+
+// ccoNamespace is the namespace of the cloud credential operator
+const ccoNamespace = "openshift-cloud-credential-operator"
+
+// healthGatePollInterval is how often the ClusterOperators are checked while
+// waiting for them to be healthy
+var healthGatePollInterval = 30 * time.Second
+
+// fetchOIDCIssuer reads the issuer served by an OIDC endpoint (replaced in tests)
+var fetchOIDCIssuer = util.FetchOIDCIssuer
+
+// VerifyCheckIDs returns the IDs of the available verification checks
+func VerifyCheckIDs() []string {
+	ids := make([]string, len(VerifyChecks))
+	for i, check := range VerifyChecks {
+		ids[i] = check.ID
+	}
+	return ids
+}
+
+// SelectVerifyChecks returns the checks with the given IDs, or the checks
+// enabled by default for the configuration when ids is empty
+func SelectVerifyChecks(ids []string, cfg *config.Config) ([]VerifyCheck, error) {
+	if len(ids) == 0 {
+		var checks []VerifyCheck
+		for _, check := range VerifyChecks {
+			if check.enabled == nil || check.enabled(cfg) {
+				checks = append(checks, check)
+			}
+		}
+		return checks, nil
+	}
+	selected := map[string]bool{}
+	for _, id := range ids {
+		found := false
+		for _, check := range VerifyChecks {
+			found = found || check.ID == id
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown verification check %q (available: %s)", id, strings.Join(VerifyCheckIDs(), ", "))
+		}
+		selected[id] = true
+	}
+
+	var checks []VerifyCheck
+	for _, check := range VerifyChecks {
+		if selected[check.ID] {
+			checks = append(checks, check)
+		}
+	}
+	return checks, nil
+}
+
+// VerifyResult is the outcome of a verification check
+type VerifyResult struct {
+	ID              string  `json:"id"`
+	Description     string  `json:"description"`
+	Status          string  `json:"status"`
+	Message         string  `json:"message,omitempty"`
+	DurationSeconds float64 `json:"durationSeconds"`
+}
+
+// VerifyReport holds the outcome of the verification of a cluster
+type VerifyReport struct {
+	ClusterName string         `json:"clusterName"`
+	Status      string         `json:"status"`
+	Checks      []VerifyResult `json:"checks"`
+}
+
+// Failed returns the IDs of the failed checks
+func (r *VerifyReport) Failed() []string {
+	var failed []string
+	for _, result := range r.Checks {
+		if result.Status == VerifyFailed {
+			failed = append(failed, result.ID)
+		}
+	}
+	return failed
+}
+
+// verifyContext is what the checks need to query the cluster
+type verifyContext struct {
+	*BaseStep
+	envVars    []string
+	serverArgs []string
+}
+
+// oc runs an oc command against the cluster
+func (c *verifyContext) oc(args ...string) (string, error) {
+	return c.executor.ExecuteWithEnv("oc", c.envVars, append(append([]string{}, c.serverArgs...), args...)...)
+}
+
+// runVerifyChecks runs the checks, logging and collecting their outcome
+func runVerifyChecks(c *verifyContext, checks []VerifyCheck) *VerifyReport {
+	report := &VerifyReport{ClusterName: c.cfg.ClusterName, Status: VerifyPassed}
+	for _, check := range checks {
+		started := time.Now()
+		message, err := check.run(c)
+		result := VerifyResult{
+			ID:              check.ID,
+			Description:     check.Description,
+			Status:          VerifyPassed,
+			Message:         message,
+			DurationSeconds: time.Since(started).Seconds(),
+		}
+		if err != nil {
+			result.Status = VerifyFailed
+			result.Message = err.Error()
+			report.Status = VerifyFailed
+			c.log.Error(fmt.Sprintf("✗ %s: %v", check.Description, err))
+		} else {
+			c.log.Info(fmt.Sprintf("✓ %s", message))
+		}
+		report.Checks = append(report.Checks, result)
+	}
+	return report
+}
+
+// verifyNoRootCredentials checks that the root AWS credentials secret is not
+// in the cluster, i.e. the cloud credential operator is in manual mode
+func verifyNoRootCredentials(c *verifyContext) (string, error) {
+	if _, err := c.oc("get", "secrets", "-n", "kube-system", "aws-creds"); err == nil {
+		return "", fmt.Errorf("root credentials secret kube-system/aws-creds exists (expected it to not exist)")
+	}
+	return "Root credentials secret does not exist (as expected)", nil
+}
+
+// verifyIAMRoles checks that the components are given short-lived credentials
+// of an IAM role
+func verifyIAMRoles(c *verifyContext) (string, error) {
+	output, err := c.oc("get", "secrets", "-n", "openshift-image-registry", "installer-cloud-credentials", "-o", "json")
+	if err != nil {
+		return "", fmt.Errorf("failed to check IAM role usage: %w", err)
+	}
+	if !strings.Contains(output, "role_arn") && !strings.Contains(output, "web_identity_token_file") {
+		return "", fmt.Errorf("components may not be using IAM roles correctly: openshift-image-registry/installer-cloud-credentials has no role_arn")
+	}
+	return "Components are using IAM roles", nil
+}
+
+// verifyCredentialsMode checks that the cloud credential operator does not
+// mint credentials, but expects them to be provided (STS)
+func verifyCredentialsMode(c *verifyContext) (string, error) {
+	output, err := c.oc("get", "cloudcredential", "cluster", "-o", "jsonpath={.spec.credentialsMode}")
+	if err != nil {
+		return "", fmt.Errorf("failed to get the cloudcredential configuration: %w", err)
+	}
+	if mode := strings.TrimSpace(output); mode != "Manual" {
+		return "", fmt.Errorf("credentialsMode is %q, expected Manual", mode)
+	}
+	return "Cloud credential operator is in Manual mode", nil
+}
+
+// verifyCredentialsSecrets checks that the secret of every AWS
+// CredentialsRequest holds the credentials of an IAM role
+func verifyCredentialsSecrets(c *verifyContext) (string, error) {
+	output, err := c.oc("get", "credentialsrequests", "-n", ccoNamespace, "-o", "json")
+	if err != nil {
+		return "", fmt.Errorf("failed to list the CredentialsRequests: %w", err)
+	}
+	var list struct {
+		Items []struct {
+			Spec struct {
+				ProviderSpec struct {
+					Kind string `json:"kind"`
+				} `json:"providerSpec"`
+				SecretRef struct {
+					Name      string `json:"name"`
+					Namespace string `json:"namespace"`
+				} `json:"secretRef"`
+			} `json:"spec"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal([]byte(output), &list); err != nil {
+		return "", fmt.Errorf("failed to parse the CredentialsRequests: %w", err)
+	}
+
+	var invalid []string
+	checked := 0
+	for _, item := range list.Items {
+		if item.Spec.ProviderSpec.Kind != "AWSProviderSpec" {
+			continue
+		}
+		checked++
+		secret := item.Spec.SecretRef.Namespace + "/" + item.Spec.SecretRef.Name
+		data, err := c.oc("get", "secret", "-n", item.Spec.SecretRef.Namespace, item.Spec.SecretRef.Name, "-o", "jsonpath={.data.credentials}")
+		if err != nil {
+			invalid = append(invalid, secret+" (not found)")
+			continue
+		}
+		credentials, err := base64.StdEncoding.DecodeString(strings.TrimSpace(data))
+		if err != nil || !strings.Contains(string(credentials), "role_arn") || !strings.Contains(string(credentials), "web_identity_token_file") {
+			invalid = append(invalid, secret+" (no role_arn/web_identity_token_file)")
+			continue
+		}
+		// A role of another partition can't be assumed from the cluster region
+		if match := roleARNPartitionPattern.FindStringSubmatch(string(credentials)); match != nil && match[1] != c.cfg.Partition() {
+			invalid = append(invalid, fmt.Sprintf("%s (role in partition %s, expected %s)", secret, match[1], c.cfg.Partition()))
+		}
+	}
+	if len(invalid) > 0 {
+		return "", fmt.Errorf("%d of %d credentials secrets do not use an IAM role: %s", len(invalid), checked, strings.Join(invalid, ", "))
+	}
+	return fmt.Sprintf("All %d credentials secrets use IAM roles", checked), nil
+}
+
+// verifyPodIdentityWebhook checks that the webhook injecting the web identity
+// token into the pods is available
+func verifyPodIdentityWebhook(c *verifyContext) (string, error) {
+	output, err := c.oc("get", "deployment", "pod-identity-webhook", "-n", ccoNamespace, "-o", "json")
+	if err != nil {
+		return "", fmt.Errorf("pod-identity-webhook deployment not found: %w", err)
+	}
+	var deployment struct {
+		Status struct {
+			Replicas          int `json:"replicas"`
+			AvailableReplicas int `json:"availableReplicas"`
+		} `json:"status"`
+	}
+	if err := json.Unmarshal([]byte(output), &deployment); err != nil {
+		return "", fmt.Errorf("failed to parse the pod-identity-webhook deployment: %w", err)
+	}
+	if deployment.Status.AvailableReplicas == 0 {
+		return "", fmt.Errorf("pod-identity-webhook has no available replica")
+	}
+	return fmt.Sprintf("Pod identity webhook is running (%d/%d replicas available)", deployment.Status.AvailableReplicas, deployment.Status.Replicas), nil
+}
+
+// verifyOIDCIssuer checks that the service account issuer of the cluster is
+// the S3/CloudFront endpoint created by ccoctl, and that the endpoint serves
+// the OIDC discovery document of that issuer
+func verifyOIDCIssuer(c *verifyContext) (string, error) {
+	output, err := c.oc("get", "authentication", "cluster", "-o", "jsonpath={.spec.serviceAccountIssuer}")
+	if err != nil {
+		return "", fmt.Errorf("failed to get the authentication configuration: %w", err)
+	}
+	issuer := strings.TrimSpace(output)
+
+	resources, err := util.ReadCcoctlResources(util.GetClusterPath(c.cfg.ClusterName, "ccoctl-output/manifests"), c.cfg.CcoctlName())
+	if err != nil {
+		return "", err
+	}
+	if resources.IssuerURL == "" {
+		return "", fmt.Errorf("no serviceAccountIssuer in the ccoctl manifests")
+	}
+	if issuer != resources.IssuerURL {
+		return "", fmt.Errorf("serviceAccountIssuer is %q, expected the endpoint created by ccoctl %q", issuer, resources.IssuerURL)
+	}
+
+	served, err := fetchOIDCIssuer(issuer)
+	if err != nil {
+		return "", err
+	}
+	if served != issuer {
+		return "", fmt.Errorf("the OIDC discovery document at %s advertises issuer %q", issuer, served)
+	}
+	return fmt.Sprintf("OIDC issuer is %s", issuer), nil
+}
+
+// verifyClusterOperators checks that every ClusterOperator is Available and
+// not Degraded, waiting up to the health gate timeout for them to settle
+func verifyClusterOperators(c *verifyContext) (string, error) {
+	timeout, err := c.cfg.GetHealthGateTimeout()
+	if err != nil {
+		return "", err
+	}
+
+	// The spinner only shows when the operators are not healthy at first
+	var spinner *logger.Spinner
+	progress := ""
+	defer func() {
+		if spinner != nil {
+			spinner.Stop()
+		}
+	}()
+
+	var unhealthy []util.ClusterOperatorStatus
+	deadline := time.Now().Add(timeout)
+	for {
+		output, err := c.oc("get", "clusteroperators", "-o", "json")
+		if err != nil {
+			return "", fmt.Errorf("failed to get the cluster operators: %w", err)
+		}
+		operators, err := util.ParseClusterOperators([]byte(output))
+		if err != nil {
+			return "", err
+		}
+		unhealthy = util.UnhealthyClusterOperators(operators)
+		if len(unhealthy) == 0 {
+			return fmt.Sprintf("All %d cluster operators are available and not degraded", len(operators)), nil
+		}
+		if !time.Now().Before(deadline) {
+			break
+		}
+
+		progress = fmt.Sprintf("%d/%d not healthy yet: %s", len(unhealthy), len(operators), clusterOperatorNames(unhealthy))
+		if spinner == nil {
+			c.log.Info(fmt.Sprintf("Waiting up to %s for the cluster operators to be healthy", timeout))
+			spinner = c.log.StartSpinner("Waiting for cluster operators", func() string { return progress })
+		}
+		if err := util.Sleep(c.executor, healthGatePollInterval); err != nil {
+			return "", err
+		}
+	}
+
+	details := make([]string, len(unhealthy))
+	for i, operator := range unhealthy {
+		details[i] = operator.String()
+	}
+	return "", fmt.Errorf("%d cluster operators not healthy after %s: %s", len(unhealthy), timeout, strings.Join(details, "; "))
+}
+
+// clusterOperatorNames returns the names of the operators, comma-separated
+func clusterOperatorNames(operators []util.ClusterOperatorStatus) string {
+	names := make([]string, len(operators))
+	for i, operator := range operators {
+		names[i] = operator.Name
+	}
+	return strings.Join(names, ", ")
+}
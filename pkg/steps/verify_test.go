@@ -0,0 +1,217 @@
+package steps
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/clobrano/openshift-sts-wrapper/pkg/config"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/logger"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/util"
+)
+
+func TestStep11VerifyReport(t *testing.T) {
+	cfg := &config.Config{
+		ReleaseImage: "quay.io/test:4.12.0-x86_64",
+		ClusterName:  "test-cluster",
+	}
+	log := logger.New(logger.LevelQuiet, nil)
+	executor := setupDeployedCluster(t, nil)
+	// The root credentials secret exists, the image registry uses an IAM role
+	executor.SetOutput("oc get secrets -n openshift-image-registry installer-cloud-credentials -o json",
+		`{"data":{"credentials":"role_arn = arn:aws:iam::123456789:role/test\nweb_identity_token_file = /var/run/secrets/token"}}`)
+
+	step, err := NewStep11(cfg, log, executor)
+	if err != nil {
+		t.Fatalf("Failed to create step: %v", err)
+	}
+	step.Checks = []string{"root-credentials", "iam-roles"}
+
+	if err := step.Execute(); err == nil {
+		t.Fatal("Expected the step to fail when a check fails")
+	}
+	report := step.Report
+	if report.Status != VerifyFailed || !reflect.DeepEqual(report.Failed(), []string{"root-credentials"}) {
+		t.Errorf("Expected only root-credentials to fail, got %+v", report)
+	}
+
+	// Only the selected checks run
+	executor = util.NewMockExecutor()
+	step, _ = NewStep11(cfg, log, executor)
+	step.Checks = []string{"iam-roles"}
+	executor.SetOutput("oc get secrets -n openshift-image-registry installer-cloud-credentials -o json", `{"data":{}}`)
+	report, err = step.Verify()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(report.Checks) != 1 || report.Checks[0].ID != "iam-roles" || report.Checks[0].Status != VerifyFailed {
+		t.Errorf("Expected only iam-roles to run and fail, got %+v", report.Checks)
+	}
+	if executor.WasExecutedContaining("kube-system") {
+		t.Error("Expected the root credentials check not to run")
+	}
+}
+
+func TestSelectVerifyChecks(t *testing.T) {
+	// The health gate only runs by default when it has a timeout
+	checks, err := SelectVerifyChecks(nil, &config.Config{})
+	if err != nil || len(checks) != len(VerifyChecks)-1 {
+		t.Errorf("Expected all checks but cluster-operators by default, got %v, %v", checks, err)
+	}
+	checks, _ = SelectVerifyChecks(nil, &config.Config{HealthGateTimeout: "30m"})
+	if len(checks) != len(VerifyChecks) || checks[len(checks)-1].ID != "cluster-operators" {
+		t.Errorf("Expected cluster-operators with a health gate timeout, got %v", checks)
+	}
+
+	// Checks run in their own order, not the selection order
+	checks, err = SelectVerifyChecks([]string{"iam-roles", "root-credentials"}, &config.Config{})
+	if err != nil || len(checks) != 2 || checks[0].ID != "root-credentials" {
+		t.Errorf("Expected the checks in order, got %v, %v", checks, err)
+	}
+
+	if _, err := SelectVerifyChecks([]string{"unknown"}, &config.Config{}); err == nil {
+		t.Error("Expected an error for an unknown check")
+	}
+}
+
+func TestVerifySTSChecks(t *testing.T) {
+	issuer := "https://test-cluster-oidc.s3.us-east-2.amazonaws.com"
+	served := map[string]string{issuer: issuer}
+	fetchOIDCIssuer = func(url string) (string, error) { return served[url], nil }
+	defer func() { fetchOIDCIssuer = util.FetchOIDCIssuer }()
+
+	cfg := &config.Config{
+		ReleaseImage: "quay.io/test:4.12.0-x86_64",
+		ClusterName:  "test-cluster",
+	}
+	log := logger.New(logger.LevelQuiet, nil)
+	executor := setupDeployedCluster(t, nil)
+	os.MkdirAll("artifacts/clusters/test-cluster/ccoctl-output/manifests", 0755)
+	os.WriteFile("artifacts/clusters/test-cluster/ccoctl-output/manifests/cluster-authentication-02-config.yaml",
+		[]byte("spec:\n  serviceAccountIssuer: https://other-oidc.s3.us-east-2.amazonaws.com\n"), 0644)
+
+	executor.SetOutput("oc get cloudcredential cluster -o jsonpath={.spec.credentialsMode}", "Manual")
+	executor.SetOutput("oc get credentialsrequests -n openshift-cloud-credential-operator -o json", `{"items": [
+		{"spec": {"providerSpec": {"kind": "AWSProviderSpec"}, "secretRef": {"name": "installer-cloud-credentials", "namespace": "openshift-image-registry"}}},
+		{"spec": {"providerSpec": {"kind": "AWSProviderSpec"}, "secretRef": {"name": "cloud-credentials", "namespace": "openshift-ingress-operator"}}},
+		{"spec": {"providerSpec": {"kind": "GCPProviderSpec"}, "secretRef": {"name": "gcp-credentials", "namespace": "openshift-machine-api"}}}
+	]}`)
+	roleCredentials := base64.StdEncoding.EncodeToString([]byte("[default]\nrole_arn = arn:aws:iam::123456789012:role/test\nweb_identity_token_file = /var/run/secrets/token\n"))
+	executor.SetOutput("oc get secret -n openshift-image-registry installer-cloud-credentials -o jsonpath={.data.credentials}", roleCredentials)
+	executor.SetOutput("oc get secret -n openshift-ingress-operator cloud-credentials -o jsonpath={.data.credentials}",
+		base64.StdEncoding.EncodeToString([]byte("[default]\naws_access_key_id = AKIA\n")))
+	executor.SetOutput("oc get deployment pod-identity-webhook -n openshift-cloud-credential-operator -o json",
+		`{"status": {"replicas": 2, "availableReplicas": 2}}`)
+	executor.SetOutput("oc get authentication cluster -o jsonpath={.spec.serviceAccountIssuer}", issuer)
+
+	step, _ := NewStep11(cfg, log, executor)
+	step.Checks = []string{"credentials-mode", "credentials-secrets", "pod-identity-webhook", "oidc-issuer"}
+	report, err := step.Verify()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	results := map[string]VerifyResult{}
+	for _, result := range report.Checks {
+		results[result.ID] = result
+	}
+	for _, id := range []string{"credentials-mode", "pod-identity-webhook"} {
+		if results[id].Status != VerifyPassed {
+			t.Errorf("Expected %s to pass, got %+v", id, results[id])
+		}
+	}
+	if result := results["credentials-secrets"]; result.Status != VerifyFailed || !strings.Contains(result.Message, "1 of 2") ||
+		!strings.Contains(result.Message, "openshift-ingress-operator/cloud-credentials") {
+		t.Errorf("Expected the static credentials of the ingress operator to be reported, got %+v", result)
+	}
+	// Roles of the commercial partition, while the cluster is in GovCloud
+	cfg.AwsRegion = "us-gov-west-1"
+	step.Checks = []string{"credentials-secrets"}
+	if report, _ := step.Verify(); report.Status != VerifyFailed || !strings.Contains(report.Checks[0].Message, "role in partition aws, expected aws-us-gov") {
+		t.Errorf("Expected the partition mismatch to be reported, got %+v", report.Checks)
+	}
+	cfg.AwsRegion = ""
+	// The cluster issuer differs from the one created by ccoctl
+	if result := results["oidc-issuer"]; result.Status != VerifyFailed || !strings.Contains(result.Message, "expected the endpoint created by ccoctl") {
+		t.Errorf("Expected the issuer mismatch to be reported, got %+v", result)
+	}
+
+	// Matching issuer, served by the endpoint
+	os.WriteFile("artifacts/clusters/test-cluster/ccoctl-output/manifests/cluster-authentication-02-config.yaml",
+		[]byte("spec:\n  serviceAccountIssuer: "+issuer+"\n"), 0644)
+	step.Checks = []string{"oidc-issuer"}
+	if report, _ := step.Verify(); report.Status != VerifyPassed {
+		t.Errorf("Expected the OIDC issuer check to pass, got %+v", report.Checks)
+	}
+
+	// The endpoint does not serve the discovery document of the issuer
+	served[issuer] = ""
+	if report, _ := step.Verify(); report.Status != VerifyFailed {
+		t.Errorf("Expected the OIDC issuer check to fail, got %+v", report.Checks)
+	}
+}
+
+func TestVerifyClusterOperators(t *testing.T) {
+	executor := setupDeployedCluster(t, map[*time.Duration]time.Duration{&healthGatePollInterval: time.Millisecond})
+
+	cfg := &config.Config{
+		ReleaseImage:      "quay.io/test:4.12.0-x86_64",
+		ClusterName:       "test-cluster",
+		HealthGateTimeout: "50ms",
+	}
+	log := logger.New(logger.LevelQuiet, nil)
+	executor.SetOutput("oc get clusteroperators -o json", `{"items": [
+		{"metadata": {"name": "authentication"}, "status": {"conditions": [
+			{"type": "Available", "status": "True"}, {"type": "Degraded", "status": "False"}]}},
+		{"metadata": {"name": "ingress"}, "status": {"conditions": [
+			{"type": "Available", "status": "True"}, {"type": "Degraded", "status": "True", "message": "Some ingresscontroller is degraded\nmore details"}]}},
+		{"metadata": {"name": "console"}, "status": {"conditions": [
+			{"type": "Available", "status": "False", "message": "DeploymentAvailable: 0 replicas available"}]}}
+	]}`)
+
+	step, _ := NewStep11(cfg, log, executor)
+	step.Checks = []string{"cluster-operators"}
+	report, err := step.Verify()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	result := report.Checks[0]
+	expected := "2 cluster operators not healthy after 50ms: console (Available=False: DeploymentAvailable: 0 replicas available); ingress (Degraded=True: Some ingresscontroller is degraded)"
+	if result.Status != VerifyFailed || result.Message != expected {
+		t.Errorf("Expected %q, got %+v", expected, result)
+	}
+	if len(executor.Commands) < 2 {
+		t.Errorf("Expected the cluster operators to be polled until the timeout, got %v", executor.Commands)
+	}
+}
+
+func TestVerifyReportJUnit(t *testing.T) {
+	report := &VerifyReport{
+		ClusterName: "test-cluster",
+		Status:      VerifyFailed,
+		Checks: []VerifyResult{
+			{ID: "root-credentials", Description: "Root credentials removed", Status: VerifyPassed, Message: "Root credentials secret does not exist (as expected)", DurationSeconds: 0.5},
+			{ID: "credentials-mode", Description: "Cloud credential operator in Manual mode", Status: VerifyFailed, Message: `credentialsMode is "Mint", expected Manual`, DurationSeconds: 0.25},
+		},
+	}
+
+	path := filepath.Join(t.TempDir(), "reports", "junit_verify.xml")
+	if err := report.WriteJUnit(path); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	for _, expected := range []string{
+		`<testsuite name="openshift-sts-wrapper verify test-cluster" tests="2" failures="1" time="0.750">`,
+		`<testcase name="[root-credentials] Root credentials removed" classname="verify" time="0.500">`,
+		`<failure message="credentialsMode is &#34;Mint&#34;, expected Manual">`,
+	} {
+		if !strings.Contains(string(data), expected) {
+			t.Errorf("Expected %s in the report, got:\n%s", expected, data)
+		}
+	}
+}
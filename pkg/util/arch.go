@@ -0,0 +1,81 @@
+package util
+
+import (
+	"runtime"
+	"strings"
+)
+
+// releaseArchitectures lists the architecture suffixes used by release image tags
+var releaseArchitectures = []string{"x86_64", "aarch64", "ppc64le", "s390x", "multi"}
+
+// ReleaseArch returns the architecture suffix of a versionArch (e.g. "4.15.0-aarch64" -> "aarch64").
+// Defaults to x86_64 when the tag carries no known architecture.
+func ReleaseArch(versionArch string) string {
+	for _, arch := range releaseArchitectures {
+		if strings.HasSuffix(versionArch, "-"+arch) {
+			return arch
+		}
+	}
+	return "x86_64"
+}
+
+// ReleaseVersion returns the OpenShift version of a versionArch (e.g. "4.14.0-rc.1-x86_64" -> "4.14.0-rc.1")
+func ReleaseVersion(versionArch string) string {
+	for _, arch := range releaseArchitectures {
+		if strings.HasSuffix(versionArch, "-"+arch) {
+			return strings.TrimSuffix(versionArch, "-"+arch)
+		}
+	}
+	return versionArch
+}
+
+// HostReleaseArch returns the release architecture matching the host running the wrapper
+func HostReleaseArch() string {
+	return releaseArchFromGOARCH(runtime.GOARCH)
+}
+
+// ClusterArchitecture returns the install-config.yaml architecture (amd64, arm64, ...)
+// for a release architecture. Multi-arch payloads use the host architecture.
+func ClusterArchitecture(releaseArch string) string {
+	switch releaseArch {
+	case "x86_64":
+		return "amd64"
+	case "aarch64":
+		return "arm64"
+	case "multi", "":
+		return runtime.GOARCH
+	default:
+		return releaseArch
+	}
+}
+
+// HostCommandOS returns the value for `oc adm release extract --command-os` that
+// selects binaries runnable on the host (e.g. linux/arm64, mac/arm64)
+func HostCommandOS() string {
+	return commandOS(runtime.GOOS, runtime.GOARCH)
+}
+
+// HostImageFilter returns the value for `oc image extract --filter-by-os`. Binaries
+// shipped inside container images only exist for Linux.
+func HostImageFilter() string {
+	return "linux/" + runtime.GOARCH
+}
+
+func commandOS(goos, goarch string) string {
+	osName := goos
+	if goos == "darwin" {
+		osName = "mac"
+	}
+	return osName + "/" + goarch
+}
+
+func releaseArchFromGOARCH(goarch string) string {
+	switch goarch {
+	case "amd64":
+		return "x86_64"
+	case "arm64":
+		return "aarch64"
+	default:
+		return goarch
+	}
+}
@@ -0,0 +1,54 @@
+package util
+
+import "testing"
+
+func TestReleaseArch(t *testing.T) {
+	tests := map[string]string{
+		"4.15.0-x86_64":      "x86_64",
+		"4.15.0-aarch64":     "aarch64",
+		"4.15.0-multi":       "multi",
+		"4.10.0-fc.4-s390x":  "s390x",
+		"4.15.0":             "x86_64",
+		"4.15.0-rc.1-x86_64": "x86_64",
+	}
+	for versionArch, expected := range tests {
+		if got := ReleaseArch(versionArch); got != expected {
+			t.Errorf("ReleaseArch(%s) = %s, expected %s", versionArch, got, expected)
+		}
+	}
+}
+
+func TestReleaseVersion(t *testing.T) {
+	tests := map[string]string{
+		"4.15.0-x86_64":      "4.15.0",
+		"4.15.0-rc.1-x86_64": "4.15.0-rc.1",
+		"4.10.0-fc.4-s390x":  "4.10.0-fc.4",
+		"4.15.0":             "4.15.0",
+	}
+	for versionArch, expected := range tests {
+		if got := ReleaseVersion(versionArch); got != expected {
+			t.Errorf("ReleaseVersion(%s) = %s, expected %s", versionArch, got, expected)
+		}
+	}
+}
+
+func TestClusterArchitecture(t *testing.T) {
+	if got := ClusterArchitecture("x86_64"); got != "amd64" {
+		t.Errorf("Expected amd64, got %s", got)
+	}
+	if got := ClusterArchitecture("aarch64"); got != "arm64" {
+		t.Errorf("Expected arm64, got %s", got)
+	}
+}
+
+func TestCommandOS(t *testing.T) {
+	if got := commandOS("darwin", "arm64"); got != "mac/arm64" {
+		t.Errorf("Expected mac/arm64, got %s", got)
+	}
+	if got := commandOS("linux", "amd64"); got != "linux/amd64" {
+		t.Errorf("Expected linux/amd64, got %s", got)
+	}
+	if got := releaseArchFromGOARCH("arm64"); got != "aarch64" {
+		t.Errorf("Expected aarch64, got %s", got)
+	}
+}
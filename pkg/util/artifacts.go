@@ -0,0 +1,206 @@
+package util
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SharedArtifact describes a shared versionArch directory and the clusters using it
+type SharedArtifact struct {
+	VersionArch string
+	Path        string
+	LastUsed    time.Time // Most recent modification time of the directory tree
+	Size        int64     // Total size in bytes
+	Clusters    []string  // Clusters whose install metadata references this versionArch, or whose release is unknown
+}
+
+// ListSharedArtifacts returns all shared versionArch directories, most recently used first
+func ListSharedArtifacts() ([]SharedArtifact, error) {
+	sharedRoot := filepath.Join("artifacts", "shared")
+	if !DirExists(sharedRoot) {
+		return nil, nil
+	}
+
+	entries, err := os.ReadDir(sharedRoot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read shared artifacts directory: %w", err)
+	}
+
+	references, unknown := clusterReferences()
+
+	var artifacts []SharedArtifact
+	for _, entry := range entries {
+		// The oc binary and the oc-mirror workspace are shared by every release
+		if !entry.IsDir() || entry.Name() == "bin" || entry.Name() == "mirror" {
+			continue
+		}
+		path := filepath.Join(sharedRoot, entry.Name())
+		lastUsed, size, err := treeStats(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to inspect %s: %w", path, err)
+		}
+		artifacts = append(artifacts, SharedArtifact{
+			VersionArch: entry.Name(),
+			Path:        path,
+			LastUsed:    lastUsed,
+			Size:        size,
+			Clusters:    withUnknownReleases(references[entry.Name()], unknown),
+		})
+	}
+
+	sort.Slice(artifacts, func(i, j int) bool {
+		return artifacts[i].LastUsed.After(artifacts[j].LastUsed)
+	})
+	return artifacts, nil
+}
+
+// SelectPrunable returns the unreferenced artifacts that can be deleted. The
+// keepLast most recently used unreferenced artifacts are preserved, and when
+// olderThan is set only artifacts unused for at least that long are selected.
+// The input must be sorted most recently used first (as returned by ListSharedArtifacts).
+func SelectPrunable(artifacts []SharedArtifact, keepLast int, olderThan time.Duration, now time.Time) []SharedArtifact {
+	var prunable []SharedArtifact
+	kept := 0
+	for _, artifact := range artifacts {
+		if len(artifact.Clusters) > 0 {
+			continue
+		}
+		if kept < keepLast {
+			kept++
+			continue
+		}
+		if olderThan > 0 && now.Sub(artifact.LastUsed) < olderThan {
+			continue
+		}
+		prunable = append(prunable, artifact)
+	}
+	return prunable
+}
+
+// ParseAge parses a duration that may also be expressed in days (e.g. "7d", "72h", "90m")
+func ParseAge(value string) (time.Duration, error) {
+	if strings.HasSuffix(value, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(value, "d"))
+		if err != nil || days < 0 {
+			return 0, fmt.Errorf("invalid age %q", value)
+		}
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+	age, err := time.ParseDuration(value)
+	if err != nil || age < 0 {
+		return 0, fmt.Errorf("invalid age %q", value)
+	}
+	return age, nil
+}
+
+// FormatSize returns a human readable size (e.g. "1.5 GiB")
+func FormatSize(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
+
+// ListClusters returns the names of the clusters with an artifacts directory, sorted
+func ListClusters() ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join("artifacts", "clusters"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read clusters directory: %w", err)
+	}
+
+	var clusters []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			clusters = append(clusters, entry.Name())
+		}
+	}
+	return clusters, nil
+}
+
+// ClusterCreationTime returns when a cluster was created: the creation time
+// recorded in its install metadata or, for clusters installed before it was
+// recorded, the time openshift-install wrote its metadata.json
+func ClusterCreationTime(clusterName string) (time.Time, error) {
+	clusterDir := GetClusterPath(clusterName, "")
+	if metadata, err := ReadInstallMetadata(clusterDir); err == nil && metadata.CreatedAt != nil {
+		return *metadata.CreatedAt, nil
+	}
+	if info, err := os.Stat(filepath.Join(clusterDir, "metadata.json")); err == nil {
+		return info.ModTime(), nil
+	}
+	return time.Time{}, fmt.Errorf("creation time of cluster %s unknown: no install-metadata.json or metadata.json", clusterName)
+}
+
+// clusterReferences maps each versionArch to the clusters whose install
+// metadata references it, and returns the clusters whose release is unknown
+// (no or unreadable install metadata), which may use any of them
+func clusterReferences() (map[string][]string, []string) {
+	references := map[string][]string{}
+	var unknown []string
+
+	clustersRoot := filepath.Join("artifacts", "clusters")
+	entries, err := os.ReadDir(clustersRoot)
+	if err != nil {
+		return references, nil
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		metadata, err := ReadInstallMetadata(filepath.Join(clustersRoot, entry.Name()))
+		if err != nil {
+			unknown = append(unknown, entry.Name())
+			continue
+		}
+		versionArch, err := ExtractVersionArch(metadata.ReleaseImage)
+		if err != nil {
+			unknown = append(unknown, entry.Name())
+			continue
+		}
+		references[versionArch] = append(references[versionArch], entry.Name())
+	}
+	return references, unknown
+}
+
+// withUnknownReleases adds the clusters whose release is unknown to the
+// clusters using an artifact: they may use it, so it must not be pruned
+func withUnknownReleases(clusters, unknown []string) []string {
+	for _, name := range unknown {
+		clusters = append(clusters, name+" (release unknown)")
+	}
+	return clusters
+}
+
+// treeStats returns the most recent modification time and the total size of a directory tree
+func treeStats(root string) (time.Time, int64, error) {
+	var lastUsed time.Time
+	var size int64
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.ModTime().After(lastUsed) {
+			lastUsed = info.ModTime()
+		}
+		if !info.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return lastUsed, size, err
+}
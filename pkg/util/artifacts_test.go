@@ -0,0 +1,154 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestListSharedArtifacts(t *testing.T) {
+	tmpDir := t.TempDir()
+	originalWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(originalWd)
+
+	for _, versionArch := range []string{"4.12.0-x86_64", "4.13.0-x86_64"} {
+		binPath := GetSharedBinaryPath(versionArch, "openshift-install")
+		os.MkdirAll(filepath.Dir(binPath), 0755)
+		os.WriteFile(binPath, []byte("installer"), 0755)
+	}
+
+	clusterDir := GetClusterPath("my-cluster", "")
+	os.MkdirAll(clusterDir, 0755)
+	SaveInstallMetadata(clusterDir, "quay.io/test:4.13.0-x86_64", "")
+
+	if clusters, err := ListClusters(); err != nil || len(clusters) != 1 || clusters[0] != "my-cluster" {
+		t.Errorf("Expected clusters [my-cluster], got %v (%v)", clusters, err)
+	}
+
+	artifacts, err := ListSharedArtifacts()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(artifacts) != 2 {
+		t.Fatalf("Expected 2 shared artifacts, got %d", len(artifacts))
+	}
+
+	for _, artifact := range artifacts {
+		switch artifact.VersionArch {
+		case "4.12.0-x86_64":
+			if len(artifact.Clusters) != 0 {
+				t.Errorf("Expected 4.12.0 to be unreferenced, got %v", artifact.Clusters)
+			}
+		case "4.13.0-x86_64":
+			if len(artifact.Clusters) != 1 || artifact.Clusters[0] != "my-cluster" {
+				t.Errorf("Expected 4.13.0 to be referenced by my-cluster, got %v", artifact.Clusters)
+			}
+		}
+		if artifact.Size != int64(len("installer")) {
+			t.Errorf("Unexpected size %d for %s", artifact.Size, artifact.VersionArch)
+		}
+	}
+
+	// A cluster without install metadata may use any of them
+	os.MkdirAll(GetClusterPath("new-cluster", ""), 0755)
+	artifacts, _ = ListSharedArtifacts()
+	for _, artifact := range artifacts {
+		if len(artifact.Clusters) == 0 || artifact.Clusters[len(artifact.Clusters)-1] != "new-cluster (release unknown)" {
+			t.Errorf("Expected %s to be referenced by new-cluster, got %v", artifact.VersionArch, artifact.Clusters)
+		}
+	}
+}
+
+func TestSelectPrunable(t *testing.T) {
+	now := time.Now()
+	artifacts := []SharedArtifact{
+		{VersionArch: "4.15.0-x86_64", LastUsed: now.Add(-1 * time.Hour)},
+		{VersionArch: "4.14.0-x86_64", LastUsed: now.Add(-48 * time.Hour), Clusters: []string{"in-use"}},
+		{VersionArch: "4.13.0-x86_64", LastUsed: now.Add(-72 * time.Hour)},
+		{VersionArch: "4.12.0-x86_64", LastUsed: now.Add(-240 * time.Hour)},
+	}
+
+	tests := []struct {
+		name      string
+		keepLast  int
+		olderThan time.Duration
+		expected  []string
+	}{
+		{"all unreferenced", 0, 0, []string{"4.15.0-x86_64", "4.13.0-x86_64", "4.12.0-x86_64"}},
+		{"keep last one", 1, 0, []string{"4.13.0-x86_64", "4.12.0-x86_64"}},
+		{"older than 4 days", 0, 96 * time.Hour, []string{"4.12.0-x86_64"}},
+		{"keep last two and older than a day", 2, 24 * time.Hour, []string{"4.12.0-x86_64"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prunable := SelectPrunable(artifacts, tt.keepLast, tt.olderThan, now)
+			if len(prunable) != len(tt.expected) {
+				t.Fatalf("Expected %v, got %v", tt.expected, prunable)
+			}
+			for i, artifact := range prunable {
+				if artifact.VersionArch != tt.expected[i] {
+					t.Errorf("Expected %s at position %d, got %s", tt.expected[i], i, artifact.VersionArch)
+				}
+			}
+		})
+	}
+}
+
+func TestParseAge(t *testing.T) {
+	if age, err := ParseAge("7d"); err != nil || age != 7*24*time.Hour {
+		t.Errorf("Expected 7 days, got %s (%v)", age, err)
+	}
+	if age, err := ParseAge("72h"); err != nil || age != 72*time.Hour {
+		t.Errorf("Expected 72h, got %s (%v)", age, err)
+	}
+	if _, err := ParseAge("soon"); err == nil {
+		t.Error("Expected error for invalid age")
+	}
+}
+
+func TestClusterCreationTime(t *testing.T) {
+	tmpDir := t.TempDir()
+	originalWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(originalWd)
+
+	clusterDir := GetClusterPath("my-cluster", "")
+	os.MkdirAll(clusterDir, 0755)
+	if _, err := ClusterCreationTime("my-cluster"); err == nil {
+		t.Error("Expected an error without metadata")
+	}
+
+	// Clusters installed before the creation time was recorded
+	created := time.Now().Add(-72 * time.Hour).Truncate(time.Second)
+	os.WriteFile(filepath.Join(clusterDir, "metadata.json"), []byte("{}"), 0644)
+	os.Chtimes(filepath.Join(clusterDir, "metadata.json"), created, created)
+	if got, err := ClusterCreationTime("my-cluster"); err != nil || !got.Equal(created) {
+		t.Errorf("Expected %v from metadata.json, got %v (%v)", created, got, err)
+	}
+
+	// The install metadata records it, and keeps it when saved again (e.g. by an upgrade)
+	if err := SaveInstallMetadata(clusterDir, "quay.io/openshift-release-dev/ocp-release:4.15.0-x86_64", ""); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	first, err := ClusterCreationTime("my-cluster")
+	if err != nil || time.Since(first) > time.Minute {
+		t.Errorf("Expected the creation time to be recorded now, got %v (%v)", first, err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	SaveInstallMetadata(clusterDir, "quay.io/openshift-release-dev/ocp-release:4.16.0-x86_64", "")
+	if again, _ := ClusterCreationTime("my-cluster"); !again.Equal(first) {
+		t.Errorf("Expected the creation time %v to be kept, got %v", first, again)
+	}
+
+	// The SSH key is kept too
+	if err := RecordSSHKey(clusterDir, "artifacts/clusters/my-cluster/ssh/id_ed25519.pub"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	SaveInstallMetadata(clusterDir, "quay.io/openshift-release-dev/ocp-release:4.17.0-x86_64", "")
+	if metadata, _ := ReadInstallMetadata(clusterDir); metadata.SSHKeyPath != "artifacts/clusters/my-cluster/ssh/id_ed25519.pub" {
+		t.Errorf("Expected the SSH key to be kept, got %+v", metadata)
+	}
+}
@@ -0,0 +1,244 @@
+package util
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/clobrano/openshift-sts-wrapper/pkg/logger"
+)
+
+// GetAuditDir returns the directory of the audit logs, one file per run
+func GetAuditDir() string {
+	return filepath.Join("artifacts", "audit")
+}
+
+// AuditEntry is an external command run by the wrapper, as stored in an audit
+// log. The values of the environment variables are not stored, and the
+// secrets of the arguments are redacted.
+type AuditEntry struct {
+	Time     time.Time `json:"time"`
+	Command  string    `json:"command"`
+	Args     []string  `json:"args"`
+	Env      []string  `json:"env,omitempty"` // Names of the environment variables set for the command
+	Dir      string    `json:"dir"`
+	ExitCode int       `json:"exitCode"` // -1 when the command could not be started
+	Duration float64   `json:"durationSeconds"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// AuditLog appends every command run through its executors to an audit file,
+// one JSON entry per line. The file is created with the first command, and
+// never rewritten.
+type AuditLog struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewAuditLog creates the audit log of a run of a wrapper command (e.g.
+// install) in the audit directory, named after the start time of the run
+func NewAuditLog(command string, start time.Time) *AuditLog {
+	name := fmt.Sprintf("%s-%s.jsonl", start.UTC().Format("20060102T150405Z"), strings.ReplaceAll(command, " ", "-"))
+	return &AuditLog{path: filepath.Join(GetAuditDir(), name)}
+}
+
+// Path returns the file of the audit log
+func (a *AuditLog) Path() string {
+	return a.path
+}
+
+// Wrap returns an executor recording the commands run by executor
+func (a *AuditLog) Wrap(executor CommandExecutor) CommandExecutor {
+	return &auditingExecutor{log: a, executor: executor}
+}
+
+// append writes an entry at the end of the audit file
+func (a *AuditLog) append(entry AuditEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if err := EnsureDir(filepath.Dir(a.path)); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(a.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	return file.Close()
+}
+
+// auditingExecutor runs the commands with its executor and records them
+type auditingExecutor struct {
+	log      *AuditLog
+	executor CommandExecutor
+}
+
+func (e *auditingExecutor) Unwrap() CommandExecutor {
+	return e.executor
+}
+
+func (e *auditingExecutor) Execute(name string, args ...string) (string, error) {
+	start := time.Now()
+	output, err := e.executor.Execute(name, args...)
+	return output, e.record(start, name, nil, args, err)
+}
+
+func (e *auditingExecutor) ExecuteWithEnv(name string, env []string, args ...string) (string, error) {
+	start := time.Now()
+	output, err := e.executor.ExecuteWithEnv(name, env, args...)
+	return output, e.record(start, name, env, args, err)
+}
+
+func (e *auditingExecutor) ExecuteInteractive(name string, args ...string) error {
+	start := time.Now()
+	err := e.executor.ExecuteInteractive(name, args...)
+	return e.record(start, name, nil, args, err)
+}
+
+func (e *auditingExecutor) ExecuteInteractiveWithEnv(name string, env []string, args ...string) error {
+	start := time.Now()
+	err := e.executor.ExecuteInteractiveWithEnv(name, env, args...)
+	return e.record(start, name, env, args, err)
+}
+
+// record appends a command to the audit log, returning its error (a failure
+// to write the audit log is reported with it, not instead of it)
+func (e *auditingExecutor) record(start time.Time, name string, env, args []string, err error) error {
+	entry := AuditEntry{
+		Time:     start.UTC(),
+		Command:  name,
+		Args:     make([]string, len(args)),
+		Duration: time.Since(start).Seconds(),
+	}
+	for i, arg := range args {
+		entry.Args[i] = logger.Redact(arg)
+	}
+	for _, variable := range env {
+		entry.Env = append(entry.Env, strings.SplitN(variable, "=", 2)[0])
+	}
+	entry.Dir, _ = os.Getwd()
+	if err != nil {
+		entry.ExitCode = -1
+		entry.Error = logger.Redact(err.Error())
+		var exitErr *exec.ExitError
+		var replayed *ReplayedError
+		if errors.As(err, &exitErr) {
+			entry.ExitCode = exitErr.ExitCode()
+		} else if errors.As(err, &replayed) {
+			entry.ExitCode = replayed.ExitCode
+		}
+	}
+
+	if auditErr := e.log.append(entry); auditErr != nil {
+		if err != nil {
+			return fmt.Errorf("%w (%v)", err, auditErr)
+		}
+		return auditErr
+	}
+	return err
+}
+
+// AuditRun is an audit log, one per run of the wrapper
+type AuditRun struct {
+	Name     string    `json:"name"` // File name, without extension
+	Path     string    `json:"path"`
+	Start    time.Time `json:"start"` // Time of the first command
+	Commands int       `json:"commands"`
+	Failed   int       `json:"failed"`
+}
+
+// ListAuditRuns returns the audit logs of the audit directory, oldest first
+func ListAuditRuns() ([]AuditRun, error) {
+	paths, err := filepath.Glob(filepath.Join(GetAuditDir(), "*.jsonl"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	var runs []AuditRun
+	for _, path := range paths {
+		entries, err := ReadAuditLog(path)
+		if err != nil {
+			return nil, err
+		}
+		run := AuditRun{Name: strings.TrimSuffix(filepath.Base(path), ".jsonl"), Path: path, Commands: len(entries)}
+		for i, entry := range entries {
+			if i == 0 {
+				run.Start = entry.Time
+			}
+			if entry.ExitCode != 0 {
+				run.Failed++
+			}
+		}
+		runs = append(runs, run)
+	}
+	return runs, nil
+}
+
+// FindAuditRun returns the audit log of a run, by its name or a unique prefix
+// of it (e.g. the date of the run)
+func FindAuditRun(name string) (*AuditRun, error) {
+	runs, err := ListAuditRuns()
+	if err != nil {
+		return nil, err
+	}
+	var matches []AuditRun
+	for _, run := range runs {
+		if run.Name == name || strings.TrimSuffix(name, ".jsonl") == run.Name {
+			return &run, nil
+		}
+		if strings.HasPrefix(run.Name, name) {
+			matches = append(matches, run)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("no audit log for run %q in %s", name, GetAuditDir())
+	case 1:
+		return &matches[0], nil
+	}
+	return nil, fmt.Errorf("%d audit logs match %q: use a longer name", len(matches), name)
+}
+
+// ReadAuditLog reads the entries of an audit log
+func ReadAuditLog(path string) ([]AuditEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+	defer file.Close()
+
+	var entries []AuditEntry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var entry AuditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("failed to parse %s line %d: %w", path, line, err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+	return entries, nil
+}
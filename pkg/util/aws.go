@@ -2,11 +2,22 @@ package util
 
 import (
 	"bufio"
+	"context"
+	"encoding/json"
 	"fmt"
+	"math"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/logger"
 )
 
 // AWSCredentials holds AWS credentials from the credentials file
@@ -14,20 +25,20 @@ type AWSCredentials struct {
 	AccessKeyID     string
 	SecretAccessKey string
 	SessionToken    string
+	Expiration      time.Time // zero for long-term credentials
 }
 
-// ReadAWSCredentials reads AWS credentials from ~/.aws/credentials for a given profile
+// ReadAWSCredentials reads AWS credentials from ~/.aws/credentials (or
+// AWS_SHARED_CREDENTIALS_FILE) for a given profile
 func ReadAWSCredentials(profile string) (*AWSCredentials, error) {
 	if profile == "" {
 		profile = "default"
 	}
 
-	homeDir, err := os.UserHomeDir()
+	credentialsPath, err := awsCredentialsPath()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get home directory: %w", err)
+		return nil, err
 	}
-
-	credentialsPath := filepath.Join(homeDir, ".aws", "credentials")
 	file, err := os.Open(credentialsPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open credentials file: %w", err)
@@ -86,13 +97,351 @@ func ReadAWSCredentials(profile string) (*AWSCredentials, error) {
 	return creds, nil
 }
 
-// GetAWSEnvVars returns environment variables for AWS credentials
-func GetAWSEnvVars(profile string) ([]string, error) {
-	// TODO: intergrate it with LoadFromEnv. The source of AWS credentials must be transparent to the users, they shall be able to set env variables or rely on aws-credential file created by aws-saml.py as they like
-	creds, err := ReadAWSCredentials(profile)
+// awsCredentialsPath returns the path of the aws CLI credentials file
+func awsCredentialsPath() (string, error) {
+	if path := os.Getenv("AWS_SHARED_CREDENTIALS_FILE"); path != "" {
+		return path, nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, ".aws", "credentials"), nil
+}
+
+// awsConfigPath returns the path of the aws CLI config file
+func awsConfigPath() (string, error) {
+	if path := os.Getenv("AWS_CONFIG_FILE"); path != "" {
+		return path, nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, ".aws", "config"), nil
+}
+
+// readAWSConfigProfile returns the settings of a profile in ~/.aws/config.
+// Profiles other than default are stored in "[profile <name>]" sections.
+func readAWSConfigProfile(profile string) (map[string]string, error) {
+	if profile == "" {
+		profile = "default"
+	}
+	section := "profile " + profile
+	if profile == "default" {
+		section = "default"
+	}
+
+	configPath, err := awsConfigPath()
 	if err != nil {
 		return nil, err
 	}
+	file, err := os.Open(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open aws config file: %w", err)
+	}
+	defer file.Close()
+
+	settings := map[string]string{}
+	inTargetSection := false
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			inTargetSection = strings.TrimSpace(strings.Trim(line, "[]")) == section
+			continue
+		}
+		if key, value, ok := strings.Cut(line, "="); ok && inTargetSection {
+			settings[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading aws config file: %w", err)
+	}
+	return settings, nil
+}
+
+// ListAWSProfiles returns the names of the profiles in ~/.aws/config and
+// ~/.aws/credentials, sorted. Either file may be missing.
+func ListAWSProfiles() ([]string, error) {
+	configPath, err := awsConfigPath()
+	if err != nil {
+		return nil, err
+	}
+	credentialsPath, err := awsCredentialsPath()
+	if err != nil {
+		return nil, err
+	}
+
+	found := map[string]bool{}
+	// Profiles other than default are "[profile <name>]" sections in the config
+	// file, and "[<name>]" sections in the credentials file
+	configErr := readAWSSections(configPath, func(section string) {
+		if section == "default" {
+			found[section] = true
+		} else if name, ok := strings.CutPrefix(section, "profile "); ok {
+			found[strings.TrimSpace(name)] = true
+		}
+	})
+	credentialsErr := readAWSSections(credentialsPath, func(section string) {
+		found[section] = true
+	})
+	if configErr != nil && credentialsErr != nil {
+		return nil, configErr
+	}
+
+	profiles := make([]string, 0, len(found))
+	for name := range found {
+		profiles = append(profiles, name)
+	}
+	sort.Strings(profiles)
+	return profiles, nil
+}
+
+// readAWSSections calls section with the name of every section of an AWS
+// config or credentials file
+func readAWSSections(path string, section func(string)) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section(strings.TrimSpace(strings.Trim(line, "[]")))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("error reading %s: %w", path, err)
+	}
+	return nil
+}
+
+// IsSSOProfile reports whether the profile gets its credentials from AWS IAM
+// Identity Center (SSO)
+func IsSSOProfile(profile string) bool {
+	settings, err := readAWSConfigProfile(profile)
+	if err != nil {
+		return false
+	}
+	return settings["sso_session"] != "" || settings["sso_start_url"] != ""
+}
+
+// SSOLoginCommand returns the command that starts a new SSO session for the profile
+func SSOLoginCommand(profile string) []string {
+	return []string{"aws", "sso", "login", "--profile", profile}
+}
+
+// ResetExportedCredentials drops the cached credentials of a profile, e.g.
+// after a new SSO session was started
+func ResetExportedCredentials(profile string) {
+	exportedCredentials.Lock()
+	defer exportedCredentials.Unlock()
+	delete(exportedCredentials.byProfile, profile)
+	delete(exportedCredentials.expiry, profile)
+}
+
+// exportedCredentials caches the temporary credentials resolved by the SDK,
+// so that commands run in a loop do not resolve them every time
+var exportedCredentials = struct {
+	sync.Mutex
+	byProfile map[string]*AWSCredentials
+	expiry    map[string]time.Time
+}{byProfile: map[string]*AWSCredentials{}, expiry: map[string]time.Time{}}
+
+// credentialsRefreshMargin is how long before expiration cached credentials are refreshed
+const credentialsRefreshMargin = 5 * time.Minute
+
+// retrieveCredentials resolves the credentials of a profile through the AWS SDK
+// default credential chain; replaced in tests
+var retrieveCredentials = func(profile string) (aws.Credentials, error) {
+	ctx := context.Background()
+	awsConfig, err := awsconfig.LoadDefaultConfig(ctx, awsconfig.WithSharedConfigProfile(profile))
+	if err != nil {
+		return aws.Credentials{}, err
+	}
+	if awsConfig.Credentials == nil {
+		return aws.Credentials{}, fmt.Errorf("no credential provider configured")
+	}
+	return awsConfig.Credentials.Retrieve(ctx)
+}
+
+// ExportAWSCredentials resolves the credentials of a profile through the AWS SDK
+// credential chain (SSO, assume-role, web identity, credential_process...) and
+// returns them as static, possibly temporary, credentials
+func ExportAWSCredentials(profile string) (*AWSCredentials, error) {
+	if profile == "" {
+		profile = "default"
+	}
+
+	exportedCredentials.Lock()
+	defer exportedCredentials.Unlock()
+
+	if creds, ok := exportedCredentials.byProfile[profile]; ok {
+		expiry := exportedCredentials.expiry[profile]
+		if expiry.IsZero() || time.Until(expiry) > credentialsRefreshMargin {
+			return creds, nil
+		}
+	}
+
+	retrieved, err := retrieveCredentials(profile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve credentials for profile '%s': %w", profile, err)
+	}
+	if retrieved.AccessKeyID == "" || retrieved.SecretAccessKey == "" {
+		return nil, fmt.Errorf("no credentials resolved for profile '%s'", profile)
+	}
+
+	creds := &AWSCredentials{
+		AccessKeyID:     retrieved.AccessKeyID,
+		SecretAccessKey: retrieved.SecretAccessKey,
+		SessionToken:    retrieved.SessionToken,
+	}
+	var expiry time.Time
+	if retrieved.CanExpire {
+		expiry = retrieved.Expires
+	}
+	exportedCredentials.byProfile[profile] = creds
+	exportedCredentials.expiry[profile] = expiry
+
+	return creds, nil
+}
+
+// sessionCredentials, when set, replace the credentials of every profile: the
+// installation then runs under an assumed role instead of the profile identity
+var sessionCredentials struct {
+	sync.Mutex
+	creds *AWSCredentials
+}
+
+// SetSessionCredentials makes every AWS call and child process use the given
+// credentials (e.g. the result of AssumeRole) instead of the profile ones
+func SetSessionCredentials(creds *AWSCredentials) {
+	sessionCredentials.Lock()
+	defer sessionCredentials.Unlock()
+	sessionCredentials.creds = creds
+}
+
+// HasSessionCredentials reports whether session credentials replace the profile ones
+func HasSessionCredentials() bool {
+	return activeSessionCredentials() != nil
+}
+
+func activeSessionCredentials() *AWSCredentials {
+	sessionCredentials.Lock()
+	defer sessionCredentials.Unlock()
+	return sessionCredentials.creds
+}
+
+// AssumeRole calls STS AssumeRole with the credentials of the profile and
+// returns the temporary credentials of the role. mfaSerial and tokenCode are
+// only needed when the role trust policy requires MFA. The session lasts for
+// duration, within the maximum session duration of the role (0 keeps the STS
+// default of one hour).
+func AssumeRole(executor CommandExecutor, profile, roleARN, sessionName, mfaSerial, tokenCode string, duration time.Duration) (*AWSCredentials, error) {
+	args := []string{"sts", "assume-role", "--role-arn", roleARN, "--role-session-name", sessionName}
+	if seconds := sessionDurationSeconds(executor, profile, roleARN, duration); seconds > 0 {
+		args = append(args, "--duration-seconds", strconv.Itoa(seconds))
+	}
+	if mfaSerial != "" {
+		args = append(args, "--serial-number", mfaSerial, "--token-code", tokenCode)
+	}
+
+	output, err := RunAWSCLI(executor, profile, "", args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to assume role %s: %w", roleARN, err)
+	}
+
+	var result struct {
+		Credentials struct {
+			AccessKeyID     string    `json:"AccessKeyId"`
+			SecretAccessKey string    `json:"SecretAccessKey"`
+			SessionToken    string    `json:"SessionToken"`
+			Expiration      time.Time `json:"Expiration"`
+		} `json:"Credentials"`
+	}
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		return nil, fmt.Errorf("failed to parse assume-role output: %w", err)
+	}
+	if result.Credentials.AccessKeyID == "" {
+		return nil, fmt.Errorf("no credentials returned when assuming role %s", roleARN)
+	}
+
+	creds := &AWSCredentials{
+		AccessKeyID:     result.Credentials.AccessKeyID,
+		SecretAccessKey: result.Credentials.SecretAccessKey,
+		SessionToken:    result.Credentials.SessionToken,
+		Expiration:      result.Credentials.Expiration,
+	}
+	creds.redact()
+	return creds, nil
+}
+
+// STS bounds of the duration of a role session
+const (
+	minRoleSessionSeconds = 900
+	maxRoleSessionSeconds = 43200
+)
+
+// sessionDurationSeconds returns the duration to request for a role session:
+// duration rounded up to STS bounds and capped to the maximum session duration
+// of the role, when the profile is allowed to read it (0 means the STS default)
+func sessionDurationSeconds(executor CommandExecutor, profile, roleARN string, duration time.Duration) int {
+	if duration <= 0 {
+		return 0
+	}
+	seconds := int(math.Ceil(duration.Seconds()))
+	seconds = max(seconds, minRoleSessionSeconds)
+	seconds = min(seconds, maxRoleSessionSeconds)
+
+	roleName := roleARN[strings.LastIndex(roleARN, "/")+1:]
+	output, err := RunAWSCLI(executor, profile, "", "iam", "get-role", "--role-name", roleName)
+	if err != nil {
+		return seconds // STS rejects a duration beyond the role maximum with a clear error
+	}
+	var result struct {
+		Role struct {
+			MaxSessionDuration int `json:"MaxSessionDuration"`
+		} `json:"Role"`
+	}
+	if json.Unmarshal([]byte(output), &result) == nil && result.Role.MaxSessionDuration > 0 {
+		seconds = min(seconds, result.Role.MaxSessionDuration)
+	}
+	return seconds
+}
+
+// redact registers the credentials with the logger, so that they never show
+// in a log or in the recorded commands
+func (c *AWSCredentials) redact() {
+	logger.AddSecret(c.AccessKeyID)
+	logger.AddSecret(c.SecretAccessKey)
+	logger.AddSecret(c.SessionToken)
+}
+
+// GetAWSEnvVars returns environment variables for AWS credentials, to be passed
+// to child processes. Static keys in ~/.aws/credentials are used as is; any
+// other profile (SSO, assume-role, web identity...) is resolved to temporary
+// credentials through the aws CLI credential chain. Session credentials, when
+// set, take precedence over the profile.
+func GetAWSEnvVars(profile string) ([]string, error) {
+	creds := activeSessionCredentials()
+	if creds == nil {
+		var err error
+		if creds, err = ReadAWSCredentials(profile); err != nil {
+			exported, exportErr := ExportAWSCredentials(profile)
+			if exportErr != nil {
+				return nil, fmt.Errorf("%v; %w", err, exportErr)
+			}
+			creds = exported
+		}
+	}
+	creds.redact()
 
 	envVars := []string{
 		fmt.Sprintf("AWS_ACCESS_KEY_ID=%s", creds.AccessKeyID),
@@ -115,8 +464,13 @@ func ValidateAWSCredentials(profile string) error {
 		return fmt.Errorf("failed to read credentials for profile '%s': %w", profile, err)
 	}
 
-	// Run aws sts get-caller-identity to validate credentials
-	cmd := exec.Command("aws", "sts", "get-caller-identity", "--profile", profile)
+	// Run aws sts get-caller-identity to validate credentials. Session
+	// credentials are only in the environment, the profile would override them.
+	args := []string{"sts", "get-caller-identity"}
+	if activeSessionCredentials() == nil {
+		args = append(args, "--profile", profile)
+	}
+	cmd := exec.Command("aws", args...)
 
 	// Set environment with credentials
 	cmd.Env = append(os.Environ(), envVars...)
@@ -127,6 +481,9 @@ func ValidateAWSCredentials(profile string) error {
 		if strings.Contains(outputStr, "ExpiredToken") || strings.Contains(outputStr, "expired") {
 			return fmt.Errorf("AWS credentials for profile '%s' have expired. Please refresh your credentials", profile)
 		}
+		if strings.Contains(outputStr, "SSO") || strings.Contains(outputStr, "sso") {
+			return fmt.Errorf("AWS SSO session for profile '%s' is not valid, run 'aws sso login --profile %s': %s", profile, profile, outputStr)
+		}
 		if strings.Contains(outputStr, "InvalidClientTokenId") {
 			return fmt.Errorf("AWS credentials for profile '%s' are invalid", profile)
 		}
@@ -135,3 +492,27 @@ func ValidateAWSCredentials(profile string) error {
 
 	return nil
 }
+
+// RunAWSCLI runs an aws CLI command with the credentials of the given profile and
+// returns its JSON output. Region is optional.
+func RunAWSCLI(executor CommandExecutor, profile, region string, args ...string) (string, error) {
+	cliArgs := append([]string{}, args...)
+	cliArgs = append(cliArgs, "--output", "json")
+	if profile != "" && activeSessionCredentials() == nil {
+		cliArgs = append(cliArgs, "--profile", profile)
+	}
+	if region != "" {
+		cliArgs = append(cliArgs, "--region", region)
+	}
+
+	envVars, err := GetAWSEnvVars(profile)
+	if err != nil {
+		envVars = nil // Rely on the aws CLI credential chain
+	}
+
+	output, err := executor.ExecuteWithEnv("aws", envVars, cliArgs...)
+	if err != nil {
+		return "", fmt.Errorf("aws %s failed: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(output))
+	}
+	return output, nil
+}
@@ -3,7 +3,11 @@ package util
 import (
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
 )
 
 func TestReadAWSCredentials(t *testing.T) {
@@ -174,3 +178,124 @@ aws_session_token = FwoGZXIvYXdzEBQaDExampleSessionToken
 		}
 	}
 }
+
+func TestGetAWSEnvVarsFromCredentialChain(t *testing.T) {
+	// No static keys in ~/.aws/credentials (e.g. an SSO profile)
+	t.Setenv("HOME", t.TempDir())
+
+	calls := 0
+	original := retrieveCredentials
+	retrieveCredentials = func(profile string) (aws.Credentials, error) {
+		calls++
+		if profile != "sso-profile" {
+			t.Errorf("Unexpected profile %s", profile)
+		}
+		return aws.Credentials{
+			AccessKeyID:     "ASIAEXAMPLE",
+			SecretAccessKey: "secret",
+			SessionToken:    "token",
+			CanExpire:       true,
+			Expires:         time.Now().Add(time.Hour),
+		}, nil
+	}
+	defer func() { retrieveCredentials = original }()
+
+	for i := 0; i < 2; i++ {
+		envVars, err := GetAWSEnvVars("sso-profile")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(envVars) != 3 || envVars[0] != "AWS_ACCESS_KEY_ID=ASIAEXAMPLE" || envVars[2] != "AWS_SESSION_TOKEN=token" {
+			t.Errorf("Unexpected environment variables: %v", envVars)
+		}
+	}
+
+	// Temporary credentials are cached until they are about to expire
+	if calls != 1 {
+		t.Errorf("Expected credentials to be exported once, got %d", calls)
+	}
+}
+
+func TestIsSSOProfile(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config")
+	configContent := `[default]
+region = us-east-1
+
+[profile sso-dev]
+sso_session = my-sso
+sso_account_id = 123456789012
+sso_role_name = Admin
+
+[profile legacy-sso]
+sso_start_url = https://example.awsapps.com/start
+
+[profile static]
+region = us-east-2
+`
+	if err := os.WriteFile(configPath, []byte(configContent), 0600); err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+	t.Setenv("AWS_CONFIG_FILE", configPath)
+	credentialsPath := filepath.Join(t.TempDir(), "credentials")
+	if err := os.WriteFile(credentialsPath, []byte("[default]\n[static]\n[ci-keys]\naws_access_key_id = AKIA\n"), 0600); err != nil {
+		t.Fatalf("Failed to create test credentials file: %v", err)
+	}
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", credentialsPath)
+
+	tests := map[string]bool{
+		"default":    false,
+		"sso-dev":    true,
+		"legacy-sso": true,
+		"static":     false,
+		"missing":    false,
+	}
+	for profile, expected := range tests {
+		if got := IsSSOProfile(profile); got != expected {
+			t.Errorf("IsSSOProfile(%q) = %v, expected %v", profile, got, expected)
+		}
+	}
+
+	profiles, err := ListAWSProfiles()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// Profiles with static keys only are in the credentials file
+	if expected := []string{"ci-keys", "default", "legacy-sso", "sso-dev", "static"}; !reflect.DeepEqual(profiles, expected) {
+		t.Errorf("Expected profiles %v, got %v", expected, profiles)
+	}
+}
+
+func TestAssumeRole(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	executor := NewMockExecutor()
+	executor.SetOutput("aws iam get-role --role-name installer --output json --profile default", `{"Role": {"MaxSessionDuration": 7200}}`)
+	executor.SetOutput("aws sts assume-role --role-arn arn:aws:iam::123456789012:role/installer --role-session-name openshift-sts-wrapper-test --duration-seconds 7200 --serial-number arn:aws:iam::123456789012:mfa/me --token-code 123456 --output json --profile default",
+		`{"Credentials": {"AccessKeyId": "ASIAROLE", "SecretAccessKey": "role-secret", "SessionToken": "role-token", "Expiration": "2030-01-01T00:00:00Z"}}`)
+
+	creds, err := AssumeRole(executor, "default", "arn:aws:iam::123456789012:role/installer", "openshift-sts-wrapper-test", "arn:aws:iam::123456789012:mfa/me", "123456", 3*time.Hour)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// The session is capped to the maximum duration of the role
+	if creds.AccessKeyID != "ASIAROLE" || creds.SessionToken != "role-token" || creds.Expiration.Year() != 2030 {
+		t.Errorf("Unexpected credentials: %+v", creds)
+	}
+
+	// Once set, the role credentials replace the profile for AWS calls and child processes
+	SetSessionCredentials(creds)
+	defer SetSessionCredentials(nil)
+
+	envVars, err := GetAWSEnvVars("default")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if envVars[0] != "AWS_ACCESS_KEY_ID=ASIAROLE" {
+		t.Errorf("Expected role credentials, got %v", envVars)
+	}
+
+	RunAWSCLI(executor, "default", "us-east-1", "ec2", "describe-vpcs")
+	if !executor.WasExecuted("aws ec2 describe-vpcs --output json --region us-east-1") {
+		t.Errorf("Expected the profile to be omitted, got %v", executor.Commands)
+	}
+}
@@ -0,0 +1,185 @@
+package util
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ArtifactCache records the sha256 checksum of every artifact extracted into a
+// shared versionArch directory, keyed by the release image digest it came from.
+// An artifact is only reused for that digest, when its current content matches
+// the recorded checksum.
+type ArtifactCache struct {
+	ReleaseImage  string            `json:"releaseImage"`
+	ReleaseDigest string            `json:"releaseDigest,omitempty"`
+	Files         map[string]string `json:"files"` // Path relative to the shared directory -> sha256
+}
+
+// GetSharedDir returns the shared artifacts directory for a versionArch
+func GetSharedDir(versionArch string) string {
+	return filepath.Join("artifacts", "shared", versionArch)
+}
+
+// GetArtifactCachePath returns the path to the checksum manifest of a shared versionArch directory
+func GetArtifactCachePath(versionArch string) string {
+	return filepath.Join(GetSharedDir(versionArch), "cache.json")
+}
+
+// FileSHA256 returns the hex encoded sha256 checksum of a file
+func FileSHA256(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// ReadArtifactCache reads the checksum manifest of a shared versionArch directory
+func ReadArtifactCache(versionArch string) (*ArtifactCache, error) {
+	data, err := os.ReadFile(GetArtifactCachePath(versionArch))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read artifact cache: %w", err)
+	}
+
+	var cache ArtifactCache
+	if err := json.Unmarshal(data, &cache); err != nil {
+		return nil, fmt.Errorf("failed to parse artifact cache: %w", err)
+	}
+	if cache.Files == nil {
+		cache.Files = map[string]string{}
+	}
+	return &cache, nil
+}
+
+// RecordArtifacts computes the checksums of the given files or directories
+// (recursively) and stores them in the checksum manifest of the release
+// digest. Entries previously recorded under a given directory are replaced.
+// Artifacts of a release whose digest is unknown are not recorded: a tag can
+// be moved to other content.
+func RecordArtifacts(versionArch, releaseImage, releaseDigest string, paths ...string) error {
+	if releaseDigest == "" {
+		return fmt.Errorf("release digest of %s unknown: artifacts not cached", releaseImage)
+	}
+	cache, err := ReadArtifactCache(versionArch)
+	if err != nil || cache.ReleaseDigest != releaseDigest {
+		// Missing, corrupted or belonging to a different release: start over
+		cache = &ArtifactCache{Files: map[string]string{}}
+	}
+	cache.ReleaseImage = releaseImage
+	cache.ReleaseDigest = releaseDigest
+
+	sharedDir := GetSharedDir(versionArch)
+	for _, path := range paths {
+		files, err := listFiles(path)
+		if err != nil {
+			return fmt.Errorf("failed to list artifacts in %s: %w", path, err)
+		}
+
+		prefix, err := filepath.Rel(sharedDir, path)
+		if err != nil {
+			return err
+		}
+		for rel := range cache.Files {
+			if rel == prefix || isUnder(rel, prefix) {
+				delete(cache.Files, rel)
+			}
+		}
+
+		for _, file := range files {
+			sum, err := FileSHA256(file)
+			if err != nil {
+				return fmt.Errorf("failed to checksum %s: %w", file, err)
+			}
+			rel, err := filepath.Rel(sharedDir, file)
+			if err != nil {
+				return err
+			}
+			cache.Files[rel] = sum
+		}
+	}
+
+	data, err := json.MarshalIndent(cache, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal artifact cache: %w", err)
+	}
+	if err := os.WriteFile(GetArtifactCachePath(versionArch), data, 0644); err != nil {
+		return fmt.Errorf("failed to write artifact cache: %w", err)
+	}
+	return nil
+}
+
+// VerifyArtifact checks that a file or directory (recursively) in the shared
+// versionArch directory matches the checksums recorded for the release
+// digest. Nothing verifies when the digest is unknown.
+func VerifyArtifact(versionArch, releaseDigest, path string) bool {
+	cache, err := ReadArtifactCache(versionArch)
+	if err != nil || releaseDigest == "" || cache.ReleaseDigest != releaseDigest {
+		return false
+	}
+
+	files, err := listFiles(path)
+	if err != nil || len(files) == 0 {
+		return false
+	}
+
+	sharedDir := GetSharedDir(versionArch)
+	for _, file := range files {
+		rel, err := filepath.Rel(sharedDir, file)
+		if err != nil {
+			return false
+		}
+		expected, ok := cache.Files[rel]
+		if !ok {
+			return false
+		}
+		sum, err := FileSHA256(file)
+		if err != nil || sum != expected {
+			return false
+		}
+	}
+	return true
+}
+
+// listFiles returns the regular files at path: the path itself if it is a
+// file, or every file below it if it is a directory
+func listFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	var files []string
+	err = filepath.Walk(path, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !fi.IsDir() {
+			files = append(files, p)
+		}
+		return nil
+	})
+	sort.Strings(files)
+	return files, err
+}
+
+// isUnder reports whether the relative path rel lies inside the relative directory dir
+func isUnder(rel, dir string) bool {
+	up, err := filepath.Rel(dir, rel)
+	return err == nil && up != "." && up != ".." && !strings.HasPrefix(up, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,71 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestArtifactCache(t *testing.T) {
+	tmpDir := t.TempDir()
+	originalWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(originalWd)
+
+	versionArch := "4.12.0-x86_64"
+	binPath := GetSharedBinaryPath(versionArch, "openshift-install")
+	credreqsPath := GetSharedCredReqsPath(versionArch)
+	os.MkdirAll(filepath.Dir(binPath), 0755)
+	os.MkdirAll(credreqsPath, 0755)
+	os.WriteFile(binPath, []byte("installer"), 0755)
+	os.WriteFile(filepath.Join(credreqsPath, "a.yaml"), []byte("a"), 0644)
+
+	if VerifyArtifact(versionArch, "sha256:aaa", binPath) {
+		t.Error("Artifact should not verify before checksums are recorded")
+	}
+	if err := RecordArtifacts(versionArch, "quay.io/test:4.12.0-x86_64", "", binPath); err == nil {
+		t.Error("Artifacts should not be recorded without a release digest")
+	}
+
+	if err := RecordArtifacts(versionArch, "quay.io/test:4.12.0-x86_64", "sha256:aaa", binPath, credreqsPath); err != nil {
+		t.Fatalf("Failed to record artifacts: %v", err)
+	}
+
+	if !VerifyArtifact(versionArch, "sha256:aaa", binPath) {
+		t.Error("Recorded binary should verify")
+	}
+	if !VerifyArtifact(versionArch, "sha256:aaa", credreqsPath) {
+		t.Error("Recorded credreqs directory should verify")
+	}
+	if VerifyArtifact(versionArch, "", credreqsPath) {
+		t.Error("Artifact should not verify without a release digest")
+	}
+	if VerifyArtifact(versionArch, "sha256:bbb", binPath) {
+		t.Error("Artifact should not verify for a different release digest")
+	}
+
+	// A file added to the directory after recording is not trusted
+	os.WriteFile(filepath.Join(credreqsPath, "b.yaml"), []byte("b"), 0644)
+	if VerifyArtifact(versionArch, "sha256:aaa", credreqsPath) {
+		t.Error("Directory with unrecorded files should not verify")
+	}
+
+	// Re-recording the directory replaces its entries
+	os.Remove(filepath.Join(credreqsPath, "a.yaml"))
+	if err := RecordArtifacts(versionArch, "quay.io/test:4.12.0-x86_64", "sha256:aaa", credreqsPath); err != nil {
+		t.Fatalf("Failed to record artifacts: %v", err)
+	}
+	cache, _ := ReadArtifactCache(versionArch)
+	if _, ok := cache.Files[filepath.Join("credreqs", "a.yaml")]; ok {
+		t.Error("Stale entry for removed file should have been dropped")
+	}
+	if !VerifyArtifact(versionArch, "sha256:aaa", binPath) {
+		t.Error("Re-recording a directory should keep other entries")
+	}
+
+	// Truncated binaries are detected
+	os.WriteFile(binPath, []byte("inst"), 0755)
+	if VerifyArtifact(versionArch, "sha256:aaa", binPath) {
+		t.Error("Modified binary should not verify")
+	}
+}
@@ -0,0 +1,297 @@
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Files written by ccoctl
+const (
+	SigningKeyFile           = "bound-service-account-signing-key.key" // In tls/: private key signing the service account tokens
+	AuthenticationConfigFile = "cluster-authentication-02-config.yaml" // In manifests/: service account issuer of the cluster
+)
+
+var (
+	roleARNPattern = regexp.MustCompile(`role_arn\s*=\s*(arn:([^:\s]+):iam::(\d+):role/([^\s"\\]+))`)
+	issuerPattern  = regexp.MustCompile(`serviceAccountIssuer:\s*"?(https://[^\s"]+)`)
+)
+
+// CcoctlResources describes the AWS resources created by `ccoctl aws create-all`
+type CcoctlResources struct {
+	Partition       string
+	AccountID       string
+	RoleNames       []string
+	IssuerURL       string
+	OIDCProviderARN string
+	BucketName      string
+}
+
+// ReadCcoctlResources discovers the resources created by ccoctl for the given
+// name from the manifests it generated (credentials secrets and authentication config)
+func ReadCcoctlResources(manifestsDir, name string) (*CcoctlResources, error) {
+	entries, err := os.ReadDir(manifestsDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ccoctl manifests: %w", err)
+	}
+
+	resources := &CcoctlResources{
+		// ccoctl names the OIDC bucket after the resource name
+		BucketName: name + "-oidc",
+	}
+	roles := map[string]bool{}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(manifestsDir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", entry.Name(), err)
+		}
+
+		for _, match := range roleARNPattern.FindAllStringSubmatch(string(content), -1) {
+			resources.Partition = match[2]
+			resources.AccountID = match[3]
+			roleName := match[4]
+			if i := strings.LastIndex(roleName, "/"); i >= 0 {
+				roleName = roleName[i+1:] // Strip the IAM path
+			}
+			roles[roleName] = true
+		}
+		if match := issuerPattern.FindStringSubmatch(string(content)); match != nil {
+			resources.IssuerURL = match[1]
+		}
+	}
+
+	for role := range roles {
+		resources.RoleNames = append(resources.RoleNames, role)
+	}
+	sort.Strings(resources.RoleNames)
+
+	if resources.IssuerURL != "" && resources.AccountID != "" {
+		resources.OIDCProviderARN = fmt.Sprintf("arn:%s:iam::%s:oidc-provider/%s",
+			resources.Partition, resources.AccountID, strings.TrimPrefix(resources.IssuerURL, "https://"))
+	}
+
+	return resources, nil
+}
+
+// FormatAWSTags returns tags as the JSON list taken by the --tags option of the
+// aws CLI, sorted by key. Unlike the shorthand syntax (Key=k,Value=v), it
+// keeps values with commas or equal signs intact.
+func FormatAWSTags(tags map[string]string) string {
+	list := make([]awsTag, 0, len(tags))
+	for _, key := range sortedKeys(tags) {
+		list = append(list, awsTag{Key: key, Value: tags[key]})
+	}
+	formatted, _ := json.Marshal(list)
+	return string(formatted)
+}
+
+// TagCcoctlResources applies the user tags to the IAM roles, OIDC provider and
+// S3 bucket created by ccoctl, which (unlike openshift-install) has no way to set them
+func TagCcoctlResources(executor CommandExecutor, profile, region string, resources *CcoctlResources, tags map[string]string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+	awsTags := FormatAWSTags(tags)
+
+	for _, role := range resources.RoleNames {
+		if _, err := RunAWSCLI(executor, profile, "", "iam", "tag-role", "--role-name", role, "--tags", awsTags); err != nil {
+			return fmt.Errorf("failed to tag IAM role %s: %w", role, err)
+		}
+	}
+
+	if resources.OIDCProviderARN != "" {
+		if _, err := RunAWSCLI(executor, profile, "", "iam", "tag-open-id-connect-provider", "--open-id-connect-provider-arn", resources.OIDCProviderARN, "--tags", awsTags); err != nil {
+			return fmt.Errorf("failed to tag OIDC provider: %w", err)
+		}
+	}
+
+	if resources.BucketName != "" {
+		if err := tagBucket(executor, profile, region, resources.BucketName, tags); err != nil {
+			return fmt.Errorf("failed to tag S3 bucket %s: %w", resources.BucketName, err)
+		}
+	}
+
+	return nil
+}
+
+type awsTagSet struct {
+	TagSet []awsTag `json:"TagSet"`
+}
+
+type awsTag struct {
+	Key   string `json:"Key"`
+	Value string `json:"Value"`
+}
+
+// tagBucket merges the tags into the bucket tag set (put-bucket-tagging replaces the whole set)
+func tagBucket(executor CommandExecutor, profile, region, bucket string, tags map[string]string) error {
+	merged := map[string]string{}
+	if output, err := RunAWSCLI(executor, profile, region, "s3api", "get-bucket-tagging", "--bucket", bucket); err == nil {
+		var existing awsTagSet
+		if json.Unmarshal([]byte(output), &existing) == nil {
+			for _, tag := range existing.TagSet {
+				merged[tag.Key] = tag.Value
+			}
+		}
+	}
+	for key, value := range tags {
+		merged[key] = value
+	}
+
+	var tagSet awsTagSet
+	for _, key := range sortedKeys(merged) {
+		tagSet.TagSet = append(tagSet.TagSet, awsTag{Key: key, Value: merged[key]})
+	}
+	tagging, err := json.Marshal(tagSet)
+	if err != nil {
+		return err
+	}
+
+	_, err = RunAWSCLI(executor, profile, region, "s3api", "put-bucket-tagging", "--bucket", bucket, "--tagging", string(tagging))
+	return err
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// FindOIDCProviderARN returns the ARN of the IAM OIDC provider of an issuer
+func FindOIDCProviderARN(executor CommandExecutor, profile, issuerURL string) (string, error) {
+	if issuerURL == "" {
+		return "", fmt.Errorf("the OIDC issuer is unknown")
+	}
+	output, err := RunAWSCLI(executor, profile, "", "iam", "list-open-id-connect-providers")
+	if err != nil {
+		return "", err
+	}
+
+	var result struct {
+		OpenIDConnectProviderList []struct {
+			Arn string `json:"Arn"`
+		} `json:"OpenIDConnectProviderList"`
+	}
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		return "", fmt.Errorf("failed to parse list-open-id-connect-providers output: %w", err)
+	}
+	suffix := ":oidc-provider/" + strings.TrimSuffix(strings.TrimPrefix(issuerURL, "https://"), "/")
+	for _, provider := range result.OpenIDConnectProviderList {
+		if strings.HasSuffix(provider.Arn, suffix) {
+			return provider.Arn, nil
+		}
+	}
+	return "", fmt.Errorf("no IAM OIDC provider found for %s", issuerURL)
+}
+
+// FetchOIDCIssuer returns the issuer advertised by the OIDC discovery document
+// served at issuerURL, i.e. what AWS STS reads when validating the tokens
+func FetchOIDCIssuer(issuerURL string) (string, error) {
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Get(strings.TrimSuffix(issuerURL, "/") + "/.well-known/openid-configuration")
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch the OIDC discovery document: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("OIDC discovery document of %s not served: %s", issuerURL, resp.Status)
+	}
+
+	var discovery struct {
+		Issuer string `json:"issuer"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&discovery); err != nil {
+		return "", fmt.Errorf("invalid OIDC discovery document: %w", err)
+	}
+	return discovery.Issuer, nil
+}
+
+// IdentityProviderFiles are the files `ccoctl aws create-identity-provider
+// --dry-run` writes instead of creating the identity provider
+type IdentityProviderFiles struct {
+	IssuerURL     string // Issuer of the discovery document
+	DiscoveryPath string // OIDC discovery document, served as .well-known/openid-configuration
+	KeysPath      string // JSON web key set, served as keys.json
+	ProviderPath  string // Input of iam create-open-id-connect-provider
+}
+
+// ReadIdentityProviderFiles finds the files of a ccoctl identity provider dry
+// run in dir. ccoctl numbers them (e.g. 02-openid-configuration), so they are
+// matched by suffix.
+func ReadIdentityProviderFiles(dir string) (*IdentityProviderFiles, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ccoctl dry run output: %w", err)
+	}
+	files := &IdentityProviderFiles{}
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		switch {
+		case strings.HasSuffix(entry.Name(), "openid-configuration"):
+			files.DiscoveryPath = path
+		case strings.HasSuffix(entry.Name(), "keys.json"):
+			files.KeysPath = path
+		case strings.HasSuffix(entry.Name(), "iam-identity-provider.json"):
+			files.ProviderPath = path
+		}
+	}
+	if files.DiscoveryPath == "" || files.KeysPath == "" || files.ProviderPath == "" {
+		return nil, fmt.Errorf("ccoctl dry run output in %s is incomplete", dir)
+	}
+
+	data, err := os.ReadFile(files.DiscoveryPath)
+	if err != nil {
+		return nil, err
+	}
+	var discovery struct {
+		Issuer string `json:"issuer"`
+	}
+	if err := json.Unmarshal(data, &discovery); err != nil || discovery.Issuer == "" {
+		return nil, fmt.Errorf("invalid OIDC discovery document %s", files.DiscoveryPath)
+	}
+	files.IssuerURL = discovery.Issuer
+	return files, nil
+}
+
+// UploadOIDCDocuments uploads the discovery document and the key set of an
+// identity provider to its bucket, publicly readable as ccoctl uploads them
+func UploadOIDCDocuments(executor CommandExecutor, profile, region, bucket string, files *IdentityProviderFiles) error {
+	documents := []struct{ key, path string }{
+		{".well-known/openid-configuration", files.DiscoveryPath},
+		{"keys.json", files.KeysPath},
+	}
+	for _, document := range documents {
+		if _, err := RunAWSCLI(executor, profile, region, "s3api", "put-object", "--bucket", bucket, "--key", document.key, "--body", document.path, "--acl", "public-read"); err != nil {
+			return fmt.Errorf("failed to upload %s to S3 bucket %s: %w", document.key, bucket, err)
+		}
+	}
+	return nil
+}
+
+// CreateOIDCProvider creates the IAM OIDC provider of an identity provider,
+// with the thumbprint, audiences and tags computed by ccoctl, and returns its ARN
+func CreateOIDCProvider(executor CommandExecutor, profile string, files *IdentityProviderFiles) (string, error) {
+	output, err := RunAWSCLI(executor, profile, "", "iam", "create-open-id-connect-provider", "--cli-input-json", "file://"+files.ProviderPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to create the OIDC provider: %w", err)
+	}
+	var result struct {
+		OpenIDConnectProviderArn string `json:"OpenIDConnectProviderArn"`
+	}
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		return "", fmt.Errorf("failed to parse create-open-id-connect-provider output: %w", err)
+	}
+	return result.OpenIDConnectProviderArn, nil
+}
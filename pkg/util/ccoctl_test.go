@@ -0,0 +1,133 @@
+package util
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeCcoctlManifests(t *testing.T, dir string) {
+	t.Helper()
+	os.MkdirAll(dir, 0755)
+	os.WriteFile(filepath.Join(dir, "openshift-image-registry-installer-cloud-credentials-credentials.yaml"), []byte(`apiVersion: v1
+stringData:
+  credentials: |-
+    [default]
+    sts_regional_endpoints = regional
+    role_arn = arn:aws:iam::123456789012:role/my-cluster-openshift-image-registry-installer-cloud-creden
+    web_identity_token_file = /var/run/secrets/openshift/serviceaccount/token
+kind: Secret
+`), 0644)
+	os.WriteFile(filepath.Join(dir, "openshift-ingress-operator-cloud-credentials-credentials.yaml"), []byte(`apiVersion: v1
+stringData:
+  credentials: "[default]\nrole_arn = arn:aws:iam::123456789012:role/my-cluster-openshift-ingress-operator-cloud-credentials\n"
+kind: Secret
+`), 0644)
+	os.WriteFile(filepath.Join(dir, "cluster-authentication-02-config.yaml"), []byte(`apiVersion: config.openshift.io/v1
+kind: Authentication
+spec:
+  serviceAccountIssuer: https://my-cluster-oidc.s3.us-east-2.amazonaws.com
+`), 0644)
+}
+
+func TestReadCcoctlResources(t *testing.T) {
+	dir := t.TempDir()
+	writeCcoctlManifests(t, dir)
+
+	resources, err := ReadCcoctlResources(dir, "my-cluster")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(resources.RoleNames) != 2 {
+		t.Fatalf("Expected 2 roles, got %v", resources.RoleNames)
+	}
+	if resources.RoleNames[1] != "my-cluster-openshift-ingress-operator-cloud-credentials" {
+		t.Errorf("Unexpected role name %s", resources.RoleNames[1])
+	}
+	if resources.OIDCProviderARN != "arn:aws:iam::123456789012:oidc-provider/my-cluster-oidc.s3.us-east-2.amazonaws.com" {
+		t.Errorf("Unexpected OIDC provider ARN %s", resources.OIDCProviderARN)
+	}
+	if resources.BucketName != "my-cluster-oidc" {
+		t.Errorf("Unexpected bucket name %s", resources.BucketName)
+	}
+}
+
+func TestTagCcoctlResources(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	executor := NewMockExecutor()
+	executor.SetOutput("aws s3api get-bucket-tagging --bucket my-cluster-oidc --output json --region us-east-2",
+		`{"TagSet": [{"Key": "openshift.io/cloud-credential-operator/my-cluster", "Value": "owned"}]}`)
+
+	resources := &CcoctlResources{
+		RoleNames:       []string{"my-cluster-role"},
+		OIDCProviderARN: "arn:aws:iam::123456789012:oidc-provider/example",
+		BucketName:      "my-cluster-oidc",
+	}
+	tags := map[string]string{"owner": "me", "team": "qe,sre"}
+
+	if err := TagCcoctlResources(executor, "", "us-east-2", resources, tags); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Values with commas are kept whole
+	if !executor.WasExecuted(`aws iam tag-role --role-name my-cluster-role --tags [{"Key":"owner","Value":"me"},{"Key":"team","Value":"qe,sre"}] --output json`) {
+		t.Errorf("Expected IAM role to be tagged, got %v", executor.Commands)
+	}
+	if !executor.WasExecutedContaining("aws iam tag-open-id-connect-provider --open-id-connect-provider-arn arn:aws:iam::123456789012:oidc-provider/example") {
+		t.Error("Expected OIDC provider to be tagged")
+	}
+	// Existing bucket tags must be preserved
+	if !executor.WasExecutedContaining(`"Key":"openshift.io/cloud-credential-operator/my-cluster","Value":"owned"`) {
+		t.Errorf("Expected existing bucket tags to be preserved, got %v", executor.Commands)
+	}
+}
+
+func TestFetchOIDCIssuer(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/.well-known/openid-configuration" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, `{"issuer": "https://my-cluster-oidc.s3.us-east-2.amazonaws.com", "jwks_uri": "https://my-cluster-oidc.s3.us-east-2.amazonaws.com/keys.json"}`)
+	}))
+	defer server.Close()
+
+	issuer, err := FetchOIDCIssuer(server.URL + "/")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if issuer != "https://my-cluster-oidc.s3.us-east-2.amazonaws.com" {
+		t.Errorf("Unexpected issuer %q", issuer)
+	}
+
+	if _, err := FetchOIDCIssuer(server.URL + "/missing"); err == nil {
+		t.Error("Expected an error when the discovery document is not served")
+	}
+}
+
+func TestReadIdentityProviderFiles(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := ReadIdentityProviderFiles(dir); err == nil {
+		t.Error("Expected an error without the dry run output")
+	}
+
+	os.WriteFile(filepath.Join(dir, "01-oidc-bucket.json"), []byte(`{"Bucket": "my-cluster-oidc"}`), 0644)
+	os.WriteFile(filepath.Join(dir, "02-openid-configuration"), []byte(`{"issuer": "https://my-cluster-oidc.s3.us-east-2.amazonaws.com"}`), 0644)
+	os.WriteFile(filepath.Join(dir, "03-keys.json"), []byte(`{"keys": []}`), 0644)
+	os.WriteFile(filepath.Join(dir, "04-iam-identity-provider.json"), []byte(`{"Url": "https://my-cluster-oidc.s3.us-east-2.amazonaws.com"}`), 0644)
+	files, err := ReadIdentityProviderFiles(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if files.IssuerURL != "https://my-cluster-oidc.s3.us-east-2.amazonaws.com" {
+		t.Errorf("Unexpected issuer %q", files.IssuerURL)
+	}
+	if filepath.Base(files.KeysPath) != "03-keys.json" || filepath.Base(files.ProviderPath) != "04-iam-identity-provider.json" {
+		t.Errorf("Unexpected files %+v", files)
+	}
+}
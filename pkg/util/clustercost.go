@@ -0,0 +1,113 @@
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// costExplorerRegion is the region serving the Cost Explorer API
+const costExplorerRegion = "us-east-1"
+
+// costDateFormat is the date format of the Cost Explorer API
+const costDateFormat = "2006-01-02"
+
+// DailyCost is the spend of a single day
+type DailyCost struct {
+	Date      string  `json:"date"`
+	Amount    float64 `json:"amount"`
+	Estimated bool    `json:"estimated,omitempty"` // The day is not closed yet
+}
+
+// ClusterCost is the spend of the resources tagged with the infra ID of a cluster
+type ClusterCost struct {
+	InfraID  string      `json:"infraID"`
+	Start    string      `json:"start"`
+	End      string      `json:"end"` // Exclusive
+	Currency string      `json:"currency"`
+	Total    float64     `json:"total"`
+	Daily    []DailyCost `json:"daily"`
+}
+
+// GetClusterCost returns the daily unblended cost, from Cost Explorer, of the
+// resources tagged kubernetes.io/cluster/<infraID> between start and end
+// (exclusive). The tag key must be activated as a cost allocation tag.
+func GetClusterCost(executor CommandExecutor, profile, infraID string, start, end time.Time) (*ClusterCost, error) {
+	filter, err := json.Marshal(map[string]interface{}{
+		"Tags": map[string]interface{}{"Key": InfraTagKeyPrefix + infraID, "Values": []string{"owned"}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	cost := &ClusterCost{
+		InfraID:  infraID,
+		Start:    start.Format(costDateFormat),
+		End:      end.Format(costDateFormat),
+		Currency: "USD",
+	}
+	nextPageToken := ""
+	for {
+		args := []string{"ce", "get-cost-and-usage",
+			"--time-period", fmt.Sprintf("Start=%s,End=%s", cost.Start, cost.End),
+			"--granularity", "DAILY",
+			"--metrics", "UnblendedCost",
+			"--filter", string(filter)}
+		if nextPageToken != "" {
+			args = append(args, "--next-page-token", nextPageToken)
+		}
+		output, err := RunAWSCLI(executor, profile, costExplorerRegion, args...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the cost of %s: %w", infraID, err)
+		}
+
+		var result struct {
+			ResultsByTime []struct {
+				TimePeriod struct {
+					Start string `json:"Start"`
+				} `json:"TimePeriod"`
+				Total map[string]struct {
+					Amount string `json:"Amount"`
+					Unit   string `json:"Unit"`
+				} `json:"Total"`
+				Estimated bool `json:"Estimated"`
+			} `json:"ResultsByTime"`
+			NextPageToken string `json:"NextPageToken"`
+		}
+		if err := json.Unmarshal([]byte(output), &result); err != nil {
+			return nil, fmt.Errorf("failed to parse get-cost-and-usage output: %w", err)
+		}
+
+		for _, day := range result.ResultsByTime {
+			daily := DailyCost{Date: day.TimePeriod.Start, Estimated: day.Estimated}
+			if total, ok := day.Total["UnblendedCost"]; ok {
+				if daily.Amount, err = strconv.ParseFloat(total.Amount, 64); err != nil {
+					return nil, fmt.Errorf("invalid cost %q for %s", total.Amount, daily.Date)
+				}
+				if total.Unit != "" {
+					cost.Currency = total.Unit
+				}
+			}
+			cost.Total += daily.Amount
+			cost.Daily = append(cost.Daily, daily)
+		}
+
+		if result.NextPageToken == "" {
+			return cost, nil
+		}
+		nextPageToken = result.NextPageToken
+	}
+}
+
+// ActivateCostAllocationTag activates the kubernetes.io/cluster/<infraID> tag
+// as a cost allocation tag, so that Cost Explorer can filter on it. Spend is
+// only attributed to the tag from about a day after the activation.
+func ActivateCostAllocationTag(executor CommandExecutor, profile, infraID string) error {
+	_, err := RunAWSCLI(executor, profile, costExplorerRegion, "ce", "update-cost-allocation-tags-status",
+		"--cost-allocation-tags-status", fmt.Sprintf("TagKey=%s%s,Status=Active", InfraTagKeyPrefix, infraID))
+	if err != nil {
+		return fmt.Errorf("failed to activate the cost allocation tag of %s: %w", infraID, err)
+	}
+	return nil
+}
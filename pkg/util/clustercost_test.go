@@ -0,0 +1,48 @@
+package util
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestGetClusterCost(t *testing.T) {
+	executor := NewMockExecutor()
+	command := `aws ce get-cost-and-usage --time-period Start=2024-03-01,End=2024-03-04 --granularity DAILY --metrics UnblendedCost ` +
+		`--filter {"Tags":{"Key":"kubernetes.io/cluster/my-cluster-x7k2p","Values":["owned"]}}`
+	executor.SetOutput(command+" --output json --region us-east-1", `{"ResultsByTime": [
+		{"TimePeriod": {"Start": "2024-03-01", "End": "2024-03-02"}, "Total": {"UnblendedCost": {"Amount": "12.5", "Unit": "USD"}}},
+		{"TimePeriod": {"Start": "2024-03-02", "End": "2024-03-03"}, "Total": {"UnblendedCost": {"Amount": "30.25", "Unit": "USD"}}}
+	], "NextPageToken": "page2"}`)
+	executor.SetOutput(command+" --next-page-token page2 --output json --region us-east-1", `{"ResultsByTime": [
+		{"TimePeriod": {"Start": "2024-03-03", "End": "2024-03-04"}, "Total": {"UnblendedCost": {"Amount": "8", "Unit": "USD"}}, "Estimated": true}
+	]}`)
+
+	start := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)
+	cost, err := GetClusterCost(executor, "", "my-cluster-x7k2p", start, end)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(cost.Daily) != 3 {
+		t.Fatalf("Expected 3 days across both pages, got %+v", cost.Daily)
+	}
+	if math.Abs(cost.Total-50.75) > 0.001 {
+		t.Errorf("Expected a total of 50.75, got %v", cost.Total)
+	}
+	if cost.Daily[1].Date != "2024-03-02" || cost.Daily[1].Amount != 30.25 || !cost.Daily[2].Estimated {
+		t.Errorf("Unexpected daily breakdown: %+v", cost.Daily)
+	}
+}
+
+func TestActivateCostAllocationTag(t *testing.T) {
+	executor := NewMockExecutor()
+	if err := ActivateCostAllocationTag(executor, "default", "my-cluster-x7k2p"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := "aws ce update-cost-allocation-tags-status --cost-allocation-tags-status TagKey=kubernetes.io/cluster/my-cluster-x7k2p,Status=Active --output json --profile default --region us-east-1"
+	if !executor.WasExecuted(expected) {
+		t.Errorf("Expected %q to be executed, got %v", expected, executor.Commands)
+	}
+}
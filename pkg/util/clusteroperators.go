@@ -0,0 +1,106 @@
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ClusterOperatorStatus is the health of a ClusterOperator
+type ClusterOperatorStatus struct {
+	Name      string `json:"name"`
+	Available bool   `json:"available"`
+	Degraded  bool   `json:"degraded"`
+	Message   string `json:"message,omitempty"` // Message of the Degraded condition, or of Available when false
+}
+
+// Healthy reports whether the operator is Available=True and Degraded=False
+func (s ClusterOperatorStatus) Healthy() bool {
+	return s.Available && !s.Degraded
+}
+
+func (s ClusterOperatorStatus) String() string {
+	conditions := []string{}
+	if !s.Available {
+		conditions = append(conditions, "Available=False")
+	}
+	if s.Degraded {
+		conditions = append(conditions, "Degraded=True")
+	}
+	if s.Message != "" {
+		conditions = append(conditions, s.Message)
+	}
+	if len(conditions) == 0 {
+		return s.Name
+	}
+	return fmt.Sprintf("%s (%s)", s.Name, strings.Join(conditions, ": "))
+}
+
+// ParseClusterOperators parses the output of 'oc get clusteroperators -o json'
+func ParseClusterOperators(data []byte) ([]ClusterOperatorStatus, error) {
+	var list struct {
+		Items []struct {
+			Metadata struct {
+				Name string `json:"name"`
+			} `json:"metadata"`
+			Status struct {
+				Conditions []struct {
+					Type    string `json:"type"`
+					Status  string `json:"status"`
+					Message string `json:"message"`
+				} `json:"conditions"`
+			} `json:"status"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse cluster operators: %w", err)
+	}
+
+	operators := make([]ClusterOperatorStatus, 0, len(list.Items))
+	for _, item := range list.Items {
+		operator := ClusterOperatorStatus{Name: item.Metadata.Name}
+		for _, condition := range item.Status.Conditions {
+			switch {
+			case condition.Type == "Available":
+				operator.Available = condition.Status == "True"
+				if !operator.Available && operator.Message == "" {
+					operator.Message = firstLine(condition.Message)
+				}
+			case condition.Type == "Degraded" && condition.Status == "True":
+				operator.Degraded = true
+				operator.Message = firstLine(condition.Message)
+			}
+		}
+		operators = append(operators, operator)
+	}
+	sort.Slice(operators, func(i, j int) bool { return operators[i].Name < operators[j].Name })
+	return operators, nil
+}
+
+// UnhealthyClusterOperators returns the operators that are not healthy
+func UnhealthyClusterOperators(operators []ClusterOperatorStatus) []ClusterOperatorStatus {
+	var unhealthy []ClusterOperatorStatus
+	for _, operator := range operators {
+		if !operator.Healthy() {
+			unhealthy = append(unhealthy, operator)
+		}
+	}
+	return unhealthy
+}
+
+// firstLine returns the first line of a condition message
+func firstLine(message string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(message), "\n")
+	return line
+}
+
+// GetClusterOperators reads the ClusterOperators of the cluster of a kubeconfig
+func GetClusterOperators(executor CommandExecutor, kubeconfigPath string) ([]ClusterOperatorStatus, error) {
+	env := []string{fmt.Sprintf("KUBECONFIG=%s", kubeconfigPath)}
+	output, err := executor.ExecuteWithEnv("oc", env, "get", "clusteroperators", "-o", "json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cluster operators: %w", err)
+	}
+	return ParseClusterOperators([]byte(output))
+}
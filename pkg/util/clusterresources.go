@@ -0,0 +1,514 @@
+package util
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// CcoctlTagKeyPrefix is the prefix of the tag ccoctl sets on the IAM roles, OIDC
+// provider and S3 bucket it creates: openshift.io/cloud-credential-operator/<name>=owned
+const CcoctlTagKeyPrefix = "openshift.io/cloud-credential-operator/"
+
+// InfraTagKeyPrefix is the prefix of the tag openshift-install sets on the
+// infrastructure it creates: kubernetes.io/cluster/<infraID>=owned
+const InfraTagKeyPrefix = "kubernetes.io/cluster/"
+
+// ClusterResources lists the AWS resources that belong to a cluster
+type ClusterResources struct {
+	IAMRoles       []string `json:"iamRoles,omitempty"`
+	OIDCProviders  []string `json:"oidcProviders,omitempty"`
+	S3Buckets      []string `json:"s3Buckets,omitempty"`
+	DNSRecords     []string `json:"dnsRecords,omitempty"`
+	Infrastructure []string `json:"infrastructure,omitempty"` // ARNs of the resources tagged with the infra ID
+}
+
+// Count returns the number of resources found
+func (r *ClusterResources) Count() int {
+	return len(r.IAMRoles) + len(r.OIDCProviders) + len(r.S3Buckets) + len(r.DNSRecords) + len(r.Infrastructure)
+}
+
+// ClusterResourceQuery identifies the cluster whose resources are looked up.
+// Resources that cannot be identified without the optional fields are not
+// looked up: DNS records need the base domain, infrastructure the infra ID.
+type ClusterResourceQuery struct {
+	Profile        string
+	Region         string
+	ClusterName    string
+	ResourcePrefix string // Name of the ccoctl resources, ClusterName if empty
+	InfraID        string
+	BaseDomain     string
+}
+
+// ccoctlName returns the name of the resources ccoctl created for the cluster
+func (q ClusterResourceQuery) ccoctlName() string {
+	if q.ResourcePrefix != "" {
+		return q.ResourcePrefix
+	}
+	return q.ClusterName
+}
+
+// FindClusterResources lists the AWS resources of a cluster, as `ccoctl aws
+// delete` and `openshift-install destroy cluster` would find them
+func FindClusterResources(executor CommandExecutor, query ClusterResourceQuery) (*ClusterResources, error) {
+	resources := &ClusterResources{}
+	var err error
+
+	if resources.IAMRoles, err = findCcoctlRoles(executor, query); err != nil {
+		return nil, err
+	}
+	if resources.OIDCProviders, err = findCcoctlOIDCProviders(executor, query); err != nil {
+		return nil, err
+	}
+	if resources.S3Buckets, err = findCcoctlBuckets(executor, query); err != nil {
+		return nil, err
+	}
+	if query.BaseDomain != "" {
+		if resources.DNSRecords, err = findDNSRecords(executor, query); err != nil {
+			return nil, err
+		}
+	}
+	if query.InfraID != "" {
+		if resources.Infrastructure, err = FindTaggedResources(executor, query.Profile, query.Region, InfraTagKeyPrefix+query.InfraID); err != nil {
+			return nil, err
+		}
+		if resources.Infrastructure, err = withoutTerminatedInstances(executor, query, resources.Infrastructure); err != nil {
+			return nil, err
+		}
+	}
+	return resources, nil
+}
+
+// findCcoctlRoles returns the IAM roles named after the cluster and tagged as owned by it.
+// The tag tells the roles apart from those of another cluster sharing the name prefix.
+func findCcoctlRoles(executor CommandExecutor, query ClusterResourceQuery) ([]string, error) {
+	output, err := RunAWSCLI(executor, query.Profile, "", "iam", "list-roles")
+	if err != nil {
+		return nil, err
+	}
+	var result struct {
+		Roles []struct {
+			RoleName string `json:"RoleName"`
+		} `json:"Roles"`
+	}
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		return nil, fmt.Errorf("failed to parse list-roles output: %w", err)
+	}
+
+	var roles []string
+	for _, role := range result.Roles {
+		if !strings.HasPrefix(role.RoleName, query.ccoctlName()+"-") {
+			continue
+		}
+		output, err := RunAWSCLI(executor, query.Profile, "", "iam", "list-role-tags", "--role-name", role.RoleName)
+		if err != nil {
+			return nil, err
+		}
+		var tags struct {
+			Tags []awsTag `json:"Tags"`
+		}
+		if err := json.Unmarshal([]byte(output), &tags); err != nil {
+			return nil, fmt.Errorf("failed to parse list-role-tags output: %w", err)
+		}
+		if hasCcoctlTag(tags.Tags, query.ccoctlName()) {
+			roles = append(roles, role.RoleName)
+		}
+	}
+	return roles, nil
+}
+
+// findCcoctlOIDCProviders returns the OIDC providers of the cluster: those
+// served from its bucket, or from CloudFront (private bucket) and tagged as owned by it
+func findCcoctlOIDCProviders(executor CommandExecutor, query ClusterResourceQuery) ([]string, error) {
+	output, err := RunAWSCLI(executor, query.Profile, "", "iam", "list-open-id-connect-providers")
+	if err != nil {
+		return nil, err
+	}
+	var result struct {
+		OpenIDConnectProviderList []struct {
+			Arn string `json:"Arn"`
+		} `json:"OpenIDConnectProviderList"`
+	}
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		return nil, fmt.Errorf("failed to parse list-open-id-connect-providers output: %w", err)
+	}
+
+	var providers []string
+	for _, provider := range result.OpenIDConnectProviderList {
+		if strings.Contains(provider.Arn, ":oidc-provider/"+query.ccoctlName()+"-oidc.") {
+			providers = append(providers, provider.Arn)
+			continue
+		}
+		if !strings.Contains(provider.Arn, ".cloudfront.net") {
+			continue
+		}
+		output, err := RunAWSCLI(executor, query.Profile, "", "iam", "get-open-id-connect-provider", "--open-id-connect-provider-arn", provider.Arn)
+		if err != nil {
+			return nil, err
+		}
+		var details struct {
+			Tags []awsTag `json:"Tags"`
+		}
+		if err := json.Unmarshal([]byte(output), &details); err != nil {
+			return nil, fmt.Errorf("failed to parse get-open-id-connect-provider output: %w", err)
+		}
+		if hasCcoctlTag(details.Tags, query.ccoctlName()) {
+			providers = append(providers, provider.Arn)
+		}
+	}
+	return providers, nil
+}
+
+// findCcoctlBuckets returns the OIDC bucket of the cluster, if it exists and
+// is tagged as owned by it
+func findCcoctlBuckets(executor CommandExecutor, query ClusterResourceQuery) ([]string, error) {
+	bucket := query.ccoctlName() + "-oidc"
+	owned, err := ownsBucket(executor, query, bucket)
+	if err != nil || !owned {
+		return nil, err
+	}
+	return []string{bucket}, nil
+}
+
+// ownsBucket reports whether the bucket exists and carries the ccoctl tag of
+// the cluster. Bucket names are global: a bucket of that name the profile
+// can't access (403) belongs to someone else.
+func ownsBucket(executor CommandExecutor, query ClusterResourceQuery, bucket string) (bool, error) {
+	if _, err := RunAWSCLI(executor, query.Profile, query.Region, "s3api", "head-bucket", "--bucket", bucket); err != nil {
+		for _, notOurs := range []string{"404", "Not Found", "403", "Forbidden"} {
+			if strings.Contains(err.Error(), notOurs) {
+				return false, nil
+			}
+		}
+		return false, err
+	}
+
+	output, err := RunAWSCLI(executor, query.Profile, query.Region, "s3api", "get-bucket-tagging", "--bucket", bucket)
+	if err != nil {
+		if strings.Contains(err.Error(), "NoSuchTagSet") {
+			return false, nil
+		}
+		return false, err
+	}
+	var tagging awsTagSet
+	if err := json.Unmarshal([]byte(output), &tagging); err != nil {
+		return false, fmt.Errorf("failed to parse get-bucket-tagging output: %w", err)
+	}
+	return hasCcoctlTag(tagging.TagSet, query.ccoctlName()), nil
+}
+
+// clusterRecordSet is a Route53 record set of the cluster domain
+type clusterRecordSet struct {
+	ZoneID string
+	Name   string
+	Type   string
+	Raw    json.RawMessage // As listed, which is what deleting it takes
+}
+
+// findDNSRecords returns the records of the cluster domain (<cluster>.<base
+// domain>) in the hosted zones of the base domain and of the cluster domain
+func findDNSRecords(executor CommandExecutor, query ClusterResourceQuery) ([]string, error) {
+	recordSets, err := listClusterRecordSets(executor, query)
+	if err != nil {
+		return nil, err
+	}
+	var records []string
+	for _, record := range recordSets {
+		records = append(records, fmt.Sprintf("%s %s", record.Name, record.Type))
+	}
+	return records, nil
+}
+
+func listClusterRecordSets(executor CommandExecutor, query ClusterResourceQuery) ([]clusterRecordSet, error) {
+	clusterDomain := query.ClusterName + "." + query.BaseDomain
+	output, err := RunAWSCLI(executor, query.Profile, "", "route53", "list-hosted-zones-by-name", "--dns-name", query.BaseDomain)
+	if err != nil {
+		return nil, err
+	}
+	var zones struct {
+		HostedZones []struct {
+			ID   string `json:"Id"`
+			Name string `json:"Name"`
+		} `json:"HostedZones"`
+	}
+	if err := json.Unmarshal([]byte(output), &zones); err != nil {
+		return nil, fmt.Errorf("failed to parse list-hosted-zones-by-name output: %w", err)
+	}
+
+	var records []clusterRecordSet
+	for _, zone := range zones.HostedZones {
+		zoneName := route53Name(zone.Name)
+		if zoneName != route53Name(query.BaseDomain) && zoneName != route53Name(clusterDomain) {
+			continue
+		}
+		output, err := RunAWSCLI(executor, query.Profile, "", "route53", "list-resource-record-sets", "--hosted-zone-id", zone.ID)
+		if err != nil {
+			return nil, err
+		}
+		var recordSets struct {
+			ResourceRecordSets []json.RawMessage `json:"ResourceRecordSets"`
+		}
+		if err := json.Unmarshal([]byte(output), &recordSets); err != nil {
+			return nil, fmt.Errorf("failed to parse list-resource-record-sets output: %w", err)
+		}
+		for _, raw := range recordSets.ResourceRecordSets {
+			var record struct {
+				Name string `json:"Name"`
+				Type string `json:"Type"`
+			}
+			if err := json.Unmarshal(raw, &record); err != nil {
+				return nil, fmt.Errorf("failed to parse list-resource-record-sets output: %w", err)
+			}
+			// The zone of the cluster domain itself goes with its NS and SOA records
+			name := route53Name(record.Name)
+			if record.Type == "NS" || record.Type == "SOA" || !strings.HasSuffix(name, "."+route53Name(clusterDomain)) {
+				continue
+			}
+			records = append(records, clusterRecordSet{ZoneID: zone.ID, Name: name, Type: record.Type, Raw: raw})
+		}
+	}
+	return records, nil
+}
+
+// FindTaggedResources returns the ARNs of the resources of the region carrying the tag key
+func FindTaggedResources(executor CommandExecutor, profile, region, tagKey string) ([]string, error) {
+	output, err := RunAWSCLI(executor, profile, region, "resourcegroupstaggingapi", "get-resources",
+		"--tag-filters", "Key="+tagKey)
+	if err != nil {
+		return nil, err
+	}
+	var result struct {
+		ResourceTagMappingList []struct {
+			ResourceARN string `json:"ResourceARN"`
+		} `json:"ResourceTagMappingList"`
+	}
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		return nil, fmt.Errorf("failed to parse get-resources output: %w", err)
+	}
+
+	var arns []string
+	for _, mapping := range result.ResourceTagMappingList {
+		arns = append(arns, mapping.ResourceARN)
+	}
+	return arns, nil
+}
+
+// withoutTerminatedInstances drops the instances that are terminated: they
+// keep their tags, and are listed, for up to an hour after being deleted
+func withoutTerminatedInstances(executor CommandExecutor, query ClusterResourceQuery, arns []string) ([]string, error) {
+	var instanceIDs []string
+	for _, arn := range arns {
+		if _, id, found := strings.Cut(arn, ":instance/"); found {
+			instanceIDs = append(instanceIDs, id)
+		}
+	}
+	if len(instanceIDs) == 0 {
+		return arns, nil
+	}
+
+	args := append([]string{"ec2", "describe-instances", "--instance-ids"}, instanceIDs...)
+	output, err := RunAWSCLI(executor, query.Profile, query.Region, args...)
+	if err != nil {
+		return nil, err
+	}
+	var result struct {
+		Reservations []struct {
+			Instances []struct {
+				InstanceID string `json:"InstanceId"`
+				State      struct {
+					Name string `json:"Name"`
+				} `json:"State"`
+			} `json:"Instances"`
+		} `json:"Reservations"`
+	}
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		return nil, fmt.Errorf("failed to parse describe-instances output: %w", err)
+	}
+	terminated := map[string]bool{}
+	for _, reservation := range result.Reservations {
+		for _, instance := range reservation.Instances {
+			if instance.State.Name == "terminated" {
+				terminated[instance.InstanceID] = true
+			}
+		}
+	}
+
+	var remaining []string
+	for _, arn := range arns {
+		if _, id, found := strings.Cut(arn, ":instance/"); !found || !terminated[id] {
+			remaining = append(remaining, arn)
+		}
+	}
+	return remaining, nil
+}
+
+// DeleteHints returns the commands deleting each of the resources by hand.
+// Infrastructure has no single command: deleting it takes openshift-install
+// or the AWS console, in dependency order.
+func DeleteHints(resources *ClusterResources) []string {
+	var hints []string
+	for _, role := range resources.IAMRoles {
+		hints = append(hints, fmt.Sprintf("aws iam delete-role --role-name %s (after deleting its policies: aws iam list-role-policies --role-name %s)", role, role))
+	}
+	for _, provider := range resources.OIDCProviders {
+		hints = append(hints, "aws iam delete-open-id-connect-provider --open-id-connect-provider-arn "+provider)
+	}
+	for _, bucket := range resources.S3Buckets {
+		hints = append(hints, fmt.Sprintf("aws s3 rb s3://%s --force", bucket))
+	}
+	for _, record := range resources.DNSRecords {
+		hints = append(hints, fmt.Sprintf("aws route53 change-resource-record-sets (DELETE %s)", record))
+	}
+	return hints
+}
+
+// PurgeClusterResources deletes the IAM roles, OIDC providers, S3 buckets and
+// DNS records of the cluster. Infrastructure is not deleted, see DeleteHints.
+// S3 buckets are only emptied and deleted when tagged as owned by the cluster.
+// All resources are attempted, the errors are returned together.
+func PurgeClusterResources(executor CommandExecutor, query ClusterResourceQuery, resources *ClusterResources) error {
+	var errs []error
+	for _, role := range resources.IAMRoles {
+		if err := deleteRole(executor, query.Profile, role); err != nil {
+			errs = append(errs, fmt.Errorf("failed to delete IAM role %s: %w", role, err))
+		}
+	}
+	for _, provider := range resources.OIDCProviders {
+		if _, err := RunAWSCLI(executor, query.Profile, "", "iam", "delete-open-id-connect-provider", "--open-id-connect-provider-arn", provider); err != nil {
+			errs = append(errs, fmt.Errorf("failed to delete OIDC provider %s: %w", provider, err))
+		}
+	}
+	for _, bucket := range resources.S3Buckets {
+		if owned, err := ownsBucket(executor, query, bucket); err != nil {
+			errs = append(errs, fmt.Errorf("failed to check the owner of S3 bucket %s: %w", bucket, err))
+			continue
+		} else if !owned {
+			errs = append(errs, fmt.Errorf("S3 bucket %s is not tagged as owned by %s, not deleting it", bucket, query.ccoctlName()))
+			continue
+		}
+		if _, err := RunAWSCLI(executor, query.Profile, query.Region, "s3", "rb", "s3://"+bucket, "--force"); err != nil {
+			errs = append(errs, fmt.Errorf("failed to delete S3 bucket %s: %w", bucket, err))
+		}
+	}
+	if len(resources.DNSRecords) > 0 {
+		// The records are listed again, deleting them takes their whole definition
+		recordSets, err := listClusterRecordSets(executor, query)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		for _, record := range recordSets {
+			if err := deleteRecordSet(executor, query.Profile, record); err != nil {
+				errs = append(errs, fmt.Errorf("failed to delete DNS record %s %s: %w", record.Name, record.Type, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// deleteRole deletes an IAM role, after its inline and attached policies
+func deleteRole(executor CommandExecutor, profile, role string) error {
+	output, err := RunAWSCLI(executor, profile, "", "iam", "list-role-policies", "--role-name", role)
+	if err != nil {
+		return err
+	}
+	var inline struct {
+		PolicyNames []string `json:"PolicyNames"`
+	}
+	if err := json.Unmarshal([]byte(output), &inline); err != nil {
+		return fmt.Errorf("failed to parse list-role-policies output: %w", err)
+	}
+	for _, policy := range inline.PolicyNames {
+		if _, err := RunAWSCLI(executor, profile, "", "iam", "delete-role-policy", "--role-name", role, "--policy-name", policy); err != nil {
+			return err
+		}
+	}
+
+	output, err = RunAWSCLI(executor, profile, "", "iam", "list-attached-role-policies", "--role-name", role)
+	if err != nil {
+		return err
+	}
+	var attached struct {
+		AttachedPolicies []struct {
+			PolicyArn string `json:"PolicyArn"`
+		} `json:"AttachedPolicies"`
+	}
+	if err := json.Unmarshal([]byte(output), &attached); err != nil {
+		return fmt.Errorf("failed to parse list-attached-role-policies output: %w", err)
+	}
+	for _, policy := range attached.AttachedPolicies {
+		if _, err := RunAWSCLI(executor, profile, "", "iam", "detach-role-policy", "--role-name", role, "--policy-arn", policy.PolicyArn); err != nil {
+			return err
+		}
+	}
+
+	_, err = RunAWSCLI(executor, profile, "", "iam", "delete-role", "--role-name", role)
+	return err
+}
+
+func deleteRecordSet(executor CommandExecutor, profile string, record clusterRecordSet) error {
+	changeBatch, err := json.Marshal(map[string]interface{}{
+		"Changes": []map[string]interface{}{{"Action": "DELETE", "ResourceRecordSet": record.Raw}},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = RunAWSCLI(executor, profile, "", "route53", "change-resource-record-sets",
+		"--hosted-zone-id", record.ZoneID, "--change-batch", string(changeBatch))
+	return err
+}
+
+// DiscoverInfraIDs returns the infra IDs of the clusters named clusterName
+// whose resources are tagged in the region. openshift-install derives the
+// infra ID from the cluster name (sanitized and truncated to 21 characters)
+// and a random suffix of 5 characters.
+func DiscoverInfraIDs(executor CommandExecutor, profile, region, clusterName string) ([]string, error) {
+	output, err := RunAWSCLI(executor, profile, region, "resourcegroupstaggingapi", "get-tag-keys")
+	if err != nil {
+		return nil, err
+	}
+	var result struct {
+		TagKeys []string `json:"TagKeys"`
+	}
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		return nil, fmt.Errorf("failed to parse get-tag-keys output: %w", err)
+	}
+
+	pattern := regexp.MustCompile("^" + regexp.QuoteMeta(InfraTagKeyPrefix+infraIDBase(clusterName)) + "-[a-z0-9]{5}$")
+	var infraIDs []string
+	for _, key := range result.TagKeys {
+		if pattern.MatchString(key) {
+			infraIDs = append(infraIDs, strings.TrimPrefix(key, InfraTagKeyPrefix))
+		}
+	}
+	sort.Strings(infraIDs)
+	return infraIDs, nil
+}
+
+// infraIDBase returns the part of the infra ID that openshift-install derives from the cluster name
+func infraIDBase(clusterName string) string {
+	base := regexp.MustCompile("[^A-Za-z0-9-]").ReplaceAllString(clusterName, "-")
+	base = regexp.MustCompile("-{2,}").ReplaceAllString(base, "-")
+	if len(base) > 21 {
+		base = base[:21]
+	}
+	return strings.TrimRight(base, "-")
+}
+
+func hasCcoctlTag(tags []awsTag, name string) bool {
+	for _, tag := range tags {
+		if tag.Key == CcoctlTagKeyPrefix+name {
+			return true
+		}
+	}
+	return false
+}
+
+// route53Name normalizes a Route53 name for comparison: lowercase, no trailing
+// dot and the octal escape of the wildcard label decoded
+func route53Name(name string) string {
+	name = strings.ReplaceAll(name, `\052`, "*")
+	return strings.ToLower(strings.TrimSuffix(name, "."))
+}
@@ -0,0 +1,215 @@
+package util
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFindClusterResources(t *testing.T) {
+	executor := NewMockExecutor()
+	executor.SetOutput("aws iam list-roles --output json", `{"Roles": [
+		{"RoleName": "my-cluster-openshift-image-registry-installer-cloud-credentials"},
+		{"RoleName": "my-cluster-2-openshift-ingress-operator-cloud-credentials"},
+		{"RoleName": "other-role"}
+	]}`)
+	executor.SetOutput("aws iam list-role-tags --role-name my-cluster-openshift-image-registry-installer-cloud-credentials --output json",
+		`{"Tags": [{"Key": "openshift.io/cloud-credential-operator/my-cluster", "Value": "owned"}]}`)
+	executor.SetOutput("aws iam list-role-tags --role-name my-cluster-2-openshift-ingress-operator-cloud-credentials --output json",
+		`{"Tags": [{"Key": "openshift.io/cloud-credential-operator/my-cluster-2", "Value": "owned"}]}`)
+	executor.SetOutput("aws iam list-open-id-connect-providers --output json", `{"OpenIDConnectProviderList": [
+		{"Arn": "arn:aws:iam::123456789012:oidc-provider/my-cluster-oidc.s3.us-east-2.amazonaws.com"},
+		{"Arn": "arn:aws:iam::123456789012:oidc-provider/my-cluster-2-oidc.s3.us-east-2.amazonaws.com"},
+		{"Arn": "arn:aws:iam::123456789012:oidc-provider/d111111abcdef8.cloudfront.net"}
+	]}`)
+	executor.SetOutput("aws iam get-open-id-connect-provider --open-id-connect-provider-arn arn:aws:iam::123456789012:oidc-provider/d111111abcdef8.cloudfront.net --output json",
+		`{"Tags": [{"Key": "openshift.io/cloud-credential-operator/other", "Value": "owned"}]}`)
+	executor.SetOutput("aws s3api get-bucket-tagging --bucket my-cluster-oidc --output json --region us-east-2",
+		`{"TagSet": [{"Key": "openshift.io/cloud-credential-operator/my-cluster", "Value": "owned"}]}`)
+	executor.SetOutput("aws route53 list-hosted-zones-by-name --dns-name example.com --output json", `{"HostedZones": [
+		{"Id": "/hostedzone/Z1", "Name": "example.com."},
+		{"Id": "/hostedzone/Z2", "Name": "my-cluster.example.com."},
+		{"Id": "/hostedzone/Z3", "Name": "example.org."}
+	]}`)
+	executor.SetOutput("aws route53 list-resource-record-sets --hosted-zone-id /hostedzone/Z1 --output json", `{"ResourceRecordSets": [
+		{"Name": "example.com.", "Type": "NS"},
+		{"Name": "api.my-cluster.example.com.", "Type": "A"},
+		{"Name": "\\052.apps.my-cluster.example.com.", "Type": "A"},
+		{"Name": "api.my-cluster-2.example.com.", "Type": "A"}
+	]}`)
+	executor.SetOutput("aws route53 list-resource-record-sets --hosted-zone-id /hostedzone/Z2 --output json", `{"ResourceRecordSets": [
+		{"Name": "my-cluster.example.com.", "Type": "NS"},
+		{"Name": "my-cluster.example.com.", "Type": "SOA"},
+		{"Name": "api-int.my-cluster.example.com.", "Type": "A"}
+	]}`)
+	executor.SetOutput("aws resourcegroupstaggingapi get-resources --tag-filters Key=kubernetes.io/cluster/my-cluster-x7k2p --output json --region us-east-2", `{"ResourceTagMappingList": [
+		{"ResourceARN": "arn:aws:ec2:us-east-2:123456789012:vpc/vpc-0a1b2c"},
+		{"ResourceARN": "arn:aws:ec2:us-east-2:123456789012:instance/i-0a1b2c"},
+		{"ResourceARN": "arn:aws:ec2:us-east-2:123456789012:instance/i-3d4e5f"}
+	]}`)
+	executor.SetOutput("aws ec2 describe-instances --instance-ids i-0a1b2c i-3d4e5f --output json --region us-east-2", `{"Reservations": [{"Instances": [
+		{"InstanceId": "i-0a1b2c", "State": {"Name": "running"}},
+		{"InstanceId": "i-3d4e5f", "State": {"Name": "terminated"}}
+	]}]}`)
+
+	resources, err := FindClusterResources(executor, ClusterResourceQuery{
+		Region:      "us-east-2",
+		ClusterName: "my-cluster",
+		InfraID:     "my-cluster-x7k2p",
+		BaseDomain:  "example.com",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := &ClusterResources{
+		IAMRoles:      []string{"my-cluster-openshift-image-registry-installer-cloud-credentials"},
+		OIDCProviders: []string{"arn:aws:iam::123456789012:oidc-provider/my-cluster-oidc.s3.us-east-2.amazonaws.com"},
+		S3Buckets:     []string{"my-cluster-oidc"},
+		DNSRecords: []string{
+			"api.my-cluster.example.com A",
+			"*.apps.my-cluster.example.com A",
+			"api-int.my-cluster.example.com A",
+		},
+		Infrastructure: []string{
+			"arn:aws:ec2:us-east-2:123456789012:vpc/vpc-0a1b2c",
+			"arn:aws:ec2:us-east-2:123456789012:instance/i-0a1b2c",
+		},
+	}
+	if !reflect.DeepEqual(resources, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, resources)
+	}
+	if resources.Count() != 8 {
+		t.Errorf("Expected 8 resources, got %d", resources.Count())
+	}
+}
+
+func TestFindClusterResourcesSkipsUnknown(t *testing.T) {
+	executor := NewMockExecutor()
+	executor.SetOutput("aws iam list-roles --output json", `{"Roles": []}`)
+	executor.SetOutput("aws iam list-open-id-connect-providers --output json", `{"OpenIDConnectProviderList": []}`)
+	executor.SetError("aws s3api head-bucket --bucket my-cluster-oidc --output json --region us-east-2",
+		fmt.Errorf("exit status 254: An error occurred (404) when calling the HeadBucket operation: Not Found"))
+
+	resources, err := FindClusterResources(executor, ClusterResourceQuery{Region: "us-east-2", ClusterName: "my-cluster"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if resources.Count() != 0 {
+		t.Errorf("Expected no resources, got %+v", resources)
+	}
+	if executor.WasExecutedContaining("route53") || executor.WasExecutedContaining("resourcegroupstaggingapi") {
+		t.Errorf("Expected DNS records and infrastructure not to be looked up without base domain and infra ID, got %v", executor.Commands)
+	}
+
+	// Bucket names are global: a bucket of another account, or without the
+	// ccoctl tag of the cluster, is not the cluster's
+	for _, setup := range []func(){
+		func() {
+			executor.SetError("aws s3api head-bucket --bucket my-cluster-oidc --output json --region us-east-2",
+				fmt.Errorf("exit status 254: An error occurred (403) when calling the HeadBucket operation: Forbidden"))
+		},
+		func() {
+			delete(executor.Errors, "aws s3api head-bucket --bucket my-cluster-oidc --output json --region us-east-2")
+			executor.SetError("aws s3api get-bucket-tagging --bucket my-cluster-oidc --output json --region us-east-2",
+				fmt.Errorf("exit status 254: An error occurred (NoSuchTagSet) when calling the GetBucketTagging operation: The TagSet does not exist"))
+		},
+		func() {
+			delete(executor.Errors, "aws s3api get-bucket-tagging --bucket my-cluster-oidc --output json --region us-east-2")
+			executor.SetOutput("aws s3api get-bucket-tagging --bucket my-cluster-oidc --output json --region us-east-2",
+				`{"TagSet": [{"Key": "openshift.io/cloud-credential-operator/other", "Value": "owned"}]}`)
+		},
+	} {
+		setup()
+		resources, err := FindClusterResources(executor, ClusterResourceQuery{Region: "us-east-2", ClusterName: "my-cluster"})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(resources.S3Buckets) != 0 {
+			t.Errorf("Expected the bucket not to be the cluster's, got %v", resources.S3Buckets)
+		}
+	}
+
+	// Other errors are not taken for a bucket of someone else
+	executor.SetError("aws s3api head-bucket --bucket my-cluster-oidc --output json --region us-east-2",
+		fmt.Errorf("exit status 255: Could not connect to the endpoint URL"))
+	if _, err := FindClusterResources(executor, ClusterResourceQuery{Region: "us-east-2", ClusterName: "my-cluster"}); err == nil {
+		t.Error("Expected an error when the bucket cannot be checked")
+	}
+}
+
+func TestPurgeClusterResources(t *testing.T) {
+	executor := NewMockExecutor()
+	executor.SetOutput("aws iam list-role-policies --role-name my-cluster-role --output json", `{"PolicyNames": ["my-cluster-role-policy"]}`)
+	executor.SetOutput("aws iam list-attached-role-policies --role-name my-cluster-role --output json",
+		`{"AttachedPolicies": [{"PolicyArn": "arn:aws:iam::aws:policy/ReadOnlyAccess"}]}`)
+	executor.SetOutput("aws route53 list-hosted-zones-by-name --dns-name example.com --output json", `{"HostedZones": [{"Id": "/hostedzone/Z1", "Name": "example.com."}]}`)
+	executor.SetOutput("aws route53 list-resource-record-sets --hosted-zone-id /hostedzone/Z1 --output json", `{"ResourceRecordSets": [
+		{"Name": "api.my-cluster.example.com.", "Type": "A", "AliasTarget": {"HostedZoneId": "Z3AADJGX6KTTL2", "DNSName": "lb.example.com.", "EvaluateTargetHealth": false}}
+	]}`)
+	executor.SetError("aws iam delete-open-id-connect-provider --open-id-connect-provider-arn arn:aws:iam::123456789012:oidc-provider/my-cluster-oidc --output json",
+		fmt.Errorf("AccessDenied"))
+	executor.SetOutput("aws s3api get-bucket-tagging --bucket my-cluster-oidc --output json --region us-east-2",
+		`{"TagSet": [{"Key": "openshift.io/cloud-credential-operator/my-cluster", "Value": "owned"}]}`)
+	executor.SetOutput("aws s3api get-bucket-tagging --bucket other-oidc --output json --region us-east-2", `{"TagSet": []}`)
+
+	query := ClusterResourceQuery{Region: "us-east-2", ClusterName: "my-cluster", BaseDomain: "example.com"}
+	resources := &ClusterResources{
+		IAMRoles:      []string{"my-cluster-role"},
+		OIDCProviders: []string{"arn:aws:iam::123456789012:oidc-provider/my-cluster-oidc"},
+		S3Buckets:     []string{"my-cluster-oidc", "other-oidc"},
+		DNSRecords:    []string{"api.my-cluster.example.com A"},
+	}
+	err := PurgeClusterResources(executor, query, resources)
+	if err == nil || !strings.Contains(err.Error(), "failed to delete OIDC provider") {
+		t.Errorf("Expected the OIDC provider deletion to fail, got %v", err)
+	}
+	if err == nil || !strings.Contains(err.Error(), "other-oidc is not tagged as owned by my-cluster") {
+		t.Errorf("Expected the untagged bucket to be refused, got %v", err)
+	}
+	if executor.WasExecutedContaining("s3 rb s3://other-oidc") {
+		t.Errorf("A bucket not owned by the cluster should not be deleted, got %v", executor.Commands)
+	}
+
+	for _, expected := range []string{
+		"aws iam delete-role-policy --role-name my-cluster-role --policy-name my-cluster-role-policy --output json",
+		"aws iam detach-role-policy --role-name my-cluster-role --policy-arn arn:aws:iam::aws:policy/ReadOnlyAccess --output json",
+		"aws iam delete-role --role-name my-cluster-role --output json",
+		"aws s3 rb s3://my-cluster-oidc --force --output json --region us-east-2",
+		`aws route53 change-resource-record-sets --hosted-zone-id /hostedzone/Z1 --change-batch {"Changes":[{"Action":"DELETE","ResourceRecordSet":{"Name":"api.my-cluster.example.com.","Type":"A","AliasTarget":{"HostedZoneId":"Z3AADJGX6KTTL2","DNSName":"lb.example.com.","EvaluateTargetHealth":false}}}]} --output json`,
+	} {
+		if !executor.WasExecuted(expected) {
+			t.Errorf("Expected %q to be executed, got %v", expected, executor.Commands)
+		}
+	}
+
+	if hints := DeleteHints(resources); len(hints) != 5 || !strings.HasPrefix(hints[2], "aws s3 rb s3://my-cluster-oidc") {
+		t.Errorf("Expected a hint per resource, got %v", hints)
+	}
+}
+
+func TestDiscoverInfraIDs(t *testing.T) {
+	executor := NewMockExecutor()
+	executor.SetOutput("aws resourcegroupstaggingapi get-tag-keys --output json --region us-east-2", `{"TagKeys": [
+		"Name",
+		"kubernetes.io/cluster/my-cluster-x7k2p",
+		"kubernetes.io/cluster/my-cluster-2-b4c5d",
+		"kubernetes.io/cluster/a-very-long-cluster-n-q8r9s",
+		"openshift.io/cloud-credential-operator/my-cluster"
+	]}`)
+
+	infraIDs, err := DiscoverInfraIDs(executor, "", "us-east-2", "my-cluster")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(infraIDs, []string{"my-cluster-x7k2p"}) {
+		t.Errorf("Expected [my-cluster-x7k2p], got %v", infraIDs)
+	}
+
+	// Long names are truncated in the infra ID
+	infraIDs, _ = DiscoverInfraIDs(executor, "", "us-east-2", "a-very-long-cluster-name")
+	if !reflect.DeepEqual(infraIDs, []string{"a-very-long-cluster-n-q8r9s"}) {
+		t.Errorf("Expected [a-very-long-cluster-n-q8r9s], got %v", infraIDs)
+	}
+}
@@ -0,0 +1,66 @@
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// ClusterVersionStatus is the upgrade status of a cluster, read from its ClusterVersion
+type ClusterVersionStatus struct {
+	Desired     string // Version the cluster is at or moving to
+	Completed   bool   // Whether the desired version is fully rolled out
+	Progress    string // Message of the Progressing condition
+	Failing     string // Message of the Failing condition, if true
+	Upgradeable string // Message of the Upgradeable condition, if false
+}
+
+type clusterVersion struct {
+	Status struct {
+		Desired struct {
+			Version string `json:"version"`
+		} `json:"desired"`
+		History []struct {
+			State   string `json:"state"`
+			Version string `json:"version"`
+		} `json:"history"`
+		Conditions []struct {
+			Type    string `json:"type"`
+			Status  string `json:"status"`
+			Message string `json:"message"`
+		} `json:"conditions"`
+	} `json:"status"`
+}
+
+// GetClusterVersion reads the ClusterVersion of the cluster of a kubeconfig
+func GetClusterVersion(executor CommandExecutor, kubeconfigPath string) (*ClusterVersionStatus, error) {
+	env := []string{fmt.Sprintf("KUBECONFIG=%s", kubeconfigPath)}
+	output, err := executor.ExecuteWithEnv("oc", env, "get", "clusterversion", "version", "-o", "json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cluster version: %w", err)
+	}
+	return parseClusterVersion([]byte(output))
+}
+
+func parseClusterVersion(data []byte) (*ClusterVersionStatus, error) {
+	var cv clusterVersion
+	if err := json.Unmarshal(data, &cv); err != nil {
+		return nil, fmt.Errorf("failed to parse cluster version: %w", err)
+	}
+
+	status := &ClusterVersionStatus{Desired: cv.Status.Desired.Version}
+	// The most recent history entry is the update to the desired version
+	if len(cv.Status.History) > 0 && cv.Status.History[0].Version == status.Desired {
+		status.Completed = cv.Status.History[0].State == "Completed"
+	}
+	for _, condition := range cv.Status.Conditions {
+		switch {
+		case condition.Type == "Progressing":
+			status.Progress = condition.Message
+		case condition.Type == "Failing" && condition.Status == "True":
+			status.Failing = condition.Message
+		case condition.Type == "Upgradeable" && condition.Status == "False":
+			status.Upgradeable = condition.Message
+		}
+	}
+	return status, nil
+}
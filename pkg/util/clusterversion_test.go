@@ -0,0 +1,42 @@
+package util
+
+import "testing"
+
+func TestParseClusterVersion(t *testing.T) {
+	status, err := parseClusterVersion([]byte(`{
+  "status": {
+    "desired": {"version": "4.13.0"},
+    "history": [
+      {"state": "Partial", "version": "4.13.0"},
+      {"state": "Completed", "version": "4.12.0"}
+    ],
+    "conditions": [
+      {"type": "Available", "status": "True", "message": "Done applying 4.12.0"},
+      {"type": "Failing", "status": "False"},
+      {"type": "Progressing", "status": "True", "message": "Working towards 4.13.0: 512 of 830 done (61% complete)"},
+      {"type": "Upgradeable", "status": "False", "message": "Cluster operator cloud-credential should not be upgraded"}
+    ]
+  }
+}`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if status.Desired != "4.13.0" || status.Completed {
+		t.Errorf("Expected an upgrade to 4.13.0 in progress, got %+v", status)
+	}
+	if status.Progress != "Working towards 4.13.0: 512 of 830 done (61% complete)" {
+		t.Errorf("Unexpected progress %q", status.Progress)
+	}
+	if status.Failing != "" || status.Upgradeable == "" {
+		t.Errorf("Unexpected conditions %+v", status)
+	}
+
+	status, _ = parseClusterVersion([]byte(`{"status": {"desired": {"version": "4.13.0"}, "history": [{"state": "Completed", "version": "4.13.0"}]}}`))
+	if !status.Completed {
+		t.Errorf("Expected the upgrade to be completed, got %+v", status)
+	}
+
+	if _, err := parseClusterVersion([]byte("not json")); err == nil {
+		t.Error("Expected error for invalid cluster version")
+	}
+}
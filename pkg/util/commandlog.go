@@ -0,0 +1,63 @@
+package util
+
+import (
+	"strings"
+)
+
+// CommandLog wraps executors to report the full command line of every command
+// they run (e.g. to a verbose logger). The environment of the commands, which
+// holds credentials, is not reported.
+type CommandLog func(commandLine string)
+
+// Wrap returns an executor reporting the commands run by executor
+func (l CommandLog) Wrap(executor CommandExecutor) CommandExecutor {
+	return &loggingExecutor{log: l, executor: executor}
+}
+
+// loggingExecutor reports the commands before running them with its executor
+type loggingExecutor struct {
+	log      CommandLog
+	executor CommandExecutor
+}
+
+func (e *loggingExecutor) Unwrap() CommandExecutor {
+	return e.executor
+}
+
+func (e *loggingExecutor) Execute(name string, args ...string) (string, error) {
+	e.log(CommandLine(name, args...))
+	return e.executor.Execute(name, args...)
+}
+
+func (e *loggingExecutor) ExecuteWithEnv(name string, env []string, args ...string) (string, error) {
+	e.log(CommandLine(name, args...))
+	return e.executor.ExecuteWithEnv(name, env, args...)
+}
+
+func (e *loggingExecutor) ExecuteInteractive(name string, args ...string) error {
+	e.log(CommandLine(name, args...))
+	return e.executor.ExecuteInteractive(name, args...)
+}
+
+func (e *loggingExecutor) ExecuteInteractiveWithEnv(name string, env []string, args ...string) error {
+	e.log(CommandLine(name, args...))
+	return e.executor.ExecuteInteractiveWithEnv(name, env, args...)
+}
+
+// CommandLine returns a command as it would be typed in a shell, quoting the
+// arguments that need it
+func CommandLine(name string, args ...string) string {
+	words := make([]string, 0, len(args)+1)
+	for _, word := range append([]string{name}, args...) {
+		words = append(words, shellQuote(word))
+	}
+	return strings.Join(words, " ")
+}
+
+// shellQuote single-quotes a word holding characters special to the shell
+func shellQuote(word string) string {
+	if word != "" && !strings.ContainsAny(word, " \t\n'\"\\$`!*?[]{}()<>|&;#~") {
+		return word
+	}
+	return "'" + strings.ReplaceAll(word, "'", `'\''`) + "'"
+}
@@ -0,0 +1,52 @@
+package util
+
+import (
+	"testing"
+)
+
+func TestCommandLog(t *testing.T) {
+	var lines []string
+	mock := NewMockExecutor()
+	mock.SetOutput("aws sts get-caller-identity", "{}")
+	executor := CommandLog(func(line string) { lines = append(lines, line) }).Wrap(mock)
+
+	output, err := executor.Execute("aws", "sts", "get-caller-identity")
+	if err != nil || output != "{}" {
+		t.Fatalf("Execute() = %q, %v: the command should run with the wrapped executor", output, err)
+	}
+	if _, err := executor.ExecuteWithEnv("oc", []string{"KUBECONFIG=/tmp/kubeconfig"}, "get", "nodes", "-o", "jsonpath={.items[*].metadata.name}"); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"aws sts get-caller-identity",
+		"oc get nodes -o 'jsonpath={.items[*].metadata.name}'",
+	}
+	if len(lines) != len(expected) {
+		t.Fatalf("Logged %v, expected %v", lines, expected)
+	}
+	for i := range expected {
+		if lines[i] != expected[i] {
+			t.Errorf("Logged %q, expected %q", lines[i], expected[i])
+		}
+	}
+	if len(mock.Commands) != 2 {
+		t.Errorf("Expected 2 commands run, got %v", mock.Commands)
+	}
+}
+
+func TestCommandLine(t *testing.T) {
+	tests := []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"ccoctl", "aws", "create-all", "--name=test"}, "ccoctl aws create-all --name=test"},
+		{[]string{"echo", "it's", ""}, `echo 'it'\''s' ''`},
+		{[]string{"sh", "-c", "echo $HOME"}, "sh -c 'echo $HOME'"},
+	}
+	for _, tt := range tests {
+		if got := CommandLine(tt.args[0], tt.args[1:]...); got != tt.expected {
+			t.Errorf("CommandLine(%q) = %q, expected %q", tt.args, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package util
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// workerMachineSetPattern matches the worker MachineSets openshift-install
+// writes to its openshift/ directory, one per zone
+const workerMachineSetPattern = "99_openshift-cluster-api_worker-machineset-*.yaml"
+
+// MachinePool is a compute pool rendered into MachineSets
+type MachinePool struct {
+	Name         string
+	InstanceType string // Empty keeps the instance type of the worker MachineSets
+	Replicas     int
+	Zones        []string // Empty for the zones of every worker MachineSet
+	Labels       map[string]string
+	Taints       []string // key[=value]:effect
+}
+
+// workerMachineSet is a worker MachineSet of the installer and its file
+type workerMachineSet struct {
+	path string
+	name string
+	zone string
+	data []byte
+}
+
+// document returns a new copy of the parsed MachineSet
+func (m workerMachineSet) document() map[string]interface{} {
+	var document map[string]interface{}
+	yaml.Unmarshal(m.data, &document) // Already parsed by readWorkerMachineSets
+	return document
+}
+
+// readWorkerMachineSets reads the worker MachineSets of the openshift/
+// directory of the installer, sorted by file name
+func readWorkerMachineSets(openshiftDir string) ([]workerMachineSet, error) {
+	paths, err := filepath.Glob(filepath.Join(openshiftDir, workerMachineSetPattern))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	var machineSets []workerMachineSet
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read MachineSet: %w", err)
+		}
+		var document map[string]interface{}
+		if err := yaml.Unmarshal(data, &document); err != nil {
+			return nil, fmt.Errorf("failed to parse MachineSet %s: %w", path, err)
+		}
+		name, _ := nestedValue(document, "metadata", "name").(string)
+		zone, _ := nestedValue(document, "spec", "template", "spec", "providerSpec", "value", "placement", "availabilityZone").(string)
+		if name == "" {
+			return nil, fmt.Errorf("MachineSet %s has no name", path)
+		}
+		machineSets = append(machineSets, workerMachineSet{path: path, name: name, zone: zone, data: data})
+	}
+	if len(machineSets) == 0 {
+		return nil, fmt.Errorf("no worker MachineSets in %s", openshiftDir)
+	}
+	return machineSets, nil
+}
+
+// LabelWorkerMachineSets adds node labels and taints to the worker
+// MachineSets of the openshift/ directory of the installer
+func LabelWorkerMachineSets(openshiftDir string, labels map[string]string, taints []string) error {
+	machineSets, err := readWorkerMachineSets(openshiftDir)
+	if err != nil {
+		return err
+	}
+	for _, machineSet := range machineSets {
+		document := machineSet.document()
+		if err := setNodeLabelsAndTaints(document, labels, taints); err != nil {
+			return err
+		}
+		if err := writeMachineSet(machineSet.path, document); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteComputePoolMachineSets writes the MachineSets of a compute pool to the
+// openshift/ directory of the installer, cloned from the worker MachineSets
+// of its zones, and returns their paths. The replicas are spread across the
+// zones, and the machines have the role of the pool, so that scaling the
+// workers leaves them alone.
+func WriteComputePoolMachineSets(openshiftDir string, pool MachinePool) ([]string, error) {
+	workers, err := readWorkerMachineSets(openshiftDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var selected []workerMachineSet
+	for _, zone := range pool.Zones {
+		found := false
+		for _, worker := range workers {
+			if worker.zone == zone {
+				selected, found = append(selected, worker), true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("compute pool %s: no worker MachineSet in zone %s", pool.Name, zone)
+		}
+	}
+	if len(pool.Zones) == 0 {
+		selected = workers
+	}
+
+	var paths []string
+	for i, replicas := range DistributeReplicas(pool.Replicas, len(selected)) {
+		document := selected[i].document()
+		name := strings.Replace(selected[i].name, "-worker-", "-"+pool.Name+"-", 1)
+		if name == selected[i].name {
+			return nil, fmt.Errorf("unexpected worker MachineSet name %s", selected[i].name)
+		}
+		if len(name) > 63 {
+			return nil, fmt.Errorf("compute pool %s: MachineSet name %s is longer than 63 characters", pool.Name, name)
+		}
+
+		nestedMap(document, "metadata")["name"] = name
+		nestedMap(document, "spec")["replicas"] = replicas
+		nestedMap(document, "spec", "selector", "matchLabels")["machine.openshift.io/cluster-api-machineset"] = name
+		templateLabels := nestedMap(document, "spec", "template", "metadata", "labels")
+		templateLabels["machine.openshift.io/cluster-api-machineset"] = name
+		templateLabels["machine.openshift.io/cluster-api-machine-role"] = pool.Name
+		templateLabels["machine.openshift.io/cluster-api-machine-type"] = pool.Name
+		if pool.InstanceType != "" {
+			nestedMap(document, "spec", "template", "spec", "providerSpec", "value")["instanceType"] = pool.InstanceType
+		}
+
+		labels := map[string]string{"node-role.kubernetes.io/" + pool.Name: ""}
+		for key, value := range pool.Labels {
+			labels[key] = value
+		}
+		if err := setNodeLabelsAndTaints(document, labels, pool.Taints); err != nil {
+			return nil, err
+		}
+
+		path := filepath.Join(openshiftDir, fmt.Sprintf("99_openshift-cluster-api_%s-machineset-%d.yaml", pool.Name, i))
+		if err := writeMachineSet(path, document); err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// setNodeLabelsAndTaints adds labels and taints to the nodes of a MachineSet
+func setNodeLabelsAndTaints(document map[string]interface{}, labels map[string]string, taints []string) error {
+	if len(labels) > 0 {
+		nodeLabels := nestedMap(document, "spec", "template", "spec", "metadata", "labels")
+		for key, value := range labels {
+			nodeLabels[key] = value
+		}
+	}
+	if len(taints) == 0 {
+		return nil
+	}
+
+	spec := nestedMap(document, "spec", "template", "spec")
+	existing, _ := spec["taints"].([]interface{})
+	for _, taint := range taints {
+		keyValue, effect, found := strings.Cut(taint, ":")
+		if !found {
+			return fmt.Errorf("invalid taint %q: must be key[=value]:effect", taint)
+		}
+		key, value, _ := strings.Cut(keyValue, "=")
+		entry := map[string]interface{}{"key": key, "effect": effect}
+		if value != "" {
+			entry["value"] = value
+		}
+		existing = append(existing, entry)
+	}
+	spec["taints"] = existing
+	return nil
+}
+
+// writeMachineSet writes a MachineSet document
+func writeMachineSet(path string, document map[string]interface{}) error {
+	data, err := yaml.Marshal(document)
+	if err != nil {
+		return fmt.Errorf("failed to marshal MachineSet: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write MachineSet: %w", err)
+	}
+	return nil
+}
+
+// nestedValue returns the value at a path of nested maps, nil if missing
+func nestedValue(document map[string]interface{}, keys ...string) interface{} {
+	var value interface{} = document
+	for _, key := range keys {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = m[key]
+	}
+	return value
+}
+
+// nestedMap returns the map at a path of nested maps, creating the missing ones
+func nestedMap(document map[string]interface{}, keys ...string) map[string]interface{} {
+	current := document
+	for _, key := range keys {
+		next, ok := current[key].(map[string]interface{})
+		if !ok {
+			next = map[string]interface{}{}
+			current[key] = next
+		}
+		current = next
+	}
+	return current
+}
@@ -0,0 +1,167 @@
+package util
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// testWorkerMachineSet is a worker MachineSet as written by openshift-install
+const testWorkerMachineSet = `apiVersion: machine.openshift.io/v1beta1
+kind: MachineSet
+metadata:
+  name: test-abc12-worker-%[1]s
+  namespace: openshift-machine-api
+spec:
+  replicas: 1
+  selector:
+    matchLabels:
+      machine.openshift.io/cluster-api-cluster: test-abc12
+      machine.openshift.io/cluster-api-machineset: test-abc12-worker-%[1]s
+  template:
+    metadata:
+      labels:
+        machine.openshift.io/cluster-api-cluster: test-abc12
+        machine.openshift.io/cluster-api-machine-role: worker
+        machine.openshift.io/cluster-api-machine-type: worker
+        machine.openshift.io/cluster-api-machineset: test-abc12-worker-%[1]s
+    spec:
+      providerSpec:
+        value:
+          instanceType: m6i.xlarge
+          placement:
+            availabilityZone: %[1]s
+            region: us-east-1
+`
+
+// writeTestWorkerMachineSets writes a worker MachineSet per zone
+func writeTestWorkerMachineSets(t *testing.T, zones ...string) string {
+	dir := t.TempDir()
+	for i, zone := range zones {
+		path := filepath.Join(dir, fmt.Sprintf("99_openshift-cluster-api_worker-machineset-%d.yaml", i))
+		if err := os.WriteFile(path, []byte(fmt.Sprintf(testWorkerMachineSet, zone)), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func readTestMachineSet(t *testing.T, path string) map[string]interface{} {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var document map[string]interface{}
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		t.Fatal(err)
+	}
+	return document
+}
+
+func TestWriteComputePoolMachineSets(t *testing.T) {
+	dir := writeTestWorkerMachineSets(t, "us-east-1a", "us-east-1b", "us-east-1c")
+
+	paths, err := WriteComputePoolMachineSets(dir, MachinePool{
+		Name:         "gpu",
+		InstanceType: "g5.xlarge",
+		Replicas:     3,
+		Zones:        []string{"us-east-1a", "us-east-1b"},
+		Labels:       map[string]string{"accelerator": "nvidia"},
+		Taints:       []string{"nvidia.com/gpu=present:NoSchedule"},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(paths) != 2 {
+		t.Fatalf("Expected a MachineSet per zone, got %v", paths)
+	}
+	if filepath.Base(paths[0]) != "99_openshift-cluster-api_gpu-machineset-0.yaml" {
+		t.Errorf("Unexpected file name %s", paths[0])
+	}
+
+	document := readTestMachineSet(t, paths[0])
+	if name := nestedValue(document, "metadata", "name"); name != "test-abc12-gpu-us-east-1a" {
+		t.Errorf("Expected the pool in the name, got %v", name)
+	}
+	if replicas := nestedValue(document, "spec", "replicas"); replicas != 2 {
+		t.Errorf("Expected 2 replicas in the first zone, got %v", replicas)
+	}
+	if selector := nestedValue(document, "spec", "selector", "matchLabels", "machine.openshift.io/cluster-api-machineset"); selector != "test-abc12-gpu-us-east-1a" {
+		t.Errorf("Expected the selector to match the MachineSet, got %v", selector)
+	}
+	if role := nestedValue(document, "spec", "template", "metadata", "labels", "machine.openshift.io/cluster-api-machine-role"); role != "gpu" {
+		t.Errorf("Expected the gpu role, got %v", role)
+	}
+	if instanceType := nestedValue(document, "spec", "template", "spec", "providerSpec", "value", "instanceType"); instanceType != "g5.xlarge" {
+		t.Errorf("Expected the instance type of the pool, got %v", instanceType)
+	}
+	labels, _ := nestedValue(document, "spec", "template", "spec", "metadata", "labels").(map[string]interface{})
+	if _, ok := labels["node-role.kubernetes.io/gpu"]; !ok || labels["accelerator"] != "nvidia" {
+		t.Errorf("Expected the role and user node labels, got %v", labels)
+	}
+	taints, _ := nestedValue(document, "spec", "template", "spec", "taints").([]interface{})
+	if len(taints) != 1 || taints[0].(map[string]interface{})["key"] != "nvidia.com/gpu" || taints[0].(map[string]interface{})["effect"] != "NoSchedule" {
+		t.Errorf("Expected the taint of the pool, got %v", taints)
+	}
+
+	if replicas := nestedValue(readTestMachineSet(t, paths[1]), "spec", "replicas"); replicas != 1 {
+		t.Errorf("Expected 1 replica in the second zone, got %v", replicas)
+	}
+	// The worker MachineSets are left alone
+	if role := nestedValue(readTestMachineSet(t, filepath.Join(dir, "99_openshift-cluster-api_worker-machineset-0.yaml")), "spec", "template", "metadata", "labels", "machine.openshift.io/cluster-api-machine-role"); role != "worker" {
+		t.Errorf("Expected the worker MachineSet to be unchanged, got role %v", role)
+	}
+}
+
+func TestWriteComputePoolMachineSetsAllZones(t *testing.T) {
+	dir := writeTestWorkerMachineSets(t, "us-east-1a", "us-east-1b")
+
+	paths, err := WriteComputePoolMachineSets(dir, MachinePool{Name: "infra", Replicas: 2})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(paths) != 2 {
+		t.Fatalf("Expected a MachineSet per worker zone, got %v", paths)
+	}
+	if instanceType := nestedValue(readTestMachineSet(t, paths[1]), "spec", "template", "spec", "providerSpec", "value", "instanceType"); instanceType != "m6i.xlarge" {
+		t.Errorf("Expected the worker instance type, got %v", instanceType)
+	}
+}
+
+func TestWriteComputePoolMachineSetsUnknownZone(t *testing.T) {
+	dir := writeTestWorkerMachineSets(t, "us-east-1a")
+
+	_, err := WriteComputePoolMachineSets(dir, MachinePool{Name: "gpu", Replicas: 1, Zones: []string{"us-east-1f"}})
+	if err == nil || !strings.Contains(err.Error(), "us-east-1f") {
+		t.Errorf("Expected an error for the zone without worker MachineSet, got %v", err)
+	}
+}
+
+func TestWriteComputePoolMachineSetsNoWorkers(t *testing.T) {
+	if _, err := WriteComputePoolMachineSets(t.TempDir(), MachinePool{Name: "gpu", Replicas: 1}); err == nil {
+		t.Error("Expected an error without worker MachineSets")
+	}
+}
+
+func TestLabelWorkerMachineSets(t *testing.T) {
+	dir := writeTestWorkerMachineSets(t, "us-east-1a")
+
+	if err := LabelWorkerMachineSets(dir, map[string]string{"tier": "frontend"}, []string{"dedicated:NoExecute"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	document := readTestMachineSet(t, filepath.Join(dir, "99_openshift-cluster-api_worker-machineset-0.yaml"))
+	if label := nestedValue(document, "spec", "template", "spec", "metadata", "labels", "tier"); label != "frontend" {
+		t.Errorf("Expected the node label, got %v", label)
+	}
+	taints, _ := nestedValue(document, "spec", "template", "spec", "taints").([]interface{})
+	if len(taints) != 1 {
+		t.Fatalf("Expected 1 taint, got %v", taints)
+	}
+	if taint := taints[0].(map[string]interface{}); taint["key"] != "dedicated" || taint["value"] != nil || taint["effect"] != "NoExecute" {
+		t.Errorf("Unexpected taint %v", taint)
+	}
+}
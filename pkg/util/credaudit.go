@@ -0,0 +1,203 @@
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Outcomes of the audit of an IAM role
+const (
+	RoleInSync     = "in-sync"
+	RoleDrifted    = "drifted"
+	RoleMissing    = "missing"    // A CredentialsRequest of the release has no role
+	RoleUnexpected = "unexpected" // The role matches no CredentialsRequest of the release
+)
+
+// CredentialsAudit is the state of the AWS resources created for the
+// credentials of a cluster, compared to its release
+type CredentialsAudit struct {
+	ClusterName   string      `json:"clusterName"`
+	ReleaseImage  string      `json:"releaseImage"`
+	OIDCProviders []string    `json:"oidcProviders"`
+	S3Buckets     []string    `json:"s3Buckets"`
+	Roles         []RoleAudit `json:"roles"`
+}
+
+// RoleAudit compares the permissions of an IAM role to those its
+// CredentialsRequest asks for. Permissions read "<effect> <action> on <resource>".
+type RoleAudit struct {
+	Role               string   `json:"role"`
+	CredentialsRequest string   `json:"credentialsRequest,omitempty"` // namespace/name
+	Status             string   `json:"status"`
+	Policies           []string `json:"policies,omitempty"`           // Inline policies of the role
+	MissingPermissions []string `json:"missingPermissions,omitempty"` // Asked for by the request, not granted
+	ExtraPermissions   []string `json:"extraPermissions,omitempty"`   // Granted, not asked for by the request
+}
+
+// Drifted returns the roles that are not in sync with the release
+func (a *CredentialsAudit) Drifted() []RoleAudit {
+	var drifted []RoleAudit
+	for _, role := range a.Roles {
+		if role.Status != RoleInSync {
+			drifted = append(drifted, role)
+		}
+	}
+	return drifted
+}
+
+// AuditRoles compares the IAM roles of a cluster (named after prefix, as
+// ccoctl does) to the CredentialsRequests of its release: the inline policies
+// of every role are diffed against the statement entries of its request
+func AuditRoles(executor CommandExecutor, profile, prefix string, requests map[string]CredentialsRequest, roles []string) ([]RoleAudit, error) {
+	found := map[string]bool{}
+	for _, role := range roles {
+		found[role] = true
+	}
+
+	var audits []RoleAudit
+	matched := map[string]bool{}
+	for _, key := range sortedRequestKeys(requests) {
+		request := requests[key]
+		audit := RoleAudit{Role: request.RoleName(prefix), CredentialsRequest: key}
+		matched[audit.Role] = true
+		if !found[audit.Role] {
+			audit.Status = RoleMissing
+			audits = append(audits, audit)
+			continue
+		}
+
+		policies, granted, err := rolePermissions(executor, profile, audit.Role)
+		if err != nil {
+			return nil, err
+		}
+		audit.Policies = policies
+		audit.MissingPermissions, audit.ExtraPermissions = diffPermissions(policyPermissions(requestPolicy(request)), granted)
+		audit.Status = RoleInSync
+		if len(audit.MissingPermissions) > 0 || len(audit.ExtraPermissions) > 0 {
+			audit.Status = RoleDrifted
+		}
+		audits = append(audits, audit)
+	}
+
+	for _, role := range roles {
+		if !matched[role] {
+			audits = append(audits, RoleAudit{Role: role, Status: RoleUnexpected})
+		}
+	}
+	return audits, nil
+}
+
+// rolePermissions returns the inline policies of a role, and the permissions
+// they grant
+func rolePermissions(executor CommandExecutor, profile, roleName string) ([]string, []string, error) {
+	output, err := RunAWSCLI(executor, profile, "", "iam", "list-role-policies", "--role-name", roleName)
+	if err != nil {
+		return nil, nil, err
+	}
+	var list struct {
+		PolicyNames []string `json:"PolicyNames"`
+	}
+	if err := json.Unmarshal([]byte(output), &list); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse list-role-policies output: %w", err)
+	}
+
+	var permissions []string
+	for _, name := range list.PolicyNames {
+		output, err := RunAWSCLI(executor, profile, "", "iam", "get-role-policy", "--role-name", roleName, "--policy-name", name)
+		if err != nil {
+			return nil, nil, err
+		}
+		// The aws CLI decodes the URL-encoded document
+		var policy struct {
+			PolicyDocument policyDocument `json:"PolicyDocument"`
+		}
+		if err := json.Unmarshal([]byte(output), &policy); err != nil {
+			return nil, nil, fmt.Errorf("failed to parse the policy %s of role %s: %w", name, roleName, err)
+		}
+		permissions = append(permissions, policyPermissions(policy.PolicyDocument)...)
+	}
+	return list.PolicyNames, permissions, nil
+}
+
+// policyPermissions returns the permissions of a policy, one per action and
+// resource of each statement
+func policyPermissions(policy policyDocument) []string {
+	var permissions []string
+	for _, statement := range policy.Statement {
+		condition := ""
+		if statement.Condition != nil {
+			data, _ := json.Marshal(statement.Condition)
+			condition = " if " + string(data)
+		}
+		resources := stringList(statement.Resource)
+		if len(resources) == 0 {
+			resources = []string{"*"}
+		}
+		for _, action := range stringList(statement.Action) {
+			for _, resource := range resources {
+				permissions = append(permissions, fmt.Sprintf("%s %s on %s%s", statement.Effect, action, resource, condition))
+			}
+		}
+	}
+	return permissions
+}
+
+// stringList returns the values of a policy element, which is a string or a
+// list of strings
+func stringList(value interface{}) []string {
+	switch value := value.(type) {
+	case string:
+		return []string{value}
+	case []interface{}:
+		list := make([]string, 0, len(value))
+		for _, item := range value {
+			list = append(list, fmt.Sprint(item))
+		}
+		return list
+	}
+	return nil
+}
+
+// diffPermissions returns the permissions expected but not granted, and those
+// granted but not expected
+func diffPermissions(expected, granted []string) (missing, extra []string) {
+	grantedSet := map[string]bool{}
+	for _, permission := range granted {
+		grantedSet[permissionKey(permission)] = true
+	}
+	expectedSet := map[string]bool{}
+	for _, permission := range expected {
+		expectedSet[permissionKey(permission)] = true
+		if !grantedSet[permissionKey(permission)] {
+			missing = append(missing, permission)
+		}
+	}
+	for _, permission := range granted {
+		if !expectedSet[permissionKey(permission)] {
+			extra = append(extra, permission)
+		}
+	}
+	return sortedUnique(missing), sortedUnique(extra)
+}
+
+// permissionKey identifies a permission: actions are case-insensitive in IAM,
+// resources are not
+func permissionKey(permission string) string {
+	effect, rest, _ := strings.Cut(permission, " ")
+	action, rest, _ := strings.Cut(rest, " ")
+	return effect + " " + strings.ToLower(action) + " " + rest
+}
+
+// sortedUnique returns the items of a list sorted, without repetitions
+func sortedUnique(items []string) []string {
+	sort.Strings(items)
+	var result []string
+	for i, item := range items {
+		if i == 0 || item != items[i-1] {
+			result = append(result, item)
+		}
+	}
+	return result
+}
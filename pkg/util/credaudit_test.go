@@ -0,0 +1,67 @@
+package util
+
+import (
+	"testing"
+)
+
+func TestAuditRoles(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	credreqsDir := t.TempDir()
+	writeCredReq(t, credreqsDir, "0000_30_ebs.yaml", "ebs", "ebs-cloud-credentials", "ec2:AttachVolume")
+	writeCredReq(t, credreqsDir, "0000_50_registry.yaml", "registry", "installer-cloud-credentials", "s3:CreateBucket")
+	writeCredReq(t, credreqsDir, "0000_50_ingress.yaml", "ingress", "cloud-credentials", "route53:ChangeResourceRecordSets")
+	requests, err := ReadCredentialsRequests(credreqsDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	executor := NewMockExecutor()
+	policies := func(role, output string) {
+		executor.SetOutput("aws iam list-role-policies --role-name "+role+" --output json --profile default", `{"PolicyNames": ["`+role+`-policy"]}`)
+		executor.SetOutput("aws iam get-role-policy --role-name "+role+" --policy-name "+role+"-policy --output json --profile default", output)
+	}
+	// In sync (actions are case-insensitive), edited by hand, and left by a previous release
+	policies("my-cluster-openshift-ebs-ebs-cloud-credentials", `{"PolicyDocument": {"Version": "2012-10-17", "Statement": [
+		{"Effect": "Allow", "Action": "EC2:AttachVolume", "Resource": "*"}]}}`)
+	policies("my-cluster-openshift-registry-installer-cloud-credentials", `{"PolicyDocument": {"Version": "2012-10-17", "Statement": [
+		{"Effect": "Allow", "Action": ["s3:*"], "Resource": "*"}]}}`)
+	roles := []string{
+		"my-cluster-openshift-ebs-ebs-cloud-credentials",
+		"my-cluster-openshift-registry-installer-cloud-credentials",
+		"my-cluster-openshift-old-old-credentials",
+	}
+
+	audits, err := AuditRoles(executor, "default", "my-cluster", requests, roles)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	statuses := map[string]RoleAudit{}
+	for _, audit := range audits {
+		statuses[audit.Role] = audit
+	}
+	expected := map[string]string{
+		"my-cluster-openshift-ebs-ebs-cloud-credentials":            RoleInSync,
+		"my-cluster-openshift-registry-installer-cloud-credentials": RoleDrifted,
+		"my-cluster-openshift-ingress-cloud-credentials":            RoleMissing,
+		"my-cluster-openshift-old-old-credentials":                  RoleUnexpected,
+	}
+	if len(audits) != len(expected) {
+		t.Errorf("Expected %d audits, got %+v", len(expected), audits)
+	}
+	for role, status := range expected {
+		if statuses[role].Status != status {
+			t.Errorf("Expected %s to be %s, got %+v", role, status, statuses[role])
+		}
+	}
+
+	drifted := statuses["my-cluster-openshift-registry-installer-cloud-credentials"]
+	if len(drifted.MissingPermissions) != 1 || drifted.MissingPermissions[0] != "Allow s3:CreateBucket on *" {
+		t.Errorf("Unexpected missing permissions %v", drifted.MissingPermissions)
+	}
+	if len(drifted.ExtraPermissions) != 1 || drifted.ExtraPermissions[0] != "Allow s3:* on *" {
+		t.Errorf("Unexpected extra permissions %v", drifted.ExtraPermissions)
+	}
+	if statuses["my-cluster-openshift-ingress-cloud-credentials"].CredentialsRequest != "openshift-cloud-credential-operator/ingress" {
+		t.Errorf("Expected the missing role to name its CredentialsRequest, got %+v", statuses["my-cluster-openshift-ingress-cloud-credentials"])
+	}
+}
@@ -0,0 +1,155 @@
+package util
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CredentialsRequest is a CredentialsRequest manifest extracted from a release image
+type CredentialsRequest struct {
+	Name            string
+	Namespace       string
+	SecretName      string // Secret the operator reads its credentials from
+	SecretNamespace string
+	File            string                 // Manifest file, relative to the extraction directory
+	Spec            map[string]interface{} // Compared to detect policy changes
+}
+
+// Key identifies a CredentialsRequest across releases
+func (c CredentialsRequest) Key() string {
+	return c.Namespace + "/" + c.Name
+}
+
+// RoleName returns the name of the IAM role ccoctl creates for the request,
+// which is <name>-<secret namespace>-<secret name> truncated to 64 characters
+func (c CredentialsRequest) RoleName(name string) string {
+	roleName := fmt.Sprintf("%s-%s-%s", name, c.SecretNamespace, c.SecretName)
+	if len(roleName) > 64 {
+		roleName = roleName[:64]
+	}
+	return roleName
+}
+
+// SecretFile returns the name of the manifest of the credentials secret ccoctl
+// writes for the request
+func (c CredentialsRequest) SecretFile() string {
+	return fmt.Sprintf("%s-%s-credentials.yaml", c.SecretNamespace, c.SecretName)
+}
+
+// credentialsRequestManifest is the subset of a CredentialsRequest read from manifests
+type credentialsRequestManifest struct {
+	Kind     string `yaml:"kind"`
+	Metadata struct {
+		Name      string `yaml:"name"`
+		Namespace string `yaml:"namespace"`
+	} `yaml:"metadata"`
+	Spec map[string]interface{} `yaml:"spec"`
+}
+
+// ReadCredentialsRequests reads the CredentialsRequests extracted to dir,
+// keyed by namespace/name
+func ReadCredentialsRequests(dir string) (map[string]CredentialsRequest, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read credentials requests: %w", err)
+	}
+
+	requests := map[string]CredentialsRequest{}
+	for _, entry := range entries {
+		if entry.IsDir() || (!strings.HasSuffix(entry.Name(), ".yaml") && !strings.HasSuffix(entry.Name(), ".yml")) {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", entry.Name(), err)
+		}
+
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		for {
+			var manifest credentialsRequestManifest
+			if err := decoder.Decode(&manifest); errors.Is(err, io.EOF) {
+				break
+			} else if err != nil {
+				return nil, fmt.Errorf("failed to parse %s: %w", entry.Name(), err)
+			}
+			if manifest.Kind != "CredentialsRequest" {
+				continue
+			}
+
+			request := CredentialsRequest{
+				Name:      manifest.Metadata.Name,
+				Namespace: manifest.Metadata.Namespace,
+				File:      entry.Name(),
+				Spec:      manifest.Spec,
+			}
+			if secretRef, ok := manifest.Spec["secretRef"].(map[string]interface{}); ok {
+				request.SecretName, _ = secretRef["name"].(string)
+				request.SecretNamespace, _ = secretRef["namespace"].(string)
+			}
+			requests[request.Key()] = request
+		}
+	}
+	return requests, nil
+}
+
+// CredentialsDiff lists the CredentialsRequests of a target release that are
+// new, or whose spec (e.g. the permissions granted) differs from the current release
+type CredentialsDiff struct {
+	Added     []CredentialsRequest
+	Changed   []CredentialsRequest
+	Unchanged []CredentialsRequest
+	Removed   []CredentialsRequest // Only in the current release
+}
+
+// DiffCredentialsRequests compares the CredentialsRequests of two releases
+func DiffCredentialsRequests(current, target map[string]CredentialsRequest) CredentialsDiff {
+	var diff CredentialsDiff
+	for _, key := range sortedRequestKeys(target) {
+		request := target[key]
+		previous, ok := current[key]
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, request)
+		case !reflect.DeepEqual(previous.Spec, request.Spec):
+			diff.Changed = append(diff.Changed, request)
+		default:
+			diff.Unchanged = append(diff.Unchanged, request)
+		}
+	}
+	for _, key := range sortedRequestKeys(current) {
+		if _, ok := target[key]; !ok {
+			diff.Removed = append(diff.Removed, current[key])
+		}
+	}
+	return diff
+}
+
+func sortedRequestKeys(requests map[string]CredentialsRequest) []string {
+	keys := make([]string, 0, len(requests))
+	for key := range requests {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// RoleExists reports whether an IAM role exists
+func RoleExists(executor CommandExecutor, profile, roleName string) (bool, error) {
+	_, err := RunAWSCLI(executor, profile, "", "iam", "get-role", "--role-name", roleName)
+	if err == nil {
+		return true, nil
+	}
+	if strings.Contains(err.Error(), "NoSuchEntity") {
+		return false, nil
+	}
+	return false, fmt.Errorf("failed to check IAM role %s: %w", roleName, err)
+}
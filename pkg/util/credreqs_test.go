@@ -0,0 +1,97 @@
+package util
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeCredReq(t *testing.T, dir, file, name, secretName, action string) {
+	t.Helper()
+	content := fmt.Sprintf(`apiVersion: cloudcredential.openshift.io/v1
+kind: CredentialsRequest
+metadata:
+  name: %s
+  namespace: openshift-cloud-credential-operator
+spec:
+  providerSpec:
+    apiVersion: cloudcredential.openshift.io/v1
+    kind: AWSProviderSpec
+    statementEntries:
+    - action:
+      - %s
+      effect: Allow
+      resource: "*"
+  secretRef:
+    name: %s
+    namespace: openshift-%s
+`, name, action, secretName, name)
+	if err := os.WriteFile(filepath.Join(dir, file), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDiffCredentialsRequests(t *testing.T) {
+	currentDir, targetDir := t.TempDir(), t.TempDir()
+	writeCredReq(t, currentDir, "0000_30_ebs.yaml", "ebs", "ebs-cloud-credentials", "ec2:AttachVolume")
+	writeCredReq(t, currentDir, "0000_50_registry.yaml", "registry", "installer-cloud-credentials", "s3:CreateBucket")
+	writeCredReq(t, currentDir, "0000_50_old.yaml", "old", "old-credentials", "ec2:DescribeInstances")
+	writeCredReq(t, targetDir, "0000_30_ebs.yaml", "ebs", "ebs-cloud-credentials", "ec2:AttachVolume")
+	writeCredReq(t, targetDir, "0000_50_registry.yaml", "registry", "installer-cloud-credentials", "s3:PutBucketTagging")
+	writeCredReq(t, targetDir, "0000_50_new.yaml", "new", "new-credentials", "ec2:DescribeRegions")
+	os.WriteFile(filepath.Join(targetDir, "0000_50_other.yaml"), []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: other\n"), 0644)
+
+	current, err := ReadCredentialsRequests(currentDir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	target, err := ReadCredentialsRequests(targetDir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(target) != 3 {
+		t.Fatalf("Expected 3 credentials requests, got %d", len(target))
+	}
+
+	diff := DiffCredentialsRequests(current, target)
+	if len(diff.Added) != 1 || diff.Added[0].Name != "new" || diff.Added[0].File != "0000_50_new.yaml" {
+		t.Errorf("Unexpected added requests: %+v", diff.Added)
+	}
+	if len(diff.Changed) != 1 || diff.Changed[0].Name != "registry" {
+		t.Errorf("Unexpected changed requests: %+v", diff.Changed)
+	}
+	if len(diff.Unchanged) != 1 || diff.Unchanged[0].Name != "ebs" {
+		t.Errorf("Unexpected unchanged requests: %+v", diff.Unchanged)
+	}
+	if len(diff.Removed) != 1 || diff.Removed[0].Name != "old" {
+		t.Errorf("Unexpected removed requests: %+v", diff.Removed)
+	}
+}
+
+func TestCredentialsRequestRoleName(t *testing.T) {
+	request := CredentialsRequest{SecretNamespace: "openshift-image-registry", SecretName: "installer-cloud-credentials"}
+	if got := request.RoleName("my-cluster"); got != "my-cluster-openshift-image-registry-installer-cloud-credentials" {
+		t.Errorf("Unexpected role name %s", got)
+	}
+	if got := request.RoleName("a-very-long-cluster-name"); len(got) != 64 || !strings.HasPrefix(got, "a-very-long-cluster-name-openshift-image-registry-") {
+		t.Errorf("Expected role name truncated to 64 characters, got %s", got)
+	}
+}
+
+func TestRoleExists(t *testing.T) {
+	executor := NewMockExecutor()
+	executor.SetError("aws iam get-role --role-name missing --output json", fmt.Errorf("An error occurred (NoSuchEntity) when calling the GetRole operation"))
+	executor.SetError("aws iam get-role --role-name denied --output json", fmt.Errorf("An error occurred (AccessDenied)"))
+
+	if exists, err := RoleExists(executor, "", "present"); !exists || err != nil {
+		t.Errorf("Expected role to exist, got %v, %v", exists, err)
+	}
+	if exists, err := RoleExists(executor, "", "missing"); exists || err != nil {
+		t.Errorf("Expected role to be missing, got %v, %v", exists, err)
+	}
+	if _, err := RoleExists(executor, "", "denied"); err == nil {
+		t.Error("Expected error when the role cannot be checked")
+	}
+}
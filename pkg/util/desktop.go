@@ -0,0 +1,36 @@
+package util
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+// desktopNotificationCommand returns the command showing a native desktop
+// notification on the given platform (nil if unsupported)
+func desktopNotificationCommand(goos, title, message string) []string {
+	switch goos {
+	case "linux":
+		return []string{"notify-send", "--app-name=openshift-sts-wrapper", title, message}
+	case "darwin":
+		script := fmt.Sprintf("display notification %s with title %s", appleScriptString(message), appleScriptString(title))
+		return []string{"osascript", "-e", script}
+	default:
+		return nil
+	}
+}
+
+// appleScriptString quotes a string as an AppleScript literal
+func appleScriptString(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// DesktopNotify shows a native desktop notification. Unsupported platforms are
+// silently ignored, like OpenBrowser.
+func DesktopNotify(executor CommandExecutor, title, message string) error {
+	command := desktopNotificationCommand(runtime.GOOS, title, message)
+	if command == nil {
+		return nil
+	}
+	return RunCommand(executor, command[0], command[1:]...)
+}
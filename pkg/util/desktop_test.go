@@ -0,0 +1,37 @@
+package util
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestDesktopNotificationCommand(t *testing.T) {
+	linux := desktopNotificationCommand("linux", "Cluster deployed", "Cluster test is up")
+	if len(linux) != 4 || linux[0] != "notify-send" || linux[2] != "Cluster deployed" || linux[3] != "Cluster test is up" {
+		t.Errorf("Unexpected linux command: %v", linux)
+	}
+
+	darwin := desktopNotificationCommand("darwin", "Installation failed", `Step "7" failed`)
+	expected := `display notification "Step \"7\" failed" with title "Installation failed"`
+	if len(darwin) != 3 || darwin[0] != "osascript" || darwin[2] != expected {
+		t.Errorf("Unexpected darwin command: %v", darwin)
+	}
+
+	if command := desktopNotificationCommand("windows", "title", "message"); command != nil {
+		t.Errorf("Expected no command on unsupported platforms, got %v", command)
+	}
+}
+
+func TestDesktopNotify(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("notify-send is only used on linux")
+	}
+
+	executor := NewMockExecutor()
+	if err := DesktopNotify(executor, "Cluster deployed", "Cluster test is up"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !executor.WasExecuted("notify-send --app-name=openshift-sts-wrapper Cluster deployed Cluster test is up") {
+		t.Errorf("Expected notify-send to be executed, got %v", executor.Commands)
+	}
+}
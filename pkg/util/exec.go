@@ -1,13 +1,22 @@
 package util
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
+	"sync"
 	"syscall"
+	"time"
+
+	"github.com/clobrano/openshift-sts-wrapper/pkg/logger"
 )
 
+// commandWaitDelay is how long an interrupted command has to exit before it is killed
+const commandWaitDelay = 10 * time.Second
+
 // CommandExecutor is an interface for executing commands (allows mocking in tests)
 type CommandExecutor interface {
 	Execute(name string, args ...string) (string, error)
@@ -17,29 +26,126 @@ type CommandExecutor interface {
 }
 
 // RealExecutor executes actual system commands
-type RealExecutor struct{}
+type RealExecutor struct {
+	// Context, when set, bounds the lifetime of every child process: the process
+	// is killed as soon as the context is cancelled or its deadline expires
+	Context context.Context
+	// Span, when set, gets a child span for every command executed
+	Span *Span
+	// Path lists directories searched for the commands before PATH, and put
+	// first in the PATH of the commands (e.g. to run the oc of the release)
+	Path []string
+}
+
+// command returns the command running name with env added to the environment
+func (e *RealExecutor) command(name string, env []string, args ...string) *exec.Cmd {
+	if path, err := e.lookPath(name); err == nil {
+		name = path
+	}
+	var cmd *exec.Cmd
+	if e.Context == nil {
+		cmd = exec.Command(name, args...)
+	} else {
+		// Interrupt rather than kill the process, so that it can clean up (e.g.
+		// restore the terminal), and kill it if it doesn't exit in time
+		cmd = exec.CommandContext(e.Context, name, args...)
+		cmd.Cancel = func() error { return cmd.Process.Signal(os.Interrupt) }
+		cmd.WaitDelay = commandWaitDelay
+	}
+
+	if len(e.Path) > 0 {
+		path := append(append([]string{}, e.Path...), os.Getenv("PATH"))
+		cmd.Env = append(os.Environ(), "PATH="+strings.Join(path, string(os.PathListSeparator)))
+	}
+	if len(env) > 0 {
+		if cmd.Env == nil {
+			cmd.Env = os.Environ()
+		}
+		cmd.Env = append(cmd.Env, env...)
+	}
+	return cmd
+}
+
+// lookPath resolves a command name like exec.LookPath, searching the
+// directories of e.Path first
+func (e *RealExecutor) lookPath(name string) (string, error) {
+	if !strings.ContainsRune(name, filepath.Separator) {
+		for _, dir := range e.Path {
+			path := filepath.Join(dir, name)
+			if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() && info.Mode().Perm()&0111 != 0 {
+				return path, nil
+			}
+		}
+	}
+	return exec.LookPath(name)
+}
+
+// ExecutorContext returns the context bounding the commands of an executor,
+// context.Background() if there is none
+func ExecutorContext(executor CommandExecutor) context.Context {
+	switch e := executor.(type) {
+	case *RealExecutor:
+		if e.Context != nil {
+			return e.Context
+		}
+	case wrappedExecutor:
+		if e.Unwrap() != nil {
+			return ExecutorContext(e.Unwrap())
+		}
+	}
+	return context.Background()
+}
+
+// Sleep pauses for d, returning the context error early when the context of
+// the executor is done (e.g. the run was interrupted)
+func Sleep(executor CommandExecutor, d time.Duration) error {
+	ctx := ExecutorContext(executor)
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(d):
+		return nil
+	}
+}
+
+// startSpan starts the span of a command, if the executor is traced
+func (e *RealExecutor) startSpan(name string, args ...string) *Span {
+	if e.Span == nil {
+		return nil
+	}
+	span := e.Span.StartChild(commandSpanName(name, args))
+	span.SetAttribute("process.executable.name", filepath.Base(name))
+	span.SetAttribute("process.command_args", logger.Redact(strings.Join(append([]string{name}, args...), " ")))
+	return span
+}
 
 func (e *RealExecutor) Execute(name string, args ...string) (string, error) {
-	cmd := exec.Command(name, args...)
+	span := e.startSpan(name, args...)
+	cmd := e.command(name, nil, args...)
 	output, err := cmd.CombinedOutput()
+	span.End(err)
 	return string(output), err
 }
 
 func (e *RealExecutor) ExecuteWithEnv(name string, env []string, args ...string) (string, error) {
-	cmd := exec.Command(name, args...)
-	cmd.Env = append(os.Environ(), env...)
+	span := e.startSpan(name, args...)
+	cmd := e.command(name, env, args...)
 	output, err := cmd.CombinedOutput()
+	span.End(err)
 	return string(output), err
 }
 
-func (e *RealExecutor) ExecuteInteractive(name string, args ...string) error {
+func (e *RealExecutor) ExecuteInteractive(name string, args ...string) (err error) {
+	span := e.startSpan(name, args...)
+	defer func() { span.End(err) }()
+
 	// For truly interactive commands, we need to ensure the TTY is properly connected
-	binary, err := exec.LookPath(name)
+	binary, err := e.lookPath(name)
 	if err != nil {
 		return fmt.Errorf("failed to find command %s: %w", name, err)
 	}
 
-	cmd := exec.Command(binary, args...)
+	cmd := e.command(binary, nil, args...)
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
@@ -53,12 +159,14 @@ func (e *RealExecutor) ExecuteInteractive(name string, args ...string) error {
 	return cmd.Run()
 }
 
-func (e *RealExecutor) ExecuteInteractiveWithEnv(name string, env []string, args ...string) error {
-	cmd := exec.Command(name, args...)
+func (e *RealExecutor) ExecuteInteractiveWithEnv(name string, env []string, args ...string) (err error) {
+	span := e.startSpan(name, args...)
+	defer func() { span.End(err) }()
+
+	cmd := e.command(name, env, args...)
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
-	cmd.Env = append(os.Environ(), env...)
 	// Set process group to allow proper signal handling and terminal control
 	cmd.SysProcAttr = &syscall.SysProcAttr{
 		Setpgid: false,
@@ -66,8 +174,9 @@ func (e *RealExecutor) ExecuteInteractiveWithEnv(name string, env []string, args
 	return cmd.Run()
 }
 
-// MockExecutor is a mock executor for testing
+// MockExecutor is a mock executor for testing. It is safe for concurrent use.
 type MockExecutor struct {
+	mu       sync.Mutex
 	Commands []string          // Records all executed commands
 	Outputs  map[string]string // Map of command -> output
 	Errors   map[string]error  // Map of command -> error
@@ -82,21 +191,18 @@ func NewMockExecutor() *MockExecutor {
 }
 
 func (e *MockExecutor) Execute(name string, args ...string) (string, error) {
-	cmdStr := name + " " + strings.Join(args, " ")
-	e.Commands = append(e.Commands, cmdStr)
-
-	if err, ok := e.Errors[cmdStr]; ok {
-		return "", err
-	}
-
-	if output, ok := e.Outputs[cmdStr]; ok {
-		return output, nil
-	}
-
-	return "", nil
+	return e.record(name, args...)
 }
 
 func (e *MockExecutor) ExecuteWithEnv(name string, env []string, args ...string) (string, error) {
+	return e.record(name, args...)
+}
+
+// record stores the command and returns its configured output or error
+func (e *MockExecutor) record(name string, args ...string) (string, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
 	cmdStr := name + " " + strings.Join(args, " ")
 	e.Commands = append(e.Commands, cmdStr)
 
@@ -112,14 +218,20 @@ func (e *MockExecutor) ExecuteWithEnv(name string, env []string, args ...string)
 }
 
 func (e *MockExecutor) SetOutput(cmd string, output string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
 	e.Outputs[cmd] = output
 }
 
 func (e *MockExecutor) SetError(cmd string, err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
 	e.Errors[cmd] = err
 }
 
 func (e *MockExecutor) WasExecuted(cmd string) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
 	for _, c := range e.Commands {
 		if c == cmd {
 			return true
@@ -129,6 +241,8 @@ func (e *MockExecutor) WasExecuted(cmd string) bool {
 }
 
 func (e *MockExecutor) WasExecutedContaining(substring string) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
 	for _, c := range e.Commands {
 		if strings.Contains(c, substring) {
 			return true
@@ -138,25 +252,13 @@ func (e *MockExecutor) WasExecutedContaining(substring string) bool {
 }
 
 func (e *MockExecutor) ExecuteInteractive(name string, args ...string) error {
-	cmdStr := name + " " + strings.Join(args, " ")
-	e.Commands = append(e.Commands, cmdStr)
-
-	if err, ok := e.Errors[cmdStr]; ok {
-		return err
-	}
-
-	return nil
+	_, err := e.record(name, args...)
+	return err
 }
 
 func (e *MockExecutor) ExecuteInteractiveWithEnv(name string, env []string, args ...string) error {
-	cmdStr := name + " " + strings.Join(args, " ")
-	e.Commands = append(e.Commands, cmdStr)
-
-	if err, ok := e.Errors[cmdStr]; ok {
-		return err
-	}
-
-	return nil
+	_, err := e.record(name, args...)
+	return err
 }
 
 // RunCommand is a helper that uses the executor
@@ -0,0 +1,61 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRealExecutorInterrupt(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	executor := &RealExecutor{Context: ctx}
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	// The command is interrupted rather than killed, so it can clean up
+	started := time.Now()
+	_, err := executor.Execute("sh", "-c", "trap 'exit 3' INT; while :; do sleep 0.05; done")
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 3 {
+		t.Errorf("Expected the command to handle the interrupt and exit with 3, got %v", err)
+	}
+	if elapsed := time.Since(started); elapsed > commandWaitDelay {
+		t.Errorf("Expected the command to stop soon after the interrupt, took %s", elapsed)
+	}
+}
+
+func TestSleep(t *testing.T) {
+	if err := Sleep(NewMockExecutor(), time.Millisecond); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := Sleep(&RealExecutor{Context: ctx}, time.Hour); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the sleep to be cancelled, got %v", err)
+	}
+}
+
+func TestRealExecutorPath(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "oc"), []byte("#!/bin/sh\necho release oc \"$PATH\"\n"), 0755); err != nil {
+		t.Fatalf("Failed to write oc: %v", err)
+	}
+	t.Setenv("PATH", "/usr/bin:/bin")
+
+	// The commands run from the directories of the executor, without changing
+	// the PATH of the process
+	output, err := (&RealExecutor{Path: []string{dir}}).ExecuteWithEnv("oc", []string{"KUBECONFIG=kubeconfig"}, "version")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if expected := "release oc " + dir + ":/usr/bin:/bin\n"; output != expected {
+		t.Errorf("Expected %q, got %q", expected, output)
+	}
+	if os.Getenv("PATH") != "/usr/bin:/bin" {
+		t.Errorf("Expected PATH to be unchanged, got %s", os.Getenv("PATH"))
+	}
+}
@@ -0,0 +1,259 @@
+package util
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Schedulers of the cleanup of an expired cluster
+const (
+	SchedulerSystemd = "systemd" // Persistent systemd user timer (~/.config/systemd/user)
+	SchedulerAt      = "at"
+)
+
+// ExpiryJob is the scheduler entry running the cleanup of a cluster at its expiry
+type ExpiryJob struct {
+	Scheduler string `json:"scheduler"`
+	ID        string `json:"id"` // systemd unit or at job number
+}
+
+var atJobPattern = regexp.MustCompile(`job (\d+) at`)
+
+// expiryEnvPrefixes select the environment variables kept by the expiry job:
+// the configuration of the wrapper and of the AWS CLI, and the AWS credentials
+var expiryEnvPrefixes = []string{"OPENSHIFT_STS_", "AWS_"}
+
+// GetExpiryScriptPath returns the script the expiry job of a cluster runs
+func GetExpiryScriptPath(clusterName string) string {
+	return GetClusterPath(clusterName, "expire.sh")
+}
+
+// DetectScheduler returns the scheduler available on this host: systemd when
+// it manages the session, or else at. It is empty when there is none.
+func DetectScheduler() string {
+	if _, err := exec.LookPath("systemctl"); err == nil && DirExists("/run/systemd/system") {
+		return SchedulerSystemd
+	}
+	if _, err := exec.LookPath("at"); err == nil {
+		return SchedulerAt
+	}
+	return ""
+}
+
+// ExpiryEnv returns the variables of environ the expiry job needs, with the
+// resolved values taking precedence: the configuration from the environment,
+// the AWS credentials and PATH, which the scheduler does not pass on
+func ExpiryEnv(environ []string, resolved map[string]string) map[string]string {
+	env := map[string]string{}
+	for _, entry := range environ {
+		name, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			continue
+		}
+		if name == "PATH" {
+			env[name] = value
+		}
+		for _, prefix := range expiryEnvPrefixes {
+			if strings.HasPrefix(name, prefix) {
+				env[name] = value
+			}
+		}
+	}
+	for name, value := range resolved {
+		if value != "" {
+			env[name] = value
+		}
+	}
+	return env
+}
+
+// WriteExpiryScript writes the script destroying a cluster at its expiry: it
+// exports env and runs command from the current directory, appending its
+// output to expire.log in the cluster directory. The script holds
+// credentials, so only the user can read it.
+func WriteExpiryScript(clusterName string, command []string, env map[string]string) (string, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	names := make([]string, 0, len(env))
+	for name := range env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var exports strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&exports, "export %s=%s\n", name, shellQuote(env[name]))
+	}
+	script := fmt.Sprintf(`#!/bin/sh
+# Destroys cluster %s at its expiry (install --expires-in)
+%scd %s || exit 1
+exec %s >> %s 2>&1
+`, clusterName, exports.String(), shellQuote(dir), CommandLine(command[0], command[1:]...), shellQuote(GetClusterPath(clusterName, "expire.log")))
+
+	path, err := filepath.Abs(GetExpiryScriptPath(clusterName))
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, []byte(script), 0700); err != nil {
+		return "", fmt.Errorf("failed to write the expiry script: %w", err)
+	}
+	// WriteFile keeps the mode of an existing script
+	if err := os.Chmod(path, 0700); err != nil {
+		return "", fmt.Errorf("failed to write the expiry script: %w", err)
+	}
+	return path, nil
+}
+
+// systemdUserDir returns the directory of the systemd units of the user
+func systemdUserDir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "systemd", "user"), nil
+}
+
+// systemdQuote quotes a word of a systemd command line
+func systemdQuote(word string) string {
+	word = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "%", "%%").Replace(word)
+	return `"` + word + `"`
+}
+
+// ensureLinger enables the lingering of the user, without which the user
+// timers only run while the user has a session
+func ensureLinger(executor CommandExecutor) error {
+	current, err := user.Current()
+	if err != nil {
+		return err
+	}
+	output, err := executor.Execute("loginctl", "show-user", current.Username, "--property=Linger", "--value")
+	if err == nil && strings.TrimSpace(output) == "yes" {
+		return nil
+	}
+	if output, err := executor.Execute("loginctl", "enable-linger", current.Username); err != nil {
+		return fmt.Errorf("lingering is disabled for %s, the timer would only run while logged in: loginctl enable-linger failed: %w: %s",
+			current.Username, err, strings.TrimSpace(output))
+	}
+	return nil
+}
+
+// installExpiryTimer writes and enables a persistent timer running script at
+// the given time. A timer missed while the host was down runs at boot.
+func installExpiryTimer(executor CommandExecutor, unit, clusterName string, at time.Time, script string) error {
+	if err := ensureLinger(executor); err != nil {
+		return err
+	}
+	dir, err := systemdUserDir()
+	if err != nil {
+		return err
+	}
+	if err := EnsureDir(dir); err != nil {
+		return err
+	}
+	service := fmt.Sprintf(`[Unit]
+Description=Destroy cluster %s at its expiry (openshift-sts-wrapper)
+
+[Service]
+Type=oneshot
+ExecStart=/bin/sh %s
+`, clusterName, systemdQuote(script))
+	timer := fmt.Sprintf(`[Unit]
+Description=Expiry of cluster %s (openshift-sts-wrapper)
+
+[Timer]
+OnCalendar=%s
+AccuracySec=1min
+Persistent=true
+
+[Install]
+WantedBy=timers.target
+`, clusterName, at.UTC().Format("2006-01-02 15:04:05 UTC"))
+	if err := os.WriteFile(filepath.Join(dir, unit+".service"), []byte(service), 0644); err != nil {
+		return fmt.Errorf("failed to write the expiry service: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, unit+".timer"), []byte(timer), 0644); err != nil {
+		return fmt.Errorf("failed to write the expiry timer: %w", err)
+	}
+	if output, err := executor.Execute("systemctl", "--user", "daemon-reload"); err != nil {
+		return fmt.Errorf("systemctl daemon-reload failed: %w: %s", err, strings.TrimSpace(output))
+	}
+	if output, err := executor.Execute("systemctl", "--user", "enable", "--now", unit+".timer"); err != nil {
+		return fmt.Errorf("systemctl enable failed: %w: %s", err, strings.TrimSpace(output))
+	}
+	return nil
+}
+
+// removeExpiryTimer disables and deletes the timer installed by
+// installExpiryTimer. Jobs scheduled by earlier versions are transient
+// timers, which are only stopped.
+func removeExpiryTimer(executor CommandExecutor, unit string) (string, error) {
+	dir, err := systemdUserDir()
+	if err != nil {
+		return "", err
+	}
+	timer := filepath.Join(dir, unit+".timer")
+	if !FileExists(timer) {
+		return executor.Execute("systemctl", "--user", "stop", unit+".timer")
+	}
+	if output, err := executor.Execute("systemctl", "--user", "disable", "--now", unit+".timer"); err != nil {
+		return output, err
+	}
+	for _, path := range []string{timer, filepath.Join(dir, unit+".service")} {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return "", err
+		}
+	}
+	return executor.Execute("systemctl", "--user", "daemon-reload")
+}
+
+// ScheduleExpiry registers with the scheduler a job running script at the
+// given time
+func ScheduleExpiry(executor CommandExecutor, scheduler, clusterName string, at time.Time, script string) (*ExpiryJob, error) {
+	switch scheduler {
+	case SchedulerSystemd:
+		unit := "openshift-sts-expire-" + clusterName
+		if err := installExpiryTimer(executor, unit, clusterName, at, script); err != nil {
+			return nil, err
+		}
+		return &ExpiryJob{Scheduler: SchedulerSystemd, ID: unit}, nil
+
+	case SchedulerAt:
+		// at takes the local time, to the minute: round up so it never runs early
+		output, err := executor.Execute("at", "-f", script, "-t", at.Add(time.Minute-time.Nanosecond).Local().Format("200601021504"))
+		if err != nil {
+			return nil, fmt.Errorf("at failed: %w: %s", err, strings.TrimSpace(output))
+		}
+		match := atJobPattern.FindStringSubmatch(output)
+		if match == nil {
+			return nil, fmt.Errorf("unexpected at output: %s", strings.TrimSpace(output))
+		}
+		return &ExpiryJob{Scheduler: SchedulerAt, ID: match[1]}, nil
+	}
+	return nil, fmt.Errorf("no scheduler available: install systemd or at")
+}
+
+// CancelExpiry removes the scheduler entry of an expiry job
+func CancelExpiry(executor CommandExecutor, job *ExpiryJob) error {
+	var output string
+	var err error
+	switch job.Scheduler {
+	case SchedulerSystemd:
+		output, err = removeExpiryTimer(executor, job.ID)
+	case SchedulerAt:
+		output, err = executor.Execute("atrm", job.ID)
+	default:
+		return fmt.Errorf("unknown scheduler %q", job.Scheduler)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to cancel %s job %s: %w: %s", job.Scheduler, job.ID, err, strings.TrimSpace(output))
+	}
+	return nil
+}
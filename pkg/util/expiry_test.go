@@ -0,0 +1,174 @@
+package util
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestScheduleExpiry(t *testing.T) {
+	at := time.Date(2026, 10, 16, 18, 0, 30, 0, time.UTC)
+	configDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configDir)
+	current, err := user.Current()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	executor := NewMockExecutor()
+	job, err := ScheduleExpiry(executor, SchedulerSystemd, "my-cluster", at, "/work/expire.sh")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if job.Scheduler != SchedulerSystemd || job.ID != "openshift-sts-expire-my-cluster" {
+		t.Errorf("Unexpected job %+v", job)
+	}
+	for _, command := range []string{
+		"loginctl enable-linger " + current.Username,
+		"systemctl --user daemon-reload",
+		"systemctl --user enable --now openshift-sts-expire-my-cluster.timer",
+	} {
+		if !executor.WasExecuted(command) {
+			t.Errorf("Expected %q, got %v", command, executor.Commands)
+		}
+	}
+	unitDir := filepath.Join(configDir, "systemd", "user")
+	service, _ := os.ReadFile(filepath.Join(unitDir, "openshift-sts-expire-my-cluster.service"))
+	if !strings.Contains(string(service), `ExecStart=/bin/sh "/work/expire.sh"`) {
+		t.Errorf("Unexpected service:\n%s", service)
+	}
+	timer, _ := os.ReadFile(filepath.Join(unitDir, "openshift-sts-expire-my-cluster.timer"))
+	for _, want := range []string{"OnCalendar=2026-10-16 18:00:30 UTC", "Persistent=true", "WantedBy=timers.target"} {
+		if !strings.Contains(string(timer), want) {
+			t.Errorf("Expected %q in the timer, got:\n%s", want, timer)
+		}
+	}
+
+	// Lingering already enabled
+	executor = NewMockExecutor()
+	executor.SetOutput("loginctl show-user "+current.Username+" --property=Linger --value", "yes\n")
+	if _, err := ScheduleExpiry(executor, SchedulerSystemd, "my-cluster", at, "/work/expire.sh"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if executor.WasExecutedContaining("enable-linger") {
+		t.Errorf("Expected lingering to be left alone, got %v", executor.Commands)
+	}
+
+	// Without lingering the timer would not run after a logout
+	executor = NewMockExecutor()
+	executor.SetError("loginctl enable-linger "+current.Username, fmt.Errorf("exit status 1"))
+	if _, err := ScheduleExpiry(executor, SchedulerSystemd, "my-cluster", at, "/work/expire.sh"); err == nil || !strings.Contains(err.Error(), "lingering") {
+		t.Errorf("Expected a lingering error, got %v", err)
+	}
+
+	executor = NewMockExecutor()
+	command := "at -f /work/expire.sh -t " + at.Add(30*time.Second).Local().Format("200601021504")
+	executor.SetOutput(command, "warning: commands will be executed using /bin/sh\njob 42 at Fri Oct 16 18:01:00 2026\n")
+	job, err = ScheduleExpiry(executor, SchedulerAt, "my-cluster", at, "/work/expire.sh")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v (commands %v)", err, executor.Commands)
+	}
+	if job.Scheduler != SchedulerAt || job.ID != "42" {
+		t.Errorf("Unexpected job %+v", job)
+	}
+
+	if _, err := ScheduleExpiry(NewMockExecutor(), "", "my-cluster", at, "/work/expire.sh"); err == nil {
+		t.Error("Expected an error without scheduler")
+	}
+}
+
+func TestCancelExpiry(t *testing.T) {
+	configDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configDir)
+	at := time.Date(2026, 10, 16, 18, 0, 30, 0, time.UTC)
+	job, err := ScheduleExpiry(NewMockExecutor(), SchedulerSystemd, "my-cluster", at, "/work/expire.sh")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	executor := NewMockExecutor()
+	if err := CancelExpiry(executor, job); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := CancelExpiry(executor, &ExpiryJob{Scheduler: SchedulerAt, ID: "42"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !executor.WasExecuted("systemctl --user disable --now openshift-sts-expire-my-cluster.timer") || !executor.WasExecuted("atrm 42") {
+		t.Errorf("Unexpected commands %v", executor.Commands)
+	}
+	if FileExists(filepath.Join(configDir, "systemd", "user", "openshift-sts-expire-my-cluster.timer")) {
+		t.Error("Expected the timer to be removed")
+	}
+
+	// Transient timer of an earlier version
+	executor = NewMockExecutor()
+	if err := CancelExpiry(executor, &ExpiryJob{Scheduler: SchedulerSystemd, ID: "openshift-sts-expire-old"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !executor.WasExecuted("systemctl --user stop openshift-sts-expire-old.timer") {
+		t.Errorf("Unexpected commands %v", executor.Commands)
+	}
+
+	executor.SetError("atrm 43", fmt.Errorf("exit status 1"))
+	if err := CancelExpiry(executor, &ExpiryJob{Scheduler: SchedulerAt, ID: "43"}); err == nil {
+		t.Error("Expected an error when atrm fails")
+	}
+}
+
+func TestExpiryMetadata(t *testing.T) {
+	tmpDir := t.TempDir()
+	originalWd := mustGetwd(t)
+	os.Chdir(tmpDir)
+	defer os.Chdir(originalWd)
+
+	clusterDir := GetClusterPath("my-cluster", "")
+	EnsureDir(clusterDir)
+	if err := SaveInstallMetadata(clusterDir, "quay.io/openshift-release-dev/ocp-release:4.15.0-x86_64", ""); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expiresAt := time.Date(2026, 10, 16, 18, 0, 0, 0, time.UTC)
+	if err := RecordExpiry(clusterDir, &expiresAt, &ExpiryJob{Scheduler: SchedulerAt, ID: "42"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// A resumed installation keeps the expiry
+	if err := SaveInstallMetadata(clusterDir, "quay.io/openshift-release-dev/ocp-release:4.15.0-x86_64", ""); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	metadata, err := ReadInstallMetadata(clusterDir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if metadata.ExpiresAt == nil || !metadata.ExpiresAt.Equal(expiresAt) || metadata.ExpiryJob == nil || metadata.ExpiryJob.ID != "42" {
+		t.Errorf("Expected the expiry to be kept, got %+v", metadata)
+	}
+
+	env := ExpiryEnv([]string{"PATH=/usr/bin", "HOME=/home/me", "AWS_PROFILE=dev", "OPENSHIFT_STS_AWS_PROFILE=dev", "OPENSHIFT_STS_WEBHOOK_URL=https://hooks.example.com/x?a=1&b=2"},
+		map[string]string{"OPENSHIFT_STS_AWS_PROFILE": "prod", "OPENSHIFT_STS_ASSUME_ROLE_ARN": ""})
+	if _, ok := env["HOME"]; ok || env["OPENSHIFT_STS_AWS_PROFILE"] != "prod" || env["AWS_PROFILE"] != "dev" || env["PATH"] != "/usr/bin" {
+		t.Errorf("Unexpected environment %v", env)
+	}
+	if _, ok := env["OPENSHIFT_STS_ASSUME_ROLE_ARN"]; ok {
+		t.Errorf("Expected empty resolved values to be skipped, got %v", env)
+	}
+
+	script, err := WriteExpiryScript("my-cluster", []string{"/usr/bin/openshift-sts-wrapper", "cleanup", "--cluster-name", "my-cluster", "--yes"}, env)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if info, err := os.Stat(script); err != nil || info.Mode().Perm() != 0700 {
+		t.Errorf("Expected a script only the user can read, got %v", info)
+	}
+	content, _ := os.ReadFile(script)
+	for _, want := range []string{"cd " + tmpDir,
+		"export OPENSHIFT_STS_AWS_PROFILE=prod\n",
+		"export OPENSHIFT_STS_WEBHOOK_URL='https://hooks.example.com/x?a=1&b=2'\n",
+		"exec /usr/bin/openshift-sts-wrapper cleanup --cluster-name my-cluster --yes >> artifacts/clusters/my-cluster/expire.log 2>&1"} {
+		if !strings.Contains(string(content), want) {
+			t.Errorf("Expected %q in the script, got:\n%s", want, content)
+		}
+	}
+}
@@ -90,19 +90,6 @@ func GetInstallConfigPath(versionArch, clusterName string) string {
 	return filepath.Join("artifacts", "clusters", clusterName, "install-config.yaml")
 }
 
-// Legacy path helpers for backward compatibility (deprecated)
-// GetBinaryPath returns the full path to a binary in the version-specific artifacts directory
-// Deprecated: Use GetSharedBinaryPath instead
-func GetBinaryPath(versionArch, binaryName string) string {
-	return filepath.Join("artifacts", versionArch, "bin", binaryName)
-}
-
-// GetCredReqsPath returns the path to the credentials requests directory
-// Deprecated: Use GetSharedCredReqsPath instead
-func GetCredReqsPath(versionArch string) string {
-	return filepath.Join("artifacts", versionArch, "credreqs")
-}
-
 // CopyFile copies a file from src to dst
 func CopyFile(src, dst string) error {
 	sourceFile, err := os.Open(src)
@@ -0,0 +1,98 @@
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// maxDurationSamples is how many durations are kept per release and step
+const maxDurationSamples = 10
+
+// historyMu serializes updates of the duration history by concurrent steps
+var historyMu sync.Mutex
+
+// DurationHistory holds the durations in seconds of the latest successful runs
+// of every step, per versionArch. Unlike the step journal it survives cleanup.
+type DurationHistory map[string]map[string][]float64
+
+// GetDurationHistoryPath returns the path to the step duration history
+func GetDurationHistoryPath() string {
+	return filepath.Join("artifacts", "durations.json")
+}
+
+// ReadDurationHistory reads the step duration history
+func ReadDurationHistory() (DurationHistory, error) {
+	data, err := os.ReadFile(GetDurationHistoryPath())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read duration history: %w", err)
+	}
+
+	history := DurationHistory{}
+	if err := json.Unmarshal(data, &history); err != nil {
+		return nil, fmt.Errorf("failed to parse duration history: %w", err)
+	}
+	return history, nil
+}
+
+// RecordStepDuration adds the duration of a successful step run to the history
+func RecordStepDuration(versionArch, step string, duration time.Duration) error {
+	historyMu.Lock()
+	defer historyMu.Unlock()
+
+	history, err := ReadDurationHistory()
+	if err != nil {
+		history = DurationHistory{}
+	}
+	if history[versionArch] == nil {
+		history[versionArch] = map[string][]float64{}
+	}
+	samples := append(history[versionArch][step], duration.Seconds())
+	if len(samples) > maxDurationSamples {
+		samples = samples[len(samples)-maxDurationSamples:]
+	}
+	history[versionArch][step] = samples
+
+	data, err := json.MarshalIndent(history, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal duration history: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(GetDurationHistoryPath()), 0755); err != nil {
+		return fmt.Errorf("failed to create artifacts directory: %w", err)
+	}
+	if err := os.WriteFile(GetDurationHistoryPath(), data, 0644); err != nil {
+		return fmt.Errorf("failed to write duration history: %w", err)
+	}
+	return nil
+}
+
+// EstimateStepDuration returns the median duration of the previous runs of a
+// step for a versionArch, and the number of runs it is based on (0 if none)
+func (h DurationHistory) EstimateStepDuration(versionArch, step string) (time.Duration, int) {
+	samples := append([]float64{}, h[versionArch][step]...)
+	if len(samples) == 0 {
+		return 0, 0
+	}
+	sort.Float64s(samples)
+	median := samples[len(samples)/2]
+	if len(samples)%2 == 0 {
+		median = (samples[len(samples)/2-1] + samples[len(samples)/2]) / 2
+	}
+	return time.Duration(median * float64(time.Second)), len(samples)
+}
+
+// FormatEstimate rounds a duration for display (e.g. 45s, 12m, 1h05m)
+func FormatEstimate(d time.Duration) string {
+	if d < time.Minute {
+		return fmt.Sprintf("%ds", int(d.Round(time.Second).Seconds()))
+	}
+	d = d.Round(time.Minute)
+	if d < time.Hour {
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	}
+	return fmt.Sprintf("%dh%02dm", int(d.Hours()), int((d % time.Hour).Minutes()))
+}
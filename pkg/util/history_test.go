@@ -0,0 +1,63 @@
+package util
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestDurationHistory(t *testing.T) {
+	tmpDir := t.TempDir()
+	originalWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(originalWd)
+
+	if _, err := ReadDurationHistory(); err == nil {
+		t.Error("Expected an error without a history file")
+	}
+
+	for _, minutes := range []int{40, 30, 35} {
+		if err := RecordStepDuration("4.15.12-x86_64", "deploy-cluster", time.Duration(minutes)*time.Minute); err != nil {
+			t.Fatalf("Failed to record duration: %v", err)
+		}
+	}
+	RecordStepDuration("4.15.12-x86_64", "create-aws-resources", 90*time.Second)
+	RecordStepDuration("4.15.12-x86_64", "create-aws-resources", 150*time.Second)
+
+	history, err := ReadDurationHistory()
+	if err != nil {
+		t.Fatalf("Failed to read history: %v", err)
+	}
+
+	if estimate, runs := history.EstimateStepDuration("4.15.12-x86_64", "deploy-cluster"); estimate != 35*time.Minute || runs != 3 {
+		t.Errorf("Expected median 35m over 3 runs, got %s over %d", estimate, runs)
+	}
+	if estimate, _ := history.EstimateStepDuration("4.15.12-x86_64", "create-aws-resources"); estimate != 2*time.Minute {
+		t.Errorf("Expected median 2m, got %s", estimate)
+	}
+	if _, runs := history.EstimateStepDuration("4.16.0-x86_64", "deploy-cluster"); runs != 0 {
+		t.Errorf("Expected no runs for another release, got %d", runs)
+	}
+
+	// Only the latest runs are kept
+	for i := 0; i < maxDurationSamples; i++ {
+		RecordStepDuration("4.15.12-x86_64", "deploy-cluster", time.Hour)
+	}
+	history, _ = ReadDurationHistory()
+	if estimate, runs := history.EstimateStepDuration("4.15.12-x86_64", "deploy-cluster"); estimate != time.Hour || runs != maxDurationSamples {
+		t.Errorf("Expected median 1h over %d runs, got %s over %d", maxDurationSamples, estimate, runs)
+	}
+}
+
+func TestFormatEstimate(t *testing.T) {
+	tests := map[time.Duration]string{
+		42 * time.Second:                "42s",
+		12*time.Minute + 20*time.Second: "12m",
+		65 * time.Minute:                "1h05m",
+	}
+	for d, expected := range tests {
+		if got := FormatEstimate(d); got != expected {
+			t.Errorf("FormatEstimate(%s) = %q, expected %q", d, got, expected)
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package util
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RunHooks runs each hook as a shell command with the given extra environment.
+// All hooks are attempted; the returned slice contains one error per failed hook.
+func RunHooks(executor CommandExecutor, hooks []string, env []string) []error {
+	var errs []error
+	for _, hook := range hooks {
+		if strings.TrimSpace(hook) == "" {
+			continue
+		}
+		output, err := executor.ExecuteWithEnv("sh", env, "-c", hook)
+		if err != nil {
+			if output != "" {
+				errs = append(errs, fmt.Errorf("hook %q failed: %w\nOutput: %s", hook, err, strings.TrimSpace(output)))
+			} else {
+				errs = append(errs, fmt.Errorf("hook %q failed: %w", hook, err))
+			}
+		}
+	}
+	return errs
+}
@@ -0,0 +1,155 @@
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// STSCredentialsFile is the file, in the cluster directory, of the temporary
+// credentials hcp creates the infrastructure of a hosted cluster with
+const STSCredentialsFile = "sts-creds.json"
+
+// stsCredentials is the output of aws sts get-session-token, the format hcp
+// reads with --sts-creds
+type stsCredentials struct {
+	Credentials struct {
+		AccessKeyID     string `json:"AccessKeyId"`
+		SecretAccessKey string `json:"SecretAccessKey"`
+		SessionToken    string `json:"SessionToken"`
+		Expiration      string `json:"Expiration,omitempty"`
+	} `json:"Credentials"`
+}
+
+// WriteSTSCredentials writes temporary credentials of the profile to path, in
+// the format of aws sts get-session-token that hcp --sts-creds reads. Session
+// credentials (an assumed role) and the temporary credentials a profile
+// resolves to (SSO, assume-role profiles) are written as they are; long-term
+// access keys are exchanged for a session token.
+func WriteSTSCredentials(executor CommandExecutor, profile, path string) error {
+	creds := activeSessionCredentials()
+	if creds == nil {
+		static, err := ReadAWSCredentials(profile)
+		if err != nil {
+			// No access keys in the credentials file
+			exported, exportErr := ExportAWSCredentials(profile)
+			if exportErr != nil {
+				return fmt.Errorf("%v; %w", err, exportErr)
+			}
+			static = exported
+		}
+		creds = static
+	}
+
+	var result stsCredentials
+	if creds != nil && creds.SessionToken != "" {
+		result.Credentials.AccessKeyID = creds.AccessKeyID
+		result.Credentials.SecretAccessKey = creds.SecretAccessKey
+		result.Credentials.SessionToken = creds.SessionToken
+	} else {
+		output, err := RunAWSCLI(executor, profile, "", "sts", "get-session-token")
+		if err != nil {
+			return fmt.Errorf("failed to get a session token: %w", err)
+		}
+		if err := json.Unmarshal([]byte(output), &result); err != nil {
+			return fmt.Errorf("failed to parse get-session-token output: %w", err)
+		}
+		if result.Credentials.SessionToken == "" {
+			return fmt.Errorf("no session token returned for profile '%s'", profile)
+		}
+	}
+
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := EnsureDir(filepath.Dir(path)); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0600); err != nil {
+		return fmt.Errorf("failed to write STS credentials: %w", err)
+	}
+	return nil
+}
+
+// HostedClusterStatus is the status of a HyperShift HostedCluster
+type HostedClusterStatus struct {
+	Available bool   // Whether the hosted control plane is available
+	Progress  string // Message of the Available condition
+	Degraded  string // Message of the Degraded condition, if true
+	Version   string // Version of the release the hosted cluster runs, once rolled out
+}
+
+type hostedCluster struct {
+	Status struct {
+		Version struct {
+			History []struct {
+				State   string `json:"state"`
+				Version string `json:"version"`
+			} `json:"history"`
+		} `json:"version"`
+		Conditions []struct {
+			Type    string `json:"type"`
+			Status  string `json:"status"`
+			Message string `json:"message"`
+		} `json:"conditions"`
+	} `json:"status"`
+}
+
+// GetHostedCluster reads the status of a HostedCluster from the management
+// cluster of a kubeconfig, nil when it doesn't exist
+func GetHostedCluster(executor CommandExecutor, kubeconfigPath, namespace, name string) (*HostedClusterStatus, error) {
+	env := []string{fmt.Sprintf("KUBECONFIG=%s", kubeconfigPath)}
+	output, err := executor.ExecuteWithEnv("oc", env, "get", "hostedcluster", name, "-n", namespace, "-o", "json", "--ignore-not-found")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get hosted cluster %s/%s: %w", namespace, name, err)
+	}
+	if strings.TrimSpace(output) == "" {
+		return nil, nil
+	}
+	return parseHostedCluster([]byte(output))
+}
+
+func parseHostedCluster(data []byte) (*HostedClusterStatus, error) {
+	var hc hostedCluster
+	if err := json.Unmarshal(data, &hc); err != nil {
+		return nil, fmt.Errorf("failed to parse hosted cluster: %w", err)
+	}
+
+	status := &HostedClusterStatus{}
+	if history := hc.Status.Version.History; len(history) > 0 && history[0].State == "Completed" {
+		status.Version = history[0].Version
+	}
+	for _, condition := range hc.Status.Conditions {
+		switch {
+		case condition.Type == "Available":
+			status.Available = condition.Status == "True"
+			status.Progress = condition.Message
+		case condition.Type == "Degraded" && condition.Status == "True":
+			status.Degraded = condition.Message
+		}
+	}
+	return status, nil
+}
+
+// WriteHostedKubeconfig writes the admin kubeconfig of a hosted cluster, read
+// from the management cluster with hcp create kubeconfig, to path
+func WriteHostedKubeconfig(executor CommandExecutor, managementKubeconfig, namespace, name, path string) error {
+	env := []string{fmt.Sprintf("KUBECONFIG=%s", managementKubeconfig)}
+	output, err := executor.ExecuteWithEnv("hcp", env, "create", "kubeconfig", "--name", name, "--namespace", namespace)
+	if err != nil {
+		return fmt.Errorf("failed to get the kubeconfig of hosted cluster %s: %w", name, err)
+	}
+	if strings.TrimSpace(output) == "" {
+		return fmt.Errorf("empty kubeconfig returned for hosted cluster %s", name)
+	}
+	if err := EnsureDir(filepath.Dir(path)); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, []byte(output), 0600); err != nil {
+		return fmt.Errorf("failed to write kubeconfig: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package util
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteSTSCredentialsFromAccessKeys(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	os.MkdirAll(filepath.Join(home, ".aws"), 0755)
+	os.WriteFile(filepath.Join(home, ".aws", "credentials"), []byte("[default]\naws_access_key_id = AKIAEXAMPLE\naws_secret_access_key = secret\n"), 0600)
+
+	executor := NewMockExecutor()
+	executor.SetOutput("aws sts get-session-token --output json --profile default",
+		`{"Credentials": {"AccessKeyId": "ASIASESSION", "SecretAccessKey": "session-secret", "SessionToken": "session-token", "Expiration": "2030-01-01T00:00:00Z"}}`)
+
+	path := filepath.Join(t.TempDir(), "cluster", STSCredentialsFile)
+	if err := WriteSTSCredentials(executor, "default", path); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("STS credentials not written: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected mode 0600, got %v", info.Mode().Perm())
+	}
+	var written stsCredentials
+	data, _ := os.ReadFile(path)
+	if err := json.Unmarshal(data, &written); err != nil {
+		t.Fatalf("Invalid STS credentials: %v", err)
+	}
+	if written.Credentials.AccessKeyID != "ASIASESSION" || written.Credentials.SessionToken != "session-token" {
+		t.Errorf("Expected the session token credentials, got %+v", written.Credentials)
+	}
+}
+
+func TestWriteSTSCredentialsFromSessionCredentials(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	SetSessionCredentials(&AWSCredentials{AccessKeyID: "ASIAROLE", SecretAccessKey: "role-secret", SessionToken: "role-token"})
+	defer SetSessionCredentials(nil)
+
+	executor := NewMockExecutor()
+	path := filepath.Join(t.TempDir(), STSCredentialsFile)
+	if err := WriteSTSCredentials(executor, "default", path); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(executor.Commands) > 0 {
+		t.Errorf("Expected the assumed role credentials to be written as they are, got %v", executor.Commands)
+	}
+
+	var written stsCredentials
+	data, _ := os.ReadFile(path)
+	json.Unmarshal(data, &written)
+	if written.Credentials.AccessKeyID != "ASIAROLE" || written.Credentials.SessionToken != "role-token" {
+		t.Errorf("Expected the assumed role credentials, got %+v", written.Credentials)
+	}
+}
+
+func TestGetHostedCluster(t *testing.T) {
+	const get = "oc get hostedcluster test-cluster -n clusters -o json --ignore-not-found"
+
+	executor := NewMockExecutor()
+	status, err := GetHostedCluster(executor, "/tmp/management", "clusters", "test-cluster")
+	if err != nil || status != nil {
+		t.Fatalf("Expected no hosted cluster, got %+v, %v", status, err)
+	}
+
+	executor.SetOutput(get, `{"status": {
+  "version": {"history": [{"state": "Completed", "version": "4.16.3"}]},
+  "conditions": [
+    {"type": "Available", "status": "True", "message": "The hosted control plane is available"},
+    {"type": "Degraded", "status": "False", "message": "The hosted cluster is not degraded"}
+  ]}}`)
+	status, err = GetHostedCluster(executor, "/tmp/management", "clusters", "test-cluster")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !status.Available || status.Degraded != "" || status.Version != "4.16.3" {
+		t.Errorf("Unexpected status: %+v", status)
+	}
+}
@@ -0,0 +1,35 @@
+package util
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestApr1Hash(t *testing.T) {
+	// Expected hashes generated with openssl passwd -apr1 -salt abcdEFGH
+	tests := map[string]string{
+		"secret": "$apr1$abcdEFGH$ejwdyPabFjRPBqGaObU/N0",
+		"a":      "$apr1$abcdEFGH$kPSvPCEQwhQEYEhaLk6mN/",
+		"averyveryverylongpassword-with-symbols!@#": "$apr1$abcdEFGH$SBfAS1lz4bDjQJcDoe7sD0",
+	}
+	for password, expected := range tests {
+		if got := apr1Hash(password, "abcdEFGH"); got != expected {
+			t.Errorf("Expected hash of %q to be %s, got %s", password, expected, got)
+		}
+	}
+}
+
+func TestHtpasswdEntry(t *testing.T) {
+	entry, err := HtpasswdEntry("admin", "secret")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	user, hash, _ := strings.Cut(entry, ":")
+	if user != "admin" || !strings.HasPrefix(hash, "$apr1$") {
+		t.Fatalf("Unexpected htpasswd entry: %s", entry)
+	}
+	salt := strings.Split(hash, "$")[2]
+	if hash != apr1Hash("secret", salt) {
+		t.Errorf("Expected the entry to hash the password with its salt, got %s", entry)
+	}
+}
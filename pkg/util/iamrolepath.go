@@ -0,0 +1,141 @@
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// IAMRoleOptions are the settings of the IAM roles created for the
+// CredentialsRequests of a cluster
+type IAMRoleOptions struct {
+	Name                string // Prefix of the role names, as the --name of ccoctl
+	ProviderARN         string // OIDC provider the roles trust
+	Path                string // IAM path of the roles, / if empty
+	PermissionsBoundary string // ARN of the permissions boundary policy, if any
+}
+
+// policyDocument is an IAM policy document
+type policyDocument struct {
+	Version   string            `json:"Version"`
+	Statement []policyStatement `json:"Statement"`
+}
+
+type policyStatement struct {
+	Effect    string      `json:"Effect"`
+	Principal interface{} `json:"Principal,omitempty"`
+	Action    interface{} `json:"Action"`
+	Resource  interface{} `json:"Resource,omitempty"`
+	Condition interface{} `json:"Condition,omitempty"`
+}
+
+// CreateIAMRoles creates the IAM role of every CredentialsRequest of
+// credreqsDir and writes their credentials secrets into <outputDir>/manifests,
+// as ccoctl aws create-iam-roles does. ccoctl has no option for the IAM path of
+// the roles, so they are created with the aws CLI when one is required. Roles
+// left by a previous run get their policies updated.
+func CreateIAMRoles(executor CommandExecutor, profile, credreqsDir, outputDir string, opts IAMRoleOptions) error {
+	requests, err := ReadCredentialsRequests(credreqsDir)
+	if err != nil {
+		return err
+	}
+	accountPrefix, issuerHost, ok := strings.Cut(opts.ProviderARN, ":oidc-provider/")
+	if !ok {
+		return fmt.Errorf("invalid OIDC provider ARN %s", opts.ProviderARN)
+	}
+	path := opts.Path
+	if path == "" {
+		path = "/"
+	}
+
+	manifestsDir := filepath.Join(outputDir, "manifests")
+	if err := EnsureDir(manifestsDir); err != nil {
+		return err
+	}
+	for _, key := range sortedRequestKeys(requests) {
+		request := requests[key]
+		roleName := request.RoleName(opts.Name)
+		if err := createIAMRole(executor, profile, request, roleName, path, issuerHost, opts); err != nil {
+			return err
+		}
+
+		roleARN := accountPrefix + ":role" + path + roleName
+		secret := fmt.Sprintf(credentialsSecretTemplate, request.SecretName, request.SecretNamespace, roleARN)
+		if err := os.WriteFile(filepath.Join(manifestsDir, request.SecretFile()), []byte(secret), 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", request.SecretFile(), err)
+		}
+	}
+	return nil
+}
+
+// createIAMRole creates (or updates) the role of a CredentialsRequest: it
+// trusts the service accounts of the request through the OIDC provider, and
+// has the permissions the request asks for as inline policy
+func createIAMRole(executor CommandExecutor, profile string, request CredentialsRequest, roleName, path, issuerHost string, opts IAMRoleOptions) error {
+	var subjects []string
+	accounts, _ := request.Spec["serviceAccountNames"].([]interface{})
+	for _, account := range accounts {
+		subjects = append(subjects, fmt.Sprintf("system:serviceaccount:%s:%v", request.SecretNamespace, account))
+	}
+	trust, err := json.Marshal(policyDocument{Version: "2012-10-17", Statement: []policyStatement{{
+		Effect:    "Allow",
+		Principal: map[string]string{"Federated": opts.ProviderARN},
+		Action:    "sts:AssumeRoleWithWebIdentity",
+		Condition: map[string]interface{}{"StringEquals": map[string][]string{issuerHost + ":sub": subjects}},
+	}}})
+	if err != nil {
+		return err
+	}
+	policy, err := json.Marshal(requestPolicy(request))
+	if err != nil {
+		return err
+	}
+
+	args := []string{
+		"iam", "create-role",
+		"--role-name", roleName,
+		"--path", path,
+		"--assume-role-policy-document", string(trust),
+		"--tags", FormatAWSTags(map[string]string{"openshift.io/cloud-credential-operator/" + opts.Name: "owned", "Name": roleName}),
+	}
+	if opts.PermissionsBoundary != "" {
+		args = append(args, "--permissions-boundary", opts.PermissionsBoundary)
+	}
+	if _, err := RunAWSCLI(executor, profile, "", args...); err != nil {
+		if !strings.Contains(err.Error(), "EntityAlreadyExists") {
+			return fmt.Errorf("failed to create IAM role %s: %w", roleName, err)
+		}
+		if _, err := RunAWSCLI(executor, profile, "", "iam", "update-assume-role-policy", "--role-name", roleName, "--policy-document", string(trust)); err != nil {
+			return fmt.Errorf("failed to update the trust policy of IAM role %s: %w", roleName, err)
+		}
+		if opts.PermissionsBoundary != "" {
+			if _, err := RunAWSCLI(executor, profile, "", "iam", "put-role-permissions-boundary", "--role-name", roleName, "--permissions-boundary", opts.PermissionsBoundary); err != nil {
+				return fmt.Errorf("failed to set the permissions boundary of IAM role %s: %w", roleName, err)
+			}
+		}
+	}
+
+	if _, err := RunAWSCLI(executor, profile, "", "iam", "put-role-policy", "--role-name", roleName, "--policy-name", roleName+"-policy", "--policy-document", string(policy)); err != nil {
+		return fmt.Errorf("failed to set the policy of IAM role %s: %w", roleName, err)
+	}
+	return nil
+}
+
+// requestPolicy returns the policy of the statement entries of a
+// CredentialsRequest (spec.providerSpec.statementEntries)
+func requestPolicy(request CredentialsRequest) policyDocument {
+	policy := policyDocument{Version: "2012-10-17"}
+	providerSpec, _ := request.Spec["providerSpec"].(map[string]interface{})
+	entries, _ := providerSpec["statementEntries"].([]interface{})
+	for _, entry := range entries {
+		fields, _ := entry.(map[string]interface{})
+		statement := policyStatement{Effect: fmt.Sprint(fields["effect"]), Action: fields["action"], Resource: fields["resource"]}
+		if condition, ok := fields["policyCondition"]; ok {
+			statement.Condition = condition
+		}
+		policy.Statement = append(policy.Statement, statement)
+	}
+	return policy
+}
@@ -0,0 +1,60 @@
+package util
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCreateIAMRoles(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	credreqsDir, outputDir := t.TempDir(), t.TempDir()
+	writeCredReq(t, credreqsDir, "0000_50_registry.yaml", "registry", "installer-cloud-credentials", "s3:CreateBucket")
+	opts := IAMRoleOptions{
+		Name:                "my-cluster",
+		ProviderARN:         "arn:aws:iam::123456789012:oidc-provider/oidc.example.com",
+		Path:                "/openshift/",
+		PermissionsBoundary: "arn:aws:iam::123456789012:policy/boundary",
+	}
+
+	executor := NewMockExecutor()
+	if err := CreateIAMRoles(executor, "default", credreqsDir, outputDir, opts); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var createRole string
+	for _, command := range executor.Commands {
+		if strings.HasPrefix(command, "aws iam create-role ") {
+			createRole = command
+		}
+	}
+	for _, expected := range []string{
+		"--role-name my-cluster-openshift-registry-installer-cloud-credentials --path /openshift/",
+		`"Federated":"arn:aws:iam::123456789012:oidc-provider/oidc.example.com"`,
+		`{"Key":"openshift.io/cloud-credential-operator/my-cluster","Value":"owned"}`,
+		"--permissions-boundary arn:aws:iam::123456789012:policy/boundary",
+	} {
+		if !strings.Contains(createRole, expected) {
+			t.Errorf("Expected %q in %q", expected, createRole)
+		}
+	}
+	if !executor.WasExecutedContaining(`"Action":["s3:CreateBucket"]`) {
+		t.Errorf("Expected the policy of the CredentialsRequest, got %v", executor.Commands)
+	}
+	secret := filepath.Join(outputDir, "manifests", "openshift-registry-installer-cloud-credentials-credentials.yaml")
+	if !FileContains(secret, "role_arn = arn:aws:iam::123456789012:role/openshift/my-cluster-openshift-registry-installer-cloud-credentials") {
+		t.Error("Expected the credentials secret to point to the role under its path")
+	}
+
+	// A role left by a previous run is updated
+	retry := NewMockExecutor()
+	retry.SetError(createRole, fmt.Errorf("An error occurred (EntityAlreadyExists) when calling the CreateRole operation"))
+	if err := CreateIAMRoles(retry, "default", credreqsDir, outputDir, opts); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, command := range []string{"aws iam update-assume-role-policy", "aws iam put-role-permissions-boundary", "aws iam put-role-policy"} {
+		if !retry.WasExecutedContaining(command) {
+			t.Errorf("Expected %q, got %v", command, retry.Commands)
+		}
+	}
+}
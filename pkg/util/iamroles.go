@@ -0,0 +1,130 @@
+package util
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ExternalIAM describes IAM roles and an OIDC provider created outside of the
+// wrapper, which the credentials secrets of the cluster point to
+type ExternalIAM struct {
+	// Roles maps a CredentialsRequest (namespace/name) or its secret
+	// (namespace/name) to the ARN of its role
+	Roles      map[string]string
+	IssuerURL  string
+	SigningKey string // Private key whose public key the OIDC provider publishes
+}
+
+// credentialsSecretTemplate is the credentials secret of a CredentialsRequest,
+// as written by ccoctl
+const credentialsSecretTemplate = `apiVersion: v1
+kind: Secret
+metadata:
+  name: %s
+  namespace: %s
+stringData:
+  credentials: |-
+    [default]
+    sts_regional_endpoints = regional
+    role_arn = %s
+    web_identity_token_file = /var/run/secrets/openshift/serviceaccount/token
+type: Opaque
+`
+
+// authenticationConfigTemplate sets the service account issuer of the cluster
+const authenticationConfigTemplate = `apiVersion: config.openshift.io/v1
+kind: Authentication
+metadata:
+  name: cluster
+spec:
+  serviceAccountIssuer: %s
+`
+
+// WriteExternalIAMManifests writes what ccoctl create-all would for the
+// CredentialsRequests of credreqsDir, using existing roles instead of
+// creating them: the credentials secrets and the authentication config into
+// <outputDir>/manifests, and the service account signing key into
+// <outputDir>/tls. It fails when a request has no role, and returns the roles
+// that match no request (e.g. left from a previous release).
+func WriteExternalIAMManifests(credreqsDir, outputDir string, iam ExternalIAM) ([]string, error) {
+	requests, err := ReadCredentialsRequests(credreqsDir)
+	if err != nil {
+		return nil, err
+	}
+
+	used := map[string]bool{}
+	secrets := map[string]string{}
+	var missing []string
+	for _, key := range sortedRequestKeys(requests) {
+		request := requests[key]
+		secretKey := request.SecretNamespace + "/" + request.SecretName
+		roleARN, found := iam.Roles[key], key
+		if roleARN == "" {
+			roleARN, found = iam.Roles[secretKey], secretKey
+		}
+		if roleARN == "" {
+			missing = append(missing, key)
+			continue
+		}
+		used[found] = true
+		secrets[request.SecretFile()] = fmt.Sprintf(credentialsSecretTemplate, request.SecretName, request.SecretNamespace, roleARN)
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("no IAM role for the CredentialsRequests %s", strings.Join(missing, ", "))
+	}
+
+	manifestsDir := filepath.Join(outputDir, "manifests")
+	tlsDir := filepath.Join(outputDir, "tls")
+	for _, dir := range []string{manifestsDir, tlsDir} {
+		if err := EnsureDir(dir); err != nil {
+			return nil, err
+		}
+	}
+	for file, content := range secrets {
+		if err := os.WriteFile(filepath.Join(manifestsDir, file), []byte(content), 0644); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", file, err)
+		}
+	}
+	if err := WriteAuthenticationConfig(manifestsDir, iam.IssuerURL); err != nil {
+		return nil, err
+	}
+
+	key, err := os.ReadFile(iam.SigningKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the service account signing key: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(tlsDir, SigningKeyFile), key, 0600); err != nil {
+		return nil, fmt.Errorf("failed to write the service account signing key: %w", err)
+	}
+
+	var unused []string
+	for key := range iam.Roles {
+		if !used[key] {
+			unused = append(unused, key)
+		}
+	}
+	sort.Strings(unused)
+	return unused, nil
+}
+
+// WriteAuthenticationConfig writes into manifestsDir the authentication config
+// setting the service account issuer of the cluster, as ccoctl
+// create-identity-provider does
+func WriteAuthenticationConfig(manifestsDir, issuerURL string) error {
+	if err := EnsureDir(manifestsDir); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(manifestsDir, AuthenticationConfigFile), []byte(AuthenticationConfig(issuerURL)), 0644); err != nil {
+		return fmt.Errorf("failed to write the authentication config: %w", err)
+	}
+	return nil
+}
+
+// AuthenticationConfig returns the authentication config setting the service
+// account issuer of the cluster
+func AuthenticationConfig(issuerURL string) string {
+	return fmt.Sprintf(authenticationConfigTemplate, strings.TrimSuffix(issuerURL, "/"))
+}
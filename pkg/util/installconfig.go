@@ -6,7 +6,9 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
+	"github.com/clobrano/openshift-sts-wrapper/pkg/config"
 	"gopkg.in/yaml.v3"
 )
 
@@ -84,10 +86,26 @@ func ExtractAllFields(installConfigPath string) (*ExtractedConfig, error) {
 }
 
 // GenerateInstallConfig generates a complete install-config.yaml file from provided values
-func GenerateInstallConfig(path string, clusterName, baseDomain, awsRegion, sshKey, pullSecret, instanceType string) error {
+func GenerateInstallConfig(path string, clusterName, baseDomain, awsRegion, sshKey, pullSecret, instanceType, architecture string) error {
+	data, err := RenderInstallConfig(clusterName, baseDomain, awsRegion, sshKey, pullSecret, instanceType, architecture)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write install-config.yaml: %w", err)
+	}
+	return nil
+}
+
+// RenderInstallConfig returns the content of the install-config.yaml that
+// GenerateInstallConfig writes
+func RenderInstallConfig(clusterName, baseDomain, awsRegion, sshKey, pullSecret, instanceType, architecture string) ([]byte, error) {
+	if architecture == "" {
+		architecture = "amd64"
+	}
 	// Use default instance type if not specified
 	if instanceType == "" {
-		instanceType = "m5.4xlarge"
+		instanceType = config.DefaultInstanceTypeFor(architecture)
 	}
 
 	installConfig := map[string]interface{}{
@@ -96,7 +114,7 @@ func GenerateInstallConfig(path string, clusterName, baseDomain, awsRegion, sshK
 		"baseDomain":                  baseDomain,
 		"compute": []interface{}{
 			map[string]interface{}{
-				"architecture":   "amd64",
+				"architecture":   architecture,
 				"hyperthreading": "Enabled",
 				"name":           "worker",
 				"platform": map[string]interface{}{
@@ -108,7 +126,7 @@ func GenerateInstallConfig(path string, clusterName, baseDomain, awsRegion, sshK
 			},
 		},
 		"controlPlane": map[string]interface{}{
-			"architecture":   "amd64",
+			"architecture":   architecture,
 			"hyperthreading": "Enabled",
 			"name":           "master",
 			"platform": map[string]interface{}{
@@ -152,7 +170,7 @@ func GenerateInstallConfig(path string, clusterName, baseDomain, awsRegion, sshK
 
 	data, err := yaml.Marshal(installConfig)
 	if err != nil {
-		return fmt.Errorf("failed to marshal install-config: %w", err)
+		return nil, fmt.Errorf("failed to marshal install-config: %w", err)
 	}
 
 	// Post-process to format SSH key with literal block scalar (|)
@@ -160,12 +178,7 @@ func GenerateInstallConfig(path string, clusterName, baseDomain, awsRegion, sshK
 	// We want: sshKey: |\n    <key content>
 	yamlStr := string(data)
 	yamlStr = strings.Replace(yamlStr, "sshKey: "+sshKey, "sshKey: |\n    "+sshKey, 1)
-
-	if err := os.WriteFile(path, []byte(yamlStr), 0644); err != nil {
-		return fmt.Errorf("failed to write install-config.yaml: %w", err)
-	}
-
-	return nil
+	return []byte(yamlStr), nil
 }
 
 // ClusterMetadata represents the metadata.json structure from artifacts directory
@@ -174,7 +187,8 @@ type ClusterMetadata struct {
 	ClusterID   string `json:"clusterID"`
 	InfraID     string `json:"infraID"`
 	AWS         struct {
-		Region string `json:"region"`
+		Region     string              `json:"region"`
+		Identifier []map[string]string `json:"identifier,omitempty"` // Tags of the cluster resources
 	} `json:"aws"`
 }
 
@@ -199,17 +213,107 @@ func ReadClusterMetadata(artifactsDir string) (*ClusterMetadata, error) {
 	return &metadata, nil
 }
 
+// WriteClusterMetadata writes metadata.json to the artifacts directory. It is
+// all that openshift-install destroy cluster needs to find the resources.
+func WriteClusterMetadata(artifactsDir string, metadata *ClusterMetadata) error {
+	data, err := json.MarshalIndent(metadata, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal metadata.json: %w", err)
+	}
+	if err := os.MkdirAll(artifactsDir, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", artifactsDir, err)
+	}
+	if err := os.WriteFile(filepath.Join(artifactsDir, "metadata.json"), data, 0644); err != nil {
+		return fmt.Errorf("failed to write metadata.json: %w", err)
+	}
+	return nil
+}
+
 // InstallMetadata contains information about the installation for cleanup purposes
 type InstallMetadata struct {
-	ReleaseImage string `json:"releaseImage"`
+	ClusterName    string     `json:"clusterName,omitempty"` // Final name of the cluster, with its generated suffix if any
+	ReleaseImage   string     `json:"releaseImage"`
+	ReleaseDigest  string     `json:"releaseDigest,omitempty"`
+	CreatedAt      *time.Time `json:"createdAt,omitempty"`      // When the metadata was first saved, i.e. the installation started
+	SSHKeyPath     string     `json:"sshKeyPath,omitempty"`     // Public SSH key of the nodes
+	ExternalIAM    bool       `json:"externalIAM,omitempty"`    // IAM roles and OIDC provider not created by the wrapper
+	ResourcePrefix string     `json:"resourcePrefix,omitempty"` // Name of the ccoctl resources, when it is not the cluster name
+	ExpiresAt      *time.Time `json:"expiresAt,omitempty"`      // When the cluster is destroyed, with install --expires-in
+	ExpiryJob      *ExpiryJob `json:"expiryJob,omitempty"`      // Scheduler entry running the cleanup at ExpiresAt
 }
 
-// SaveInstallMetadata saves installation metadata to the cluster directory
-func SaveInstallMetadata(clusterDir string, releaseImage string) error {
+// SaveInstallMetadata saves installation metadata to the cluster directory,
+// keeping the creation time, SSH key, IAM mode, resource prefix and expiry of
+// the metadata it replaces
+func SaveInstallMetadata(clusterDir string, releaseImage string, releaseDigest string) error {
 	metadata := InstallMetadata{
-		ReleaseImage: releaseImage,
+		ClusterName:   filepath.Base(clusterDir),
+		ReleaseImage:  releaseImage,
+		ReleaseDigest: releaseDigest,
+	}
+	previous, err := ReadInstallMetadata(clusterDir)
+	if err == nil {
+		metadata.SSHKeyPath = previous.SSHKeyPath
+		metadata.ExternalIAM = previous.ExternalIAM
+		metadata.ResourcePrefix = previous.ResourcePrefix
+		metadata.ExpiresAt = previous.ExpiresAt
+		metadata.ExpiryJob = previous.ExpiryJob
+	}
+	if err == nil && previous.CreatedAt != nil {
+		metadata.CreatedAt = previous.CreatedAt
+	} else {
+		now := time.Now().UTC()
+		metadata.CreatedAt = &now
+	}
+	return writeInstallMetadata(clusterDir, &metadata)
+}
+
+// RecordSSHKey records the SSH key of the nodes in the installation metadata
+func RecordSSHKey(clusterDir, sshKeyPath string) error {
+	metadata, err := ReadInstallMetadata(clusterDir)
+	if err != nil {
+		return err
+	}
+	metadata.SSHKeyPath = sshKeyPath
+	return writeInstallMetadata(clusterDir, metadata)
+}
+
+// RecordExternalIAM records in the installation metadata that the IAM roles and
+// the OIDC provider of the cluster were not created by the wrapper, so that
+// cleanup leaves them alone
+func RecordExternalIAM(clusterDir string) error {
+	metadata, err := ReadInstallMetadata(clusterDir)
+	if err != nil {
+		return err
+	}
+	metadata.ExternalIAM = true
+	return writeInstallMetadata(clusterDir, metadata)
+}
+
+// RecordResourcePrefix records in the installation metadata the name of the
+// resources ccoctl creates, which cleanup and credentials refresh need
+func RecordResourcePrefix(clusterDir, prefix string) error {
+	metadata, err := ReadInstallMetadata(clusterDir)
+	if err != nil {
+		return err
 	}
+	metadata.ResourcePrefix = prefix
+	return writeInstallMetadata(clusterDir, metadata)
+}
+
+// RecordExpiry records in the installation metadata when the cluster expires
+// and the scheduler entry destroying it then (nil clears them)
+func RecordExpiry(clusterDir string, expiresAt *time.Time, job *ExpiryJob) error {
+	metadata, err := ReadInstallMetadata(clusterDir)
+	if err != nil {
+		return err
+	}
+	metadata.ExpiresAt = expiresAt
+	metadata.ExpiryJob = job
+	return writeInstallMetadata(clusterDir, metadata)
+}
 
+func writeInstallMetadata(clusterDir string, metadata *InstallMetadata) error {
 	data, err := json.MarshalIndent(metadata, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal install metadata: %w", err)
@@ -0,0 +1,168 @@
+package util
+
+import (
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// installLogTail is how much of the end of the installer log is scanned for
+// the current phase
+const installLogTail = 64 * 1024
+
+// installLogInfoPattern matches the info messages of the installer log, which
+// describe the install phases (debug messages are too noisy to show)
+var installLogInfoPattern = regexp.MustCompile(`level=info msg=("(?:[^"\\]|\\.)*")`)
+
+// installLogFatalPattern matches the error that stopped the installer
+var installLogFatalPattern = regexp.MustCompile(`level=fatal msg=("(?:[^"\\]|\\.)*")`)
+
+// installBootstrapFailurePattern matches the fatal errors of the bootstrap phase
+var installBootstrapFailurePattern = regexp.MustCompile(`(?i)bootstrap|Kubernetes API`)
+
+// GetInstallLogPath returns the path to the log openshift-install writes in the cluster directory
+func GetInstallLogPath(clusterName string) string {
+	return GetClusterPath(clusterName, ".openshift_install.log")
+}
+
+// LastInstallLogMessage returns the latest info message of an openshift-install
+// log, which tells the current install phase ("" if there is none yet)
+func LastInstallLogMessage(path string) string {
+	return lastInstallLogMatch(path, installLogInfoPattern)
+}
+
+// InstallFailedAtBootstrap reports whether the latest fatal error of an
+// openshift-install log is a bootstrap failure: the Kubernetes API never came
+// up, or bootstrapping did not complete
+func InstallFailedAtBootstrap(path string) bool {
+	return installBootstrapFailurePattern.MatchString(lastInstallLogMatch(path, installLogFatalPattern))
+}
+
+// lastInstallLogMatch returns the (unquoted) message of the last line of the
+// end of an openshift-install log matching pattern, "" if there is none
+func lastInstallLogMatch(path string, pattern *regexp.Regexp) string {
+	file, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	if info, err := file.Stat(); err == nil && info.Size() > installLogTail {
+		file.Seek(info.Size()-installLogTail, io.SeekStart)
+	}
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return ""
+	}
+
+	lines := strings.Split(string(data), "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		match := pattern.FindStringSubmatch(lines[i])
+		if match == nil {
+			continue
+		}
+		if message, err := strconv.Unquote(match[1]); err == nil {
+			return message
+		}
+		return strings.Trim(match[1], `"`)
+	}
+	return ""
+}
+
+// InstallMilestone is a high-level event of the installation found in the
+// openshift-install log
+type InstallMilestone struct {
+	Name string
+	Time time.Time
+}
+
+// installMilestones maps installer info messages to milestones, in install order
+var installMilestones = []struct {
+	pattern *regexp.Regexp
+	name    string
+}{
+	{regexp.MustCompile(`^Creating infrastructure resources`), "Creating infrastructure"},
+	{regexp.MustCompile(`^Waiting up to \S+ .*for the Kubernetes API`), "Infrastructure created, waiting for the Kubernetes API"},
+	{regexp.MustCompile(`^API \S+ up$`), "Kubernetes API up"},
+	{regexp.MustCompile(`^Waiting up to \S+ .*for bootstrapping to complete`), "Waiting for bootstrap to complete"},
+	{regexp.MustCompile(`^Destroying the bootstrap resources`), "Bootstrap complete, destroying bootstrap resources"},
+	{regexp.MustCompile(`^Waiting up to \S+ .*for the cluster at \S+ to initialize`), "Waiting for cluster operators"},
+	{regexp.MustCompile(`^Install complete!`), "Install complete"},
+}
+
+// installLogTimePattern matches the timestamp of an installer log line
+var installLogTimePattern = regexp.MustCompile(`^time="([^"]+)"`)
+
+// InstallLogWatcher reports the milestones written to an openshift-install log
+// since it was created. openshift-install appends to the log of previous
+// attempts, so their content is ignored.
+type InstallLogWatcher struct {
+	path    string
+	offset  int64
+	partial string
+	seen    map[string]bool
+}
+
+// NewInstallLogWatcher starts watching the end of an openshift-install log
+func NewInstallLogWatcher(path string) *InstallLogWatcher {
+	watcher := &InstallLogWatcher{path: path, seen: map[string]bool{}}
+	if info, err := os.Stat(path); err == nil {
+		watcher.offset = info.Size()
+	}
+	return watcher
+}
+
+// Poll returns the milestones logged since the previous call, each reported once
+func (w *InstallLogWatcher) Poll() []InstallMilestone {
+	file, err := os.Open(w.path)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	if info, err := file.Stat(); err == nil && info.Size() < w.offset {
+		// The log was replaced
+		w.offset, w.partial = 0, ""
+	}
+	if _, err := file.Seek(w.offset, io.SeekStart); err != nil {
+		return nil
+	}
+	data, err := io.ReadAll(file)
+	if err != nil || len(data) == 0 {
+		return nil
+	}
+	w.offset += int64(len(data))
+
+	// The last line may still be being written
+	lines := strings.Split(w.partial+string(data), "\n")
+	w.partial = lines[len(lines)-1]
+
+	var milestones []InstallMilestone
+	for _, line := range lines[:len(lines)-1] {
+		match := installLogInfoPattern.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		message, err := strconv.Unquote(match[1])
+		if err != nil {
+			continue
+		}
+		for _, milestone := range installMilestones {
+			if w.seen[milestone.name] || !milestone.pattern.MatchString(message) {
+				continue
+			}
+			w.seen[milestone.name] = true
+			timestamp := time.Now()
+			if match := installLogTimePattern.FindStringSubmatch(line); match != nil {
+				if parsed, err := time.Parse(time.RFC3339, match[1]); err == nil {
+					timestamp = parsed
+				}
+			}
+			milestones = append(milestones, InstallMilestone{Name: milestone.name, Time: timestamp})
+		}
+	}
+	return milestones
+}
@@ -0,0 +1,124 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLastInstallLogMessage(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), ".openshift_install.log")
+
+	if message := LastInstallLogMessage(logPath); message != "" {
+		t.Errorf("Expected no message for a missing log, got %q", message)
+	}
+
+	content := `time="2024-05-01T10:00:00Z" level=info msg="Creating infrastructure resources..."
+time="2024-05-01T10:05:00Z" level=info msg="Waiting up to 20m0s (until 10:25AM UTC) for the Kubernetes API at https://api.test.example.com:6443..."
+time="2024-05-01T10:05:01Z" level=debug msg="Still waiting for the Kubernetes API: Get \"https://api.test.example.com:6443/version\": dial tcp: i/o timeout"
+`
+	os.WriteFile(logPath, []byte(content), 0644)
+
+	expected := "Waiting up to 20m0s (until 10:25AM UTC) for the Kubernetes API at https://api.test.example.com:6443..."
+	if message := LastInstallLogMessage(logPath); message != expected {
+		t.Errorf("Expected %q, got %q", expected, message)
+	}
+
+	// Only the end of large logs is scanned
+	large := strings.Repeat(`time="2024-05-01T10:05:01Z" level=debug msg="noise"`+"\n", 4000)
+	large += `time="2024-05-01T10:30:00Z" level=info msg="Install complete!"` + "\n"
+	os.WriteFile(logPath, []byte(content+large), 0644)
+	if message := LastInstallLogMessage(logPath); message != "Install complete!" {
+		t.Errorf("Expected %q, got %q", "Install complete!", message)
+	}
+}
+
+func TestInstallLogWatcher(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), ".openshift_install.log")
+
+	// Content of a previous attempt is ignored
+	os.WriteFile(logPath, []byte(`time="2024-05-01T09:00:00Z" level=info msg="Creating infrastructure resources..."`+"\n"), 0644)
+	watcher := NewInstallLogWatcher(logPath)
+	if milestones := watcher.Poll(); len(milestones) != 0 {
+		t.Errorf("Expected no milestones from a previous attempt, got %v", milestones)
+	}
+
+	appendLog := func(content string) {
+		file, _ := os.OpenFile(logPath, os.O_APPEND|os.O_WRONLY, 0644)
+		file.WriteString(content)
+		file.Close()
+	}
+
+	appendLog(`time="2024-05-01T10:00:00Z" level=info msg="Creating infrastructure resources..."
+time="2024-05-01T10:04:00Z" level=debug msg="module.vpc: Creation complete"
+time="2024-05-01T10:05:00Z" level=info msg="Waiting up to 20m0s (until 10:25AM UTC) for the Kubernetes API at https://api.test.example.com:6443..."
+time="2024-05-01T10:09:00Z" level=info msg="API v1.29.4 up"
+time="2024-05-01T10:09:00Z" level=info msg="Waiting up to 45m0s (until 10:54AM UTC) for bootstrapping to complete..."
+time="2024-05-01T10:20:00Z" level=info msg="Destroying the bootstrap resou`)
+
+	milestones := watcher.Poll()
+	expected := []string{
+		"Creating infrastructure",
+		"Infrastructure created, waiting for the Kubernetes API",
+		"Kubernetes API up",
+		"Waiting for bootstrap to complete",
+	}
+	if len(milestones) != len(expected) {
+		t.Fatalf("Expected %d milestones, got %v", len(expected), milestones)
+	}
+	for i, name := range expected {
+		if milestones[i].Name != name {
+			t.Errorf("Expected milestone %d to be %q, got %q", i, name, milestones[i].Name)
+		}
+	}
+	if milestones[0].Time.UTC().Format("15:04") != "10:00" {
+		t.Errorf("Expected the milestone time from the log, got %s", milestones[0].Time)
+	}
+
+	// The partially written line is reported once complete
+	appendLog(`rces..."
+time="2024-05-01T10:21:00Z" level=info msg="Waiting up to 40m0s (until 11:01AM UTC) for the cluster at https://api.test.example.com:6443 to initialize..."
+time="2024-05-01T10:40:00Z" level=info msg="Install complete!"
+`)
+	milestones = watcher.Poll()
+	expected = []string{"Bootstrap complete, destroying bootstrap resources", "Waiting for cluster operators", "Install complete"}
+	if len(milestones) != len(expected) {
+		t.Fatalf("Expected %d milestones, got %v", len(expected), milestones)
+	}
+	for i, name := range expected {
+		if milestones[i].Name != name {
+			t.Errorf("Expected milestone %d to be %q, got %q", i, name, milestones[i].Name)
+		}
+	}
+
+	if milestones := watcher.Poll(); len(milestones) != 0 {
+		t.Errorf("Expected no new milestones, got %v", milestones)
+	}
+}
+
+func TestInstallFailedAtBootstrap(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), ".openshift_install.log")
+
+	if InstallFailedAtBootstrap(logPath) {
+		t.Error("Expected no bootstrap failure for a missing log")
+	}
+
+	content := `time="2024-05-01T10:00:00Z" level=info msg="Creating infrastructure resources..."
+time="2024-05-01T10:02:00Z" level=fatal msg="failed to fetch Cluster: failed to generate asset \"Cluster\": failed to create cluster: error creating VPC"
+`
+	os.WriteFile(logPath, []byte(content), 0644)
+	if InstallFailedAtBootstrap(logPath) {
+		t.Error("Expected an infrastructure failure not to be a bootstrap failure")
+	}
+
+	// The latest attempt failed while bootstrapping
+	content += `time="2024-05-01T11:00:00Z" level=info msg="Waiting up to 30m0s (until 11:30AM UTC) for bootstrapping to complete..."
+time="2024-05-01T11:30:00Z" level=error msg="Bootstrap failed to complete: timed out waiting for the condition"
+time="2024-05-01T11:30:01Z" level=fatal msg="Bootstrap failed to complete"
+`
+	os.WriteFile(logPath, []byte(content), 0644)
+	if !InstallFailedAtBootstrap(logPath) {
+		t.Error("Expected a bootstrap failure")
+	}
+}
@@ -0,0 +1,85 @@
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ClusterInstance is an EC2 instance of a cluster
+type ClusterInstance struct {
+	ID    string
+	Name  string // Value of the Name tag
+	State string // pending, running, stopping or stopped
+}
+
+// FindClusterInstances returns the instances tagged as owned by the cluster
+// that aren't terminated, sorted by name
+func FindClusterInstances(executor CommandExecutor, profile, region, infraID string) ([]ClusterInstance, error) {
+	output, err := RunAWSCLI(executor, profile, region, "ec2", "describe-instances",
+		"--filters", fmt.Sprintf("Name=tag:%s%s,Values=owned", InfraTagKeyPrefix, infraID),
+		"Name=instance-state-name,Values=pending,running,stopping,stopped")
+	if err != nil {
+		return nil, err
+	}
+	return parseClusterInstances([]byte(output))
+}
+
+func parseClusterInstances(data []byte) ([]ClusterInstance, error) {
+	var result struct {
+		Reservations []struct {
+			Instances []struct {
+				InstanceID string `json:"InstanceId"`
+				State      struct {
+					Name string `json:"Name"`
+				} `json:"State"`
+				Tags []awsTag `json:"Tags"`
+			} `json:"Instances"`
+		} `json:"Reservations"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse describe-instances output: %w", err)
+	}
+
+	var instances []ClusterInstance
+	for _, reservation := range result.Reservations {
+		for _, item := range reservation.Instances {
+			instance := ClusterInstance{ID: item.InstanceID, State: item.State.Name}
+			for _, tag := range item.Tags {
+				if tag.Key == "Name" {
+					instance.Name = tag.Value
+				}
+			}
+			instances = append(instances, instance)
+		}
+	}
+	sort.Slice(instances, func(i, j int) bool { return instances[i].Name < instances[j].Name })
+	return instances, nil
+}
+
+// StopInstances stops the instances and waits until they are stopped
+func StopInstances(executor CommandExecutor, profile, region string, ids []string) error {
+	return changeInstancesState(executor, profile, region, "stop-instances", "instance-stopped", ids)
+}
+
+// StartInstances starts the instances and waits until they are running
+func StartInstances(executor CommandExecutor, profile, region string, ids []string) error {
+	return changeInstancesState(executor, profile, region, "start-instances", "instance-running", ids)
+}
+
+func changeInstancesState(executor CommandExecutor, profile, region, action, waiter string, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	args := append([]string{"ec2", action, "--instance-ids"}, ids...)
+	if _, err := RunAWSCLI(executor, profile, region, args...); err != nil {
+		return fmt.Errorf("%s failed: %w", action, err)
+	}
+	// The waiter polls every 15 seconds, for up to 10 minutes
+	args = append([]string{"ec2", "wait", waiter, "--instance-ids"}, ids...)
+	if _, err := RunAWSCLI(executor, profile, region, args...); err != nil {
+		return fmt.Errorf("instances not %s: %w", strings.TrimPrefix(waiter, "instance-"), err)
+	}
+	return nil
+}
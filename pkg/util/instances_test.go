@@ -0,0 +1,42 @@
+package util
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFindClusterInstances(t *testing.T) {
+	executor := NewMockExecutor()
+	executor.SetOutput("aws ec2 describe-instances --filters Name=tag:kubernetes.io/cluster/test-x7k2p,Values=owned Name=instance-state-name,Values=pending,running,stopping,stopped --output json --region us-east-2",
+		`{"Reservations": [
+  {"Instances": [{"InstanceId": "i-0b", "State": {"Name": "running"}, "Tags": [{"Key": "Name", "Value": "test-x7k2p-worker-us-east-2a-abcde"}]}]},
+  {"Instances": [{"InstanceId": "i-0a", "State": {"Name": "stopped"}, "Tags": [{"Key": "Name", "Value": "test-x7k2p-master-0"}]}]}
+]}`)
+
+	instances, err := FindClusterInstances(executor, "", "us-east-2", "test-x7k2p")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := []ClusterInstance{
+		{ID: "i-0a", Name: "test-x7k2p-master-0", State: "stopped"},
+		{ID: "i-0b", Name: "test-x7k2p-worker-us-east-2a-abcde", State: "running"},
+	}
+	if !reflect.DeepEqual(instances, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, instances)
+	}
+}
+
+func TestStopInstances(t *testing.T) {
+	executor := NewMockExecutor()
+
+	if err := StopInstances(executor, "", "us-east-2", []string{"i-0a", "i-0b"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := []string{
+		"aws ec2 stop-instances --instance-ids i-0a i-0b --output json --region us-east-2",
+		"aws ec2 wait instance-stopped --instance-ids i-0a i-0b --output json --region us-east-2",
+	}
+	if !reflect.DeepEqual(executor.Commands, expected) {
+		t.Errorf("Expected %v, got %v", expected, executor.Commands)
+	}
+}
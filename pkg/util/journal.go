@@ -0,0 +1,221 @@
+package util
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/clobrano/openshift-sts-wrapper/pkg/logger"
+)
+
+// Step statuses recorded in the journal
+const (
+	StepRunning     = "running"
+	StepSucceeded   = "succeeded"
+	StepFailed      = "failed"
+	StepInterrupted = "interrupted" // The run was interrupted (e.g. Ctrl-C) while the step was running
+)
+
+// ErrInterrupted is the error of a step stopped because the run was interrupted
+var ErrInterrupted = errors.New("interrupted")
+
+// StepRecord is the journal entry of a step
+type StepRecord struct {
+	Status     string     `json:"status"`
+	StartedAt  time.Time  `json:"startedAt"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+	Duration   float64    `json:"durationSeconds,omitempty"`
+	ExitCode   *int       `json:"exitCode,omitempty"` // Exit code of the failed command, if any
+	Error      string     `json:"error,omitempty"`
+	Attempts   int        `json:"attempts,omitempty"` // Times the step was started for this release
+	Failures   int        `json:"failures,omitempty"` // Times the step failed for this release
+}
+
+// Journal records the outcome of every step of a cluster installation in
+// state.json in the cluster directory. It is safe for concurrent use.
+type Journal struct {
+	mu            sync.Mutex
+	clusterName   string
+	ReleaseImage  string                 `json:"releaseImage"`
+	ReleaseDigest string                 `json:"releaseDigest,omitempty"`
+	Steps         map[string]*StepRecord `json:"steps"` // Step name -> record
+}
+
+// GetJournalPath returns the path to the step journal of a cluster
+func GetJournalPath(clusterName string) string {
+	return GetClusterPath(clusterName, "state.json")
+}
+
+// ReadJournal reads the step journal of a cluster
+func ReadJournal(clusterName string) (*Journal, error) {
+	data, err := os.ReadFile(GetJournalPath(clusterName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read step journal: %w", err)
+	}
+
+	journal := &Journal{clusterName: clusterName}
+	if err := json.Unmarshal(data, journal); err != nil {
+		return nil, fmt.Errorf("failed to parse step journal: %w", err)
+	}
+	if journal.Steps == nil {
+		journal.Steps = map[string]*StepRecord{}
+	}
+	return journal, nil
+}
+
+// OpenJournal reads the step journal of a cluster, starting a new one when it
+// is missing, corrupted or belongs to a different release
+func OpenJournal(clusterName, releaseImage, releaseDigest string) *Journal {
+	journal, err := ReadJournal(clusterName)
+	if err != nil || !journal.SameRelease(releaseImage, releaseDigest) {
+		journal = &Journal{clusterName: clusterName, Steps: map[string]*StepRecord{}}
+	}
+	journal.ReleaseImage = releaseImage
+	if releaseDigest != "" {
+		journal.ReleaseDigest = releaseDigest
+	}
+	return journal
+}
+
+// SameRelease reports whether the journal records the steps of a release: the
+// digests are compared when both are known, the release images otherwise
+func (j *Journal) SameRelease(releaseImage, releaseDigest string) bool {
+	if releaseDigest != "" && j.ReleaseDigest != "" {
+		return releaseDigest == j.ReleaseDigest
+	}
+	return releaseImage == "" || j.ReleaseImage == "" || releaseImage == j.ReleaseImage
+}
+
+// ReleaseChangedError is the error of a run of a cluster whose earlier steps
+// used another release
+type ReleaseChangedError struct {
+	ClusterName    string
+	PreviousImage  string // Release image of the earlier steps
+	PreviousDigest string
+	CurrentImage   string
+	CurrentDigest  string
+}
+
+// Previous returns the release of the earlier steps, with its digest when the
+// images are the same (the tag moved)
+func (e *ReleaseChangedError) Previous() string {
+	if e.PreviousImage == e.CurrentImage {
+		return e.PreviousImage + "@" + e.PreviousDigest
+	}
+	return e.PreviousImage
+}
+
+// Current returns the release of the run, like Previous
+func (e *ReleaseChangedError) Current() string {
+	if e.PreviousImage == e.CurrentImage {
+		return e.CurrentImage + "@" + e.CurrentDigest
+	}
+	return e.CurrentImage
+}
+
+func (e *ReleaseChangedError) Error() string {
+	return fmt.Sprintf("earlier steps of cluster %s used release %s, not %s", e.ClusterName, e.Previous(), e.Current())
+}
+
+// CheckJournalRelease returns a *ReleaseChangedError when the step journal of
+// a cluster records steps of another release: mixing the artifacts of two
+// releases fails late and in confusing ways
+func CheckJournalRelease(clusterName, releaseImage, releaseDigest string) error {
+	journal, err := ReadJournal(clusterName)
+	if err != nil || len(journal.Steps) == 0 || journal.SameRelease(releaseImage, releaseDigest) {
+		return nil
+	}
+	return &ReleaseChangedError{
+		ClusterName:    clusterName,
+		PreviousImage:  journal.ReleaseImage,
+		PreviousDigest: journal.ReleaseDigest,
+		CurrentImage:   releaseImage,
+		CurrentDigest:  releaseDigest,
+	}
+}
+
+// Record returns the journal entry of a step, if any
+func (j *Journal) Record(step string) (StepRecord, bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	record, ok := j.Steps[step]
+	if !ok {
+		return StepRecord{}, false
+	}
+	return *record, true
+}
+
+// StartStep records that a step is running and saves the journal
+func (j *Journal) StartStep(step string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	record := &StepRecord{Status: StepRunning, StartedAt: time.Now().UTC(), Attempts: 1}
+	if previous, ok := j.Steps[step]; ok {
+		record.Attempts += previous.Attempts
+		record.Failures = previous.Failures
+	}
+	j.Steps[step] = record
+	return j.save()
+}
+
+// FinishStep records the outcome of a step and saves the journal
+func (j *Journal) FinishStep(step string, stepErr error) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	record, ok := j.Steps[step]
+	if !ok {
+		record = &StepRecord{StartedAt: time.Now().UTC()}
+		j.Steps[step] = record
+	}
+	finishedAt := time.Now().UTC()
+	record.FinishedAt = &finishedAt
+	record.Duration = finishedAt.Sub(record.StartedAt).Seconds()
+	record.Status = StepSucceeded
+	record.ExitCode = nil
+	record.Error = ""
+	if errors.Is(stepErr, ErrInterrupted) {
+		// Not a failure of the step: it runs again on resume
+		record.Status = StepInterrupted
+		record.Error = logger.Redact(stepErr.Error())
+	} else if stepErr != nil {
+		record.Status = StepFailed
+		record.Error = logger.Redact(stepErr.Error())
+		record.Failures++
+		var exitErr *exec.ExitError
+		if errors.As(stepErr, &exitErr) {
+			code := exitErr.ExitCode()
+			record.ExitCode = &code
+		}
+	}
+	return j.save()
+}
+
+// save writes the journal to the cluster directory. The cluster directory is
+// only created by Step 4, so records of earlier steps are kept in memory until
+// it exists.
+func (j *Journal) save() error {
+	clusterDir := GetClusterPath(j.clusterName, "")
+	if !DirExists(clusterDir) {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal step journal: %w", err)
+	}
+
+	// Write atomically so that an interrupted run never leaves a truncated journal
+	path := GetJournalPath(j.clusterName)
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write step journal: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to write step journal: %w", err)
+	}
+	return nil
+}
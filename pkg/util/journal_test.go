@@ -0,0 +1,124 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"testing"
+)
+
+func TestJournal(t *testing.T) {
+	tmpDir := t.TempDir()
+	originalWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(originalWd)
+
+	clusterName := "test-cluster"
+	journal := OpenJournal(clusterName, "quay.io/test:4.12.0-x86_64", "sha256:aaa")
+
+	// Records are kept in memory until the cluster directory exists
+	if err := journal.StartStep("extract-credreqs"); err != nil {
+		t.Fatalf("Failed to start step: %v", err)
+	}
+	if err := journal.FinishStep("extract-credreqs", nil); err != nil {
+		t.Fatalf("Failed to finish step: %v", err)
+	}
+	if FileExists(GetJournalPath(clusterName)) {
+		t.Error("Journal should not be written before the cluster directory exists")
+	}
+
+	os.MkdirAll(GetClusterPath(clusterName, ""), 0755)
+	exitErr := exec.Command("sh", "-c", "exit 3").Run()
+	journal.StartStep("create-aws-resources")
+	journal.FinishStep("create-aws-resources", fmt.Errorf("command failed: %w", exitErr))
+
+	saved, err := ReadJournal(clusterName)
+	if err != nil {
+		t.Fatalf("Failed to read journal: %v", err)
+	}
+	if saved.ReleaseDigest != "sha256:aaa" {
+		t.Errorf("Expected release digest sha256:aaa, got %q", saved.ReleaseDigest)
+	}
+	if record, ok := saved.Record("extract-credreqs"); !ok || record.Status != StepSucceeded || record.FinishedAt == nil || record.Duration < 0 {
+		t.Errorf("Expected extract-credreqs to be recorded as succeeded, got %+v", record)
+	}
+	record, ok := saved.Record("create-aws-resources")
+	if !ok || record.Status != StepFailed {
+		t.Fatalf("Expected create-aws-resources to be recorded as failed, got %+v", record)
+	}
+	if record.ExitCode == nil || *record.ExitCode != 3 {
+		t.Errorf("Expected exit code 3, got %v", record.ExitCode)
+	}
+
+	// A retry that succeeds clears the failure but keeps the counters
+	journal.StartStep("create-aws-resources")
+	journal.FinishStep("create-aws-resources", nil)
+	saved, _ = ReadJournal(clusterName)
+	if record, _ := saved.Record("create-aws-resources"); record.Status != StepSucceeded || record.ExitCode != nil || record.Error != "" {
+		t.Errorf("Expected create-aws-resources to be recorded as succeeded, got %+v", record)
+	}
+	if record, _ := saved.Record("create-aws-resources"); record.Attempts != 2 || record.Failures != 1 {
+		t.Errorf("Expected 2 attempts and 1 failure, got %+v", record)
+	}
+
+	// Records of a different release are discarded
+	if _, ok := OpenJournal(clusterName, "quay.io/test:4.12.1-x86_64", "sha256:bbb").Record("extract-credreqs"); ok {
+		t.Error("Expected a new journal for a different release digest")
+	}
+	if _, ok := OpenJournal(clusterName, "quay.io/test:4.12.0-x86_64", "sha256:aaa").Record("extract-credreqs"); !ok {
+		t.Error("Expected the journal to be reused for the same release digest")
+	}
+
+	if err := journal.FinishStep("copy-tls", errors.New("boom")); err != nil {
+		t.Fatalf("Failed to finish step: %v", err)
+	}
+	saved, _ = ReadJournal(clusterName)
+	if record, _ := saved.Record("copy-tls"); record.ExitCode != nil || record.Error != "boom" {
+		t.Errorf("Expected no exit code for a non-command error, got %+v", record)
+	}
+
+	// An interrupted step is not a failure
+	journal.StartStep("deploy-cluster")
+	journal.FinishStep("deploy-cluster", ErrInterrupted)
+	saved, _ = ReadJournal(clusterName)
+	if record, _ := saved.Record("deploy-cluster"); record.Status != StepInterrupted || record.Failures != 0 {
+		t.Errorf("Expected deploy-cluster to be recorded as interrupted, got %+v", record)
+	}
+}
+
+func TestCheckJournalRelease(t *testing.T) {
+	tmpDir := t.TempDir()
+	originalWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(originalWd)
+
+	clusterName := "test-cluster"
+	if err := CheckJournalRelease(clusterName, "quay.io/test:4.12.1-x86_64", ""); err != nil {
+		t.Errorf("Expected no error without journal, got %v", err)
+	}
+
+	os.MkdirAll(GetClusterPath(clusterName, ""), 0755)
+	journal := OpenJournal(clusterName, "quay.io/test:4.12.0-x86_64", "sha256:aaa")
+	journal.StartStep("create-install-config")
+	journal.FinishStep("create-install-config", nil)
+
+	if err := CheckJournalRelease(clusterName, "quay.io/test:4.12.0-x86_64", "sha256:aaa"); err != nil {
+		t.Errorf("Expected no error for the same release, got %v", err)
+	}
+	if err := CheckJournalRelease(clusterName, "quay.io/test:4.12.0-x86_64", ""); err != nil {
+		t.Errorf("Expected no error for the same release image without digest, got %v", err)
+	}
+
+	var releaseChanged *ReleaseChangedError
+	err := CheckJournalRelease(clusterName, "quay.io/test:4.12.1-x86_64", "")
+	if !errors.As(err, &releaseChanged) || releaseChanged.Previous() != "quay.io/test:4.12.0-x86_64" {
+		t.Errorf("Expected a release change from 4.12.0, got %v", err)
+	}
+
+	// The tag now points to other content
+	err = CheckJournalRelease(clusterName, "quay.io/test:4.12.0-x86_64", "sha256:bbb")
+	if !errors.As(err, &releaseChanged) || releaseChanged.Current() != "quay.io/test:4.12.0-x86_64@sha256:bbb" {
+		t.Errorf("Expected a release change to digest sha256:bbb, got %v", err)
+	}
+}
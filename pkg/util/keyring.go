@@ -0,0 +1,113 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// KeyringService is the service name under which secrets are stored in the OS keyring
+const KeyringService = "openshift-sts-wrapper"
+
+// Secrets that can be stored in the OS keyring
+const (
+	KeyringPullSecret = "pull-secret"
+	KeyringOCMToken   = "ocm-token"
+)
+
+// KeyringSecrets lists the secret names accepted by KeyringSet and KeyringGet
+var KeyringSecrets = []string{KeyringPullSecret, KeyringOCMToken}
+
+// keyringCommand runs a keyring tool with the given stdin; replaced in tests
+var keyringCommand = func(stdin string, name string, args ...string) (string, error) {
+	cmd := exec.Command(name, args...)
+	cmd.Stdin = strings.NewReader(stdin)
+	output, err := cmd.Output()
+	if exitErr, ok := err.(*exec.ExitError); ok && len(exitErr.Stderr) > 0 {
+		return string(output), fmt.Errorf("%w: %s", err, strings.TrimSpace(string(exitErr.Stderr)))
+	}
+	return string(output), err
+}
+
+func validateKeyringSecret(name string) error {
+	for _, known := range KeyringSecrets {
+		if name == known {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown secret %q (valid secrets: %s)", name, strings.Join(KeyringSecrets, ", "))
+}
+
+// KeyringSet stores a secret in the OS keyring: the Secret Service (via
+// secret-tool) on Linux, the login Keychain (via security) on macOS
+func KeyringSet(name, value string) error {
+	if err := validateKeyringSecret(name); err != nil {
+		return err
+	}
+
+	var err error
+	switch runtime.GOOS {
+	case "darwin":
+		// With -w last, security prompts for the secret (twice) instead of
+		// taking it from the command line, where other users could read it
+		if strings.ContainsAny(value, "\r\n") {
+			return fmt.Errorf("%s must be a single line to be stored in the macOS Keychain", name)
+		}
+		_, err = keyringCommand(value+"\n"+value+"\n", "security", "add-generic-password", "-U", "-s", KeyringService, "-a", name, "-w")
+	case "linux":
+		_, err = keyringCommand(value, "secret-tool", "store", "--label", KeyringService+" "+name, "service", KeyringService, "account", name)
+	default:
+		return fmt.Errorf("OS keyring is not supported on %s", runtime.GOOS)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to store %s in the OS keyring: %w", name, err)
+	}
+	return nil
+}
+
+// KeyringGet retrieves a secret from the OS keyring. It returns an empty string
+// when the secret is not stored.
+func KeyringGet(name string) (string, error) {
+	if err := validateKeyringSecret(name); err != nil {
+		return "", err
+	}
+
+	var output string
+	var err error
+	switch runtime.GOOS {
+	case "darwin":
+		output, err = keyringCommand("", "security", "find-generic-password", "-s", KeyringService, "-a", name, "-w")
+	case "linux":
+		output, err = keyringCommand("", "secret-tool", "lookup", "service", KeyringService, "account", name)
+	default:
+		return "", fmt.Errorf("OS keyring is not supported on %s", runtime.GOOS)
+	}
+	if err != nil {
+		// Both tools exit with status 1 (and no output) when the secret is missing
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 && output == "" {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to read %s from the OS keyring: %w", name, err)
+	}
+	return strings.TrimRight(output, "\n"), nil
+}
+
+// WriteTempSecret writes a secret to a temporary file readable by the current
+// user only, for tools that only accept a file path. The caller removes it.
+func WriteTempSecret(pattern, value string) (string, error) {
+	file, err := os.CreateTemp("", pattern)
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.WriteString(value); err != nil {
+		os.Remove(file.Name())
+		return "", fmt.Errorf("failed to write temporary file: %w", err)
+	}
+	return file.Name(), nil
+}
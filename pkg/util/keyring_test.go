@@ -0,0 +1,67 @@
+package util
+
+import (
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestKeyring(t *testing.T) {
+	if runtime.GOOS != "linux" && runtime.GOOS != "darwin" {
+		t.Skipf("OS keyring not supported on %s", runtime.GOOS)
+	}
+
+	stored := map[string]string{}
+	original := keyringCommand
+	keyringCommand = func(stdin string, name string, args ...string) (string, error) {
+		command := name + " " + strings.Join(args, " ")
+		account := args[len(args)-1]
+		switch {
+		case strings.Contains(command, "secret-tool store"):
+			stored[account] = stdin
+		case strings.Contains(command, "add-generic-password"):
+			if args[len(args)-1] != "-w" {
+				t.Errorf("Expected the secret on stdin, got %v", args)
+			}
+			stored[args[5]], _, _ = strings.Cut(stdin, "\n")
+		case strings.Contains(command, "find-generic-password"):
+			return stored[args[4]] + "\n", nil
+		default:
+			return stored[account], nil
+		}
+		return "", nil
+	}
+	defer func() { keyringCommand = original }()
+
+	if err := KeyringSet("password", "secret"); err == nil {
+		t.Error("Expected error for an unknown secret")
+	}
+
+	if err := KeyringSet(KeyringOCMToken, "offline-token"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	token, err := KeyringGet(KeyringOCMToken)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if token != "offline-token" {
+		t.Errorf("Expected offline-token, got %q", token)
+	}
+}
+
+func TestWriteTempSecret(t *testing.T) {
+	path, err := WriteTempSecret("pull-secret-*.json", `{"auths":{}}`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer os.Remove(path)
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected 0600 permissions, got %o", info.Mode().Perm())
+	}
+}
@@ -0,0 +1,212 @@
+package util
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// kubeconfig is the subset of the kubeconfig format needed to merge files.
+// Unknown keys are preserved.
+type kubeconfig struct {
+	APIVersion     string                 `yaml:"apiVersion,omitempty"`
+	Kind           string                 `yaml:"kind,omitempty"`
+	Clusters       []kubeconfigCluster    `yaml:"clusters"`
+	Contexts       []kubeconfigContext    `yaml:"contexts"`
+	Users          []kubeconfigUser       `yaml:"users"`
+	CurrentContext string                 `yaml:"current-context"`
+	Extra          map[string]interface{} `yaml:",inline"`
+}
+
+type kubeconfigCluster struct {
+	Name    string                 `yaml:"name"`
+	Cluster map[string]interface{} `yaml:"cluster"`
+}
+
+type kubeconfigContext struct {
+	Name    string                 `yaml:"name"`
+	Context map[string]interface{} `yaml:"context"`
+}
+
+type kubeconfigUser struct {
+	Name string                 `yaml:"name"`
+	User map[string]interface{} `yaml:"user"`
+}
+
+// GetKubeconfigPath returns the path to the admin kubeconfig of a cluster
+func GetKubeconfigPath(clusterName string) string {
+	return GetClusterPath(clusterName, "auth/kubeconfig")
+}
+
+// DefaultKubeconfigPath returns the kubeconfig file kubectl and oc use by
+// default: the first file in KUBECONFIG, or ~/.kube/config
+func DefaultKubeconfigPath() (string, error) {
+	if paths := filepath.SplitList(os.Getenv("KUBECONFIG")); len(paths) > 0 && paths[0] != "" {
+		return paths[0], nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find the home directory: %w", err)
+	}
+	return filepath.Join(home, ".kube", "config"), nil
+}
+
+// KubeconfigServer returns the API server URL and the CA bundle (PEM) of the
+// cluster of the current context of a kubeconfig
+func KubeconfigServer(path string) (string, []byte, error) {
+	config, err := readKubeconfig(path)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read kubeconfig: %w", err)
+	}
+	clusterName := ""
+	for i, context := range config.Contexts {
+		if context.Name == config.CurrentContext || (config.CurrentContext == "" && i == 0) {
+			clusterName, _ = context.Context["cluster"].(string)
+			break
+		}
+	}
+	for _, cluster := range config.Clusters {
+		if cluster.Name != clusterName {
+			continue
+		}
+		server, _ := cluster.Cluster["server"].(string)
+		encoded, _ := cluster.Cluster["certificate-authority-data"].(string)
+		caBundle, err := base64.StdEncoding.DecodeString(encoded)
+		if server == "" || encoded == "" || err != nil {
+			return "", nil, fmt.Errorf("no server or CA certificate in %s", path)
+		}
+		return server, caBundle, nil
+	}
+	return "", nil, fmt.Errorf("no cluster found in %s", path)
+}
+
+// WriteTokenKubeconfig writes a private kubeconfig logging in to the API server
+// as user with a token, trusting the PEM certificates of caBundle
+func WriteTokenKubeconfig(path, server string, caBundle []byte, user, token string) error {
+	config := &kubeconfig{
+		APIVersion: "v1",
+		Kind:       "Config",
+		Clusters: []kubeconfigCluster{{Name: "cluster", Cluster: map[string]interface{}{
+			"server":                     server,
+			"certificate-authority-data": base64.StdEncoding.EncodeToString(caBundle),
+		}}},
+		Contexts:       []kubeconfigContext{{Name: user, Context: map[string]interface{}{"cluster": "cluster", "user": user}}},
+		Users:          []kubeconfigUser{{Name: user, User: map[string]interface{}{"token": token}}},
+		CurrentContext: user,
+	}
+	data, err := yaml.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("failed to marshal kubeconfig: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write kubeconfig: %w", err)
+	}
+	return nil
+}
+
+func readKubeconfig(path string) (*kubeconfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	config := &kubeconfig{}
+	if err := yaml.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return config, nil
+}
+
+// MergeKubeconfig adds the current context of the kubeconfig at sourcePath to
+// the kubeconfig at targetPath (created if missing) as contextName. Its cluster
+// and user are renamed after the context (the installer names them after the
+// cluster and "admin"), replacing entries of previous merges. The current
+// context of the target is only set when it has none.
+func MergeKubeconfig(sourcePath, targetPath, contextName string) error {
+	source, err := readKubeconfig(sourcePath)
+	if err != nil {
+		return fmt.Errorf("failed to read kubeconfig: %w", err)
+	}
+
+	var context *kubeconfigContext
+	for i := range source.Contexts {
+		if source.Contexts[i].Name == source.CurrentContext || (source.CurrentContext == "" && i == 0) {
+			context = &source.Contexts[i]
+			break
+		}
+	}
+	if context == nil {
+		return fmt.Errorf("no context found in %s", sourcePath)
+	}
+	clusterName, _ := context.Context["cluster"].(string)
+	userName, _ := context.Context["user"].(string)
+
+	target, err := readKubeconfig(targetPath)
+	if os.IsNotExist(err) {
+		target = &kubeconfig{APIVersion: "v1", Kind: "Config"}
+	} else if err != nil {
+		return fmt.Errorf("failed to read kubeconfig: %w", err)
+	}
+
+	// Replace the entries of previous merges
+	newUser := "admin/" + contextName
+	clusters, contexts, users := target.Clusters[:0], target.Contexts[:0], target.Users[:0]
+	for _, cluster := range target.Clusters {
+		if cluster.Name != contextName {
+			clusters = append(clusters, cluster)
+		}
+	}
+	for _, ctx := range target.Contexts {
+		if ctx.Name != contextName {
+			contexts = append(contexts, ctx)
+		}
+	}
+	for _, user := range target.Users {
+		if user.Name != newUser {
+			users = append(users, user)
+		}
+	}
+	target.Clusters, target.Contexts, target.Users = clusters, contexts, users
+
+	for _, cluster := range source.Clusters {
+		if cluster.Name == clusterName {
+			target.Clusters = append(target.Clusters, kubeconfigCluster{Name: contextName, Cluster: cluster.Cluster})
+		}
+	}
+	for _, user := range source.Users {
+		if user.Name == userName {
+			target.Users = append(target.Users, kubeconfigUser{Name: newUser, User: user.User})
+		}
+	}
+	contextBody := map[string]interface{}{}
+	for key, value := range context.Context {
+		contextBody[key] = value
+	}
+	contextBody["cluster"] = contextName
+	contextBody["user"] = newUser
+	target.Contexts = append(target.Contexts, kubeconfigContext{Name: contextName, Context: contextBody})
+
+	if target.CurrentContext == "" {
+		target.CurrentContext = contextName
+	}
+
+	data, err := yaml.Marshal(target)
+	if err != nil {
+		return fmt.Errorf("failed to marshal kubeconfig: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(targetPath), 0755); err != nil {
+		return fmt.Errorf("failed to create kubeconfig directory: %w", err)
+	}
+
+	// The kubeconfig holds credentials: keep it private and write it atomically
+	tmpPath := targetPath + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write kubeconfig: %w", err)
+	}
+	if err := os.Rename(tmpPath, targetPath); err != nil {
+		return fmt.Errorf("failed to write kubeconfig: %w", err)
+	}
+	return nil
+}
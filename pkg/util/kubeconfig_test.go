@@ -0,0 +1,144 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const installerKubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: my-cluster
+  cluster:
+    server: https://api.my-cluster.example.com:6443
+    certificate-authority-data: Q0EK
+contexts:
+- name: admin
+  context:
+    cluster: my-cluster
+    user: admin
+current-context: admin
+users:
+- name: admin
+  user:
+    client-certificate-data: Q0VSVAo=
+    client-key-data: S0VZCg==
+preferences: {}
+`
+
+func TestMergeKubeconfig(t *testing.T) {
+	tmpDir := t.TempDir()
+	source := filepath.Join(tmpDir, "kubeconfig")
+	os.WriteFile(source, []byte(installerKubeconfig), 0600)
+
+	target := filepath.Join(tmpDir, ".kube", "config")
+	os.MkdirAll(filepath.Dir(target), 0755)
+	os.WriteFile(target, []byte(`apiVersion: v1
+kind: Config
+clusters:
+- name: other
+  cluster:
+    server: https://api.other.example.com:6443
+contexts:
+- name: other
+  context:
+    cluster: other
+    user: other
+current-context: other
+users:
+- name: other
+  user:
+    token: sha256~abc
+`), 0600)
+
+	// Merging twice replaces the entries of the first merge
+	for i := 0; i < 2; i++ {
+		if err := MergeKubeconfig(source, target, "my-cluster"); err != nil {
+			t.Fatalf("Failed to merge kubeconfig: %v", err)
+		}
+	}
+
+	merged, err := readKubeconfig(target)
+	if err != nil {
+		t.Fatalf("Failed to read merged kubeconfig: %v", err)
+	}
+	if len(merged.Clusters) != 2 || len(merged.Contexts) != 2 || len(merged.Users) != 2 {
+		t.Fatalf("Expected 2 clusters, contexts and users, got %+v", merged)
+	}
+	if merged.CurrentContext != "other" {
+		t.Errorf("Expected the current context to be kept, got %q", merged.CurrentContext)
+	}
+
+	context := merged.Contexts[1]
+	if context.Name != "my-cluster" || context.Context["cluster"] != "my-cluster" || context.Context["user"] != "admin/my-cluster" {
+		t.Errorf("Unexpected merged context: %+v", context)
+	}
+	if merged.Clusters[1].Cluster["server"] != "https://api.my-cluster.example.com:6443" {
+		t.Errorf("Unexpected merged cluster: %+v", merged.Clusters[1])
+	}
+	if merged.Users[1].Name != "admin/my-cluster" || merged.Users[1].User["client-key-data"] != "S0VZCg==" {
+		t.Errorf("Unexpected merged user: %+v", merged.Users[1])
+	}
+	if _, ok := merged.Extra["preferences"]; ok {
+		t.Errorf("Unexpected preferences copied from the source: %+v", merged.Extra)
+	}
+
+	info, _ := os.Stat(target)
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected kubeconfig permissions 0600, got %v", info.Mode().Perm())
+	}
+}
+
+func TestMergeKubeconfigNewFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	source := filepath.Join(tmpDir, "kubeconfig")
+	os.WriteFile(source, []byte(installerKubeconfig), 0600)
+
+	target := filepath.Join(tmpDir, "home", ".kube", "config")
+	if err := MergeKubeconfig(source, target, "dev"); err != nil {
+		t.Fatalf("Failed to merge kubeconfig: %v", err)
+	}
+	merged, err := readKubeconfig(target)
+	if err != nil {
+		t.Fatalf("Failed to read merged kubeconfig: %v", err)
+	}
+	if merged.CurrentContext != "dev" || merged.APIVersion != "v1" || merged.Kind != "Config" {
+		t.Errorf("Expected a new kubeconfig using the merged context, got %+v", merged)
+	}
+}
+
+func TestDefaultKubeconfigPath(t *testing.T) {
+	t.Setenv("KUBECONFIG", "/tmp/first"+string(os.PathListSeparator)+"/tmp/second")
+	if path, _ := DefaultKubeconfigPath(); path != "/tmp/first" {
+		t.Errorf("Expected the first KUBECONFIG file, got %s", path)
+	}
+
+	t.Setenv("KUBECONFIG", "")
+	t.Setenv("HOME", "/home/test")
+	if path, _ := DefaultKubeconfigPath(); path != "/home/test/.kube/config" {
+		t.Errorf("Expected ~/.kube/config, got %s", path)
+	}
+}
+
+func TestWriteTokenKubeconfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kubeconfig")
+	if err := WriteTokenKubeconfig(path, "https://api.example.com:6443", []byte("ca"), "admin", "sha256~token"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0600 {
+		t.Errorf("Expected kubeconfig permissions 0600, got %v", info.Mode().Perm())
+	}
+
+	server, caBundle, err := KubeconfigServer(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if server != "https://api.example.com:6443" || string(caBundle) != "ca" {
+		t.Errorf("Unexpected server %s and CA %q", server, caBundle)
+	}
+	if data, _ := os.ReadFile(path); !strings.Contains(string(data), "token: sha256~token") {
+		t.Errorf("Expected the token in the kubeconfig, got:\n%s", data)
+	}
+}
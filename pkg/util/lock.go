@@ -0,0 +1,136 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// ClusterLock is an advisory lock preventing concurrent runs against the same
+// cluster. The lock file holds the PID of the owner and is locked with flock,
+// so the lock is released by the kernel even if the owner dies.
+type ClusterLock struct {
+	file *os.File
+	path string
+}
+
+// LockedError reports that the cluster is locked by another process
+type LockedError struct {
+	Path  string
+	PID   int  // 0 if unknown
+	Stale bool // The owner PID is not running, but the file is still locked (e.g. network file system)
+}
+
+func (e *LockedError) Error() string {
+	owner := "another process"
+	if e.PID > 0 {
+		owner = fmt.Sprintf("process %d", e.PID)
+	}
+	if e.Stale {
+		return fmt.Sprintf("%s is held by %s, which is no longer running", e.Path, owner)
+	}
+	return fmt.Sprintf("%s is held by %s", e.Path, owner)
+}
+
+// GetClusterLockPath returns the path to the lock file of a cluster. It lives
+// next to the cluster directory, which may not exist yet.
+func GetClusterLockPath(clusterName string) string {
+	return filepath.Join("artifacts", "clusters", "."+clusterName+".lock")
+}
+
+// LockCluster takes the lock of a cluster without waiting
+func LockCluster(clusterName string) (*ClusterLock, error) {
+	path := GetClusterLockPath(clusterName)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create lock directory: %w", err)
+	}
+
+	for {
+		file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open lock file: %w", err)
+		}
+
+		if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+			file.Close()
+			if errors.Is(err, syscall.EWOULDBLOCK) {
+				pid := readLockPID(path)
+				return nil, &LockedError{Path: path, PID: pid, Stale: pid > 0 && !processRunning(pid)}
+			}
+			return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+		}
+
+		// The previous owner may have removed the file between our open and
+		// flock: retry on the new file so that two processes never both own it
+		if sameFile(file, path) {
+			lock := &ClusterLock{file: file, path: path}
+			if err := lock.writePID(); err != nil {
+				lock.Unlock()
+				return nil, err
+			}
+			return lock, nil
+		}
+		file.Close()
+	}
+}
+
+// Unlock releases the lock and removes the lock file, unless it was replaced
+// by the lock of another process after a forced unlock
+func (l *ClusterLock) Unlock() error {
+	if sameFile(l.file, l.path) {
+		os.Remove(l.path)
+	}
+	return l.file.Close()
+}
+
+// ForceUnlockCluster removes the lock file of a cluster, so that the next
+// LockCluster succeeds even if the lock is still held (e.g. by a hung process)
+func ForceUnlockCluster(clusterName string) error {
+	if err := os.Remove(GetClusterLockPath(clusterName)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove lock file: %w", err)
+	}
+	return nil
+}
+
+func (l *ClusterLock) writePID() error {
+	if err := l.file.Truncate(0); err != nil {
+		return fmt.Errorf("failed to write lock file: %w", err)
+	}
+	if _, err := l.file.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0); err != nil {
+		return fmt.Errorf("failed to write lock file: %w", err)
+	}
+	return nil
+}
+
+// readLockPID returns the PID recorded in a lock file (0 if unknown)
+func readLockPID(path string) int {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0
+	}
+	pid, _ := strconv.Atoi(strings.TrimSpace(string(data)))
+	return pid
+}
+
+// processRunning reports whether a process with the given PID exists
+func processRunning(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+// sameFile reports whether an open file is still the one at path
+func sameFile(file *os.File, path string) bool {
+	opened, err := file.Stat()
+	if err != nil {
+		return false
+	}
+	current, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	return os.SameFile(opened, current)
+}
@@ -0,0 +1,62 @@
+package util
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestClusterLock(t *testing.T) {
+	tmpDir := t.TempDir()
+	originalWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(originalWd)
+
+	lock, err := LockCluster("test-cluster")
+	if err != nil {
+		t.Fatalf("Failed to lock cluster: %v", err)
+	}
+
+	_, err = LockCluster("test-cluster")
+	var lockedErr *LockedError
+	if !errors.As(err, &lockedErr) {
+		t.Fatalf("Expected LockedError for a locked cluster, got %v", err)
+	}
+	if lockedErr.PID != os.Getpid() || lockedErr.Stale {
+		t.Errorf("Expected lock held by running process %d, got %+v", os.Getpid(), lockedErr)
+	}
+
+	// Other clusters are not affected
+	other, err := LockCluster("other-cluster")
+	if err != nil {
+		t.Fatalf("Failed to lock another cluster: %v", err)
+	}
+	other.Unlock()
+
+	if err := lock.Unlock(); err != nil {
+		t.Fatalf("Failed to unlock cluster: %v", err)
+	}
+	if FileExists(GetClusterLockPath("test-cluster")) {
+		t.Error("Lock file should be removed on unlock")
+	}
+
+	// A lock still held by a hung process can be forcibly removed
+	hung, err := LockCluster("test-cluster")
+	if err != nil {
+		t.Fatalf("Failed to lock cluster after unlock: %v", err)
+	}
+	if err := ForceUnlockCluster("test-cluster"); err != nil {
+		t.Fatalf("Failed to force unlock: %v", err)
+	}
+	forced, err := LockCluster("test-cluster")
+	if err != nil {
+		t.Fatalf("Failed to lock cluster after force unlock: %v", err)
+	}
+
+	// When the hung process ends, it must not remove the lock of the new owner
+	hung.Unlock()
+	if !FileExists(GetClusterLockPath("test-cluster")) {
+		t.Error("Lock file of the new owner should not be removed")
+	}
+	forced.Unlock()
+}
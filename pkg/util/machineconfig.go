@@ -0,0 +1,113 @@
+package util
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// machineConfigRoles are the pools the node customizations apply to
+var machineConfigRoles = []string{"master", "worker"}
+
+// NodeCustomizations are the node settings rendered into MachineConfigs
+type NodeCustomizations struct {
+	NTPServers         []string
+	KernelArguments    []string
+	InsecureRegistries []string
+}
+
+// RenderMachineConfigs returns the MachineConfig manifests of the node
+// customizations, for the master and worker pools, by file name (for the
+// openshift/ directory of the installer)
+func RenderMachineConfigs(nodes NodeCustomizations) (map[string][]byte, error) {
+	manifests := map[string][]byte{}
+	for _, role := range machineConfigRoles {
+		if len(nodes.NTPServers) > 0 {
+			name := fmt.Sprintf("99-%s-chrony", role)
+			spec := ignitionFilesSpec(map[string]string{"/etc/chrony.conf": chronyConf(nodes.NTPServers)})
+			if err := addMachineConfig(manifests, name, role, spec); err != nil {
+				return nil, err
+			}
+		}
+		if len(nodes.KernelArguments) > 0 {
+			name := fmt.Sprintf("99-%s-kernel-args", role)
+			spec := map[string]interface{}{"kernelArguments": nodes.KernelArguments}
+			if err := addMachineConfig(manifests, name, role, spec); err != nil {
+				return nil, err
+			}
+		}
+		if len(nodes.InsecureRegistries) > 0 {
+			name := fmt.Sprintf("99-%s-insecure-registries", role)
+			spec := ignitionFilesSpec(map[string]string{"/etc/containers/registries.conf.d/99-insecure-registries.conf": insecureRegistriesConf(nodes.InsecureRegistries)})
+			if err := addMachineConfig(manifests, name, role, spec); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return manifests, nil
+}
+
+// addMachineConfig renders a MachineConfig of a pool into the manifests
+func addMachineConfig(manifests map[string][]byte, name, role string, spec map[string]interface{}) error {
+	machineConfig := map[string]interface{}{
+		"apiVersion": "machineconfiguration.openshift.io/v1",
+		"kind":       "MachineConfig",
+		"metadata": map[string]interface{}{
+			"name":   name,
+			"labels": map[string]string{"machineconfiguration.openshift.io/role": role},
+		},
+		"spec": spec,
+	}
+	data, err := yaml.Marshal(machineConfig)
+	if err != nil {
+		return fmt.Errorf("failed to render MachineConfig %s: %w", name, err)
+	}
+	manifests[name+".yaml"] = data
+	return nil
+}
+
+// ignitionFilesSpec returns a MachineConfig spec writing files (path -> content)
+func ignitionFilesSpec(files map[string]string) map[string]interface{} {
+	var entries []interface{}
+	for _, path := range sortedKeys(files) {
+		entries = append(entries, map[string]interface{}{
+			"path":      path,
+			"mode":      0644,
+			"overwrite": true,
+			"contents": map[string]string{
+				"source": "data:text/plain;charset=utf-8;base64," + base64.StdEncoding.EncodeToString([]byte(files[path])),
+			},
+		})
+	}
+	return map[string]interface{}{
+		"config": map[string]interface{}{
+			"ignition": map[string]string{"version": "3.2.0"},
+			"storage":  map[string]interface{}{"files": entries},
+		},
+	}
+}
+
+// chronyConf returns a chrony configuration using the NTP servers
+func chronyConf(servers []string) string {
+	var conf strings.Builder
+	for _, server := range servers {
+		fmt.Fprintf(&conf, "server %s iburst\n", server)
+	}
+	conf.WriteString("driftfile /var/lib/chrony/drift\nmakestep 1.0 3\nrtcsync\nlogdir /var/log/chrony\n")
+	return conf.String()
+}
+
+// insecureRegistriesConf returns a containers registries.conf drop-in allowing
+// plain HTTP and unverified TLS for the registries
+func insecureRegistriesConf(registries []string) string {
+	var conf strings.Builder
+	for i, registry := range registries {
+		if i > 0 {
+			conf.WriteString("\n")
+		}
+		fmt.Fprintf(&conf, "[[registry]]\nlocation = %q\ninsecure = true\n", registry)
+	}
+	return conf.String()
+}
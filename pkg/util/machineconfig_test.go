@@ -0,0 +1,75 @@
+package util
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestRenderMachineConfigs(t *testing.T) {
+	manifests, err := RenderMachineConfigs(NodeCustomizations{
+		NTPServers:         []string{"ntp1.example.com", "ntp2.example.com"},
+		KernelArguments:    []string{"nosmt", "mitigations=auto"},
+		InsecureRegistries: []string{"registry.example.com:5000"},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(manifests) != 6 {
+		t.Errorf("Expected 3 MachineConfigs per pool, got %d", len(manifests))
+	}
+
+	var chrony struct {
+		Kind     string `yaml:"kind"`
+		Metadata struct {
+			Name   string            `yaml:"name"`
+			Labels map[string]string `yaml:"labels"`
+		} `yaml:"metadata"`
+		Spec struct {
+			Config struct {
+				Storage struct {
+					Files []struct {
+						Path     string `yaml:"path"`
+						Mode     int    `yaml:"mode"`
+						Contents struct {
+							Source string `yaml:"source"`
+						} `yaml:"contents"`
+					} `yaml:"files"`
+				} `yaml:"storage"`
+			} `yaml:"config"`
+		} `yaml:"spec"`
+	}
+	if err := yaml.Unmarshal(manifests["99-worker-chrony.yaml"], &chrony); err != nil {
+		t.Fatalf("Failed to parse the chrony MachineConfig: %v", err)
+	}
+	if chrony.Kind != "MachineConfig" || chrony.Metadata.Labels["machineconfiguration.openshift.io/role"] != "worker" {
+		t.Errorf("Unexpected MachineConfig %s", manifests["99-worker-chrony.yaml"])
+	}
+	files := chrony.Spec.Config.Storage.Files
+	if len(files) != 1 || files[0].Path != "/etc/chrony.conf" || files[0].Mode != 0644 {
+		t.Fatalf("Unexpected files %+v", files)
+	}
+	content, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(files[0].Contents.Source, "data:text/plain;charset=utf-8;base64,"))
+	if !strings.HasPrefix(string(content), "server ntp1.example.com iburst\nserver ntp2.example.com iburst\n") {
+		t.Errorf("Unexpected chrony.conf %q", content)
+	}
+
+	var kernelArgs struct {
+		Spec struct {
+			KernelArguments []string `yaml:"kernelArguments"`
+		} `yaml:"spec"`
+	}
+	yaml.Unmarshal(manifests["99-master-kernel-args.yaml"], &kernelArgs)
+	if strings.Join(kernelArgs.Spec.KernelArguments, " ") != "nosmt mitigations=auto" {
+		t.Errorf("Unexpected kernel arguments %v", kernelArgs.Spec.KernelArguments)
+	}
+	if !strings.Contains(string(manifests["99-master-insecure-registries.yaml"]), "/etc/containers/registries.conf.d/99-insecure-registries.conf") {
+		t.Errorf("Unexpected registries MachineConfig %s", manifests["99-master-insecure-registries.yaml"])
+	}
+
+	if manifests, _ := RenderMachineConfigs(NodeCustomizations{}); len(manifests) != 0 {
+		t.Errorf("Expected no MachineConfig without customizations, got %d", len(manifests))
+	}
+}
@@ -0,0 +1,88 @@
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// machineAPINamespace is where the MachineSets of a cluster live
+const machineAPINamespace = "openshift-machine-api"
+
+// MachineSet is the scaling status of a MachineSet
+type MachineSet struct {
+	Name          string
+	Replicas      int // Desired
+	Current       int // Machines that exist
+	ReadyReplicas int // Machines whose node is Ready
+}
+
+type machineSetList struct {
+	Items []struct {
+		Metadata struct {
+			Name string `json:"name"`
+		} `json:"metadata"`
+		Spec struct {
+			Replicas *int `json:"replicas"`
+			Template struct {
+				Metadata struct {
+					Labels map[string]string `json:"labels"`
+				} `json:"metadata"`
+			} `json:"template"`
+		} `json:"spec"`
+		Status struct {
+			Replicas      int `json:"replicas"`
+			ReadyReplicas int `json:"readyReplicas"`
+		} `json:"status"`
+	} `json:"items"`
+}
+
+// GetWorkerMachineSets returns the MachineSets creating worker machines, sorted by name
+func GetWorkerMachineSets(executor CommandExecutor, kubeconfigPath string) ([]MachineSet, error) {
+	env := []string{fmt.Sprintf("KUBECONFIG=%s", kubeconfigPath)}
+	output, err := executor.ExecuteWithEnv("oc", env, "get", "machinesets", "-n", machineAPINamespace, "-o", "json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get machinesets: %w", err)
+	}
+	return parseWorkerMachineSets([]byte(output))
+}
+
+func parseWorkerMachineSets(data []byte) ([]MachineSet, error) {
+	var list machineSetList
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse machinesets: %w", err)
+	}
+
+	var machineSets []MachineSet
+	for _, item := range list.Items {
+		if item.Spec.Template.Metadata.Labels["machine.openshift.io/cluster-api-machine-role"] != "worker" {
+			continue
+		}
+		machineSet := MachineSet{Name: item.Metadata.Name, Current: item.Status.Replicas, ReadyReplicas: item.Status.ReadyReplicas}
+		if item.Spec.Replicas != nil {
+			machineSet.Replicas = *item.Spec.Replicas
+		}
+		machineSets = append(machineSets, machineSet)
+	}
+	sort.Slice(machineSets, func(i, j int) bool { return machineSets[i].Name < machineSets[j].Name })
+	return machineSets, nil
+}
+
+// ScaleMachineSet sets the replicas of a MachineSet
+func ScaleMachineSet(executor CommandExecutor, kubeconfigPath, name string, replicas int) error {
+	env := []string{fmt.Sprintf("KUBECONFIG=%s", kubeconfigPath)}
+	return RunCommandWithEnv(executor, env, "oc", "scale", "machineset", name, "-n", machineAPINamespace, fmt.Sprintf("--replicas=%d", replicas))
+}
+
+// DistributeReplicas spreads replicas over n MachineSets (one per zone) as
+// evenly as possible, the first ones getting the remainder, as the installer does
+func DistributeReplicas(replicas, n int) []int {
+	distribution := make([]int, n)
+	for i := range distribution {
+		distribution[i] = replicas / n
+		if i < replicas%n {
+			distribution[i]++
+		}
+	}
+	return distribution
+}
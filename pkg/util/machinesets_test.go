@@ -0,0 +1,47 @@
+package util
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseWorkerMachineSets(t *testing.T) {
+	machineSets, err := parseWorkerMachineSets([]byte(`{"items": [
+  {"metadata": {"name": "test-x7k2p-worker-us-east-2b"},
+   "spec": {"replicas": 1, "template": {"metadata": {"labels": {"machine.openshift.io/cluster-api-machine-role": "worker"}}}},
+   "status": {"replicas": 1, "readyReplicas": 0}},
+  {"metadata": {"name": "test-x7k2p-infra-us-east-2a"},
+   "spec": {"replicas": 2, "template": {"metadata": {"labels": {"machine.openshift.io/cluster-api-machine-role": "infra"}}}}},
+  {"metadata": {"name": "test-x7k2p-worker-us-east-2a"},
+   "spec": {"replicas": 2, "template": {"metadata": {"labels": {"machine.openshift.io/cluster-api-machine-role": "worker"}}}},
+   "status": {"replicas": 2, "readyReplicas": 2}}
+]}`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []MachineSet{
+		{Name: "test-x7k2p-worker-us-east-2a", Replicas: 2, Current: 2, ReadyReplicas: 2},
+		{Name: "test-x7k2p-worker-us-east-2b", Replicas: 1, Current: 1, ReadyReplicas: 0},
+	}
+	if !reflect.DeepEqual(machineSets, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, machineSets)
+	}
+}
+
+func TestDistributeReplicas(t *testing.T) {
+	tests := []struct {
+		replicas, n int
+		expected    []int
+	}{
+		{5, 3, []int{2, 2, 1}},
+		{6, 3, []int{2, 2, 2}},
+		{1, 3, []int{1, 0, 0}},
+		{0, 2, []int{0, 0}},
+	}
+	for _, test := range tests {
+		if got := DistributeReplicas(test.replicas, test.n); !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("DistributeReplicas(%d, %d) = %v, expected %v", test.replicas, test.n, got, test.expected)
+		}
+	}
+}
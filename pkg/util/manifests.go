@@ -0,0 +1,164 @@
+package util
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ExtraManifest is a manifest of the extra manifests directory, and the
+// installer directory (manifests or openshift) it is copied to
+type ExtraManifest struct {
+	Path   string
+	Target string
+}
+
+// ReadExtraManifests lists and validates the YAML files of an extra manifests
+// directory. Files at its top level and in its manifests/ subdirectory go to
+// the manifests/ directory of the installer, files in its openshift/
+// subdirectory go to the openshift/ directory.
+func ReadExtraManifests(dir string) ([]ExtraManifest, error) {
+	if !DirExists(dir) {
+		return nil, fmt.Errorf("extra manifests directory %s does not exist", dir)
+	}
+
+	var manifests []ExtraManifest
+	for _, source := range []struct{ dir, target string }{
+		{dir, "manifests"},
+		{filepath.Join(dir, "manifests"), "manifests"},
+		{filepath.Join(dir, "openshift"), "openshift"},
+	} {
+		entries, err := os.ReadDir(source.dir)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to read extra manifests: %w", err)
+		}
+		for _, entry := range entries {
+			ext := strings.ToLower(filepath.Ext(entry.Name()))
+			if entry.IsDir() || (ext != ".yaml" && ext != ".yml") {
+				continue
+			}
+			path := filepath.Join(source.dir, entry.Name())
+			if err := ValidateManifest(path); err != nil {
+				return nil, err
+			}
+			manifests = append(manifests, ExtraManifest{Path: path, Target: source.target})
+		}
+	}
+
+	// Two manifests with the same name would overwrite each other
+	seen := map[string]string{}
+	for _, manifest := range manifests {
+		target := filepath.Join(manifest.Target, filepath.Base(manifest.Path))
+		if other, ok := seen[target]; ok {
+			return nil, fmt.Errorf("extra manifests %s and %s are both copied to %s", other, manifest.Path, target)
+		}
+		seen[target] = manifest.Path
+	}
+	sort.Slice(manifests, func(i, j int) bool { return manifests[i].Path < manifests[j].Path })
+	return manifests, nil
+}
+
+// ValidateManifest checks that every document of a YAML file is a Kubernetes
+// object (with apiVersion and kind)
+func ValidateManifest(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	objects := 0
+	for {
+		var object map[string]interface{}
+		err := decoder.Decode(&object)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("invalid manifest %s: %w", path, err)
+		}
+		if object == nil {
+			continue // Empty document
+		}
+		objects++
+		if object["apiVersion"] == nil || object["kind"] == nil {
+			return fmt.Errorf("invalid manifest %s: document %d has no apiVersion or kind", path, objects)
+		}
+	}
+	if objects == 0 {
+		return fmt.Errorf("invalid manifest %s: no Kubernetes object", path)
+	}
+	return nil
+}
+
+// CopyExtraManifests copies the manifests of an extra manifests directory into
+// the installer directory of a cluster. A manifest may not replace one of the
+// installer or ccoctl, unless it is the same file (e.g. copied by an earlier
+// run) or its path relative to clusterDir is in replaceable: nothing is copied
+// then.
+func CopyExtraManifests(dir, clusterDir string, replaceable map[string]bool) ([]ExtraManifest, error) {
+	manifests, err := ReadExtraManifests(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, manifest := range manifests {
+		rel := filepath.Join(manifest.Target, filepath.Base(manifest.Path))
+		existing, err := os.ReadFile(filepath.Join(clusterDir, rel))
+		if err != nil || replaceable[rel] {
+			continue
+		}
+		if content, err := os.ReadFile(manifest.Path); err != nil || !bytes.Equal(content, existing) {
+			return nil, fmt.Errorf("extra manifest %s would replace %s of the installer or ccoctl: rename it", manifest.Path, rel)
+		}
+	}
+	for _, manifest := range manifests {
+		if err := EnsureDir(filepath.Join(clusterDir, manifest.Target)); err != nil {
+			return nil, err
+		}
+		if err := CopyFile(manifest.Path, filepath.Join(clusterDir, manifest.Target, filepath.Base(manifest.Path))); err != nil {
+			return nil, fmt.Errorf("failed to copy extra manifest %s: %w", manifest.Path, err)
+		}
+	}
+	return manifests, nil
+}
+
+// ReadPostInstallManifests lists and validates the YAML files at the top level
+// of a post-install manifests directory, in the order they are applied: by
+// name, so that e.g. 00-namespace.yaml comes before 10-deployment.yaml
+func ReadPostInstallManifests(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("post-install manifests directory %s does not exist", dir)
+		}
+		return nil, fmt.Errorf("failed to read post-install manifests: %w", err)
+	}
+
+	// ReadDir sorts the entries by name
+	var manifests []string
+	for _, entry := range entries {
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		if entry.IsDir() || (ext != ".yaml" && ext != ".yml") {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		if err := ValidateManifest(path); err != nil {
+			return nil, err
+		}
+		manifests = append(manifests, path)
+	}
+	if len(manifests) == 0 {
+		return nil, fmt.Errorf("no YAML manifests in %s", dir)
+	}
+	return manifests, nil
+}
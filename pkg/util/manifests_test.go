@@ -0,0 +1,121 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testConfigMap = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: cluster-monitoring-config
+  namespace: openshift-monitoring
+data:
+  config.yaml: |
+    enableUserWorkload: true
+`
+
+func TestCopyExtraManifests(t *testing.T) {
+	dir := t.TempDir()
+	clusterDir := t.TempDir()
+
+	os.WriteFile(filepath.Join(dir, "cluster-monitoring-config.yaml"), []byte(testConfigMap), 0644)
+	os.WriteFile(filepath.Join(dir, "README.md"), []byte("not a manifest"), 0644)
+	os.MkdirAll(filepath.Join(dir, "openshift"), 0755)
+	os.WriteFile(filepath.Join(dir, "openshift", "99-worker-chrony.yaml"), []byte(`---
+apiVersion: machineconfiguration.openshift.io/v1
+kind: MachineConfig
+metadata:
+  name: 99-worker-chrony
+---
+apiVersion: machineconfiguration.openshift.io/v1
+kind: MachineConfig
+metadata:
+  name: 99-master-chrony
+`), 0644)
+
+	manifests, err := CopyExtraManifests(dir, clusterDir, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(manifests) != 2 {
+		t.Errorf("Expected 2 manifests, got %+v", manifests)
+	}
+	for _, path := range []string{"manifests/cluster-monitoring-config.yaml", "openshift/99-worker-chrony.yaml"} {
+		if !FileExists(filepath.Join(clusterDir, path)) {
+			t.Errorf("Expected %s to be copied", path)
+		}
+	}
+	if FileExists(filepath.Join(clusterDir, "manifests", "README.md")) {
+		t.Error("Expected only YAML files to be copied")
+	}
+
+	// Copying again the same files is no collision
+	if _, err := CopyExtraManifests(dir, clusterDir, nil); err != nil {
+		t.Errorf("Unexpected error copying the manifests again: %v", err)
+	}
+
+	// Manifests of the installer are not replaced, unless replaceable
+	installerManifest := filepath.Join(clusterDir, "openshift", "99-worker-chrony.yaml")
+	os.WriteFile(installerManifest, []byte("rendered"), 0644)
+	if _, err := CopyExtraManifests(dir, clusterDir, nil); err == nil || !strings.Contains(err.Error(), "would replace openshift/99-worker-chrony.yaml") {
+		t.Errorf("Expected a collision error, got %v", err)
+	}
+	if content, _ := os.ReadFile(installerManifest); string(content) != "rendered" {
+		t.Error("Expected nothing to be copied on a collision")
+	}
+	if _, err := CopyExtraManifests(dir, clusterDir, map[string]bool{"openshift/99-worker-chrony.yaml": true}); err != nil {
+		t.Errorf("Unexpected error replacing a replaceable manifest: %v", err)
+	}
+}
+
+func TestReadExtraManifestsInvalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    map[string]string
+		expected string
+	}{
+		{"unparsable", map[string]string{"bad.yaml": "kind: [ConfigMap"}, "invalid manifest"},
+		{"not an object", map[string]string{"list.yaml": "apiVersion: v1\n---\nfoo: bar\n"}, "has no apiVersion or kind"},
+		{"empty", map[string]string{"empty.yaml": "---\n"}, "no Kubernetes object"},
+		{"same name", map[string]string{"cm.yaml": testConfigMap, "manifests/cm.yaml": testConfigMap}, "are both copied to"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tt.files {
+				os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0755)
+				os.WriteFile(filepath.Join(dir, name), []byte(content), 0644)
+			}
+			if _, err := ReadExtraManifests(dir); err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("Expected an error containing %q, got %v", tt.expected, err)
+			}
+		})
+	}
+
+	if _, err := ReadExtraManifests(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("Expected an error for a missing directory")
+	}
+}
+
+func TestReadPostInstallManifests(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "10-config.yaml"), []byte(testConfigMap), 0644)
+	os.WriteFile(filepath.Join(dir, "00-namespace.yml"), []byte("apiVersion: v1\nkind: Namespace\nmetadata:\n  name: demo\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not a manifest"), 0644)
+
+	manifests, err := ReadPostInstallManifests(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := []string{filepath.Join(dir, "00-namespace.yml"), filepath.Join(dir, "10-config.yaml")}
+	if strings.Join(manifests, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected %v, got %v", expected, manifests)
+	}
+
+	if _, err := ReadPostInstallManifests(t.TempDir()); err == nil || !strings.Contains(err.Error(), "no YAML manifests") {
+		t.Errorf("Expected an error for an empty directory, got %v", err)
+	}
+}
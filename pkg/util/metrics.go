@@ -0,0 +1,143 @@
+package util
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// metricsJob is the Pushgateway job metrics are grouped under
+const metricsJob = "openshift-sts-wrapper"
+
+// RunMetrics describes an install run for FormatMetrics
+type RunMetrics struct {
+	Release    string // Release version and architecture (e.g. 4.12.0-x86_64)
+	Succeeded  bool
+	Duration   time.Duration
+	FinishedAt time.Time
+}
+
+// FormatMetrics renders the outcome of an install run and the step counters of
+// the journal in the Prometheus text exposition format. Every sample is
+// labeled with the cluster name and release.
+func FormatMetrics(journal *Journal, run RunMetrics) string {
+	var b strings.Builder
+	labels := fmt.Sprintf(`cluster="%s",release="%s"`, escapeLabel(journal.clusterName), escapeLabel(run.Release))
+
+	writeMetric := func(name, kind, help string, samples func()) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+		samples()
+	}
+
+	writeMetric("openshift_sts_install_success", "gauge", "Whether the last install run succeeded.", func() {
+		fmt.Fprintf(&b, "openshift_sts_install_success{%s} %d\n", labels, boolValue(run.Succeeded))
+	})
+	writeMetric("openshift_sts_install_duration_seconds", "gauge", "Duration of the last install run.", func() {
+		fmt.Fprintf(&b, "openshift_sts_install_duration_seconds{%s} %g\n", labels, run.Duration.Seconds())
+	})
+	writeMetric("openshift_sts_install_last_run_timestamp_seconds", "gauge", "Time the last install run finished.", func() {
+		fmt.Fprintf(&b, "openshift_sts_install_last_run_timestamp_seconds{%s} %d\n", labels, run.FinishedAt.Unix())
+	})
+
+	journal.mu.Lock()
+	defer journal.mu.Unlock()
+	var steps []string
+	for step := range journal.Steps {
+		steps = append(steps, step)
+	}
+	sort.Strings(steps)
+
+	stepMetric := func(name, kind, help string, value func(record *StepRecord) (float64, bool)) {
+		writeMetric(name, kind, help, func() {
+			for _, step := range steps {
+				if v, ok := value(journal.Steps[step]); ok {
+					fmt.Fprintf(&b, "%s{%s,step=\"%s\"} %g\n", name, labels, escapeLabel(step), v)
+				}
+			}
+		})
+	}
+
+	stepMetric("openshift_sts_step_duration_seconds", "gauge", "Duration of the last run of the step.", func(record *StepRecord) (float64, bool) {
+		return record.Duration, record.FinishedAt != nil
+	})
+	stepMetric("openshift_sts_step_success", "gauge", "Whether the last run of the step succeeded.", func(record *StepRecord) (float64, bool) {
+		return float64(boolValue(record.Status == StepSucceeded)), record.FinishedAt != nil
+	})
+	stepMetric("openshift_sts_step_attempts_total", "counter", "Times the step was started for the release.", func(record *StepRecord) (float64, bool) {
+		return float64(record.Attempts), true
+	})
+	stepMetric("openshift_sts_step_failures_total", "counter", "Times the step failed for the release.", func(record *StepRecord) (float64, bool) {
+		return float64(record.Failures), true
+	})
+	stepMetric("openshift_sts_step_retries_total", "counter", "Times the step was run again after its first attempt.", func(record *StepRecord) (float64, bool) {
+		if record.Attempts == 0 {
+			return 0, true
+		}
+		return float64(record.Attempts - 1), true
+	})
+
+	return b.String()
+}
+
+// PushMetrics replaces the metrics of a cluster on a Prometheus Pushgateway
+func PushMetrics(gatewayURL, clusterName, metrics string) error {
+	target := strings.TrimSuffix(gatewayURL, "/") + "/metrics/job/" + metricsJob + "/cluster/" + url.PathEscape(clusterName)
+	req, err := http.NewRequest(http.MethodPut, target, strings.NewReader(metrics))
+	if err != nil {
+		return fmt.Errorf("invalid Pushgateway URL: %w", err)
+	}
+	req.Header.Set("Content-Type", "text/plain; version=0.0.4")
+
+	client := &http.Client{Timeout: 15 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		// The URL may embed basic auth credentials, keep it out of the error
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("failed to push metrics: %w", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("Pushgateway returned %s: %s", resp.Status, bytes.TrimSpace(body))
+	}
+	return nil
+}
+
+// WriteMetricsFile writes metrics for the node_exporter textfile collector.
+// The file is replaced atomically so that the collector never reads a partial file.
+func WriteMetricsFile(path, metrics string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create metrics directory: %w", err)
+	}
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, []byte(metrics), 0644); err != nil {
+		return fmt.Errorf("failed to write metrics file: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to write metrics file: %w", err)
+	}
+	return nil
+}
+
+func escapeLabel(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+func boolValue(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
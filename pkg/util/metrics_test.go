@@ -0,0 +1,98 @@
+package util
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormatMetrics(t *testing.T) {
+	tmpDir := t.TempDir()
+	originalWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(originalWd)
+
+	journal := OpenJournal("test-cluster", "quay.io/test:4.12.0-x86_64", "")
+	journal.StartStep("create-aws-resources")
+	journal.FinishStep("create-aws-resources", fmt.Errorf("quota exceeded"))
+	journal.StartStep("create-aws-resources")
+	journal.FinishStep("create-aws-resources", nil)
+	journal.StartStep("deploy-cluster")
+
+	metrics := FormatMetrics(journal, RunMetrics{
+		Release:    "4.12.0-x86_64",
+		Succeeded:  false,
+		Duration:   90 * time.Second,
+		FinishedAt: time.Unix(1700000000, 0),
+	})
+
+	labels := `cluster="test-cluster",release="4.12.0-x86_64"`
+	for _, expected := range []string{
+		"# TYPE openshift_sts_install_success gauge",
+		"openshift_sts_install_success{" + labels + "} 0",
+		"openshift_sts_install_duration_seconds{" + labels + "} 90",
+		"openshift_sts_install_last_run_timestamp_seconds{" + labels + "} 1700000000",
+		"# TYPE openshift_sts_step_attempts_total counter",
+		"openshift_sts_step_success{" + labels + `,step="create-aws-resources"} 1`,
+		"openshift_sts_step_attempts_total{" + labels + `,step="create-aws-resources"} 2`,
+		"openshift_sts_step_failures_total{" + labels + `,step="create-aws-resources"} 1`,
+		"openshift_sts_step_retries_total{" + labels + `,step="create-aws-resources"} 1`,
+		"openshift_sts_step_attempts_total{" + labels + `,step="deploy-cluster"} 1`,
+	} {
+		if !strings.Contains(metrics, expected+"\n") {
+			t.Errorf("Expected metrics to contain %q, got:\n%s", expected, metrics)
+		}
+	}
+
+	// Steps still running have no duration or result yet
+	if strings.Contains(metrics, `openshift_sts_step_success{`+labels+`,step="deploy-cluster"}`) {
+		t.Errorf("Expected no result for a running step, got:\n%s", metrics)
+	}
+}
+
+func TestEscapeLabel(t *testing.T) {
+	if got := escapeLabel("a\"b\\c\nd"); got != `a\"b\\c\nd` {
+		t.Errorf("Unexpected escaped label: %s", got)
+	}
+}
+
+func TestPushMetrics(t *testing.T) {
+	var method, path, body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, path = r.Method, r.URL.Path
+		data, _ := io.ReadAll(r.Body)
+		body = string(data)
+		if strings.Contains(r.URL.Path, "fail") {
+			http.Error(w, "text format parsing error", http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	if err := PushMetrics(server.URL+"/", "test-cluster", "metric 1\n"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if method != http.MethodPut || path != "/metrics/job/openshift-sts-wrapper/cluster/test-cluster" || body != "metric 1\n" {
+		t.Errorf("Unexpected request: %s %s %q", method, path, body)
+	}
+
+	if err := PushMetrics(server.URL, "fail", "metric\n"); err == nil || !strings.Contains(err.Error(), "parsing error") {
+		t.Errorf("Expected error for non-2xx status, got %v", err)
+	}
+}
+
+func TestWriteMetricsFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "textfile", "openshift-sts.prom")
+	if err := WriteMetricsFile(path, "metric 1\n"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != "metric 1\n" {
+		t.Errorf("Unexpected metrics file content %q: %v", data, err)
+	}
+}
@@ -0,0 +1,143 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// artifactsLayoutNames are the entries of the artifacts directory that belong
+// to the current layout
+var artifactsLayoutNames = map[string]bool{"shared": true, "clusters": true, "audit": true, "batch": true}
+
+// LegacyArtifacts is a directory of the legacy artifacts layout,
+// artifacts/<versionArch>/, which held the binaries and credentials requests of
+// a release next to the installation files of a cluster
+type LegacyArtifacts struct {
+	VersionArch string
+	Path        string
+	ClusterName string // Cluster installed from the directory, empty if none
+}
+
+// ArtifactMove is a file or directory moved by a migration
+type ArtifactMove struct {
+	From    string
+	To      string
+	Skipped string // Why it was not moved
+}
+
+// FindLegacyArtifacts returns the directories of the legacy layout, sorted by
+// versionArch
+func FindLegacyArtifacts() ([]LegacyArtifacts, error) {
+	entries, err := os.ReadDir("artifacts")
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read artifacts directory: %w", err)
+	}
+
+	var legacy []LegacyArtifacts
+	for _, entry := range entries {
+		if !entry.IsDir() || artifactsLayoutNames[entry.Name()] {
+			continue
+		}
+		path := filepath.Join("artifacts", entry.Name())
+		if !isLegacyArtifactsDir(path) {
+			continue
+		}
+		legacy = append(legacy, LegacyArtifacts{
+			VersionArch: entry.Name(),
+			Path:        path,
+			ClusterName: legacyClusterName(path),
+		})
+	}
+	return legacy, nil
+}
+
+// isLegacyArtifactsDir reports whether a directory has the content of a
+// legacy artifacts/<versionArch> directory
+func isLegacyArtifactsDir(path string) bool {
+	return DirExists(filepath.Join(path, "bin")) ||
+		DirExists(filepath.Join(path, "credreqs")) ||
+		FileExists(filepath.Join(path, "install-config.yaml")) ||
+		FileExists(filepath.Join(path, "install-config.yaml.backup")) ||
+		FileExists(filepath.Join(path, "metadata.json"))
+}
+
+// legacyClusterName returns the name of the cluster installed from a legacy
+// directory, read from metadata.json or install-config.yaml (consumed by
+// openshift-install, but kept as a backup)
+func legacyClusterName(path string) string {
+	if metadata, err := ReadClusterMetadata(path); err == nil && metadata.ClusterName != "" {
+		return metadata.ClusterName
+	}
+	for _, name := range []string{"install-config.yaml", "install-config.yaml.backup"} {
+		if installConfig, err := ReadInstallConfig(filepath.Join(path, name)); err == nil && installConfig.Metadata.Name != "" {
+			return installConfig.Metadata.Name
+		}
+	}
+	return ""
+}
+
+// MigrateLegacyArtifacts moves a legacy directory into the current layout:
+// bin/ and credreqs/ into artifacts/shared/<versionArch>/, everything else
+// into the directory of its cluster, whose install metadata records the
+// release so that cleanup finds its binaries. Nothing is overwritten: entries
+// whose destination exists are left in place, and so is the legacy directory
+// until it is empty. With dryRun, the moves are only returned.
+func MigrateLegacyArtifacts(legacy LegacyArtifacts, dryRun bool) ([]ArtifactMove, error) {
+	entries, err := os.ReadDir(legacy.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", legacy.Path, err)
+	}
+
+	var moves []ArtifactMove
+	clusterMoved := false
+	for _, entry := range entries {
+		move := ArtifactMove{From: filepath.Join(legacy.Path, entry.Name())}
+		toCluster := false
+		switch {
+		case entry.IsDir() && (entry.Name() == "bin" || entry.Name() == "credreqs"):
+			move.To = filepath.Join(filepath.Dir(GetSharedCredReqsPath(legacy.VersionArch)), entry.Name())
+		case legacy.ClusterName == "":
+			move.Skipped = "no cluster name in metadata.json or install-config.yaml"
+		default:
+			move.To = GetClusterPath(legacy.ClusterName, entry.Name())
+			toCluster = true
+		}
+		if move.To != "" {
+			if _, err := os.Lstat(move.To); err == nil {
+				move.Skipped = move.To + " already exists"
+			}
+		}
+
+		if move.Skipped == "" && !dryRun {
+			if err := EnsureDir(filepath.Dir(move.To)); err != nil {
+				return moves, err
+			}
+			if err := os.Rename(move.From, move.To); err != nil {
+				return moves, fmt.Errorf("failed to move %s: %w", move.From, err)
+			}
+			clusterMoved = clusterMoved || toCluster
+		}
+		moves = append(moves, move)
+	}
+	if dryRun {
+		return moves, nil
+	}
+
+	if clusterMoved {
+		clusterDir := GetClusterPath(legacy.ClusterName, "")
+		if metadata, err := ReadInstallMetadata(clusterDir); err != nil || metadata.ReleaseImage == "" {
+			// The legacy layout only kept the tag of the release image
+			if err := SaveInstallMetadata(clusterDir, ReleaseImageRepository+":"+legacy.VersionArch, ""); err != nil {
+				return moves, err
+			}
+		}
+	}
+	// Left in place when something could not be moved
+	os.Remove(legacy.Path)
+	return moves, nil
+}
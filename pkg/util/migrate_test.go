@@ -0,0 +1,89 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMigrateLegacyArtifacts(t *testing.T) {
+	tmpDir := t.TempDir()
+	originalWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(originalWd)
+
+	legacyDir := filepath.Join("artifacts", "4.12.0-x86_64")
+	os.MkdirAll(filepath.Join(legacyDir, "bin"), 0755)
+	os.WriteFile(filepath.Join(legacyDir, "bin", "ccoctl"), []byte("ccoctl"), 0755)
+	os.MkdirAll(filepath.Join(legacyDir, "credreqs"), 0755)
+	os.MkdirAll(filepath.Join(legacyDir, "auth"), 0755)
+	os.WriteFile(filepath.Join(legacyDir, "metadata.json"), []byte(`{"clusterName": "old-cluster", "infraID": "old-cluster-x7k2p"}`), 0644)
+	os.MkdirAll(GetClusterPath("other-cluster", ""), 0755)
+	os.MkdirAll(filepath.Join("artifacts", "shared", "4.13.0-x86_64", "bin"), 0755)
+
+	legacy, err := FindLegacyArtifacts()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(legacy) != 1 || legacy[0].VersionArch != "4.12.0-x86_64" || legacy[0].ClusterName != "old-cluster" {
+		t.Fatalf("Expected the legacy directory of old-cluster, got %+v", legacy)
+	}
+
+	moves, err := MigrateLegacyArtifacts(legacy[0], true)
+	if err != nil || len(moves) != 4 {
+		t.Fatalf("Expected 4 moves, got %+v (%v)", moves, err)
+	}
+	if !DirExists(filepath.Join(legacyDir, "bin")) {
+		t.Error("Expected a dry run to move nothing")
+	}
+
+	if _, err := MigrateLegacyArtifacts(legacy[0], false); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !FileExists(GetSharedBinaryPath("4.12.0-x86_64", "ccoctl")) || !DirExists(GetSharedCredReqsPath("4.12.0-x86_64")) {
+		t.Error("Expected the binaries and credentials requests in the shared artifacts")
+	}
+	if !FileExists(GetClusterPath("old-cluster", "metadata.json")) || !DirExists(GetClusterPath("old-cluster", "auth")) {
+		t.Error("Expected the cluster files in the cluster directory")
+	}
+	if DirExists(legacyDir) {
+		t.Error("Expected the emptied legacy directory to be removed")
+	}
+
+	metadata, err := ReadInstallMetadata(GetClusterPath("old-cluster", ""))
+	if err != nil {
+		t.Fatalf("Expected install metadata: %v", err)
+	}
+	if metadata.ReleaseImage != ReleaseImageRepository+":4.12.0-x86_64" {
+		t.Errorf("Unexpected release image %q", metadata.ReleaseImage)
+	}
+}
+
+func TestMigrateLegacyArtifactsKeepsExisting(t *testing.T) {
+	tmpDir := t.TempDir()
+	originalWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(originalWd)
+
+	legacyDir := filepath.Join("artifacts", "4.12.0-x86_64")
+	os.MkdirAll(filepath.Join(legacyDir, "bin"), 0755)
+	os.WriteFile(filepath.Join(legacyDir, "install-config.yaml.backup"), []byte("apiVersion: v1\n"), 0644)
+	os.MkdirAll(filepath.Join("artifacts", "shared", "4.12.0-x86_64", "bin"), 0755)
+
+	legacy, err := FindLegacyArtifacts()
+	if err != nil || len(legacy) != 1 {
+		t.Fatalf("Expected one legacy directory, got %+v (%v)", legacy, err)
+	}
+	moves, err := MigrateLegacyArtifacts(legacy[0], false)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, move := range moves {
+		if move.Skipped == "" {
+			t.Errorf("Expected %s to be skipped", move.From)
+		}
+	}
+	if !DirExists(filepath.Join(legacyDir, "bin")) || !FileExists(filepath.Join(legacyDir, "install-config.yaml.backup")) {
+		t.Error("Expected the skipped entries to stay in the legacy directory")
+	}
+}
@@ -0,0 +1,283 @@
+package util
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// OperatorCatalogIndexes are the index images of the default catalog sources
+// of OpenShift, by CatalogSource name, without their v<major>.<minor> tag
+var OperatorCatalogIndexes = map[string]string{
+	"redhat-operators":    "registry.redhat.io/redhat/redhat-operator-index",
+	"certified-operators": "registry.redhat.io/redhat/certified-operator-index",
+	"community-operators": "registry.redhat.io/redhat/community-operator-index",
+	"redhat-marketplace":  "registry.redhat.io/redhat/redhat-marketplace-index",
+}
+
+// GetMirrorWorkspacePath returns the oc-mirror workspace, which is kept so
+// that later runs only mirror what changed
+func GetMirrorWorkspacePath() string {
+	return filepath.Join("artifacts", "shared", "mirror")
+}
+
+// GetSharedMirrorPath returns the directory of the cluster resources oc-mirror
+// wrote for a release (image mirror sets and catalog sources)
+func GetSharedMirrorPath(versionArch string) string {
+	return filepath.Join("artifacts", "shared", versionArch, "mirror")
+}
+
+// MirroredReleaseImage returns where oc-mirror mirrors the release image of
+// versionArch in a registry (e.g. registry.example.com:5000/ocp)
+func MirroredReleaseImage(registry, versionArch string) string {
+	return strings.TrimSuffix(registry, "/") + "/openshift/release-images:" + versionArch
+}
+
+// MirrorOperator is an operator package mirrored from a catalog
+type MirrorOperator struct {
+	Catalog string // CatalogSource name, e.g. redhat-operators
+	Package string
+	Channel string // All channels if empty
+}
+
+// imageSetConfiguration is the oc-mirror v2 configuration of what to mirror
+type imageSetConfiguration struct {
+	APIVersion string `yaml:"apiVersion"`
+	Kind       string `yaml:"kind"`
+	Mirror     struct {
+		Platform struct {
+			Architectures []string          `yaml:"architectures"`
+			Channels      []imageSetChannel `yaml:"channels"`
+		} `yaml:"platform"`
+		Operators []imageSetCatalog `yaml:"operators,omitempty"`
+	} `yaml:"mirror"`
+}
+
+type imageSetChannel struct {
+	Name       string `yaml:"name"`
+	MinVersion string `yaml:"minVersion,omitempty"`
+	MaxVersion string `yaml:"maxVersion,omitempty"`
+}
+
+type imageSetCatalog struct {
+	Catalog  string            `yaml:"catalog"`
+	Packages []imageSetPackage `yaml:"packages"`
+}
+
+type imageSetPackage struct {
+	Name     string            `yaml:"name"`
+	Channels []imageSetChannel `yaml:"channels,omitempty"`
+}
+
+// RenderImageSetConfiguration returns the oc-mirror ImageSetConfiguration
+// mirroring the release of versionArch from an update channel
+// (stable-<major>.<minor> if empty), and operators from the catalogs of the
+// release
+func RenderImageSetConfiguration(versionArch, channel string, operators []MirrorOperator) ([]byte, error) {
+	version := ReleaseVersion(versionArch)
+	parts := strings.SplitN(version, ".", 3)
+	if len(parts) < 2 || version[0] < '0' || version[0] > '9' {
+		return nil, fmt.Errorf("release %s is not a version: only the releases of an update channel can be mirrored", versionArch)
+	}
+	majorMinor := parts[0] + "." + parts[1]
+	if channel == "" {
+		channel = "stable-" + majorMinor
+	}
+
+	var isc imageSetConfiguration
+	isc.APIVersion = "mirror.openshift.io/v2alpha1"
+	isc.Kind = "ImageSetConfiguration"
+	arch := ReleaseArch(versionArch)
+	if arch != "multi" {
+		arch = ClusterArchitecture(arch)
+	}
+	isc.Mirror.Platform.Architectures = []string{arch}
+	isc.Mirror.Platform.Channels = []imageSetChannel{{Name: channel, MinVersion: version, MaxVersion: version}}
+
+	catalogs := map[string]*imageSetCatalog{}
+	for _, operator := range operators {
+		index, ok := OperatorCatalogIndexes[operator.Catalog]
+		if !ok {
+			return nil, fmt.Errorf("operator %s: catalog source %s is not a default catalog of OpenShift", operator.Package, operator.Catalog)
+		}
+		catalog, ok := catalogs[index]
+		if !ok {
+			catalog = &imageSetCatalog{Catalog: index + ":v" + majorMinor}
+			catalogs[index] = catalog
+		}
+		pkg := imageSetPackage{Name: operator.Package}
+		if operator.Channel != "" {
+			pkg.Channels = []imageSetChannel{{Name: operator.Channel}}
+		}
+		catalog.Packages = append(catalog.Packages, pkg)
+	}
+	indexes := make([]string, 0, len(catalogs))
+	for index := range catalogs {
+		indexes = append(indexes, index)
+	}
+	sort.Strings(indexes)
+	for _, index := range indexes {
+		isc.Mirror.Operators = append(isc.Mirror.Operators, *catalogs[index])
+	}
+
+	data, err := yaml.Marshal(isc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render ImageSetConfiguration: %w", err)
+	}
+	return data, nil
+}
+
+// ImageDigestSource is a repository and its mirrors, as in the
+// imageDigestSources of install-config.yaml
+type ImageDigestSource struct {
+	Source  string   `yaml:"source"`
+	Mirrors []string `yaml:"mirrors"`
+}
+
+// MirrorCatalogSource is a CatalogSource written by oc-mirror for a mirrored catalog
+type MirrorCatalogSource struct {
+	Name    string
+	Image   string
+	Path    string // Manifest file
+	Default string // Default catalog source it replaces (e.g. redhat-operators), if any
+}
+
+// MirrorResources are the cluster resources oc-mirror writes for a mirror
+type MirrorResources struct {
+	DigestSources  []ImageDigestSource // From the ImageDigestMirrorSets (or ImageContentSourcePolicies)
+	IDMSFile       string              // ImageDigestMirrorSets, for oc --idms-file
+	ICSPFile       string              // ImageContentSourcePolicies of oc-mirror v1, for oc --icsp-file
+	TagMirrorSets  []string            // ImageTagMirrorSet manifests
+	CatalogSources []MirrorCatalogSource
+}
+
+// mirrorManifest is the subset of the resources of oc-mirror read from manifests
+type mirrorManifest struct {
+	Kind     string `yaml:"kind"`
+	Metadata struct {
+		Name string `yaml:"name"`
+	} `yaml:"metadata"`
+	Spec struct {
+		ImageDigestMirrors      []ImageDigestSource `yaml:"imageDigestMirrors"`
+		RepositoryDigestMirrors []ImageDigestSource `yaml:"repositoryDigestMirrors"`
+		Image                   string              `yaml:"image"`
+	} `yaml:"spec"`
+}
+
+// ReadMirrorResources reads the cluster resources oc-mirror wrote to dir
+func ReadMirrorResources(dir string) (*MirrorResources, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("no mirror resources in %s", dir)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mirror resources: %w", err)
+	}
+
+	resources := &MirrorResources{}
+	for _, entry := range entries {
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		if entry.IsDir() || (ext != ".yaml" && ext != ".yml") {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		for {
+			var manifest mirrorManifest
+			if err := decoder.Decode(&manifest); errors.Is(err, io.EOF) {
+				break
+			} else if err != nil {
+				return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+			}
+			switch manifest.Kind {
+			case "ImageDigestMirrorSet":
+				resources.DigestSources = append(resources.DigestSources, manifest.Spec.ImageDigestMirrors...)
+				resources.IDMSFile = path
+			case "ImageContentSourcePolicy":
+				resources.DigestSources = append(resources.DigestSources, manifest.Spec.RepositoryDigestMirrors...)
+				resources.ICSPFile = path
+			case "ImageTagMirrorSet":
+				if n := len(resources.TagMirrorSets); n == 0 || resources.TagMirrorSets[n-1] != path {
+					resources.TagMirrorSets = append(resources.TagMirrorSets, path)
+				}
+			case "CatalogSource":
+				resources.CatalogSources = append(resources.CatalogSources, MirrorCatalogSource{
+					Name:    manifest.Metadata.Name,
+					Image:   manifest.Spec.Image,
+					Path:    path,
+					Default: defaultCatalogSource(manifest.Spec.Image),
+				})
+			}
+		}
+	}
+	if len(resources.DigestSources) == 0 {
+		return nil, fmt.Errorf("no ImageDigestMirrorSet or ImageContentSourcePolicy in %s", dir)
+	}
+	return resources, nil
+}
+
+// defaultCatalogSource returns the default catalog source of OpenShift a
+// mirrored index image comes from, or "" if none
+func defaultCatalogSource(image string) string {
+	repo, _, _ := strings.Cut(image, "@")
+	if i := strings.LastIndex(repo, ":"); i > strings.LastIndex(repo, "/") {
+		repo = repo[:i]
+	}
+	repo = repo[strings.LastIndex(repo, "/")+1:]
+	for name, index := range OperatorCatalogIndexes {
+		if strings.HasSuffix(index, "/"+repo) {
+			return name
+		}
+	}
+	return ""
+}
+
+// OCMirrorArgs returns the arguments making oc resolve the images of the
+// release through the mirror
+func (r *MirrorResources) OCMirrorArgs() []string {
+	if r.IDMSFile != "" {
+		return []string{"--idms-file=" + r.IDMSFile}
+	}
+	return []string{"--icsp-file=" + r.ICSPFile}
+}
+
+// CopyMirrorResources copies the cluster resources of an oc-mirror workspace
+// (working-dir/cluster-resources) to dir, replacing the ones of an earlier run
+func CopyMirrorResources(workspace, dir string) ([]string, error) {
+	source := filepath.Join(workspace, "working-dir", "cluster-resources")
+	entries, err := os.ReadDir(source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the cluster resources of oc-mirror: %w", err)
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return nil, err
+	}
+	if err := EnsureDir(dir); err != nil {
+		return nil, err
+	}
+
+	var copied []string
+	for _, entry := range entries {
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		if entry.IsDir() || (ext != ".yaml" && ext != ".yml") {
+			continue
+		}
+		if err := CopyFile(filepath.Join(source, entry.Name()), filepath.Join(dir, entry.Name())); err != nil {
+			return nil, fmt.Errorf("failed to copy %s: %w", entry.Name(), err)
+		}
+		copied = append(copied, filepath.Join(dir, entry.Name()))
+	}
+	return copied, nil
+}
@@ -0,0 +1,117 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRenderImageSetConfiguration(t *testing.T) {
+	data, err := RenderImageSetConfiguration("4.15.12-x86_64", "", []MirrorOperator{
+		{Catalog: "redhat-operators", Package: "web-terminal", Channel: "fast"},
+		{Catalog: "certified-operators", Package: "cert-manager"},
+		{Catalog: "redhat-operators", Package: "local-storage-operator"},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	config := string(data)
+	for _, want := range []string{
+		"kind: ImageSetConfiguration",
+		"- amd64",
+		"name: stable-4.15",
+		"minVersion: 4.15.12",
+		"maxVersion: 4.15.12",
+		"catalog: registry.redhat.io/redhat/certified-operator-index:v4.15",
+		"catalog: registry.redhat.io/redhat/redhat-operator-index:v4.15",
+		"name: web-terminal",
+		"name: fast",
+	} {
+		if !strings.Contains(config, want) {
+			t.Errorf("Expected %q in:\n%s", want, config)
+		}
+	}
+	if strings.Count(config, "redhat-operator-index") != 1 {
+		t.Errorf("Expected the operators of a catalog to be grouped:\n%s", config)
+	}
+
+	if _, err := RenderImageSetConfiguration("4.15.12-x86_64", "", []MirrorOperator{{Catalog: "my-catalog", Package: "foo"}}); err == nil {
+		t.Error("Expected an error for a catalog that is not a default one")
+	}
+	if _, err := RenderImageSetConfiguration("latest", "", nil); err == nil {
+		t.Error("Expected an error for a release that is not a version")
+	}
+}
+
+func TestReadMirrorResources(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "idms-oc-mirror.yaml"), []byte(`apiVersion: config.openshift.io/v1
+kind: ImageDigestMirrorSet
+metadata:
+  name: idms-release-0
+spec:
+  imageDigestMirrors:
+  - mirrors:
+    - registry.example.com:5000/ocp/openshift-release-dev
+    source: quay.io/openshift-release-dev
+`), 0644)
+	os.WriteFile(filepath.Join(dir, "itms-oc-mirror.yaml"), []byte(`kind: ImageTagMirrorSet
+metadata:
+  name: itms-generic-0
+---
+kind: ImageTagMirrorSet
+metadata:
+  name: itms-generic-1
+`), 0644)
+	os.WriteFile(filepath.Join(dir, "cs-redhat-operator-index-v4-15.yaml"), []byte(`kind: CatalogSource
+metadata:
+  name: cs-redhat-operator-index-v4-15
+spec:
+  image: registry.example.com:5000/ocp/redhat/redhat-operator-index:v4.15
+`), 0644)
+
+	resources, err := ReadMirrorResources(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(resources.DigestSources) != 1 || resources.DigestSources[0].Source != "quay.io/openshift-release-dev" {
+		t.Errorf("Unexpected digest sources %+v", resources.DigestSources)
+	}
+	if args := resources.OCMirrorArgs(); len(args) != 1 || args[0] != "--idms-file="+filepath.Join(dir, "idms-oc-mirror.yaml") {
+		t.Errorf("Unexpected oc arguments %v", args)
+	}
+	if len(resources.TagMirrorSets) != 1 {
+		t.Errorf("Expected the tag mirror sets file once, got %v", resources.TagMirrorSets)
+	}
+	if len(resources.CatalogSources) != 1 || resources.CatalogSources[0].Default != "redhat-operators" {
+		t.Errorf("Unexpected catalog sources %+v", resources.CatalogSources)
+	}
+
+	if _, err := ReadMirrorResources(t.TempDir()); err == nil {
+		t.Error("Expected an error without image mirror sets")
+	}
+}
+
+func TestCopyMirrorResources(t *testing.T) {
+	workspace := t.TempDir()
+	source := filepath.Join(workspace, "working-dir", "cluster-resources")
+	os.MkdirAll(source, 0755)
+	os.WriteFile(filepath.Join(source, "idms-oc-mirror.yaml"), []byte("kind: ImageDigestMirrorSet"), 0644)
+	os.WriteFile(filepath.Join(source, "signature-configmap.json"), []byte("{}"), 0644)
+
+	dir := filepath.Join(t.TempDir(), "mirror")
+	os.MkdirAll(dir, 0755)
+	os.WriteFile(filepath.Join(dir, "idms-old.yaml"), []byte("kind: ImageDigestMirrorSet"), 0644)
+
+	copied, err := CopyMirrorResources(workspace, dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(copied) != 1 || !FileExists(filepath.Join(dir, "idms-oc-mirror.yaml")) {
+		t.Errorf("Expected the manifests to be copied, got %v", copied)
+	}
+	if FileExists(filepath.Join(dir, "idms-old.yaml")) {
+		t.Error("Expected the resources of an earlier run to be removed")
+	}
+}
@@ -0,0 +1,98 @@
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// NodeStatus counts the nodes of a cluster and those that are Ready
+type NodeStatus struct {
+	Total int
+	Ready int
+}
+
+// GetNodeStatus returns how many nodes the cluster has, and how many are Ready
+func GetNodeStatus(executor CommandExecutor, kubeconfigPath string) (*NodeStatus, error) {
+	env := []string{fmt.Sprintf("KUBECONFIG=%s", kubeconfigPath)}
+	output, err := executor.ExecuteWithEnv("oc", env, "get", "nodes", "-o", "json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get nodes: %w", err)
+	}
+	return parseNodeStatus([]byte(output))
+}
+
+func parseNodeStatus(data []byte) (*NodeStatus, error) {
+	var list struct {
+		Items []struct {
+			Status struct {
+				Conditions []struct {
+					Type   string `json:"type"`
+					Status string `json:"status"`
+				} `json:"conditions"`
+			} `json:"status"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse nodes: %w", err)
+	}
+
+	status := &NodeStatus{Total: len(list.Items)}
+	for _, item := range list.Items {
+		for _, condition := range item.Status.Conditions {
+			if condition.Type == "Ready" && condition.Status == "True" {
+				status.Ready++
+			}
+		}
+	}
+	return status, nil
+}
+
+// GetPendingCSRs returns the names of the certificate signing requests that
+// are neither approved nor denied, sorted
+func GetPendingCSRs(executor CommandExecutor, kubeconfigPath string) ([]string, error) {
+	env := []string{fmt.Sprintf("KUBECONFIG=%s", kubeconfigPath)}
+	output, err := executor.ExecuteWithEnv("oc", env, "get", "csr", "-o", "json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get certificate signing requests: %w", err)
+	}
+	return parsePendingCSRs([]byte(output))
+}
+
+func parsePendingCSRs(data []byte) ([]string, error) {
+	var list struct {
+		Items []struct {
+			Metadata struct {
+				Name string `json:"name"`
+			} `json:"metadata"`
+			Status struct {
+				Conditions []json.RawMessage `json:"conditions"`
+			} `json:"status"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse certificate signing requests: %w", err)
+	}
+
+	var pending []string
+	for _, item := range list.Items {
+		if len(item.Status.Conditions) == 0 {
+			pending = append(pending, item.Metadata.Name)
+		}
+	}
+	sort.Strings(pending)
+	return pending, nil
+}
+
+// ApproveCSRs approves the certificate signing requests
+func ApproveCSRs(executor CommandExecutor, kubeconfigPath string, names []string) error {
+	if len(names) == 0 {
+		return nil
+	}
+	env := []string{fmt.Sprintf("KUBECONFIG=%s", kubeconfigPath)}
+	args := append([]string{"adm", "certificate", "approve"}, names...)
+	if _, err := executor.ExecuteWithEnv("oc", env, args...); err != nil {
+		return fmt.Errorf("failed to approve certificate signing requests: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package util
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseNodeStatus(t *testing.T) {
+	status, err := parseNodeStatus([]byte(`{"items": [
+  {"status": {"conditions": [{"type": "MemoryPressure", "status": "False"}, {"type": "Ready", "status": "True"}]}},
+  {"status": {"conditions": [{"type": "Ready", "status": "Unknown"}]}}
+]}`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if status.Total != 2 || status.Ready != 1 {
+		t.Errorf("Expected 1/2 nodes ready, got %+v", status)
+	}
+}
+
+func TestParsePendingCSRs(t *testing.T) {
+	pending, err := parsePendingCSRs([]byte(`{"items": [
+  {"metadata": {"name": "csr-b"}, "status": {}},
+  {"metadata": {"name": "csr-approved"}, "status": {"conditions": [{"type": "Approved", "status": "True"}]}},
+  {"metadata": {"name": "csr-a"}, "status": {}}
+]}`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if expected := []string{"csr-a", "csr-b"}; !reflect.DeepEqual(pending, expected) {
+		t.Errorf("Expected %v, got %v", expected, pending)
+	}
+}
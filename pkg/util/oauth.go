@@ -0,0 +1,81 @@
+package util
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// oauthChallengingClient is the OAuth client oc uses to log in with a user
+// name and a password
+const oauthChallengingClient = "openshift-challenging-client"
+
+// RequestOAuthToken logs in to the OAuth server of the cluster of the API
+// server with a user name and a password, as `oc login -u -p` does, and returns
+// the access token. The API server and the OAuth route must be signed by one
+// of the PEM certificates of caBundle.
+func RequestOAuthToken(server string, caBundle []byte, user, password string) (string, error) {
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(caBundle) {
+		return "", fmt.Errorf("no CA certificate to verify %s", server)
+	}
+	client := &http.Client{
+		Timeout:   30 * time.Second,
+		Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}},
+		// The token is in the fragment of the redirect
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+
+	resp, err := client.Get(strings.TrimSuffix(server, "/") + "/.well-known/oauth-authorization-server")
+	if err != nil {
+		return "", fmt.Errorf("failed to discover the OAuth server: %w", err)
+	}
+	defer resp.Body.Close()
+	var metadata struct {
+		AuthorizationEndpoint string `json:"authorization_endpoint"`
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to discover the OAuth server: %s", resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&metadata); err != nil || metadata.AuthorizationEndpoint == "" {
+		return "", fmt.Errorf("failed to discover the OAuth server: invalid metadata")
+	}
+
+	authorize, err := url.Parse(metadata.AuthorizationEndpoint)
+	if err != nil {
+		return "", fmt.Errorf("invalid OAuth authorization endpoint: %w", err)
+	}
+	authorize.RawQuery = url.Values{"client_id": {oauthChallengingClient}, "response_type": {"token"}}.Encode()
+	req, err := http.NewRequest(http.MethodGet, authorize.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	req.SetBasicAuth(user, password)
+	req.Header.Set("X-CSRF-Token", "1")
+
+	resp, err = client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to log in as %s: %w", user, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		return "", fmt.Errorf("failed to log in as %s: %s", user, resp.Status)
+	}
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		return "", fmt.Errorf("failed to log in as %s: invalid redirect: %w", user, err)
+	}
+	fragment, _ := url.ParseQuery(location.Fragment)
+	if token := fragment.Get("access_token"); token != "" {
+		return token, nil
+	}
+	if reason := fragment.Get("error_description"); reason != "" {
+		return "", fmt.Errorf("failed to log in as %s: %s", user, reason)
+	}
+	return "", fmt.Errorf("failed to log in as %s: no access token returned", user)
+}
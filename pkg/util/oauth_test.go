@@ -0,0 +1,52 @@
+package util
+
+import (
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRequestOAuthToken(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/oauth-authorization-server":
+			json.NewEncoder(w).Encode(map[string]string{"authorization_endpoint": server.URL + "/oauth/authorize"})
+		case "/oauth/authorize":
+			user, password, ok := r.BasicAuth()
+			if !ok || user != "admin" || password != "secret" || r.Header.Get("X-CSRF-Token") == "" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			if r.URL.Query().Get("client_id") != "openshift-challenging-client" || r.URL.Query().Get("response_type") != "token" {
+				http.Error(w, "invalid client", http.StatusBadRequest)
+				return
+			}
+			w.Header().Set("Location", server.URL+"/oauth/token/implicit#access_token=sha256~token&token_type=Bearer")
+			w.WriteHeader(http.StatusFound)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	caBundle := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+
+	token, err := RequestOAuthToken(server.URL, caBundle, "admin", "secret")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if token != "sha256~token" {
+		t.Errorf("Expected sha256~token, got %q", token)
+	}
+
+	if _, err := RequestOAuthToken(server.URL, caBundle, "admin", "wrong"); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("Expected an authentication error, got %v", err)
+	}
+
+	if _, err := RequestOAuthToken(server.URL, nil, "admin", "secret"); err == nil {
+		t.Error("Expected an error without a CA bundle")
+	}
+}
@@ -0,0 +1,182 @@
+package util
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// DefaultOCMirrorURL is where the OpenShift clients are published
+const DefaultOCMirrorURL = "https://mirror.openshift.com/pub/openshift-v4/x86_64/clients/ocp"
+
+// GetSharedBinDir returns the directory of the binaries shared by every release
+// (e.g. a downloaded oc client)
+func GetSharedBinDir() string {
+	return filepath.Join("artifacts", "shared", "bin")
+}
+
+// OCClientURL returns the URL of the oc client archive of a version (the latest
+// stable one if empty) for the host OS and architecture
+func OCClientURL(mirror, version string) string {
+	return ocClientURL(mirror, version, runtime.GOOS, runtime.GOARCH)
+}
+
+func ocClientURL(mirror, version, goos, goarch string) string {
+	if mirror == "" {
+		mirror = DefaultOCMirrorURL
+	}
+	name := "openshift-client-linux"
+	if goos == "darwin" {
+		name = "openshift-client-mac"
+	}
+	// Every client is published in the x86_64 directory, suffixed by its architecture
+	if goarch == "arm64" {
+		name += "-arm64"
+	}
+
+	dir := version
+	if version == "" {
+		dir = "stable"
+	} else {
+		name += "-" + version
+	}
+	return fmt.Sprintf("%s/%s/%s.tar.gz", strings.TrimRight(mirror, "/"), dir, name)
+}
+
+// DownloadOC downloads the oc client archive at url and extracts the oc binary
+// into dir, returning its path
+func DownloadOC(url, dir string) (string, error) {
+	client := &http.Client{Timeout: 10 * time.Minute}
+	resp, err := client.Get(url)
+	if err != nil {
+		return "", fmt.Errorf("failed to download oc: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to download oc: %s returned %s", url, resp.Status)
+	}
+
+	archive, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read oc archive: %w", err)
+	}
+	defer archive.Close()
+
+	if err := EnsureDir(dir); err != nil {
+		return "", err
+	}
+	reader := tar.NewReader(archive)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return "", fmt.Errorf("no oc binary in %s", url)
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to read oc archive: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg || filepath.Base(header.Name) != "oc" {
+			continue
+		}
+
+		// Write next to the destination and rename, so that a partial
+		// download never replaces a working binary
+		path := filepath.Join(dir, "oc")
+		partial := path + ".partial"
+		file, err := os.OpenFile(partial, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0755)
+		if err != nil {
+			return "", err
+		}
+		if _, err := io.Copy(file, reader); err != nil {
+			file.Close()
+			os.Remove(partial)
+			return "", fmt.Errorf("failed to extract oc: %w", err)
+		}
+		if err := file.Close(); err != nil {
+			os.Remove(partial)
+			return "", err
+		}
+		return path, os.Rename(partial, path)
+	}
+}
+
+// UseSharedBinaries puts the shared bin directory first in PATH, so that the
+// binaries downloaded there are used by the wrapper and its child processes.
+// It does nothing when the directory doesn't exist.
+func UseSharedBinaries() error {
+	dir, err := filepath.Abs(GetSharedBinDir())
+	if err != nil {
+		return err
+	}
+	if !DirExists(dir) {
+		return nil
+	}
+	return prependPath(dir)
+}
+
+// UseOCBinary puts the directory of a configured oc binary first in PATH, so
+// that it is the oc run by the wrapper and its child processes
+func UseOCBinary(ocPath string) error {
+	dir, err := OCBinaryDir(ocPath)
+	if err != nil {
+		return err
+	}
+	return prependPath(dir)
+}
+
+// OCBinaryDir returns the absolute directory of a configured oc binary, to be
+// put first in PATH
+func OCBinaryDir(ocPath string) (string, error) {
+	if filepath.Base(ocPath) != "oc" {
+		return "", fmt.Errorf("%s must be named oc", ocPath)
+	}
+	if !FileExists(ocPath) {
+		return "", fmt.Errorf("oc binary %s not found", ocPath)
+	}
+	return filepath.Abs(filepath.Dir(ocPath))
+}
+
+// UseReleaseClients puts the bin directory of the shared artifacts of a
+// release first in PATH, so that the oc and kubectl clients Step 2 extracted
+// from the release are used instead of the system ones. It does nothing, and
+// reports false, unless that oc matches its recorded checksum.
+func UseReleaseClients(versionArch, releaseDigest string) (bool, error) {
+	dir, err := ReleaseClientsDir(versionArch, releaseDigest)
+	if dir == "" || err != nil {
+		return false, err
+	}
+	return true, prependPath(dir)
+}
+
+// ReleaseClientsDir returns the absolute bin directory of the shared artifacts
+// of a release, holding the oc and kubectl clients Step 2 extracted from the
+// release. It returns an empty string unless that oc matches its recorded
+// checksum.
+func ReleaseClientsDir(versionArch, releaseDigest string) (string, error) {
+	if versionArch == "" {
+		return "", nil
+	}
+	ocPath := GetSharedBinaryPath(versionArch, "oc")
+	if !VerifyArtifact(versionArch, releaseDigest, ocPath) {
+		return "", nil
+	}
+	return filepath.Abs(filepath.Dir(ocPath))
+}
+
+// prependPath puts a directory first in PATH, moving it there if it is
+// already in PATH behind others
+func prependPath(dir string) error {
+	entries := []string{dir}
+	for _, entry := range filepath.SplitList(os.Getenv("PATH")) {
+		if entry != dir {
+			entries = append(entries, entry)
+		}
+	}
+	return os.Setenv("PATH", strings.Join(entries, string(os.PathListSeparator)))
+}
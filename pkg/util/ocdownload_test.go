@@ -0,0 +1,117 @@
+package util
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestOCClientURL(t *testing.T) {
+	tests := []struct {
+		version, goos, goarch, expected string
+	}{
+		{"4.15.0", "linux", "amd64", "https://mirror.openshift.com/pub/openshift-v4/x86_64/clients/ocp/4.15.0/openshift-client-linux-4.15.0.tar.gz"},
+		{"4.15.0", "linux", "arm64", "https://mirror.openshift.com/pub/openshift-v4/x86_64/clients/ocp/4.15.0/openshift-client-linux-arm64-4.15.0.tar.gz"},
+		{"4.15.0", "darwin", "arm64", "https://mirror.openshift.com/pub/openshift-v4/x86_64/clients/ocp/4.15.0/openshift-client-mac-arm64-4.15.0.tar.gz"},
+		{"", "darwin", "amd64", "https://mirror.openshift.com/pub/openshift-v4/x86_64/clients/ocp/stable/openshift-client-mac.tar.gz"},
+	}
+	for _, tt := range tests {
+		if url := ocClientURL("", tt.version, tt.goos, tt.goarch); url != tt.expected {
+			t.Errorf("Expected %s, got %s", tt.expected, url)
+		}
+	}
+}
+
+func TestDownloadOC(t *testing.T) {
+	var archive bytes.Buffer
+	gz := gzip.NewWriter(&archive)
+	tw := tar.NewWriter(gz)
+	for name, content := range map[string]string{"README.md": "readme", "oc": "#!/bin/sh\necho oc\n"} {
+		tw.WriteHeader(&tar.Header{Name: name, Mode: 0755, Size: int64(len(content)), Typeflag: tar.TypeReg})
+		tw.Write([]byte(content))
+	}
+	tw.Close()
+	gz.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "openshift-client-linux-4.15.0.tar.gz") {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(archive.Bytes())
+	}))
+	defer server.Close()
+
+	dir := filepath.Join(t.TempDir(), "bin")
+	path, err := DownloadOC(ocClientURL(server.URL, "4.15.0", "linux", "amd64"), dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	info, _ := os.Stat(path)
+	if string(data) != "#!/bin/sh\necho oc\n" || info.Mode()&0100 == 0 {
+		t.Errorf("Expected an executable oc binary, got %q (%s)", data, info.Mode())
+	}
+
+	if _, err := DownloadOC(ocClientURL(server.URL, "4.1.0", "linux", "amd64"), dir); err == nil {
+		t.Error("Expected an error for a missing version")
+	}
+}
+
+func TestUseOCBinary(t *testing.T) {
+	dir := t.TempDir()
+	ocPath := filepath.Join(dir, "oc")
+	os.WriteFile(ocPath, []byte("#!/bin/sh\n"), 0755)
+	t.Setenv("PATH", "/usr/bin"+string(os.PathListSeparator)+dir)
+
+	if err := UseOCBinary(ocPath); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if entries := filepath.SplitList(os.Getenv("PATH")); len(entries) != 2 || entries[0] != dir {
+		t.Errorf("Expected %s first in PATH, only once, got %s", dir, os.Getenv("PATH"))
+	}
+
+	if err := UseOCBinary(filepath.Join(dir, "oc-4.15")); err == nil {
+		t.Error("Expected an error for a binary not named oc")
+	}
+	if err := UseOCBinary(filepath.Join(dir, "missing", "oc")); err == nil {
+		t.Error("Expected an error for a missing binary")
+	}
+}
+
+func TestUseReleaseClients(t *testing.T) {
+	tmpDir := t.TempDir()
+	originalWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(originalWd)
+	t.Setenv("PATH", "/usr/bin")
+
+	if used, err := UseReleaseClients("4.15.0-x86_64", ""); used || err != nil {
+		t.Fatalf("Expected nothing to use without the oc of the release, got %v (%v)", used, err)
+	}
+
+	ocPath := GetSharedBinaryPath("4.15.0-x86_64", "oc")
+	os.MkdirAll(filepath.Dir(ocPath), 0755)
+	os.WriteFile(ocPath, []byte("#!/bin/sh\n"), 0755)
+	if used, _ := UseReleaseClients("4.15.0-x86_64", ""); used {
+		t.Error("Expected an oc without recorded checksum not to be used")
+	}
+
+	RecordArtifacts("4.15.0-x86_64", "quay.io/test:4.15.0-x86_64", "sha256:aaa", ocPath)
+	dir, _ := filepath.Abs(filepath.Dir(ocPath))
+	if releaseDir, err := ReleaseClientsDir("4.15.0-x86_64", "sha256:aaa"); releaseDir != dir || err != nil {
+		t.Errorf("Expected %s, got %q (%v)", dir, releaseDir, err)
+	}
+	if used, err := UseReleaseClients("4.15.0-x86_64", "sha256:aaa"); !used || err != nil {
+		t.Fatalf("Expected the oc of the release to be used, got %v (%v)", used, err)
+	}
+	if entries := filepath.SplitList(os.Getenv("PATH")); entries[0] != dir {
+		t.Errorf("Expected %s first in PATH, got %s", dir, os.Getenv("PATH"))
+	}
+}
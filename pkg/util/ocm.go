@@ -0,0 +1,114 @@
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DefaultOCMTokenURL is the Red Hat SSO endpoint exchanging an offline OCM token
+// for an access token
+const DefaultOCMTokenURL = "https://sso.redhat.com/auth/realms/redhat-external/protocol/openid-connect/token"
+
+// DefaultOCMAPIURL is the OpenShift Cluster Manager API
+const DefaultOCMAPIURL = "https://api.openshift.com"
+
+// ocmClientID is the public client used by the OCM tools for offline tokens
+const ocmClientID = "cloud-services"
+
+// FetchPullSecret retrieves the pull secret of the account owning the offline
+// OCM token (from https://console.redhat.com/openshift/token)
+func FetchPullSecret(tokenURL, apiURL, offlineToken string) ([]byte, error) {
+	if tokenURL == "" {
+		tokenURL = DefaultOCMTokenURL
+	}
+	if apiURL == "" {
+		apiURL = DefaultOCMAPIURL
+	}
+	client := &http.Client{Timeout: 30 * time.Second}
+
+	accessToken, err := ocmAccessToken(client, tokenURL, offlineToken)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, strings.TrimSuffix(apiURL, "/")+"/api/accounts_mgmt/v1/access_token", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to request pull secret: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pull secret: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("OCM API returned %s when requesting the pull secret", resp.Status)
+	}
+
+	var pullSecret struct {
+		Auths map[string]json.RawMessage `json:"auths"`
+	}
+	if err := json.Unmarshal(body, &pullSecret); err != nil || len(pullSecret.Auths) == 0 {
+		return nil, fmt.Errorf("OCM API returned an invalid pull secret")
+	}
+
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, body); err != nil {
+		return nil, err
+	}
+	return compact.Bytes(), nil
+}
+
+// ocmAccessToken exchanges an offline token for a short-lived access token
+func ocmAccessToken(client *http.Client, tokenURL, offlineToken string) (string, error) {
+	form := url.Values{}
+	form.Set("grant_type", "refresh_token")
+	form.Set("client_id", ocmClientID)
+	form.Set("refresh_token", offlineToken)
+
+	resp, err := client.PostForm(tokenURL, form)
+	if err != nil {
+		return "", fmt.Errorf("failed to authenticate with Red Hat SSO: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("Red Hat SSO returned %s: the OCM token is invalid or expired, get a new one at https://console.redhat.com/openshift/token", resp.Status)
+	}
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil || token.AccessToken == "" {
+		return "", fmt.Errorf("failed to parse Red Hat SSO response")
+	}
+	return token.AccessToken, nil
+}
+
+// SavePullSecret writes the pull secret readable by the current user only
+func SavePullSecret(path string, pullSecret []byte) error {
+	if dir := filepath.Dir(path); dir != "." {
+		if err := EnsureDir(dir); err != nil {
+			return err
+		}
+	}
+	if err := os.WriteFile(path, pullSecret, 0600); err != nil {
+		return fmt.Errorf("failed to write pull secret: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package util
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFetchPullSecret(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			r.ParseForm()
+			if r.Form.Get("refresh_token") != "offline-token" || r.Form.Get("client_id") != "cloud-services" {
+				http.Error(w, "invalid grant", http.StatusBadRequest)
+				return
+			}
+			w.Write([]byte(`{"access_token": "access-token"}`))
+		case "/api/accounts_mgmt/v1/access_token":
+			if r.Header.Get("Authorization") != "Bearer access-token" {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte(`{
+				"auths": {"quay.io": {"auth": "dGVzdA==", "email": "me@example.com"}}
+			}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	pullSecret, err := FetchPullSecret(server.URL+"/token", server.URL, "offline-token")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if string(pullSecret) != `{"auths":{"quay.io":{"auth":"dGVzdA==","email":"me@example.com"}}}` {
+		t.Errorf("Unexpected pull secret %s", pullSecret)
+	}
+
+	if _, err := FetchPullSecret(server.URL+"/token", server.URL, "expired-token"); err == nil {
+		t.Error("Expected error for an invalid offline token")
+	}
+}
@@ -0,0 +1,235 @@
+package util
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/clobrano/openshift-sts-wrapper/pkg/logger"
+)
+
+// ErrNotRecorded is returned when replaying a command that is not in the fixtures
+var ErrNotRecorded = errors.New("command not recorded")
+
+// RecordedCommand is an external command and its outcome, as stored in a
+// fixtures file
+type RecordedCommand struct {
+	Command  string `json:"command"`
+	Output   string `json:"output,omitempty"`
+	Error    string `json:"error,omitempty"`
+	ExitCode int    `json:"exitCode,omitempty"`
+}
+
+// Fixtures are the commands of a run, in execution order
+type Fixtures struct {
+	Commands []RecordedCommand `json:"commands"`
+}
+
+// LoadFixtures reads a fixtures file written by a Recorder
+func LoadFixtures(path string) (*Fixtures, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixtures: %w", err)
+	}
+	var fixtures Fixtures
+	if err := json.Unmarshal(data, &fixtures); err != nil {
+		return nil, fmt.Errorf("failed to parse fixtures %s: %w", path, err)
+	}
+	return &fixtures, nil
+}
+
+// ReplayedError is the error of a command that failed when it was recorded
+type ReplayedError struct {
+	Message  string
+	ExitCode int
+}
+
+func (e *ReplayedError) Error() string {
+	return e.Message
+}
+
+// wrappedExecutor is implemented by the executors that delegate to (or take
+// their context from) another executor
+type wrappedExecutor interface {
+	Unwrap() CommandExecutor
+}
+
+// Recorder writes every command run through its executors, and the command
+// output, to a fixtures file. The file is rewritten after each command, so an
+// interrupted run still leaves its fixtures. The secrets the logger redacts
+// are redacted from the fixtures too, but outputs may contain others: review
+// the file before sharing it.
+type Recorder struct {
+	path     string
+	mu       sync.Mutex
+	fixtures Fixtures
+}
+
+// NewRecorder creates a recorder writing to path
+func NewRecorder(path string) *Recorder {
+	return &Recorder{path: path}
+}
+
+// Wrap returns an executor recording the commands run by executor
+func (r *Recorder) Wrap(executor CommandExecutor) CommandExecutor {
+	return &recordingExecutor{recorder: r, executor: executor}
+}
+
+// record stores the outcome of a command and saves the fixtures
+func (r *Recorder) record(name string, args []string, output string, err error) error {
+	command := RecordedCommand{Command: logger.Redact(commandKey(name, args)), Output: logger.Redact(output)}
+	if err != nil {
+		command.Error = logger.Redact(err.Error())
+		command.ExitCode = 1
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			command.ExitCode = exitErr.ExitCode()
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.fixtures.Commands = append(r.fixtures.Commands, command)
+
+	data, err := json.MarshalIndent(r.fixtures, "", "  ")
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(r.path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(r.path, data, 0600)
+}
+
+// recordingExecutor runs the commands with its executor and records them
+type recordingExecutor struct {
+	recorder *Recorder
+	executor CommandExecutor
+}
+
+func (e *recordingExecutor) Unwrap() CommandExecutor {
+	return e.executor
+}
+
+func (e *recordingExecutor) Execute(name string, args ...string) (string, error) {
+	output, err := e.executor.Execute(name, args...)
+	return output, e.save(name, args, output, err)
+}
+
+// ExecuteWithEnv records the command without its environment, which holds credentials
+func (e *recordingExecutor) ExecuteWithEnv(name string, env []string, args ...string) (string, error) {
+	output, err := e.executor.ExecuteWithEnv(name, env, args...)
+	return output, e.save(name, args, output, err)
+}
+
+// ExecuteInteractive records the outcome of the command: its output goes to the terminal
+func (e *recordingExecutor) ExecuteInteractive(name string, args ...string) error {
+	err := e.executor.ExecuteInteractive(name, args...)
+	return e.save(name, args, "", err)
+}
+
+func (e *recordingExecutor) ExecuteInteractiveWithEnv(name string, env []string, args ...string) error {
+	err := e.executor.ExecuteInteractiveWithEnv(name, env, args...)
+	return e.save(name, args, "", err)
+}
+
+// save records a command, returning its error (a failure to write the
+// fixtures is reported with it, not instead of it)
+func (e *recordingExecutor) save(name string, args []string, output string, err error) error {
+	if saveErr := e.recorder.record(name, args, output, err); saveErr != nil {
+		if err != nil {
+			return fmt.Errorf("%w (failed to record command: %v)", err, saveErr)
+		}
+		return fmt.Errorf("failed to record command: %w", saveErr)
+	}
+	return err
+}
+
+// Replayer serves the outcome of the commands of a fixtures file instead of
+// running them. A command run more times than it was recorded gets its last
+// recorded outcome again (e.g. while polling).
+type Replayer struct {
+	mu     sync.Mutex
+	queues map[string][]RecordedCommand
+}
+
+// NewReplayer creates a replayer serving the recorded commands
+func NewReplayer(fixtures *Fixtures) *Replayer {
+	r := &Replayer{queues: map[string][]RecordedCommand{}}
+	for _, command := range fixtures.Commands {
+		r.queues[command.Command] = append(r.queues[command.Command], command)
+	}
+	return r
+}
+
+// Wrap returns an executor replaying the commands. executor never runs any
+// command: it only provides the context bounding the run.
+func (r *Replayer) Wrap(executor CommandExecutor) CommandExecutor {
+	return &replayingExecutor{replayer: r, executor: executor}
+}
+
+// next returns the outcome of the next run of a command. The command is
+// redacted like the recorded ones, so that commands with secrets in their
+// arguments are found.
+func (r *Replayer) next(name string, args []string) (string, error) {
+	key := logger.Redact(commandKey(name, args))
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	queue := r.queues[key]
+	if len(queue) == 0 {
+		return "", fmt.Errorf("%w: %s", ErrNotRecorded, key)
+	}
+	command := queue[0]
+	if len(queue) > 1 {
+		r.queues[key] = queue[1:]
+	}
+	if command.Error != "" || command.ExitCode != 0 {
+		return command.Output, &ReplayedError{Message: command.Error, ExitCode: command.ExitCode}
+	}
+	return command.Output, nil
+}
+
+// replayingExecutor serves the recorded outcome of the commands
+type replayingExecutor struct {
+	replayer *Replayer
+	executor CommandExecutor
+}
+
+func (e *replayingExecutor) Unwrap() CommandExecutor {
+	return e.executor
+}
+
+func (e *replayingExecutor) Execute(name string, args ...string) (string, error) {
+	if err := ExecutorContext(e).Err(); err != nil {
+		return "", err
+	}
+	return e.replayer.next(name, args)
+}
+
+func (e *replayingExecutor) ExecuteWithEnv(name string, env []string, args ...string) (string, error) {
+	return e.Execute(name, args...)
+}
+
+func (e *replayingExecutor) ExecuteInteractive(name string, args ...string) error {
+	_, err := e.Execute(name, args...)
+	return err
+}
+
+func (e *replayingExecutor) ExecuteInteractiveWithEnv(name string, env []string, args ...string) error {
+	_, err := e.Execute(name, args...)
+	return err
+}
+
+// commandKey identifies a command in the fixtures, like the commands of the
+// MockExecutor
+func commandKey(name string, args []string) string {
+	return name + " " + strings.Join(args, " ")
+}
@@ -0,0 +1,158 @@
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultUpdateServiceURL is the OpenShift update service (Cincinnati) graph endpoint
+const DefaultUpdateServiceURL = "https://api.openshift.com/api/upgrades_info/v1/graph"
+
+// ReleaseImageRepository is the repository hosting the official OpenShift release images
+const ReleaseImageRepository = "quay.io/openshift-release-dev/ocp-release"
+
+// ResolvedRelease is a release image resolved from a version number or channel
+type ResolvedRelease struct {
+	Version string
+	Channel string
+	Image   string // Tag based pullspec (e.g. quay.io/openshift-release-dev/ocp-release:4.15.12-x86_64)
+	Digest  string // Digest of the release payload (sha256:...)
+}
+
+type releaseGraph struct {
+	Nodes []struct {
+		Version string `json:"version"`
+		Payload string `json:"payload"`
+	} `json:"nodes"`
+}
+
+// ResolveRelease queries the update service graph and returns the release image
+// for the given version and/or channel. When the channel is empty it defaults to
+// stable-<major>.<minor> of the version; when the version is empty the latest
+// version available in the channel is used. arch is the release image
+// architecture suffix (e.g. x86_64).
+func ResolveRelease(graphURL, version, channel, arch string) (*ResolvedRelease, error) {
+	if version == "" && channel == "" {
+		return nil, fmt.Errorf("either a version or a channel is required")
+	}
+	if channel == "" {
+		parts := strings.Split(version, ".")
+		if len(parts) < 2 {
+			return nil, fmt.Errorf("invalid version %q: expected <major>.<minor>.<patch>", version)
+		}
+		channel = fmt.Sprintf("stable-%s.%s", parts[0], parts[1])
+	}
+	if graphURL == "" {
+		graphURL = DefaultUpdateServiceURL
+	}
+
+	query := url.Values{}
+	query.Set("channel", channel)
+	query.Set("arch", GraphArch(arch))
+
+	req, err := http.NewRequest(http.MethodGet, graphURL+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query update service: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("update service returned %s for channel %s", resp.Status, channel)
+	}
+
+	var graph releaseGraph
+	if err := json.NewDecoder(resp.Body).Decode(&graph); err != nil {
+		return nil, fmt.Errorf("failed to parse update service response: %w", err)
+	}
+
+	var found *ResolvedRelease
+	for _, node := range graph.Nodes {
+		if version != "" && node.Version != version {
+			continue
+		}
+		if found != nil && CompareVersions(node.Version, found.Version) <= 0 {
+			continue
+		}
+		found = &ResolvedRelease{Version: node.Version, Channel: channel, Digest: payloadDigest(node.Payload)}
+	}
+
+	if found == nil {
+		if version != "" {
+			return nil, fmt.Errorf("version %s not found in channel %s", version, channel)
+		}
+		return nil, fmt.Errorf("no releases found in channel %s", channel)
+	}
+
+	found.Image = fmt.Sprintf("%s:%s-%s", ReleaseImageRepository, found.Version, arch)
+	return found, nil
+}
+
+// GraphArch converts a release image architecture suffix to the name used by the update service
+func GraphArch(arch string) string {
+	switch arch {
+	case "x86_64", "":
+		return "amd64"
+	case "aarch64":
+		return "arm64"
+	default:
+		return arch
+	}
+}
+
+// CompareVersions compares two dotted versions numerically (pre-release suffixes
+// such as -rc.1 sort before the release). Returns -1, 0 or 1.
+func CompareVersions(a, b string) int {
+	aMain, aPre, _ := strings.Cut(a, "-")
+	bMain, bPre, _ := strings.Cut(b, "-")
+
+	aParts := strings.Split(aMain, ".")
+	bParts := strings.Split(bMain, ".")
+	for i := 0; i < len(aParts) || i < len(bParts); i++ {
+		var x, y int
+		if i < len(aParts) {
+			x, _ = strconv.Atoi(aParts[i])
+		}
+		if i < len(bParts) {
+			y, _ = strconv.Atoi(bParts[i])
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+
+	switch {
+	case aPre == bPre:
+		return 0
+	case aPre == "":
+		return 1
+	case bPre == "":
+		return -1
+	case aPre < bPre:
+		return -1
+	default:
+		return 1
+	}
+}
+
+// payloadDigest extracts the digest from a digest based pullspec (repo@sha256:...)
+func payloadDigest(payload string) string {
+	if _, digest, ok := strings.Cut(payload, "@"); ok {
+		return digest
+	}
+	return ""
+}
@@ -0,0 +1,69 @@
+package util
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestResolveRelease(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("channel") != "stable-4.15" {
+			http.Error(w, "unknown channel", http.StatusNotFound)
+			return
+		}
+		if r.URL.Query().Get("arch") != "amd64" {
+			t.Errorf("Expected arch amd64, got %s", r.URL.Query().Get("arch"))
+		}
+		w.Write([]byte(`{"nodes":[
+			{"version":"4.15.9","payload":"quay.io/openshift-release-dev/ocp-release@sha256:aaa"},
+			{"version":"4.15.12","payload":"quay.io/openshift-release-dev/ocp-release@sha256:bbb"},
+			{"version":"4.15.10","payload":"quay.io/openshift-release-dev/ocp-release@sha256:ccc"}
+		]}`))
+	}))
+	defer server.Close()
+
+	release, err := ResolveRelease(server.URL, "4.15.10", "", "x86_64")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if release.Image != "quay.io/openshift-release-dev/ocp-release:4.15.10-x86_64" {
+		t.Errorf("Unexpected image %s", release.Image)
+	}
+	if release.Digest != "sha256:ccc" {
+		t.Errorf("Unexpected digest %s", release.Digest)
+	}
+
+	latest, err := ResolveRelease(server.URL, "", "stable-4.15", "x86_64")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if latest.Version != "4.15.12" {
+		t.Errorf("Expected latest version 4.15.12, got %s", latest.Version)
+	}
+
+	if _, err := ResolveRelease(server.URL, "4.15.99", "", "x86_64"); err == nil {
+		t.Error("Expected error for unknown version")
+	}
+	if _, err := ResolveRelease(server.URL, "4.16.0", "", "x86_64"); err == nil {
+		t.Error("Expected error for unknown channel")
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{"4.15.12", "4.15.9", 1},
+		{"4.14.0", "4.15.0", -1},
+		{"4.15.0", "4.15.0", 0},
+		{"4.15.0-rc.1", "4.15.0", -1},
+		{"4.15.0-rc.2", "4.15.0-rc.1", 1},
+	}
+	for _, tt := range tests {
+		if got := CompareVersions(tt.a, tt.b); got != tt.expected {
+			t.Errorf("CompareVersions(%s, %s) = %d, expected %d", tt.a, tt.b, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,349 @@
+package util
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DefaultReleaseSignatureStore is the store of the signatures of the official
+// OpenShift release images
+const DefaultReleaseSignatureStore = "https://mirror.openshift.com/pub/openshift-v4/signatures/openshift/release"
+
+// DefaultClientsMirror publishes the client tarballs of the official releases,
+// under <arch>/clients/ocp/<version>, with their signed checksums
+const DefaultClientsMirror = "https://mirror.openshift.com/pub/openshift-v4"
+
+// InstallerReleaseImage returns the release image embedded in an openshift-install
+// binary, as reported by 'openshift-install version'
+func InstallerReleaseImage(executor CommandExecutor, installBin string) (string, error) {
+	output, err := executor.Execute(installBin, "version")
+	if err != nil {
+		return "", fmt.Errorf("failed to get the version of %s: %w", installBin, err)
+	}
+	for _, line := range strings.Split(output, "\n") {
+		if image, ok := strings.CutPrefix(strings.TrimSpace(line), "release image "); ok {
+			return strings.TrimSpace(image), nil
+		}
+	}
+	return "", fmt.Errorf("%s does not report its release image", installBin)
+}
+
+// VerifyInstallerRelease checks that an openshift-install binary was built for
+// the release with the given digest: the release image it embeds, which is the
+// one it installs, is pinned to that digest
+func VerifyInstallerRelease(executor CommandExecutor, installBin, releaseDigest string) error {
+	if releaseDigest == "" {
+		return fmt.Errorf("the release image digest is unknown")
+	}
+	image, err := InstallerReleaseImage(executor, installBin)
+	if err != nil {
+		return err
+	}
+	if _, digest, _ := strings.Cut(image, "@"); digest != releaseDigest {
+		return fmt.Errorf("%s installs release image %s, expected digest %s", filepath.Base(installBin), image, releaseDigest)
+	}
+	return nil
+}
+
+// InstallerVersion returns the OpenShift version of an openshift-install
+// binary, as reported on the first line of 'openshift-install version'
+func InstallerVersion(executor CommandExecutor, installBin string) (string, error) {
+	output, err := executor.Execute(installBin, "version")
+	if err != nil {
+		return "", fmt.Errorf("failed to get the version of %s: %w", installBin, err)
+	}
+	line, _, _ := strings.Cut(strings.TrimSpace(output), "\n")
+	if fields := strings.Fields(line); len(fields) == 2 {
+		return fields[1], nil
+	}
+	return "", fmt.Errorf("%s does not report its version", installBin)
+}
+
+// VerifyInstallerVersion checks that an openshift-install binary runs and
+// reports the version of versionArch. Tags that are not versions (e.g. the
+// "latest" of a CI payload) can't be compared, so only the binary running is
+// checked for them.
+func VerifyInstallerVersion(executor CommandExecutor, installBin, versionArch string) error {
+	version, err := InstallerVersion(executor, installBin)
+	if err != nil {
+		return err
+	}
+	expected := ReleaseVersion(versionArch)
+	if expected == "" || expected[0] < '0' || expected[0] > '9' {
+		return nil
+	}
+	if version != expected {
+		return fmt.Errorf("%s reports version %s, expected %s", filepath.Base(installBin), version, expected)
+	}
+	return nil
+}
+
+// VerifyCcoctl checks that a ccoctl binary runs. ccoctl doesn't report its
+// version, so its help is requested instead.
+func VerifyCcoctl(executor CommandExecutor, ccoctlBin string) error {
+	if _, err := executor.Execute(ccoctlBin, "--help"); err != nil {
+		return fmt.Errorf("%s does not run: %w", filepath.Base(ccoctlBin), err)
+	}
+	return nil
+}
+
+// VerifyPinnedImage checks that an image reference taken from the release
+// metadata is pinned to a digest, so that its content is verified on pull
+func VerifyPinnedImage(image string) error {
+	if _, digest, ok := strings.Cut(image, "@"); !ok || !strings.HasPrefix(digest, "sha256:") {
+		return fmt.Errorf("image %s is not pinned to a digest", image)
+	}
+	return nil
+}
+
+// releaseSignature is the payload of an atomic container signature
+type releaseSignature struct {
+	Critical struct {
+		Type  string `json:"type"`
+		Image struct {
+			DockerManifestDigest string `json:"docker-manifest-digest"`
+		} `json:"image"`
+	} `json:"critical"`
+}
+
+// VerifyReleaseSignature checks the signature of a release image digest with a
+// GPG public key (e.g. the Red Hat release key). The signature is downloaded
+// from the signature store (DefaultReleaseSignatureStore if empty) and checked
+// with gpg, using a temporary keyring holding only the key.
+func VerifyReleaseSignature(executor CommandExecutor, store, keyPath, releaseDigest string) error {
+	if releaseDigest == "" {
+		return fmt.Errorf("the release image digest is unknown")
+	}
+	if store == "" {
+		store = DefaultReleaseSignatureStore
+	}
+
+	// Signatures are stored by digest, sha256=<hex>/signature-<n>
+	url := fmt.Sprintf("%s/%s/signature-1", strings.TrimRight(store, "/"), strings.Replace(releaseDigest, ":", "=", 1))
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Get(url)
+	if err != nil {
+		return fmt.Errorf("failed to download release signature: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("no signature for release %s: %s returned %s", releaseDigest, url, resp.Status)
+	}
+	signature, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to download release signature: %w", err)
+	}
+
+	dir, err := os.MkdirTemp("", "release-signature-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	signaturePath := filepath.Join(dir, "signature-1")
+	if err := os.WriteFile(signaturePath, signature, 0600); err != nil {
+		return err
+	}
+
+	gpg, err := gpgKeyring(executor, dir, keyPath)
+	if err != nil {
+		return err
+	}
+	// --decrypt fails unless the signature is good, and prints the signed payload
+	payload, err := executor.Execute("gpg", append(gpg, "--quiet", "--decrypt", signaturePath)...)
+	if err != nil {
+		return fmt.Errorf("invalid release signature: %w", err)
+	}
+
+	var signed releaseSignature
+	if err := json.Unmarshal([]byte(signedPayload(payload)), &signed); err != nil {
+		return fmt.Errorf("failed to parse release signature: %w", err)
+	}
+	if signed.Critical.Type != "atomic container signature" || signed.Critical.Image.DockerManifestDigest != releaseDigest {
+		return fmt.Errorf("release signature is for %s, expected %s", signed.Critical.Image.DockerManifestDigest, releaseDigest)
+	}
+	return nil
+}
+
+// signedPayload returns the JSON document of the gpg output, which is combined
+// with the gpg messages
+func signedPayload(output string) string {
+	start := strings.Index(output, "{")
+	end := strings.LastIndex(output, "}")
+	if start < 0 || end < start {
+		return output
+	}
+	return output[start : end+1]
+}
+
+// gpgKeyring imports a GPG public key into a keyring of dir holding only that
+// key, and returns the gpg arguments using it
+func gpgKeyring(executor CommandExecutor, dir, keyPath string) ([]string, error) {
+	gpg := []string{"--batch", "--no-default-keyring", "--keyring", filepath.Join(dir, "keyring.gpg")}
+	if err := RunCommand(executor, "gpg", append(gpg, "--import", keyPath)...); err != nil {
+		return nil, fmt.Errorf("failed to import release signing key: %w", err)
+	}
+	return gpg, nil
+}
+
+// ClientTarball returns the name of the mirror tarball holding a command
+// built for a host (as returned by HostCommandOS, e.g. "mac/arm64"). Linux
+// tarballs carry the host architecture when it differs from the release one.
+func ClientTarball(command, commandOS, versionArch string) string {
+	osName, goarch, _ := strings.Cut(commandOS, "/")
+	suffix := osName
+	switch {
+	case osName == "mac" && goarch == "arm64":
+		suffix += "-arm64"
+	case osName != "mac" && releaseArchFromGOARCH(goarch) != ReleaseArch(versionArch) && ReleaseArch(versionArch) != "multi":
+		suffix += "-" + goarch
+	}
+	return fmt.Sprintf("%s-%s-%s.tar.gz", command, suffix, ReleaseVersion(versionArch))
+}
+
+// clientsURL returns the mirror directory of the clients of a release
+func clientsURL(mirror, versionArch string) string {
+	if mirror == "" {
+		mirror = DefaultClientsMirror
+	}
+	return fmt.Sprintf("%s/%s/clients/ocp/%s", strings.TrimRight(mirror, "/"), ReleaseArch(versionArch), ReleaseVersion(versionArch))
+}
+
+// ClientChecksums downloads the sha256 checksums of the client tarballs of a
+// release from the mirror (DefaultClientsMirror if empty), by tarball name.
+// With a GPG public key, the checksums must be signed by it
+// (sha256sum.txt.gpg), as checked with gpg.
+func ClientChecksums(executor CommandExecutor, mirror, keyPath, versionArch string) (map[string]string, error) {
+	base := clientsURL(mirror, versionArch)
+	checksums, err := download(base + "/sha256sum.txt")
+	if err != nil {
+		return nil, fmt.Errorf("failed to download the client checksums: %w", err)
+	}
+
+	if keyPath != "" {
+		signature, err := download(base + "/sha256sum.txt.gpg")
+		if err != nil {
+			return nil, fmt.Errorf("failed to download the client checksums signature: %w", err)
+		}
+		dir, err := os.MkdirTemp("", "client-checksums-")
+		if err != nil {
+			return nil, err
+		}
+		defer os.RemoveAll(dir)
+		checksumsPath := filepath.Join(dir, "sha256sum.txt")
+		signaturePath := filepath.Join(dir, "sha256sum.txt.gpg")
+		if err := os.WriteFile(checksumsPath, checksums, 0600); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(signaturePath, signature, 0600); err != nil {
+			return nil, err
+		}
+		gpg, err := gpgKeyring(executor, dir, keyPath)
+		if err != nil {
+			return nil, err
+		}
+		if err := RunCommand(executor, "gpg", append(gpg, "--verify", signaturePath, checksumsPath)...); err != nil {
+			return nil, fmt.Errorf("invalid client checksums signature: %w", err)
+		}
+	}
+
+	sums := map[string]string{}
+	scanner := bufio.NewScanner(strings.NewReader(string(checksums)))
+	for scanner.Scan() {
+		if fields := strings.Fields(scanner.Text()); len(fields) == 2 {
+			sums[strings.TrimPrefix(fields[1], "*")] = fields[0]
+		}
+	}
+	return sums, nil
+}
+
+// VerifyClientBinary checks a binary extracted from the release against the
+// checksums of the client tarballs: the mirror tarball holding the command
+// must match its checksum, and contain the same file as path
+func VerifyClientBinary(mirror, versionArch string, checksums map[string]string, tarball, path string) error {
+	expected, ok := checksums[tarball]
+	if !ok {
+		return fmt.Errorf("no checksum published for %s", tarball)
+	}
+	actual, err := FileSHA256(path)
+	if err != nil {
+		return err
+	}
+
+	url := clientsURL(mirror, versionArch) + "/" + tarball
+	client := &http.Client{Timeout: 30 * time.Minute}
+	resp, err := client.Get(url)
+	if err != nil {
+		return fmt.Errorf("failed to download %s: %w", tarball, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to download %s: %s", url, resp.Status)
+	}
+
+	// The tarball is hashed while the binary is read from it
+	tarballHash := sha256.New()
+	body := io.TeeReader(resp.Body, tarballHash)
+	gz, err := gzip.NewReader(body)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", tarball, err)
+	}
+	published := ""
+	archive := tar.NewReader(gz)
+	for {
+		header, err := archive.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", tarball, err)
+		}
+		if header.Typeflag != tar.TypeReg || filepath.Base(header.Name) != filepath.Base(path) {
+			continue
+		}
+		hash := sha256.New()
+		if _, err := io.Copy(hash, archive); err != nil {
+			return fmt.Errorf("failed to read %s: %w", tarball, err)
+		}
+		published = hex.EncodeToString(hash.Sum(nil))
+	}
+	if _, err := io.Copy(io.Discard, body); err != nil {
+		return fmt.Errorf("failed to download %s: %w", tarball, err)
+	}
+
+	if sum := hex.EncodeToString(tarballHash.Sum(nil)); sum != expected {
+		return fmt.Errorf("%s has checksum %s, expected %s", tarball, sum, expected)
+	}
+	if published == "" {
+		return fmt.Errorf("%s does not contain %s", tarball, filepath.Base(path))
+	}
+	if actual != published {
+		return fmt.Errorf("%s has checksum %s, expected %s as published in %s", filepath.Base(path), actual, published, tarball)
+	}
+	return nil
+}
+
+// download returns the content of a URL
+func download(url string) ([]byte, error) {
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned %s", url, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
@@ -0,0 +1,194 @@
+package util
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestVerifyInstallerRelease(t *testing.T) {
+	executor := NewMockExecutor()
+	executor.SetOutput("bin/openshift-install version", `bin/openshift-install 4.15.0
+built from commit 0123abcd
+release image quay.io/openshift-release-dev/ocp-release@sha256:1111
+release architecture amd64
+`)
+
+	if err := VerifyInstallerRelease(executor, "bin/openshift-install", "sha256:1111"); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if err := VerifyInstallerRelease(executor, "bin/openshift-install", "sha256:2222"); err == nil || !strings.Contains(err.Error(), "expected digest sha256:2222") {
+		t.Errorf("Expected a digest mismatch, got %v", err)
+	}
+	if err := VerifyInstallerRelease(executor, "bin/openshift-install", ""); err == nil {
+		t.Error("Expected an error without release digest")
+	}
+
+	if err := VerifyInstallerVersion(executor, "bin/openshift-install", "4.15.0-x86_64"); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if err := VerifyInstallerVersion(executor, "bin/openshift-install", "4.14.0-x86_64"); err == nil || !strings.Contains(err.Error(), "reports version 4.15.0, expected 4.14.0") {
+		t.Errorf("Expected a version mismatch, got %v", err)
+	}
+	if err := VerifyInstallerVersion(executor, "bin/openshift-install", "latest"); err != nil {
+		t.Errorf("Expected a tag that is not a version to be accepted, got %v", err)
+	}
+	executor.SetError("bin/openshift-install version", fmt.Errorf("exec format error"))
+	if err := VerifyInstallerVersion(executor, "bin/openshift-install", "latest"); err == nil {
+		t.Error("Expected an error for a binary that does not run")
+	}
+
+	if err := VerifyPinnedImage("quay.io/openshift-release-dev/ocp-v4.0-art-dev@sha256:3333"); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if err := VerifyPinnedImage("quay.io/openshift-release-dev/ocp-v4.0-art-dev:latest"); err == nil {
+		t.Error("Expected an error for an image pinned by tag")
+	}
+}
+
+// gpgExecutor prints a signed payload on gpg --decrypt
+type gpgExecutor struct {
+	*MockExecutor
+	payload string
+}
+
+func (e *gpgExecutor) Execute(name string, args ...string) (string, error) {
+	e.MockExecutor.Execute(name, args...)
+	if name == "gpg" && strings.Contains(strings.Join(args, " "), "--decrypt") {
+		return "gpg: Signature made Mon May  1 10:00:00 2024 UTC\n" + e.payload + "\ngpg: Good signature from \"Red Hat, Inc. (release key 2)\"\n", nil
+	}
+	return "", nil
+}
+
+func TestVerifyReleaseSignature(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/sha256=1111/signature-1" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("signature"))
+	}))
+	defer server.Close()
+
+	executor := &gpgExecutor{
+		MockExecutor: NewMockExecutor(),
+		payload:      `{"critical":{"image":{"docker-manifest-digest":"sha256:1111"},"type":"atomic container signature","identity":{"docker-reference":"quay.io/openshift-release-dev/ocp-release:4.15.0-x86_64"}},"optional":{"creator":"Red Hat OpenShift Signing Authority 0.0.1"}}`,
+	}
+	if err := VerifyReleaseSignature(executor, server.URL, "release-key.gpg", "sha256:1111"); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if !executor.WasExecutedContaining("--import release-key.gpg") {
+		t.Errorf("Expected the signing key to be imported, got %v", executor.Commands)
+	}
+
+	// The signature is for another release
+	executor.payload = strings.Replace(executor.payload, "sha256:1111", "sha256:2222", 1)
+	if err := VerifyReleaseSignature(executor, server.URL, "release-key.gpg", "sha256:1111"); err == nil {
+		t.Error("Expected an error for a signature of another release")
+	}
+
+	if err := VerifyReleaseSignature(executor, server.URL, "release-key.gpg", "sha256:3333"); err == nil || !strings.Contains(err.Error(), "no signature") {
+		t.Errorf("Expected an error for a release without signature, got %v", err)
+	}
+}
+
+func TestClientTarball(t *testing.T) {
+	tests := []struct {
+		command, commandOS, versionArch, expected string
+	}{
+		{"openshift-install", "linux/amd64", "4.15.0-x86_64", "openshift-install-linux-4.15.0.tar.gz"},
+		{"openshift-install", "linux/arm64", "4.15.0-x86_64", "openshift-install-linux-arm64-4.15.0.tar.gz"},
+		{"openshift-install", "linux/arm64", "4.15.0-aarch64", "openshift-install-linux-4.15.0.tar.gz"},
+		{"openshift-install", "mac/amd64", "4.15.0-x86_64", "openshift-install-mac-4.15.0.tar.gz"},
+		{"openshift-install", "mac/arm64", "4.15.0-x86_64", "openshift-install-mac-arm64-4.15.0.tar.gz"},
+		{"ccoctl", "linux/amd64", "4.15.0-x86_64", "ccoctl-linux-4.15.0.tar.gz"},
+	}
+	for _, tt := range tests {
+		if got := ClientTarball(tt.command, tt.commandOS, tt.versionArch); got != tt.expected {
+			t.Errorf("ClientTarball(%s, %s, %s) = %s, expected %s", tt.command, tt.commandOS, tt.versionArch, got, tt.expected)
+		}
+	}
+}
+
+func TestVerifyClientBinary(t *testing.T) {
+	var archive bytes.Buffer
+	gz := gzip.NewWriter(&archive)
+	tw := tar.NewWriter(gz)
+	tw.WriteHeader(&tar.Header{Name: "README.md", Mode: 0644, Size: 6, Typeflag: tar.TypeReg})
+	tw.Write([]byte("readme"))
+	tw.WriteHeader(&tar.Header{Name: "ccoctl", Mode: 0755, Size: 6, Typeflag: tar.TypeReg})
+	tw.Write([]byte("ccoctl"))
+	tw.Close()
+	gz.Close()
+	sum := sha256.Sum256(archive.Bytes())
+	checksums := hex.EncodeToString(sum[:]) + "  ccoctl-linux-4.15.0.tar.gz\n"
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/x86_64/clients/ocp/4.15.0/sha256sum.txt":
+			w.Write([]byte(checksums))
+		case "/x86_64/clients/ocp/4.15.0/sha256sum.txt.gpg":
+			w.Write([]byte("signature"))
+		case "/x86_64/clients/ocp/4.15.0/ccoctl-linux-4.15.0.tar.gz":
+			w.Write(archive.Bytes())
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	executor := NewMockExecutor()
+	sums, err := ClientChecksums(executor, server.URL, "release-key.gpg", "4.15.0-x86_64")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !executor.WasExecutedContaining("--import release-key.gpg") || !executor.WasExecutedContaining("--verify") {
+		t.Errorf("Expected the checksums signature to be verified, got %v", executor.Commands)
+	}
+
+	binary := filepath.Join(t.TempDir(), "ccoctl")
+	os.WriteFile(binary, []byte("ccoctl"), 0755)
+	if err := VerifyClientBinary(server.URL, "4.15.0-x86_64", sums, "ccoctl-linux-4.15.0.tar.gz", binary); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	os.WriteFile(binary, []byte("tampered"), 0755)
+	if err := VerifyClientBinary(server.URL, "4.15.0-x86_64", sums, "ccoctl-linux-4.15.0.tar.gz", binary); err == nil || !strings.Contains(err.Error(), "as published in") {
+		t.Errorf("Expected a checksum error for a tampered binary, got %v", err)
+	}
+	if err := VerifyClientBinary(server.URL, "4.15.0-x86_64", sums, "ccoctl-linux-arm64-4.15.0.tar.gz", binary); err == nil || !strings.Contains(err.Error(), "no checksum") {
+		t.Errorf("Expected an error for a tarball without checksum, got %v", err)
+	}
+
+	// The tarball does not match its published checksum
+	sums["ccoctl-linux-4.15.0.tar.gz"] = strings.Repeat("0", 64)
+	os.WriteFile(binary, []byte("ccoctl"), 0755)
+	if err := VerifyClientBinary(server.URL, "4.15.0-x86_64", sums, "ccoctl-linux-4.15.0.tar.gz", binary); err == nil || !strings.Contains(err.Error(), "ccoctl-linux-4.15.0.tar.gz has checksum") {
+		t.Errorf("Expected a checksum error for the tarball, got %v", err)
+	}
+
+	if _, err := ClientChecksums(&gpgFailExecutor{executor}, server.URL, "release-key.gpg", "4.15.0-x86_64"); err == nil {
+		t.Error("Expected an error for a bad checksums signature")
+	}
+}
+
+// gpgFailExecutor fails the gpg signature verification
+type gpgFailExecutor struct {
+	*MockExecutor
+}
+
+func (e *gpgFailExecutor) Execute(name string, args ...string) (string, error) {
+	if name == "gpg" && strings.Contains(strings.Join(args, " "), "--verify") {
+		return "gpg: BAD signature", fmt.Errorf("exit status 1")
+	}
+	return e.MockExecutor.Execute(name, args...)
+}
@@ -0,0 +1,169 @@
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DefaultWrapperReleasesURL is the GitHub API endpoint of the latest release of
+// the wrapper
+const DefaultWrapperReleasesURL = "https://api.github.com/repos/clobrano/openshift-sts-wrapper/releases/latest"
+
+// WrapperRelease is a published release of the wrapper
+type WrapperRelease struct {
+	Version string         // Tag without the v prefix (e.g. 0.2.0)
+	URL     string         // Release notes page
+	Assets  []ReleaseAsset // Binaries attached to the release
+}
+
+// ReleaseAsset is a file attached to a release
+type ReleaseAsset struct {
+	Name string
+	URL  string
+}
+
+// LatestWrapperRelease queries the GitHub API for the latest release of the
+// wrapper
+func LatestWrapperRelease(releasesURL string) (*WrapperRelease, error) {
+	if releasesURL == "" {
+		releasesURL = DefaultWrapperReleasesURL
+	}
+	req, err := http.NewRequest(http.MethodGet, releasesURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query the latest release: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to query the latest release: %s returned %s", releasesURL, resp.Status)
+	}
+
+	var release struct {
+		TagName string `json:"tag_name"`
+		HTMLURL string `json:"html_url"`
+		Assets  []struct {
+			Name               string `json:"name"`
+			BrowserDownloadURL string `json:"browser_download_url"`
+		} `json:"assets"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+		return nil, fmt.Errorf("failed to parse the latest release: %w", err)
+	}
+	if release.TagName == "" {
+		return nil, fmt.Errorf("the latest release has no tag")
+	}
+
+	result := &WrapperRelease{Version: strings.TrimPrefix(release.TagName, "v"), URL: release.HTMLURL}
+	for _, asset := range release.Assets {
+		result.Assets = append(result.Assets, ReleaseAsset{Name: asset.Name, URL: asset.BrowserDownloadURL})
+	}
+	return result, nil
+}
+
+// WrapperAssetName returns the name of the release binary of the wrapper for
+// an OS and architecture (e.g. openshift-sts-wrapper-linux-amd64)
+func WrapperAssetName(goos, goarch string) string {
+	return fmt.Sprintf("openshift-sts-wrapper-%s-%s", goos, goarch)
+}
+
+// Asset returns the asset of the release with the given name, nil if missing
+func (r *WrapperRelease) Asset(name string) *ReleaseAsset {
+	for i := range r.Assets {
+		if r.Assets[i].Name == name {
+			return &r.Assets[i]
+		}
+	}
+	return nil
+}
+
+// ReplaceExecutable replaces the binary at path with the asset of the release
+// named assetName, verified against the <assetName>.sha256 asset of the
+// release (make release). The new binary is written next to the old one and
+// renamed over it, so that a failed download never breaks it.
+func ReplaceExecutable(release *WrapperRelease, assetName, path string) error {
+	asset := release.Asset(assetName)
+	if asset == nil {
+		return fmt.Errorf("release %s has no %s binary", release.Version, assetName)
+	}
+	checksumAsset := release.Asset(assetName + ".sha256")
+	if checksumAsset == nil {
+		return fmt.Errorf("release %s has no %s.sha256 checksum, not installing an unverified binary", release.Version, assetName)
+	}
+
+	client := &http.Client{Timeout: 10 * time.Minute}
+	resp, err := client.Get(asset.URL)
+	if err != nil {
+		return fmt.Errorf("failed to download %s: %w", assetName, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to download %s: %s returned %s", assetName, asset.URL, resp.Status)
+	}
+
+	partial := filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+".partial")
+	file, err := os.OpenFile(partial, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0755)
+	if err != nil {
+		return fmt.Errorf("cannot write next to %s: %w", path, err)
+	}
+	if _, err := io.Copy(file, resp.Body); err != nil {
+		file.Close()
+		os.Remove(partial)
+		return fmt.Errorf("failed to download %s: %w", assetName, err)
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(partial)
+		return err
+	}
+
+	if err := verifyDownload(client, checksumAsset.URL, partial); err != nil {
+		os.Remove(partial)
+		return err
+	}
+	if err := os.Rename(partial, path); err != nil {
+		os.Remove(partial)
+		return fmt.Errorf("failed to replace %s: %w", path, err)
+	}
+	return nil
+}
+
+// verifyDownload compares the sha256 checksum of a file with the one published
+// at checksumURL, in the format of sha256sum
+func verifyDownload(client *http.Client, checksumURL, path string) error {
+	resp, err := client.Get(checksumURL)
+	if err != nil {
+		return fmt.Errorf("failed to download the checksum: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to download the checksum: %s returned %s", checksumURL, resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to download the checksum: %w", err)
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return fmt.Errorf("empty checksum at %s", checksumURL)
+	}
+
+	actual, err := FileSHA256(path)
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(actual, fields[0]) {
+		return fmt.Errorf("checksum mismatch: expected %s, got %s", fields[0], actual)
+	}
+	return nil
+}
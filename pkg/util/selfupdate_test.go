@@ -0,0 +1,121 @@
+package util
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newReleaseServer serves a latest release with a linux/amd64 binary and its
+// checksum
+func newReleaseServer(t *testing.T, binary, checksum string) *httptest.Server {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/releases/latest":
+			fmt.Fprintf(w, `{"tag_name": "v0.2.0", "html_url": "https://example.com/v0.2.0", "assets": [
+				{"name": "openshift-sts-wrapper-linux-amd64", "browser_download_url": "%[1]s/linux-amd64"},
+				{"name": "openshift-sts-wrapper-linux-amd64.sha256", "browser_download_url": "%[1]s/linux-amd64.sha256"}]}`, server.URL)
+		case "/linux-amd64":
+			w.Write([]byte(binary))
+		case "/linux-amd64.sha256":
+			fmt.Fprintf(w, "%s  openshift-sts-wrapper-linux-amd64\n", checksum)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestLatestWrapperRelease(t *testing.T) {
+	server := newReleaseServer(t, "", "")
+
+	release, err := LatestWrapperRelease(server.URL + "/releases/latest")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if release.Version != "0.2.0" || release.URL != "https://example.com/v0.2.0" {
+		t.Errorf("Unexpected release %+v", release)
+	}
+	if asset := release.Asset(WrapperAssetName("linux", "amd64")); asset == nil || asset.URL != server.URL+"/linux-amd64" {
+		t.Errorf("Expected the linux/amd64 binary, got %+v", asset)
+	}
+	if asset := release.Asset(WrapperAssetName("darwin", "arm64")); asset != nil {
+		t.Errorf("Expected no darwin/arm64 binary, got %+v", asset)
+	}
+
+	if _, err := LatestWrapperRelease(server.URL + "/missing"); err == nil {
+		t.Error("Expected an error for a missing release")
+	}
+}
+
+func TestReplaceExecutable(t *testing.T) {
+	binary := "#!/bin/sh\necho 0.2.0\n"
+	sum := sha256.Sum256([]byte(binary))
+	server := newReleaseServer(t, binary, hex.EncodeToString(sum[:]))
+	release, err := LatestWrapperRelease(server.URL + "/releases/latest")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "openshift-sts-wrapper")
+	os.WriteFile(path, []byte("old"), 0755)
+	if err := ReplaceExecutable(release, "openshift-sts-wrapper-linux-amd64", path); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	info, _ := os.Stat(path)
+	if string(data) != binary || info.Mode()&0100 == 0 {
+		t.Errorf("Expected the executable of the release, got %q (%s)", data, info.Mode())
+	}
+
+	if err := ReplaceExecutable(release, "openshift-sts-wrapper-darwin-arm64", path); err == nil {
+		t.Error("Expected an error for a platform without binary")
+	}
+}
+
+func TestReplaceExecutableWithoutChecksum(t *testing.T) {
+	server := newReleaseServer(t, "#!/bin/sh\necho 0.2.0\n", "")
+	release, err := LatestWrapperRelease(server.URL + "/releases/latest")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	release.Assets = release.Assets[:1]
+
+	path := filepath.Join(t.TempDir(), "openshift-sts-wrapper")
+	os.WriteFile(path, []byte("old"), 0755)
+	if err := ReplaceExecutable(release, "openshift-sts-wrapper-linux-amd64", path); err == nil || !strings.Contains(err.Error(), ".sha256") {
+		t.Fatalf("Expected an error without checksum, got %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "old" {
+		t.Errorf("Expected the binary to be kept, got %q", data)
+	}
+}
+
+func TestReplaceExecutableChecksumMismatch(t *testing.T) {
+	server := newReleaseServer(t, "tampered", "0000")
+	release, err := LatestWrapperRelease(server.URL + "/releases/latest")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "openshift-sts-wrapper")
+	os.WriteFile(path, []byte("old"), 0755)
+	if err := ReplaceExecutable(release, "openshift-sts-wrapper-linux-amd64", path); err == nil {
+		t.Fatal("Expected a checksum mismatch")
+	}
+	if data, _ := os.ReadFile(path); string(data) != "old" {
+		t.Errorf("Expected the binary to be kept, got %q", data)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("Expected the partial download to be removed, got %v", entries)
+	}
+}
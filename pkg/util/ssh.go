@@ -1,12 +1,20 @@
 package util
 
 import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/pem"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 )
 
+// defaultSSHKeys are the public keys looked for in ~/.ssh, in order of preference
+var defaultSSHKeys = []string{"id_ed25519.pub", "id_ecdsa.pub", "id_rsa.pub"}
+
 // FindSSHKeyPath searches for a file in ~/.ssh directory that contains the given SSH key content.
 // If multiple files match, returns the first one found.
 // Returns error if ~/.ssh doesn't exist or no matching file is found.
@@ -50,3 +58,80 @@ func FindSSHKeyPath(sshKeyContent string) (string, error) {
 
 	return "", fmt.Errorf("no matching SSH key file found in ~/.ssh")
 }
+
+// FindDefaultSSHKey returns the path of the default SSH public key of the user
+// (~/.ssh/id_ed25519.pub, id_ecdsa.pub or id_rsa.pub), or an empty string when
+// there is none
+func FindDefaultSSHKey() string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	for _, name := range defaultSSHKeys {
+		if path := filepath.Join(homeDir, ".ssh", name); FileExists(path) {
+			return path
+		}
+	}
+	return ""
+}
+
+// GenerateSSHKeyPair generates an ed25519 key pair in dir (id_ed25519 and
+// id_ed25519.pub, in OpenSSH format) and returns the path of the public key
+func GenerateSSHKeyPair(dir, comment string) (string, error) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate SSH key: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+
+	privatePath := filepath.Join(dir, "id_ed25519")
+	privatePEM := pem.EncodeToMemory(&pem.Block{Type: "OPENSSH PRIVATE KEY", Bytes: marshalOpenSSHPrivateKey(publicKey, privateKey, comment)})
+	if err := os.WriteFile(privatePath, privatePEM, 0600); err != nil {
+		return "", fmt.Errorf("failed to write SSH private key: %w", err)
+	}
+
+	publicPath := privatePath + ".pub"
+	authorizedKey := fmt.Sprintf("ssh-ed25519 %s %s\n", base64.StdEncoding.EncodeToString(marshalSSHPublicKey(publicKey)), comment)
+	if err := os.WriteFile(publicPath, []byte(authorizedKey), 0644); err != nil {
+		return "", fmt.Errorf("failed to write SSH public key: %w", err)
+	}
+	return publicPath, nil
+}
+
+// sshString encodes data as an SSH wire format string (length-prefixed)
+func sshString(data []byte) []byte {
+	encoded := binary.BigEndian.AppendUint32(nil, uint32(len(data)))
+	return append(encoded, data...)
+}
+
+// marshalSSHPublicKey encodes an ed25519 public key in the SSH wire format
+func marshalSSHPublicKey(publicKey ed25519.PublicKey) []byte {
+	return append(sshString([]byte("ssh-ed25519")), sshString(publicKey)...)
+}
+
+// marshalOpenSSHPrivateKey encodes an unencrypted ed25519 private key in the
+// openssh-key-v1 format (see PROTOCOL.key in the OpenSSH sources)
+func marshalOpenSSHPrivateKey(publicKey ed25519.PublicKey, privateKey ed25519.PrivateKey, comment string) []byte {
+	check := make([]byte, 4)
+	rand.Read(check)
+
+	private := append(append([]byte{}, check...), check...)
+	private = append(private, sshString([]byte("ssh-ed25519"))...)
+	private = append(private, sshString(publicKey)...)
+	private = append(private, sshString(privateKey)...)
+	private = append(private, sshString([]byte(comment))...)
+	// Pad to the cipher block size (8 without encryption) with 1, 2, 3...
+	for i := byte(1); len(private)%8 != 0; i++ {
+		private = append(private, i)
+	}
+
+	key := []byte("openssh-key-v1\x00")
+	key = append(key, sshString([]byte("none"))...) // cipher
+	key = append(key, sshString([]byte("none"))...) // kdf
+	key = append(key, sshString(nil)...)            // kdf options
+	key = binary.BigEndian.AppendUint32(key, 1)     // number of keys
+	key = append(key, sshString(marshalSSHPublicKey(publicKey))...)
+	return append(key, sshString(private)...)
+}
@@ -0,0 +1,65 @@
+package util
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFindDefaultSSHKey(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	if path := FindDefaultSSHKey(); path != "" {
+		t.Errorf("Expected no key, got %s", path)
+	}
+
+	os.MkdirAll(filepath.Join(home, ".ssh"), 0700)
+	os.WriteFile(filepath.Join(home, ".ssh", "id_rsa.pub"), []byte("ssh-rsa AAAA user@host\n"), 0644)
+	os.WriteFile(filepath.Join(home, ".ssh", "id_ed25519.pub"), []byte("ssh-ed25519 AAAA user@host\n"), 0644)
+	if path := FindDefaultSSHKey(); path != filepath.Join(home, ".ssh", "id_ed25519.pub") {
+		t.Errorf("Expected the ed25519 key to be preferred, got %s", path)
+	}
+}
+
+func TestGenerateSSHKeyPair(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "ssh")
+
+	publicPath, err := GenerateSSHKeyPair(dir, "openshift-sts-wrapper-test")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if publicPath != filepath.Join(dir, "id_ed25519.pub") {
+		t.Errorf("Unexpected public key path %s", publicPath)
+	}
+
+	public, _ := os.ReadFile(publicPath)
+	fields := strings.Fields(string(public))
+	if len(fields) != 3 || fields[0] != "ssh-ed25519" || fields[2] != "openshift-sts-wrapper-test" {
+		t.Fatalf("Unexpected public key %q", public)
+	}
+	publicBlob, err := base64.StdEncoding.DecodeString(fields[1])
+	if err != nil {
+		t.Fatalf("Public key is not base64 encoded: %v", err)
+	}
+
+	info, err := os.Stat(filepath.Join(dir, "id_ed25519"))
+	if err != nil {
+		t.Fatalf("Private key not written: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected the private key to be readable by the owner only, got %v", info.Mode().Perm())
+	}
+	private, _ := os.ReadFile(filepath.Join(dir, "id_ed25519"))
+	block, _ := pem.Decode(private)
+	if block == nil || block.Type != "OPENSSH PRIVATE KEY" {
+		t.Fatalf("Unexpected private key %q", private)
+	}
+	if !bytes.HasPrefix(block.Bytes, []byte("openssh-key-v1\x00")) || !bytes.Contains(block.Bytes, publicBlob) {
+		t.Error("Expected an openssh-key-v1 private key holding the public key")
+	}
+}
@@ -0,0 +1,250 @@
+package util
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tracingService is the service.name of exported spans
+const tracingService = "openshift-sts-wrapper"
+
+// Tracer collects the spans of a run, to be exported via OTLP at the end of it.
+// A nil Tracer (and the nil spans it returns) is valid and records nothing.
+type Tracer struct {
+	mu      sync.Mutex
+	traceID string
+	spans   []*Span
+}
+
+// Span is a timed operation of a trace
+type Span struct {
+	tracer     *Tracer
+	id         string
+	parentID   string
+	name       string
+	start      time.Time
+	end        time.Time
+	attributes map[string]string
+	err        error
+}
+
+// NewTracer starts a new trace
+func NewTracer() *Tracer {
+	return &Tracer{traceID: randomHex(16)}
+}
+
+// TraceID returns the hex-encoded trace ID, or "" for a nil tracer
+func (t *Tracer) TraceID() string {
+	if t == nil {
+		return ""
+	}
+	return t.traceID
+}
+
+// StartSpan starts a span, child of parent if not nil
+func (t *Tracer) StartSpan(name string, parent *Span) *Span {
+	if t == nil {
+		return nil
+	}
+	span := &Span{tracer: t, id: randomHex(8), name: name, start: time.Now(), attributes: map[string]string{}}
+	if parent != nil {
+		span.parentID = parent.id
+	}
+	t.mu.Lock()
+	t.spans = append(t.spans, span)
+	t.mu.Unlock()
+	return span
+}
+
+// StartChild starts a span that is a child of s
+func (s *Span) StartChild(name string) *Span {
+	if s == nil {
+		return nil
+	}
+	return s.tracer.StartSpan(name, s)
+}
+
+// SetAttribute sets a string attribute of the span
+func (s *Span) SetAttribute(key, value string) {
+	if s == nil {
+		return
+	}
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	s.attributes[key] = value
+}
+
+// End ends the span, marking it as failed if err is not nil
+func (s *Span) End(err error) {
+	if s == nil {
+		return
+	}
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	s.end = time.Now()
+	s.err = err
+}
+
+// commandSpanName names the span of an external command after the binary and
+// its subcommand (e.g. "aws s3api", "oc adm")
+func commandSpanName(name string, args []string) string {
+	spanName := filepath.Base(name)
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		spanName += " " + args[0]
+	}
+	return spanName
+}
+
+// OTLP/HTTP JSON encoding of the exported spans
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Status            otlpStatus      `json:"status"`
+}
+
+type otlpAttribute struct {
+	Key   string         `json:"key"`
+	Value otlpStringAttr `json:"value"`
+}
+
+type otlpStringAttr struct {
+	StringValue string `json:"stringValue"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code,omitempty"` // 1 = OK, 2 = ERROR
+	Message string `json:"message,omitempty"`
+}
+
+// otlpPayload encodes the spans of the trace. Spans that were never ended
+// (e.g. on a crash) are exported as ending now.
+func (t *Tracer) otlpPayload() otlpRequest {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	var spans []otlpSpan
+	for _, span := range t.spans {
+		end := span.end
+		if end.IsZero() {
+			end = now
+		}
+		encoded := otlpSpan{
+			TraceID:           t.traceID,
+			SpanID:            span.id,
+			ParentSpanID:      span.parentID,
+			Name:              span.name,
+			Kind:              1, // SPAN_KIND_INTERNAL
+			StartTimeUnixNano: strconv.FormatInt(span.start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(end.UnixNano(), 10),
+			Attributes:        otlpAttributes(span.attributes),
+			Status:            otlpStatus{Code: 1},
+		}
+		if span.err != nil {
+			encoded.Status = otlpStatus{Code: 2, Message: span.err.Error()}
+		}
+		spans = append(spans, encoded)
+	}
+
+	return otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource:   otlpResource{Attributes: otlpAttributes(map[string]string{"service.name": tracingService})},
+		ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{Name: tracingService}, Spans: spans}},
+	}}}
+}
+
+func otlpAttributes(attributes map[string]string) []otlpAttribute {
+	var keys []string
+	for key := range attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var encoded []otlpAttribute
+	for _, key := range keys {
+		encoded = append(encoded, otlpAttribute{Key: key, Value: otlpStringAttr{StringValue: attributes[key]}})
+	}
+	return encoded
+}
+
+// Export sends the spans of the trace to an OTLP/HTTP collector. The endpoint
+// is the collector base URL (e.g. http://localhost:4318); the /v1/traces path
+// is appended unless already present.
+func (t *Tracer) Export(endpoint string) error {
+	if t == nil {
+		return nil
+	}
+	body, err := json.Marshal(t.otlpPayload())
+	if err != nil {
+		return fmt.Errorf("failed to encode spans: %w", err)
+	}
+
+	target := strings.TrimSuffix(endpoint, "/")
+	if !strings.HasSuffix(target, "/v1/traces") {
+		target += "/v1/traces"
+	}
+
+	client := &http.Client{Timeout: 15 * time.Second}
+	resp, err := client.Post(target, "application/json", bytes.NewReader(body))
+	if err != nil {
+		// The URL may embed credentials, keep it out of the error
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("failed to export spans: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("OTLP collector returned %s", resp.Status)
+	}
+	return nil
+}
+
+func randomHex(size int) string {
+	b := make([]byte, size)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
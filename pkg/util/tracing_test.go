@@ -0,0 +1,90 @@
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/clobrano/openshift-sts-wrapper/pkg/logger"
+)
+
+func TestTracerExport(t *testing.T) {
+	var path string
+	var received otlpRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		json.NewDecoder(r.Body).Decode(&received)
+	}))
+	defer server.Close()
+
+	tracer := NewTracer()
+	root := tracer.StartSpan("install", nil)
+	step := root.StartChild("[Step 7] Create AWS resources")
+	executor := &RealExecutor{Span: step}
+	logger.AddSecret("span-secret")
+	executor.Execute("sh", "-c", "exit 3", "span-secret")
+	step.End(fmt.Errorf("step failed"))
+	root.End(nil)
+
+	if err := tracer.Export(server.URL); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if path != "/v1/traces" {
+		t.Errorf("Expected spans to be posted to /v1/traces, got %s", path)
+	}
+
+	spans := received.ResourceSpans[0].ScopeSpans[0].Spans
+	if len(spans) != 3 {
+		t.Fatalf("Expected 3 spans, got %d", len(spans))
+	}
+	byName := map[string]otlpSpan{}
+	for _, span := range spans {
+		if span.TraceID != tracer.TraceID() || len(span.TraceID) != 32 || len(span.SpanID) != 16 {
+			t.Errorf("Unexpected span IDs: %+v", span)
+		}
+		byName[span.Name] = span
+	}
+
+	command, ok := byName["sh"]
+	if !ok {
+		t.Fatalf("Expected a span for the command, got %+v", spans)
+	}
+	if command.ParentSpanID != byName["[Step 7] Create AWS resources"].SpanID || command.Status.Code != 2 {
+		t.Errorf("Expected a failed child span of the step, got %+v", command)
+	}
+	for _, attribute := range command.Attributes {
+		if strings.Contains(attribute.Value.StringValue, "span-secret") {
+			t.Errorf("Expected secrets to be redacted, got %s=%s", attribute.Key, attribute.Value.StringValue)
+		}
+	}
+	if byName["install"].ParentSpanID != "" || byName["install"].Status.Code != 1 {
+		t.Errorf("Expected a successful root span, got %+v", byName["install"])
+	}
+}
+
+func TestNilTracer(t *testing.T) {
+	var tracer *Tracer
+	span := tracer.StartSpan("install", nil)
+	span.StartChild("step").End(nil)
+	span.SetAttribute("key", "value")
+	span.End(nil)
+	if tracer.TraceID() != "" || tracer.Export("http://localhost:4318") != nil {
+		t.Error("Expected a nil tracer to record and export nothing")
+	}
+}
+
+func TestCommandSpanName(t *testing.T) {
+	tests := map[string][]string{
+		"aws s3api": {"aws", "s3api", "create-bucket"},
+		"oc adm":    {"/usr/bin/oc", "adm", "release", "extract"},
+		"ccoctl":    {"ccoctl", "--help"},
+	}
+	for expected, command := range tests {
+		if got := commandSpanName(command[0], command[1:]); got != expected {
+			t.Errorf("Expected %q, got %q", expected, got)
+		}
+	}
+}
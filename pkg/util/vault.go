@@ -0,0 +1,143 @@
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// VaultClient reads secrets from the HashiCorp Vault HTTP API
+type VaultClient struct {
+	Address string
+	Token   string
+	client  *http.Client
+}
+
+// NewVaultClient returns a client for the given address (VAULT_ADDR when
+// empty), authenticated with VAULT_TOKEN or the token saved by `vault login`
+func NewVaultClient(address string) (*VaultClient, error) {
+	if address == "" {
+		address = os.Getenv("VAULT_ADDR")
+	}
+	if address == "" {
+		return nil, fmt.Errorf("vault address not configured (set vault.address or VAULT_ADDR)")
+	}
+
+	token := os.Getenv("VAULT_TOKEN")
+	if token == "" {
+		if homeDir, err := os.UserHomeDir(); err == nil {
+			if data, err := os.ReadFile(filepath.Join(homeDir, ".vault-token")); err == nil {
+				token = strings.TrimSpace(string(data))
+			}
+		}
+	}
+	if token == "" {
+		return nil, fmt.Errorf("no vault token found (set VAULT_TOKEN or run 'vault login')")
+	}
+
+	return &VaultClient{
+		Address: strings.TrimSuffix(address, "/"),
+		Token:   token,
+		client:  &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+// ReadSecret returns the data of the secret at path. KV version 2 secrets
+// (path including "/data/") are unwrapped, so callers see the stored fields.
+func (v *VaultClient) ReadSecret(path string) (map[string]interface{}, error) {
+	req, err := http.NewRequest(http.MethodGet, v.Address+"/v1/"+strings.TrimPrefix(path, "/"), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", v.Token)
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read vault secret %s: %w", path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("vault returned %s for secret %s", resp.Status, path)
+	}
+
+	var secret struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&secret); err != nil {
+		return nil, fmt.Errorf("failed to parse vault secret %s: %w", path, err)
+	}
+
+	if inner, ok := secret.Data["data"].(map[string]interface{}); ok {
+		if _, versioned := secret.Data["metadata"]; versioned {
+			return inner, nil
+		}
+	}
+	return secret.Data, nil
+}
+
+// ReadField returns a single string field of a secret. The reference has the
+// form <path>#<field>.
+func (v *VaultClient) ReadField(ref string) (string, error) {
+	path, field, ok := strings.Cut(ref, "#")
+	if !ok || path == "" || field == "" {
+		return "", fmt.Errorf("invalid vault reference %q: expected <path>#<field>", ref)
+	}
+
+	data, err := v.ReadSecret(path)
+	if err != nil {
+		return "", err
+	}
+	return stringField(data, path, field)
+}
+
+// ReadAWSCredentials returns the AWS credentials stored at path, either as a
+// KV secret (aws_access_key_id, aws_secret_access_key, aws_session_token) or
+// generated by the AWS secrets engine (access_key, secret_key, security_token)
+func (v *VaultClient) ReadAWSCredentials(path string) (*AWSCredentials, error) {
+	data, err := v.ReadSecret(path)
+	if err != nil {
+		return nil, err
+	}
+
+	creds := &AWSCredentials{}
+	for _, names := range []struct{ access, secret, token string }{
+		{"aws_access_key_id", "aws_secret_access_key", "aws_session_token"},
+		{"access_key", "secret_key", "security_token"},
+	} {
+		if _, ok := data[names.access]; !ok {
+			continue
+		}
+		if creds.AccessKeyID, err = stringField(data, path, names.access); err != nil {
+			return nil, err
+		}
+		if creds.SecretAccessKey, err = stringField(data, path, names.secret); err != nil {
+			return nil, err
+		}
+		creds.SessionToken, _ = data[names.token].(string)
+		return creds, nil
+	}
+	return nil, fmt.Errorf("vault secret %s does not contain AWS credentials", path)
+}
+
+func stringField(data map[string]interface{}, path, field string) (string, error) {
+	value, ok := data[field]
+	if !ok {
+		return "", fmt.Errorf("field %q not found in vault secret %s", field, path)
+	}
+	switch value := value.(type) {
+	case string:
+		return value, nil
+	default:
+		// e.g. a pull secret stored as a JSON object instead of a string
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return "", fmt.Errorf("field %q of vault secret %s is not a string", field, path)
+		}
+		return string(encoded), nil
+	}
+}
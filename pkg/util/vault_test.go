@@ -0,0 +1,59 @@
+package util
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestVaultClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "test-token" {
+			http.Error(w, `{"errors":["permission denied"]}`, http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/secret/data/openshift":
+			w.Write([]byte(`{"data": {"data": {
+				"pullSecret": {"auths": {"quay.io": {"auth": "dGVzdA=="}}},
+				"sshKey": "ssh-ed25519 AAAA test"
+			}, "metadata": {"version": 3}}}`))
+		case "/v1/aws/creds/installer":
+			w.Write([]byte(`{"data": {"access_key": "AKIAVAULT", "secret_key": "vault-secret", "security_token": null}}`))
+		default:
+			http.Error(w, `{"errors":[]}`, http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	t.Setenv("VAULT_TOKEN", "test-token")
+	client, err := NewVaultClient(server.URL)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	sshKey, err := client.ReadField("secret/data/openshift#sshKey")
+	if err != nil || sshKey != "ssh-ed25519 AAAA test" {
+		t.Errorf("Unexpected SSH key %q (error: %v)", sshKey, err)
+	}
+
+	pullSecret, err := client.ReadField("secret/data/openshift#pullSecret")
+	if err != nil || pullSecret != `{"auths":{"quay.io":{"auth":"dGVzdA=="}}}` {
+		t.Errorf("Unexpected pull secret %q (error: %v)", pullSecret, err)
+	}
+
+	if _, err := client.ReadField("secret/data/openshift#missing"); err == nil {
+		t.Error("Expected error for a missing field")
+	}
+	if _, err := client.ReadField("secret/data/openshift"); err == nil {
+		t.Error("Expected error for a reference without field")
+	}
+
+	creds, err := client.ReadAWSCredentials("aws/creds/installer")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if creds.AccessKeyID != "AKIAVAULT" || creds.SecretAccessKey != "vault-secret" || creds.SessionToken != "" {
+		t.Errorf("Unexpected credentials: %+v", creds)
+	}
+}
@@ -1,6 +1,7 @@
 package util
 
 import (
+	"encoding/json"
 	"fmt"
 	"strings"
 )
@@ -26,3 +27,23 @@ func ExtractVersionArch(releaseImage string) (string, error) {
 
 	return tag, nil
 }
+
+// GetReleaseDigest returns the digest (sha256:...) of a release image using `oc adm release info`
+func GetReleaseDigest(executor CommandExecutor, releaseImage string) (string, error) {
+	output, err := executor.Execute("oc", "adm", "release", "info", releaseImage, "--output=json")
+	if err != nil {
+		return "", fmt.Errorf("failed to get release info: %w", err)
+	}
+
+	var info struct {
+		Digest string `json:"digest"`
+	}
+	if err := json.Unmarshal([]byte(output), &info); err != nil {
+		return "", fmt.Errorf("failed to parse release info: %w", err)
+	}
+	if info.Digest == "" {
+		return "", fmt.Errorf("release info does not contain a digest")
+	}
+
+	return info.Digest, nil
+}
@@ -0,0 +1,45 @@
+package util
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPostWebhook(t *testing.T) {
+	var received map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/json" {
+			http.Error(w, "unsupported content type", http.StatusUnsupportedMediaType)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&received)
+		if r.URL.Path == "/fail" {
+			http.Error(w, "invalid_token", http.StatusForbidden)
+		}
+	}))
+	defer server.Close()
+
+	if err := PostWebhook(server.URL+"/ok", map[string]string{"text": "done"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if received["text"] != "done" {
+		t.Errorf("Expected payload to be received, got %v", received)
+	}
+
+	if err := PostWebhook(server.URL+"/fail", map[string]string{}); err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("Expected error for non-2xx status, got %v", err)
+	}
+
+	// Connection errors do not leak the URL, which holds the webhook credentials
+	server.Close()
+	err := PostWebhook(server.URL+"/secret-token", map[string]string{})
+	if err == nil {
+		t.Fatal("Expected error for unreachable webhook")
+	}
+	if strings.Contains(err.Error(), "secret-token") {
+		t.Errorf("Error should not contain the webhook URL: %v", err)
+	}
+}
@@ -0,0 +1,578 @@
+// Package wrapper is the installation engine of openshift-sts-wrapper, for Go
+// programs embedding the STS installation flow:
+//
+//	cfg := &config.Config{ReleaseImage: "...", ClusterName: "my-cluster", ...}
+//	installer := wrapper.New(cfg)
+//	result, err := installer.Run(ctx)
+//
+// The installer runs the steps that are selected and not completed yet, like
+// the install command, and records them in the step journal. Prompts,
+// credentials, preflight checks and notifications are left to the caller.
+package wrapper
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/clobrano/openshift-sts-wrapper/pkg/config"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/errors"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/logger"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/steps"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/util"
+)
+
+// EventType is the kind of an installation event
+type EventType string
+
+// Installation events
+const (
+	EventStepStarted   EventType = "step-started"
+	EventStepSucceeded EventType = "step-succeeded"
+	EventStepFailed    EventType = "step-failed"
+	EventStepSkipped   EventType = "step-skipped"
+)
+
+// Event reports the progress of a step
+type Event struct {
+	Type     EventType
+	Step     string        // Stable step name, e.g. deploy-cluster
+	Number   int           // Step number
+	Label    string        // Label of the step in logs and summaries, e.g. "[Step 10] Deploy cluster"
+	Reason   string        // Why the step was skipped
+	Duration time.Duration // Duration of a finished step
+	Err      error         // Error of a failed step
+}
+
+// Result is the outcome of an installation run
+type Result struct {
+	Summary      *errors.Summary
+	Journal      *util.Journal
+	Deployed     bool                // The cluster was deployed (step 10 succeeded) during the run
+	Interrupted  bool                // The context was cancelled before the installation completed
+	Verification *steps.VerifyReport // Outcome of the checks of step 11, if they ran
+}
+
+// Installer runs the installation steps for a configuration
+type Installer struct {
+	Config *config.Config
+	// Log receives the progress messages (default: discarded)
+	Log *logger.Logger
+	// Events, when set, receives an event for every step. Run blocks on
+	// sending, so the caller must receive until Run returns.
+	Events chan<- Event
+	// Confirm is asked before each step when Config.ConfirmEachStep is set;
+	// steps it rejects are skipped (default: every step is confirmed)
+	Confirm func(label string) bool
+	// ConfirmReleaseChange is asked when earlier steps of the cluster used
+	// another release; unless it confirms, Run fails with a
+	// *util.ReleaseChangedError (default: Run fails)
+	ConfirmReleaseChange func(previous, current string) bool
+	// Trace, when set, gets a child span for every step
+	Trace *util.Span
+	// WrapExecutor, when set, wraps the executor of the commands of every step,
+	// e.g. to record or replay them (see util.Recorder and util.Replayer)
+	WrapExecutor func(util.CommandExecutor) util.CommandExecutor
+}
+
+// executor returns the executor running the commands of real
+func (i *Installer) executor(real *util.RealExecutor) util.CommandExecutor {
+	if i.WrapExecutor == nil {
+		return real
+	}
+	return i.WrapExecutor(real)
+}
+
+// New creates an installer for a configuration, setting its defaults
+func New(cfg *config.Config) *Installer {
+	cfg.SetDefaults()
+	return &Installer{Config: cfg}
+}
+
+type pendingStep struct {
+	num      int
+	step     steps.Step
+	executor *util.RealExecutor
+	err      error
+	duration time.Duration
+	eta      time.Duration // Estimated duration, 0 if unknown
+}
+
+// Run executes the installation. Cancelling ctx interrupts the running
+// commands, records the current step as interrupted and returns
+// util.ErrInterrupted; reaching the installation timeout of the configuration
+// fails the current step instead. The returned error is also set when the
+// configuration is invalid (without result) or a step failed. A random name
+// suffix of the configuration is appended to its cluster name first.
+func (i *Installer) Run(ctx context.Context) (*Result, error) {
+	cfg := i.Config
+	log := i.Log
+	if log == nil {
+		log = logger.New(logger.LevelQuiet, nil)
+	}
+	if _, err := cfg.ApplyNameSuffix(); err != nil {
+		return nil, err
+	}
+	if err := config.ValidateConfig(cfg); err != nil {
+		return nil, fmt.Errorf("configuration error: %w", err)
+	}
+	// Installs from a mirror use the resources the mirror command wrote
+	if cfg.Mirror.Registry != "" {
+		versionArch, _ := util.ExtractVersionArch(cfg.ReleaseImage)
+		if _, err := util.ReadMirrorResources(util.GetSharedMirrorPath(versionArch)); err != nil {
+			return nil, fmt.Errorf("configuration error: %w (run 'openshift-sts-wrapper mirror' first)", err)
+		}
+	}
+
+	// Bound the whole installation by the overall timeout, if any
+	installCtx := ctx
+	timeout, _ := cfg.GetInstallTimeout()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		installCtx, cancel = context.WithTimeout(installCtx, timeout)
+		defer cancel()
+	}
+
+	// A configured oc is the one every step runs. The directory of the oc to
+	// run is given to the executors rather than set in the PATH of the process.
+	var binDirs []string
+	if cfg.Binaries.OC != "" {
+		dir, err := util.OCBinaryDir(cfg.Binaries.OC)
+		if err != nil {
+			return nil, fmt.Errorf("configuration error: %w", err)
+		}
+		binDirs = []string{dir}
+	}
+
+	// Pin the release image to its digest so that every step (and cleanup) uses
+	// the same content even if the tag moves, and cached artifacts are only
+	// reused for that content
+	pinReleaseDigest(log, cfg, i.executor(&util.RealExecutor{Path: binDirs}))
+
+	// A resumed run must use the release of the earlier steps
+	var releaseChanged *util.ReleaseChangedError
+	if err := util.CheckJournalRelease(cfg.ClusterName, cfg.ReleaseImage, cfg.ReleaseDigest); stderrors.As(err, &releaseChanged) {
+		if i.ConfirmReleaseChange == nil || !i.ConfirmReleaseChange(releaseChanged.Previous(), releaseChanged.Current()) {
+			return nil, err
+		}
+		log.Info(fmt.Sprintf("⚠  Continuing with release %s: the steps completed with %s are detected from their outputs only", releaseChanged.Current(), releaseChanged.Previous()))
+	}
+
+	// Configured binaries replace the ones of Steps 2 and 3: they must be the
+	// ones of the release
+	if err := steps.VerifyExternalBinaries(cfg, i.executor(&util.RealExecutor{Path: binDirs})); err != nil {
+		return nil, fmt.Errorf("configuration error: %w", err)
+	}
+
+	detector := steps.NewDetector(cfg)
+	detector.ValidateBinaries(i.executor(&util.RealExecutor{Path: binDirs}), log)
+
+	// Record the outcome of every step in the cluster's step journal
+	result := &Result{
+		Summary: errors.NewSummary(),
+		Journal: util.OpenJournal(cfg.ClusterName, cfg.ReleaseImage, cfg.ReleaseDigest),
+	}
+	summary := result.Summary
+	summary.ClusterName = cfg.ClusterName
+
+	// Estimate durations from previous runs of the same release
+	versionArch, _ := util.ExtractVersionArch(cfg.ReleaseImage)
+	estimates := estimateDurations(log, cfg, detector, versionArch)
+
+	// Steps 1-3 only download from the release image and don't depend on each
+	// other, so they run concurrently. All other steps run one at a time.
+	for _, phase := range groupPhases(len(config.StepNames), steps.ParallelSteps) {
+		// Once Step 2 extracted the oc client of the release, the next steps run it
+		if binDirs == nil {
+			binDirs = releaseClientsDirs(log, cfg, versionArch)
+		}
+
+		var runnable []pendingStep
+		for _, num := range phase {
+			// Each step gets its own executor so that per-step timeouts don't interfere
+			executor := &util.RealExecutor{Path: binDirs}
+
+			// Create step to get its name
+			step, err := steps.NewInstallStep(num, cfg, log, i.executor(executor))
+			if err != nil {
+				log.Error(fmt.Sprintf("Failed to create step: %v", err))
+				label := fmt.Sprintf("Step %d", num)
+				summary.AddStep(config.StepName(num), label, 0, err)
+				i.emit(Event{Type: EventStepFailed, Step: config.StepName(num), Number: num, Label: label, Err: err})
+				continue
+			}
+
+			label := StepLabel(num, step)
+			reason := ""
+			switch {
+			case cfg.StepSkipped(num):
+				log.Info(fmt.Sprintf("⏭  Skipping %s (--skip-steps)", label))
+				reason = "skip-steps"
+			case !cfg.StepSelected(num):
+				log.Debug(fmt.Sprintf("Skipping %s (not selected)", label))
+				reason = "not selected"
+			case steps.ExternalBinary(cfg, num) != "":
+				log.Info(fmt.Sprintf("⏭  Skipping %s (using %s)", label, steps.ExternalBinary(cfg, num)))
+				reason = "external binary"
+			// A step selected with --only-step runs even if it looks completed
+			case cfg.OnlyStep == 0 && detector.ShouldSkipStep(num):
+				log.Info(fmt.Sprintf("⏭  Skipping %s (already completed)", label))
+				reason = "already completed"
+			// Optionally confirm before executing the step
+			case cfg.ConfirmEachStep && i.Confirm != nil && !i.Confirm(label):
+				log.Info(fmt.Sprintf("⏭  Skipping %s (user choice)", label))
+				reason = "user choice"
+			}
+			if reason != "" {
+				summary.AddSkipped(config.StepName(num), label, reason)
+				i.emit(Event{Type: EventStepSkipped, Step: config.StepName(num), Number: num, Label: label, Reason: reason})
+				continue
+			}
+
+			runnable = append(runnable, pendingStep{num: num, step: step, executor: executor, eta: estimates[num]})
+		}
+
+		if len(runnable) == 0 {
+			continue
+		}
+
+		if ctx.Err() != nil {
+			result.Interrupted = true
+			break
+		}
+		if installCtx.Err() != nil {
+			num := runnable[0].num
+			label := StepLabel(num, runnable[0].step)
+			err := fmt.Errorf("installation timed out after %s", timeout)
+			summary.AddStep(config.StepName(num), label, 0, err)
+			i.emit(Event{Type: EventStepFailed, Step: config.StepName(num), Number: num, Label: label, Err: err})
+			break
+		}
+
+		if len(runnable) > 1 {
+			log.Info(fmt.Sprintf("Running %d steps in parallel...", len(runnable)))
+		}
+
+		failed := false
+		for _, p := range i.runPhase(installCtx, log, result.Journal, runnable) {
+			label := StepLabel(p.num, p.step)
+			summary.AddStep(config.StepName(p.num), label, p.duration, p.err)
+			if verify, ok := p.step.(*steps.Step11Verify); ok && verify.Report != nil {
+				result.Verification = verify.Report
+			}
+			if p.err != nil {
+				result.Interrupted = result.Interrupted || stderrors.Is(p.err, util.ErrInterrupted)
+				failed = true
+				continue
+			}
+			if err := util.RecordStepDuration(versionArch, config.StepName(p.num), p.duration); err != nil {
+				log.Debug(fmt.Sprintf("Could not record step duration: %v", err))
+			}
+			afterStep(log, cfg, p.num)
+			if p.num == 10 {
+				result.Deployed = true
+			}
+		}
+		if failed {
+			break
+		}
+	}
+
+	addInstallArtifacts(cfg, summary)
+	if result.Interrupted {
+		return result, util.ErrInterrupted
+	}
+	if summary.HasErrors() {
+		return result, fmt.Errorf("%s failed: %w", summary.Failed[0].StepName, summary.Failed[0].Error)
+	}
+	return result, nil
+}
+
+// emit sends an event to the events channel, if any
+func (i *Installer) emit(event Event) {
+	if i.Events != nil {
+		i.Events <- event
+	}
+}
+
+// StepLabel returns the label of a step in logs and summaries
+func StepLabel(num int, step steps.Step) string {
+	return fmt.Sprintf("[Step %d] %s", num, step.Name())
+}
+
+// groupPhases splits the steps, in execution order, into execution phases:
+// consecutive steps listed in parallel share a phase, every other step gets its own
+func groupPhases(count int, parallel map[int]bool) [][]int {
+	var phases [][]int
+	for num := 1; num <= count; num++ {
+		last := len(phases) - 1
+		if parallel[num] && last >= 0 && parallel[phases[last][0]] {
+			phases[last] = append(phases[last], num)
+			continue
+		}
+		phases = append(phases, []int{num})
+	}
+	return phases
+}
+
+// runPhase executes the given steps concurrently and returns them, in order,
+// with their execution errors set and recorded in the journal. Each step is
+// traced as a child span of the installer trace.
+func (i *Installer) runPhase(installCtx context.Context, log *logger.Logger, journal *util.Journal, phase []pendingStep) []pendingStep {
+	var wg sync.WaitGroup
+	for n := range phase {
+		wg.Add(1)
+		go func(p *pendingStep) {
+			defer wg.Done()
+			name := config.StepName(p.num)
+			label := StepLabel(p.num, p.step)
+			log.StartStep(label)
+			i.emit(Event{Type: EventStepStarted, Step: name, Number: p.num, Label: label})
+			if p.eta > 0 {
+				log.Info(fmt.Sprintf("   Usually takes ~%s (ETA %s)", util.FormatEstimate(p.eta), time.Now().Add(p.eta).Format("15:04")))
+			}
+			if err := journal.StartStep(name); err != nil {
+				log.Debug(fmt.Sprintf("Could not update step journal: %v", err))
+			}
+			span := i.Trace.StartChild(label)
+			span.SetAttribute("step.name", name)
+			span.SetAttribute("step.number", strconv.Itoa(p.num))
+			p.executor.Span = span
+			started := time.Now()
+			p.err = executeStep(installCtx, p.executor, i.Config, p.num, p.step)
+			p.duration = time.Since(started)
+			span.End(p.err)
+			if err := journal.FinishStep(name, p.err); err != nil {
+				log.Debug(fmt.Sprintf("Could not update step journal: %v", err))
+			}
+			if stderrors.Is(p.err, util.ErrInterrupted) {
+				for _, path := range steps.PartialOutputs(i.Config.ClusterName, p.num) {
+					log.Debug(fmt.Sprintf("Removing partial output %s", path))
+					os.RemoveAll(path)
+				}
+			}
+			if p.err != nil {
+				log.FailStep(label)
+				i.emit(Event{Type: EventStepFailed, Step: name, Number: p.num, Label: label, Duration: p.duration, Err: p.err})
+			} else {
+				log.CompleteStep(label)
+				i.emit(Event{Type: EventStepSucceeded, Step: name, Number: p.num, Label: label, Duration: p.duration})
+			}
+		}(&phase[n])
+	}
+	wg.Wait()
+	return phase
+}
+
+// executeStep runs a step bounded by its configured timeout (and the overall
+// installation deadline). Child processes are killed when the deadline expires.
+func executeStep(installCtx context.Context, executor *util.RealExecutor, cfg *config.Config, stepNum int, step steps.Step) error {
+	stepTimeout, _ := cfg.GetStepTimeout(stepNum)
+	stepCtx, cancel := installCtx, context.CancelFunc(func() {})
+	if stepTimeout > 0 {
+		stepCtx, cancel = context.WithTimeout(installCtx, stepTimeout)
+	}
+	defer cancel()
+
+	executor.Context = stepCtx
+
+	err := step.Execute()
+	if installCtx.Err() == context.Canceled {
+		return util.ErrInterrupted
+	}
+	if stepCtx.Err() == context.DeadlineExceeded {
+		if installCtx.Err() == context.DeadlineExceeded {
+			timeout, _ := cfg.GetInstallTimeout()
+			return fmt.Errorf("installation timed out after %s", timeout)
+		}
+		return fmt.Errorf("step timed out after %s", stepTimeout)
+	}
+	return err
+}
+
+// estimateDurations returns the estimated duration of every step that will
+// run, based on the previous runs of the release, and shows the estimate of
+// the whole installation
+func estimateDurations(log *logger.Logger, cfg *config.Config, detector *steps.Detector, versionArch string) map[int]time.Duration {
+	estimates := map[int]time.Duration{}
+	history, err := util.ReadDurationHistory()
+	if err != nil {
+		return estimates
+	}
+
+	var total time.Duration
+	unknown := 0
+	for num := 1; num <= len(config.StepNames); num++ {
+		if !cfg.StepSelected(num) || (cfg.OnlyStep == 0 && detector.ShouldSkipStep(num)) {
+			continue
+		}
+		estimate, runs := history.EstimateStepDuration(versionArch, config.StepName(num))
+		if runs == 0 {
+			unknown++
+			continue
+		}
+		estimates[num] = estimate
+		// Parallel steps overlap, the longest one counts
+		if steps.ParallelSteps[num] {
+			estimate = max(0, estimate-parallelEstimate(estimates, num))
+		}
+		total += estimate
+	}
+
+	if total > 0 {
+		message := fmt.Sprintf("Estimated installation time: ~%s (ETA %s), based on previous runs of %s", util.FormatEstimate(total), time.Now().Add(total).Format("15:04"), versionArch)
+		if unknown > 0 {
+			message += fmt.Sprintf("; %d step(s) without previous runs not included", unknown)
+		}
+		log.Info(message)
+	}
+	return estimates
+}
+
+// parallelEstimate returns the longest estimate among the parallel steps
+// before the given one
+func parallelEstimate(estimates map[int]time.Duration, num int) time.Duration {
+	var longest time.Duration
+	for other, estimate := range estimates {
+		if other < num && steps.ParallelSteps[other] {
+			longest = max(longest, estimate)
+		}
+	}
+	return longest
+}
+
+// releaseClientsDirs returns the directory of the oc and kubectl clients
+// extracted from the release, to search first for the commands of the steps,
+// or nil if there are none
+func releaseClientsDirs(log *logger.Logger, cfg *config.Config, versionArch string) []string {
+	dir, err := util.ReleaseClientsDir(versionArch, cfg.ReleaseDigest)
+	if err != nil {
+		log.Debug(fmt.Sprintf("Could not use the oc client of the release: %v", err))
+		return nil
+	}
+	if dir == "" {
+		return nil
+	}
+	log.Debug(fmt.Sprintf("Using the oc client of release %s", versionArch))
+	return []string{dir}
+}
+
+// afterStep performs the bookkeeping that follows a successful step
+func afterStep(log *logger.Logger, cfg *config.Config, stepNum int) {
+	// Record checksums of the shared artifacts produced by the step
+	if versionArch, err := util.ExtractVersionArch(cfg.ReleaseImage); err == nil {
+		if artifacts := steps.CachedArtifacts(versionArch, stepNum); len(artifacts) > 0 {
+			if err := util.RecordArtifacts(versionArch, cfg.ReleaseImage, cfg.ReleaseDigest, artifacts...); err != nil {
+				log.Debug(fmt.Sprintf("Could not record artifact checksums: %v", err))
+			}
+		}
+	}
+
+	switch stepNum {
+	case 1, 4:
+		// Save installation metadata for cleanup purposes (and to track which shared
+		// artifacts the cluster uses). The cluster directory only exists after Step 4.
+		clusterDir := util.GetClusterPath(cfg.ClusterName, "")
+		if err := util.SaveInstallMetadata(clusterDir, cfg.ReleaseImage, cfg.ReleaseDigest); err != nil {
+			log.Debug(fmt.Sprintf("Could not save install metadata: %v", err))
+		} else {
+			log.Debug(fmt.Sprintf("Saved installation metadata to %s/install-metadata.json", clusterDir))
+		}
+		if stepNum == 4 && cfg.SSHKeyPath != "" {
+			if err := util.RecordSSHKey(clusterDir, cfg.SSHKeyPath); err != nil {
+				log.Debug(fmt.Sprintf("Could not record the SSH key: %v", err))
+			}
+		}
+		// Recorded before Step 7 creates the resources, so that cleanup finds them
+		if stepNum == 4 && cfg.ResourcePrefix != "" {
+			if err := util.RecordResourcePrefix(clusterDir, cfg.ResourcePrefix); err != nil {
+				log.Debug(fmt.Sprintf("Could not record the resource prefix: %v", err))
+			}
+		}
+	case 5:
+		// After Step 5, backup install-config.yaml before Step 6 consumes it
+		versionArch, err := util.ExtractVersionArch(cfg.ReleaseImage)
+		if err == nil {
+			installConfigPath := util.GetInstallConfigPath(versionArch, cfg.ClusterName)
+			if util.FileExists(installConfigPath) {
+				backupPath := installConfigPath + ".backup"
+				if err := util.CopyFile(installConfigPath, backupPath); err != nil {
+					log.Debug(fmt.Sprintf("Could not backup install-config.yaml: %v", err))
+				} else {
+					log.Debug(fmt.Sprintf("Backed up install-config.yaml to %s", backupPath))
+				}
+			}
+		}
+	case 7:
+		// Cleanup must not delete roles it didn't create
+		if cfg.ExternalIAM() {
+			if err := util.RecordExternalIAM(util.GetClusterPath(cfg.ClusterName, "")); err != nil {
+				log.Debug(fmt.Sprintf("Could not record the existing IAM roles: %v", err))
+			}
+		}
+	}
+}
+
+// addInstallArtifacts records the files produced by the installation and the
+// web console URL of a deployed cluster in the summary
+func addInstallArtifacts(cfg *config.Config, summary *errors.Summary) {
+	artifacts := map[string]string{
+		"clusterDir":        util.GetClusterPath(cfg.ClusterName, ""),
+		"journal":           util.GetJournalPath(cfg.ClusterName),
+		"installConfig":     util.GetInstallConfigPath("", cfg.ClusterName) + ".backup",
+		"installLog":        util.GetClusterPath(cfg.ClusterName, ".openshift_install.log"),
+		"kubeconfig":        util.GetKubeconfigPath(cfg.ClusterName),
+		"kubeadminPassword": util.GetClusterPath(cfg.ClusterName, "auth/kubeadmin-password"),
+	}
+	for name, path := range artifacts {
+		if util.FileExists(path) || util.DirExists(path) {
+			summary.AddArtifact(name, path)
+		}
+	}
+
+	if _, deployed := summary.Artifacts["kubeconfig"]; !deployed {
+		return
+	}
+	baseDomain := cfg.BaseDomain
+	if fields, err := util.ExtractAllFields(artifacts["installConfig"]); err == nil && fields.BaseDomain != "" {
+		baseDomain = fields.BaseDomain
+	}
+	if baseDomain != "" {
+		summary.ConsoleURL = fmt.Sprintf("https://console-openshift-console.apps.%s.%s", cfg.ClusterName, baseDomain)
+	}
+}
+
+// pinReleaseDigest resolves the digest of the release image. A digest already
+// recorded for this cluster takes precedence, so resumed runs keep using the
+// content of the original run.
+func pinReleaseDigest(log *logger.Logger, cfg *config.Config, executor util.CommandExecutor) {
+	if cfg.ReleaseDigest == "" {
+		metadata, err := util.ReadInstallMetadata(util.GetClusterPath(cfg.ClusterName, ""))
+		if err == nil && metadata.ReleaseImage == cfg.ReleaseImage && metadata.ReleaseDigest != "" {
+			cfg.ReleaseDigest = metadata.ReleaseDigest
+			log.Debug(fmt.Sprintf("Using release digest recorded in install metadata: %s", cfg.ReleaseDigest))
+		}
+	}
+
+	if cfg.ReleaseDigest == "" {
+		// The tag is resolved in the mirror when the release can't be reached:
+		// the mirrored release image has the same digest
+		image := cfg.ReleaseImage
+		if versionArch, err := util.ExtractVersionArch(image); err == nil && cfg.Mirror.Registry != "" && !strings.Contains(image, "@") {
+			image = util.MirroredReleaseImage(cfg.Mirror.Registry, versionArch)
+		}
+		digest, err := util.GetReleaseDigest(executor, image)
+		if err != nil {
+			log.Info(fmt.Sprintf("⚠  Could not resolve the release image digest, using the tag: %v", err))
+			return
+		}
+		cfg.ReleaseDigest = digest
+	}
+
+	log.Info(fmt.Sprintf("Release image pinned to %s", cfg.PullSpec()))
+}
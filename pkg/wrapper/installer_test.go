@@ -0,0 +1,211 @@
+package wrapper
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/clobrano/openshift-sts-wrapper/pkg/config"
+	"github.com/clobrano/openshift-sts-wrapper/pkg/util"
+)
+
+func TestGroupPhases(t *testing.T) {
+	phases := groupPhases(5, map[int]bool{1: true, 2: true, 3: true})
+	expected := [][]int{{1, 2, 3}, {4}, {5}}
+	if !reflect.DeepEqual(phases, expected) {
+		t.Errorf("Expected phases %v, got %v", expected, phases)
+	}
+}
+
+func TestRunInvalidConfig(t *testing.T) {
+	result, err := New(&config.Config{ClusterName: "test-cluster"}).Run(context.Background())
+	if err == nil || result != nil {
+		t.Errorf("Expected a configuration error without result, got %v, %v", result, err)
+	}
+}
+
+func TestRunMissingExternalBinary(t *testing.T) {
+	cfg := &config.Config{
+		ReleaseImage:  "quay.io/openshift-release-dev/ocp-release:4.15.0-x86_64",
+		ReleaseDigest: "sha256:0123456789abcdef",
+		ClusterName:   "test-cluster",
+		Binaries:      config.BinariesConfig{OpenshiftInstall: filepath.Join(t.TempDir(), "openshift-install")},
+	}
+	result, err := New(cfg).Run(context.Background())
+	if err == nil || result != nil || !strings.Contains(err.Error(), "openshift-install not found") {
+		t.Errorf("Expected a configuration error without result, got %v, %v", result, err)
+	}
+}
+
+func TestRunEvents(t *testing.T) {
+	tmpDir := t.TempDir()
+	originalWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(originalWd)
+
+	cfg := &config.Config{
+		ReleaseImage:    "quay.io/openshift-release-dev/ocp-release:4.15.0-x86_64",
+		ReleaseDigest:   "sha256:0123456789abcdef",
+		ClusterName:     "test-cluster",
+		SkipSteps:       []string{"verify"},
+		ConfirmEachStep: true,
+	}
+	cfg.SetDefaults()
+
+	events := make(chan Event, len(config.StepNames))
+	installer := New(cfg)
+	installer.Events = events
+	confirmed := 0
+	installer.Confirm = func(label string) bool {
+		confirmed++
+		return false
+	}
+
+	result, err := installer.Run(context.Background())
+	close(events)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result.Deployed || result.Summary.HasErrors() {
+		t.Errorf("Expected no step to run, got %+v", result.Summary)
+	}
+	reasons := map[string]string{}
+	for event := range events {
+		if event.Type != EventStepSkipped {
+			t.Errorf("Unexpected event %+v", event)
+		}
+		reasons[event.Step] = event.Reason
+	}
+	if len(reasons) != len(config.StepNames) {
+		t.Errorf("Expected an event for every step, got %v", reasons)
+	}
+	if reasons["verify"] != "skip-steps" || reasons["deploy-cluster"] != "user choice" {
+		t.Errorf("Unexpected skip reasons %v", reasons)
+	}
+
+	// Only the steps that would run are confirmed
+	rejected := 0
+	for _, reason := range reasons {
+		if reason == "user choice" {
+			rejected++
+		}
+	}
+	if confirmed != rejected {
+		t.Errorf("Expected %d confirmations, got %d", rejected, confirmed)
+	}
+}
+
+func TestRunReleaseChanged(t *testing.T) {
+	tmpDir := t.TempDir()
+	originalWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(originalWd)
+
+	os.MkdirAll(util.GetClusterPath("test-cluster", ""), 0755)
+	journal := util.OpenJournal("test-cluster", "quay.io/openshift-release-dev/ocp-release:4.14.0-x86_64", "sha256:1111")
+	journal.StartStep("create-install-config")
+	journal.FinishStep("create-install-config", nil)
+
+	cfg := &config.Config{
+		ReleaseImage:  "quay.io/openshift-release-dev/ocp-release:4.15.0-x86_64",
+		ReleaseDigest: "sha256:0123456789abcdef",
+		ClusterName:   "test-cluster",
+		StartFromStep: 5,
+	}
+	cfg.SetDefaults()
+
+	installer := New(cfg)
+	var releaseChanged *util.ReleaseChangedError
+	if result, err := installer.Run(context.Background()); result != nil || !errors.As(err, &releaseChanged) {
+		t.Fatalf("Expected the release change to fail the run, got %v", err)
+	}
+
+	asked := ""
+	installer.ConfirmReleaseChange = func(previous, current string) bool {
+		asked = previous + " -> " + current
+		return true
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := installer.Run(ctx); !errors.Is(err, util.ErrInterrupted) {
+		t.Errorf("Expected the confirmed run to go on, got %v", err)
+	}
+	if asked != "quay.io/openshift-release-dev/ocp-release:4.14.0-x86_64 -> quay.io/openshift-release-dev/ocp-release:4.15.0-x86_64" {
+		t.Errorf("Unexpected confirmation %q", asked)
+	}
+}
+
+// fakeStep is a step that runs a command through its executor
+type fakeStep struct {
+	executor util.CommandExecutor
+}
+
+func (s *fakeStep) Name() string { return "Fake step" }
+
+func (s *fakeStep) Execute() error {
+	_, err := s.executor.Execute("sleep", "5")
+	return err
+}
+
+func TestExecuteStepInterrupted(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	executor := &util.RealExecutor{}
+	err := executeStep(ctx, executor, &config.Config{}, 7, &fakeStep{executor: executor})
+	if !errors.Is(err, util.ErrInterrupted) {
+		t.Errorf("Expected the step to be interrupted, got %v", err)
+	}
+}
+
+func TestRunInterrupted(t *testing.T) {
+	tmpDir := t.TempDir()
+	originalWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(originalWd)
+
+	cfg := &config.Config{
+		ReleaseImage:  "quay.io/openshift-release-dev/ocp-release:4.15.0-x86_64",
+		ReleaseDigest: "sha256:0123456789abcdef",
+		ClusterName:   "test-cluster",
+	}
+	cfg.SetDefaults()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	result, err := New(cfg).Run(ctx)
+	if !errors.Is(err, util.ErrInterrupted) || !result.Interrupted {
+		t.Errorf("Expected an interrupted run, got %v", err)
+	}
+	if result.Summary.HasErrors() {
+		t.Errorf("Expected no step to run, got %+v", result.Summary.Failed)
+	}
+}
+
+func TestRunWrapExecutor(t *testing.T) {
+	tmpDir := t.TempDir()
+	originalWd, _ := os.Getwd()
+	os.Chdir(tmpDir)
+	defer os.Chdir(originalWd)
+
+	cfg := &config.Config{
+		ReleaseImage:  "quay.io/openshift-release-dev/ocp-release:4.15.0-x86_64",
+		ReleaseDigest: "sha256:0123456789abcdef",
+		ClusterName:   "test-cluster",
+		OnlyStep:      1,
+	}
+	cfg.SetDefaults()
+
+	// Nothing was recorded, so the command of the step fails without running
+	installer := New(cfg)
+	installer.WrapExecutor = util.NewReplayer(&util.Fixtures{}).Wrap
+	result, _ := installer.Run(context.Background())
+	if len(result.Summary.Failed) != 1 || !errors.Is(result.Summary.Failed[0].Error, util.ErrNotRecorded) {
+		t.Errorf("Expected the step to fail on the replay, got %+v", result.Summary.Failed)
+	}
+}